@@ -28,6 +28,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -48,9 +49,12 @@ var (
 	ErrNotFound = errors.New("resources not found")
 	// ErrAlreadyInUse is returned by Acquire when resources are already being requested.
 	ErrAlreadyInUse = errors.New("resources already used by another user")
-	// ErrContextRequired is returned by AcquireWait and AcquireByStateWait when
-	// they are invoked with a nil context.
+	// ErrContextRequired is returned by AcquireWait, AcquireByStateWait and
+	// ClaimReservationWait when they are invoked with a nil context.
 	ErrContextRequired = errors.New("context required")
+	// ErrReservationNotFound is returned by reservation methods when the
+	// reservation does not exist or expired.
+	ErrReservationNotFound = errors.New("reservation not found")
 )
 
 // Client defines the public Boskos client object
@@ -236,6 +240,153 @@ func (c *Client) AcquireByStateWait(ctx context.Context, state, dest string, nam
 	}
 }
 
+// AcquireSet asks boskos for resources of several types in certain state at
+// once, and sets them to dest state. Either all resources are acquired or none
+// of them. Returns the resources on success.
+func (c *Client) AcquireSet(requests []common.ResourceRequest, state, dest string) ([]common.Resource, error) {
+	return c.AcquireSetWithPriority(requests, state, dest, "")
+}
+
+// AcquireSetWithPriority asks boskos for resources of several types in certain
+// state at once, and sets them to dest state. The request gets a place in the
+// queue of every requested type. Returns the resources on success.
+// Boskos Priority are FIFO.
+func (c *Client) AcquireSetWithPriority(requests []common.ResourceRequest, state, dest, requestID string) ([]common.Resource, error) {
+	resources, err := c.acquireSet(requests, state, dest, requestID)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, r := range resources {
+		c.storage.Add(r)
+	}
+	return resources, nil
+}
+
+// AcquireSetWait blocks until AcquireSet returns all requested resources or
+// the provided context is cancelled or its deadline exceeded.
+func (c *Client) AcquireSetWait(ctx context.Context, requests []common.ResourceRequest, state, dest string) ([]common.Resource, error) {
+	if ctx == nil {
+		return nil, ErrContextRequired
+	}
+	// request with FIFO priority
+	requestID := uuid.New().String()
+	// Try to acquire the resources until available or the context is
+	// cancelled or its deadline exceeded.
+	for {
+		r, err := c.AcquireSetWithPriority(requests, state, dest, requestID)
+		if err != nil {
+			if err == ErrAlreadyInUse || err == ErrNotFound {
+				select {
+				case <-ctx.Done():
+					return nil, err
+				case <-time.After(3 * time.Second):
+					continue
+				}
+			}
+			return nil, err
+		}
+		return r, nil
+	}
+}
+
+// Reserve asks boskos to hold a place for count resources of certain type in
+// certain state. Boskos sets resources aside for the reservation as they
+// become free, until the reservation expires after the duration. A zero
+// duration uses the longest duration boskos allows.
+// Returns the reservation on success.
+func (c *Client) Reserve(rtype, state string, count int, duration time.Duration) (*common.Reservation, error) {
+	values := url.Values{}
+	values.Set("type", rtype)
+	values.Set("state", state)
+	values.Set("owner", c.owner)
+	values.Set("count", strconv.Itoa(count))
+	if duration > 0 {
+		values.Set("duration", duration.String())
+	}
+	return c.reservation(http.MethodPost, "/reserve", values)
+}
+
+// GetReservation returns the current state of a reservation of the client.
+func (c *Client) GetReservation(id string) (*common.Reservation, error) {
+	values := url.Values{}
+	values.Set("id", id)
+	values.Set("owner", c.owner)
+	return c.reservation(http.MethodGet, "/reservation", values)
+}
+
+// ClaimReservation acquires all resources of a reservation at once and sets
+// them to dest state. Returns ErrNotFound while not all of them are set aside.
+// Returns the resources on success.
+func (c *Client) ClaimReservation(id, dest string) ([]common.Resource, error) {
+	resources, err := c.claimReservation(id, dest)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, r := range resources {
+		c.storage.Add(r)
+	}
+	return resources, nil
+}
+
+// ClaimReservationWait blocks until ClaimReservation returns the resources
+// of the reservation or the provided context is cancelled or its deadline
+// exceeded.
+func (c *Client) ClaimReservationWait(ctx context.Context, id, dest string) ([]common.Resource, error) {
+	if ctx == nil {
+		return nil, ErrContextRequired
+	}
+	for {
+		r, err := c.ClaimReservation(id, dest)
+		if err != nil {
+			if err == ErrNotFound {
+				select {
+				case <-ctx.Done():
+					return nil, err
+				case <-time.After(3 * time.Second):
+					continue
+				}
+			}
+			return nil, err
+		}
+		return r, nil
+	}
+}
+
+// CancelReservation deletes a reservation of the client, which frees the
+// resources set aside for it.
+func (c *Client) CancelReservation(id string) error {
+	values := url.Values{}
+	values.Set("id", id)
+	values.Set("owner", c.owner)
+
+	work := func(retriedErrs *[]error) (bool, error) {
+		resp, err := c.httpPost("/cancelreservation", values, "", nil)
+		if err != nil {
+			*retriedErrs = append(*retriedErrs, err)
+			return false, nil
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return true, nil
+		case http.StatusUnauthorized:
+			return false, ErrAlreadyInUse
+		case http.StatusGone:
+			return false, ErrReservationNotFound
+		default:
+			*retriedErrs = append(*retriedErrs, fmt.Errorf("status %s, status code %v cancelling reservation %s", resp.Status, resp.StatusCode, id))
+			return false, nil
+		}
+	}
+
+	return retry(work)
+}
+
 // ReleaseAll returns all resources hold by the client back to boskos and set them to dest state.
 func (c *Client) ReleaseAll(dest string) error {
 	c.lock.Lock()
@@ -298,6 +449,50 @@ func (c *Client) UpdateAll(state string) error {
 	return allErrors
 }
 
+// ReleaseSet returns a set of resources hold by the client back to boskos and
+// sets them to dest state. All of them are released even if some fail.
+func (c *Client) ReleaseSet(names []string, dest string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var allErrors error
+	for _, name := range names {
+		if _, err := c.storage.Get(name); err != nil {
+			allErrors = multierror.Append(allErrors, fmt.Errorf("no resource name %v", name))
+			continue
+		}
+		c.storage.Delete(name)
+		if err := c.Release(name, dest); err != nil {
+			allErrors = multierror.Append(allErrors, err)
+		}
+	}
+	return allErrors
+}
+
+// UpdateSet signals update for a set of resources hold by the client, which
+// keeps the whole set from being reaped.
+func (c *Client) UpdateSet(names []string, state string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var allErrors error
+	for _, name := range names {
+		r, err := c.storage.Get(name)
+		if err != nil {
+			allErrors = multierror.Append(allErrors, fmt.Errorf("no resource name %v", name))
+			continue
+		}
+		if err := c.Update(r.Name, state, nil); err != nil {
+			allErrors = multierror.Append(allErrors, err)
+			continue
+		}
+		if err := c.updateLocalResource(r, state, nil); err != nil {
+			allErrors = multierror.Append(allErrors, err)
+		}
+	}
+	return allErrors
+}
+
 // SyncAll signals update for all resources hold by the client.
 func (c *Client) SyncAll() error {
 	c.lock.Lock()
@@ -455,6 +650,119 @@ func (c *Client) acquireByState(state, dest string, names []string) ([]common.Re
 	return resources, retry(work)
 }
 
+func (c *Client) acquireSet(requests []common.ResourceRequest, state, dest, requestID string) ([]common.Resource, error) {
+	values := url.Values{}
+	values.Set("resources", common.ResourceRequestsToString(requests))
+	values.Set("state", state)
+	values.Set("dest", dest)
+	values.Set("owner", c.owner)
+	values.Set("request_id", requestID)
+	var resources []common.Resource
+
+	work := func(retriedErrs *[]error) (bool, error) {
+		resp, err := c.httpPost("/acquire-set", values, "", nil)
+		if err != nil {
+			*retriedErrs = append(*retriedErrs, err)
+			return false, nil
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			if err := json.NewDecoder(resp.Body).Decode(&resources); err != nil {
+				return false, err
+			}
+			return true, nil
+		case http.StatusUnauthorized:
+			return false, ErrAlreadyInUse
+		case http.StatusNotFound:
+			return false, ErrNotFound
+		default:
+			*retriedErrs = append(*retriedErrs, fmt.Errorf("status %s, status code %v", resp.Status, resp.StatusCode))
+			return false, nil
+		}
+	}
+
+	return resources, retry(work)
+}
+
+func (c *Client) reservation(method, action string, values url.Values) (*common.Reservation, error) {
+	var reservation common.Reservation
+
+	work := func(retriedErrs *[]error) (bool, error) {
+		var resp *http.Response
+		var err error
+		if method == http.MethodGet {
+			resp, err = c.httpGet(action, values)
+		} else {
+			resp, err = c.httpPost(action, values, "", nil)
+		}
+		if err != nil {
+			*retriedErrs = append(*retriedErrs, err)
+			return false, nil
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			if err := json.NewDecoder(resp.Body).Decode(&reservation); err != nil {
+				return false, err
+			}
+			return true, nil
+		case http.StatusBadRequest:
+			body, _ := ioutil.ReadAll(resp.Body)
+			return false, fmt.Errorf("invalid reservation: %s", strings.TrimSpace(string(body)))
+		case http.StatusUnauthorized:
+			return false, ErrAlreadyInUse
+		case http.StatusNotFound:
+			return false, ErrNotFound
+		case http.StatusGone:
+			return false, ErrReservationNotFound
+		default:
+			*retriedErrs = append(*retriedErrs, fmt.Errorf("status %s, status code %v", resp.Status, resp.StatusCode))
+			return false, nil
+		}
+	}
+
+	return &reservation, retry(work)
+}
+
+func (c *Client) claimReservation(id, dest string) ([]common.Resource, error) {
+	values := url.Values{}
+	values.Set("id", id)
+	values.Set("dest", dest)
+	values.Set("owner", c.owner)
+	var resources []common.Resource
+
+	work := func(retriedErrs *[]error) (bool, error) {
+		resp, err := c.httpPost("/claimreservation", values, "", nil)
+		if err != nil {
+			*retriedErrs = append(*retriedErrs, err)
+			return false, nil
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			if err := json.NewDecoder(resp.Body).Decode(&resources); err != nil {
+				return false, err
+			}
+			return true, nil
+		case http.StatusUnauthorized:
+			return false, ErrAlreadyInUse
+		case http.StatusNotFound:
+			return false, ErrNotFound
+		case http.StatusGone:
+			return false, ErrReservationNotFound
+		default:
+			*retriedErrs = append(*retriedErrs, fmt.Errorf("status %s, status code %v", resp.Status, resp.StatusCode))
+			return false, nil
+		}
+	}
+
+	return resources, retry(work)
+}
+
 // Release a lease for a resource and set its state to the destination state
 func (c *Client) Release(name, dest string) error {
 	values := url.Values{}
@@ -51,6 +51,7 @@ var (
 	dynamicResourceUpdatePeriod = flag.Duration("dynamic-resource-update-period", defaultDynamicResourceUpdatePeriod,
 		"Period at which to update dynamic resources. Set to 0 to disable.")
 	requestTTL        = flag.Duration("request-ttl", defaultRequestTTL, "request TTL before losing priority in the queue")
+	maxReservation    = flag.Duration("max-reservation-duration", ranch.DefaultMaxReservationDuration, "longest time a reservation holds its place for resources")
 	kubeClientOptions crds.KubernetesClientOptions
 	logLevel          = flag.String("log-level", "info", fmt.Sprintf("Log level is one of %v.", logrus.AllLevels))
 	namespace         = flag.String("namespace", corev1.NamespaceDefault, "namespace to install on")
@@ -101,6 +102,7 @@ func main() {
 	if err != nil {
 		logrus.WithError(err).Fatalf("failed to create ranch! Config: %v", *configPath)
 	}
+	r.MaxReservationDuration = *maxReservation
 
 	boskos := &http.Server{
 		Handler: traceHandler(handlers.NewBoskosHandler(r)),
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -150,6 +151,63 @@ func NewMetric(rtype string) Metric {
 	}
 }
 
+// Reservation holds a place for a number of resources of a type in a state
+// until it expires. The ranch sets free resources aside for the oldest
+// reservations, and the resources of a reservation can be claimed at once
+// when all of them are set aside.
+type Reservation struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	State      string    `json:"state"`
+	Owner      string    `json:"owner"`
+	Count      int       `json:"count"`
+	Expiration time.Time `json:"expiration"`
+	// Resources are the names of the resources set aside so far.
+	Resources []string `json:"resources,omitempty"`
+}
+
+// Ready determines whether all resources of the reservation are set aside.
+func (r Reservation) Ready() bool {
+	return len(r.Resources) >= r.Count
+}
+
+// ResourceRequest asks for a number of resources of a type.
+type ResourceRequest struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+func (r ResourceRequest) String() string {
+	return fmt.Sprintf("%s:%d", r.Type, r.Count)
+}
+
+// ResourceRequestsToString formats resource requests as a comma separated
+// list of type:count pairs.
+func ResourceRequestsToString(requests []ResourceRequest) string {
+	var pairs []string
+	for _, request := range requests {
+		pairs = append(pairs, request.String())
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ParseResourceRequests parses a comma separated list of type:count pairs.
+func ParseResourceRequests(value string) ([]ResourceRequest, error) {
+	var requests []ResourceRequest
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("%q is not of the form type:count", pair)
+		}
+		count, err := strconv.Atoi(parts[1])
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("count of %q must be a positive number", pair)
+		}
+		requests = append(requests, ResourceRequest{Type: parts[0], Count: count})
+	}
+	return requests, nil
+}
+
 // NewResource creates a new Boskos Resource.
 func NewResource(name, rtype, state, owner string, t time.Time) Resource {
 	// If no state defined, mark as Free
@@ -87,3 +87,53 @@ func TestUserData_JSON(t *testing.T) {
 		t.Errorf("src %v does not match %v", ud.ToMap(), decodedUD.ToMap())
 	}
 }
+
+func TestParseResourceRequests(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		value       string
+		expected    []ResourceRequest
+		expectedErr bool
+	}{
+		{
+			name:     "single type",
+			value:    "gce-project:1",
+			expected: []ResourceRequest{{Type: "gce-project", Count: 1}},
+		},
+		{
+			name:     "several types",
+			value:    "gce-project:1,aws-account:2",
+			expected: []ResourceRequest{{Type: "gce-project", Count: 1}, {Type: "aws-account", Count: 2}},
+		},
+		{
+			name:        "missing count",
+			value:       "gce-project",
+			expectedErr: true,
+		},
+		{
+			name:        "count must be positive",
+			value:       "gce-project:0",
+			expectedErr: true,
+		},
+		{
+			name:        "missing type",
+			value:       ":1",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests, err := ParseResourceRequests(tc.value)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %t, got %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(requests, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, requests)
+			}
+			if err == nil && ResourceRequestsToString(requests) != tc.value {
+				t.Errorf("expected %q to round trip, got %q", tc.value, ResourceRequestsToString(requests))
+			}
+		})
+	}
+}
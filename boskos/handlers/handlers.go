@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -41,10 +42,15 @@ func NewBoskosSimplifier() simplifypath.Simplifier {
 	return simplifypath.NewSimplifier(l("", // shadow element mimicing the root
 		l("acquire"),
 		l("acquirebystate"),
+		l("acquire-set"),
 		l("release"),
 		l("reset"),
 		l("update"),
 		l("metric"),
+		l("reserve"),
+		l("reservation"),
+		l("claimreservation"),
+		l("cancelreservation"),
 	))
 }
 
@@ -54,10 +60,15 @@ func NewBoskosHandler(r *ranch.Ranch) *http.ServeMux {
 	mux.Handle("/", handleDefault(r))
 	mux.Handle("/acquire", handleAcquire(r))
 	mux.Handle("/acquirebystate", handleAcquireByState(r))
+	mux.Handle("/acquire-set", handleAcquireSet(r))
 	mux.Handle("/release", handleRelease(r))
 	mux.Handle("/reset", handleReset(r))
 	mux.Handle("/update", handleUpdate(r))
 	mux.Handle("/metric", handleMetric(r))
+	mux.Handle("/reserve", handleReserve(r))
+	mux.Handle("/reservation", handleReservation(r))
+	mux.Handle("/claimreservation", handleClaimReservation(r))
+	mux.Handle("/cancelreservation", handleCancelReservation(r))
 	return mux
 }
 
@@ -74,6 +85,10 @@ func errorToStatus(err error) int {
 		return http.StatusNotFound
 	case *ranch.StateNotMatch:
 		return http.StatusConflict
+	case *ranch.ReservationNotFound:
+		return http.StatusGone
+	case *ranch.InvalidReservation:
+		return http.StatusBadRequest
 	}
 }
 
@@ -206,6 +221,75 @@ func handleAcquireByState(r *ranch.Ranch) http.HandlerFunc {
 	}
 }
 
+//  handleAcquireSet: Handler for /acquire-set
+//  Method: POST
+// 	URLParams:
+//		Required: resources=[string] : comma separated type:count pairs of requested resources
+//		Required: state=[string]     : current state of the requested resources
+//		Required: dest=[string]      : destination state of the requested resources
+//		Required: owner=[string]     : requester of the resources
+//		Optional: request_id=[string] : request ID to get a priority in the queues
+func handleAcquireSet(r *ranch.Ranch) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		logrus.WithField("handler", "handleAcquireSet").Infof("From %v", req.RemoteAddr)
+
+		if req.Method != http.MethodPost {
+			msg := fmt.Sprintf("Method %v, /acquire-set only accepts POST.", req.Method)
+			logrus.Warning(msg)
+			http.Error(res, msg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		resources := req.URL.Query().Get("resources")
+		state := req.URL.Query().Get("state")
+		dest := req.URL.Query().Get("dest")
+		owner := req.URL.Query().Get("owner")
+		requestID := req.URL.Query().Get("request_id")
+		if resources == "" || state == "" || dest == "" || owner == "" {
+			msg := fmt.Sprintf("Resources: %v, state: %v, dest: %v, owner: %v, all of them must be set in the request.", resources, state, dest, owner)
+			logrus.Warning(msg)
+			http.Error(res, msg, http.StatusBadRequest)
+			return
+		}
+		requests, err := common.ParseResourceRequests(resources)
+		if err != nil {
+			msg := fmt.Sprintf("Invalid resources %v: %v", resources, err)
+			logrus.Warning(msg)
+			http.Error(res, msg, http.StatusBadRequest)
+			return
+		}
+
+		logrus.Infof("Request for %v %v from %v, dest %v", state, resources, owner, dest)
+
+		acquired, err := r.AcquireSet(requests, state, dest, owner, requestID)
+		if err != nil {
+			logrus.WithError(err).Errorf("No available resources")
+			http.Error(res, err.Error(), errorToStatus(err))
+			return
+		}
+
+		var apiResources []common.Resource
+		for _, resource := range acquired {
+			apiResources = append(apiResources, resource.ToResource())
+		}
+
+		resBytes := new(bytes.Buffer)
+		if err := json.NewEncoder(resBytes).Encode(apiResources); err != nil {
+			logrus.WithError(err).Errorf("json.Marshal failed: %v, resources will be released", apiResources)
+			http.Error(res, err.Error(), errorToStatus(err))
+			for _, resource := range acquired {
+				err := r.Release(resource.Name, state, owner)
+				if err != nil {
+					logrus.WithError(err).Warningf("unable to release resource %s", resource.Name)
+				}
+			}
+			return
+		}
+		logrus.Infof("Resources leased: %v", resBytes.String())
+		fmt.Fprint(res, resBytes.String())
+	}
+}
+
 //  handleRelease: Handler for /release
 //  Method: POST
 //	URL Params:
@@ -390,3 +474,192 @@ func handleMetric(r *ranch.Ranch) http.HandlerFunc {
 		res.Write(js)
 	}
 }
+
+//  handleReserve: Handler for /reserve
+//  Method: POST
+//  URLParams:
+//		Required: type=[string]         : type of reserved resources
+//		Required: state=[string]        : state of reserved resources
+//		Required: owner=[string]        : owner of the reservation
+//		Required: count=[int]           : number of reserved resources
+//		Optional: duration=[durationStr] : how long the reservation holds its place
+func handleReserve(r *ranch.Ranch) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		logrus.WithField("handler", "handleReserve").Infof("From %v", req.RemoteAddr)
+
+		if req.Method != http.MethodPost {
+			msg := fmt.Sprintf("Method %v, /reserve only accepts POST.", req.Method)
+			logrus.Warning(msg)
+			http.Error(res, msg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		rtype := req.URL.Query().Get("type")
+		state := req.URL.Query().Get("state")
+		owner := req.URL.Query().Get("owner")
+		countStr := req.URL.Query().Get("count")
+		if rtype == "" || state == "" || owner == "" || countStr == "" {
+			msg := fmt.Sprintf("Type: %v, state: %v, owner: %v, count: %v, all of them must be set in the request.", rtype, state, owner, countStr)
+			logrus.Warning(msg)
+			http.Error(res, msg, http.StatusBadRequest)
+			return
+		}
+		count, err := strconv.Atoi(countStr)
+		if err != nil {
+			logrus.WithError(err).Errorf("Invalid count: %v", countStr)
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var duration time.Duration
+		if durationStr := req.URL.Query().Get("duration"); durationStr != "" {
+			duration, err = time.ParseDuration(durationStr)
+			if err != nil {
+				logrus.WithError(err).Errorf("Invalid duration: %v", durationStr)
+				http.Error(res, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		reservation, err := r.Reserve(rtype, state, owner, count, duration)
+		if err != nil {
+			logrus.WithError(err).Errorf("Reserve failed: %d %v %v (from %v)", count, state, rtype, owner)
+			http.Error(res, err.Error(), errorToStatus(err))
+			return
+		}
+		writeReservation(res, reservation)
+	}
+}
+
+//  handleReservation: Handler for /reservation
+//  Method: GET
+//  URLParams:
+//		Required: id=[string]    : ID of the reservation
+//		Required: owner=[string] : owner of the reservation
+func handleReservation(r *ranch.Ranch) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		logrus.WithField("handler", "handleReservation").Infof("From %v", req.RemoteAddr)
+
+		if req.Method != http.MethodGet {
+			msg := fmt.Sprintf("Method %v, /reservation only accepts GET.", req.Method)
+			logrus.Warning(msg)
+			http.Error(res, msg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := req.URL.Query().Get("id")
+		owner := req.URL.Query().Get("owner")
+		if id == "" || owner == "" {
+			msg := fmt.Sprintf("ID: %v, owner: %v, all of them must be set in the request.", id, owner)
+			logrus.Warning(msg)
+			http.Error(res, msg, http.StatusBadRequest)
+			return
+		}
+
+		reservation, err := r.GetReservation(id, owner)
+		if err != nil {
+			logrus.WithError(err).Errorf("Getting reservation %v failed (from %v)", id, owner)
+			http.Error(res, err.Error(), errorToStatus(err))
+			return
+		}
+		writeReservation(res, reservation)
+	}
+}
+
+func writeReservation(res http.ResponseWriter, reservation *common.Reservation) {
+	js, err := json.Marshal(reservation)
+	if err != nil {
+		logrus.WithError(err).Errorf("json.Marshal failed: %v", reservation)
+		http.Error(res, err.Error(), errorToStatus(err))
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(js)
+}
+
+//  handleClaimReservation: Handler for /claimreservation
+//  Method: POST
+//  URLParams:
+//		Required: id=[string]    : ID of the reservation
+//		Required: owner=[string] : owner of the reservation
+//		Required: dest=[string]  : destination state of the reserved resources
+func handleClaimReservation(r *ranch.Ranch) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		logrus.WithField("handler", "handleClaimReservation").Infof("From %v", req.RemoteAddr)
+
+		if req.Method != http.MethodPost {
+			msg := fmt.Sprintf("Method %v, /claimreservation only accepts POST.", req.Method)
+			logrus.Warning(msg)
+			http.Error(res, msg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := req.URL.Query().Get("id")
+		owner := req.URL.Query().Get("owner")
+		dest := req.URL.Query().Get("dest")
+		if id == "" || owner == "" || dest == "" {
+			msg := fmt.Sprintf("ID: %v, owner: %v, dest: %v, all of them must be set in the request.", id, owner, dest)
+			logrus.Warning(msg)
+			http.Error(res, msg, http.StatusBadRequest)
+			return
+		}
+
+		resources, err := r.ClaimReservation(id, owner, dest)
+		if err != nil {
+			logrus.WithError(err).Errorf("Claiming reservation %v failed (from %v)", id, owner)
+			http.Error(res, err.Error(), errorToStatus(err))
+			return
+		}
+
+		var apiResources []common.Resource
+		for _, resource := range resources {
+			apiResources = append(apiResources, resource.ToResource())
+		}
+		resBytes := new(bytes.Buffer)
+		if err := json.NewEncoder(resBytes).Encode(apiResources); err != nil {
+			logrus.WithError(err).Errorf("json.Marshal failed: %v, resources will be released", apiResources)
+			http.Error(res, err.Error(), errorToStatus(err))
+			for _, resource := range resources {
+				if err := r.Release(resource.Name, dest, owner); err != nil {
+					logrus.WithError(err).Warningf("unable to release resource %s", resource.Name)
+				}
+			}
+			return
+		}
+		logrus.Infof("Resource leased: %v", resBytes.String())
+		fmt.Fprint(res, resBytes.String())
+	}
+}
+
+//  handleCancelReservation: Handler for /cancelreservation
+//  Method: POST
+//  URLParams:
+//		Required: id=[string]    : ID of the reservation
+//		Required: owner=[string] : owner of the reservation
+func handleCancelReservation(r *ranch.Ranch) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		logrus.WithField("handler", "handleCancelReservation").Infof("From %v", req.RemoteAddr)
+
+		if req.Method != http.MethodPost {
+			msg := fmt.Sprintf("Method %v, /cancelreservation only accepts POST.", req.Method)
+			logrus.Warning(msg)
+			http.Error(res, msg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := req.URL.Query().Get("id")
+		owner := req.URL.Query().Get("owner")
+		if id == "" || owner == "" {
+			msg := fmt.Sprintf("ID: %v, owner: %v, all of them must be set in the request.", id, owner)
+			logrus.Warning(msg)
+			http.Error(res, msg, http.StatusBadRequest)
+			return
+		}
+
+		if err := r.CancelReservation(id, owner); err != nil {
+			logrus.WithError(err).Errorf("Cancelling reservation %v failed (from %v)", id, owner)
+			http.Error(res, err.Error(), errorToStatus(err))
+			return
+		}
+		logrus.Infof("Cancelled reservation %v", id)
+	}
+}
@@ -18,6 +18,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -297,6 +298,114 @@ func TestClientServerUpdate(t *testing.T) {
 	}
 }
 
+func TestClientServerReservation(t *testing.T) {
+	r := MakeTestRanch([]runtime.Object{
+		newResource("free", "type", common.Free, "", time.Time{}),
+		newResource("busy", "type", common.Busy, "other", time.Time{}),
+	})
+	boskos := httptest.NewServer(NewBoskosHandler(r))
+	defer boskos.Close()
+	c, err := client.NewClient("owner", boskos.URL, "", "")
+	if err != nil {
+		t.Fatalf("failed to create the Boskos client")
+	}
+
+	if _, err := c.Reserve("type", common.Free, 3, time.Minute); err == nil {
+		t.Error("expected reserving more resources than exist to fail")
+	}
+	reservation, err := c.Reserve("type", common.Free, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+	if expected := []string{"free"}; !reflect.DeepEqual(reservation.Resources, expected) {
+		t.Errorf("expected %v to be set aside, got %v", expected, reservation.Resources)
+	}
+	if _, err := c.Acquire("type", common.Free, common.Busy); err != client.ErrNotFound {
+		t.Errorf("expected reserved resources not to be acquired, got %v", err)
+	}
+	if _, err := c.ClaimReservation(reservation.ID, common.Busy); err != client.ErrNotFound {
+		t.Errorf("expected claiming a reservation that is not ready to fail, got %v", err)
+	}
+
+	if err := r.Release("busy", common.Free, "other"); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	if reservation, err = c.GetReservation(reservation.ID); err != nil || !reservation.Ready() {
+		t.Errorf("expected reservation to be ready, got %v: %v", reservation, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	resources, err := c.ClaimReservationWait(ctx, reservation.ID, common.Busy)
+	if err != nil {
+		t.Fatalf("failed to claim reservation: %v", err)
+	}
+	if len(resources) != 2 || !c.HasResource() {
+		t.Errorf("expected the client to hold the two reserved resources, got %v", resources)
+	}
+	if err := c.CancelReservation(reservation.ID); err != client.ErrReservationNotFound {
+		t.Errorf("expected the claimed reservation to be gone, got %v", err)
+	}
+}
+
+func TestClientServerAcquireSet(t *testing.T) {
+	r := MakeTestRanch([]runtime.Object{
+		newResource("project", "gcp", common.Free, "", time.Time{}),
+		newResource("account-1", "aws", common.Free, "", time.Time{}),
+		newResource("account-2", "aws", common.Busy, "other", time.Time{}),
+	})
+	boskos := httptest.NewServer(NewBoskosHandler(r))
+	defer boskos.Close()
+	c, err := client.NewClient("owner", boskos.URL, "", "")
+	if err != nil {
+		t.Fatalf("failed to create the Boskos client")
+	}
+	requests := []common.ResourceRequest{{Type: "gcp", Count: 1}, {Type: "aws", Count: 2}}
+
+	if _, err := c.AcquireSet(requests, common.Free, common.Busy); err != client.ErrNotFound {
+		t.Errorf("expected the set not to be acquired, got %v", err)
+	}
+	if c.HasResource() {
+		t.Error("expected the client not to hold any resource")
+	}
+	if err := r.Release("account-2", common.Free, "other"); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	resources, err := c.AcquireSetWait(ctx, requests, common.Free, common.Busy)
+	if err != nil {
+		t.Fatalf("failed to acquire the set: %v", err)
+	}
+	var names []string
+	for _, res := range resources {
+		names = append(names, res.Name)
+	}
+	sort.Strings(names)
+	if expected := []string{"account-1", "account-2", "project"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected to acquire %v, got %v", expected, names)
+	}
+
+	if err := c.UpdateSet(names, common.Busy); err != nil {
+		t.Errorf("failed to update the set: %v", err)
+	}
+	if err := c.ReleaseSet(names, common.Dirty); err != nil {
+		t.Errorf("failed to release the set: %v", err)
+	}
+	if c.HasResource() {
+		t.Error("expected the client not to hold any resource after releasing the set")
+	}
+	for _, name := range names {
+		res, err := r.Storage.GetResource(name)
+		if err != nil {
+			t.Fatalf("failed to get resource: %v", err)
+		}
+		if res.Status.Owner != "" || res.Status.State != common.Dirty {
+			t.Errorf("expected %s to be released as dirty, got %s owned by %q", name, res.Status.State, res.Status.Owner)
+		}
+	}
+}
+
 func diffResourceObjects(a, b *crds.ResourceObject) []string {
 	a.TypeMeta = metav1.TypeMeta{}
 	b.TypeMeta = metav1.TypeMeta{}
@@ -0,0 +1,187 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ranch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/retry"
+
+	"k8s.io/test-infra/boskos/common"
+	"k8s.io/test-infra/boskos/crds"
+)
+
+// AcquireSet checks out resources of several types in a state at once. Either
+// all requested resources are acquired or none of them. The request gets a
+// priority in the queue of every requested type, and is only granted once
+// enough free resources of every type are left for the requests ahead of it.
+// In: requests - types and numbers of the requested resources
+//     state - current state of the requested resources
+//     dest - destination state of the requested resources
+//     owner - requester of the resources
+//     requestID - request ID to get a priority in the queues
+// Out: A valid list of Resource objects on success, or
+//      ResourceNotFound error if not enough resources of some type are free, or
+//      ResourceTypeNotFound error if some type of resource does not exist.
+func (r *Ranch) AcquireSet(requests []common.ResourceRequest, state, dest, owner, requestID string) ([]*crds.ResourceObject, error) {
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("must request at least one resource")
+	}
+	// Requests for the same type are merged, so that they share a rank.
+	var types []string
+	counts := map[string]int{}
+	for _, request := range requests {
+		if request.Count < 1 {
+			return nil, fmt.Errorf("must request a positive number of resources of type %s, not %d", request.Type, request.Count)
+		}
+		if _, exists := counts[request.Type]; !exists {
+			types = append(types, request.Type)
+		}
+		counts[request.Type] += request.Count
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"resources":  common.ResourceRequestsToString(requests),
+		"state":      state,
+		"dest":       dest,
+		"owner":      owner,
+		"identifier": requestID,
+	})
+
+	var returnRes []*crds.ResourceObject
+	if err := retryOnConflict(retry.DefaultBackoff, func() error {
+		resources, err := r.Storage.GetResources()
+		if err != nil {
+			logger.WithError(err).Errorf("could not get resources")
+			return &ResourceNotFound{strings.Join(types, ",")}
+		}
+		held := r.heldResources(resources.Items)
+
+		typeCounts := map[string]int{}
+		free := map[string][]crds.ResourceObject{}
+		for _, res := range resources.Items {
+			typeCounts[res.Spec.Type]++
+			// Resources set aside for reservations cannot be acquired.
+			if state != res.Status.State || res.Status.Owner != "" || held.Has(res.Name) {
+				continue
+			}
+			free[res.Spec.Type] = append(free[res.Spec.Type], res)
+		}
+
+		var chosen []crds.ResourceObject
+		var missing, unknown []string
+		for _, rType := range types {
+			rank, new := r.requestMgr.GetRank(acquireRequestPriorityKey{rType: rType, state: state}, requestID)
+			// Like Acquire, leave one free resource to every request ahead.
+			ahead := rank - 1
+			if len(free[rType]) >= ahead+counts[rType] {
+				chosen = append(chosen, free[rType][ahead:ahead+counts[rType]]...)
+				continue
+			}
+			missing = append(missing, rType)
+			if new {
+				r.addDynamicResources(rType, typeCounts[rType], ahead+counts[rType]-len(free[rType]))
+			}
+			if typeCounts[rType] == 0 {
+				unknown = append(unknown, rType)
+			}
+		}
+		if len(unknown) > 0 {
+			return &ResourceTypeNotFound{strings.Join(unknown, ",")}
+		}
+		if len(missing) > 0 {
+			return &ResourceNotFound{strings.Join(missing, ",")}
+		}
+
+		var acquired []*crds.ResourceObject
+		for idx := range chosen {
+			res := chosen[idx]
+			res.Status.Owner = owner
+			res.Status.State = dest
+			updatedRes, err := r.Storage.UpdateResource(&res)
+			if err != nil {
+				logger.WithError(err).Warningf("Failed to update resource %s, releasing the acquired resources.", res.Name)
+				r.undoAcquire(acquired, state, owner)
+				return err
+			}
+			acquired = append(acquired, updatedRes)
+		}
+		// Deleting this request since it has been fulfilled
+		if requestID != "" {
+			for _, rType := range types {
+				r.requestMgr.Delete(acquireRequestPriorityKey{rType: rType, state: state}, requestID)
+			}
+		}
+		logger.Debugf("Successfully acquired %d resources.", len(acquired))
+		returnRes = acquired
+		return nil
+	}); err != nil {
+		switch err.(type) {
+		case *ResourceNotFound:
+			// Running out of resources is a normal part of operation.
+		default:
+			logger.WithError(err).Error("AcquireSet failed")
+		}
+		return nil, err
+	}
+	return returnRes, nil
+}
+
+// addDynamicResources adds up to count new resources of a dynamic type, as
+// long as the type has less than its maximum number of resources.
+func (r *Ranch) addDynamicResources(rType string, typeCount, count int) {
+	lifeCycle, err := r.Storage.GetDynamicResourceLifeCycle(rType)
+	// Assuming error means no associated dynamic resource.
+	if err != nil {
+		logrus.WithError(err).Debug("Failed listing DRLC")
+		return
+	}
+	for i := 0; i < count && typeCount < lifeCycle.Spec.MaxCount; i++ {
+		res := newResourceFromNewDynamicResourceLifeCycle(r.Storage.generateName(), lifeCycle, r.now())
+		if err := r.Storage.AddResource(res); err != nil {
+			logrus.WithError(err).Warningf("unable to add a new resource of type %s", rType)
+			return
+		}
+		typeCount++
+		logrus.Infof("Added dynamic resource %s of type %s", res.Name, res.Spec.Type)
+	}
+}
+
+// undoAcquire gives acquired resources back in their original state, without
+// touching their expiration like a release does.
+func (r *Ranch) undoAcquire(resources []*crds.ResourceObject, state, owner string) {
+	for _, acquired := range resources {
+		name := acquired.Name
+		err := retryOnConflict(retry.DefaultBackoff, func() error {
+			res, err := r.Storage.GetResource(name)
+			if err != nil {
+				return err
+			}
+			if res.Status.Owner != owner {
+				return &OwnerNotMatch{request: owner, owner: res.Status.Owner}
+			}
+			res.Status.Owner = ""
+			res.Status.State = state
+			_, err = r.Storage.UpdateResource(res)
+			return err
+		})
+		if err != nil {
+			logrus.WithError(err).Warningf("unable to give back resource %s", name)
+		}
+	}
+}
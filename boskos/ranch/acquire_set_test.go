@@ -0,0 +1,212 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ranch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"k8s.io/test-infra/boskos/common"
+	"k8s.io/test-infra/boskos/crds"
+)
+
+func TestAcquireSet(t *testing.T) {
+	var testcases = []struct {
+		name      string
+		resources []runtime.Object
+		requests  []common.ResourceRequest
+		expected  []string
+		expectErr error
+	}{
+		{
+			name: "all types are acquired",
+			resources: []runtime.Object{
+				newResource("project", "gcp", common.Free, "", startTime),
+				newResource("account-1", "aws", common.Free, "", startTime),
+				newResource("account-2", "aws", common.Free, "", startTime),
+				newResource("account-3", "aws", common.Free, "", startTime),
+			},
+			requests: []common.ResourceRequest{{Type: "gcp", Count: 1}, {Type: "aws", Count: 2}},
+			expected: []string{"account-1", "account-2", "project"},
+		},
+		{
+			name: "requests for the same type add up",
+			resources: []runtime.Object{
+				newResource("account-1", "aws", common.Free, "", startTime),
+				newResource("account-2", "aws", common.Free, "", startTime),
+			},
+			requests: []common.ResourceRequest{{Type: "aws", Count: 1}, {Type: "aws", Count: 1}},
+			expected: []string{"account-1", "account-2"},
+		},
+		{
+			name: "nothing is acquired if one type is short",
+			resources: []runtime.Object{
+				newResource("project", "gcp", common.Free, "", startTime),
+				newResource("account-1", "aws", common.Free, "", startTime),
+				newResource("account-2", "aws", common.Busy, "other", startTime),
+			},
+			requests:  []common.ResourceRequest{{Type: "gcp", Count: 1}, {Type: "aws", Count: 2}},
+			expectErr: &ResourceNotFound{"aws"},
+		},
+		{
+			name: "unknown type",
+			resources: []runtime.Object{
+				newResource("project", "gcp", common.Free, "", startTime),
+			},
+			requests:  []common.ResourceRequest{{Type: "gcp", Count: 1}, {Type: "azure", Count: 1}},
+			expectErr: &ResourceTypeNotFound{"azure"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := makeTestRanch(tc.resources)
+			acquired, err := r.AcquireSet(tc.requests, common.Free, common.Busy, "user", "")
+			if !AreErrorsEqual(err, tc.expectErr) {
+				t.Fatalf("got error %v, expected error %v", err, tc.expectErr)
+			}
+			var names []string
+			for _, res := range acquired {
+				names = append(names, res.Name)
+				if res.Status.Owner != "user" || res.Status.State != common.Busy {
+					t.Errorf("expected %s to be busy and owned by user, got %s and %s", res.Name, res.Status.State, res.Status.Owner)
+				}
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("expected to acquire %v, got %v", tc.expected, names)
+			}
+			if err == nil {
+				return
+			}
+			resources, err := r.Storage.GetResources()
+			if err != nil {
+				t.Fatalf("failed to get resources: %v", err)
+			}
+			for _, res := range resources.Items {
+				if res.Status.Owner == "user" {
+					t.Errorf("expected no resource to be acquired, got %s", res.Name)
+				}
+			}
+		})
+	}
+
+	r := makeTestRanch([]runtime.Object{newResource("project", "gcp", common.Free, "", startTime)})
+	if _, err := r.AcquireSet([]common.ResourceRequest{{Type: "gcp", Count: 0}}, common.Free, common.Busy, "user", ""); err == nil {
+		t.Error("expected an error for a count that is not positive")
+	}
+}
+
+func TestAcquireSetPriority(t *testing.T) {
+	r := makeTestRanch([]runtime.Object{
+		newResource("project", "gcp", common.Free, "", startTime),
+		newResource("account", "aws", common.Busy, "other", startTime),
+	})
+	requests := []common.ResourceRequest{{Type: "gcp", Count: 1}, {Type: "aws", Count: 1}}
+
+	// The set request queues up for both types, and holds its place for the
+	// project while it waits for the account.
+	if _, err := r.AcquireSet(requests, common.Free, common.Busy, "set", "request_id_1"); !AreErrorsEqual(err, &ResourceNotFound{"aws"}) {
+		t.Fatalf("expected the set not to be acquired, got %v", err)
+	}
+	if _, err := r.Acquire("gcp", common.Free, common.Busy, "single", "request_id_2"); !AreErrorsEqual(err, &ResourceNotFound{"gcp"}) {
+		t.Errorf("expected the project to be kept for the set, got %v", err)
+	}
+
+	if err := r.Release("account", common.Free, "other"); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	acquired, err := r.AcquireSet(requests, common.Free, common.Busy, "set", "request_id_1")
+	if err != nil {
+		t.Fatalf("expected the set to be acquired, got %v", err)
+	}
+	if len(acquired) != 2 {
+		t.Errorf("expected two resources, got %d", len(acquired))
+	}
+	if _, err := r.AcquireSet(requests, common.Free, common.Busy, "set", "request_id_1"); err == nil {
+		t.Error("expected a fulfilled request to lose its place")
+	}
+	for _, res := range acquired {
+		if err := r.Release(res.Name, common.Free, "set"); err != nil {
+			t.Fatalf("failed to release: %v", err)
+		}
+	}
+	if _, err := r.Acquire("gcp", common.Free, common.Busy, "single", "request_id_2"); err != nil {
+		t.Errorf("expected the next request to get the project, got %v", err)
+	}
+}
+
+func TestAcquireSetCreatesDynamicResources(t *testing.T) {
+	r := makeTestRanch([]runtime.Object{
+		&crds.DRLCObject{ObjectMeta: newResource("t", "", "", "", startTime).ObjectMeta, Spec: crds.DRLCSpec{InitialState: common.Free, MaxCount: 3}},
+	})
+	requests := []common.ResourceRequest{{Type: "t", Count: 2}}
+	if _, err := r.AcquireSet(requests, common.Free, common.Busy, "user", "request_id"); !AreErrorsEqual(err, &ResourceTypeNotFound{"t"}) {
+		t.Fatalf("expected the resources not to exist yet, got %v", err)
+	}
+	acquired, err := r.AcquireSet(requests, common.Free, common.Busy, "user", "request_id")
+	if err != nil {
+		t.Fatalf("expected the created resources to be acquired, got %v", err)
+	}
+	if len(acquired) != 2 {
+		t.Errorf("expected two resources, got %d", len(acquired))
+	}
+}
+
+// laterConflictingClient returns an IsConflict error on the second Update request it receives.
+type laterConflictingClient struct {
+	updates int
+	ctrlruntimeclient.Client
+}
+
+func (lcc *laterConflictingClient) Update(ctx context.Context, obj runtime.Object, opts ...ctrlruntimeclient.UpdateOption) error {
+	lcc.updates++
+	if lcc.updates == 2 {
+		return kerrors.NewConflict(schema.GroupResource{}, "obj", errors.New("conflicting as requested"))
+	}
+	return lcc.Client.Update(ctx, obj, opts...)
+}
+
+func TestAcquireSetGivesBackOnConflict(t *testing.T) {
+	r := makeTestRanch([]runtime.Object{
+		newResource("res-1", "t", common.Free, "", startTime),
+		newResource("res-2", "t", common.Free, "", startTime),
+	})
+	client := &laterConflictingClient{Client: r.Storage.client.(*onceConflictingClient).Client}
+	r.Storage.client = client
+
+	acquired, err := r.AcquireSet([]common.ResourceRequest{{Type: "t", Count: 2}}, common.Free, common.Busy, "user", "")
+	if err != nil {
+		t.Fatalf("expected the set to be acquired after a retry, got %v", err)
+	}
+	if len(acquired) != 2 {
+		t.Errorf("expected two resources, got %d", len(acquired))
+	}
+	// One update for the first resource, the conflict, one update to give
+	// it back and two updates for the retry.
+	if client.updates != 5 {
+		t.Errorf("expected 5 updates, got %d", client.updates)
+	}
+}
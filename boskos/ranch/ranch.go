@@ -37,6 +37,9 @@ import (
 type Ranch struct {
 	Storage    *Storage
 	requestMgr *RequestManager
+	// MaxReservationDuration is how long a reservation holds its place at most.
+	MaxReservationDuration time.Duration
+	reservations           *reservationManager
 	//
 	now func() time.Time
 }
@@ -81,15 +84,35 @@ func (s StateNotMatch) Error() string {
 	return fmt.Sprintf("state mismatch - expected %v, current %v", s.expect, s.current)
 }
 
+// ReservationNotFound will be returned if requested reservation does not exist or expired.
+type ReservationNotFound struct {
+	id string
+}
+
+func (r ReservationNotFound) Error() string {
+	return fmt.Sprintf("reservation %s does not exist or expired", r.id)
+}
+
+// InvalidReservation will be returned if a reservation can never be fulfilled.
+type InvalidReservation struct {
+	reason string
+}
+
+func (i InvalidReservation) Error() string {
+	return fmt.Sprintf("invalid reservation: %s", i.reason)
+}
+
 // NewRanch creates a new Ranch object.
 // In: config - path to resource file
 //     storage - path to where to save/restore the state data
 // Out: A Ranch object, loaded from config/storage, or error
 func NewRanch(config string, s *Storage, ttl time.Duration) (*Ranch, error) {
 	newRanch := &Ranch{
-		Storage:    s,
-		requestMgr: NewRequestManager(ttl),
-		now:        time.Now,
+		Storage:                s,
+		requestMgr:             NewRequestManager(ttl),
+		MaxReservationDuration: DefaultMaxReservationDuration,
+		reservations:           newReservationManager(),
+		now:                    time.Now,
 	}
 	if config != "" {
 		if err := newRanch.SyncConfig(config); err != nil {
@@ -136,6 +159,7 @@ func (r *Ranch) Acquire(rType, state, dest, owner, requestID string) (*crds.Reso
 			return &ResourceNotFound{rType}
 		}
 		logger.Debugf("Considering %d resources.", len(resources.Items))
+		held := r.heldResources(resources.Items)
 
 		// For request priority we need to go over all the list until a matching rank
 		matchingResoucesCount := 0
@@ -147,7 +171,8 @@ func (r *Ranch) Acquire(rType, state, dest, owner, requestID string) (*crds.Reso
 			}
 			typeCount++
 
-			if state != res.Status.State || res.Status.Owner != "" {
+			// Resources set aside for reservations cannot be acquired.
+			if state != res.Status.State || res.Status.Owner != "" || held.Has(res.Name) {
 				continue
 			}
 			matchingResoucesCount++
@@ -175,20 +200,7 @@ func (r *Ranch) Acquire(rType, state, dest, owner, requestID string) (*crds.Reso
 
 		if new {
 			logger.Debug("Checking for associated dynamic resource type...")
-			lifeCycle, err := r.Storage.GetDynamicResourceLifeCycle(rType)
-			// Assuming error means no associated dynamic resource.
-			if err == nil {
-				if typeCount < lifeCycle.Spec.MaxCount {
-					logger.Debug("Adding new dynamic resources...")
-					res := newResourceFromNewDynamicResourceLifeCycle(r.Storage.generateName(), lifeCycle, r.now())
-					if err := r.Storage.AddResource(res); err != nil {
-						logger.WithError(err).Warningf("unable to add a new resource of type %s", rType)
-					}
-					logger.Infof("Added dynamic resource %s of type %s", res.Name, res.Spec.Type)
-				}
-			} else {
-				logrus.WithError(err).Debug("Failed listing DRLC")
-			}
+			r.addDynamicResources(rType, typeCount, 1)
 		}
 
 		if typeCount > 0 {
@@ -234,10 +246,11 @@ func (r *Ranch) AcquireByState(state, dest, owner string, names []string) ([]*cr
 		}
 
 		var resources []*crds.ResourceObject
+		held := r.heldResources(allResources.Items)
 
 		for idx := range allResources.Items {
 			res := allResources.Items[idx]
-			if state != res.Status.State || res.Status.Owner != "" || !rNames.Has(res.Name) {
+			if state != res.Status.State || res.Status.Owner != "" || !rNames.Has(res.Name) || held.Has(res.Name) {
 				continue
 			}
 
@@ -128,6 +128,14 @@ func AreErrorsEqual(got error, expect error) bool {
 			}
 		}
 		return false
+	case *ReservationNotFound:
+		if o, ok := expect.(*ReservationNotFound); ok {
+			return o.id == got.(*ReservationNotFound).id
+		}
+		return false
+	case *InvalidReservation:
+		_, ok := expect.(*InvalidReservation)
+		return ok
 	default:
 		return false
 	}
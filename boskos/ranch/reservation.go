@@ -0,0 +1,310 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ranch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/retry"
+
+	"k8s.io/test-infra/boskos/common"
+	"k8s.io/test-infra/boskos/crds"
+)
+
+// DefaultMaxReservationDuration is how long a reservation holds its place
+// at most, unless configured otherwise.
+const DefaultMaxReservationDuration = time.Hour
+
+// reservationManager keeps track of the reservations and of the resources
+// set aside for them. Like the request queues, reservations only live in
+// memory.
+type reservationManager struct {
+	lock sync.Mutex
+	// reservations are ordered by age, oldest first.
+	reservations []*common.Reservation
+}
+
+func newReservationManager() *reservationManager {
+	return &reservationManager{}
+}
+
+func (m *reservationManager) add(reservation *common.Reservation) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.reservations = append(m.reservations, reservation)
+}
+
+func (m *reservationManager) delete(id string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for i, reservation := range m.reservations {
+		if reservation.ID == id {
+			m.reservations = append(m.reservations[:i], m.reservations[i+1:]...)
+			return
+		}
+	}
+}
+
+// get returns a copy of the reservation.
+func (m *reservationManager) get(id string) (common.Reservation, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, reservation := range m.reservations {
+		if reservation.ID == id {
+			copied := *reservation
+			copied.Resources = append([]string(nil), reservation.Resources...)
+			return copied, true
+		}
+	}
+	return common.Reservation{}, false
+}
+
+func isFreeFor(res crds.ResourceObject, reservation *common.Reservation) bool {
+	return res.Spec.Type == reservation.Type && res.Status.State == reservation.State && res.Status.Owner == ""
+}
+
+// setAside updates the resources set aside for the reservations and returns
+// the names of all of them. Resources that are no longer free are dropped
+// from their reservation. Then the free resources are set aside for the
+// reservations that are not ready yet, oldest first. Expired reservations
+// are deleted.
+func (m *reservationManager) setAside(resources []crds.ResourceObject, now time.Time) sets.String {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	byName := make(map[string]crds.ResourceObject, len(resources))
+	for _, res := range resources {
+		byName[res.Name] = res
+	}
+
+	held := sets.NewString()
+	var active []*common.Reservation
+	for _, reservation := range m.reservations {
+		if now.After(reservation.Expiration) {
+			logrus.WithField("reservation", reservation.ID).Info("Reservation expired.")
+			continue
+		}
+		active = append(active, reservation)
+		var kept []string
+		for _, name := range reservation.Resources {
+			if res, exists := byName[name]; exists && isFreeFor(res, reservation) && !held.Has(name) {
+				kept = append(kept, name)
+				held.Insert(name)
+			}
+		}
+		reservation.Resources = kept
+	}
+	m.reservations = active
+
+	for _, reservation := range m.reservations {
+		for _, res := range resources {
+			if reservation.Ready() {
+				break
+			}
+			if isFreeFor(res, reservation) && !held.Has(res.Name) {
+				reservation.Resources = append(reservation.Resources, res.Name)
+				held.Insert(res.Name)
+			}
+		}
+	}
+	return held
+}
+
+// heldResources returns the names of the resources set aside for
+// reservations, which nobody else can acquire.
+func (r *Ranch) heldResources(resources []crds.ResourceObject) sets.String {
+	return r.reservations.setAside(resources, r.now())
+}
+
+// Reserve holds a place for a number of resources of a type in a state. As
+// they become free, the ranch sets resources aside for the reservation until
+// it can be claimed. The reservation expires after the duration, which is
+// capped by the MaxReservationDuration of the ranch.
+// In: rtype - type of the reserved resources
+//     state - state of the reserved resources
+//     owner - owner of the reservation
+//     count - number of reserved resources
+//     duration - how long the reservation holds its place
+// Out: The reservation on success, or
+//      InvalidReservation error if the reservation can never be fulfilled, or
+//      ResourceTypeNotFound error if the type of resource does not exist.
+func (r *Ranch) Reserve(rType, state, owner string, count int, duration time.Duration) (*common.Reservation, error) {
+	if count < 1 {
+		return nil, &InvalidReservation{fmt.Sprintf("count must be positive, not %d", count)}
+	}
+	if duration <= 0 || duration > r.MaxReservationDuration {
+		duration = r.MaxReservationDuration
+	}
+
+	resources, err := r.Storage.GetResources()
+	if err != nil {
+		logrus.WithError(err).Error("could not get resources")
+		return nil, err
+	}
+	var typeCount int
+	for _, res := range resources.Items {
+		if res.Spec.Type == rType {
+			typeCount++
+		}
+	}
+	lifeCycle, err := r.Storage.GetDynamicResourceLifeCycle(rType)
+	// Assuming error means no associated dynamic resource.
+	isDynamic := err == nil
+	capacity := typeCount
+	if isDynamic && lifeCycle.Spec.MaxCount > capacity {
+		capacity = lifeCycle.Spec.MaxCount
+	}
+	if capacity == 0 {
+		return nil, &ResourceTypeNotFound{rType}
+	}
+	if count > capacity {
+		return nil, &InvalidReservation{fmt.Sprintf("only %d resources of type %s exist, cannot reserve %d", capacity, rType, count)}
+	}
+
+	reservation := &common.Reservation{
+		ID:         uuid.New().String(),
+		Type:       rType,
+		State:      state,
+		Owner:      owner,
+		Count:      count,
+		Expiration: r.now().Add(duration),
+	}
+	r.reservations.add(reservation)
+	r.heldResources(resources.Items)
+	logrus.WithFields(logrus.Fields{
+		"reservation": reservation.ID,
+		"type":        rType,
+		"state":       state,
+		"owner":       owner,
+		"count":       count,
+	}).Info("Added reservation.")
+
+	reserved, _ := r.reservations.get(reservation.ID)
+	if isDynamic {
+		missing := count - len(reserved.Resources)
+		for i := 0; i < missing && typeCount < lifeCycle.Spec.MaxCount; i++ {
+			res := newResourceFromNewDynamicResourceLifeCycle(r.Storage.generateName(), lifeCycle, r.now())
+			if err := r.Storage.AddResource(res); err != nil {
+				logrus.WithError(err).Warningf("unable to add a new resource of type %s", rType)
+				break
+			}
+			typeCount++
+			logrus.Infof("Added dynamic resource %s of type %s", res.Name, res.Spec.Type)
+		}
+	}
+	return &reserved, nil
+}
+
+// GetReservation returns the current state of a reservation.
+// In: id - ID of the reservation
+//     owner - owner of the reservation
+// Out: The reservation on success, or
+//      ReservationNotFound error if the reservation does not exist or expired, or
+//      OwnerNotMatch error if owner does not match the owner of the reservation.
+func (r *Ranch) GetReservation(id, owner string) (*common.Reservation, error) {
+	resources, err := r.Storage.GetResources()
+	if err != nil {
+		logrus.WithError(err).Error("could not get resources")
+		return nil, err
+	}
+	r.heldResources(resources.Items)
+	reservation, exists := r.reservations.get(id)
+	if !exists {
+		return nil, &ReservationNotFound{id}
+	}
+	if reservation.Owner != owner {
+		return nil, &OwnerNotMatch{request: owner, owner: reservation.Owner}
+	}
+	return &reservation, nil
+}
+
+// ClaimReservation acquires all resources of a ready reservation at once and
+// moves them to the destination state. Either all resources are acquired or
+// none of them.
+// In: id - ID of the reservation
+//     owner - owner of the reservation
+//     dest - destination state of the resources
+// Out: The acquired resources on success, or
+//      ReservationNotFound error if the reservation does not exist or expired, or
+//      OwnerNotMatch error if owner does not match the owner of the reservation, or
+//      ResourceNotFound error if the reservation is not ready yet.
+func (r *Ranch) ClaimReservation(id, owner, dest string) ([]*crds.ResourceObject, error) {
+	reservation, err := r.GetReservation(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	if !reservation.Ready() {
+		return nil, &ResourceNotFound{reservation.Type}
+	}
+
+	var claimed []*crds.ResourceObject
+	for _, name := range reservation.Resources {
+		var updated *crds.ResourceObject
+		err := retryOnConflict(retry.DefaultBackoff, func() error {
+			res, err := r.Storage.GetResource(name)
+			if err != nil {
+				return &ResourceNotFound{name}
+			}
+			if !isFreeFor(*res, reservation) {
+				return &ResourceNotFound{name}
+			}
+			res.Status.Owner = owner
+			res.Status.State = dest
+			updated, err = r.Storage.UpdateResource(res)
+			return err
+		})
+		if err != nil {
+			logrus.WithError(err).WithField("reservation", id).Errorf("Failed to claim %s, releasing the claimed resources.", name)
+			for _, res := range claimed {
+				if err := r.Release(res.Name, reservation.State, owner); err != nil {
+					logrus.WithError(err).Warningf("unable to release resource %s", res.Name)
+				}
+			}
+			return nil, err
+		}
+		claimed = append(claimed, updated)
+	}
+
+	r.reservations.delete(id)
+	logrus.WithField("reservation", id).Infof("Claimed %d resources.", len(claimed))
+	return claimed, nil
+}
+
+// CancelReservation deletes a reservation, which frees the resources set
+// aside for it.
+// In: id - ID of the reservation
+//     owner - owner of the reservation
+// Out: nil on success, or
+//      ReservationNotFound error if the reservation does not exist or expired, or
+//      OwnerNotMatch error if owner does not match the owner of the reservation.
+func (r *Ranch) CancelReservation(id, owner string) error {
+	reservation, exists := r.reservations.get(id)
+	if !exists {
+		return &ReservationNotFound{id}
+	}
+	if reservation.Owner != owner {
+		return &OwnerNotMatch{request: owner, owner: reservation.Owner}
+	}
+	r.reservations.delete(id)
+	logrus.WithField("reservation", id).Info("Cancelled reservation.")
+	return nil
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ranch
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"k8s.io/test-infra/boskos/common"
+	"k8s.io/test-infra/boskos/crds"
+)
+
+func TestReserveValidation(t *testing.T) {
+	var testcases = []struct {
+		name      string
+		resources []runtime.Object
+		rtype     string
+		count     int
+		expectErr error
+	}{
+		{
+			name:      "unknown type",
+			rtype:     "t",
+			count:     1,
+			expectErr: &ResourceTypeNotFound{"t"},
+		},
+		{
+			name:      "count must be positive",
+			resources: []runtime.Object{newResource("res", "t", common.Free, "", startTime)},
+			rtype:     "t",
+			expectErr: &InvalidReservation{},
+		},
+		{
+			name:      "more resources than exist",
+			resources: []runtime.Object{newResource("res", "t", common.Free, "", startTime)},
+			rtype:     "t",
+			count:     2,
+			expectErr: &InvalidReservation{},
+		},
+		{
+			name: "more resources than are free",
+			resources: []runtime.Object{
+				newResource("res-1", "t", common.Free, "", startTime),
+				newResource("res-2", "t", common.Busy, "other", startTime),
+			},
+			rtype: "t",
+			count: 2,
+		},
+		{
+			name: "dynamic resources that can still be created",
+			resources: []runtime.Object{
+				newResource("res", "t", common.Free, "", startTime),
+				&crds.DRLCObject{ObjectMeta: newResource("t", "", "", "", startTime).ObjectMeta, Spec: crds.DRLCSpec{InitialState: common.Dirty, MaxCount: 3}},
+			},
+			rtype: "t",
+			count: 3,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := makeTestRanch(tc.resources)
+			reservation, err := r.Reserve(tc.rtype, common.Free, "user", tc.count, time.Minute)
+			if !AreErrorsEqual(err, tc.expectErr) {
+				t.Fatalf("got error %v, expected error %v", err, tc.expectErr)
+			}
+			if err == nil && reservation.Count != tc.count {
+				t.Errorf("expected a reservation of %d resources, got %d", tc.count, reservation.Count)
+			}
+		})
+	}
+}
+
+func TestReserveCreatesDynamicResources(t *testing.T) {
+	r := makeTestRanch([]runtime.Object{
+		newResource("res", "t", common.Free, "", startTime),
+		&crds.DRLCObject{ObjectMeta: newResource("t", "", "", "", startTime).ObjectMeta, Spec: crds.DRLCSpec{InitialState: common.Dirty, MaxCount: 3}},
+	})
+	if _, err := r.Reserve("t", common.Free, "user", 3, time.Minute); err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+	resources, err := r.Storage.GetResources()
+	if err != nil {
+		t.Fatalf("failed to get resources: %v", err)
+	}
+	if len(resources.Items) != 3 {
+		t.Errorf("expected the missing dynamic resources to be created, got %d resources", len(resources.Items))
+	}
+}
+
+func TestReservation(t *testing.T) {
+	r := makeTestRanch([]runtime.Object{
+		newResource("res-1", "t", common.Free, "", startTime),
+		newResource("res-2", "t", common.Busy, "other", startTime),
+		newResource("res-3", "t", common.Busy, "other", startTime),
+		newResource("unrelated", "u", common.Free, "", startTime),
+	})
+
+	reservation, err := r.Reserve("t", common.Free, "big", 2, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+	if expected := []string{"res-1"}; !reflect.DeepEqual(reservation.Resources, expected) {
+		t.Errorf("expected %v to be set aside, got %v", expected, reservation.Resources)
+	}
+
+	// The free resource is held for the reservation.
+	if _, err := r.Acquire("t", common.Free, common.Busy, "small", ""); !AreErrorsEqual(err, &ResourceNotFound{"t"}) {
+		t.Errorf("expected reserved resources not to be acquired, got %v", err)
+	}
+	if _, err := r.AcquireByState(common.Free, common.Busy, "small", []string{"res-1"}); !AreErrorsEqual(err, &ResourceNotFound{common.Free}) {
+		t.Errorf("expected reserved resources not to be acquired by state, got %v", err)
+	}
+	if _, err := r.Acquire("u", common.Free, common.Busy, "small", ""); err != nil {
+		t.Errorf("expected resources of other types to be acquired, got %v", err)
+	}
+	if _, err := r.ClaimReservation(reservation.ID, "big", common.Busy); !AreErrorsEqual(err, &ResourceNotFound{"t"}) {
+		t.Errorf("expected claiming a reservation that is not ready to fail, got %v", err)
+	}
+	if _, err := r.GetReservation(reservation.ID, "small"); !AreErrorsEqual(err, &OwnerNotMatch{request: "small", owner: "big"}) {
+		t.Errorf("expected others not to get the reservation, got %v", err)
+	}
+
+	// The next resource that is freed completes the reservation.
+	if err := r.Release("res-2", common.Free, "other"); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	reservation, err = r.GetReservation(reservation.ID, "big")
+	if err != nil {
+		t.Fatalf("failed to get reservation: %v", err)
+	}
+	if !reservation.Ready() {
+		t.Errorf("expected reservation to be ready, got %v", reservation.Resources)
+	}
+
+	claimed, err := r.ClaimReservation(reservation.ID, "big", common.Busy)
+	if err != nil {
+		t.Fatalf("failed to claim reservation: %v", err)
+	}
+	var names []string
+	for _, res := range claimed {
+		names = append(names, res.Name)
+		if res.Status.Owner != "big" || res.Status.State != common.Busy {
+			t.Errorf("expected %s to be busy and owned by big, got %s and %s", res.Name, res.Status.State, res.Status.Owner)
+		}
+	}
+	sort.Strings(names)
+	if expected := []string{"res-1", "res-2"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected to claim %v, got %v", expected, names)
+	}
+	if _, err := r.GetReservation(reservation.ID, "big"); !AreErrorsEqual(err, &ReservationNotFound{reservation.ID}) {
+		t.Errorf("expected claimed reservation to be gone, got %v", err)
+	}
+}
+
+func TestReservationOrder(t *testing.T) {
+	r := makeTestRanch([]runtime.Object{
+		newResource("res-1", "t", common.Busy, "other", startTime),
+		newResource("res-2", "t", common.Busy, "other", startTime),
+	})
+	older, err := r.Reserve("t", common.Free, "older", 2, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+	newer, err := r.Reserve("t", common.Free, "newer", 1, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+
+	for _, name := range []string{"res-1", "res-2"} {
+		if err := r.Release(name, common.Free, "other"); err != nil {
+			t.Fatalf("failed to release: %v", err)
+		}
+	}
+	if older, err = r.GetReservation(older.ID, "older"); err != nil || !older.Ready() {
+		t.Errorf("expected the older reservation to be ready, got %v: %v", older, err)
+	}
+	if newer, err = r.GetReservation(newer.ID, "newer"); err != nil || len(newer.Resources) != 0 {
+		t.Errorf("expected the newer reservation to wait, got %v: %v", newer, err)
+	}
+
+	if err := r.CancelReservation(older.ID, "newer"); !AreErrorsEqual(err, &OwnerNotMatch{request: "newer", owner: "older"}) {
+		t.Errorf("expected others not to cancel the reservation, got %v", err)
+	}
+	if err := r.CancelReservation(older.ID, "older"); err != nil {
+		t.Fatalf("failed to cancel reservation: %v", err)
+	}
+	if newer, err = r.GetReservation(newer.ID, "newer"); err != nil || !newer.Ready() {
+		t.Errorf("expected the newer reservation to be ready once the older one is cancelled, got %v: %v", newer, err)
+	}
+}
+
+func TestReservationExpires(t *testing.T) {
+	r := makeTestRanch([]runtime.Object{
+		newResource("res", "t", common.Free, "", startTime),
+	})
+	r.MaxReservationDuration = time.Minute
+	reservation, err := r.Reserve("t", common.Free, "user", 1, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+	if expected := fakeNow.Add(time.Minute); !reservation.Expiration.Equal(expected) {
+		t.Errorf("expected the duration to be capped to expire at %v, got %v", expected, reservation.Expiration)
+	}
+
+	r.now = func() time.Time { return fakeNow.Add(2 * time.Minute) }
+	if _, err := r.GetReservation(reservation.ID, "user"); !AreErrorsEqual(err, &ReservationNotFound{reservation.ID}) {
+		t.Errorf("expected reservation to expire, got %v", err)
+	}
+	if _, err := r.Acquire("t", common.Free, common.Busy, "other", ""); err != nil {
+		t.Errorf("expected resources of expired reservation to be acquired, got %v", err)
+	}
+}
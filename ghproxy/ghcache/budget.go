@@ -0,0 +1,279 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ghcache
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/test-infra/ghproxy/ghmetrics"
+)
+
+// ClientHeader identifies the client of a request. Clients that do not set it
+// are identified by their user agent without version.
+const ClientHeader = "X-Ghproxy-Client"
+
+// BudgetConfig configures how the clients of ghproxy share the API rate
+// limit of every token.
+type BudgetConfig struct {
+	// Clients configures the budget of clients by name.
+	Clients map[string]ClientBudget `json:"clients,omitempty"`
+	// Default configures the budget of clients that are not listed.
+	Default ClientBudget `json:"default,omitempty"`
+}
+
+// ClientBudget configures the budget of a client.
+type ClientBudget struct {
+	// Share is the fraction of the remaining rate limit of a token that the
+	// client may use until the limit resets. Every request the client makes
+	// counts against what remained before it, so its allowance shrinks as
+	// other clients use the token. Zero means no limit.
+	Share float64 `json:"share,omitempty"`
+	// MinRemaining is the fraction of the rate limit of a token that must
+	// remain for the client to be served. Low priority clients should have
+	// a higher MinRemaining, so that they are throttled first as the budget
+	// shrinks.
+	MinRemaining float64 `json:"min_remaining,omitempty"`
+	// MaxWait is how long a throttled request is queued until the rate limit
+	// resets. Requests that would have to wait longer are rejected with 429.
+	// Zero rejects throttled requests right away.
+	MaxWait *metav1.Duration `json:"max_wait,omitempty"`
+}
+
+func (b ClientBudget) validate() error {
+	if b.Share < 0 || b.Share > 1 {
+		return fmt.Errorf("share must be between 0 and 1, not %v", b.Share)
+	}
+	if b.MinRemaining < 0 || b.MinRemaining > 1 {
+		return fmt.Errorf("min_remaining must be between 0 and 1, not %v", b.MinRemaining)
+	}
+	if b.MaxWait != nil && b.MaxWait.Duration < 0 {
+		return fmt.Errorf("max_wait must not be negative, not %v", b.MaxWait.Duration)
+	}
+	return nil
+}
+
+// Validate returns an error if a budget is out of range.
+func (c *BudgetConfig) Validate() error {
+	if err := c.Default.validate(); err != nil {
+		return fmt.Errorf("default: %v", err)
+	}
+	for name, budget := range c.Clients {
+		if err := budget.validate(); err != nil {
+			return fmt.Errorf("client %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (c *BudgetConfig) budgetFor(client string) ClientBudget {
+	if budget, ok := c.Clients[client]; ok {
+		return budget
+	}
+	return c.Default
+}
+
+// LoadBudgetConfig loads and validates a budget config file.
+func LoadBudgetConfig(path string) (*BudgetConfig, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read budget config: %v", err)
+	}
+	config := &BudgetConfig{}
+	if err := yaml.UnmarshalStrict(raw, config); err != nil {
+		return nil, fmt.Errorf("failed to parse budget config: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid budget config: %v", err)
+	}
+	return config, nil
+}
+
+// clientName identifies the client of a request.
+func clientName(req *http.Request) string {
+	if client := req.Header.Get(ClientHeader); client != "" {
+		return client
+	}
+	userAgent := req.Header.Get("User-Agent")
+	if userAgent == "" {
+		return "unknown"
+	}
+	return strings.SplitN(userAgent, "/", 2)[0]
+}
+
+// tokenBudget tracks the rate limit of a token until it resets.
+type tokenBudget struct {
+	limit     int
+	remaining int
+	reset     time.Time
+	// used counts the requests of every client that cost a token since the
+	// rate limit was last reset.
+	used map[string]int
+}
+
+// budgetingTransport shares the rate limit of every token between clients as
+// configured. Requests of a client that exceeds its budget are queued until
+// the rate limit resets or rejected with 429.
+type budgetingTransport struct {
+	config   *BudgetConfig
+	delegate http.RoundTripper
+
+	lock   sync.Mutex
+	tokens map[string]*tokenBudget
+
+	// For testing
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+}
+
+func newBudgetingTransport(config *BudgetConfig, delegate http.RoundTripper) *budgetingTransport {
+	return &budgetingTransport{
+		config:   config,
+		delegate: delegate,
+		tokens:   map[string]*tokenBudget{},
+		now:      time.Now,
+		after:    time.After,
+	}
+}
+
+func (b *budgetingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// GraphQL requests are charged by complexity against a separate limit,
+	// so only the REST API is budgeted.
+	if isGraphQL(req) {
+		return b.delegate.RoundTrip(req)
+	}
+	tokenHash := requestTokenHash(req)
+	client := clientName(req)
+	logger := logrus.WithFields(logrus.Fields{"client": client, "cache-key": req.URL.String()})
+
+	if wait, throttled := b.throttle(tokenHash, client); throttled {
+		maxWait := b.config.budgetFor(client).MaxWait
+		if maxWait == nil || wait > maxWait.Duration {
+			logger.WithField("retry-after", wait).Info("Rejecting request of client that exceeds its budget.")
+			ghmetrics.CollectClientThrottlingMetrics(tokenHash, client, "rejected")
+			return budgetExceededResponse(req, client, wait), nil
+		}
+		logger.WithField("wait", wait).Debug("Queueing request of client that exceeds its budget.")
+		ghmetrics.CollectClientThrottlingMetrics(tokenHash, client, "queued")
+		select {
+		case <-b.after(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	resp, err := b.delegate.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b.record(tokenHash, client, resp)
+	return resp, nil
+}
+
+// throttle determines whether a request of the client must be held back and
+// how long until the rate limit of the token resets.
+func (b *budgetingTransport) throttle(tokenHash, client string) (time.Duration, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	token, ok := b.tokens[tokenHash]
+	if !ok || token.limit == 0 {
+		// Nothing is known about the token until the first response.
+		return 0, false
+	}
+	wait := token.reset.Sub(b.now())
+	if wait <= 0 {
+		// The rate limit has been reset since the last response.
+		return 0, false
+	}
+	budget := b.config.budgetFor(client)
+	used := token.used[client]
+	if budget.Share > 0 && float64(used) >= budget.Share*float64(used+token.remaining) {
+		return wait, true
+	}
+	if budget.MinRemaining > 0 && float64(token.remaining) < budget.MinRemaining*float64(token.limit) {
+		return wait, true
+	}
+	return wait, false
+}
+
+// record updates the rate limit of the token from the response headers and
+// counts the request against the budget of the client if it cost a token.
+func (b *budgetingTransport) record(tokenHash, client string, resp *http.Response) {
+	limit, limitErr := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	remaining, remainingErr := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, resetErr := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if limitErr != nil || remainingErr != nil || resetErr != nil {
+		return
+	}
+	// Conditional requests for unchanged resources are free.
+	free := resp.StatusCode == http.StatusNotModified
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	token, ok := b.tokens[tokenHash]
+	resetTime := time.Unix(reset, 0)
+	if ok && token.reset.Equal(resetTime) {
+		// Responses may arrive out of order, the lowest count is the newest.
+		if remaining > token.remaining {
+			remaining = token.remaining
+		}
+	} else {
+		token = &tokenBudget{used: map[string]int{}}
+		b.tokens[tokenHash] = token
+	}
+	token.limit = limit
+	token.reset = resetTime
+	token.remaining = remaining
+	if !free {
+		token.used[client]++
+		ghmetrics.CollectClientTokenUsageMetrics(tokenHash, client)
+	}
+}
+
+// budgetExceededResponse tells a client that it exceeded its budget and when
+// to retry.
+func budgetExceededResponse(req *http.Request, client string, retryAfter time.Duration) *http.Response {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	body := fmt.Sprintf("ghproxy: client %s exceeded its budget of the API rate limit, retry after %d seconds.", client, seconds)
+	header := http.Header{}
+	header.Set("Retry-After", strconv.Itoa(seconds))
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	// Don't store the rejection.
+	header.Set("Cache-Control", "no-store")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests)),
+		StatusCode:    http.StatusTooManyRequests,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewBufferString(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ghcache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// rateLimitDelegate is a fake upstream that counts down the rate limit of a
+// single token with every request that is not conditional.
+type rateLimitDelegate struct {
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+func (d *rateLimitDelegate) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := http.StatusOK
+	if req.Header.Get("If-None-Match") != "" {
+		status = http.StatusNotModified
+	} else {
+		d.remaining--
+	}
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", strconv.Itoa(d.limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(d.remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(d.reset.Unix(), 10))
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       ioutil.NopCloser(bytes.NewBufferString("Response")),
+	}, nil
+}
+
+func budgetRequest(t *testing.T, client string, conditional bool) *http.Request {
+	req, err := http.NewRequest(http.MethodGet, "http://api.github.com/repos/org/repo", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("User-Agent", client+"/v20200101-abcdef")
+	if conditional {
+		req.Header.Set("If-None-Match", "etag")
+	}
+	return req
+}
+
+func TestBudgetingTransport(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	var testCases = []struct {
+		name      string
+		config    BudgetConfig
+		remaining int
+		requests  []string
+		// expected holds the status code of every request.
+		expected []int
+	}{
+		{
+			name:      "clients without a budget are not throttled",
+			remaining: 10,
+			requests:  []string{"hook", "hook", "hook"},
+			expected:  []int{200, 200, 200},
+		},
+		{
+			name: "client is rejected once it used its share of the remaining budget",
+			config: BudgetConfig{Clients: map[string]ClientBudget{
+				"peribolos": {Share: 0.2},
+			}},
+			remaining: 10,
+			requests:  []string{"peribolos", "peribolos", "peribolos", "hook"},
+			expected:  []int{200, 200, 429, 200},
+		},
+		{
+			name: "share shrinks as other clients use the token",
+			config: BudgetConfig{Clients: map[string]ClientBudget{
+				"peribolos": {Share: 0.2},
+			}},
+			remaining: 10,
+			requests:  []string{"peribolos", "hook", "hook", "hook", "hook", "hook", "peribolos"},
+			expected:  []int{200, 200, 200, 200, 200, 200, 429},
+		},
+		{
+			name: "low priority clients are rejected first as the budget shrinks",
+			config: BudgetConfig{Default: ClientBudget{MinRemaining: 0.5}, Clients: map[string]ClientBudget{
+				"hook": {},
+			}},
+			remaining: 5,
+			requests:  []string{"branchprotector", "branchprotector", "hook"},
+			expected:  []int{200, 429, 200},
+		},
+		{
+			name: "throttled client is queued until the reset if it may wait long enough",
+			config: BudgetConfig{Clients: map[string]ClientBudget{
+				"peribolos": {Share: 0.1, MaxWait: &metav1.Duration{Duration: time.Hour}},
+			}},
+			remaining: 10,
+			requests:  []string{"peribolos", "peribolos"},
+			expected:  []int{200, 200},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			delegate := &rateLimitDelegate{limit: 10, remaining: tc.remaining, reset: now.Add(time.Hour)}
+			transport := newBudgetingTransport(&tc.config, delegate)
+			transport.now = func() time.Time { return now }
+			var waited time.Duration
+			transport.after = func(d time.Duration) <-chan time.Time {
+				waited += d
+				c := make(chan time.Time, 1)
+				c <- now.Add(d)
+				return c
+			}
+
+			for i, client := range tc.requests {
+				resp, err := transport.RoundTrip(budgetRequest(t, client, false))
+				if err != nil {
+					t.Fatalf("request %d failed: %v", i, err)
+				}
+				if resp.StatusCode != tc.expected[i] {
+					t.Errorf("expected request %d of %s to get %d, got %d", i, client, tc.expected[i], resp.StatusCode)
+				}
+				if resp.StatusCode == http.StatusTooManyRequests && resp.Header.Get("Retry-After") != "3600" {
+					t.Errorf("expected to retry after the reset, got %q", resp.Header.Get("Retry-After"))
+				}
+			}
+			if tc.config.Clients["peribolos"].MaxWait != nil && waited != time.Hour {
+				t.Errorf("expected to wait for the reset, waited %v", waited)
+			}
+		})
+	}
+}
+
+func TestBudgetingTransportIgnoresFreeRequests(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	delegate := &rateLimitDelegate{limit: 10, remaining: 10, reset: now.Add(time.Hour)}
+	transport := newBudgetingTransport(&BudgetConfig{Default: ClientBudget{Share: 0.1}}, delegate)
+	transport.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		if resp, err := transport.RoundTrip(budgetRequest(t, "peribolos", true)); err != nil || resp.StatusCode != http.StatusNotModified {
+			t.Fatalf("expected conditional request to be served, got %v", err)
+		}
+	}
+	if resp, err := transport.RoundTrip(budgetRequest(t, "peribolos", false)); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the first request that costs a token to be served, got %v", err)
+	}
+	if resp, err := transport.RoundTrip(budgetRequest(t, "peribolos", false)); err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected the client to exceed its budget, got %v", err)
+	}
+
+	// The budget of the client is restored once the rate limit resets.
+	now = now.Add(time.Hour)
+	delegate.remaining = 10
+	delegate.reset = now.Add(time.Hour)
+	if resp, err := transport.RoundTrip(budgetRequest(t, "peribolos", false)); err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the client to be served after the reset, got %v", err)
+	}
+}
+
+func TestClientName(t *testing.T) {
+	req := budgetRequest(t, "hook", false)
+	if name := clientName(req); name != "hook" {
+		t.Errorf("expected client to be identified by user agent, got %q", name)
+	}
+	req.Header.Set(ClientHeader, "tide")
+	if name := clientName(req); name != "tide" {
+		t.Errorf("expected client to be identified by header, got %q", name)
+	}
+}
+
+func TestLoadBudgetConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "budget")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var testCases = []struct {
+		name        string
+		config      string
+		expectedErr bool
+	}{
+		{
+			name: "valid config",
+			config: `default:
+  min_remaining: 0.5
+clients:
+  hook: {}
+  peribolos:
+    share: 0.1
+    max_wait: 5s
+`,
+		},
+		{
+			name:        "share out of range",
+			config:      "default:\n  share: 2\n",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown field",
+			config:      "default:\n  shares: 0.5\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, "budget.yaml")
+			if err := ioutil.WriteFile(path, []byte(tc.config), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			config, err := LoadBudgetConfig(path)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %t, got %v", tc.expectedErr, err)
+			}
+			if err == nil && config.Clients["peribolos"].MaxWait.Duration != 5*time.Second {
+				t.Errorf("expected max wait to be parsed, got %v", config.Clients["peribolos"].MaxWait)
+			}
+		})
+	}
+}
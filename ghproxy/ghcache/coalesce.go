@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"net/http"
 	"net/http/httputil"
+	"strings"
 	"sync"
 
 	"github.com/sirupsen/logrus"
@@ -35,6 +36,8 @@ type requestCoalescer struct {
 	keys map[string]*responseWaiter
 
 	delegate http.RoundTripper
+	// budgeted is set when clients have budgets of the rate limit of tokens.
+	budgeted bool
 }
 
 type responseWaiter struct {
@@ -58,9 +61,17 @@ func (r *requestCoalescer) RoundTrip(req *http.Request) (*http.Response, error)
 		return r.delegate.RoundTrip(req)
 	}
 
+	key := req.URL.String()
+	if r.budgeted {
+		// Budgets throttle requests by token and client, so requests are only
+		// coalesced with those of the same token and client to not share a
+		// throttled response.
+		req = withTokenHash(req)
+		key = strings.Join([]string{requestTokenHash(req), clientName(req), key}, " ")
+	}
+
 	var cacheMode = ModeError
 	resp, err := func() (*http.Response, error) {
-		key := req.URL.String()
 		r.Lock()
 		waiter, ok := r.keys[key]
 		if ok {
@@ -89,7 +100,7 @@ func (r *requestCoalescer) RoundTrip(req *http.Request) (*http.Response, error)
 			}
 			resp, err := http.ReadResponse(bufio.NewReader(bytes.NewBuffer(waiter.resp)), nil)
 			if err != nil {
-				logrus.WithField("cache-key", key).WithError(err).Error("Error loading response.")
+				logrus.WithField("cache-key", req.URL.String()).WithError(err).Error("Error loading response.")
 				return nil, err
 			}
 
@@ -122,7 +133,7 @@ func (r *requestCoalescer) RoundTrip(req *http.Request) (*http.Response, error)
 		waiter.L.Unlock()
 
 		if err != nil {
-			logrus.WithField("cache-key", key).WithError(err).Warn("Error from cache transport layer.")
+			logrus.WithField("cache-key", req.URL.String()).WithError(err).Warn("Error from cache transport layer.")
 			return nil, err
 		}
 		cacheMode = cacheResponseMode(resp.Header)
@@ -109,6 +109,92 @@ func TestRoundTrip(t *testing.T) {
 	}
 }
 
+func TestRoundTripCoalescesTokens(t *testing.T) {
+	// Check that concurrent requests only share a response with requests of
+	// the same token when clients have budgets.
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		budgeted bool
+
+		expectedHits int
+	}{
+		{
+			name:         "tokens share responses without budgets",
+			expectedHits: 1,
+		},
+		{
+			name:         "tokens don't share responses with budgets",
+			budgeted:     true,
+			expectedHits: 2,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			delegate := &testDelegate{
+				hits:            make(map[string]int),
+				beginResponding: sync.NewCond(&sync.Mutex{}),
+			}
+			coalesce := &requestCoalescer{
+				keys:     make(map[string]*responseWaiter),
+				delegate: delegate,
+				budgeted: tc.budgeted,
+			}
+			wg := sync.WaitGroup{}
+			for _, token := range []string{"token one", "token two"} {
+				for i := 0; i < 5; i++ {
+					wg.Add(1)
+					go func(token string) {
+						defer wg.Done()
+						req, err := http.NewRequest(http.MethodGet, "http://foo.com/resource", nil)
+						if err != nil {
+							t.Errorf("Failed to create request: %v.", err)
+							return
+						}
+						req.Header.Set("Authorization", token)
+						resp, err := coalesce.RoundTrip(req)
+						if err != nil {
+							t.Errorf("Failed to run request: %v.", err)
+							return
+						}
+						resp.Body.Close()
+					}(token)
+				}
+			}
+			// Same race as in TestRoundTrip.
+			time.Sleep(time.Second * 3)
+			delegate.beginResponding.Broadcast()
+			wg.Wait()
+
+			expectedHits := map[string]int{"/resource": tc.expectedHits}
+			if !reflect.DeepEqual(delegate.hits, expectedHits) {
+				t.Errorf("Unexpected hit count(s). Diff: %v.", diff.ObjectReflectDiff(expectedHits, delegate.hits))
+			}
+		})
+	}
+}
+
+func TestRequestTokenHash(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "http://foo.com/resource", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v.", err)
+	}
+	req.Header.Set("Authorization", "token one")
+	expected := authHeaderHash(req)
+	if hash := requestTokenHash(req); hash != expected {
+		t.Errorf("Expected hash %s of the header, but got %s.", expected, hash)
+	}
+
+	// Transports after the coalescer reuse the hash instead of hashing again.
+	req = withTokenHash(req)
+	req.Header.Set("Authorization", "token two")
+	if hash := requestTokenHash(req); hash != expected {
+		t.Errorf("Expected stored hash %s, but got %s.", expected, hash)
+	}
+}
+
 func TestCacheModeHeader(t *testing.T) {
 	t.Parallel()
 	wg := sync.WaitGroup{}
@@ -149,22 +149,7 @@ type upstreamTransport struct {
 
 func (u upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	etag := req.Header.Get("if-none-match")
-
-	// get authorization header to convert to sha256
-	authHeader := req.Header.Get("Authorization")
-	if authHeader == "" {
-		logrus.Warn("Couldn't retrieve 'Authorization' header, adding to unknown bucket")
-		authHeader = "unknown"
-	}
-	logrus.WithFields(logrus.Fields{
-		"words":      len(strings.Split(authHeader, " ")),
-		"length":     len(authHeader),
-		"user-agent": req.Header.Get("User-Agent"),
-		"source-ip":  req.RemoteAddr,
-	}).Debug("Hashing auth header.")
-	hasher := sha256.New()
-	hasher.Write([]byte(authHeader))
-	authHeaderHash := fmt.Sprintf("%x", hasher.Sum(nil)) // use %x to make this a utf-8 string for use as a label
+	authHeaderHash := requestTokenHash(req)
 
 	reqStartTime := time.Now()
 	// Don't modify request, just pass to delegate.
@@ -188,7 +173,7 @@ func (u upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	}
 
 	apiVersion := "v3"
-	if strings.HasPrefix(req.URL.Path, "graphql") || strings.HasPrefix(req.URL.Path, "/graphql") {
+	if isGraphQL(req) {
 		resp.Header.Set("Cache-Control", "no-store")
 		apiVersion = "v4"
 	}
@@ -199,9 +184,50 @@ func (u upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	return resp, nil
 }
 
+// authHeaderHash hashes the authorization header of a request with sha256, so
+// that tokens can be told apart without being exposed.
+func authHeaderHash(req *http.Request) string {
+	authHeader := req.Header.Get("Authorization")
+	if authHeader == "" {
+		logrus.Warn("Couldn't retrieve 'Authorization' header, adding to unknown bucket")
+		authHeader = "unknown"
+	}
+	logrus.WithFields(logrus.Fields{
+		"words":      len(strings.Split(authHeader, " ")),
+		"length":     len(authHeader),
+		"user-agent": req.Header.Get("User-Agent"),
+		"source-ip":  req.RemoteAddr,
+	}).Debug("Hashing auth header.")
+	hasher := sha256.New()
+	hasher.Write([]byte(authHeader))
+	return fmt.Sprintf("%x", hasher.Sum(nil)) // use %x to make this a utf-8 string for use as a label
+}
+
+// tokenHashKey is the context key of the authHeaderHash of a request.
+type tokenHashKey struct{}
+
+// withTokenHash stores the authHeaderHash of the request in its context, so
+// that the transports the request passes through only hash the header once.
+func withTokenHash(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), tokenHashKey{}, authHeaderHash(req)))
+}
+
+// requestTokenHash returns the authHeaderHash of the request, reusing the one
+// stored by withTokenHash if there is one.
+func requestTokenHash(req *http.Request) string {
+	if hash, ok := req.Context().Value(tokenHashKey{}).(string); ok {
+		return hash
+	}
+	return authHeaderHash(req)
+}
+
+func isGraphQL(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, "graphql") || strings.HasPrefix(req.URL.Path, "/graphql")
+}
+
 // NewDiskCache creates a GitHub cache RoundTripper that is backed by a disk
 // cache.
-func NewDiskCache(delegate http.RoundTripper, cacheDir string, cacheSizeGB, maxConcurrency int) http.RoundTripper {
+func NewDiskCache(delegate http.RoundTripper, cacheDir string, cacheSizeGB, maxConcurrency int, budget *BudgetConfig) http.RoundTripper {
 	return NewFromCache(delegate, diskcache.NewWithDiskv(
 		diskv.New(diskv.Options{
 			BasePath:     path.Join(cacheDir, "data"),
@@ -209,32 +235,39 @@ func NewDiskCache(delegate http.RoundTripper, cacheDir string, cacheSizeGB, maxC
 			CacheSizeMax: uint64(cacheSizeGB) * uint64(1000000000), // convert G to B
 		})),
 		maxConcurrency,
+		budget,
 	)
 }
 
 // NewMemCache creates a GitHub cache RoundTripper that is backed by a memory
 // cache.
-func NewMemCache(delegate http.RoundTripper, maxConcurrency int) http.RoundTripper {
-	return NewFromCache(delegate, httpcache.NewMemoryCache(), maxConcurrency)
+func NewMemCache(delegate http.RoundTripper, maxConcurrency int, budget *BudgetConfig) http.RoundTripper {
+	return NewFromCache(delegate, httpcache.NewMemoryCache(), maxConcurrency, budget)
 }
 
 // NewFromCache creates a GitHub cache RoundTripper that is backed by the
-// specified httpcache.Cache implementation.
-func NewFromCache(delegate http.RoundTripper, cache httpcache.Cache, maxConcurrency int) http.RoundTripper {
+// specified httpcache.Cache implementation. If a budget is given, the rate
+// limit of every token is shared between clients accordingly.
+func NewFromCache(delegate http.RoundTripper, cache httpcache.Cache, maxConcurrency int, budget *BudgetConfig) http.RoundTripper {
 	cacheTransport := httpcache.NewTransport(cache)
 	cacheTransport.Transport = newThrottlingTransport(maxConcurrency, upstreamTransport{delegate: delegate})
+	if budget != nil {
+		// Queued requests must not hold on to the outbound concurrency.
+		cacheTransport.Transport = newBudgetingTransport(budget, cacheTransport.Transport)
+	}
 	return &requestCoalescer{
 		keys:     make(map[string]*responseWaiter),
 		delegate: cacheTransport,
+		budgeted: budget != nil,
 	}
 }
 
 // NewRedisCache creates a GitHub cache RoundTripper that is backed by a Redis
 // cache.
-func NewRedisCache(delegate http.RoundTripper, redisAddress string, maxConcurrency int) http.RoundTripper {
+func NewRedisCache(delegate http.RoundTripper, redisAddress string, maxConcurrency int, budget *BudgetConfig) http.RoundTripper {
 	conn, err := redis.Dial("tcp", redisAddress)
 	if err != nil {
 		logrus.WithError(err).Fatal("Error connecting to Redis")
 	}
-	return NewFromCache(delegate, rediscache.NewWithClient(conn), maxConcurrency)
+	return NewFromCache(delegate, rediscache.NewWithClient(conn), maxConcurrency, budget)
 }
@@ -79,6 +79,27 @@ var timeoutDuration = prometheus.NewHistogramVec(
 	[]string{"token_hash", "path", "user_agent"},
 )
 
+// clientTokenUsageCounter provides the 'github_client_token_usage' counter
+// that keeps track of how many API tokens every client of the proxy used.
+var clientTokenUsageCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "github_client_token_usage",
+		Help: "How many GitHub API tokens each client used.",
+	},
+	[]string{"token_hash", "client"},
+)
+
+// clientThrottlingCounter provides the 'github_client_throttled_requests'
+// counter that keeps track of the requests of clients that exceeded their
+// budget by outcome.
+var clientThrottlingCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "github_client_throttled_requests",
+		Help: "How many requests of each client were queued or rejected for exceeding its budget.",
+	},
+	[]string{"token_hash", "client", "outcome"},
+)
+
 var muxTokenUsage, muxRequestMetrics sync.Mutex
 var lastGitHubResponse time.Time
 
@@ -88,6 +109,8 @@ func init() {
 	prometheus.MustRegister(ghRequestDurationHistVec)
 	prometheus.MustRegister(cacheCounter)
 	prometheus.MustRegister(timeoutDuration)
+	prometheus.MustRegister(clientTokenUsageCounter)
+	prometheus.MustRegister(clientThrottlingCounter)
 }
 
 // CollectGitHubTokenMetrics publishes the rate limits of the github api to
@@ -159,3 +182,15 @@ func CollectCacheRequestMetrics(mode, path, userAgent string) {
 func CollectRequestTimeoutMetrics(tokenHash, path, userAgent string, reqStartTime, responseTime time.Time) {
 	timeoutDuration.With(prometheus.Labels{"token_hash": tokenHash, "path": simplifier.Simplify(path), "user_agent": userAgentWithoutVersion(userAgent)}).Observe(float64(responseTime.Sub(reqStartTime).Seconds()))
 }
+
+// CollectClientTokenUsageMetrics counts an API token used by a client to
+// 'github_client_token_usage' on prometheus.
+func CollectClientTokenUsageMetrics(tokenHash, client string) {
+	clientTokenUsageCounter.With(prometheus.Labels{"token_hash": tokenHash, "client": client}).Inc()
+}
+
+// CollectClientThrottlingMetrics counts a request of a client that exceeded
+// its budget to 'github_client_throttled_requests' on prometheus.
+func CollectClientThrottlingMetrics(tokenHash, client, outcome string) {
+	clientThrottlingCounter.With(prometheus.Labels{"token_hash": tokenHash, "client": client, "outcome": outcome}).Inc()
+}
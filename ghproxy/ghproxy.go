@@ -66,6 +66,7 @@ func init() {
 //  v ^ reverse proxy
 //  v ^ ghcache: downstreamTransport (coalescing, instrumentation)
 //  v ^ ghcache: httpcache layer
+//  v ^ ghcache: budgetingTransport (per client rate limit shares, optional)
 //  v ^ ghcache: upstreamTransport (cache-control, instrumentation)
 //  v ^ http.DefaultTransport
 //  > ^   <Upstream>
@@ -82,6 +83,9 @@ type options struct {
 
 	maxConcurrency int
 
+	budgetConfig string
+	budget       *ghcache.BudgetConfig
+
 	// pushGateway fields are used to configure pushing prometheus metrics.
 	pushGateway         string
 	pushGatewayInterval time.Duration
@@ -106,6 +110,11 @@ func (o *options) validate() error {
 		return fmt.Errorf("failed to parse upstream URL: %v", err)
 	}
 	o.upstreamParsed = upstreamURL
+	if o.budgetConfig != "" {
+		if o.budget, err = ghcache.LoadBudgetConfig(o.budgetConfig); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -117,6 +126,7 @@ func flagOptions() *options {
 	flag.IntVar(&o.port, "port", 8888, "Port to listen on.")
 	flag.StringVar(&o.upstream, "upstream", "https://api.github.com", "Scheme, host, and base path of reverse proxy upstream.")
 	flag.IntVar(&o.maxConcurrency, "concurrency", 25, "Maximum number of concurrent in-flight requests to GitHub.")
+	flag.StringVar(&o.budgetConfig, "budget-config", "", "If specified, share the rate limit of every token between clients as configured in this file.")
 	flag.StringVar(&o.pushGateway, "push-gateway", "", "If specified, push prometheus metrics to this endpoint.")
 	flag.DurationVar(&o.pushGatewayInterval, "push-gateway-interval", time.Minute, "Interval at which prometheus metrics are pushed.")
 	flag.StringVar(&o.logLevel, "log-level", "debug", fmt.Sprintf("Log level is one of %v.", logrus.AllLevels))
@@ -135,11 +145,11 @@ func main() {
 
 	var cache http.RoundTripper
 	if o.redisAddress != "" {
-		cache = ghcache.NewRedisCache(http.DefaultTransport, o.redisAddress, o.maxConcurrency)
+		cache = ghcache.NewRedisCache(http.DefaultTransport, o.redisAddress, o.maxConcurrency, o.budget)
 	} else if o.dir == "" {
-		cache = ghcache.NewMemCache(http.DefaultTransport, o.maxConcurrency)
+		cache = ghcache.NewMemCache(http.DefaultTransport, o.maxConcurrency, o.budget)
 	} else {
-		cache = ghcache.NewDiskCache(http.DefaultTransport, o.dir, o.sizeGB, o.maxConcurrency)
+		cache = ghcache.NewDiskCache(http.DefaultTransport, o.dir, o.sizeGB, o.maxConcurrency, o.budget)
 		go diskMonitor(o.pushGatewayInterval, o.dir)
 	}
 
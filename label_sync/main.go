@@ -83,9 +83,11 @@ type Label struct {
 
 // Configuration is a list of Repos defining Required Labels to sync into them
 // There is also a Default list of labels applied to every Repo
+// Migrations are only run by the migrate action
 type Configuration struct {
-	Repos   map[string]RepoConfig `json:"repos,omitempty"`
-	Default RepoConfig            `json:"default"`
+	Repos      map[string]RepoConfig `json:"repos,omitempty"`
+	Default    RepoConfig            `json:"default"`
+	Migrations []Migration           `json:"migrations,omitempty"`
 }
 
 // RepoConfig contains only labels for the moment
@@ -126,13 +128,15 @@ var (
 	orgs            = flag.String("orgs", "", "Comma separated list of orgs to sync")
 	skipRepos       = flag.String("skip", "", "Comma separated list of org/repos to skip syncing")
 	token           = flag.String("token", "", "Path to github oauth secret")
-	action          = flag.String("action", "sync", "One of: sync, docs")
+	action          = flag.String("action", "sync", "One of: sync, docs, css, migrate")
 	cssTemplate     = flag.String("css-template", "", "Path to template file for label css")
 	cssOutput       = flag.String("css-output", "", "Path to output file for css")
 	docsTemplate    = flag.String("docs-template", "", "Path to template file for label docs")
 	docsOutput      = flag.String("docs-output", "", "Path to output file for docs")
 	tokens          = flag.Int("tokens", defaultTokens, "Throttle hourly token consumption (0 to disable)")
 	tokenBurst      = flag.Int("token-burst", defaultBurst, "Allow consuming a subset of hourly tokens in a short burst")
+	checkpointPath  = flag.String("checkpoint", "", "Path to a file recording the progress of the migrate action, which resumes from it")
+	reportPath      = flag.String("report", "", "Path to write the usage of each migrated label in each repo to")
 )
 
 func init() {
@@ -274,6 +278,14 @@ func (c Configuration) validate(orgs string) error {
 			}
 		}
 	}
+	// Check migrations only target labels we manage
+	known := sets.NewString()
+	for _, l := range c.Labels() {
+		known.Insert(strings.ToLower(l.Name))
+	}
+	if err := validateMigrations(c.Migrations, known); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
 	return nil
 }
 
@@ -714,53 +726,98 @@ func main() {
 		if err != nil {
 			logrus.WithError(err).Fatal("failed to create client")
 		}
-
-		// there are three ways to configure which repos to sync:
-		//  - a whitelist of org/repo values
-		//  - a list of orgs for which we sync all repos
-		//  - a list of orgs with a blacklist of org/repo values
-		if *onlyRepos != "" {
-			reposToSync, parseError := parseCommaDelimitedList(*onlyRepos)
-			if parseError != nil {
-				logrus.WithError(err).Fatal("invalid value for --only")
-			}
-			for org := range reposToSync {
-				if err = syncOrg(org, githubClient, *config, reposToSync[org]); err != nil {
-					logrus.WithError(err).Fatalf("failed to update %s", org)
-				}
-			}
-			return
+		reposToSync, err := selectRepos(githubClient)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to select repos")
 		}
-
-		skippedRepos := map[string][]string{}
-		if *skipRepos != "" {
-			reposToSkip, parseError := parseCommaDelimitedList(*skipRepos)
-			if parseError != nil {
-				logrus.WithError(err).Fatal("invalid value for --skip")
+		for _, org := range sortedOrgs(reposToSync) {
+			if err = syncOrg(org, githubClient, *config, reposToSync[org]); err != nil {
+				logrus.WithError(err).Fatalf("failed to update %s", org)
 			}
-			skippedRepos = reposToSkip
 		}
-
-		for _, org := range strings.Split(*orgs, ",") {
-			org = strings.TrimSpace(org)
-			logger := logrus.WithField("org", org)
-			logger.Info("Reading repos")
-			repos, err := loadRepos(org, githubClient)
-			if err != nil {
-				logger.WithError(err).Fatalf("failed to read repos")
-			}
-			if skipped, exist := skippedRepos[org]; exist {
-				repos = sets.NewString(repos...).Difference(sets.NewString(skipped...)).UnsortedList()
+	case *action == "migrate":
+		githubClient, err := newClient(*token, *tokens, *tokenBurst, !*confirm, *graphqlEndpoint, endpoint.Strings()...)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to create client")
+		}
+		reposToMigrate, err := selectRepos(githubClient)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to select repos")
+		}
+		// Only record progress when it is real
+		checkpoint := migrationCheckpoint{}
+		save := func() error { return nil }
+		if *confirm && *checkpointPath != "" {
+			if checkpoint, err = loadCheckpoint(*checkpointPath); err != nil {
+				logrus.WithError(err).Fatalf("failed to load --checkpoint=%s", *checkpointPath)
 			}
-			if err = syncOrg(org, githubClient, *config, repos); err != nil {
-				logrus.WithError(err).Fatalf("failed to update %s", org)
+			save = func() error { return checkpoint.save(*checkpointPath) }
+		}
+		for _, org := range sortedOrgs(reposToMigrate) {
+			if err = migrateOrg(org, githubClient, config.Migrations, reposToMigrate[org], checkpoint, save); err != nil {
+				logrus.WithError(err).Errorf("failed to migrate %s", org)
+				break
 			}
 		}
+		if werr := writeReport(*reportPath, checkpoint); werr != nil {
+			logrus.WithError(werr).Fatalf("failed to write --report=%s", *reportPath)
+		}
+		if err != nil {
+			logrus.Fatal("migration failed, rerun with the same --checkpoint to resume")
+		}
 	default:
 		logrus.Fatalf("unrecognized action: %s", *action)
 	}
 }
 
+// selectRepos returns the repos to act on in each org.
+// There are three ways to configure which repos to act on:
+//   - a whitelist of org/repo values
+//   - a list of orgs for which we act on all repos
+//   - a list of orgs with a blacklist of org/repo values
+func selectRepos(gc client) (map[string][]string, error) {
+	if *onlyRepos != "" {
+		reposToSync, err := parseCommaDelimitedList(*onlyRepos)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for --only: %v", err)
+		}
+		return reposToSync, nil
+	}
+
+	skippedRepos := map[string][]string{}
+	if *skipRepos != "" {
+		reposToSkip, err := parseCommaDelimitedList(*skipRepos)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for --skip: %v", err)
+		}
+		skippedRepos = reposToSkip
+	}
+
+	selected := map[string][]string{}
+	for _, org := range strings.Split(*orgs, ",") {
+		org = strings.TrimSpace(org)
+		logrus.WithField("org", org).Info("Reading repos")
+		repos, err := loadRepos(org, gc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read repos of %s: %v", org, err)
+		}
+		if skipped, exist := skippedRepos[org]; exist {
+			repos = sets.NewString(repos...).Difference(sets.NewString(skipped...)).UnsortedList()
+		}
+		selected[org] = repos
+	}
+	return selected, nil
+}
+
+func sortedOrgs(repos map[string][]string) []string {
+	var orgs []string
+	for org := range repos {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+	return orgs
+}
+
 // parseCommaDelimitedList parses values in the format:
 //   org/repo,org2/repo2,org/repo3
 // into a mapping of org to repos, i.e.:
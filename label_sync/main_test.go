@@ -82,6 +82,27 @@ func TestValidate(t *testing.T) {
 			},
 			expectedError: false,
 		},
+		{
+			name: "Migration to a repo label",
+			config: Configuration{
+				Repos: map[string]RepoConfig{
+					"org/repo1": {Labels: []Label{
+						{Name: "lab1", Description: "Test Label 1", Color: "deadbe"},
+					}},
+				},
+				Migrations: []Migration{{From: "old", To: []string{"lab1"}}},
+			},
+		},
+		{
+			name: "Migration to an unknown label",
+			config: Configuration{
+				Default: RepoConfig{Labels: []Label{
+					{Name: "lab1", Description: "Test Label 1", Color: "deadbe"},
+				}},
+				Migrations: []Migration{{From: "old", To: []string{"lab2"}}},
+			},
+			expectedError: true,
+		},
 	}
 	// Do tests
 	for _, tc := range testcases {
@@ -0,0 +1,369 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+// Migration moves the issues and PRs carrying a label onto other labels.
+// Several migrations to the same label merge their sources, and rules split
+// a source label into several labels.
+type Migration struct {
+	// From is the label to migrate away from
+	From string `json:"from"`
+	// To lists the labels added to every issue and PR carrying From
+	To []string `json:"to,omitempty"`
+	// Rules add further labels to the issues and PRs they match
+	Rules []MigrationRule `json:"rules,omitempty"`
+	// Keep leaves From on the issues and PRs once they are migrated
+	Keep bool `json:"keep,omitempty"`
+}
+
+// MigrationRule adds labels to the issues and PRs matching all of its conditions.
+type MigrationRule struct {
+	// Target limits the rule to prs or issues, defaulting to both
+	Target LabelTarget `json:"target,omitempty"`
+	// HasLabels requires the issue or PR to carry all of these labels
+	HasLabels []string `json:"hasLabels,omitempty"`
+	// TitleRegexp requires the title to match this regular expression
+	TitleRegexp string `json:"titleRegexp,omitempty"`
+	// To lists the labels to add
+	To []string `json:"to"`
+}
+
+// validateMigrations ensures each migration has a source and targets, that
+// rules are well formed and that every target is a configured label.
+func validateMigrations(migrations []Migration, known sets.String) error {
+	seen := sets.NewString()
+	checkTargets := func(from string, to []string) error {
+		for _, label := range to {
+			lower := strings.ToLower(label)
+			if lower == strings.ToLower(from) {
+				return fmt.Errorf("migration of %s cannot target itself", from)
+			}
+			if !known.Has(lower) {
+				return fmt.Errorf("migration of %s targets %s, which is not a configured label", from, label)
+			}
+		}
+		return nil
+	}
+	for _, m := range migrations {
+		if m.From == "" {
+			return errors.New("migration without a from label")
+		}
+		from := strings.ToLower(m.From)
+		if seen.Has(from) {
+			return fmt.Errorf("duplicate migration of %s", m.From)
+		}
+		seen.Insert(from)
+		if len(m.To) == 0 && len(m.Rules) == 0 {
+			return fmt.Errorf("migration of %s needs to or rules", m.From)
+		}
+		if err := checkTargets(m.From, m.To); err != nil {
+			return err
+		}
+		for i, r := range m.Rules {
+			if len(r.To) == 0 {
+				return fmt.Errorf("rule %d of the migration of %s has no to labels", i, m.From)
+			}
+			switch r.Target {
+			case "", prTarget, issueTarget, bothTarget:
+			default:
+				return fmt.Errorf("rule %d of the migration of %s has invalid target %q", i, m.From, r.Target)
+			}
+			if _, err := regexp.Compile(r.TitleRegexp); err != nil {
+				return fmt.Errorf("rule %d of the migration of %s has invalid titleRegexp: %v", i, m.From, err)
+			}
+			if err := checkTargets(m.From, r.To); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether the issue or PR meets every condition of the rule.
+func (r MigrationRule) matches(issue github.Issue) (bool, error) {
+	switch {
+	case r.Target == prTarget && !issue.IsPullRequest():
+		return false, nil
+	case r.Target == issueTarget && issue.IsPullRequest():
+		return false, nil
+	}
+	for _, label := range r.HasLabels {
+		if !issue.HasLabel(label) {
+			return false, nil
+		}
+	}
+	if r.TitleRegexp == "" {
+		return true, nil
+	}
+	re, err := regexp.Compile(r.TitleRegexp)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(issue.Title), nil
+}
+
+// labelsFor returns the sorted labels the migration adds to the issue or PR,
+// leaving out those it already carries.
+func (m Migration) labelsFor(issue github.Issue) ([]string, error) {
+	wanted := append([]string{}, m.To...)
+	for _, r := range m.Rules {
+		match, err := r.matches(issue)
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			wanted = append(wanted, r.To...)
+		}
+	}
+	var labels []string
+	seen := sets.NewString()
+	for _, label := range wanted {
+		lower := strings.ToLower(label)
+		if seen.Has(lower) || issue.HasLabel(label) {
+			continue
+		}
+		seen.Insert(lower)
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels, nil
+}
+
+// LabelUsage counts the issues and PRs carrying a label in a repo.
+type LabelUsage struct {
+	OpenIssues   int `json:"openIssues"`
+	ClosedIssues int `json:"closedIssues"`
+	OpenPRs      int `json:"openPRs"`
+	ClosedPRs    int `json:"closedPRs"`
+}
+
+func (u *LabelUsage) add(issue github.Issue) {
+	open := issue.State == "open"
+	switch {
+	case issue.IsPullRequest() && open:
+		u.OpenPRs++
+	case issue.IsPullRequest():
+		u.ClosedPRs++
+	case open:
+		u.OpenIssues++
+	default:
+		u.ClosedIssues++
+	}
+}
+
+// migrationProgress records how far the migration of a label in a repo got.
+type migrationProgress struct {
+	// Since is the creation time of the last issue or PR migrated.
+	// Searches resume from it.
+	Since time.Time `json:"since,omitempty"`
+	// Done lists the issues and PRs created at Since that are migrated.
+	Done []int `json:"done,omitempty"`
+	// Complete is set once every issue and PR is migrated.
+	Complete bool `json:"complete,omitempty"`
+	// Usage counts the issues and PRs that carried the label.
+	Usage LabelUsage `json:"usage"`
+}
+
+// migrationCheckpoint maps each migrated label to its progress in each org/repo.
+type migrationCheckpoint map[string]map[string]*migrationProgress
+
+// loadCheckpoint reads the checkpoint at path, which may not exist yet.
+func loadCheckpoint(path string) (migrationCheckpoint, error) {
+	c := migrationCheckpoint{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// save atomically writes the checkpoint to path.
+func (c migrationCheckpoint) save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// progress returns the progress of migrating label in org/repo.
+func (c migrationCheckpoint) progress(label, org, repo string) *migrationProgress {
+	label = strings.ToLower(label)
+	if c[label] == nil {
+		c[label] = map[string]*migrationProgress{}
+	}
+	fullName := org + "/" + repo
+	if c[label][fullName] == nil {
+		c[label][fullName] = &migrationProgress{}
+	}
+	return c[label][fullName]
+}
+
+// report returns the usage of each migrated label in each org/repo.
+func (c migrationCheckpoint) report() map[string]map[string]LabelUsage {
+	r := map[string]map[string]LabelUsage{}
+	for label, repos := range c {
+		r[label] = map[string]LabelUsage{}
+		for repo, p := range repos {
+			r[label][repo] = p.Usage
+		}
+	}
+	return r
+}
+
+// writeReport writes the usage report of the checkpoint to path as YAML,
+// logging it instead when path is empty.
+func writeReport(path string, c migrationCheckpoint) error {
+	data, err := yaml.Marshal(c.report())
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		logrus.Info("Label usage:\n" + string(data))
+		return nil
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// migrateRepo applies the migration to every open and closed issue and PR of
+// org/repo carrying its source label, recording progress in p and calling
+// save after each one.
+//
+// Search only returns a page of results, so issues and PRs are walked in
+// creation order, each search resuming from the last one migrated. When the
+// source label is kept, a search may only return issues and PRs created at
+// the same time as the last one, which are already migrated. The walk then
+// moves past that time, skipping any others created in the same second that
+// did not fit on the page.
+func migrateRepo(gc client, org, repo string, m Migration, p *migrationProgress, save func() error) error {
+	if p.Complete {
+		return nil
+	}
+	logger := logrus.WithFields(logrus.Fields{"org": org, "repo": repo, "label": m.From})
+	query := fmt.Sprintf("repo:%s/%s label:\"%s\"", org, repo, m.From)
+	done := sets.NewInt(p.Done...)
+	after := false
+	for {
+		q := query
+		if !p.Since.IsZero() {
+			op := ">="
+			if after {
+				op = ">"
+			}
+			q += " created:" + op + p.Since.UTC().Format(time.RFC3339)
+		}
+		issues, err := gc.FindIssues(q, "created", true)
+		if err != nil {
+			return fmt.Errorf("failed to search for %s in %s/%s: %v", m.From, org, repo, err)
+		}
+		var pending []github.Issue
+		for _, issue := range issues {
+			if !done.Has(issue.Number) {
+				pending = append(pending, issue)
+			}
+		}
+		if len(pending) == 0 {
+			if after || len(issues) == 0 {
+				break
+			}
+			after = true
+			continue
+		}
+		after = false
+		for _, issue := range pending {
+			labels, err := m.labelsFor(issue)
+			if err != nil {
+				return err
+			}
+			logger.WithFields(logrus.Fields{"number": issue.Number, "add": labels}).Info("migrate")
+			if *confirm {
+				for _, label := range labels {
+					if err := gc.AddLabel(org, repo, issue.Number, label); err != nil {
+						return fmt.Errorf("failed to add %s to %s/%s#%d: %v", label, org, repo, issue.Number, err)
+					}
+				}
+				if !m.Keep {
+					if err := gc.RemoveLabel(org, repo, issue.Number, m.From); err != nil {
+						return fmt.Errorf("failed to remove %s from %s/%s#%d: %v", m.From, org, repo, issue.Number, err)
+					}
+				}
+			}
+			p.Usage.add(issue)
+			if !issue.CreatedAt.Equal(p.Since) {
+				p.Since = issue.CreatedAt
+				done = sets.NewInt()
+			}
+			done.Insert(issue.Number)
+			p.Done = done.List()
+			if err := save(); err != nil {
+				return fmt.Errorf("failed to save checkpoint: %v", err)
+			}
+		}
+	}
+	p.Complete = true
+	return save()
+}
+
+// migrateOrg runs each migration over the repos of the org in turn.
+func migrateOrg(org string, gc client, migrations []Migration, repos []string, checkpoint migrationCheckpoint, save func() error) error {
+	sorted := append([]string{}, repos...)
+	sort.Strings(sorted)
+	for _, m := range migrations {
+		for _, repo := range sorted {
+			if err := migrateRepo(gc, org, repo, m, checkpoint.progress(m.From, org, repo), save); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,403 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+// fakeSearchClient searches its issues like GitHub does for migrateRepo,
+// returning a page of results at a time.
+type fakeSearchClient struct {
+	client
+	issues   map[int]*github.Issue
+	pageSize int
+	// failAfter fails AddLabel once this many labels were added, when positive
+	failAfter int
+
+	queries []string
+	added   []string
+	removed []string
+}
+
+var (
+	fakeLabelRE   = regexp.MustCompile(`label:"([^"]+)"`)
+	fakeCreatedRE = regexp.MustCompile(`created:(>=?)(\S+)`)
+)
+
+func (c *fakeSearchClient) FindIssues(query, order string, ascending bool) ([]github.Issue, error) {
+	c.queries = append(c.queries, query)
+	if order != "created" || !ascending {
+		return nil, fmt.Errorf("unexpected order %s ascending=%t", order, ascending)
+	}
+	label := fakeLabelRE.FindStringSubmatch(query)[1]
+	var since time.Time
+	after := false
+	if m := fakeCreatedRE.FindStringSubmatch(query); m != nil {
+		var err error
+		if since, err = time.Parse(time.RFC3339, m[2]); err != nil {
+			return nil, err
+		}
+		after = m[1] == ">"
+	}
+	var found []github.Issue
+	for _, issue := range c.issues {
+		if after && issue.CreatedAt.Equal(since) {
+			continue
+		}
+		if issue.HasLabel(label) && !issue.CreatedAt.Before(since) {
+			found = append(found, *issue)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].CreatedAt.Equal(found[j].CreatedAt) {
+			return found[i].Number < found[j].Number
+		}
+		return found[i].CreatedAt.Before(found[j].CreatedAt)
+	})
+	if len(found) > c.pageSize {
+		found = found[:c.pageSize]
+	}
+	return found, nil
+}
+
+func (c *fakeSearchClient) AddLabel(org, repo string, number int, label string) error {
+	if c.failAfter > 0 && len(c.added) == c.failAfter {
+		return errors.New("injected failure")
+	}
+	c.added = append(c.added, fmt.Sprintf("%d:%s", number, label))
+	c.issues[number].Labels = append(c.issues[number].Labels, github.Label{Name: label})
+	return nil
+}
+
+func (c *fakeSearchClient) RemoveLabel(org, repo string, number int, label string) error {
+	c.removed = append(c.removed, fmt.Sprintf("%d:%s", number, label))
+	var labels []github.Label
+	for _, l := range c.issues[number].Labels {
+		if l.Name != label {
+			labels = append(labels, l)
+		}
+	}
+	c.issues[number].Labels = labels
+	return nil
+}
+
+func newFakeSearchClient(pageSize int, issues ...github.Issue) *fakeSearchClient {
+	c := &fakeSearchClient{issues: map[int]*github.Issue{}, pageSize: pageSize}
+	for i := range issues {
+		c.issues[issues[i].Number] = &issues[i]
+	}
+	return c
+}
+
+func labeled(number int, created time.Time, pr bool, state string, labels ...string) github.Issue {
+	issue := github.Issue{Number: number, CreatedAt: created, State: state, Title: fmt.Sprintf("issue %d", number)}
+	if pr {
+		issue.PullRequest = &struct{}{}
+	}
+	for _, l := range labels {
+		issue.Labels = append(issue.Labels, github.Label{Name: l})
+	}
+	return issue
+}
+
+func TestValidateMigrations(t *testing.T) {
+	known := sets.NewString("kind/bug", "kind/feature", "area/a", "area/b")
+	var testcases = []struct {
+		name          string
+		migrations    []Migration
+		expectedError bool
+	}{
+		{
+			name: "No migrations",
+		},
+		{
+			name: "Merge two labels",
+			migrations: []Migration{
+				{From: "bug", To: []string{"kind/bug"}},
+				{From: "defect", To: []string{"Kind/Bug"}},
+			},
+		},
+		{
+			name: "Split a label",
+			migrations: []Migration{
+				{From: "area", Rules: []MigrationRule{
+					{Target: prTarget, To: []string{"area/a"}},
+					{TitleRegexp: "^b", HasLabels: []string{"kind/bug"}, To: []string{"area/b"}},
+				}},
+			},
+		},
+		{
+			name:          "Missing from",
+			migrations:    []Migration{{To: []string{"kind/bug"}}},
+			expectedError: true,
+		},
+		{
+			name:          "No targets",
+			migrations:    []Migration{{From: "bug"}},
+			expectedError: true,
+		},
+		{
+			name:          "Unknown target",
+			migrations:    []Migration{{From: "bug", To: []string{"kind/bugs"}}},
+			expectedError: true,
+		},
+		{
+			name:          "Migrate to itself",
+			migrations:    []Migration{{From: "Kind/Bug", To: []string{"kind/bug"}}},
+			expectedError: true,
+		},
+		{
+			name: "Duplicate source",
+			migrations: []Migration{
+				{From: "bug", To: []string{"kind/bug"}},
+				{From: "Bug", To: []string{"kind/feature"}},
+			},
+			expectedError: true,
+		},
+		{
+			name:          "Rule without targets",
+			migrations:    []Migration{{From: "area", Rules: []MigrationRule{{Target: prTarget}}}},
+			expectedError: true,
+		},
+		{
+			name:          "Rule with bad target",
+			migrations:    []Migration{{From: "area", Rules: []MigrationRule{{Target: "commits", To: []string{"area/a"}}}}},
+			expectedError: true,
+		},
+		{
+			name:          "Rule with bad regexp",
+			migrations:    []Migration{{From: "area", Rules: []MigrationRule{{TitleRegexp: "(", To: []string{"area/a"}}}}},
+			expectedError: true,
+		},
+	}
+	for _, tc := range testcases {
+		err := validateMigrations(tc.migrations, known)
+		if err == nil && tc.expectedError {
+			t.Errorf("%s: failed to raise error", tc.name)
+		} else if err != nil && !tc.expectedError {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestLabelsFor(t *testing.T) {
+	migration := Migration{
+		From: "area",
+		To:   []string{"kind/cleanup"},
+		Rules: []MigrationRule{
+			{Target: prTarget, To: []string{"area/pr"}},
+			{Target: issueTarget, HasLabels: []string{"kind/bug"}, To: []string{"area/bug"}},
+			{TitleRegexp: "(?i)^docs", To: []string{"area/docs", "kind/cleanup"}},
+		},
+	}
+	var testcases = []struct {
+		name     string
+		issue    github.Issue
+		expected []string
+	}{
+		{
+			name:     "Plain issue only gets the unconditional labels",
+			issue:    github.Issue{Title: "flake"},
+			expected: []string{"kind/cleanup"},
+		},
+		{
+			name:     "PR rule",
+			issue:    github.Issue{Title: "fix", PullRequest: &struct{}{}},
+			expected: []string{"area/pr", "kind/cleanup"},
+		},
+		{
+			name:     "Label rule does not apply to PRs",
+			issue:    github.Issue{Title: "fix", PullRequest: &struct{}{}, Labels: []github.Label{{Name: "kind/bug"}}},
+			expected: []string{"area/pr", "kind/cleanup"},
+		},
+		{
+			name:     "Label and title rules",
+			issue:    github.Issue{Title: "Docs are wrong", Labels: []github.Label{{Name: "Kind/Bug"}}},
+			expected: []string{"area/bug", "area/docs", "kind/cleanup"},
+		},
+		{
+			name:  "Labels already present are not added",
+			issue: github.Issue{Title: "flake", Labels: []github.Label{{Name: "kind/cleanup"}}},
+		},
+	}
+	for _, tc := range testcases {
+		actual, err := migration.labelsFor(tc.issue)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestMigrateRepo(t *testing.T) {
+	old := *confirm
+	defer func() { *confirm = old }()
+
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	issues := func() []github.Issue {
+		return []github.Issue{
+			labeled(1, t0, false, "open", "bug"),
+			labeled(2, t0, true, "closed", "bug"),
+			labeled(3, t0.Add(time.Hour), false, "closed", "bug", "kind/bug"),
+			labeled(4, t0.Add(2*time.Hour), true, "open", "bug"),
+			labeled(5, t0.Add(3*time.Hour), false, "open", "other"),
+		}
+	}
+	expectedUsage := LabelUsage{OpenIssues: 1, ClosedIssues: 1, OpenPRs: 1, ClosedPRs: 1}
+
+	var testcases = []struct {
+		name            string
+		migration       Migration
+		confirm         bool
+		expectedAdded   []string
+		expectedRemoved []string
+	}{
+		{
+			name:      "Dry run only reports usage",
+			migration: Migration{From: "bug", To: []string{"kind/bug"}},
+		},
+		{
+			name:            "Move every issue and PR across pages",
+			migration:       Migration{From: "bug", To: []string{"kind/bug"}},
+			confirm:         true,
+			expectedAdded:   []string{"1:kind/bug", "2:kind/bug", "4:kind/bug"},
+			expectedRemoved: []string{"1:bug", "2:bug", "3:bug", "4:bug"},
+		},
+		{
+			name:          "Keeping the source label still pages through everything",
+			migration:     Migration{From: "bug", To: []string{"kind/bug"}, Keep: true},
+			confirm:       true,
+			expectedAdded: []string{"1:kind/bug", "2:kind/bug", "4:kind/bug"},
+		},
+		{
+			name: "Split by rules",
+			migration: Migration{From: "bug", Rules: []MigrationRule{
+				{Target: prTarget, To: []string{"kind/regression"}},
+				{Target: issueTarget, To: []string{"kind/bug"}},
+			}},
+			confirm:         true,
+			expectedAdded:   []string{"1:kind/bug", "2:kind/regression", "4:kind/regression"},
+			expectedRemoved: []string{"1:bug", "2:bug", "3:bug", "4:bug"},
+		},
+	}
+	for _, tc := range testcases {
+		*confirm = tc.confirm
+		gc := newFakeSearchClient(2, issues()...)
+		progress := &migrationProgress{}
+		saves := 0
+		if err := migrateRepo(gc, "org", "repo", tc.migration, progress, func() error { saves++; return nil }); err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(gc.added, tc.expectedAdded) {
+			t.Errorf("%s: expected added %v, got %v", tc.name, tc.expectedAdded, gc.added)
+		}
+		if !reflect.DeepEqual(gc.removed, tc.expectedRemoved) {
+			t.Errorf("%s: expected removed %v, got %v", tc.name, tc.expectedRemoved, gc.removed)
+		}
+		if !progress.Complete {
+			t.Errorf("%s: expected the migration to complete", tc.name)
+		}
+		if progress.Usage != expectedUsage {
+			t.Errorf("%s: expected usage %+v, got %+v", tc.name, expectedUsage, progress.Usage)
+		}
+		if saves != 5 {
+			t.Errorf("%s: expected a save per issue and on completion, got %d", tc.name, saves)
+		}
+	}
+}
+
+func TestMigrateRepoResumes(t *testing.T) {
+	old := *confirm
+	defer func() { *confirm = old }()
+	*confirm = true
+
+	dir, err := ioutil.TempDir("", "label_sync")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoint.yaml")
+
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	gc := newFakeSearchClient(2,
+		labeled(1, t0, false, "open", "bug"),
+		labeled(2, t0, false, "open", "bug"),
+		labeled(3, t0.Add(time.Hour), false, "open", "bug"),
+	)
+	gc.failAfter = 2
+	migration := Migration{From: "bug", To: []string{"kind/bug"}, Keep: true}
+
+	checkpoint, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("failed to load missing checkpoint: %v", err)
+	}
+	save := func() error { return checkpoint.save(path) }
+	if err := migrateOrg("org", gc, []Migration{migration}, []string{"repo"}, checkpoint, save); err == nil {
+		t.Fatal("expected the injected failure")
+	}
+
+	gc.failAfter = 0
+	if checkpoint, err = loadCheckpoint(path); err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if err := migrateOrg("org", gc, []Migration{migration}, []string{"repo"}, checkpoint, save); err != nil {
+		t.Fatalf("unexpected error resuming: %v", err)
+	}
+	expectedAdded := []string{"1:kind/bug", "2:kind/bug", "3:kind/bug"}
+	if !reflect.DeepEqual(gc.added, expectedAdded) {
+		t.Errorf("expected added %v, got %v", expectedAdded, gc.added)
+	}
+	if last := gc.queries[len(gc.queries)-1]; !strings.Contains(last, "created:>"+t0.Add(time.Hour).Format(time.RFC3339)) {
+		t.Errorf("expected the last search to move past the last issue, got %q", last)
+	}
+
+	if checkpoint, err = loadCheckpoint(path); err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	expectedReport := map[string]map[string]LabelUsage{"bug": {"org/repo": {OpenIssues: 3}}}
+	if report := checkpoint.report(); !reflect.DeepEqual(report, expectedReport) {
+		t.Errorf("expected report %v, got %v", expectedReport, report)
+	}
+
+	// A complete migration does not search again
+	searches := len(gc.queries)
+	if err := migrateOrg("org", gc, []Migration{migration}, []string{"repo"}, checkpoint, save); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gc.queries) != searches {
+		t.Errorf("expected no more searches, got %v", gc.queries[searches:])
+	}
+}
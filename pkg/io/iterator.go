@@ -0,0 +1,166 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package io
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"gocloud.dev/blob"
+	"google.golang.org/api/iterator"
+)
+
+// ObjectAttributes describes a single entry of a listing
+type ObjectAttributes struct {
+	// Name is the full path of the object, in the same format the Opener
+	// accepts, e.g. gs://bucket/logs/job/1/build-log.txt.
+	// Directories end with the delimiter used for the listing.
+	Name    string
+	IsDir   bool
+	Size    int64
+	Updated time.Time
+}
+
+// ObjectIterator iterates over the result of a listing
+type ObjectIterator interface {
+	// Next returns the next entry, or io.EOF once all entries have been returned.
+	Next(ctx context.Context) (ObjectAttributes, error)
+}
+
+type gcsObjectIterator struct {
+	bucket   string
+	iterator *storage.ObjectIterator
+}
+
+func (i *gcsObjectIterator) Next(_ context.Context) (ObjectAttributes, error) {
+	attrs, err := i.iterator.Next()
+	if err == iterator.Done {
+		return ObjectAttributes{}, io.EOF
+	}
+	if err != nil {
+		return ObjectAttributes{}, err
+	}
+	// Synthetic directories only have the Prefix set
+	if attrs.Prefix != "" {
+		return ObjectAttributes{Name: i.bucket + attrs.Prefix, IsDir: true}, nil
+	}
+	return ObjectAttributes{
+		Name:    i.bucket + attrs.Name,
+		Size:    attrs.Size,
+		Updated: attrs.Updated,
+	}, nil
+}
+
+type blobObjectIterator struct {
+	bucket   string
+	iterator *blob.ListIterator
+}
+
+func (i *blobObjectIterator) Next(ctx context.Context) (ObjectAttributes, error) {
+	obj, err := i.iterator.Next(ctx)
+	if err != nil {
+		return ObjectAttributes{}, err
+	}
+	return ObjectAttributes{
+		Name:    i.bucket + obj.Key,
+		IsDir:   obj.IsDir,
+		Size:    obj.Size,
+		Updated: obj.ModTime,
+	}, nil
+}
+
+// localObjectIterator lists local files the same way a bucket listing would,
+// i.e. by matching full paths against the prefix rather than by directory.
+type localObjectIterator struct {
+	objects []ObjectAttributes
+}
+
+func newLocalObjectIterator(prefix, delimiter string) (*localObjectIterator, error) {
+	root := prefix
+	if !strings.HasSuffix(prefix, "/") {
+		root = filepath.Dir(prefix)
+	}
+	var files []ObjectAttributes
+	if delimiter == "/" {
+		// Only the entries of root itself can show up in the listing.
+		infos, err := ioutil.ReadDir(root)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, info := range infos {
+			name := filepath.Join(root, info.Name())
+			if info.IsDir() {
+				name += "/"
+			}
+			files = append(files, ObjectAttributes{Name: name, Size: info.Size(), Updated: info.ModTime()})
+		}
+	} else {
+		err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !info.IsDir() {
+				files = append(files, ObjectAttributes{Name: name, Size: info.Size(), Updated: info.ModTime()})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	it := &localObjectIterator{}
+	seenDirs := map[string]bool{}
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			rest := strings.TrimPrefix(f.Name, prefix)
+			if idx := strings.Index(rest, delimiter); idx >= 0 {
+				dir := prefix + rest[:idx+len(delimiter)]
+				if !seenDirs[dir] {
+					seenDirs[dir] = true
+					it.objects = append(it.objects, ObjectAttributes{Name: dir, IsDir: true})
+				}
+				continue
+			}
+		}
+		it.objects = append(it.objects, f)
+	}
+	sort.Slice(it.objects, func(i, j int) bool { return it.objects[i].Name < it.objects[j].Name })
+	return it, nil
+}
+
+func (i *localObjectIterator) Next(_ context.Context) (ObjectAttributes, error) {
+	if len(i.objects) == 0 {
+		return ObjectAttributes{}, io.EOF
+	}
+	next := i.objects[0]
+	i.objects = i.objects[1:]
+	return next, nil
+}
@@ -22,10 +22,13 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
@@ -48,10 +51,25 @@ type (
 	WriteCloser = io.WriteCloser
 )
 
-// Opener has methods to read and write paths
+// Opener has methods to read, write, list, stat and delete paths
 type Opener interface {
 	Reader(ctx context.Context, path string) (ReadCloser, error)
 	Writer(ctx context.Context, path string, opts ...WriterOptions) (WriteCloser, error)
+	// Iterator lists the objects below prefix. If delimiter is not empty,
+	// objects whose names contain the delimiter after the prefix are
+	// collapsed into a single directory entry.
+	Iterator(ctx context.Context, prefix, delimiter string) (ObjectIterator, error)
+	Attributes(ctx context.Context, path string) (Attributes, error)
+	Delete(ctx context.Context, path string) error
+}
+
+// Attributes are the attributes of a single stored object
+type Attributes struct {
+	Size            int64
+	Updated         time.Time
+	ContentType     string
+	ContentEncoding string
+	Metadata        map[string]string
 }
 
 type opener struct {
@@ -79,18 +97,31 @@ func NewOpener(ctx context.Context, gcsCredentialsFile, s3CredentialsFile string
 		logrus.WithError(err).Debug("Cannot load application default gcp credentials")
 		gcsClient = nil
 	}
+	return NewOpenerFromGCSClient(gcsClient, s3CredentialsFile)
+}
+
+// NewOpenerFromGCSClient returns an opener that uses the given client for GCS paths
+// and can additionally read S3 and local paths.
+// The GCS client may be nil, in which case GCS paths can't be opened.
+// For the content of the s3CredentialsFile see NewOpener.
+func NewOpenerFromGCSClient(gcsClient *storage.Client, s3CredentialsFile string) (Opener, error) {
 	var s3Credentials []byte
 	if s3CredentialsFile != "" {
+		var err error
 		s3Credentials, err = ioutil.ReadFile(s3CredentialsFile)
 		if err != nil {
 			return nil, err
 		}
 	}
-	return &opener{
-		gcsClient:     gcsClient,
+	o := &opener{
 		s3Credentials: s3Credentials,
 		cachedBuckets: map[string]*blob.Bucket{},
-	}, nil
+	}
+	// Don't store a typed nil pointer, so openGCS can detect the missing client
+	if gcsClient != nil {
+		o.gcsClient = gcsClient
+	}
+	return o, nil
 }
 
 // ErrNotFoundTest can be used for unit tests to simulate NotFound errors.
@@ -122,17 +153,27 @@ func (o *opener) openGCS(path string) (*storage.ObjectHandle, error) {
 	if !strings.HasPrefix(path, "gs://") {
 		return nil, nil
 	}
+	bucket, _, object, err := o.parseGCS(path)
+	if err != nil {
+		return nil, err
+	}
+	if object == "" {
+		return nil, errors.New("object name is empty")
+	}
+	return bucket.Object(object), nil
+}
+
+// parseGCS returns the bucket handle, bucket name and object name of a gs:// path.
+// The object name may be empty, e.g. when listing a whole bucket.
+func (o *opener) parseGCS(path string) (*storage.BucketHandle, string, string, error) {
 	if o.gcsClient == nil {
-		return nil, errors.New("no gcs client configured")
+		return nil, "", "", errors.New("no gcs client configured")
 	}
 	var p gcs.Path
 	if err := p.Set(path); err != nil {
-		return nil, err
-	}
-	if p.Object() == "" {
-		return nil, errors.New("object name is empty")
+		return nil, "", "", err
 	}
-	return o.gcsClient.Bucket(p.Bucket()).Object(p.Object()), nil
+	return o.gcsClient.Bucket(p.Bucket()), p.Bucket(), p.Object(), nil
 }
 
 // getBucket opens a bucket
@@ -218,3 +259,105 @@ func (o *opener) Writer(ctx context.Context, p string, opts ...WriterOptions) (i
 	}
 	return writer, nil
 }
+
+// Iterator lists all objects below the prefix, returning directories
+// separately when a delimiter is given.
+func (o *opener) Iterator(ctx context.Context, prefix, delimiter string) (ObjectIterator, error) {
+	if strings.HasPrefix(prefix, "gs://") {
+		bucket, bucketName, object, err := o.parseGCS(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("bad gcs path: %v", err)
+		}
+		return &gcsObjectIterator{
+			bucket:   fmt.Sprintf("gs://%s/", bucketName),
+			iterator: bucket.Objects(ctx, &storage.Query{Prefix: object, Delimiter: delimiter}),
+		}, nil
+	}
+	if strings.HasPrefix(prefix, "/") {
+		return newLocalObjectIterator(prefix, delimiter)
+	}
+
+	storageProvider, bucketName, _, err := providers.ParseStoragePath(prefix)
+	if err != nil {
+		return nil, err
+	}
+	bucket, relativePath, err := o.getBucket(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &blobObjectIterator{
+		bucket:   fmt.Sprintf("%s://%s/", storageProvider, bucketName),
+		iterator: bucket.List(&blob.ListOptions{Prefix: relativePath, Delimiter: delimiter}),
+	}, nil
+}
+
+// Attributes returns the attributes of the object at path, returning an IsNotExist() error when missing
+func (o *opener) Attributes(ctx context.Context, path string) (Attributes, error) {
+	if strings.HasPrefix(path, "gs://") {
+		g, err := o.openGCS(path)
+		if err != nil {
+			return Attributes{}, fmt.Errorf("bad gcs path: %v", err)
+		}
+		attrs, err := g.Attrs(ctx)
+		if err != nil {
+			return Attributes{}, err
+		}
+		return Attributes{
+			Size:            attrs.Size,
+			Updated:         attrs.Updated,
+			ContentType:     attrs.ContentType,
+			ContentEncoding: attrs.ContentEncoding,
+			Metadata:        attrs.Metadata,
+		}, nil
+	}
+	if strings.HasPrefix(path, "/") {
+		info, err := os.Stat(path)
+		if err != nil {
+			return Attributes{}, err
+		}
+		if info.IsDir() {
+			return Attributes{}, fmt.Errorf("%q is a directory", path)
+		}
+		return Attributes{
+			Size:        info.Size(),
+			Updated:     info.ModTime(),
+			ContentType: mime.TypeByExtension(filepath.Ext(path)),
+		}, nil
+	}
+
+	bucket, relativePath, err := o.getBucket(ctx, path)
+	if err != nil {
+		return Attributes{}, err
+	}
+	attrs, err := bucket.Attributes(ctx, relativePath)
+	if err != nil {
+		return Attributes{}, err
+	}
+	return Attributes{
+		Size:            attrs.Size,
+		Updated:         attrs.ModTime,
+		ContentType:     attrs.ContentType,
+		ContentEncoding: attrs.ContentEncoding,
+		Metadata:        attrs.Metadata,
+	}, nil
+}
+
+// Delete removes the object at path, returning an IsNotExist() error when missing
+func (o *opener) Delete(ctx context.Context, path string) error {
+	if strings.HasPrefix(path, "gs://") {
+		g, err := o.openGCS(path)
+		if err != nil {
+			return fmt.Errorf("bad gcs path: %v", err)
+		}
+		return g.Delete(ctx)
+	}
+	if strings.HasPrefix(path, "/") {
+		return os.Remove(path)
+	}
+
+	bucket, relativePath, err := o.getBucket(ctx, path)
+	if err != nil {
+		return err
+	}
+	return bucket.Delete(ctx, relativePath)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package io
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"gocloud.dev/blob"
+)
+
+var testObjects = map[string]string{
+	"logs/job/1/build-log.txt":         "log one",
+	"logs/job/1/artifacts/junit_1.xml": "<testsuite/>",
+	"logs/job/2/build-log.txt":         "log two",
+	"logs/job/latest-build.txt":        "2",
+	"logs/other/1/build-log.txt":       "other",
+}
+
+func newTestOpener() *opener {
+	return &opener{cachedBuckets: map[string]*blob.Bucket{}}
+}
+
+func listNames(t *testing.T, o Opener, prefix, delimiter string) []string {
+	it, err := o.Iterator(context.Background(), prefix, delimiter)
+	if err != nil {
+		t.Fatalf("failed to list %q: %v", prefix, err)
+	}
+	var names []string
+	for {
+		attrs, err := it.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to iterate %q: %v", prefix, err)
+		}
+		names = append(names, attrs.Name)
+	}
+	return names
+}
+
+func TestOpener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "opener")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, base := range []string{dir + "/", "mem://bucket/"} {
+		t.Run(base, func(t *testing.T) {
+			ctx := context.Background()
+			o := newTestOpener()
+			for name, content := range testObjects {
+				contentType := "text/plain"
+				w, err := o.Writer(ctx, base+name, WriterOptions{ContentType: &contentType})
+				if err != nil {
+					t.Fatalf("failed to open writer for %q: %v", name, err)
+				}
+				if _, err := w.Write([]byte(content)); err != nil {
+					t.Fatalf("failed to write %q: %v", name, err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatalf("failed to close %q: %v", name, err)
+				}
+			}
+
+			if got, expected := listNames(t, o, base+"logs/job/", "/"), []string{
+				base + "logs/job/1/",
+				base + "logs/job/2/",
+				base + "logs/job/latest-build.txt",
+			}; !reflect.DeepEqual(got, expected) {
+				t.Errorf("listing with delimiter: expected %v, got %v", expected, got)
+			}
+			if got, expected := listNames(t, o, base+"logs/job/1", ""), []string{
+				base + "logs/job/1/artifacts/junit_1.xml",
+				base + "logs/job/1/build-log.txt",
+			}; !reflect.DeepEqual(got, expected) {
+				t.Errorf("recursive listing: expected %v, got %v", expected, got)
+			}
+			if got := listNames(t, o, base+"logs/missing/", "/"); len(got) != 0 {
+				t.Errorf("expected empty listing for missing prefix, got %v", got)
+			}
+
+			attrs, err := o.Attributes(ctx, base+"logs/job/2/build-log.txt")
+			if err != nil {
+				t.Fatalf("failed to get attributes: %v", err)
+			}
+			if attrs.Size != int64(len("log two")) {
+				t.Errorf("expected size %d, got %d", len("log two"), attrs.Size)
+			}
+			if attrs.Updated.IsZero() {
+				t.Error("expected updated time to be set")
+			}
+			if _, err := o.Attributes(ctx, base+"logs/job/3/build-log.txt"); !IsNotExist(err) {
+				t.Errorf("expected not exist error for missing object, got %v", err)
+			}
+
+			if err := o.Delete(ctx, base+"logs/job/2/build-log.txt"); err != nil {
+				t.Fatalf("failed to delete: %v", err)
+			}
+			if _, err := o.Reader(ctx, base+"logs/job/2/build-log.txt"); !IsNotExist(err) {
+				t.Errorf("expected not exist error after delete, got %v", err)
+			}
+			if err := o.Delete(ctx, base+"logs/job/2/build-log.txt"); !IsNotExist(err) {
+				t.Errorf("expected not exist error deleting twice, got %v", err)
+			}
+		})
+	}
+}
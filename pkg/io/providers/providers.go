@@ -32,7 +32,10 @@ import (
 )
 
 const (
-	providerS3 = "s3"
+	// GS is the storageProvider of Google Cloud Storage paths, e.g. gs://prow-artifacts
+	GS = "gs"
+	// S3 is the storageProvider of AWS S3 and S3-compatible paths, e.g. s3://prow-artifacts
+	S3 = "s3"
 )
 
 // GetBucket opens and returns a gocloud blob.Bucket based on credentials and a path.
@@ -64,7 +67,7 @@ func GetBucket(ctx context.Context, s3Credentials []byte, path string) (*blob.Bu
 	if err != nil {
 		return nil, err
 	}
-	if storageProvider == providerS3 && len(s3Credentials) > 0 {
+	if storageProvider == S3 && len(s3Credentials) > 0 {
 		return getS3Bucket(ctx, s3Credentials, bucket)
 	}
 
@@ -139,3 +142,25 @@ func ParseStoragePath(storagePath string) (storageProvider, bucket, relativePath
 	}
 	return storageProvider, bucket, relativePath, nil
 }
+
+// ParseBucket parses a bucket as configured in the GCSConfiguration of a job and returns
+// the storageProvider and the bucket name.
+// For example s3://prow-artifacts results in (s3, prow-artifacts).
+// Buckets without a storageProvider prefix are treated as GCS buckets for backwards
+// compatibility reasons, so prow-artifacts results in (gs, prow-artifacts).
+func ParseBucket(bucket string) (storageProvider, bucketName string, err error) {
+	if !strings.Contains(bucket, "://") {
+		if bucket == "" {
+			return "", "", fmt.Errorf("bucket is empty")
+		}
+		return GS, bucket, nil
+	}
+	storageProvider, bucketName, relativePath, err := ParseStoragePath(bucket)
+	if err != nil {
+		return "", "", err
+	}
+	if relativePath != "" {
+		return "", "", fmt.Errorf("bucket %q must not contain a path", bucket)
+	}
+	return storageProvider, bucketName, nil
+}
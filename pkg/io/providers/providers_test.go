@@ -97,3 +97,56 @@ func TestParseStoragePath(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBucket(t *testing.T) {
+	tests := []struct {
+		name                string
+		bucket              string
+		wantStorageProvider string
+		wantBucket          string
+		wantErr             bool
+	}{
+		{
+			name:                "bucket without prefix defaults to gs",
+			bucket:              "prow-artifacts",
+			wantStorageProvider: "gs",
+			wantBucket:          "prow-artifacts",
+		},
+		{
+			name:                "gs bucket",
+			bucket:              "gs://prow-artifacts",
+			wantStorageProvider: "gs",
+			wantBucket:          "prow-artifacts",
+		},
+		{
+			name:                "s3 bucket",
+			bucket:              "s3://prow-artifacts/",
+			wantStorageProvider: "s3",
+			wantBucket:          "prow-artifacts",
+		},
+		{
+			name:    "bucket with path fails",
+			bucket:  "s3://prow-artifacts/logs",
+			wantErr: true,
+		},
+		{
+			name:    "empty bucket fails",
+			bucket:  "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStorageProvider, gotBucket, err := providers.ParseBucket(tt.bucket)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBucket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotStorageProvider != tt.wantStorageProvider {
+				t.Errorf("ParseBucket() gotStorageProvider = %v, want %v", gotStorageProvider, tt.wantStorageProvider)
+			}
+			if gotBucket != tt.wantBucket {
+				t.Errorf("ParseBucket() gotBucket = %v, want %v", gotBucket, tt.wantBucket)
+			}
+		})
+	}
+}
@@ -20,7 +20,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"mime"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -131,11 +133,17 @@ type ProwJobSpec struct {
 	// MaxConcurrency restricts the total number of instances
 	// of this job that can run in parallel at once
 	MaxConcurrency int `json:"max_concurrency,omitempty"`
+	// Priority determines the order in which plank starts
+	// triggered jobs, a higher priority is started first
+	Priority int `json:"priority,omitempty"`
 	// ErrorOnEviction indicates that the ProwJob should be completed and given
 	// the ErrorState status if the pod that is executing the job is evicted.
 	// If this field is unspecified or false, a new pod will be created to replace
 	// the evicted one.
 	ErrorOnEviction bool `json:"error_on_eviction,omitempty"`
+	// Retry configures whether and how often the job is run again
+	// after its pod ended because of an infrastructure problem.
+	Retry *RetryPolicy `json:"retry,omitempty"`
 
 	// PodSpec provides the basis for running the test under
 	// a Kubernetes agent
@@ -268,11 +276,137 @@ func (rac *RerunAuthConfig) IsAllowAnyone() bool {
 }
 
 type ReporterConfig struct {
-	Slack *SlackReporterConfig `json:"slack,omitempty"`
+	Slack  *SlackReporterConfig  `json:"slack,omitempty"`
+	GitHub *GitHubReporterConfig `json:"github,omitempty"`
 }
 
 type SlackReporterConfig struct {
 	Channel string `json:"channel"`
+	// Owners are the GitHub logins of the owners of the job, who are
+	// mentioned when it fails if the slack reporter maps them to Slack users.
+	Owners []string `json:"owners,omitempty"`
+}
+
+// GitHubReporterConfig holds the GitHub reporting options of a job.
+type GitHubReporterConfig struct {
+	// CheckRun reports the job as a check run instead of a commit status.
+	// Defaults to the check_run_repos of the github_reporter config.
+	CheckRun *bool `json:"check_run,omitempty"`
+}
+
+// Reasons plank records for attempts that ended without the pod
+// reporting a reason of its own.
+const (
+	// PodUnknownReason means the pod went into the Unknown phase,
+	// usually because its node disappeared.
+	PodUnknownReason = "PodUnknown"
+	// PodSchedulingTimeoutReason means the pod was not scheduled in time.
+	PodSchedulingTimeoutReason = "PodSchedulingTimeout"
+	// PodPendingTimeoutReason means the pod stayed pending for too long.
+	PodPendingTimeoutReason = "PodPendingTimeout"
+)
+
+// RetryPolicy configures how plank retries a job whose pod ended because
+// of an infrastructure problem rather than a test failure. Every attempt
+// runs a new pod under the same ProwJob and is recorded in its status.
+//
+// If a retry policy is set, evicted pods and pods that went into the
+// Unknown phase are no longer replaced indefinitely, instead the attempt
+// ends in the error state and is only retried if the policy allows it.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of pods that are run for the
+	// job, including the first one. Values below 2 disable retries.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// States are the outcomes of an attempt that may be retried, only
+	// error and failure are allowed. Defaults to error.
+	States []ProwJobState `json:"states,omitempty"`
+	// Reasons restricts retries to attempts that ended for one of these
+	// reasons. The reason of an attempt is the reason of the pod status
+	// (e.g. Evicted or NodeLost), the termination reason of the first
+	// container that failed (e.g. OOMKilled) or one of PodUnknown,
+	// PodSchedulingTimeout and PodPendingTimeout. If empty, every attempt
+	// in one of the retryable states is retried.
+	Reasons []string `json:"reasons,omitempty"`
+	// Backoff is how long plank waits before it starts the next attempt.
+	// The wait doubles with every further attempt.
+	Backoff *Duration `json:"backoff,omitempty"`
+	// MaxBackoff caps the wait between two attempts.
+	MaxBackoff *Duration `json:"max_backoff,omitempty"`
+}
+
+// Validate ensures all the values set in the RetryPolicy are valid.
+func (r *RetryPolicy) Validate() error {
+	if r == nil {
+		return nil
+	}
+	if r.MaxAttempts < 0 {
+		return fmt.Errorf("max_attempts: %d must be a non-negative number", r.MaxAttempts)
+	}
+	for _, state := range r.States {
+		if state != ErrorState && state != FailureState {
+			return fmt.Errorf("states: %q is not one of %q or %q", state, ErrorState, FailureState)
+		}
+	}
+	if r.Backoff.Get() < 0 || r.MaxBackoff.Get() < 0 {
+		return errors.New("backoff and max_backoff must not be negative")
+	}
+	return nil
+}
+
+// ShouldRetry returns true if an attempt that ended in the given state for
+// the given reason should be followed by another one, where attempts is the
+// number of attempts made so far, including the one that just ended.
+func (r *RetryPolicy) ShouldRetry(attempts int, state ProwJobState, reason string) bool {
+	if r == nil || attempts >= r.MaxAttempts {
+		return false
+	}
+	states := r.States
+	if len(states) == 0 {
+		states = []ProwJobState{ErrorState}
+	}
+	var retryableState bool
+	for _, s := range states {
+		if s == state {
+			retryableState = true
+			break
+		}
+	}
+	if !retryableState {
+		return false
+	}
+	if len(r.Reasons) == 0 {
+		return true
+	}
+	for _, want := range r.Reasons {
+		if want == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// BackoffFor returns how long to wait before the next attempt after the
+// given number of attempts ended.
+func (r *RetryPolicy) BackoffFor(attempts int) time.Duration {
+	if r == nil || attempts < 1 {
+		return 0
+	}
+	backoff := r.Backoff.Get()
+	max := r.MaxBackoff.Get()
+	for i := 1; i < attempts && backoff > 0; i++ {
+		if max > 0 && backoff >= max {
+			break
+		}
+		// Stop doubling before the duration overflows.
+		if backoff > time.Duration(math.MaxInt64/2) {
+			break
+		}
+		backoff *= 2
+	}
+	if max > 0 && backoff > max {
+		backoff = max
+	}
+	return backoff
 }
 
 // Duration is a wrapper around time.Duration that parses times in either
@@ -351,6 +485,25 @@ type DecorationConfig struct {
 	// OauthTokenSecret is a Kubernetes secret that contains the OAuth token,
 	// which is going to be used for fetching a private repository.
 	OauthTokenSecret *OauthTokenSecret `json:"oauth_token_secret,omitempty"`
+
+	// CensorSecrets determines if the sidecar censors the values of the
+	// secrets mounted into the test container from the build log and
+	// the artifacts before uploading them. Defaults to true.
+	CensorSecrets *bool `json:"censor_secrets,omitempty"`
+	// CensoringOptions holds options for censoring secrets.
+	CensoringOptions *CensoringOptions `json:"censoring_options,omitempty"`
+}
+
+// CensoringOptions holds options for censoring the secrets of a job from
+// its build log and artifacts.
+type CensoringOptions struct {
+	// MaxArtifactSize is the size in bytes of the biggest text artifact
+	// that is censored. Bigger text artifacts are not uploaded at all, as
+	// they could leak secrets. Defaults to 100MiB.
+	MaxArtifactSize *int64 `json:"max_artifact_size,omitempty"`
+	// ExcludeArtifacts are glob patterns, relative to the artifacts
+	// directory, of artifacts that are uploaded without censoring.
+	ExcludeArtifacts []string `json:"exclude_artifacts,omitempty"`
 }
 
 // Resources holds resource requests and limits for
@@ -436,6 +589,12 @@ func (d *DecorationConfig) ApplyDefault(def *DecorationConfig) *DecorationConfig
 	if merged.CookiefileSecret == "" {
 		merged.CookiefileSecret = def.CookiefileSecret
 	}
+	if merged.CensorSecrets == nil {
+		merged.CensorSecrets = def.CensorSecrets
+	}
+	if merged.CensoringOptions == nil {
+		merged.CensoringOptions = def.CensoringOptions
+	}
 
 	return &merged
 }
@@ -474,9 +633,24 @@ func (d *DecorationConfig) Validate() error {
 	if d.OauthTokenSecret != nil && len(d.SSHKeySecrets) > 0 {
 		return errors.New("both OAuth token and SSH key secrets are specified")
 	}
+	if d.CensoringOptions != nil {
+		if d.CensoringOptions.MaxArtifactSize != nil && *d.CensoringOptions.MaxArtifactSize < 0 {
+			return errors.New("censoring_options.max_artifact_size must not be negative")
+		}
+		for _, pattern := range d.CensoringOptions.ExcludeArtifacts {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("censoring_options.exclude_artifacts contains invalid glob %q: %v", pattern, err)
+			}
+		}
+	}
 	return nil
 }
 
+// ShouldCensorSecrets determines if the secrets of the job are censored.
+func (d *DecorationConfig) ShouldCensorSecrets() bool {
+	return d.CensorSecrets == nil || *d.CensorSecrets
+}
+
 func (d *Duration) Get() time.Duration {
 	if d == nil {
 		return 0
@@ -655,6 +829,27 @@ type ProwJobStatus struct {
 	// PrevReportStates stores the previous reported prowjob state per reporter
 	// So crier won't make duplicated report attempt
 	PrevReportStates map[string]ProwJobState `json:"prev_report_states,omitempty"`
+
+	// Attempts records the earlier attempts of a job that plank retried
+	// according to its RetryPolicy. The current attempt is described by
+	// the rest of the status.
+	Attempts []ProwJobAttempt `json:"attempts,omitempty"`
+}
+
+// ProwJobAttempt describes a finished attempt of a ProwJob that was retried.
+type ProwJobAttempt struct {
+	// BuildID is the build identifier of the attempt.
+	BuildID string `json:"build_id,omitempty"`
+	// PodName is the name of the pod that ran the attempt.
+	PodName string `json:"pod_name,omitempty"`
+	// CompletionTime is when plank observed the end of the attempt.
+	CompletionTime metav1.Time `json:"completionTime"`
+	// State is the outcome of the attempt.
+	State ProwJobState `json:"state"`
+	// Reason is the reason the pod of the attempt ended for.
+	Reason string `json:"reason,omitempty"`
+	// Description describes the outcome in a human readable way.
+	Description string `json:"description,omitempty"`
 }
 
 // Complete returns true if the prow job has finished
@@ -157,6 +157,26 @@ func TestDecorationDefaulting(t *testing.T) {
 				return def
 			},
 		},
+		{
+			name: "censor_secrets provided",
+			provided: &DecorationConfig{
+				CensorSecrets: &lies,
+			},
+			expected: func(orig, def *DecorationConfig) *DecorationConfig {
+				def.CensorSecrets = orig.CensorSecrets
+				return def
+			},
+		},
+		{
+			name: "censoring options provided",
+			provided: &DecorationConfig{
+				CensoringOptions: &CensoringOptions{ExcludeArtifacts: []string{"*.log"}},
+			},
+			expected: func(orig, def *DecorationConfig) *DecorationConfig {
+				def.CensoringOptions = orig.CensoringOptions
+				return def
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -182,6 +202,8 @@ func TestDecorationDefaulting(t *testing.T) {
 				SSHKeySecrets:        []string{"first", "second"},
 				SSHHostFingerprints:  []string{"primero", "segundo"},
 				SkipCloning:          &truth,
+				CensorSecrets:        &truth,
+				CensoringOptions:     &CensoringOptions{ExcludeArtifacts: []string{"*.json"}},
 			}
 			t.Parallel()
 
@@ -384,3 +406,148 @@ func TestRerunAuthConfigIsAllowAnyone(t *testing.T) {
 		})
 	}
 }
+
+func TestRetryPolicyValidate(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		policy      *RetryPolicy
+		errExpected bool
+	}{
+		{
+			name: "no policy",
+		},
+		{
+			name:   "valid policy",
+			policy: &RetryPolicy{MaxAttempts: 3, States: []ProwJobState{ErrorState, FailureState}, Backoff: &Duration{Duration: time.Minute}},
+		},
+		{
+			name:        "negative max attempts",
+			policy:      &RetryPolicy{MaxAttempts: -1},
+			errExpected: true,
+		},
+		{
+			name:        "success is not retryable",
+			policy:      &RetryPolicy{MaxAttempts: 2, States: []ProwJobState{SuccessState}},
+			errExpected: true,
+		},
+		{
+			name:        "negative backoff",
+			policy:      &RetryPolicy{MaxAttempts: 2, Backoff: &Duration{Duration: -time.Minute}},
+			errExpected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.policy.Validate(); (err != nil) != tc.errExpected {
+				t.Errorf("Expected error %v, got %v", tc.errExpected, err)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		policy   *RetryPolicy
+		attempts int
+		state    ProwJobState
+		reason   string
+		expected bool
+	}{
+		{
+			name:     "no policy",
+			attempts: 1,
+			state:    ErrorState,
+		},
+		{
+			name:     "errors are retried by default",
+			policy:   &RetryPolicy{MaxAttempts: 2},
+			attempts: 1,
+			state:    ErrorState,
+			reason:   "Evicted",
+			expected: true,
+		},
+		{
+			name:     "failures are not retried by default",
+			policy:   &RetryPolicy{MaxAttempts: 2},
+			attempts: 1,
+			state:    FailureState,
+		},
+		{
+			name:     "attempts exhausted",
+			policy:   &RetryPolicy{MaxAttempts: 2},
+			attempts: 2,
+			state:    ErrorState,
+		},
+		{
+			name:     "configured state and reason",
+			policy:   &RetryPolicy{MaxAttempts: 3, States: []ProwJobState{FailureState}, Reasons: []string{"OOMKilled"}},
+			attempts: 2,
+			state:    FailureState,
+			reason:   "OOMKilled",
+			expected: true,
+		},
+		{
+			name:     "reason not configured",
+			policy:   &RetryPolicy{MaxAttempts: 3, Reasons: []string{PodUnknownReason}},
+			attempts: 1,
+			state:    ErrorState,
+			reason:   "Evicted",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.policy.ShouldRetry(tc.attempts, tc.state, tc.reason); actual != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoffFor(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		policy   *RetryPolicy
+		attempts int
+		expected time.Duration
+	}{
+		{
+			name:     "no policy",
+			attempts: 1,
+		},
+		{
+			name:     "first retry",
+			policy:   &RetryPolicy{Backoff: &Duration{Duration: time.Minute}},
+			attempts: 1,
+			expected: time.Minute,
+		},
+		{
+			name:     "doubles with every attempt",
+			policy:   &RetryPolicy{Backoff: &Duration{Duration: time.Minute}},
+			attempts: 3,
+			expected: 4 * time.Minute,
+		},
+		{
+			name:     "capped",
+			policy:   &RetryPolicy{Backoff: &Duration{Duration: time.Minute}, MaxBackoff: &Duration{Duration: 3 * time.Minute}},
+			attempts: 3,
+			expected: 3 * time.Minute,
+		},
+		{
+			name:     "does not overflow",
+			policy:   &RetryPolicy{Backoff: &Duration{Duration: time.Hour}},
+			attempts: 100,
+			expected: time.Hour << 21,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.policy.BackoffFor(tc.attempts); actual != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
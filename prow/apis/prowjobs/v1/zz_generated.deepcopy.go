@@ -26,6 +26,32 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CensoringOptions) DeepCopyInto(out *CensoringOptions) {
+	*out = *in
+	if in.MaxArtifactSize != nil {
+		in, out := &in.MaxArtifactSize, &out.MaxArtifactSize
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ExcludeArtifacts != nil {
+		in, out := &in.ExcludeArtifacts, &out.ExcludeArtifacts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CensoringOptions.
+func (in *CensoringOptions) DeepCopy() *CensoringOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(CensoringOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DecorationConfig) DeepCopyInto(out *DecorationConfig) {
 	*out = *in
@@ -74,6 +100,16 @@ func (in *DecorationConfig) DeepCopyInto(out *DecorationConfig) {
 		*out = new(OauthTokenSecret)
 		**out = **in
 	}
+	if in.CensorSecrets != nil {
+		in, out := &in.CensorSecrets, &out.CensorSecrets
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CensoringOptions != nil {
+		in, out := &in.CensoringOptions, &out.CensoringOptions
+		*out = new(CensoringOptions)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -126,6 +162,27 @@ func (in *GCSConfiguration) DeepCopy() *GCSConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubReporterConfig) DeepCopyInto(out *GitHubReporterConfig) {
+	*out = *in
+	if in.CheckRun != nil {
+		in, out := &in.CheckRun, &out.CheckRun
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubReporterConfig.
+func (in *GitHubReporterConfig) DeepCopy() *GitHubReporterConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubReporterConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitHubTeamSlug) DeepCopyInto(out *GitHubTeamSlug) {
 	*out = *in
@@ -202,6 +259,23 @@ func (in *ProwJob) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProwJobAttempt) DeepCopyInto(out *ProwJobAttempt) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProwJobAttempt.
+func (in *ProwJobAttempt) DeepCopy() *ProwJobAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(ProwJobAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProwJobList) DeepCopyInto(out *ProwJobList) {
 	*out = *in
@@ -250,6 +324,11 @@ func (in *ProwJobSpec) DeepCopyInto(out *ProwJobSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PodSpec != nil {
 		in, out := &in.PodSpec, &out.PodSpec
 		*out = new(corev1.PodSpec)
@@ -312,6 +391,13 @@ func (in *ProwJobStatus) DeepCopyInto(out *ProwJobStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]ProwJobAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -368,7 +454,12 @@ func (in *ReporterConfig) DeepCopyInto(out *ReporterConfig) {
 	if in.Slack != nil {
 		in, out := &in.Slack, &out.Slack
 		*out = new(SlackReporterConfig)
-		**out = **in
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GitHub != nil {
+		in, out := &in.GitHub, &out.GitHub
+		*out = new(GitHubReporterConfig)
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
@@ -455,9 +546,50 @@ func (in *Resources) DeepCopy() *Resources {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.States != nil {
+		in, out := &in.States, &out.States
+		*out = make([]ProwJobState, len(*in))
+		copy(*out, *in)
+	}
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Backoff != nil {
+		in, out := &in.Backoff, &out.Backoff
+		*out = new(Duration)
+		**out = **in
+	}
+	if in.MaxBackoff != nil {
+		in, out := &in.MaxBackoff, &out.MaxBackoff
+		*out = new(Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SlackReporterConfig) DeepCopyInto(out *SlackReporterConfig) {
 	*out = *in
+	if in.Owners != nil {
+		in, out := &in.Owners, &out.Owners
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
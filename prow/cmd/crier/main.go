@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"flag"
+	"io/ioutil"
 	"os"
 	"time"
 
@@ -27,6 +29,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"google.golang.org/api/option"
 
+	pkgio "k8s.io/test-infra/pkg/io"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	v1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	prowjobinformer "k8s.io/test-infra/prow/client/informers/externalversions"
@@ -39,6 +42,7 @@ import (
 	githubreporter "k8s.io/test-infra/prow/crier/reporters/github"
 	pubsubreporter "k8s.io/test-infra/prow/crier/reporters/pubsub"
 	slackreporter "k8s.io/test-infra/prow/crier/reporters/slack"
+	webhookreporter "k8s.io/test-infra/prow/crier/reporters/webhook"
 	prowflagutil "k8s.io/test-infra/prow/flagutil"
 	gerritclient "k8s.io/test-infra/prow/gerrit/client"
 	"k8s.io/test-infra/prow/interrupts"
@@ -62,17 +66,22 @@ type options struct {
 	configPath    string
 	jobConfigPath string
 
-	gerritWorkers int
-	pubsubWorkers int
-	githubWorkers int
-	slackWorkers  int
-	gcsWorkers    int
-	k8sGCSWorkers int
+	gerritWorkers  int
+	pubsubWorkers  int
+	githubWorkers  int
+	slackWorkers   int
+	gcsWorkers     int
+	k8sGCSWorkers  int
+	webhookWorkers int
 
 	slackTokenFile string
 
+	webhookHMACSecretFile string
+
 	gcsCredentialsFile string
 
+	githubJUnitFromGCS bool
+
 	k8sReportFraction float64
 
 	dryrun      bool
@@ -91,7 +100,7 @@ func (o *options) validate() error {
 		o.gerritWorkers = 1
 	}
 
-	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers+o.slackWorkers+o.gcsWorkers+o.k8sGCSWorkers <= 0 {
+	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers+o.slackWorkers+o.gcsWorkers+o.k8sGCSWorkers+o.webhookWorkers <= 0 {
 		return errors.New("crier need to have at least one report worker to start")
 	}
 
@@ -128,6 +137,14 @@ func (o *options) validate() error {
 	return nil
 }
 
+func (o *options) storageClient() (*storage.Client, error) {
+	var opts []option.ClientOption
+	if o.gcsCredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(o.gcsCredentialsFile))
+	}
+	return storage.NewClient(context.Background(), opts...)
+}
+
 func (o *options) parseArgs(fs *flag.FlagSet, args []string) error {
 
 	o.gerritProjects = gerritclient.ProjectsFlag{}
@@ -140,16 +157,19 @@ func (o *options) parseArgs(fs *flag.FlagSet, args []string) error {
 	fs.IntVar(&o.slackWorkers, "slack-workers", 0, "Number of Slack report workers (0 means disabled)")
 	fs.IntVar(&o.gcsWorkers, "gcs-workers", 0, "Number of GCS report workers (0 means disabled)")
 	fs.IntVar(&o.k8sGCSWorkers, "kubernetes-gcs-workers", 0, "Number of Kubernetes-specific GCS report workers (0 means disabled)")
+	fs.IntVar(&o.webhookWorkers, "webhook-workers", 0, "Number of webhook report workers (0 means disabled)")
 	fs.Float64Var(&o.k8sReportFraction, "kubernetes-report-fraction", 1.0, "Approximate portion of jobs to report pod information for, if kubernetes-gcs-workers are enabled (0 - > none, 1.0 -> all)")
-	fs.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "Location of the GCS credentials file, if gcs-workers is non-zero")
+	fs.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "Location of the GCS credentials file, if gcs-workers is non-zero or github-junit-from-gcs is set")
+	fs.BoolVar(&o.githubJUnitFromGCS, "github-junit-from-gcs", false, "Read the JUnit results of failed jobs from GCS to annotate the check runs of the github reporter")
 	fs.StringVar(&o.slackTokenFile, "slack-token-file", "", "Path to a Slack token file")
+	fs.StringVar(&o.webhookHMACSecretFile, "webhook-hmac-secret-file", "", "Path to the HMAC secret to sign the events of the webhook reporter with, leave empty to not sign them")
 	fs.StringVar(&o.reportAgent, "report-agent", "", "Only report specified agent - empty means report to all agents (effective for github and Slack only)")
 
 	fs.StringVar(&o.configPath, "config-path", "", "Path to config.yaml.")
 	fs.StringVar(&o.jobConfigPath, "job-config-path", "", "Path to prow job configs.")
 
 	// TODO(krzyzacy): implement dryrun for gerrit/pubsub
-	fs.BoolVar(&o.dryrun, "dry-run", false, "Run in dry-run mode, not doing actual report (effective for github and Slack only)")
+	fs.BoolVar(&o.dryrun, "dry-run", false, "Run in dry-run mode, not doing actual report (effective for github, Slack and webhook only)")
 
 	o.github.AddFlags(fs)
 	o.client.AddFlags(fs)
@@ -204,6 +224,7 @@ func main() {
 		if err != nil {
 			logrus.WithError(err).Fatal("failed to create slackreporter")
 		}
+		interrupts.TickLiteral(slackReporter.SendDigests, time.Minute)
 		controllers = append(
 			controllers,
 			crier.NewController(
@@ -214,6 +235,32 @@ func main() {
 				o.slackWorkers))
 	}
 
+	if o.webhookWorkers > 0 {
+		if len(cfg().WebhookReporter.Endpoints) == 0 {
+			logrus.Fatal("webhookreporter is enabled but has no endpoints")
+		}
+		var hmacSecret []byte
+		if o.webhookHMACSecretFile != "" {
+			secret, err := ioutil.ReadFile(o.webhookHMACSecretFile)
+			if err != nil {
+				logrus.WithError(err).Fatal("failed to read --webhook-hmac-secret-file")
+			}
+			hmacSecret = bytes.TrimSpace(secret)
+		}
+		webhookConfig := func() config.WebhookReporter {
+			return cfg().WebhookReporter
+		}
+		webhookReporter := webhookreporter.NewReporter(webhookConfig, hmacSecret, o.dryrun)
+		controllers = append(
+			controllers,
+			crier.NewController(
+				prowjobClientset,
+				kube.RateLimiter(webhookReporter.GetName()),
+				prowjobInformerFactory.Prow().V1().ProwJobs(),
+				webhookReporter,
+				o.webhookWorkers))
+	}
+
 	if o.gerritWorkers > 0 {
 		informer := prowjobInformerFactory.Prow().V1().ProwJobs()
 		gerritReporter, err := gerritreporter.NewReporter(o.cookiefilePath, o.gerritProjects, informer.Lister())
@@ -256,7 +303,20 @@ func main() {
 			logrus.WithError(err).Fatal("Error getting GitHub client.")
 		}
 
-		githubReporter := githubreporter.NewReporter(githubClient, cfg, v1.ProwJobAgent(o.reportAgent))
+		var junitReader githubreporter.JUnitReader
+		if o.githubJUnitFromGCS {
+			s, err := o.storageClient()
+			if err != nil {
+				logrus.WithError(err).Fatal("Error creating storage client for github workers.")
+			}
+			opener, err := pkgio.NewOpenerFromGCSClient(s, "")
+			if err != nil {
+				logrus.WithError(err).Fatal("Error creating opener for github workers.")
+			}
+			junitReader = gcsreporter.NewJUnitReader(cfg, opener)
+		}
+
+		githubReporter := githubreporter.NewReporter(githubClient, cfg, v1.ProwJobAgent(o.reportAgent), junitReader)
 		controllers = append(
 			controllers,
 			crier.NewController(
@@ -268,13 +328,7 @@ func main() {
 	}
 
 	if o.gcsWorkers > 0 || o.k8sGCSWorkers > 0 {
-		var s *storage.Client
-		var opts []option.ClientOption
-		if o.gcsCredentialsFile != "" {
-			opts = append(opts, option.WithCredentialsFile(o.gcsCredentialsFile))
-		}
-		s, err = storage.NewClient(context.Background(), opts...)
-
+		s, err := o.storageClient()
 		if err != nil {
 			logrus.WithError(err).Fatal("Error creating storage client for gcs workers.")
 		}
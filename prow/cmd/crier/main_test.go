@@ -126,6 +126,19 @@ func TestOptions(t *testing.T) {
 				k8sReportFraction: 1.0,
 			},
 		},
+		//Webhook Reporter
+		{
+			name: "webhook workers, sets workers and secret",
+			args: []string{"--webhook-workers=2", "--webhook-hmac-secret-file=/etc/webhook/hmac", "--config-path=foo"},
+			expected: &options{
+				webhookWorkers:        2,
+				webhookHMACSecretFile: "/etc/webhook/hmac",
+				configPath:            "foo",
+				github:                defaultGitHubOptions,
+				gerritProjects:        defaultGerritProjects,
+				k8sReportFraction:     1.0,
+			},
+		},
 		{
 			name: "Dry run with no --deck-url, rejects",
 			args: []string{"--slack-workers=13", "--slack-token-file=/bar/baz", "--config-path=foo", "--dry-run"},
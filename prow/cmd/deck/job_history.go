@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/url"
 	"path"
@@ -29,9 +30,10 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
-	"google.golang.org/api/iterator"
+
+	pkgio "k8s.io/test-infra/pkg/io"
+	"k8s.io/test-infra/pkg/io/providers"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/pod-utils/gcs"
 )
@@ -44,12 +46,14 @@ const (
 	// ** Job history assumes the GCS layout specified here:
 	// https://github.com/kubernetes/test-infra/tree/master/gubernator#gcs-bucket-layout
 	logsPrefix     = gcs.NonPRLogs
-	spyglassPrefix = "/view/gcs"
+	spyglassPrefix = "/view"
+	gcsKeyType     = "gcs"
+	s3KeyType      = providers.S3
 	emptyID        = int64(-1) // indicates no build id was specified
 )
 
 var (
-	prefixRe = regexp.MustCompile("gs://.*?/")
+	prefixRe = regexp.MustCompile("^[a-z0-9]+://.*?/")
 	linkRe   = regexp.MustCompile("/([0-9]+)\\.txt$")
 )
 
@@ -68,15 +72,26 @@ type buildData struct {
 // storageBucket is an abstraction for unit testing
 type storageBucket interface {
 	getName() string
+	getStorageProvider() string
 	listSubDirs(prefix string) ([]string, error)
 	listAll(prefix string) ([]string, error)
 	readObject(key string) ([]byte, error)
 }
 
-// gcsBucket is our real implementation of storageBucket
-type gcsBucket struct {
-	name string
-	*storage.BucketHandle
+// blobStorageBucket is our real implementation of storageBucket.
+// It goes through an io.Opener, so it works with every storage provider the opener supports.
+type blobStorageBucket struct {
+	name            string
+	storageProvider string
+	pkgio.Opener
+}
+
+func newBlobStorageBucket(bucketName, storageProvider string, opener pkgio.Opener) blobStorageBucket {
+	return blobStorageBucket{
+		name:            bucketName,
+		storageProvider: storageProvider,
+		Opener:          opener,
+	}
 }
 
 type jobHistoryTemplate struct {
@@ -89,19 +104,53 @@ type jobHistoryTemplate struct {
 	Builds       []buildData
 }
 
-func (bucket gcsBucket) readObject(key string) ([]byte, error) {
-	obj := bucket.Object(key)
-	rc, err := obj.NewReader(context.Background())
+// fullPath returns the path of key including storage provider and bucket, e.g. gs://bucket/key
+func (bucket blobStorageBucket) fullPath(key string) string {
+	return fmt.Sprintf("%s://%s/%s", bucket.storageProvider, bucket.name, key)
+}
+
+func (bucket blobStorageBucket) readObject(key string) ([]byte, error) {
+	rc, err := bucket.Reader(context.Background(), bucket.fullPath(key))
 	if err != nil {
-		return []byte{}, fmt.Errorf("failed to get reader for GCS object: %v", err)
+		return []byte{}, fmt.Errorf("failed to get reader for object %s: %v", key, err)
 	}
+	defer rc.Close()
 	return ioutil.ReadAll(rc)
 }
 
-func (bucket gcsBucket) getName() string {
+func (bucket blobStorageBucket) getName() string {
 	return bucket.name
 }
 
+func (bucket blobStorageBucket) getStorageProvider() string {
+	return bucket.storageProvider
+}
+
+// bucketPath returns the bucket part of job-history links.
+// GCS buckets are linked without storage provider to keep existing links stable.
+func bucketPath(bucket storageBucket) string {
+	if bucket.getStorageProvider() == providers.GS {
+		return bucket.getName()
+	}
+	return path.Join(bucket.getStorageProvider(), bucket.getName())
+}
+
+// spyglassLink returns the spyglass link of the job run stored in dir.
+// Spyglass can only open runs stored in GCS and S3, so the link is empty for
+// other storage providers.
+func spyglassLink(bucket storageBucket, dir string) string {
+	var keyType string
+	switch bucket.getStorageProvider() {
+	case providers.GS:
+		keyType = gcsKeyType
+	case providers.S3:
+		keyType = s3KeyType
+	default:
+		return ""
+	}
+	return path.Join(spyglassPrefix, keyType, bucket.getName(), dir)
+}
+
 func readLatestBuild(bucket storageBucket, root string) (int64, error) {
 	key := path.Join(root, latestBuildFile)
 	data, err := bucket.readObject(key)
@@ -116,25 +165,25 @@ func readLatestBuild(bucket storageBucket, root string) (int64, error) {
 }
 
 // resolve sym links into the actual log directory for a particular test run
-func (bucket gcsBucket) resolveSymLink(symLink string) (string, error) {
+func (bucket blobStorageBucket) resolveSymLink(symLink string) (string, error) {
 	data, err := bucket.readObject(symLink)
 	if err != nil {
 		return "", fmt.Errorf("failed to read %s: %v", symLink, err)
 	}
-	// strip gs://<bucket-name> from global address `u`
+	// strip <storage-provider>://<bucket-name> from global address `u`
 	u := strings.TrimSpace(string(data))
 	return prefixRe.ReplaceAllString(u, ""), nil
 }
 
-func (bucket gcsBucket) spyglassLink(root, id string) (string, error) {
+func (bucket blobStorageBucket) spyglassLink(root, id string) (string, error) {
 	p, err := bucket.getPath(root, id, "")
 	if err != nil {
 		return "", fmt.Errorf("failed to get path: %v", err)
 	}
-	return path.Join(spyglassPrefix, bucket.getName(), p), nil
+	return spyglassLink(bucket, p), nil
 }
 
-func (bucket gcsBucket) getPath(root, id, fname string) (string, error) {
+func (bucket blobStorageBucket) getPath(root, id, fname string) (string, error) {
 	if strings.HasPrefix(root, logsPrefix) {
 		return path.Join(root, id, fname), nil
 	}
@@ -159,57 +208,60 @@ func readJSON(bucket storageBucket, key string, data interface{}) error {
 	return nil
 }
 
-// Lists the GCS "directory paths" immediately under prefix.
-func (bucket gcsBucket) listSubDirs(prefix string) ([]string, error) {
+// Lists the "directory paths" immediately under prefix.
+func (bucket blobStorageBucket) listSubDirs(prefix string) ([]string, error) {
 	if !strings.HasSuffix(prefix, "/") {
 		prefix += "/"
 	}
 	dirs := []string{}
-	it := bucket.Objects(context.Background(), &storage.Query{
-		Prefix:    prefix,
-		Delimiter: "/",
-	})
+	ctx := context.Background()
+	it, err := bucket.Iterator(ctx, bucket.fullPath(prefix), "/")
+	if err != nil {
+		return dirs, err
+	}
 	for {
-		attrs, err := it.Next()
-		if err == iterator.Done {
+		attrs, err := it.Next(ctx)
+		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			return dirs, err
 		}
-		if attrs.Prefix != "" {
-			dirs = append(dirs, attrs.Prefix)
+		if attrs.IsDir {
+			dirs = append(dirs, strings.TrimPrefix(attrs.Name, bucket.fullPath("")))
 		}
 	}
 	return dirs, nil
 }
 
-// Lists all GCS keys with given prefix.
-func (bucket gcsBucket) listAll(prefix string) ([]string, error) {
+// Lists all keys with given prefix.
+func (bucket blobStorageBucket) listAll(prefix string) ([]string, error) {
 	keys := []string{}
-	it := bucket.Objects(context.Background(), &storage.Query{
-		Prefix: prefix,
-	})
+	ctx := context.Background()
+	it, err := bucket.Iterator(ctx, bucket.fullPath(prefix), "")
+	if err != nil {
+		return keys, err
+	}
 	for {
-		attrs, err := it.Next()
-		if err == iterator.Done {
+		attrs, err := it.Next(ctx)
+		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			return keys, err
 		}
-		keys = append(keys, attrs.Name)
+		keys = append(keys, strings.TrimPrefix(attrs.Name, bucket.fullPath("")))
 	}
 	return keys, nil
 }
 
 // Gets all build ids for a job.
-func (bucket gcsBucket) listBuildIDs(root string) ([]int64, error) {
+func (bucket blobStorageBucket) listBuildIDs(root string) ([]int64, error) {
 	ids := []int64{}
 	if strings.HasPrefix(root, logsPrefix) {
 		dirs, err := bucket.listSubDirs(root)
 		if err != nil {
-			return ids, fmt.Errorf("failed to list GCS directories: %v", err)
+			return ids, fmt.Errorf("failed to list directories: %v", err)
 		}
 		for _, dir := range dirs {
 			leaf := path.Base(dir)
@@ -217,13 +269,13 @@ func (bucket gcsBucket) listBuildIDs(root string) ([]int64, error) {
 			if err == nil {
 				ids = append(ids, i)
 			} else {
-				logrus.WithField("gcs-path", dir).Warningf("unrecognized directory name (expected int64): %s", leaf)
+				logrus.WithField("path", dir).Warningf("unrecognized directory name (expected int64): %s", leaf)
 			}
 		}
 	} else {
 		keys, err := bucket.listAll(root)
 		if err != nil {
-			return ids, fmt.Errorf("failed to list GCS keys: %v", err)
+			return ids, fmt.Errorf("failed to list keys: %v", err)
 		}
 		for _, key := range keys {
 			matches := linkRe.FindStringSubmatch(key)
@@ -240,22 +292,31 @@ func (bucket gcsBucket) listBuildIDs(root string) ([]int64, error) {
 	return ids, nil
 }
 
-func parseJobHistURL(url *url.URL) (bucketName, root string, buildID int64, err error) {
+// parseJobHistURL parses job history URLs. Expects one of these formats:
+// /job-history/<gcs-bucket-name>/<root>
+// /job-history/<storage-provider>/<bucket-name>/<root>
+func parseJobHistURL(url *url.URL) (storageProvider, bucketName, root string, buildID int64, err error) {
 	buildID = emptyID
 	p := strings.TrimPrefix(url.Path, "/job-history/")
+	storageProvider = providers.GS
+	// Storage providers are shorter than the 3 characters a GCS bucket name needs at least,
+	// so they can't be confused with a bucket name.
+	if s := strings.SplitN(p, "/", 2); len(s) == 2 && (s[0] == providers.GS || s[0] == providers.S3) {
+		storageProvider, p = s[0], s[1]
+	}
 	s := strings.SplitN(p, "/", 2)
 	if len(s) < 2 {
-		err = fmt.Errorf("invalid path (expected /job-history/<gcs-path>): %v", url.Path)
+		err = fmt.Errorf("invalid path (expected /job-history/[<storage-provider>/]<bucket-path>): %v", url.Path)
 		return
 	}
 	bucketName = s[0]
-	root = s[1] // `root` is the root GCS "directory" prefix for this job's results
+	root = s[1] // `root` is the root "directory" prefix for this job's results
 	if bucketName == "" {
-		err = fmt.Errorf("missing GCS bucket name: %v", url.Path)
+		err = fmt.Errorf("missing bucket name: %v", url.Path)
 		return
 	}
 	if root == "" {
-		err = fmt.Errorf("invalid GCS path for job: %v", url.Path)
+		err = fmt.Errorf("invalid path for job: %v", url.Path)
 		return
 	}
 
@@ -352,17 +413,17 @@ func (a int64slice) Len() int           { return len(a) }
 func (a int64slice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a int64slice) Less(i, j int) bool { return a[i] < a[j] }
 
-// Gets job history from the GCS bucket specified in config.
-func getJobHistory(url *url.URL, config *config.Config, gcsClient *storage.Client) (jobHistoryTemplate, error) {
+// Gets job history from the bucket specified in config.
+func getJobHistory(url *url.URL, config *config.Config, opener pkgio.Opener) (jobHistoryTemplate, error) {
 	start := time.Now()
 	tmpl := jobHistoryTemplate{}
 
-	bucketName, root, top, err := parseJobHistURL(url)
+	storageProvider, bucketName, root, top, err := parseJobHistURL(url)
 	if err != nil {
 		return tmpl, fmt.Errorf("invalid url %s: %v", url.String(), err)
 	}
 	tmpl.Name = root
-	bucket := gcsBucket{bucketName, gcsClient.Bucket(bucketName)}
+	bucket := newBlobStorageBucket(bucketName, storageProvider, opener)
 
 	latest, err := readLatestBuild(bucket, root)
 	if err != nil {
@@ -17,36 +17,62 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"net/url"
+	"reflect"
 	"testing"
+
+	pkgio "k8s.io/test-infra/pkg/io"
 )
 
 func TestJobHistURL(t *testing.T) {
 	cases := []struct {
-		name    string
-		address string
-		bktName string
-		root    string
-		id      int64
-		expErr  bool
+		name            string
+		address         string
+		storageProvider string
+		bktName         string
+		root            string
+		id              int64
+		expErr          bool
 	}{
 		{
-			address: "http://www.example.com/job-history/foo-bucket/logs/bar-e2e",
-			bktName: "foo-bucket",
-			root:    "logs/bar-e2e",
-			id:      emptyID,
+			address:         "http://www.example.com/job-history/foo-bucket/logs/bar-e2e",
+			storageProvider: "gs",
+			bktName:         "foo-bucket",
+			root:            "logs/bar-e2e",
+			id:              emptyID,
+		},
+		{
+			address:         "http://www.example.com/job-history/foo-bucket/logs/bar-e2e?buildId=",
+			storageProvider: "gs",
+			bktName:         "foo-bucket",
+			root:            "logs/bar-e2e",
+			id:              emptyID,
+		},
+		{
+			address:         "http://www.example.com/job-history/foo-bucket/logs/bar-e2e?buildId=123456789123456789",
+			storageProvider: "gs",
+			bktName:         "foo-bucket",
+			root:            "logs/bar-e2e",
+			id:              123456789123456789,
+		},
+		{
+			address:         "http://www.example.com/job-history/gs/foo-bucket/logs/bar-e2e",
+			storageProvider: "gs",
+			bktName:         "foo-bucket",
+			root:            "logs/bar-e2e",
+			id:              emptyID,
 		},
 		{
-			address: "http://www.example.com/job-history/foo-bucket/logs/bar-e2e?buildId=",
-			bktName: "foo-bucket",
-			root:    "logs/bar-e2e",
-			id:      emptyID,
+			address:         "http://www.example.com/job-history/s3/foo-bucket/pr-logs/directory/bar-e2e?buildId=42",
+			storageProvider: "s3",
+			bktName:         "foo-bucket",
+			root:            "pr-logs/directory/bar-e2e",
+			id:              42,
 		},
 		{
-			address: "http://www.example.com/job-history/foo-bucket/logs/bar-e2e?buildId=123456789123456789",
-			bktName: "foo-bucket",
-			root:    "logs/bar-e2e",
-			id:      123456789123456789,
+			address: "http://www.example.com/job-history/s3/foo-bucket",
+			expErr:  true,
 		},
 		{
 			address: "http://www.example.com/job-history/foo-bucket",
@@ -67,7 +93,7 @@ func TestJobHistURL(t *testing.T) {
 	}
 	for _, tc := range cases {
 		u, _ := url.Parse(tc.address)
-		bktName, root, id, err := parseJobHistURL(u)
+		storageProvider, bktName, root, id, err := parseJobHistURL(u)
 		if tc.expErr {
 			if err == nil && tc.expErr {
 				t.Errorf("parsing %q: expected error", tc.address)
@@ -77,6 +103,9 @@ func TestJobHistURL(t *testing.T) {
 		if err != nil {
 			t.Errorf("parsing %q: unexpected error: %v", tc.address, err)
 		}
+		if storageProvider != tc.storageProvider {
+			t.Errorf("parsing %q: expected storage provider %s, got %s", tc.address, tc.storageProvider, storageProvider)
+		}
 		if bktName != tc.bktName {
 			t.Errorf("parsing %q: expected bucket %s, got %s", tc.address, tc.bktName, bktName)
 		}
@@ -176,3 +205,74 @@ func TestLinkID(t *testing.T) {
 		}
 	}
 }
+
+func TestBlobStorageBucket(t *testing.T) {
+	objects := map[string]string{
+		"logs/bar-e2e/latest-build.txt":           "456",
+		"logs/bar-e2e/123/started.json":           `{"timestamp": 10}`,
+		"logs/bar-e2e/456/started.json":           `{"timestamp": 20}`,
+		"pr-logs/directory/bar-e2e/789.txt":       "s3://blob-bucket/pr-logs/pull/1/bar-e2e/789",
+		"pr-logs/pull/1/bar-e2e/789/started.json": `{"timestamp": 30}`,
+	}
+	// mem:// behaves like s3:// or gs:// buckets but doesn't need credentials
+	opener, err := pkgio.NewOpenerFromGCSClient(nil, "")
+	if err != nil {
+		t.Fatalf("failed to create opener: %v", err)
+	}
+	bucket := newBlobStorageBucket("blob-bucket", "mem", opener)
+	for key, content := range objects {
+		w, err := opener.Writer(context.Background(), bucket.fullPath(key))
+		if err != nil {
+			t.Fatalf("failed to open writer: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", key, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("failed to close %s: %v", key, err)
+		}
+	}
+
+	latest, err := readLatestBuild(bucket, "logs/bar-e2e")
+	if err != nil {
+		t.Fatalf("failed to read latest build: %v", err)
+	}
+	if latest != 456 {
+		t.Errorf("expected latest build 456, got %d", latest)
+	}
+	dirs, err := bucket.listSubDirs("logs/bar-e2e")
+	if err != nil {
+		t.Fatalf("failed to list sub dirs: %v", err)
+	}
+	if expected := []string{"logs/bar-e2e/123/", "logs/bar-e2e/456/"}; !reflect.DeepEqual(dirs, expected) {
+		t.Errorf("expected sub dirs %v, got %v", expected, dirs)
+	}
+	ids, err := bucket.listBuildIDs("logs/bar-e2e")
+	if err != nil {
+		t.Fatalf("failed to list build ids: %v", err)
+	}
+	if expected := []int64{123, 456}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected build ids %v, got %v", expected, ids)
+	}
+	ids, err = bucket.listBuildIDs("pr-logs/directory/bar-e2e")
+	if err != nil {
+		t.Fatalf("failed to list build ids from symlinks: %v", err)
+	}
+	if expected := []int64{789}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected build ids %v, got %v", expected, ids)
+	}
+	dir, err := bucket.getPath("pr-logs/directory/bar-e2e", "789", "")
+	if err != nil {
+		t.Fatalf("failed to resolve the path of a build: %v", err)
+	}
+	if expected := "pr-logs/pull/1/bar-e2e/789"; dir != expected {
+		t.Errorf("expected build path %q, got %q", expected, dir)
+	}
+	link, err := bucket.spyglassLink("pr-logs/directory/bar-e2e", "789")
+	if err != nil {
+		t.Fatalf("failed to get spyglass link: %v", err)
+	}
+	if link != "" {
+		t.Errorf("expected no spyglass link outside of GCS, got %q", link)
+	}
+}
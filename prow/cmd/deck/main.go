@@ -53,6 +53,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/yaml"
 
+	pkgio "k8s.io/test-infra/pkg/io"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	prowv1 "k8s.io/test-infra/prow/client/clientset/versioned/typed/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
@@ -119,6 +120,7 @@ type options struct {
 	gcsCredentialsFile    string
 	gcsNoAuth             bool
 	gcsCookieAuth         bool
+	s3CredentialsFile     string
 	rerunCreatesJob       bool
 	allowInsecure         bool
 	dryRun                bool
@@ -189,7 +191,8 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	fs.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "Path to the GCS credentials file")
 	fs.BoolVar(&o.gcsNoAuth, "gcs-no-auth", false, "Whether to use anonymous auth for GCP. Requires when running outside of GCP and not setting gcs-credentials-file")
 	fs.BoolVar(&o.gcsCookieAuth, "gcs-cookie-auth", false, "Use storage.cloud.google.com instead of signed URLs")
-	fs.BoolVar(&o.rerunCreatesJob, "rerun-creates-job", false, "Change the re-run option in Deck to actually create the job. **WARNING:** Only use this with non-public deck instances, otherwise strangers can DOS your Prow instance")
+	fs.StringVar(&o.s3CredentialsFile, "s3-credentials-file", "", "Path to the S3 credentials file, used for job and PR history of jobs uploading to s3:// buckets. For the exact format see https://github.com/kubernetes/test-infra/blob/master/pkg/io/providers/providers.go")
+	fs.BoolVar(&o.rerunCreatesJob, "rerun-creates-job", false, "Change the re-run option in Deck to actually create the job and allow aborting running jobs. **WARNING:** Only use this with non-public deck instances, otherwise strangers can DOS your Prow instance")
 	fs.BoolVar(&o.allowInsecure, "allow-insecure", false, "Allows insecure requests for CSRF and GitHub oauth.")
 	fs.BoolVar(&o.dryRun, "dry-run", false, "Whether or not to make mutating API calls to GitHub.")
 	fs.StringVar(&o.pluginConfig, "plugin-config", "", "Path to plugin config file, probably /etc/plugins/plugins.yaml")
@@ -218,6 +221,7 @@ func init() {
 }
 
 var simplifier = simplifypath.NewSimplifier(l("", // shadow element mimicing the root
+	l("abort"),
 	l("badge.svg"),
 	l("command-help"),
 	l("config"),
@@ -659,6 +663,7 @@ func prodOnlyMain(cfg config.Getter, pluginAgent *plugins.ConfigAgent, authCfgGe
 	}
 
 	mux.Handle("/rerun", gziphandler.GzipHandler(handleRerun(prowJobClient, o.rerunCreatesJob, authCfgGetter, goa, githuboauth.NewAuthenticatedUserIdentifier(&o.github), githubClient, pluginAgent, logrus.WithField("handler", "/rerun"))))
+	mux.Handle("/abort", gziphandler.GzipHandler(handleAbort(prowJobClient, o.rerunCreatesJob, authCfgGetter, goa, githuboauth.NewAuthenticatedUserIdentifier(&o.github), githubClient, pluginAgent, logrus.WithField("handler", "/abort"))))
 
 	// optionally inject http->https redirect handler when behind loadbalancer
 	if o.redirectHTTPTo != "" {
@@ -699,14 +704,19 @@ func initSpyglass(cfg config.Getter, o options, mux *http.ServeMux, ja *jobs.Job
 	if err != nil {
 		logrus.WithError(err).Fatal("Error getting GCS client")
 	}
-	sg := spyglass.New(ctx, ja, cfg, c, o.gcsCredentialsFile, o.gcsCookieAuth)
+	opener, err := pkgio.NewOpenerFromGCSClient(c, o.s3CredentialsFile)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating opener")
+	}
+	sg := spyglass.New(ctx, ja, cfg, opener, c, o.gcsCredentialsFile, o.gcsCookieAuth)
 	sg.Start()
 
 	mux.Handle("/spyglass/static/", http.StripPrefix("/spyglass/static", staticHandlerFromDir(o.spyglassFilesLocation)))
 	mux.Handle("/spyglass/lens/", gziphandler.GzipHandler(http.StripPrefix("/spyglass/lens/", handleArtifactView(o, sg, cfg))))
+	mux.Handle("/view/compare", gziphandler.GzipHandler(handleCompareJobViews(sg, cfg, o, logrus.WithField("handler", "/view/compare"))))
 	mux.Handle("/view/", gziphandler.GzipHandler(handleRequestJobViews(sg, cfg, o, logrus.WithField("handler", "/view"))))
-	mux.Handle("/job-history/", gziphandler.GzipHandler(handleJobHistory(o, cfg, c, logrus.WithField("handler", "/job-history"))))
-	mux.Handle("/pr-history/", gziphandler.GzipHandler(handlePRHistory(o, cfg, c, gitHubClient, gitClient, logrus.WithField("handler", "/pr-history"))))
+	mux.Handle("/job-history/", gziphandler.GzipHandler(handleJobHistory(o, cfg, opener, logrus.WithField("handler", "/job-history"))))
+	mux.Handle("/pr-history/", gziphandler.GzipHandler(handlePRHistory(o, cfg, opener, gitHubClient, gitClient, logrus.WithField("handler", "/pr-history"))))
 }
 
 func loadToken(file string) ([]byte, error) {
@@ -793,9 +803,19 @@ func handleProwJobs(ja *jobs.JobAgent, log *logrus.Entry) http.HandlerFunc {
 			}
 		}
 
+		// Only expose the queue positions of the jobs we serve.
+		queuePositions := map[string]int{}
+		allQueuePositions := ja.QueuePositions()
+		for _, job := range jobs {
+			if position, queued := allQueuePositions[job.Name]; queued {
+				queuePositions[job.Name] = position
+			}
+		}
+
 		jd, err := json.Marshal(struct {
-			Items []prowapi.ProwJob `json:"items"`
-		}{jobs})
+			Items          []prowapi.ProwJob `json:"items"`
+			QueuePositions map[string]int    `json:"queue_positions,omitempty"`
+		}{jobs, queuePositions})
 		if err != nil {
 			log.WithError(err).Error("Error marshaling jobs.")
 			jd = []byte("{}")
@@ -857,10 +877,17 @@ func handleBadge(ja *jobs.JobAgent) http.HandlerFunc {
 //
 // Example:
 // - /job-history/kubernetes-jenkins/logs/ci-kubernetes-e2e-prow-canary
-func handleJobHistory(o options, cfg config.Getter, gcsClient *storage.Client, log *logrus.Entry) http.HandlerFunc {
+//
+// Buckets of other storage providers are prefixed with the storage provider:
+//
+// /job-history/<storage-provider>/<bucket-name>/logs/<job-name>
+//
+// Example:
+// - /job-history/s3/prow-logs/logs/ci-kubernetes-e2e-prow-canary
+func handleJobHistory(o options, cfg config.Getter, opener pkgio.Opener, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
-		tmpl, err := getJobHistory(r.URL, cfg(), gcsClient)
+		tmpl, err := getJobHistory(r.URL, cfg(), opener)
 		if err != nil {
 			msg := fmt.Sprintf("failed to get job history: %v", err)
 			log.WithField("url", r.URL.String()).Warn(msg)
@@ -875,10 +902,10 @@ func handleJobHistory(o options, cfg config.Getter, gcsClient *storage.Client, l
 // The url must look like this:
 //
 // /pr-history?org=<org>&repo=<repo>&pr=<pr number>
-func handlePRHistory(o options, cfg config.Getter, gcsClient *storage.Client, gitHubClient deckGitHubClient, gitClient git.ClientFactory, log *logrus.Entry) http.HandlerFunc {
+func handlePRHistory(o options, cfg config.Getter, opener pkgio.Opener, gitHubClient deckGitHubClient, gitClient git.ClientFactory, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
-		tmpl, err := getPRHistory(r.URL, cfg(), gcsClient, gitHubClient, gitClient)
+		tmpl, err := getPRHistory(r.URL, cfg(), opener, gitHubClient, gitClient)
 		if err != nil {
 			msg := fmt.Sprintf("failed to get PR history: %v", err)
 			log.WithField("url", r.URL.String()).Info(msg)
@@ -890,12 +917,13 @@ func handlePRHistory(o options, cfg config.Getter, gcsClient *storage.Client, gi
 }
 
 // handleRequestJobViews handles requests to get all available artifact views for a given job.
-// The url must specify a storage key type, such as "prowjob" or "gcs":
+// The url must specify a storage key type, such as "prowjob", "gcs" or "s3":
 //
 // /view/<key-type>/<key>
 //
 // Examples:
 // - /view/gcs/kubernetes-jenkins/pr-logs/pull/test-infra/9557/pull-test-infra-verify-gofmt/15688/
+// - /view/s3/prow-artifacts/logs/periodic-echo-test/1046875594609922048/
 // - /view/prowjob/echo-test/1046875594609922048
 func handleRequestJobViews(sg *spyglass.Spyglass, cfg config.Getter, o options, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -922,25 +950,9 @@ func handleRequestJobViews(sg *spyglass.Spyglass, cfg config.Getter, o options,
 	}
 }
 
-// renderSpyglass returns a pre-rendered Spyglass page from the given source string
-func renderSpyglass(sg *spyglass.Spyglass, cfg config.Getter, src string, o options, csrfToken string, log *logrus.Entry) (string, error) {
-	renderStart := time.Now()
-
-	src = strings.TrimSuffix(src, "/")
-	realPath, err := sg.ResolveSymlink(src)
-	if err != nil {
-		return "", fmt.Errorf("error when resolving real path %s: %v", src, err)
-	}
-	src = realPath
-
-	artifactNames, err := sg.ListArtifacts(src)
-	if err != nil {
-		return "", fmt.Errorf("error listing artifacts: %v", err)
-	}
-	if len(artifactNames) == 0 {
-		return "", fmt.Errorf("found no artifacts for %s", src)
-	}
-
+// matchLenses returns the indexes of the configured lenses whose required files
+// are all present in artifactNames, along with the artifacts each of them matched.
+func matchLenses(cfg config.Getter, artifactNames []string) (map[int][]string, []int) {
 	regexCache := cfg().Deck.Spyglass.RegexCache
 	lensCache := map[int][]string{}
 	var lensIndexes []int
@@ -977,6 +989,29 @@ lensesLoop:
 		lensIndexes = append(lensIndexes, i)
 	}
 
+	return lensCache, lensIndexes
+}
+
+// renderSpyglass returns a pre-rendered Spyglass page from the given source string
+func renderSpyglass(sg *spyglass.Spyglass, cfg config.Getter, src string, o options, csrfToken string, log *logrus.Entry) (string, error) {
+	renderStart := time.Now()
+
+	src = strings.TrimSuffix(src, "/")
+	realPath, err := sg.ResolveSymlink(src)
+	if err != nil {
+		return "", fmt.Errorf("error when resolving real path %s: %v", src, err)
+	}
+	src = realPath
+
+	artifactNames, err := sg.ListArtifacts(src)
+	if err != nil {
+		return "", fmt.Errorf("error listing artifacts: %v", err)
+	}
+	if len(artifactNames) == 0 {
+		return "", fmt.Errorf("found no artifacts for %s", src)
+	}
+
+	lensCache, lensIndexes := matchLenses(cfg, artifactNames)
 	lensIndexes, ls := sg.Lenses(lensIndexes)
 
 	jobHistLink := ""
@@ -1119,11 +1154,156 @@ lensesLoop:
 	return viewBuf.String(), nil
 }
 
+// handleCompareJobViews handles requests to compare two runs.
+//
+// /view/compare?a=<src>&b=<src>
+//
+// Where each src is of the form <key-type>/<key>, as used by /view/. Run a is
+// the baseline and run b is the run compared to it.
+func handleCompareJobViews(sg *spyglass.Spyglass, cfg config.Getter, o options, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		setHeadersNoCaching(w)
+		before := r.URL.Query().Get("a")
+		after := r.URL.Query().Get("b")
+		if before == "" || after == "" {
+			http.Error(w, "both the a and b query parameters are required", http.StatusBadRequest)
+			return
+		}
+
+		csrfToken := csrf.Token(r)
+		page, err := renderCompare(sg, cfg, before, after, o, csrfToken, log)
+		if err != nil {
+			log.WithError(err).Error("error rendering spyglass compare page")
+			message := fmt.Sprintf("error rendering spyglass compare page: %v", err)
+			http.Error(w, message, http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprint(w, page)
+		elapsed := time.Since(start)
+		log.WithFields(logrus.Fields{
+			"duration": elapsed.String(),
+			"endpoint": r.URL.Path,
+			"before":   before,
+			"after":    after,
+		}).Info("Loading compare view completed.")
+	}
+}
+
+// compareRun holds what the compare view needs to know about one of the runs.
+type compareRun struct {
+	Source        string
+	JobName       string
+	BuildID       string
+	Link          string
+	LensArtifacts map[int][]string
+}
+
+func resolveCompareRun(sg *spyglass.Spyglass, cfg config.Getter, src string) (compareRun, []int, error) {
+	src = strings.TrimSuffix(src, "/")
+	realPath, err := sg.ResolveSymlink(src)
+	if err != nil {
+		return compareRun{}, nil, fmt.Errorf("error when resolving real path %s: %v", src, err)
+	}
+	src = realPath
+
+	artifactNames, err := sg.ListArtifacts(src)
+	if err != nil {
+		return compareRun{}, nil, fmt.Errorf("error listing artifacts for %s: %v", src, err)
+	}
+	jobName, buildID, err := sg.KeyToJob(src)
+	if err != nil {
+		return compareRun{}, nil, fmt.Errorf("error determining jobName / buildID: %v", err)
+	}
+	lensCache, lensIndexes := matchLenses(cfg, artifactNames)
+	return compareRun{
+		Source:        src,
+		JobName:       jobName,
+		BuildID:       buildID,
+		Link:          path.Join("/view", src),
+		LensArtifacts: lensCache,
+	}, lensIndexes, nil
+}
+
+// renderCompare returns a pre-rendered Spyglass page comparing the runs at the
+// given sources, showing every lens that supports comparisons and matches either run.
+func renderCompare(sg *spyglass.Spyglass, cfg config.Getter, before, after string, o options, csrfToken string, log *logrus.Entry) (string, error) {
+	renderStart := time.Now()
+
+	beforeRun, beforeIndexes, err := resolveCompareRun(sg, cfg, before)
+	if err != nil {
+		return "", err
+	}
+	afterRun, afterIndexes, err := resolveCompareRun(sg, cfg, after)
+	if err != nil {
+		return "", err
+	}
+
+	// A lens is shown if it matches either run, so that e.g. tests that only
+	// ran in one of them are still reported.
+	matched := sets.NewInt(beforeIndexes...).Insert(afterIndexes...)
+	var lensIndexes []int
+	for _, i := range matched.List() {
+		lens, err := lenses.GetLens(cfg().Deck.Spyglass.Lenses[i].Lens.Name)
+		if err != nil {
+			continue
+		}
+		if _, ok := lens.(lenses.Comparer); ok {
+			lensIndexes = append(lensIndexes, i)
+		}
+	}
+	lensIndexes, ls := sg.Lenses(lensIndexes)
+	for _, i := range lensIndexes {
+		// The frontend serializes these, and a missing entry would be null.
+		if beforeRun.LensArtifacts[i] == nil {
+			beforeRun.LensArtifacts[i] = []string{}
+		}
+		if afterRun.LensArtifacts[i] == nil {
+			afterRun.LensArtifacts[i] = []string{}
+		}
+	}
+
+	var viewBuf bytes.Buffer
+	type compareTemplate struct {
+		Lenses      map[int]lenses.Lens
+		LensIndexes []int
+		Before      compareRun
+		After       compareRun
+	}
+	cTmpl := compareTemplate{
+		Lenses:      ls,
+		LensIndexes: lensIndexes,
+		Before:      beforeRun,
+		After:       afterRun,
+	}
+	t := template.New("spyglass-compare.html")
+
+	if _, err := prepareBaseTemplate(o, cfg, csrfToken, t); err != nil {
+		return "", fmt.Errorf("error preparing base template: %v", err)
+	}
+	t, err = t.ParseFiles(path.Join(o.templateFilesLocation, "spyglass-compare.html"))
+	if err != nil {
+		return "", fmt.Errorf("error parsing template: %v", err)
+	}
+
+	if err = t.Execute(&viewBuf, cTmpl); err != nil {
+		return "", fmt.Errorf("error rendering template: %v", err)
+	}
+	log.WithFields(logrus.Fields{
+		"duration": time.Since(renderStart).String(),
+		"before":   beforeRun.Source,
+		"after":    afterRun.Source,
+	}).Info("Rendered spyglass compare views.")
+	return viewBuf.String(), nil
+}
+
 // handleArtifactView handles requests to load a single view for a job. This is what viewers
 // will use to call back to themselves.
 // Query params:
 // - name: required, specifies the name of the viewer to load
 // - src: required, specifies the job source from which to fetch artifacts
+// - compareSrc: required for the compare resource, specifies the job source to compare src to
 func handleArtifactView(o options, sg *spyglass.Spyglass, cfg config.Getter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
@@ -1178,6 +1358,39 @@ func handleArtifactView(o options, sg *spyglass.Spyglass, cfg config.Getter) htt
 				template.HTML(lens.Header(artifacts, lensResourcesDir, cfg().Deck.Spyglass.Lenses[request.Index].Lens.Config)),
 				template.HTML(lens.Body(artifacts, lensResourcesDir, "", cfg().Deck.Spyglass.Lenses[request.Index].Lens.Config)),
 			})
+		case "compare":
+			comparer, ok := lens.(lenses.Comparer)
+			if !ok {
+				http.Error(w, fmt.Sprintf("Lens %s does not support comparisons", lensName), http.StatusBadRequest)
+				return
+			}
+			if request.CompareSource == "" {
+				http.Error(w, "Missing source to compare to", http.StatusBadRequest)
+				return
+			}
+			compareArtifacts, err := sg.FetchArtifacts(request.CompareSource, "", cfg().Deck.Spyglass.SizeLimit, request.CompareArtifacts)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to retrieve expected artifacts to compare to: %v", err), http.StatusInternalServerError)
+				return
+			}
+			t, err := template.ParseFiles(path.Join(o.templateFilesLocation, "spyglass-lens.html"))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to load template: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/html; encoding=utf-8")
+			t.Execute(w, struct {
+				Title   string
+				BaseURL string
+				Head    template.HTML
+				Body    template.HTML
+			}{
+				lensConfig.Title,
+				"/spyglass/static/" + lensName + "/",
+				template.HTML(lens.Header(artifacts, lensResourcesDir, cfg().Deck.Spyglass.Lenses[request.Index].Lens.Config)),
+				template.HTML(comparer.Compare(artifacts, compareArtifacts, lensResourcesDir, cfg().Deck.Spyglass.Lenses[request.Index].Lens.Config)),
+			})
 		case "rerender":
 			data, err := ioutil.ReadAll(r.Body)
 			if err != nil {
@@ -1378,6 +1591,44 @@ func canTriggerJob(user string, pj prowapi.ProwJob, cfg *prowapi.RerunAuthConfig
 	return false, nil
 }
 
+// isAllowedToRerun determines whether the user sending the request may rerun or abort the given
+// job, based on the same RerunAuthConfig rules. It returns the GitHub login of the user, which is
+// empty when anyone is allowed and no login is needed. If ok is false, an error response was
+// already written and the caller must not write to w anymore.
+func isAllowedToRerun(w http.ResponseWriter, r *http.Request, action string, pj prowapi.ProwJob, cfg authCfgGetter, goa *githuboauth.Agent, ghc githuboauth.AuthenticatedUserIdentifier, cli prowgithub.RerunClient, pluginAgent *plugins.ConfigAgent, l *logrus.Entry) (login string, allowed bool, ok bool) {
+	authConfig := cfg(pj.Spec.Refs)
+	if pj.Spec.RerunAuthConfig.IsAllowAnyone() || authConfig.IsAllowAnyone() {
+		// Skip getting the users login via GH oauth if anyone is allowed to rerun
+		// jobs so that GH oauth doesn't need to be set up for private Prows.
+		// If the user happens to be logged in, we still want to know who they are.
+		if goa != nil {
+			if login, err := goa.GetLogin(r, ghc); err == nil {
+				return login, true, true
+			}
+		}
+		return "", true, true
+	}
+	if goa == nil {
+		msg := fmt.Sprintf("GitHub oauth must be configured to %s jobs unless 'allow_anyone: true' is specified.", action)
+		http.Error(w, msg, http.StatusInternalServerError)
+		l.Error(msg)
+		return "", false, false
+	}
+	login, err := goa.GetLogin(r, ghc)
+	if err != nil {
+		l.WithError(err).Errorf("Error retrieving GitHub login")
+		http.Error(w, "Error retrieving GitHub login", http.StatusUnauthorized)
+		return "", false, false
+	}
+	allowed, err = canTriggerJob(login, pj, authConfig, cli, pluginAgent, l.WithField("user", login))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error checking if user can trigger job: %v", err), http.StatusInternalServerError)
+		l.WithError(err).WithField("user", login).Errorf("Error checking if user can trigger job")
+		return login, false, false
+	}
+	return login, allowed, true
+}
+
 // handleRerun triggers a rerun of the given job if that features is enabled, it receives a
 // POST request, and the user has the necessary permissions. Otherwise, it writes the config
 // for a new job but does not trigger it.
@@ -1408,32 +1659,12 @@ func handleRerun(prowJobClient prowv1.ProwJobInterface, createProwJob bool, cfg
 				http.Error(w, "Direct rerun feature is not enabled. Enable with the '--rerun-creates-job' flag.", http.StatusMethodNotAllowed)
 				return
 			}
-			authConfig := cfg(pj.Spec.Refs)
-			var allowed bool
-			if pj.Spec.RerunAuthConfig.IsAllowAnyone() || authConfig.IsAllowAnyone() {
-				// Skip getting the users login via GH oauth if anyone is allowed to rerun
-				// jobs so that GH oauth doesn't need to be set up for private Prows.
-				allowed = true
-			} else {
-				if goa == nil {
-					msg := "GitHub oauth must be configured to rerun jobs unless 'allow_anyone: true' is specified."
-					http.Error(w, msg, http.StatusInternalServerError)
-					l.Error(msg)
-					return
-				}
-				login, err := goa.GetLogin(r, ghc)
-				if err != nil {
-					l.WithError(err).Errorf("Error retrieving GitHub login")
-					http.Error(w, "Error retrieving GitHub login", http.StatusUnauthorized)
-					return
-				}
+			login, allowed, ok := isAllowedToRerun(w, r, "rerun", newPJ, cfg, goa, ghc, cli, pluginAgent, l)
+			if !ok {
+				return
+			}
+			if login != "" {
 				l = l.WithField("user", login)
-				allowed, err = canTriggerJob(login, newPJ, authConfig, cli, pluginAgent, l)
-				if err != nil {
-					http.Error(w, fmt.Sprintf("Error checking if user can trigger job: %v", err), http.StatusInternalServerError)
-					l.WithError(err).Errorf("Error checking if user can trigger job")
-					return
-				}
 			}
 
 			l = l.WithField("allowed", allowed)
@@ -1463,6 +1694,74 @@ func handleRerun(prowJobClient prowv1.ProwJobInterface, createProwJob bool, cfg
 	}
 }
 
+// handleAbort aborts the given job if it receives a POST request and the user has the necessary
+// permissions to rerun the job. Plank or the pipeline controller then tear down the resources of the job.
+func handleAbort(prowJobClient prowv1.ProwJobInterface, abortEnabled bool, cfg authCfgGetter, goa *githuboauth.Agent, ghc githuboauth.AuthenticatedUserIdentifier, cli prowgithub.RerunClient, pluginAgent *plugins.ConfigAgent, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("prowjob")
+		l := log.WithField("prowjob", name)
+		if name == "" {
+			http.Error(w, "request did not provide the 'prowjob' query parameter", http.StatusBadRequest)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("bad verb %v", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		if !abortEnabled {
+			http.Error(w, "Abort feature is not enabled. Enable with the '--rerun-creates-job' flag.", http.StatusMethodNotAllowed)
+			return
+		}
+		pj, err := prowJobClient.Get(name, metav1.GetOptions{})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("ProwJob not found: %v", err), http.StatusNotFound)
+			if !kerrors.IsNotFound(err) {
+				// admins only care about errors other than not found
+				l.WithError(err).Warning("ProwJob not found.")
+			}
+			return
+		}
+		l = l.WithField("job", pj.Spec.Job)
+		if pj.Complete() {
+			http.Error(w, fmt.Sprintf("Job is already complete with state %q.", pj.Status.State), http.StatusBadRequest)
+			return
+		}
+
+		login, allowed, ok := isAllowedToRerun(w, r, "abort", *pj, cfg, goa, ghc, cli, pluginAgent, l)
+		if !ok {
+			return
+		}
+		if login != "" {
+			l = l.WithField("user", login)
+		}
+		l = l.WithField("allowed", allowed)
+		l.Info("Attempted abort")
+		if !allowed {
+			if _, err = w.Write([]byte("You don't have permission to abort that job")); err != nil {
+				l.WithError(err).Error("Error writing to abort response.")
+			}
+			return
+		}
+
+		abortedPJ := pj.DeepCopy()
+		abortedPJ.Status.State = prowapi.AbortedState
+		if login != "" {
+			abortedPJ.Status.Description = fmt.Sprintf("Aborted by %s.", login)
+		} else {
+			abortedPJ.Status.Description = "Aborted via deck."
+		}
+		if _, err := pjutil.PatchProwjob(prowJobClient, l, *pj, *abortedPJ); err != nil {
+			l.WithError(err).Error("Error aborting job")
+			http.Error(w, fmt.Sprintf("Error aborting job: %v", err), http.StatusInternalServerError)
+			return
+		}
+		l.Info("Successfully aborted PJ.")
+		if _, err = w.Write([]byte("Job successfully aborted.")); err != nil {
+			l.WithError(err).Error("Error writing to abort response.")
+		}
+	}
+}
+
 func handleSerialize(w http.ResponseWriter, name string, data interface{}, l *logrus.Entry) {
 	setHeadersNoCaching(w)
 	b, err := yaml.Marshal(data)
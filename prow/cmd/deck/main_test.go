@@ -30,6 +30,8 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"testing"
 	"time"
@@ -467,6 +469,145 @@ func TestRerun(t *testing.T) {
 	}
 }
 
+func TestAbort(t *testing.T) {
+	testCases := []struct {
+		name          string
+		login         string
+		authorized    []string
+		allowAnyone   bool
+		abortEnabled  bool
+		state         prowapi.ProwJobState
+		httpMethod    string
+		httpCode      int
+		expectedState prowapi.ProwJobState
+		expectedDesc  string
+	}{
+		{
+			name:          "Authorized user aborts job",
+			login:         "authorized",
+			authorized:    []string{"authorized", "alsoauthorized"},
+			abortEnabled:  true,
+			state:         prowapi.PendingState,
+			httpMethod:    http.MethodPost,
+			httpCode:      http.StatusOK,
+			expectedState: prowapi.AbortedState,
+			expectedDesc:  "Aborted by authorized.",
+		},
+		{
+			name:          "User not authorized to abort job",
+			login:         "random-dude",
+			authorized:    []string{"authorized", "alsoauthorized"},
+			abortEnabled:  true,
+			state:         prowapi.PendingState,
+			httpMethod:    http.MethodPost,
+			httpCode:      http.StatusOK,
+			expectedState: prowapi.PendingState,
+		},
+		{
+			name:          "Allow anyone set to true, aborts job",
+			login:         "ugh",
+			allowAnyone:   true,
+			abortEnabled:  true,
+			state:         prowapi.TriggeredState,
+			httpMethod:    http.MethodPost,
+			httpCode:      http.StatusOK,
+			expectedState: prowapi.AbortedState,
+			expectedDesc:  "Aborted by ugh.",
+		},
+		{
+			name:          "Abort disabled",
+			login:         "authorized",
+			authorized:    []string{"authorized"},
+			abortEnabled:  false,
+			state:         prowapi.PendingState,
+			httpMethod:    http.MethodPost,
+			httpCode:      http.StatusMethodNotAllowed,
+			expectedState: prowapi.PendingState,
+		},
+		{
+			name:          "Get requests are rejected",
+			login:         "authorized",
+			authorized:    []string{"authorized"},
+			abortEnabled:  true,
+			state:         prowapi.PendingState,
+			httpMethod:    http.MethodGet,
+			httpCode:      http.StatusMethodNotAllowed,
+			expectedState: prowapi.PendingState,
+		},
+		{
+			name:          "Complete jobs can't be aborted",
+			login:         "authorized",
+			authorized:    []string{"authorized"},
+			abortEnabled:  true,
+			state:         prowapi.SuccessState,
+			httpMethod:    http.MethodPost,
+			httpCode:      http.StatusBadRequest,
+			expectedState: prowapi.SuccessState,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pj := &prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "wowsuch",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Job:  "whoa",
+					Type: prowapi.PeriodicJob,
+				},
+				Status: prowapi.ProwJobStatus{
+					State: tc.state,
+				},
+			}
+			if tc.state == prowapi.SuccessState {
+				pj.SetComplete()
+			}
+			fakeProwJobClient := fake.NewSimpleClientset(pj)
+			authCfgGetter := func(refs *prowapi.Refs) *prowapi.RerunAuthConfig {
+				return &prowapi.RerunAuthConfig{
+					AllowAnyone: tc.allowAnyone,
+					GitHubUsers: tc.authorized,
+				}
+			}
+
+			req, err := http.NewRequest(tc.httpMethod, "/abort?prowjob=wowsuch", nil)
+			if err != nil {
+				t.Fatalf("Error making request: %v", err)
+			}
+			mockCookieStore := sessions.NewCookieStore([]byte("secret-key"))
+			session, err := sessions.GetRegistry(req).Get(mockCookieStore, "access-token-session")
+			if err != nil {
+				t.Fatalf("Error making access token session: %v", err)
+			}
+			session.Values["access-token"] = &oauth2.Token{AccessToken: "validtoken"}
+
+			rr := httptest.NewRecorder()
+			goa := githuboauth.NewAgent(&githuboauth.Config{CookieStore: mockCookieStore}, &logrus.Entry{})
+			ghc := &fakeAuthenticatedUserIdentifier{login: tc.login}
+			rc := &fakegithub.FakeClient{}
+			pca := plugins.NewFakeConfigAgent()
+			handler := handleAbort(fakeProwJobClient.ProwV1().ProwJobs("prowjobs"), tc.abortEnabled, authCfgGetter, goa, ghc, rc, &pca, logrus.WithField("handler", "/abort"))
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tc.httpCode {
+				t.Fatalf("Bad error code: %d", rr.Code)
+			}
+
+			got, err := fakeProwJobClient.ProwV1().ProwJobs("prowjobs").Get("wowsuch", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get prowjob: %v", err)
+			}
+			if got.Status.State != tc.expectedState {
+				t.Errorf("expected state %q, got %q", tc.expectedState, got.Status.State)
+			}
+			if got.Status.Description != tc.expectedDesc {
+				t.Errorf("expected description %q, got %q", tc.expectedDesc, got.Status.Description)
+			}
+		})
+	}
+}
+
 func TestTide(t *testing.T) {
 	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		pools := []tide.Pool{
@@ -1128,3 +1269,65 @@ func (p *possiblyErroringFakeCtrlRuntimeClient) List(
 	}
 	return p.Client.List(ctx, pjl, opts...)
 }
+
+func TestMatchLenses(t *testing.T) {
+	cfg := func() *config.Config {
+		c := &config.Config{}
+		c.Deck.Spyglass.Lenses = []config.LensFileConfig{
+			{RequiredFiles: []string{"started.json|finished.json"}, OptionalFiles: []string{"podinfo.json"}, Lens: config.LensConfig{Name: "metadata"}},
+			{RequiredFiles: []string{"build-log.txt"}, Lens: config.LensConfig{Name: "buildlog"}},
+			{RequiredFiles: []string{`artifacts/junit.*\.xml`}, Lens: config.LensConfig{Name: "junit"}},
+		}
+		c.Deck.Spyglass.RegexCache = map[string]*regexp.Regexp{}
+		for _, lens := range c.Deck.Spyglass.Lenses {
+			for _, re := range append(lens.RequiredFiles, lens.OptionalFiles...) {
+				c.Deck.Spyglass.RegexCache[re] = regexp.MustCompile(re)
+			}
+		}
+		return c
+	}
+
+	lensCache, lensIndexes := matchLenses(cfg, []string{"build-log.txt", "finished.json", "podinfo.json"})
+	if expected := []int{0, 1}; !reflect.DeepEqual(lensIndexes, expected) {
+		t.Errorf("expected lens indexes %v, got %v", expected, lensIndexes)
+	}
+	for i := range lensCache {
+		sort.Strings(lensCache[i])
+	}
+	expected := map[int][]string{
+		0: {"finished.json", "podinfo.json"},
+		1: {"build-log.txt"},
+	}
+	if !reflect.DeepEqual(lensCache, expected) {
+		t.Errorf("expected lens artifacts %v, got %v", expected, lensCache)
+	}
+}
+
+func TestHandleCompareJobViewsRequiresBothRuns(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{
+			name: "no runs",
+		},
+		{
+			name:  "only the baseline",
+			query: "a=gcs/bucket/logs/job/1",
+		},
+		{
+			name:  "only the run compared to it",
+			query: "b=gcs/bucket/logs/job/2",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/view/compare?"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handleCompareJobViews(nil, nil, options{}, logrus.WithField("handler", "/view/compare")).ServeHTTP(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+			}
+		})
+	}
+}
@@ -27,9 +27,11 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/util/sets"
+
+	pkgio "k8s.io/test-infra/pkg/io"
+	"k8s.io/test-infra/pkg/io/providers"
 	v1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/gcsupload"
@@ -93,8 +95,8 @@ func githubCommitLink(org, repo, commitHash string) string {
 	return fmt.Sprintf("https://github.com/%s/%s/commit/%s", org, repo, commitHash)
 }
 
-func jobHistLink(bucketName, jobName string) string {
-	return fmt.Sprintf("/job-history/%s/pr-logs/directory/%s", bucketName, jobName)
+func jobHistLink(bucket storageBucket, jobName string) string {
+	return fmt.Sprintf("/job-history/%s/pr-logs/directory/%s", bucketPath(bucket), jobName)
 }
 
 // gets the pull commit hash from metadata
@@ -144,7 +146,7 @@ func getPRBuildData(bucket storageBucket, jobs []jobBuilds) []buildData {
 					logrus.WithError(err).Warningf("build %s information incomplete", buildPrefix)
 				}
 				split := strings.Split(strings.TrimSuffix(buildPrefix, "/"), "/")
-				build.SpyglassLink = path.Join(spyglassPrefix, bucket.getName(), buildPrefix)
+				build.SpyglassLink = spyglassLink(bucket, buildPrefix)
 				build.ID = split[len(split)-1]
 				build.jobName = jobName
 				build.prefix = buildPrefix
@@ -202,7 +204,8 @@ func parsePullURL(u *url.URL) (org, repo string, pr int, err error) {
 	return org, repo, pr, nil
 }
 
-// getGCSDirsForPR returns a map from bucket names -> set of "directories" containing presubmit data
+// getGCSDirsForPR returns a map from buckets -> set of "directories" containing presubmit data.
+// The buckets are keyed as configured in the GCSConfiguration and may include a storage provider.
 func getGCSDirsForPR(c *config.Config, gitHubClient deckGitHubClient, gitClient git.ClientFactory, org, repo string, prNumber int) (map[string]sets.String, error) {
 	toSearch := make(map[string]sets.String)
 	fullRepo := org + "/" + repo
@@ -248,7 +251,7 @@ func getGCSDirsForPR(c *config.Config, gitHubClient deckGitHubClient, gitClient
 	return toSearch, nil
 }
 
-func getPRHistory(url *url.URL, config *config.Config, gcsClient *storage.Client, gitHubClient deckGitHubClient, gitClient git.ClientFactory) (prHistoryTemplate, error) {
+func getPRHistory(url *url.URL, config *config.Config, opener pkgio.Opener, gitHubClient deckGitHubClient, gitClient git.ClientFactory) (prHistoryTemplate, error) {
 	start := time.Now()
 	template := prHistoryTemplate{}
 
@@ -268,8 +271,12 @@ func getPRHistory(url *url.URL, config *config.Config, gcsClient *storage.Client
 	// job name -> commit hash -> list of builds
 	jobCommitBuilds := make(map[string]map[string][]buildData)
 
-	for bucketName, gcsPaths := range toSearch {
-		bucket := gcsBucket{bucketName, gcsClient.Bucket(bucketName)}
+	for configuredBucket, gcsPaths := range toSearch {
+		storageProvider, bucketName, err := providers.ParseBucket(configuredBucket)
+		if err != nil {
+			return template, fmt.Errorf("failed to parse bucket %q: %v", configuredBucket, err)
+		}
+		bucket := newBlobStorageBucket(bucketName, storageProvider, opener)
 		for gcsPath := range gcsPaths {
 			jobPrefixes, err := bucket.listSubDirs(gcsPath)
 			if err != nil {
@@ -280,7 +287,7 @@ func getPRHistory(url *url.URL, config *config.Config, gcsClient *storage.Client
 				jobName := path.Base(jobPrefix)
 				jobData := prJobData{
 					Name: jobName,
-					Link: jobHistLink(bucketName, jobName),
+					Link: jobHistLink(bucket, jobName),
 				}
 				template.Jobs = append(template.Jobs, jobData)
 				jobCommitBuilds[jobName] = make(map[string][]buildData)
@@ -32,14 +32,19 @@ import (
 )
 
 type fakeBucket struct {
-	name    string
-	objects map[string]string
+	name            string
+	storageProvider string
+	objects         map[string]string
 }
 
 func (bucket fakeBucket) getName() string {
 	return bucket.name
 }
 
+func (bucket fakeBucket) getStorageProvider() string {
+	return bucket.storageProvider
+}
+
 func (bucket fakeBucket) listSubDirs(prefix string) ([]string, error) {
 	dirs := sets.String{}
 	for k := range bucket.objects {
@@ -217,7 +222,8 @@ func TestParsePullURL(t *testing.T) {
 }
 
 var testBucket = fakeBucket{
-	name: "chum-bucket",
+	name:            "chum-bucket",
+	storageProvider: "gs",
 	objects: map[string]string{
 		"pr-logs/pull/123/build-snowman/456/started.json": `{
 			"timestamp": 55555
@@ -445,3 +451,35 @@ func TestGetGCSDirsForPR(t *testing.T) {
 		}
 	}
 }
+
+func TestHistoryLinks(t *testing.T) {
+	cases := []struct {
+		name        string
+		bucket      fakeBucket
+		expSpyglass string
+		expJobHist  string
+	}{
+		{
+			name:        "gcs bucket keeps legacy links",
+			bucket:      fakeBucket{name: "chum-bucket", storageProvider: "gs"},
+			expSpyglass: "/view/gcs/chum-bucket/pr-logs/pull/123/build-snowman/456",
+			expJobHist:  "/job-history/chum-bucket/pr-logs/directory/build-snowman",
+		},
+		{
+			name:        "s3 bucket links include the storage provider",
+			bucket:      fakeBucket{name: "chum-bucket", storageProvider: "s3"},
+			expSpyglass: "/view/s3/chum-bucket/pr-logs/pull/123/build-snowman/456",
+			expJobHist:  "/job-history/s3/chum-bucket/pr-logs/directory/build-snowman",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := spyglassLink(tc.bucket, "pr-logs/pull/123/build-snowman/456"); got != tc.expSpyglass {
+				t.Errorf("expected spyglass link %q, got %q", tc.expSpyglass, got)
+			}
+			if got := jobHistLink(tc.bucket, "build-snowman"); got != tc.expJobHist {
+				t.Errorf("expected job history link %q, got %q", tc.expJobHist, got)
+			}
+		})
+	}
+}
@@ -37,6 +37,9 @@ export interface ProwJobList {
   apiVersion?: string;
   metadata: ListMeta;
   items: ProwJob[];
+  // queue_positions is added by deck, it maps the names of triggered
+  // ProwJobs to their position in the queue of plank.
+  queue_positions?: { [key: string]: number };
 }
 
 // ProwJob contains the spec as well as runtime metadata.
@@ -98,6 +101,7 @@ export interface ProwJobSpec {
   context?: string;
   rerun_command?: string;
   max_concurrency?: number;
+  priority?: number;
   error_on_eviction?: boolean;
   pod_spec?: object;
   build_spec?: object;
@@ -10,4 +10,11 @@ export interface Record {
   baseSHA?: string;
   target?: Pull[];
   err?: string;
+  bisection?: Bisection;
+}
+
+export interface Bisection {
+  batch: Pull[];
+  failedJob?: string;
+  culprits?: Pull[];
 }
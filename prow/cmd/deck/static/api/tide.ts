@@ -31,7 +31,7 @@ export interface PullRequest extends BasePullRequest {
   };
 }
 
-export type Action = "WAIT" | "TRIGGER" | "TRIGGER_BATCH" | "MERGE" | "MERGE_BATCH" | "BLOCKED";
+export type Action = "WAIT" | "TRIGGER" | "TRIGGER_BATCH" | "TRIGGER_SPECULATIVE_BATCH" | "BISECT" | "MERGE" | "MERGE_BATCH" | "BLOCKED";
 
 export interface Blocker {
   Number: number;
@@ -49,10 +49,16 @@ export interface TidePool {
   MissingPRs: PullRequest[];
 
   BatchPending: PullRequest[];
+  // SpeculativeBatches are the PRs each speculative batch tests on top of
+  // the pending batch and the speculative batches before it.
+  SpeculativeBatches?: PullRequest[][];
 
   Action: Action;
   Target: PullRequest[];
   Blockers: Blocker[];
+
+  // GerritInstance is set for pools of Gerrit changes.
+  GerritInstance?: string;
 }
 
 export interface TideData {
@@ -568,7 +568,12 @@ function redraw(fz: FuzzySearch): void {
         }
         displayedJob++;
         const r = document.createElement("tr");
-        r.appendChild(cell.state(state));
+        const stateCell = cell.state(state);
+        const queuePosition = allBuilds.queue_positions ? allBuilds.queue_positions[prowJobName] : undefined;
+        if (state === "triggered" && queuePosition) {
+            stateCell.title = `Triggered, #${queuePosition} in queue`;
+        }
+        r.appendChild(stateCell);
         if ((agent === "kubernetes" && pod_name) || agent !== "kubernetes") {
             const logIcon = icon.create("description", "Build log");
             logIcon.href = `log?job=${job}&id=${build_id}`;
@@ -579,7 +584,7 @@ function redraw(fz: FuzzySearch): void {
         } else {
             r.appendChild(cell.text(""));
         }
-        r.appendChild(createRerunCell(modal, rerunCommand, prowJobName));
+        r.appendChild(createRerunCell(modal, rerunCommand, prowJobName, state));
         r.appendChild(createViewJobCell(prowJobName));
         const key = groupKey(build);
         if (key !== lastKey) {
@@ -683,8 +688,9 @@ function redraw(fz: FuzzySearch): void {
     }
 }
 
-function createRerunCell(modal: HTMLElement, rerunElement: HTMLElement, prowjob: string): HTMLTableDataCellElement {
+function createRerunCell(modal: HTMLElement, rerunElement: HTMLElement, prowjob: string, state: ProwJobState): HTMLTableDataCellElement {
     const url = `${location.protocol}//${location.host}/rerun?prowjob=${prowjob}`;
+    const abortURL = `${location.protocol}//${location.host}/abort?prowjob=${prowjob}`;
     const c = document.createElement("td");
     const i = icon.create("refresh", "Show instructions for rerunning this job");
 
@@ -721,6 +727,30 @@ function createRerunCell(modal: HTMLElement, rerunElement: HTMLElement, prowjob:
                 }
             };
             rerunElement.appendChild(runButton);
+            if (state === "triggered" || state === "pending") {
+                const abortButton = document.createElement('a');
+                abortButton.innerHTML = "<button class='mdl-button mdl-js-button'>Abort</button>";
+                abortButton.onclick = async () => {
+                    gtag("event", "abort", {
+                        event_category: "engagement",
+                        transport_type: "beacon",
+                    });
+                    const result = await fetch(abortURL, {
+                        headers: {
+                            "Content-type": "application/x-www-form-urlencoded; charset=UTF-8",
+                            "X-CSRF-Token": csrfToken,
+                        },
+                        method: 'post',
+                    });
+                    const data = await result.text();
+                    if (result.status === 401) {
+                        window.location.href = window.location.origin + `/github-login?dest=${relativeURL({rerun: "gh_redirect"})}`;
+                    } else {
+                        rerunElement.innerHTML = data;
+                    }
+                };
+                rerunElement.appendChild(abortButton);
+            }
         }
     };
     c.appendChild(i);
@@ -4,13 +4,21 @@ declare const src: string;
 declare const lensArtifacts: {[index: string]: string[]};
 declare const lensIndexes: number[];
 declare const csrfToken: string;
+// Only set by the compare view, which compares the run at src to the one at compareSrc.
+declare const compareSrc: string | undefined;
+declare const compareLensArtifacts: {[index: string]: string[]} | undefined;
+
+function comparing(): boolean {
+  return typeof compareSrc !== 'undefined';
+}
 
 // Loads views for this job
 function loadLenses(): void {
   const hashes = parseHash();
   for (const lensIndex of lensIndexes) {
     const frame = document.querySelector<HTMLIFrameElement>(`#iframe-${lensIndex}`)!;
-    let url = urlForLensRequest(frame.dataset.lensName!, Number(frame.dataset.lensIndex!), 'iframe');
+    const request = comparing() ? 'compare' : 'iframe';
+    let url = urlForLensRequest(frame.dataset.lensName!, Number(frame.dataset.lensIndex!), request);
     url += `&topURL=${escape(location.href.split('#')[0])}&lensIndex=${lensIndex}`;
     const hash = hashes[lensIndex];
     if (hash) {
@@ -20,12 +28,24 @@ function loadLenses(): void {
   }
 }
 
+interface LensRequest {
+  artifacts: string[];
+  index: number;
+  src: string;
+  compareSrc?: string;
+  compareArtifacts?: string[];
+}
+
 function queryForLens(lens: string, index: number): string {
-  const data = {
+  const data: LensRequest = {
     artifacts: lensArtifacts[index],
     index,
     src,
   };
+  if (comparing()) {
+    data.compareSrc = compareSrc;
+    data.compareArtifacts = compareLensArtifacts![index];
+  }
   return `req=${encodeURIComponent(JSON.stringify(data))}`;
 }
 
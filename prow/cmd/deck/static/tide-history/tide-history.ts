@@ -265,7 +265,7 @@ function redrawRecords(recs: FilteredRecord[]): void {
       r.appendChild(cell.text(""));
       r.appendChild(cell.text(""));
     }
-    r.appendChild(cell.text(rec.action));
+    r.appendChild(cell.text(actionText(rec)));
     r.appendChild(targetCell(rec));
     r.appendChild(cell.time(nextID(), moment(rec.time)));
     r.appendChild(cell.text(rec.err || ""));
@@ -275,6 +275,16 @@ function redrawRecords(recs: FilteredRecord[]): void {
   recCount.textContent = `Showing ${displayCount}/${recs.length} records`;
 }
 
+// actionText returns the action of the record and the culprits found by the
+// bisection it is part of.
+function actionText(rec: FilteredRecord): string {
+  const culprits = rec.bisection && rec.bisection.culprits || [];
+  if (culprits.length === 0) {
+    return rec.action;
+  }
+  return `${rec.action} (culprits: ${culprits.map((p) => `#${p.number}`).join(" ")})`;
+}
+
 function targetCell(rec: FilteredRecord): HTMLTableDataCellElement {
   const target = rec.target || [];
   switch (target.length) {
@@ -237,7 +237,10 @@ function createHistoryCell(pool: TidePool): HTMLTableDataCellElement {
 
 function createRepoCell(pool: TidePool): HTMLTableDataCellElement {
     const deckLink = `/?repo=` + encodeURIComponent(`${pool.Org}/${pool.Repo}`);
-    const branchLink = `https://github.com/${pool.Org}/${pool.Repo}/tree/${pool.Branch}`;
+    let branchLink = `https://github.com/${pool.Org}/${pool.Repo}/tree/${pool.Branch}`;
+    if (pool.GerritInstance) {
+        branchLink = `${pool.GerritInstance}/q/project:${pool.Repo}+branch:${pool.Branch}`;
+    }
     const linksTD = document.createElement("td");
     linksTD.appendChild(createLink(deckLink, `${pool.Org}/${pool.Repo}`));
     linksTD.appendChild(document.createTextNode(" "));
@@ -248,7 +251,7 @@ function createRepoCell(pool: TidePool): HTMLTableDataCellElement {
 function createActionCell(pool: TidePool): HTMLTableDataCellElement {
     const targeted = pool.Target && pool.Target.length;
     const blocked = pool.Blockers && pool.Blockers.length;
-    let action = pool.Action.replace("_", " ");
+    let action = pool.Action.replace(/_/g, " ");
     if (targeted || blocked) {
         action += ": ";
     }
@@ -273,38 +276,60 @@ function createPRCell(pool: TidePool, prs: PullRequest[]): HTMLTableDataCellElem
 function createBatchCell(pool: TidePool): HTMLTableDataCellElement {
     const td = document.createElement('td');
     if (pool.BatchPending) {
-        const numbers = pool.BatchPending.map((p) => String(p.Number));
-        const batchRef = `${pool.Branch},${numbers.join(',')}`;
-        const encodedRepo = encodeURIComponent(`${pool.Org}/${pool.Repo}`);
-        const href = `/?repo=${encodedRepo}&type=batch&pull=${encodeURIComponent(batchRef)}`;
-        const link = document.createElement('a');
-        link.href = href;
-        for (let i = 0; i < pool.BatchPending.length; i++) {
-            const pr = pool.BatchPending[i];
-            const text = document.createElement('span');
-            text.appendChild(document.createTextNode("#" + String(pr.Number)));
-            text.id = `pr-${pool.Org}-${pool.Repo}-${pr.Number}-${nextID()}`;
-            if (pr.Title) {
-                const tip = tooltip.forElem(text.id, document.createTextNode(pr.Title));
-                text.appendChild(tip);
-            }
-            link.appendChild(text);
-            // Add a space after each PR number except the last.
-            if (i + 1 < pool.BatchPending.length) {
-                link.appendChild(document.createTextNode(" "));
-            }
+        td.appendChild(createBatchLink(pool, [], pool.BatchPending));
+        // Speculative batches are tested on top of the batches before them,
+        // so their jobs are listed under all of their PRs.
+        let base = pool.BatchPending;
+        for (const own of pool.SpeculativeBatches || []) {
+            td.appendChild(document.createTextNode(" + "));
+            td.appendChild(createBatchLink(pool, base, own));
+            base = base.concat(own);
         }
-        td.appendChild(link);
     }
     return td;
 }
 
+// createBatchLink creates a link to the jobs of the batch of the base and own
+// PRs, showing only its own PRs.
+function createBatchLink(pool: TidePool, base: PullRequest[], own: PullRequest[]): HTMLAnchorElement {
+    const numbers = base.concat(own).map((p) => String(p.Number));
+    const batchRef = `${pool.Branch},${numbers.join(',')}`;
+    const encodedRepo = encodeURIComponent(`${pool.Org}/${pool.Repo}`);
+    const href = `/?repo=${encodedRepo}&type=batch&pull=${encodeURIComponent(batchRef)}`;
+    const link = document.createElement('a');
+    link.href = href;
+    for (let i = 0; i < own.length; i++) {
+        const pr = own[i];
+        const text = document.createElement('span');
+        text.appendChild(document.createTextNode("#" + String(pr.Number)));
+        text.id = `pr-${pool.Org}-${pool.Repo}-${pr.Number}-${nextID()}`;
+        if (pr.Title) {
+            const tip = tooltip.forElem(text.id, document.createTextNode(pr.Title));
+            text.appendChild(tip);
+        }
+        link.appendChild(text);
+        // Add a space after each PR number except the last.
+        if (i + 1 < own.length) {
+            link.appendChild(document.createTextNode(" "));
+        }
+    }
+    return link;
+}
+
+// prLink returns the link to the PR on github, or to the change on gerrit.
+function prLink(pool: TidePool, pr: PullRequest): string {
+    if (pool.GerritInstance) {
+        return `${pool.GerritInstance}/c/${pool.Repo}/+/${pr.Number}`;
+    }
+    return `https://github.com/${pool.Org}/${pool.Repo}/pull/${pr.Number}`;
+}
+
 // addPRsToElem adds a space separated list of PR numbers that link to the corresponding PR on github.
 function addPRsToElem(elem: HTMLElement, pool: TidePool, prs?: PullRequest[]): void {
     if (prs) {
         for (let i = 0; i < prs.length; i++) {
             const a = document.createElement("a");
-            a.href = `https://github.com/${pool.Org}/${pool.Repo}/pull/${prs[i].Number}`;
+            a.href = prLink(pool, prs[i]);
             a.appendChild(document.createTextNode("#" + prs[i].Number));
             a.id = `pr-${pool.Org}-${pool.Repo}-${prs[i].Number}-${nextID()}`;
             if (prs[i].Title) {
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -49,9 +50,12 @@ type options struct {
 	// lastSyncFallback is the path to sync the latest timestamp
 	// Can be /local/path, gs://path/to/object or s3://path/to/object.
 	lastSyncFallback string
-	dryRun           bool
-	kubernetes       prowflagutil.KubernetesOptions
-	storage          prowflagutil.StorageClientOptions
+	// eventCursorPath is the path to persist the position in the events-log
+	// of the instances, setting it makes the adapter consume the events-log.
+	eventCursorPath string
+	dryRun          bool
+	kubernetes      prowflagutil.KubernetesOptions
+	storage         prowflagutil.StorageClientOptions
 }
 
 func (o *options) Validate() error {
@@ -77,6 +81,10 @@ func (o *options) Validate() error {
 	if strings.HasPrefix(o.lastSyncFallback, "s3://") && !o.storage.HasS3Credentials() {
 		logrus.WithField("last-sync-fallback", o.lastSyncFallback).Warn("--s3-credentials-file unset, will try and access with auto-discovered credentials")
 	}
+
+	if o.eventCursorPath != "" && o.cookiefilePath == "" {
+		return errors.New("--cookiefile must be set to read the events-log")
+	}
 	return nil
 }
 
@@ -88,6 +96,7 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	fs.StringVar(&o.cookiefilePath, "cookiefile", "", "Path to git http.cookiefile, leave empty for anonymous")
 	fs.Var(&o.projects, "gerrit-projects", "Set of gerrit repos to monitor on a host example: --gerrit-host=https://android.googlesource.com=platform/build,toolchain/llvm, repeat fs for each host")
 	fs.StringVar(&o.lastSyncFallback, "last-sync-fallback", "", "The /local/path, gs://path/to/object or s3://path/to/object to sync the latest timestamp")
+	fs.StringVar(&o.eventCursorPath, "event-cursor-path", "", "The /local/path, gs://path/to/object or s3://path/to/object to sync the position in the events-log. If set, changes are triggered from the events-log plugin of the instances and polling only reconciles missed changes")
 	fs.BoolVar(&o.dryRun, "dry-run", false, "Run in dry-run mode, performing no modifying actions.")
 	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.storage} {
 		group.AddFlags(fs)
@@ -220,6 +229,74 @@ func (st *syncTime) Update(newState client.LastSyncState) error {
 	return nil
 }
 
+type eventCursors struct {
+	val    map[string]adapter.EventCursor
+	lock   sync.RWMutex
+	path   string
+	opener io.Opener
+	ctx    context.Context
+}
+
+func (ec *eventCursors) init() error {
+	ec.lock.Lock()
+	defer ec.lock.Unlock()
+	ec.val = map[string]adapter.EventCursor{}
+	r, err := ec.opener.Reader(ec.ctx, ec.path)
+	if io.IsNotExist(err) {
+		logrus.Warnf("event cursors not found at %q, starting from now", ec.path)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("open: %v", err)
+	}
+	defer io.LogClose(r)
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read: %v", err)
+	}
+	if err := json.Unmarshal(buf, &ec.val); err != nil {
+		return fmt.Errorf("unmarshal: %v", err)
+	}
+	logrus.WithField("cursors", ec.val).Info("Initialized event cursors.")
+	return nil
+}
+
+func (ec *eventCursors) Current() map[string]adapter.EventCursor {
+	ec.lock.RLock()
+	defer ec.lock.RUnlock()
+	current := map[string]adapter.EventCursor{}
+	for instance, cursor := range ec.val {
+		current[instance] = cursor
+	}
+	return current
+}
+
+func (ec *eventCursors) Update(newCursors map[string]adapter.EventCursor) error {
+	ec.lock.Lock()
+	defer ec.lock.Unlock()
+
+	if reflect.DeepEqual(ec.val, newCursors) {
+		return nil
+	}
+
+	w, err := ec.opener.Writer(ec.ctx, ec.path)
+	if err != nil {
+		return fmt.Errorf("open for write %q: %v", ec.path, err)
+	}
+	cursorBytes, err := json.Marshal(newCursors)
+	if err != nil {
+		return fmt.Errorf("marshall cursors: %v", err)
+	}
+	if _, err := fmt.Fprint(w, string(cursorBytes)); err != nil {
+		io.LogClose(w)
+		return fmt.Errorf("write %q: %v", ec.path, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("close %q: %v", ec.path, err)
+	}
+	ec.val = newCursors
+	return nil
+}
+
 func main() {
 	logrusutil.ComponentInit()
 
@@ -256,13 +333,35 @@ func main() {
 	if err := st.init(o.projects); err != nil {
 		logrus.WithError(err).Fatal("Error initializing lastSyncFallback.")
 	}
-	c, err := adapter.NewController(&st, o.cookiefilePath, o.projects, prowJobClient, cfg)
+	var cursors adapter.EventCursorTracker
+	if o.eventCursorPath != "" {
+		ec := &eventCursors{
+			path:   o.eventCursorPath,
+			ctx:    ctx,
+			opener: op,
+		}
+		if err := ec.init(); err != nil {
+			logrus.WithError(err).Fatal("Error initializing event cursors.")
+		}
+		cursors = ec
+	}
+	c, err := adapter.NewController(&st, cursors, o.cookiefilePath, o.projects, prowJobClient, cfg)
 	if err != nil {
 		logrus.WithError(err).Fatal("Error creating gerrit client.")
 	}
 
 	logrus.Infof("Starting gerrit fetcher")
 
+	if cursors != nil {
+		interrupts.Tick(func() {
+			if err := c.SyncEvents(); err != nil {
+				logrus.WithError(err).Error("Error syncing events.")
+			}
+		}, func() time.Duration {
+			return cfg().Gerrit.EventsInterval.Duration
+		})
+	}
+
 	interrupts.Tick(func() {
 		start := time.Now()
 		if err := c.Sync(); err != nil {
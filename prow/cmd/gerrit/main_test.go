@@ -31,6 +31,7 @@ import (
 	"cloud.google.com/go/storage"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/test-infra/pkg/io"
+	"k8s.io/test-infra/prow/gerrit/adapter"
 	"k8s.io/test-infra/prow/gerrit/client"
 )
 
@@ -44,6 +45,18 @@ func (o fakeOpener) Writer(ctx context.Context, path string, _ ...io.WriterOptio
 	return nil, errors.New("do not call Writer")
 }
 
+func (o fakeOpener) Iterator(ctx context.Context, prefix, delimiter string) (io.ObjectIterator, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (o fakeOpener) Attributes(ctx context.Context, path string) (io.Attributes, error) {
+	return io.Attributes{}, errors.New("not implemented")
+}
+
+func (o fakeOpener) Delete(ctx context.Context, path string) error {
+	return errors.New("not implemented")
+}
+
 func TestFlags(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -253,3 +266,40 @@ func TestNewProjectAddition(t *testing.T) {
 		t.Error("expected tracker to initialize a new entry for qwe/qux, but did not")
 	}
 }
+
+func TestEventCursors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fake-gerrit-cursors")
+	if err != nil {
+		t.Fatalf("Could not create temp file: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cursors.json")
+
+	var noCreds string
+	ctx := context.Background()
+	open, err := io.NewOpener(ctx, noCreds, noCreds)
+	if err != nil {
+		t.Fatalf("Failed to create opener: %v", err)
+	}
+
+	ec := eventCursors{path: path, opener: open, ctx: ctx}
+	if err := ec.init(); err != nil {
+		t.Fatalf("Failed init: %v", err)
+	}
+	if current := ec.Current(); len(current) != 0 {
+		t.Errorf("expected no cursors without a file, got %v", current)
+	}
+
+	expected := map[string]adapter.EventCursor{"foo": {CreatedOn: 100, Processed: []string{"a", "b"}}}
+	if err := ec.Update(expected); err != nil {
+		t.Fatalf("Failed update: %v", err)
+	}
+
+	ec = eventCursors{path: path, opener: open, ctx: ctx}
+	if err := ec.init(); err != nil {
+		t.Fatalf("Failed init: %v", err)
+	}
+	if actual := ec.Current(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("init() failed to reload %v, got %v", expected, actual)
+	}
+}
@@ -33,6 +33,7 @@ import (
 	prowflagutil "k8s.io/test-infra/prow/flagutil"
 	"k8s.io/test-infra/prow/git/v2"
 	"k8s.io/test-infra/prow/hook"
+	"k8s.io/test-infra/prow/hook/queue"
 	"k8s.io/test-infra/prow/logrusutil"
 	"k8s.io/test-infra/prow/metrics"
 	"k8s.io/test-infra/prow/pjutil"
@@ -44,7 +45,8 @@ import (
 )
 
 type options struct {
-	port int
+	port      int
+	adminPort int
 
 	configPath    string
 	jobConfigPath string
@@ -58,6 +60,11 @@ type options struct {
 
 	webhookSecretFile string
 	slackTokenFile    string
+
+	queueDir         string
+	queueInMemory    bool
+	queueRetention   time.Duration
+	queueMaxAttempts int
 }
 
 func (o *options) Validate() error {
@@ -73,6 +80,7 @@ func (o *options) Validate() error {
 func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	var o options
 	fs.IntVar(&o.port, "port", 8888, "Port to listen on.")
+	fs.IntVar(&o.adminPort, "admin-port", 8889, "Port to serve the replay endpoint on. It must not be exposed publicly.")
 
 	fs.StringVar(&o.configPath, "config-path", "", "Path to config.yaml.")
 	fs.StringVar(&o.jobConfigPath, "job-config-path", "", "Path to prow job configs.")
@@ -86,6 +94,10 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 
 	fs.StringVar(&o.webhookSecretFile, "hmac-secret-file", "/etc/webhook/hmac", "Path to the file containing the GitHub HMAC secret.")
 	fs.StringVar(&o.slackTokenFile, "slack-token-file", "", "Path to the file containing the Slack token to use.")
+	fs.StringVar(&o.queueDir, "queue-dir", "", "Directory to persist events in until they are processed.")
+	fs.BoolVar(&o.queueInMemory, "queue-in-memory", false, "Keep events in memory for replay when --queue-dir is unset. They do not survive restarts.")
+	fs.DurationVar(&o.queueRetention, "queue-retention", 24*time.Hour, "How long to keep events for replay.")
+	fs.IntVar(&o.queueMaxAttempts, "queue-max-attempts", 3, "How many times to attempt processing an event before giving up on it.")
 	fs.Parse(args)
 	o.configPath = config.ConfigPath(o.configPath)
 	return o
@@ -196,6 +208,17 @@ func main() {
 
 	promMetrics := hook.NewMetrics()
 
+	// Events are not persisted unless asked for, the memory queue holds
+	// every payload received within the retention.
+	var eventQueue queue.Queue
+	if o.queueDir != "" {
+		if eventQueue, err = queue.NewDisk(o.queueDir, o.queueRetention); err != nil {
+			logrus.WithError(err).Fatal("Error creating event queue.")
+		}
+	} else if o.queueInMemory {
+		eventQueue = queue.NewMemory(o.queueRetention)
+	}
+
 	defer interrupts.WaitForGracefulShutdown()
 
 	// Expose prometheus metrics
@@ -208,6 +231,7 @@ func main() {
 		Plugins:        pluginAgent,
 		Metrics:        promMetrics,
 		TokenGenerator: secretAgent.GetTokenGenerator(o.webhookSecretFile),
+		Queue:          eventQueue,
 	}
 	interrupts.OnInterrupt(func() {
 		server.GracefulShutdown()
@@ -229,7 +253,19 @@ func main() {
 
 	httpServer := &http.Server{Addr: ":" + strconv.Itoa(o.port)}
 
+	// Serve the replay endpoint on its own port, so that it is not exposed
+	// along with /hook.
+	adminMux := http.NewServeMux()
+	adminMux.HandleFunc("/replay", server.ServeReplay)
+	adminServer := &http.Server{Addr: ":" + strconv.Itoa(o.adminPort), Handler: adminMux}
+
+	// Process the events that were being handled when hook last stopped.
+	if err := server.ProcessUnacked(o.queueMaxAttempts); err != nil {
+		logrus.WithError(err).Error("Error processing unacked events.")
+	}
+
 	health.ServeReady()
 
+	interrupts.ListenAndServe(adminServer, o.gracePeriod)
 	interrupts.ListenAndServe(httpServer, o.gracePeriod)
 }
@@ -96,12 +96,15 @@ func Test_gatherOptions(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			expected := &options{
 				port:              8888,
+				adminPort:         8889,
 				configPath:        "yo",
 				pluginConfig:      "/etc/plugins/plugins.yaml",
 				dryRun:            true,
 				gracePeriod:       180 * time.Second,
 				kubernetes:        flagutil.KubernetesOptions{DeckURI: "http://whatever"},
 				webhookSecretFile: "/etc/webhook/hmac",
+				queueRetention:    24 * time.Hour,
+				queueMaxAttempts:  3,
 			}
 			expectedfs := flag.NewFlagSet("fake-flags", flag.PanicOnError)
 			expected.github.AddFlags(expectedfs)
@@ -0,0 +1,274 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// hookreplay records GitHub webhooks and replays them against a local hook
+// talking to a GitHub API responder. See the README for the workflow.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/hook/queue"
+	"k8s.io/test-infra/prow/hookreplay"
+	"k8s.io/test-infra/prow/interrupts"
+	"k8s.io/test-infra/prow/logrusutil"
+)
+
+const usage = `Usage: hookreplay <mode> [flags]
+
+Modes:
+  record  Archive the webhooks sent to it.
+  replay  Send archived webhooks to a hook.
+  github  Serve the GitHub API of a local hook and log the calls of plugins.
+  diff    Compare the calls logged by two runs of github.
+`
+
+type options struct {
+	mode string
+
+	port        int
+	archive     string
+	retention   time.Duration
+	hmacFile    string
+	address     string
+	guids       stringSlice
+	since       string
+	until       string
+	upstream    string
+	responses   string
+	record      string
+	calls       string
+	gracePeriod time.Duration
+	before      string
+	after       string
+}
+
+type stringSlice []string
+
+func (s *stringSlice) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *stringSlice) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func gatherOptions(args []string) (options, error) {
+	var o options
+	if len(args) == 0 {
+		return o, errors.New("a mode is required")
+	}
+	o.mode = args[0]
+	fs := flag.NewFlagSet(o.mode, flag.ExitOnError)
+	switch o.mode {
+	case "record":
+		fs.IntVar(&o.port, "port", 8888, "Port to receive webhooks on.")
+		fs.StringVar(&o.hmacFile, "hmac-secret-file", "", "Path to the file containing the HMAC secret of the webhooks.")
+		fs.DurationVar(&o.retention, "retention", 7*24*time.Hour, "How long to keep recorded webhooks.")
+	case "replay":
+		fs.StringVar(&o.address, "address", "http://localhost:8888/hook", "Where to send the webhooks.")
+		fs.StringVar(&o.hmacFile, "hmac-secret-file", "", "Path to the file containing the HMAC secret of the hook.")
+		fs.Var(&o.guids, "guid", "GUID of a webhook to replay. May be repeated.")
+		fs.StringVar(&o.since, "since", "", "Replay the webhooks received since this time, in RFC 3339 format.")
+		fs.StringVar(&o.until, "until", "", "Replay the webhooks received until this time, in RFC 3339 format. Defaults to now.")
+	case "github":
+		fs.IntVar(&o.port, "port", 8890, "Port to serve the GitHub API on. The default avoids the ports of a local hook.")
+		fs.StringVar(&o.upstream, "upstream", "", "GitHub API to proxy reads to and record, such as https://api.github.com. Only recorded responses are served if unset.")
+		fs.StringVar(&o.responses, "responses", "", "Path to the file of recorded responses to serve.")
+		fs.StringVar(&o.record, "record", "", "Path to write the served responses to on exit, to serve them again later.")
+		fs.StringVar(&o.calls, "calls", "", "Path to write the calls of plugins to on exit.")
+		fs.DurationVar(&o.gracePeriod, "grace-period", 10*time.Second, "On shutdown, try to handle remaining requests for the specified duration.")
+	case "diff":
+		fs.StringVar(&o.before, "before", "", "Path to the calls of the first run.")
+		fs.StringVar(&o.after, "after", "", "Path to the calls of the second run.")
+	default:
+		return o, fmt.Errorf("unknown mode %q", o.mode)
+	}
+	if o.mode == "record" || o.mode == "replay" {
+		fs.StringVar(&o.archive, "archive", "", "Directory of the recorded webhooks. Directories of hook's --queue-dir can be replayed too.")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return o, err
+	}
+	return o, o.Validate()
+}
+
+func (o *options) Validate() error {
+	switch o.mode {
+	case "record", "replay":
+		if o.archive == "" {
+			return errors.New("--archive is required")
+		}
+		if o.hmacFile == "" {
+			return errors.New("--hmac-secret-file is required")
+		}
+	}
+	if o.mode == "replay" {
+		if len(o.guids) == 0 && o.since == "" {
+			return errors.New("select webhooks with --guid or --since")
+		}
+		if len(o.guids) > 0 && (o.since != "" || o.until != "") {
+			return errors.New("webhooks are selected by --guid or by --since and --until, not both")
+		}
+	}
+	if o.mode == "diff" && (o.before == "" || o.after == "") {
+		return errors.New("--before and --after are required")
+	}
+	return nil
+}
+
+func main() {
+	logrusutil.ComponentInit()
+
+	o, err := gatherOptions(os.Args[1:])
+	if err != nil {
+		fmt.Fprint(os.Stderr, usage)
+		logrus.WithError(err).Fatal("Invalid options")
+	}
+
+	switch o.mode {
+	case "record":
+		err = record(o)
+	case "replay":
+		err = replay(o)
+	case "github":
+		err = serveGitHub(o)
+	case "diff":
+		err = diff(o)
+	}
+	if err != nil {
+		logrus.WithError(err).Fatalf("Failed to %s.", o.mode)
+	}
+}
+
+func record(o options) error {
+	hmac, err := ioutil.ReadFile(o.hmacFile)
+	if err != nil {
+		return fmt.Errorf("failed to read hmac secret: %v", err)
+	}
+	archive, err := queue.NewDisk(o.archive, o.retention)
+	if err != nil {
+		return err
+	}
+	defer interrupts.WaitForGracefulShutdown()
+	mux := http.NewServeMux()
+	mux.Handle("/hook", &hookreplay.Recorder{Archive: archive, TokenGenerator: func() []byte { return hmac }})
+	interrupts.ListenAndServe(&http.Server{Addr: ":" + strconv.Itoa(o.port), Handler: mux}, 10*time.Second)
+	return nil
+}
+
+func replay(o options) error {
+	hmac, err := ioutil.ReadFile(o.hmacFile)
+	if err != nil {
+		return fmt.Errorf("failed to read hmac secret: %v", err)
+	}
+	// Archived webhooks are never pruned while replaying them.
+	archive, err := queue.NewDisk(o.archive, time.Duration(1<<63-1))
+	if err != nil {
+		return err
+	}
+	var since, until time.Time
+	if o.since != "" {
+		if since, err = time.Parse(time.RFC3339, o.since); err != nil {
+			return fmt.Errorf("invalid --since: %v", err)
+		}
+		until = time.Now()
+		if o.until != "" {
+			if until, err = time.Parse(time.RFC3339, o.until); err != nil {
+				return fmt.Errorf("invalid --until: %v", err)
+			}
+		}
+	}
+	events, err := hookreplay.Select(archive, o.guids, since, until)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		l := logrus.WithFields(logrus.Fields{"event-type": event.Type, "event-GUID": event.GUID})
+		if err := hookreplay.Send(o.address, hmac, event); err != nil {
+			return fmt.Errorf("failed to replay %s: %v", event.GUID, err)
+		}
+		l.Info("Replayed event.")
+	}
+	return nil
+}
+
+func serveGitHub(o options) error {
+	var exchanges []hookreplay.Exchange
+	if o.responses != "" {
+		if err := readJSON(o.responses, &exchanges); err != nil {
+			return err
+		}
+	}
+	responder := hookreplay.NewResponder(o.upstream, exchanges)
+	defer interrupts.WaitForGracefulShutdown()
+	interrupts.OnInterrupt(func() {
+		if o.record != "" {
+			if err := writeJSON(o.record, responder.Exchanges()); err != nil {
+				logrus.WithError(err).Error("Failed to write responses.")
+			}
+		}
+		if o.calls != "" {
+			if err := writeJSON(o.calls, responder.Calls()); err != nil {
+				logrus.WithError(err).Error("Failed to write calls.")
+			}
+		}
+	})
+	interrupts.ListenAndServe(&http.Server{Addr: ":" + strconv.Itoa(o.port), Handler: responder}, o.gracePeriod)
+	return nil
+}
+
+func diff(o options) error {
+	var before, after []hookreplay.Call
+	if err := readJSON(o.before, &before); err != nil {
+		return err
+	}
+	if err := readJSON(o.after, &after); err != nil {
+		return err
+	}
+	fmt.Print(hookreplay.Diff(before, after))
+	return nil
+}
+
+func readJSON(path string, into interface{}) error {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if err := json.Unmarshal(raw, into); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %v", path, err)
+	}
+	return nil
+}
+
+func writeJSON(path string, value interface{}) error {
+	raw, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, raw, 0644)
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -44,27 +45,35 @@ const (
 )
 
 type options struct {
-	config            string
-	confirm           bool
-	dump              string
-	dumpFull          bool
-	maximumDelta      float64
-	minAdmins         int
-	requireSelf       bool
-	requiredAdmins    flagutil.Strings
-	fixOrg            bool
-	fixOrgMembers     bool
-	fixTeamMembers    bool
-	fixTeams          bool
-	fixTeamRepos      bool
-	fixRepos          bool
-	ignoreSecretTeams bool
-	allowRepoArchival bool
-	allowRepoPublish  bool
-	github            flagutil.GitHubOptions
-	tokenBurst        int
-	tokensPerHour     int
-	logLevel          string
+	config               string
+	confirm              bool
+	plan                 string
+	planMarkdown         string
+	applyPlan            string
+	dump                 string
+	dumpFull             bool
+	maximumDelta         float64
+	minAdmins            int
+	requireSelf          bool
+	requiredAdmins       flagutil.Strings
+	fixOrg               bool
+	fixOrgMembers        bool
+	fixTeamMembers       bool
+	fixTeams             bool
+	fixTeamRepos         bool
+	fixRepos             bool
+	fixRepoCollaborators bool
+	fixRepoWebhooks      bool
+	fixRepoTopics        bool
+	fixRepoSecurity      bool
+	webhookSecretDir     string
+	ignoreSecretTeams    bool
+	allowRepoArchival    bool
+	allowRepoPublish     bool
+	github               flagutil.GitHubOptions
+	tokenBurst           int
+	tokensPerHour        int
+	logLevel             string
 }
 
 func parseOptions() options {
@@ -83,6 +92,9 @@ func (o *options) parseArgs(flags *flag.FlagSet, args []string) error {
 	flags.Float64Var(&o.maximumDelta, "maximum-removal-delta", defaultDelta, "Fail if config removes more than this fraction of current members")
 	flags.StringVar(&o.config, "config-path", "", "Path to org config.yaml")
 	flags.BoolVar(&o.confirm, "confirm", false, "Mutate github if set")
+	flags.StringVar(&o.plan, "plan", "", "Write the changes needed to sync --config-path to this file as JSON instead of making them")
+	flags.StringVar(&o.planMarkdown, "plan-markdown", "", "Also write a Markdown summary of the --plan changes to this file")
+	flags.StringVar(&o.applyPlan, "apply-plan", "", "Make exactly the changes in this --plan file, failing if they no longer match --config-path")
 	flags.IntVar(&o.tokensPerHour, "tokens", defaultTokens, "Throttle hourly token consumption (0 to disable)")
 	flags.IntVar(&o.tokenBurst, "token-burst", defaultBurst, "Allow consuming a subset of hourly tokens in a short burst")
 	flags.StringVar(&o.dump, "dump", "", "Output current config of this org if set")
@@ -94,6 +106,11 @@ func (o *options) parseArgs(flags *flag.FlagSet, args []string) error {
 	flags.BoolVar(&o.fixTeamMembers, "fix-team-members", false, "Add/remove team members if set")
 	flags.BoolVar(&o.fixTeamRepos, "fix-team-repos", false, "Add/remove team permissions on repos if set")
 	flags.BoolVar(&o.fixRepos, "fix-repos", false, "Create/update repositories if set")
+	flags.BoolVar(&o.fixRepoCollaborators, "fix-repo-collaborators", false, "Add/remove direct collaborators of repos that configure them if set")
+	flags.BoolVar(&o.fixRepoWebhooks, "fix-repo-webhooks", false, "Create/update/delete webhooks of repos that configure them if set")
+	flags.BoolVar(&o.fixRepoTopics, "fix-repo-topics", false, "Replace topics of repos that configure them if set")
+	flags.BoolVar(&o.fixRepoSecurity, "fix-repo-security", false, "Enable/disable vulnerability alerts of repos that configure them if set")
+	flags.StringVar(&o.webhookSecretDir, "webhook-secret-dir", "", "Directory holding the webhook secrets repos refer to by file name")
 	flags.BoolVar(&o.allowRepoArchival, "allow-repo-archival", false, "If set, archiving repos is allowed while updating repos")
 	flags.BoolVar(&o.allowRepoPublish, "allow-repo-publish", false, "If set, making private repos public is allowed while updating repos")
 	flags.StringVar(&o.logLevel, "log-level", logrus.InfoLevel.String(), fmt.Sprintf("Logging level, one of %v", logrus.AllLevels))
@@ -125,6 +142,22 @@ func (o *options) parseArgs(flags *flag.FlagSet, args []string) error {
 		return fmt.Errorf("--config-path=%s and --dump=%s cannot both be set", o.config, o.dump)
 	}
 
+	if o.plan != "" && o.config == "" {
+		return errors.New("--plan requires --config-path")
+	}
+	if o.plan != "" && o.confirm {
+		return fmt.Errorf("--plan=%s cannot be used with --confirm", o.plan)
+	}
+	if o.planMarkdown != "" && o.plan == "" {
+		return errors.New("--plan-markdown can't be used without --plan")
+	}
+	if o.applyPlan != "" && o.config == "" {
+		return errors.New("--apply-plan requires --config-path")
+	}
+	if o.applyPlan != "" && !o.confirm {
+		return fmt.Errorf("--apply-plan=%s requires --confirm", o.applyPlan)
+	}
+
 	if o.dumpFull && o.dump == "" {
 		return errors.New("--dump-full can't be used without --dump")
 	}
@@ -137,6 +170,17 @@ func (o *options) parseArgs(flags *flag.FlagSet, args []string) error {
 		return fmt.Errorf("--fix-team-repos requires --fix-teams")
 	}
 
+	for flag, set := range map[string]bool{
+		"--fix-repo-collaborators": o.fixRepoCollaborators,
+		"--fix-repo-webhooks":      o.fixRepoWebhooks,
+		"--fix-repo-topics":        o.fixRepoTopics,
+		"--fix-repo-security":      o.fixRepoSecurity,
+	} {
+		if set && !o.fixRepos {
+			return fmt.Errorf("%s requires --fix-repos", flag)
+		}
+	}
+
 	level, err := logrus.ParseLevel(o.logLevel)
 	if err != nil {
 		return fmt.Errorf("--log-level invalid: %v", err)
@@ -165,7 +209,7 @@ func main() {
 	}
 
 	if o.dump != "" {
-		ret, err := dumpOrgConfig(githubClient, o.dump, o.ignoreSecretTeams)
+		ret, err := dumpOrgConfig(githubClient, o.dump, o.ignoreSecretTeams, o.dumpFull)
 		if err != nil {
 			logrus.WithError(err).Fatalf("Dump %s failed to collect current data.", o.dump)
 		}
@@ -196,6 +240,22 @@ func main() {
 		logrus.WithError(err).Fatal("Failed to load configuration")
 	}
 
+	if o.plan != "" {
+		if err := writePlan(o, githubClient, cfg); err != nil {
+			logrus.WithError(err).Fatal("Planning failed.")
+		}
+		logrus.Infof("Wrote plan to %s.", o.plan)
+		return
+	}
+
+	if o.applyPlan != "" {
+		if err := applySavedPlan(o, githubClient, cfg); err != nil {
+			logrus.WithError(err).Fatal("Applying plan failed.")
+		}
+		logrus.Info("Finished applying plan.")
+		return
+	}
+
 	for name, orgcfg := range cfg.Orgs {
 		if err := configureOrg(o, githubClient, name, orgcfg); err != nil {
 			logrus.Fatalf("Configuration failed: %v", err)
@@ -212,10 +272,14 @@ type dumpClient interface {
 	ListTeamRepos(id int) ([]github.Repo, error)
 	GetRepo(owner, name string) (github.FullRepo, error)
 	GetRepos(org string, isUser bool) ([]github.Repo, error)
+	ListDirectCollaborators(org, repo string) ([]github.User, error)
+	ListRepoHooks(org, repo string) ([]github.Hook, error)
+	GetRepoTopics(owner, name string) ([]string, error)
+	GetVulnerabilityAlerts(owner, name string) (bool, error)
 	BotName() (string, error)
 }
 
-func dumpOrgConfig(client dumpClient, orgName string, ignoreSecretTeams bool) (*org.Config, error) {
+func dumpOrgConfig(client dumpClient, orgName string, ignoreSecretTeams, withRepoSettings bool) (*org.Config, error) {
 	out := org.Config{}
 	meta, err := client.GetOrg(orgName)
 	if err != nil {
@@ -363,24 +427,70 @@ func dumpOrgConfig(client dumpClient, orgName string, ignoreSecretTeams bool) (*
 			return nil, fmt.Errorf("failed to get repo: %v", err)
 		}
 		logrus.WithField("repo", full.FullName).Debug("Recording repo.")
-		out.Repos[full.Name] = org.PruneRepoDefaults(org.Repo{
-			Description:      &full.Description,
-			HomePage:         &full.Homepage,
-			Private:          &full.Private,
-			HasIssues:        &full.HasIssues,
-			HasProjects:      &full.HasProjects,
-			HasWiki:          &full.HasWiki,
-			AllowMergeCommit: &full.AllowMergeCommit,
-			AllowSquashMerge: &full.AllowSquashMerge,
-			AllowRebaseMerge: &full.AllowRebaseMerge,
-			Archived:         &full.Archived,
-			DefaultBranch:    &full.DefaultBranch,
-		})
+		// The settings cost several requests per repo, so they are only
+		// dumped for full configs.
+		settings := &org.Repo{}
+		if withRepoSettings {
+			if settings, err = dumpRepoSettings(client, orgName, full.Name); err != nil {
+				return nil, fmt.Errorf("failed to get repo %s settings: %v", full.Name, err)
+			}
+		}
+		settings.Description = &full.Description
+		settings.HomePage = &full.Homepage
+		settings.Private = &full.Private
+		settings.HasIssues = &full.HasIssues
+		settings.HasProjects = &full.HasProjects
+		settings.HasWiki = &full.HasWiki
+		settings.AllowMergeCommit = &full.AllowMergeCommit
+		settings.AllowSquashMerge = &full.AllowSquashMerge
+		settings.AllowRebaseMerge = &full.AllowRebaseMerge
+		settings.DeleteBranchOnMerge = &full.DeleteBranchOnMerge
+		settings.Archived = &full.Archived
+		settings.DefaultBranch = &full.DefaultBranch
+		out.Repos[full.Name] = org.PruneRepoDefaults(*settings)
 	}
 
 	return &out, nil
 }
 
+// dumpRepoSettings records the collaborators, webhooks, topics and security
+// settings of a repo. Webhook secrets cannot be read back from GitHub.
+func dumpRepoSettings(client dumpClient, orgName, repoName string) (*org.Repo, error) {
+	var out org.Repo
+	collaborators, err := client.ListDirectCollaborators(orgName, repoName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collaborators: %v", err)
+	}
+	out.Collaborators = make(map[string]github.RepoPermissionLevel, len(collaborators))
+	for _, u := range collaborators {
+		out.Collaborators[u.Login] = github.LevelFromPermissions(u.Permissions)
+	}
+
+	hooks, err := client.ListRepoHooks(orgName, repoName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %v", err)
+	}
+	out.Webhooks = make(map[string]org.Webhook, len(hooks))
+	for _, h := range hooks {
+		active := h.Active
+		out.Webhooks[h.Config.URL] = org.Webhook{
+			Events:      h.Events,
+			ContentType: h.Config.ContentType,
+			Active:      &active,
+		}
+	}
+
+	if out.Topics, err = client.GetRepoTopics(orgName, repoName); err != nil {
+		return nil, fmt.Errorf("failed to get topics: %v", err)
+	}
+	alerts, err := client.GetVulnerabilityAlerts(orgName, repoName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vulnerability alerts: %v", err)
+	}
+	out.VulnerabilityAlerts = &alerts
+	return &out, nil
+}
+
 type orgClient interface {
 	BotName() (string, error)
 	ListOrgMembers(org, role string) ([]github.TeamMember, error)
@@ -837,6 +947,8 @@ func configureOrg(opt options, client github.Client, orgName string, orgConfig o
 		logrus.Info("Skipping org repositories configuration")
 	} else if err := configureRepos(opt, client, orgName, orgConfig); err != nil {
 		return fmt.Errorf("failed to configure %s repos: %v", orgName, err)
+	} else if err := configureRepoSettings(opt, client, orgName, orgConfig); err != nil {
+		return fmt.Errorf("failed to configure %s repo settings: %v", orgName, err)
 	}
 
 	if !opt.fixTeams {
@@ -887,6 +999,8 @@ func newRepoCreateRequest(name string, definition org.Repo) github.RepoCreateReq
 			AllowSquashMerge: definition.AllowSquashMerge,
 			AllowMergeCommit: definition.AllowMergeCommit,
 			AllowRebaseMerge: definition.AllowRebaseMerge,
+
+			DeleteBranchOnMerge: definition.DeleteBranchOnMerge,
 		},
 	}
 
@@ -922,6 +1036,21 @@ func validateRepos(repos map[string]org.Repo) error {
 		return fmt.Errorf("found duplicate repo names (GitHub repo names are case-insensitive): %s", strings.Join(dups, ", "))
 	}
 
+	// GitHub has no way to give a collaborator no permission, leaving them
+	// out of the collaborators removes them.
+	var noAccess []string
+	for name, repo := range repos {
+		for user, permission := range repo.Collaborators {
+			if permission == github.None {
+				noAccess = append(noAccess, fmt.Sprintf("%s/%s", name, user))
+			}
+		}
+	}
+	if len(noAccess) > 0 {
+		sort.Strings(noAccess)
+		return fmt.Errorf("found collaborators with permission %s, remove them from the collaborators instead: %s", github.None, strings.Join(noAccess, ", "))
+	}
+
 	return nil
 }
 
@@ -952,6 +1081,8 @@ func newRepoUpdateRequest(current github.FullRepo, name string, repo org.Repo) g
 			AllowSquashMerge: setBool(current.AllowSquashMerge, repo.AllowSquashMerge),
 			AllowMergeCommit: setBool(current.AllowMergeCommit, repo.AllowMergeCommit),
 			AllowRebaseMerge: setBool(current.AllowRebaseMerge, repo.AllowRebaseMerge),
+
+			DeleteBranchOnMerge: setBool(current.DeleteBranchOnMerge, repo.DeleteBranchOnMerge),
 		},
 		DefaultBranch: setString(current.DefaultBranch, repo.DefaultBranch),
 		Archived:      setBool(current.Archived, repo.Archived),
@@ -118,6 +118,79 @@ func TestOptions(t *testing.T) {
 			name: "reject --fix-team-members without --fix-teams",
 			args: []string{"--config-path=foo", "--fix-team-members"},
 		},
+		{
+			name: "reject --plan and --confirm",
+			args: []string{"--config-path=foo", "--plan=plan.json", "--confirm"},
+		},
+		{
+			name: "reject --plan without --config-path",
+			args: []string{"--dump=frogger", "--plan=plan.json"},
+		},
+		{
+			name: "reject --plan-markdown without --plan",
+			args: []string{"--config-path=foo", "--plan-markdown=plan.md"},
+		},
+		{
+			name: "reject --apply-plan without --confirm",
+			args: []string{"--config-path=foo", "--apply-plan=plan.json"},
+		},
+		{
+			name: "allow --plan with --plan-markdown",
+			args: []string{"--config-path=foo", "--plan=plan.json", "--plan-markdown=plan.md"},
+			expected: &options{
+				config:        "foo",
+				plan:          "plan.json",
+				planMarkdown:  "plan.md",
+				minAdmins:     defaultMinAdmins,
+				requireSelf:   true,
+				maximumDelta:  defaultDelta,
+				tokensPerHour: defaultTokens,
+				tokenBurst:    defaultBurst,
+				logLevel:      "info",
+			},
+		},
+		{
+			name: "allow --apply-plan with --confirm",
+			args: []string{"--config-path=foo", "--apply-plan=plan.json", "--confirm"},
+			expected: &options{
+				config:        "foo",
+				confirm:       true,
+				applyPlan:     "plan.json",
+				minAdmins:     defaultMinAdmins,
+				requireSelf:   true,
+				maximumDelta:  defaultDelta,
+				tokensPerHour: defaultTokens,
+				tokenBurst:    defaultBurst,
+				logLevel:      "info",
+			},
+		},
+		{
+			name: "reject --fix-repo-collaborators without --fix-repos",
+			args: []string{"--config-path=foo", "--fix-repo-collaborators"},
+		},
+		{
+			name: "reject --fix-repo-webhooks without --fix-repos",
+			args: []string{"--config-path=foo", "--fix-repo-webhooks"},
+		},
+		{
+			name: "allow repo settings with --fix-repos",
+			args: []string{"--config-path=foo", "--fix-repos", "--fix-repo-collaborators", "--fix-repo-webhooks", "--fix-repo-topics", "--fix-repo-security", "--webhook-secret-dir=/etc/hooks"},
+			expected: &options{
+				config:               "foo",
+				fixRepos:             true,
+				fixRepoCollaborators: true,
+				fixRepoWebhooks:      true,
+				fixRepoTopics:        true,
+				fixRepoSecurity:      true,
+				webhookSecretDir:     "/etc/hooks",
+				minAdmins:            defaultMinAdmins,
+				requireSelf:          true,
+				maximumDelta:         defaultDelta,
+				tokensPerHour:        defaultTokens,
+				tokenBurst:           defaultBurst,
+				logLevel:             "info",
+			},
+		},
 		{
 			name: "allow disabled throttle",
 			args: []string{"--config-path=foo", "--tokens=0"},
@@ -1732,10 +1805,13 @@ func TestDumpOrgConfig(t *testing.T) {
 	repoDescription := "awesome testing project"
 	repoHomepage := "https://www.somewhe.re/something/"
 	master := "master-branch"
+	contentType := "json"
+	none := github.RepoPermissionLevel("")
 	cases := []struct {
 		name              string
 		orgOverride       string
 		ignoreSecretTeams bool
+		repoSettings      bool
 		meta              github.Organization
 		members           []string
 		admins            []string
@@ -1744,6 +1820,10 @@ func TestDumpOrgConfig(t *testing.T) {
 		maintainers       map[int][]string
 		repoPermissions   map[int][]github.Repo
 		repos             []github.FullRepo
+		collaborators     map[string][]github.User
+		hooks             map[string][]github.Hook
+		topics            map[string][]string
+		alerts            map[string]bool
 		expected          org.Config
 		err               bool
 	}{
@@ -1919,6 +1999,99 @@ func TestDumpOrgConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:         "dumps repo settings",
+			repoSettings: true,
+			admins:       []string{"admin"},
+			repos: []github.FullRepo{
+				{
+					Repo:                github.Repo{Name: repoName, HasIssues: true, HasWiki: true, DefaultBranch: "master"},
+					AllowMergeCommit:    true,
+					AllowSquashMerge:    true,
+					AllowRebaseMerge:    true,
+					DeleteBranchOnMerge: true,
+				},
+			},
+			collaborators: map[string][]github.User{
+				repoName: {{Login: "alice", Permissions: github.RepoPermissions{Pull: true, Push: true}}},
+			},
+			hooks: map[string][]github.Hook{
+				repoName: {{ID: 1, Events: []string{"push"}, Active: true, Config: github.HookConfig{URL: "https://hook.example.com", ContentType: &contentType}}},
+			},
+			topics: map[string][]string{repoName: {"testing"}},
+			alerts: map[string]bool{repoName: true},
+			expected: org.Config{
+				Metadata: org.Metadata{
+					Name:                         &empty,
+					BillingEmail:                 &empty,
+					Company:                      &empty,
+					Email:                        &empty,
+					Description:                  &empty,
+					Location:                     &empty,
+					HasOrganizationProjects:      &no,
+					HasRepositoryProjects:        &no,
+					DefaultRepositoryPermission:  &none,
+					MembersCanCreateRepositories: &no,
+				},
+				Teams:  map[string]org.Team{},
+				Admins: []string{"admin"},
+				Repos: map[string]org.Repo{
+					repoName: {
+						HasProjects:         &no,
+						DeleteBranchOnMerge: &yes,
+						VulnerabilityAlerts: &yes,
+						Topics:              []string{"testing"},
+						Collaborators:       map[string]github.RepoPermissionLevel{"alice": github.Write},
+						Webhooks: map[string]org.Webhook{
+							"https://hook.example.com": {Events: []string{"push"}, ContentType: &contentType, Active: &yes},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "only dumps repo settings for full configs",
+			admins: []string{"admin"},
+			repos: []github.FullRepo{
+				{
+					Repo:                github.Repo{Name: repoName, HasIssues: true, HasWiki: true, DefaultBranch: "master"},
+					AllowMergeCommit:    true,
+					AllowSquashMerge:    true,
+					AllowRebaseMerge:    true,
+					DeleteBranchOnMerge: true,
+				},
+			},
+			collaborators: map[string][]github.User{
+				repoName: {{Login: "alice", Permissions: github.RepoPermissions{Pull: true, Push: true}}},
+			},
+			hooks: map[string][]github.Hook{
+				repoName: {{ID: 1, Events: []string{"push"}, Active: true, Config: github.HookConfig{URL: "https://hook.example.com", ContentType: &contentType}}},
+			},
+			topics: map[string][]string{repoName: {"testing"}},
+			alerts: map[string]bool{repoName: true},
+			expected: org.Config{
+				Metadata: org.Metadata{
+					Name:                         &empty,
+					BillingEmail:                 &empty,
+					Company:                      &empty,
+					Email:                        &empty,
+					Description:                  &empty,
+					Location:                     &empty,
+					HasOrganizationProjects:      &no,
+					HasRepositoryProjects:        &no,
+					DefaultRepositoryPermission:  &none,
+					MembersCanCreateRepositories: &no,
+				},
+				Teams:  map[string]org.Team{},
+				Admins: []string{"admin"},
+				Repos: map[string]org.Repo{
+					repoName: {
+						HasProjects:         &no,
+						DeleteBranchOnMerge: &yes,
+					},
+				},
+			},
+		},
 		{
 			name:              "ignores private teams when expected to",
 			ignoreSecretTeams: true,
@@ -2039,8 +2212,12 @@ func TestDumpOrgConfig(t *testing.T) {
 				maintainers:     tc.maintainers,
 				repoPermissions: tc.repoPermissions,
 				repos:           tc.repos,
+				collaborators:   tc.collaborators,
+				hooks:           tc.hooks,
+				topics:          tc.topics,
+				alerts:          tc.alerts,
 			}
-			actual, err := dumpOrgConfig(fc, orgName, tc.ignoreSecretTeams)
+			actual, err := dumpOrgConfig(fc, orgName, tc.ignoreSecretTeams, tc.repoSettings)
 			switch {
 			case err != nil:
 				if !tc.err {
@@ -2072,6 +2249,10 @@ type fakeDumpClient struct {
 	maintainers     map[int][]string
 	repoPermissions map[int][]github.Repo
 	repos           []github.FullRepo
+	collaborators   map[string][]github.User
+	hooks           map[string][]github.Hook
+	topics          map[string][]string
+	alerts          map[string]bool
 }
 
 func (c fakeDumpClient) GetOrg(name string) (*github.Organization, error) {
@@ -2172,6 +2353,22 @@ func (c fakeDumpClient) GetRepo(owner, repo string) (github.FullRepo, error) {
 	return github.FullRepo{}, fmt.Errorf("not found")
 }
 
+func (c fakeDumpClient) ListDirectCollaborators(org, repo string) ([]github.User, error) {
+	return c.collaborators[repo], nil
+}
+
+func (c fakeDumpClient) ListRepoHooks(org, repo string) ([]github.Hook, error) {
+	return c.hooks[repo], nil
+}
+
+func (c fakeDumpClient) GetRepoTopics(org, repo string) ([]string, error) {
+	return c.topics[repo], nil
+}
+
+func (c fakeDumpClient) GetVulnerabilityAlerts(org, repo string) (bool, error) {
+	return c.alerts[repo], nil
+}
+
 func (c fakeDumpClient) BotName() (string, error) {
 	return "admin", nil
 }
@@ -2996,6 +3193,19 @@ func TestValidateRepos(t *testing.T) {
 				"repo": {Previously: []string{"REPO"}},
 			},
 		},
+		{
+			description: "allows collaborators with permissions",
+			config: map[string]org.Repo{
+				"repo": {Collaborators: map[string]github.RepoPermissionLevel{"alice": github.Read, "bob": github.Admin}},
+			},
+		},
+		{
+			description: "rejects collaborators without permission",
+			config: map[string]org.Repo{
+				"repo": {Collaborators: map[string]github.RepoPermissionLevel{"alice": github.Read, "bob": github.None}},
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tc := range testCases {
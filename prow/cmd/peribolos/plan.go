@@ -0,0 +1,801 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config/org"
+	"k8s.io/test-infra/prow/github"
+)
+
+// Kinds of operations in a plan, one for each mutating GitHub call peribolos makes.
+const (
+	opEditOrg              = "edit-org"
+	opUpdateOrgMembership  = "update-org-membership"
+	opRemoveOrgMembership  = "remove-org-membership"
+	opCreateRepo           = "create-repo"
+	opUpdateRepo           = "update-repo"
+	opCreateTeam           = "create-team"
+	opEditTeam             = "edit-team"
+	opDeleteTeam           = "delete-team"
+	opUpdateTeamMembership = "update-team-membership"
+	opRemoveTeamMembership = "remove-team-membership"
+	opUpdateTeamRepo       = "update-team-repo"
+	opRemoveTeamRepo       = "remove-team-repo"
+	opAddCollaborator      = "add-collaborator"
+	opRemoveCollaborator   = "remove-collaborator"
+	opCreateRepoHook       = "create-repo-hook"
+	opEditRepoHook         = "edit-repo-hook"
+	opDeleteRepoHook       = "delete-repo-hook"
+	opReplaceRepoTopics    = "replace-repo-topics"
+	opSetVulnAlerts        = "set-vulnerability-alerts"
+)
+
+// redacted replaces webhook secrets in plans, which are meant to be shared.
+const redacted = "<redacted>"
+
+// plan is the ordered list of mutations needed to bring GitHub in line with the config.
+type plan struct {
+	Operations []operation `json:"operations"`
+
+	// placeholders counts the teams the plan creates, which are given negative IDs
+	// until the plan is applied.
+	placeholders int
+}
+
+// operation is a single mutation of GitHub. Only the fields relevant to its Kind are set.
+type operation struct {
+	Kind         string                     `json:"kind"`
+	Org          string                     `json:"org"`
+	Description  string                     `json:"description"`
+	User         string                     `json:"user,omitempty"`
+	Admin        bool                       `json:"admin,omitempty"`
+	Maintainer   bool                       `json:"maintainer,omitempty"`
+	TeamID       int                        `json:"team_id,omitempty"`
+	TeamName     string                     `json:"team_name,omitempty"`
+	ParentTeam   string                     `json:"parent_team,omitempty"`
+	Repo         string                     `json:"repo,omitempty"`
+	Permission   github.RepoPermissionLevel `json:"permission,omitempty"`
+	Organization *github.Organization       `json:"organization,omitempty"`
+	Team         *github.Team               `json:"team,omitempty"`
+	RepoCreate   *github.RepoCreateRequest  `json:"repo_create,omitempty"`
+	RepoUpdate   *github.RepoUpdateRequest  `json:"repo_update,omitempty"`
+	HookID       int                        `json:"hook_id,omitempty"`
+	Hook         *github.HookRequest        `json:"hook,omitempty"`
+	Topics       []string                   `json:"topics,omitempty"`
+	Enabled      bool                       `json:"enabled,omitempty"`
+
+	// secret is the webhook secret redacted from Hook, which is only known
+	// to plans computed in this process.
+	secret string
+}
+
+// planningClient records the mutations peribolos would make to an org instead
+// of making them. Reads are passed through, except for teams and repos the plan
+// creates, which are reported as empty, and repos the plan renames, which are
+// read under their current name.
+type planningClient struct {
+	github.Client
+	plan      *plan
+	org       string
+	teamNames map[int]string
+	// repos maps the lower-cased names of repos the plan creates or renames
+	// to their current name, which is empty for created repos.
+	repos map[string]string
+}
+
+func newPlanningClient(client github.Client, p *plan, orgName string) *planningClient {
+	return &planningClient{Client: client, plan: p, org: orgName, teamNames: map[int]string{}, repos: map[string]string{}}
+}
+
+// repo returns the current name of a repo, and whether it exists yet.
+func (c *planningClient) repo(name string) (string, bool) {
+	current, planned := c.repos[strings.ToLower(name)]
+	if !planned {
+		return name, true
+	}
+	return current, current != ""
+}
+
+func (c *planningClient) record(op operation) {
+	op.Org = c.org
+	if op.TeamID != 0 {
+		op.TeamName = c.teamNames[op.TeamID]
+	}
+	if op.Team != nil && op.Team.ParentTeamID != nil {
+		op.ParentTeam = c.teamNames[*op.Team.ParentTeamID]
+	}
+	logrus.WithFields(logrus.Fields{"org": op.Org, "kind": op.Kind}).Info(op.Description)
+	c.plan.Operations = append(c.plan.Operations, op)
+}
+
+func (c *planningClient) teamName(id int) string {
+	name, ok := c.teamNames[id]
+	switch {
+	case ok && id < 0:
+		return name
+	case ok:
+		return fmt.Sprintf("%s(%d)", name, id)
+	}
+	return fmt.Sprintf("%d", id)
+}
+
+func (c *planningClient) EditOrg(name string, config github.Organization) (*github.Organization, error) {
+	cur, err := c.Client.GetOrg(name)
+	if err != nil {
+		return nil, err
+	}
+	c.record(operation{
+		Kind:         opEditOrg,
+		Description:  fmt.Sprintf("Update %s metadata: %s", name, strings.Join(changedFields(*cur, config), ", ")),
+		Organization: &config,
+	})
+	return &config, nil
+}
+
+func (c *planningClient) UpdateOrgMembership(orgName, user string, admin bool) (*github.OrgMembership, error) {
+	role := github.RoleMember
+	if admin {
+		role = github.RoleAdmin
+	}
+	c.record(operation{
+		Kind:        opUpdateOrgMembership,
+		Description: fmt.Sprintf("Set %s as a %s of %s", user, role, orgName),
+		User:        user,
+		Admin:       admin,
+	})
+	return &github.OrgMembership{Membership: github.Membership{Role: role, State: github.StateActive}}, nil
+}
+
+func (c *planningClient) RemoveOrgMembership(orgName, user string) error {
+	c.record(operation{
+		Kind:        opRemoveOrgMembership,
+		Description: fmt.Sprintf("Remove %s from %s", user, orgName),
+		User:        user,
+	})
+	return nil
+}
+
+func (c *planningClient) CreateRepo(owner string, isUser bool, repo github.RepoCreateRequest) (*github.FullRepo, error) {
+	c.record(operation{
+		Kind:        opCreateRepo,
+		Description: fmt.Sprintf("Create repo %s/%s", owner, *repo.Name),
+		Repo:        *repo.Name,
+		RepoCreate:  &repo,
+	})
+	c.repos[strings.ToLower(*repo.Name)] = ""
+	return repo.ToRepo(), nil
+}
+
+func (c *planningClient) UpdateRepo(owner, name string, repo github.RepoUpdateRequest) (*github.FullRepo, error) {
+	c.record(operation{
+		Kind:        opUpdateRepo,
+		Description: fmt.Sprintf("Update repo %s/%s: %s", owner, name, strings.Join(setFields(repo), ", ")),
+		Repo:        name,
+		RepoUpdate:  &repo,
+	})
+	if repo.Name != nil && *repo.Name != name {
+		current, _ := c.repo(name)
+		c.repos[strings.ToLower(*repo.Name)] = current
+	}
+	return repo.ToRepo(), nil
+}
+
+func (c *planningClient) AddCollaborator(orgName, repo, user string, permission github.RepoPermissionLevel) error {
+	c.record(operation{
+		Kind:        opAddCollaborator,
+		Description: fmt.Sprintf("Give %s %s permission on %s/%s", user, permission, orgName, repo),
+		Repo:        repo,
+		User:        user,
+		Permission:  permission,
+	})
+	return nil
+}
+
+func (c *planningClient) RemoveCollaborator(orgName, repo, user string) error {
+	c.record(operation{
+		Kind:        opRemoveCollaborator,
+		Description: fmt.Sprintf("Remove collaborator %s from %s/%s", user, orgName, repo),
+		Repo:        repo,
+		User:        user,
+	})
+	return nil
+}
+
+// redact returns a copy of the request without its secret, and the secret.
+func redact(req github.HookRequest) (*github.HookRequest, string) {
+	if req.Config == nil || req.Config.Secret == nil {
+		return &req, ""
+	}
+	config := *req.Config
+	secret := *config.Secret
+	r := redacted
+	config.Secret = &r
+	req.Config = &config
+	return &req, secret
+}
+
+func (c *planningClient) CreateRepoHook(orgName, repo string, req github.HookRequest) (int, error) {
+	hook, secret := redact(req)
+	c.record(operation{
+		Kind:        opCreateRepoHook,
+		Description: fmt.Sprintf("Create webhook %s on %s/%s for %s", req.Config.URL, orgName, repo, strings.Join(req.Events, ", ")),
+		Repo:        repo,
+		Hook:        hook,
+		secret:      secret,
+	})
+	return 0, nil
+}
+
+func (c *planningClient) EditRepoHook(orgName, repo string, id int, req github.HookRequest) error {
+	hook, secret := redact(req)
+	c.record(operation{
+		Kind:        opEditRepoHook,
+		Description: fmt.Sprintf("Update webhook %s on %s/%s for %s", req.Config.URL, orgName, repo, strings.Join(req.Events, ", ")),
+		Repo:        repo,
+		HookID:      id,
+		Hook:        hook,
+		secret:      secret,
+	})
+	return nil
+}
+
+func (c *planningClient) DeleteRepoHook(orgName, repo string, id int) error {
+	c.record(operation{
+		Kind:        opDeleteRepoHook,
+		Description: fmt.Sprintf("Delete webhook %d from %s/%s", id, orgName, repo),
+		Repo:        repo,
+		HookID:      id,
+	})
+	return nil
+}
+
+func (c *planningClient) ReplaceRepoTopics(orgName, repo string, topics []string) error {
+	c.record(operation{
+		Kind:        opReplaceRepoTopics,
+		Description: fmt.Sprintf("Set topics of %s/%s to %s", orgName, repo, strings.Join(topics, ", ")),
+		Repo:        repo,
+		Topics:      topics,
+	})
+	return nil
+}
+
+func (c *planningClient) SetVulnerabilityAlerts(orgName, repo string, enabled bool) error {
+	state := "Disable"
+	if enabled {
+		state = "Enable"
+	}
+	c.record(operation{
+		Kind:        opSetVulnAlerts,
+		Description: fmt.Sprintf("%s vulnerability alerts on %s/%s", state, orgName, repo),
+		Repo:        repo,
+		Enabled:     enabled,
+	})
+	return nil
+}
+
+func (c *planningClient) ListDirectCollaborators(orgName, repo string) ([]github.User, error) {
+	current, exists := c.repo(repo)
+	if !exists {
+		return nil, nil
+	}
+	return c.Client.ListDirectCollaborators(orgName, current)
+}
+
+func (c *planningClient) ListRepoInvitations(orgName, repo string) ([]github.RepoInvitation, error) {
+	current, exists := c.repo(repo)
+	if !exists {
+		return nil, nil
+	}
+	return c.Client.ListRepoInvitations(orgName, current)
+}
+
+func (c *planningClient) ListRepoHooks(orgName, repo string) ([]github.Hook, error) {
+	current, exists := c.repo(repo)
+	if !exists {
+		return nil, nil
+	}
+	return c.Client.ListRepoHooks(orgName, current)
+}
+
+func (c *planningClient) GetRepoTopics(orgName, repo string) ([]string, error) {
+	current, exists := c.repo(repo)
+	if !exists {
+		return nil, nil
+	}
+	return c.Client.GetRepoTopics(orgName, current)
+}
+
+func (c *planningClient) GetVulnerabilityAlerts(orgName, repo string) (bool, error) {
+	current, exists := c.repo(repo)
+	if !exists {
+		return false, nil
+	}
+	return c.Client.GetVulnerabilityAlerts(orgName, current)
+}
+
+func (c *planningClient) ListTeams(orgName string) ([]github.Team, error) {
+	teams, err := c.Client.ListTeams(orgName)
+	for _, t := range teams {
+		c.teamNames[t.ID] = t.Name
+	}
+	return teams, err
+}
+
+func (c *planningClient) CreateTeam(orgName string, team github.Team) (*github.Team, error) {
+	c.plan.placeholders++
+	id := -c.plan.placeholders
+	c.teamNames[id] = team.Name
+	request := team
+	c.record(operation{
+		Kind:        opCreateTeam,
+		Description: fmt.Sprintf("Create team %s", team.Name),
+		TeamID:      id,
+		Team:        &request,
+	})
+	team.ID = id
+	return &team, nil
+}
+
+func (c *planningClient) EditTeam(team github.Team) (*github.Team, error) {
+	c.teamNames[team.ID] = team.Name
+	edit := team
+	c.record(operation{
+		Kind:        opEditTeam,
+		Description: fmt.Sprintf("Update team %s", c.teamName(team.ID)),
+		TeamID:      team.ID,
+		Team:        &edit,
+	})
+	return &team, nil
+}
+
+func (c *planningClient) DeleteTeam(id int) error {
+	c.record(operation{
+		Kind:        opDeleteTeam,
+		Description: fmt.Sprintf("Delete team %s", c.teamName(id)),
+		TeamID:      id,
+	})
+	return nil
+}
+
+func (c *planningClient) UpdateTeamMembership(id int, user string, maintainer bool) (*github.TeamMembership, error) {
+	role := github.RoleMember
+	if maintainer {
+		role = github.RoleMaintainer
+	}
+	c.record(operation{
+		Kind:        opUpdateTeamMembership,
+		Description: fmt.Sprintf("Set %s as a %s of team %s", user, role, c.teamName(id)),
+		TeamID:      id,
+		User:        user,
+		Maintainer:  maintainer,
+	})
+	return &github.TeamMembership{Membership: github.Membership{Role: role, State: github.StateActive}}, nil
+}
+
+func (c *planningClient) RemoveTeamMembership(id int, user string) error {
+	c.record(operation{
+		Kind:        opRemoveTeamMembership,
+		Description: fmt.Sprintf("Remove %s from team %s", user, c.teamName(id)),
+		TeamID:      id,
+		User:        user,
+	})
+	return nil
+}
+
+func (c *planningClient) UpdateTeamRepo(id int, orgName, repo string, permission github.RepoPermissionLevel) error {
+	c.record(operation{
+		Kind:        opUpdateTeamRepo,
+		Description: fmt.Sprintf("Give team %s %s permission on %s/%s", c.teamName(id), permission, orgName, repo),
+		TeamID:      id,
+		Repo:        repo,
+		Permission:  permission,
+	})
+	return nil
+}
+
+func (c *planningClient) RemoveTeamRepo(id int, orgName, repo string) error {
+	c.record(operation{
+		Kind:        opRemoveTeamRepo,
+		Description: fmt.Sprintf("Remove team %s permissions on %s/%s", c.teamName(id), orgName, repo),
+		TeamID:      id,
+		Repo:        repo,
+	})
+	return nil
+}
+
+func (c *planningClient) ListTeamMembers(id int, role string) ([]github.TeamMember, error) {
+	if id < 0 {
+		return nil, nil
+	}
+	return c.Client.ListTeamMembers(id, role)
+}
+
+func (c *planningClient) ListTeamInvitations(id int) ([]github.OrgInvitation, error) {
+	if id < 0 {
+		return nil, nil
+	}
+	return c.Client.ListTeamInvitations(id)
+}
+
+func (c *planningClient) ListTeamRepos(id int) ([]github.Repo, error) {
+	if id < 0 {
+		return nil, nil
+	}
+	return c.Client.ListTeamRepos(id)
+}
+
+// fields returns the JSON fields of v.
+func fields(v interface{}) map[string]interface{} {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil
+	}
+	return out
+}
+
+// changedFields describes the JSON fields that differ between have and want.
+func changedFields(have, want interface{}) []string {
+	h, w := fields(have), fields(want)
+	var changes []string
+	for k, v := range w {
+		if !reflect.DeepEqual(h[k], v) {
+			changes = append(changes, fmt.Sprintf("%s %v -> %v", k, h[k], v))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// setFields describes the JSON fields set in a request.
+func setFields(request interface{}) []string {
+	var changes []string
+	for k, v := range fields(request) {
+		changes = append(changes, fmt.Sprintf("%s -> %v", k, v))
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// computePlan returns the operations needed to bring every org in cfg in line
+// with it, without mutating GitHub.
+func computePlan(opt options, client github.Client, cfg org.FullConfig) (*plan, error) {
+	p := &plan{}
+	var orgs []string
+	for name := range cfg.Orgs {
+		orgs = append(orgs, name)
+	}
+	sort.Strings(orgs)
+	for _, name := range orgs {
+		if err := configureOrg(opt, newPlanningClient(client, p, name), name, cfg.Orgs[name]); err != nil {
+			return nil, fmt.Errorf("failed to plan %s: %v", name, err)
+		}
+	}
+	return p, nil
+}
+
+func loadPlan(path string) (*plan, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p plan
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// markdown renders a human-readable summary of the plan, grouped by org and by
+// the kind of change.
+func (p plan) markdown() string {
+	sections := []struct {
+		title string
+		kinds []string
+	}{
+		{"Organization metadata", []string{opEditOrg}},
+		{"Organization members", []string{opUpdateOrgMembership, opRemoveOrgMembership}},
+		{"Repositories", []string{opCreateRepo, opUpdateRepo, opReplaceRepoTopics, opSetVulnAlerts}},
+		{"Repository collaborators", []string{opAddCollaborator, opRemoveCollaborator}},
+		{"Repository webhooks", []string{opCreateRepoHook, opEditRepoHook, opDeleteRepoHook}},
+		{"Teams", []string{opCreateTeam, opEditTeam, opDeleteTeam}},
+		{"Team members", []string{opUpdateTeamMembership, opRemoveTeamMembership}},
+		{"Team repositories", []string{opUpdateTeamRepo, opRemoveTeamRepo}},
+	}
+
+	byOrg := map[string]map[string][]string{}
+	for _, op := range p.Operations {
+		if byOrg[op.Org] == nil {
+			byOrg[op.Org] = map[string][]string{}
+		}
+		byOrg[op.Org][op.Kind] = append(byOrg[op.Org][op.Kind], op.Description)
+	}
+	var orgs []string
+	for name := range byOrg {
+		orgs = append(orgs, name)
+	}
+	sort.Strings(orgs)
+
+	var buf bytes.Buffer
+	buf.WriteString("# Peribolos plan\n\n")
+	if len(p.Operations) == 0 {
+		buf.WriteString("No changes.\n")
+		return buf.String()
+	}
+	fmt.Fprintf(&buf, "%d changes to %d orgs.\n", len(p.Operations), len(orgs))
+	for _, name := range orgs {
+		fmt.Fprintf(&buf, "\n## %s\n", name)
+		for _, section := range sections {
+			var lines []string
+			for _, kind := range section.kinds {
+				lines = append(lines, byOrg[name][kind]...)
+			}
+			if len(lines) == 0 {
+				continue
+			}
+			sort.Strings(lines)
+			fmt.Fprintf(&buf, "\n### %s\n\n", section.title)
+			for _, line := range lines {
+				fmt.Fprintf(&buf, "- %s\n", line)
+			}
+		}
+	}
+	return buf.String()
+}
+
+// key identifies an operation regardless of its description and of the
+// placeholder IDs given to the teams the plan creates, which differ between runs.
+// Those teams are identified by name instead.
+func (op operation) key() string {
+	op.Description = ""
+	if op.TeamID < 0 {
+		op.TeamID = 0
+	}
+	if op.Team != nil {
+		t := *op.Team
+		if t.ID < 0 {
+			t.ID = 0
+		}
+		if t.ParentTeamID != nil && *t.ParentTeamID < 0 {
+			zero := 0
+			t.ParentTeamID = &zero
+		}
+		op.Team = &t
+	}
+	raw, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Sprintf("%+v", op)
+	}
+	return string(raw)
+}
+
+// drift describes the operations that are only in one of the two plans.
+func drift(saved, current plan) []string {
+	counts := map[string]int{}
+	descriptions := map[string]string{}
+	for _, op := range saved.Operations {
+		counts[op.key()]++
+		descriptions[op.key()] = op.Description
+	}
+	for _, op := range current.Operations {
+		counts[op.key()]--
+		descriptions[op.key()] = op.Description
+	}
+	var out []string
+	for k, n := range counts {
+		switch {
+		case n > 0:
+			out = append(out, fmt.Sprintf("no longer needed: %s", descriptions[k]))
+		case n < 0:
+			out = append(out, fmt.Sprintf("not in the plan: %s", descriptions[k]))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// planClient makes the mutations described by a plan.
+type planClient interface {
+	EditOrg(name string, config github.Organization) (*github.Organization, error)
+	UpdateOrgMembership(org, user string, admin bool) (*github.OrgMembership, error)
+	RemoveOrgMembership(org, user string) error
+	CreateRepo(owner string, isUser bool, repo github.RepoCreateRequest) (*github.FullRepo, error)
+	UpdateRepo(owner, name string, repo github.RepoUpdateRequest) (*github.FullRepo, error)
+	CreateTeam(org string, team github.Team) (*github.Team, error)
+	EditTeam(t github.Team) (*github.Team, error)
+	DeleteTeam(id int) error
+	UpdateTeamMembership(id int, user string, maintainer bool) (*github.TeamMembership, error)
+	RemoveTeamMembership(id int, user string) error
+	UpdateTeamRepo(id int, org, repo string, permission github.RepoPermissionLevel) error
+	RemoveTeamRepo(id int, org, repo string) error
+	AddCollaborator(org, repo, user string, permission github.RepoPermissionLevel) error
+	RemoveCollaborator(org, repo, user string) error
+	CreateRepoHook(org, repo string, req github.HookRequest) (int, error)
+	EditRepoHook(org, repo string, id int, req github.HookRequest) error
+	DeleteRepoHook(org, repo string, id int) error
+	ReplaceRepoTopics(owner, name string, topics []string) error
+	SetVulnerabilityAlerts(owner, name string, enabled bool) error
+}
+
+// unredactedHook returns the webhook request with the secret put back into it,
+// as it is redacted from the operations of saved plans.
+func (op operation) unredactedHook() (github.HookRequest, error) {
+	hook := *op.Hook
+	if hook.Config == nil || hook.Config.Secret == nil {
+		return hook, nil
+	}
+	if op.secret == "" {
+		return hook, errors.New("the webhook secret is redacted from saved plans")
+	}
+	config := *hook.Config
+	config.Secret = &op.secret
+	hook.Config = &config
+	return hook, nil
+}
+
+// applyPlan makes the mutations in the plan in order, stopping at the first failure.
+func applyPlan(client planClient, p plan) error {
+	created := map[int]int{} // placeholder team ID -> real team ID
+	teamID := func(id int) (int, error) {
+		if id >= 0 {
+			return id, nil
+		}
+		real, ok := created[id]
+		if !ok {
+			return 0, fmt.Errorf("team %d is not created by an earlier operation", id)
+		}
+		return real, nil
+	}
+
+	for i, op := range p.Operations {
+		logrus.WithFields(logrus.Fields{"org": op.Org, "kind": op.Kind}).Info(op.Description)
+		var err error
+		switch op.Kind {
+		case opEditOrg:
+			_, err = client.EditOrg(op.Org, *op.Organization)
+		case opUpdateOrgMembership:
+			_, err = client.UpdateOrgMembership(op.Org, op.User, op.Admin)
+		case opRemoveOrgMembership:
+			err = client.RemoveOrgMembership(op.Org, op.User)
+		case opCreateRepo:
+			_, err = client.CreateRepo(op.Org, false, *op.RepoCreate)
+		case opUpdateRepo:
+			_, err = client.UpdateRepo(op.Org, op.Repo, *op.RepoUpdate)
+		case opCreateTeam:
+			var t *github.Team
+			if t, err = client.CreateTeam(op.Org, *op.Team); err == nil {
+				created[op.TeamID] = t.ID
+			}
+		case opEditTeam:
+			t := *op.Team
+			if t.ID, err = teamID(t.ID); err != nil {
+				break
+			}
+			if t.ParentTeamID != nil {
+				var parent int
+				if parent, err = teamID(*t.ParentTeamID); err != nil {
+					break
+				}
+				t.ParentTeamID = &parent
+			}
+			_, err = client.EditTeam(t)
+		case opDeleteTeam:
+			err = client.DeleteTeam(op.TeamID)
+		case opUpdateTeamMembership:
+			var id int
+			if id, err = teamID(op.TeamID); err == nil {
+				_, err = client.UpdateTeamMembership(id, op.User, op.Maintainer)
+			}
+		case opRemoveTeamMembership:
+			var id int
+			if id, err = teamID(op.TeamID); err == nil {
+				err = client.RemoveTeamMembership(id, op.User)
+			}
+		case opUpdateTeamRepo:
+			var id int
+			if id, err = teamID(op.TeamID); err == nil {
+				err = client.UpdateTeamRepo(id, op.Org, op.Repo, op.Permission)
+			}
+		case opRemoveTeamRepo:
+			var id int
+			if id, err = teamID(op.TeamID); err == nil {
+				err = client.RemoveTeamRepo(id, op.Org, op.Repo)
+			}
+		case opAddCollaborator:
+			err = client.AddCollaborator(op.Org, op.Repo, op.User, op.Permission)
+		case opRemoveCollaborator:
+			err = client.RemoveCollaborator(op.Org, op.Repo, op.User)
+		case opCreateRepoHook:
+			var hook github.HookRequest
+			if hook, err = op.unredactedHook(); err == nil {
+				_, err = client.CreateRepoHook(op.Org, op.Repo, hook)
+			}
+		case opEditRepoHook:
+			var hook github.HookRequest
+			if hook, err = op.unredactedHook(); err == nil {
+				err = client.EditRepoHook(op.Org, op.Repo, op.HookID, hook)
+			}
+		case opDeleteRepoHook:
+			err = client.DeleteRepoHook(op.Org, op.Repo, op.HookID)
+		case opReplaceRepoTopics:
+			err = client.ReplaceRepoTopics(op.Org, op.Repo, op.Topics)
+		case opSetVulnAlerts:
+			err = client.SetVulnerabilityAlerts(op.Org, op.Repo, op.Enabled)
+		default:
+			err = fmt.Errorf("unknown kind %q", op.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("operation %d (%s) failed, %d operations not applied: %v", i, op.Description, len(p.Operations)-i, err)
+		}
+	}
+	return nil
+}
+
+// writePlan computes the changes needed to sync cfg and writes them to --plan,
+// and a summary of them to --plan-markdown if set.
+func writePlan(opt options, client github.Client, cfg org.FullConfig) error {
+	p, err := computePlan(opt, client, cfg)
+	if err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %v", err)
+	}
+	if err := ioutil.WriteFile(opt.plan, raw, 0644); err != nil {
+		return fmt.Errorf("failed to write --plan=%s: %v", opt.plan, err)
+	}
+	if opt.planMarkdown == "" {
+		return nil
+	}
+	if err := ioutil.WriteFile(opt.planMarkdown, []byte(p.markdown()), 0644); err != nil {
+		return fmt.Errorf("failed to write --plan-markdown=%s: %v", opt.planMarkdown, err)
+	}
+	return nil
+}
+
+// applySavedPlan applies the plan in --apply-plan, provided it is still exactly
+// what is needed to sync cfg with GitHub. The identical plan computed here is
+// the one applied, as only it knows the webhook secrets redacted from the saved one.
+func applySavedPlan(opt options, client github.Client, cfg org.FullConfig) error {
+	saved, err := loadPlan(opt.applyPlan)
+	if err != nil {
+		return fmt.Errorf("failed to load --apply-plan=%s: %v", opt.applyPlan, err)
+	}
+	current, err := computePlan(opt, client, cfg)
+	if err != nil {
+		return err
+	}
+	if d := drift(*saved, *current); len(d) > 0 {
+		return fmt.Errorf("GitHub changed since the plan was made, %d differences:\n%s", len(d), strings.Join(d, "\n"))
+	}
+	return applyPlan(client, *current)
+}
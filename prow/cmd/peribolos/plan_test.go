@@ -0,0 +1,408 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+// fakePlanReads serves the reads a planningClient passes through.
+type fakePlanReads struct {
+	github.Client
+	org   github.Organization
+	teams []github.Team
+}
+
+func (c *fakePlanReads) GetOrg(name string) (*github.Organization, error) {
+	return &c.org, nil
+}
+
+func (c *fakePlanReads) ListTeams(org string) ([]github.Team, error) {
+	return c.teams, nil
+}
+
+func (c *fakePlanReads) ListTeamMembers(id int, role string) ([]github.TeamMember, error) {
+	return []github.TeamMember{{Login: "existing"}}, nil
+}
+
+func TestPlanningClient(t *testing.T) {
+	p := &plan{}
+	client := newPlanningClient(&fakePlanReads{
+		org:   github.Organization{Name: "old", Company: "same"},
+		teams: []github.Team{{ID: 5, Name: "existing"}},
+	}, p, "org")
+
+	if _, err := client.EditOrg("org", github.Organization{Name: "new", Company: "same"}); err != nil {
+		t.Fatalf("EditOrg: %v", err)
+	}
+	if _, err := client.ListTeams("org"); err != nil {
+		t.Fatalf("ListTeams: %v", err)
+	}
+	team, err := client.CreateTeam("org", github.Team{Name: "new-team"})
+	if err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	if team.ID >= 0 {
+		t.Errorf("created team should have a placeholder ID, got %d", team.ID)
+	}
+	members, err := client.ListTeamMembers(team.ID, github.RoleAll)
+	if err != nil || len(members) != 0 {
+		t.Errorf("expected no members of a planned team, got %v, %v", members, err)
+	}
+	if members, _ := client.ListTeamMembers(5, github.RoleAll); len(members) != 1 {
+		t.Errorf("expected members of an existing team to be listed, got %v", members)
+	}
+	membership, err := client.UpdateTeamMembership(team.ID, "alice", true)
+	if err != nil {
+		t.Fatalf("UpdateTeamMembership: %v", err)
+	}
+	if membership.State != github.StateActive || membership.Role != github.RoleMaintainer {
+		t.Errorf("expected an active maintainer membership, got %+v", membership)
+	}
+	if err := client.RemoveTeamRepo(5, "org", "repo"); err != nil {
+		t.Fatalf("RemoveTeamRepo: %v", err)
+	}
+
+	expected := []string{
+		"Update org metadata: name old -> new",
+		"Create team new-team",
+		"Set alice as a maintainer of team new-team",
+		"Remove team existing(5) permissions on org/repo",
+	}
+	var actual []string
+	for _, op := range p.Operations {
+		if op.Org != "org" {
+			t.Errorf("operation %q has org %q", op.Description, op.Org)
+		}
+		actual = append(actual, op.Description)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected operations %q, got %q", expected, actual)
+	}
+}
+
+func TestPlanMarkdown(t *testing.T) {
+	if actual := (plan{}).markdown(); !strings.Contains(actual, "No changes.") {
+		t.Errorf("expected an empty plan to say there are no changes, got:\n%s", actual)
+	}
+
+	p := plan{Operations: []operation{
+		{Kind: opUpdateTeamMembership, Org: "b", Description: "Set zed as a member of team t"},
+		{Kind: opRemoveOrgMembership, Org: "a", Description: "Remove bob from a"},
+		{Kind: opUpdateOrgMembership, Org: "a", Description: "Set alice as a member of a"},
+	}}
+	expected := `# Peribolos plan
+
+3 changes to 2 orgs.
+
+## a
+
+### Organization members
+
+- Remove bob from a
+- Set alice as a member of a
+
+## b
+
+### Team members
+
+- Set zed as a member of team t
+`
+	if actual := p.markdown(); actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	parent := -2
+	saved := plan{Operations: []operation{
+		{Kind: opCreateTeam, TeamID: -1, TeamName: "a", Team: &github.Team{Name: "a"}},
+		{Kind: opCreateTeam, TeamID: -2, TeamName: "b", Team: &github.Team{Name: "b"}},
+		{Kind: opEditTeam, TeamID: -1, TeamName: "a", Team: &github.Team{ID: -1, Name: "a", ParentTeamID: &parent}, ParentTeam: "b"},
+		{Kind: opRemoveOrgMembership, User: "bob", Description: "Remove bob"},
+	}}
+	otherParent := -1
+	reordered := plan{Operations: []operation{
+		{Kind: opCreateTeam, TeamID: -1, TeamName: "b", Team: &github.Team{Name: "b"}},
+		{Kind: opCreateTeam, TeamID: -2, TeamName: "a", Team: &github.Team{Name: "a"}},
+		{Kind: opEditTeam, TeamID: -2, TeamName: "a", Team: &github.Team{ID: -2, Name: "a", ParentTeamID: &otherParent}, ParentTeam: "b"},
+		{Kind: opRemoveOrgMembership, User: "bob", Description: "Remove bob from org"},
+	}}
+	if d := drift(saved, reordered); len(d) != 0 {
+		t.Errorf("expected plans that only differ in placeholder IDs to match, got %q", d)
+	}
+
+	changed := plan{Operations: append([]operation{}, reordered.Operations[:3]...)}
+	changed.Operations = append(changed.Operations, operation{Kind: opRemoveOrgMembership, User: "carol", Description: "Remove carol"})
+	expected := []string{"no longer needed: Remove bob", "not in the plan: Remove carol"}
+	if d := drift(saved, changed); !reflect.DeepEqual(expected, d) {
+		t.Errorf("expected drift %q, got %q", expected, d)
+	}
+}
+
+// fakePlanClient records the calls made to apply a plan.
+type fakePlanClient struct {
+	calls  []string
+	nextID int
+	fail   string
+}
+
+func (c *fakePlanClient) call(format string, args ...interface{}) error {
+	call := fmt.Sprintf(format, args...)
+	c.calls = append(c.calls, call)
+	if call == c.fail {
+		return errors.New("injected failure")
+	}
+	return nil
+}
+
+func (c *fakePlanClient) EditOrg(name string, config github.Organization) (*github.Organization, error) {
+	return &config, c.call("EditOrg(%s, %s)", name, config.Name)
+}
+
+func (c *fakePlanClient) UpdateOrgMembership(org, user string, admin bool) (*github.OrgMembership, error) {
+	return &github.OrgMembership{}, c.call("UpdateOrgMembership(%s, %s, %t)", org, user, admin)
+}
+
+func (c *fakePlanClient) RemoveOrgMembership(org, user string) error {
+	return c.call("RemoveOrgMembership(%s, %s)", org, user)
+}
+
+func (c *fakePlanClient) CreateRepo(owner string, isUser bool, repo github.RepoCreateRequest) (*github.FullRepo, error) {
+	return repo.ToRepo(), c.call("CreateRepo(%s, %s)", owner, *repo.Name)
+}
+
+func (c *fakePlanClient) UpdateRepo(owner, name string, repo github.RepoUpdateRequest) (*github.FullRepo, error) {
+	return repo.ToRepo(), c.call("UpdateRepo(%s, %s)", owner, name)
+}
+
+func (c *fakePlanClient) CreateTeam(org string, team github.Team) (*github.Team, error) {
+	c.nextID++
+	team.ID = 100 + c.nextID
+	return &team, c.call("CreateTeam(%s, %s)", org, team.Name)
+}
+
+func (c *fakePlanClient) EditTeam(t github.Team) (*github.Team, error) {
+	parent := 0
+	if t.ParentTeamID != nil {
+		parent = *t.ParentTeamID
+	}
+	return &t, c.call("EditTeam(%d, %s, parent %d)", t.ID, t.Name, parent)
+}
+
+func (c *fakePlanClient) DeleteTeam(id int) error {
+	return c.call("DeleteTeam(%d)", id)
+}
+
+func (c *fakePlanClient) UpdateTeamMembership(id int, user string, maintainer bool) (*github.TeamMembership, error) {
+	return &github.TeamMembership{}, c.call("UpdateTeamMembership(%d, %s, %t)", id, user, maintainer)
+}
+
+func (c *fakePlanClient) RemoveTeamMembership(id int, user string) error {
+	return c.call("RemoveTeamMembership(%d, %s)", id, user)
+}
+
+func (c *fakePlanClient) UpdateTeamRepo(id int, org, repo string, permission github.RepoPermissionLevel) error {
+	return c.call("UpdateTeamRepo(%d, %s, %s, %s)", id, org, repo, permission)
+}
+
+func (c *fakePlanClient) RemoveTeamRepo(id int, org, repo string) error {
+	return c.call("RemoveTeamRepo(%d, %s, %s)", id, org, repo)
+}
+
+func (c *fakePlanClient) AddCollaborator(org, repo, user string, permission github.RepoPermissionLevel) error {
+	return c.call("AddCollaborator(%s, %s, %s, %s)", org, repo, user, permission)
+}
+
+func (c *fakePlanClient) RemoveCollaborator(org, repo, user string) error {
+	return c.call("RemoveCollaborator(%s, %s, %s)", org, repo, user)
+}
+
+func (c *fakePlanClient) CreateRepoHook(org, repo string, req github.HookRequest) (int, error) {
+	secret := ""
+	if req.Config.Secret != nil {
+		secret = *req.Config.Secret
+	}
+	return 1, c.call("CreateRepoHook(%s, %s, %s, secret %q)", org, repo, req.Config.URL, secret)
+}
+
+func (c *fakePlanClient) EditRepoHook(org, repo string, id int, req github.HookRequest) error {
+	return c.call("EditRepoHook(%s, %s, %d)", org, repo, id)
+}
+
+func (c *fakePlanClient) DeleteRepoHook(org, repo string, id int) error {
+	return c.call("DeleteRepoHook(%s, %s, %d)", org, repo, id)
+}
+
+func (c *fakePlanClient) ReplaceRepoTopics(org, repo string, topics []string) error {
+	return c.call("ReplaceRepoTopics(%s, %s, %v)", org, repo, topics)
+}
+
+func (c *fakePlanClient) SetVulnerabilityAlerts(org, repo string, enabled bool) error {
+	return c.call("SetVulnerabilityAlerts(%s, %s, %t)", org, repo, enabled)
+}
+
+func TestApplyPlan(t *testing.T) {
+	parent := -1
+	p := plan{Operations: []operation{
+		{Kind: opEditOrg, Org: "org", Organization: &github.Organization{Name: "new"}},
+		{Kind: opCreateTeam, Org: "org", TeamID: -1, Team: &github.Team{Name: "parent"}},
+		{Kind: opCreateTeam, Org: "org", TeamID: -2, Team: &github.Team{Name: "child"}},
+		{Kind: opEditTeam, Org: "org", TeamID: -2, Team: &github.Team{ID: -2, Name: "child", ParentTeamID: &parent}},
+		{Kind: opUpdateTeamMembership, Org: "org", TeamID: -2, User: "alice", Maintainer: true},
+		{Kind: opUpdateTeamRepo, Org: "org", TeamID: 5, Repo: "repo", Permission: github.Write},
+		{Kind: opDeleteTeam, Org: "org", TeamID: 6},
+	}}
+
+	cases := []struct {
+		name          string
+		plan          plan
+		fail          string
+		expectedCalls []string
+		expectedErr   bool
+	}{
+		{
+			name: "placeholder team IDs are replaced with created IDs",
+			plan: p,
+			expectedCalls: []string{
+				"EditOrg(org, new)",
+				"CreateTeam(org, parent)",
+				"CreateTeam(org, child)",
+				"EditTeam(102, child, parent 101)",
+				"UpdateTeamMembership(102, alice, true)",
+				"UpdateTeamRepo(5, org, repo, write)",
+				"DeleteTeam(6)",
+			},
+		},
+		{
+			name: "stop at the first failure",
+			plan: p,
+			fail: "CreateTeam(org, child)",
+			expectedCalls: []string{
+				"EditOrg(org, new)",
+				"CreateTeam(org, parent)",
+				"CreateTeam(org, child)",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "reject unknown placeholder IDs",
+			plan: plan{Operations: []operation{
+				{Kind: opRemoveTeamMembership, Org: "org", TeamID: -1, User: "alice"},
+			}},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakePlanClient{fail: tc.fail}
+			err := applyPlan(client, tc.plan)
+			if err != nil && !tc.expectedErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err == nil && tc.expectedErr {
+				t.Error("expected an error")
+			}
+			if !reflect.DeepEqual(tc.expectedCalls, client.calls) {
+				t.Errorf("expected calls %q, got %q", tc.expectedCalls, client.calls)
+			}
+		})
+	}
+}
+
+func TestPlanRedactsWebhookSecrets(t *testing.T) {
+	p := &plan{}
+	client := newPlanningClient(nil, p, "org")
+	secret := "hunter2"
+	req := github.HookRequest{Name: "web", Events: []string{"push"}, Config: &github.HookConfig{URL: "https://hook.example.com", Secret: &secret}}
+	if _, err := client.CreateRepoHook("org", "repo", req); err != nil {
+		t.Fatalf("CreateRepoHook: %v", err)
+	}
+	if *req.Config.Secret != secret {
+		t.Error("planning must not modify the request")
+	}
+
+	raw, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("failed to marshal plan: %v", err)
+	}
+	if strings.Contains(string(raw), secret) {
+		t.Errorf("saved plan leaks the webhook secret: %s", raw)
+	}
+	var saved plan
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		t.Fatalf("failed to unmarshal plan: %v", err)
+	}
+	if d := drift(saved, *p); len(d) != 0 {
+		t.Errorf("expected the saved plan to match the computed one, got %q", d)
+	}
+
+	if err := applyPlan(&fakePlanClient{}, saved); err == nil {
+		t.Error("expected a saved plan without the webhook secret to be refused")
+	}
+	applier := &fakePlanClient{}
+	if err := applyPlan(applier, *p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{`CreateRepoHook(org, repo, https://hook.example.com, secret "hunter2")`}
+	if !reflect.DeepEqual(expected, applier.calls) {
+		t.Errorf("expected calls %q, got %q", expected, applier.calls)
+	}
+}
+
+func TestPlanningClientPlannedRepos(t *testing.T) {
+	p := &plan{}
+	reads := &fakeRepoReads{}
+	client := newPlanningClient(reads, p, "org")
+
+	name := "new"
+	if _, err := client.CreateRepo("org", false, github.RepoCreateRequest{RepoRequest: github.RepoRequest{Name: &name}}); err != nil {
+		t.Fatalf("CreateRepo: %v", err)
+	}
+	renamed := "renamed"
+	if _, err := client.UpdateRepo("org", "old", github.RepoUpdateRequest{RepoRequest: github.RepoRequest{Name: &renamed}}); err != nil {
+		t.Fatalf("UpdateRepo: %v", err)
+	}
+	for _, repo := range []string{"New", "renamed", "other"} {
+		if _, err := client.GetRepoTopics("org", repo); err != nil {
+			t.Fatalf("GetRepoTopics(%s): %v", repo, err)
+		}
+	}
+	expected := []string{"old", "other"}
+	if !reflect.DeepEqual(expected, reads.topicsRead) {
+		t.Errorf("expected topics of %q to be read, got %q", expected, reads.topicsRead)
+	}
+}
+
+// fakeRepoReads records the repos whose topics are read.
+type fakeRepoReads struct {
+	github.Client
+	topicsRead []string
+}
+
+func (c *fakeRepoReads) GetRepoTopics(org, repo string) ([]string, error) {
+	c.topicsRead = append(c.topicsRead, repo)
+	return nil, nil
+}
@@ -0,0 +1,303 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config/org"
+	"k8s.io/test-infra/prow/github"
+)
+
+type repoSettingsClient interface {
+	collaboratorClient
+	webhookClient
+	GetRepoTopics(owner, name string) ([]string, error)
+	ReplaceRepoTopics(owner, name string, topics []string) error
+	GetVulnerabilityAlerts(owner, name string) (bool, error)
+	SetVulnerabilityAlerts(owner, name string, enabled bool) error
+}
+
+// configureRepoSettings reconciles the collaborators, webhooks, topics and
+// security settings of each configured repo that is not archived.
+func configureRepoSettings(opt options, client repoSettingsClient, orgName string, orgConfig org.Config) error {
+	var names []string
+	for name := range orgConfig.Repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var allErrors []error
+	for _, name := range names {
+		repo := orgConfig.Repos[name]
+		if repo.Archived != nil && *repo.Archived {
+			logrus.WithField("repo", name).Debug("Skipping settings of archived repo.")
+			continue
+		}
+		if opt.fixRepoCollaborators && repo.Collaborators != nil {
+			if err := configureCollaborators(client, orgName, name, repo.Collaborators); err != nil {
+				allErrors = append(allErrors, fmt.Errorf("failed to configure %s collaborators: %v", name, err))
+			}
+		}
+		if opt.fixRepoWebhooks && repo.Webhooks != nil {
+			if err := configureWebhooks(client, opt.webhookSecret, orgName, name, repo.Webhooks); err != nil {
+				allErrors = append(allErrors, fmt.Errorf("failed to configure %s webhooks: %v", name, err))
+			}
+		}
+		if opt.fixRepoTopics && repo.Topics != nil {
+			if err := configureTopics(client, orgName, name, repo.Topics); err != nil {
+				allErrors = append(allErrors, fmt.Errorf("failed to configure %s topics: %v", name, err))
+			}
+		}
+		if opt.fixRepoSecurity && repo.VulnerabilityAlerts != nil {
+			if err := configureVulnerabilityAlerts(client, orgName, name, *repo.VulnerabilityAlerts); err != nil {
+				allErrors = append(allErrors, fmt.Errorf("failed to configure %s vulnerability alerts: %v", name, err))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(allErrors)
+}
+
+type collaboratorClient interface {
+	ListDirectCollaborators(org, repo string) ([]github.User, error)
+	ListRepoInvitations(org, repo string) ([]github.RepoInvitation, error)
+	AddCollaborator(org, repo, user string, permission github.RepoPermissionLevel) error
+	RemoveCollaborator(org, repo, user string) error
+}
+
+// configureCollaborators gives each wanted user their permission on the repo
+// and removes other direct collaborators. Users with a pending invitation
+// for the wanted permission are not invited again.
+func configureCollaborators(client collaboratorClient, orgName, repoName string, want map[string]github.RepoPermissionLevel) error {
+	have := map[string]github.RepoPermissionLevel{}
+	users, err := client.ListDirectCollaborators(orgName, repoName)
+	if err != nil {
+		return fmt.Errorf("failed to list collaborators: %v", err)
+	}
+	for _, u := range users {
+		have[github.NormLogin(u.Login)] = github.LevelFromPermissions(u.Permissions)
+	}
+	invitations, err := client.ListRepoInvitations(orgName, repoName)
+	if err != nil {
+		return fmt.Errorf("failed to list invitations: %v", err)
+	}
+	invited := map[string]github.RepoPermissionLevel{}
+	for _, i := range invitations {
+		invited[github.NormLogin(i.Invitee.Login)] = github.RepoPermissionLevel(i.Permissions)
+	}
+
+	wanted := sets.NewString()
+	var errs []error
+	for user, permission := range want {
+		login := github.NormLogin(user)
+		wanted.Insert(login)
+		if have[login] == permission || invited[login] == permission {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{"repo": repoName, "user": user, "permission": permission}).Info("Adding collaborator.")
+		if err := client.AddCollaborator(orgName, repoName, user, permission); err != nil {
+			errs = append(errs, fmt.Errorf("failed to add %s: %v", user, err))
+		}
+	}
+	for user := range have {
+		if wanted.Has(user) {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{"repo": repoName, "user": user}).Info("Removing collaborator.")
+		if err := client.RemoveCollaborator(orgName, repoName, user); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %v", user, err))
+		}
+	}
+	for user := range invited {
+		if !wanted.Has(user) {
+			logrus.WithFields(logrus.Fields{"repo": repoName, "user": user}).Warn("Ignoring pending invitation of a user who is not a configured collaborator.")
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// webhookSecret reads the secret a webhook refers to from --webhook-secret-dir.
+func (o options) webhookSecret(name string) (string, error) {
+	if o.webhookSecretDir == "" {
+		return "", errors.New("--webhook-secret-dir is required to configure webhooks with secrets")
+	}
+	if name == "" || name != filepath.Base(name) {
+		return "", fmt.Errorf("bad secret name %q", name)
+	}
+	raw, err := ioutil.ReadFile(filepath.Join(o.webhookSecretDir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+type webhookClient interface {
+	ListRepoHooks(org, repo string) ([]github.Hook, error)
+	CreateRepoHook(org, repo string, req github.HookRequest) (int, error)
+	EditRepoHook(org, repo string, id int, req github.HookRequest) error
+	DeleteRepoHook(org, repo string, id int) error
+}
+
+// newHookRequest creates the request that configures a webhook for url,
+// applying GitHub's defaults to unset fields.
+func newHookRequest(url string, hook org.Webhook, secret func(name string) (string, error)) (github.HookRequest, error) {
+	active := true
+	if hook.Active != nil {
+		active = *hook.Active
+	}
+	events := []string{"push"}
+	if len(hook.Events) > 0 {
+		events = append([]string{}, hook.Events...)
+		sort.Strings(events)
+	}
+	contentType := "form"
+	if hook.ContentType != nil {
+		contentType = *hook.ContentType
+	}
+	req := github.HookRequest{
+		Name:   "web",
+		Active: &active,
+		Events: events,
+		Config: &github.HookConfig{
+			URL:         url,
+			ContentType: &contentType,
+		},
+	}
+	if hook.Secret != nil {
+		s, err := secret(*hook.Secret)
+		if err != nil {
+			return req, fmt.Errorf("failed to read secret %s: %v", *hook.Secret, err)
+		}
+		req.Config.Secret = &s
+	}
+	return req, nil
+}
+
+// hookDiffers reports whether an existing webhook differs from the request.
+// Secrets are never returned by GitHub so they cannot be compared.
+func hookDiffers(have github.Hook, want github.HookRequest) bool {
+	haveType := "form"
+	if have.Config.ContentType != nil {
+		haveType = *have.Config.ContentType
+	}
+	return have.Active != *want.Active || haveType != *want.Config.ContentType ||
+		!sets.NewString(have.Events...).Equal(sets.NewString(want.Events...))
+}
+
+// configureWebhooks creates or updates the wanted webhooks, identified by
+// their payload URL, and deletes the other webhooks of the repo.
+func configureWebhooks(client webhookClient, secret func(name string) (string, error), orgName, repoName string, want map[string]org.Webhook) error {
+	hooks, err := client.ListRepoHooks(orgName, repoName)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %v", err)
+	}
+	have := map[string]github.Hook{}
+	for _, h := range hooks {
+		have[h.Config.URL] = h
+	}
+
+	var urls []string
+	for url := range want {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	var errs []error
+	for _, url := range urls {
+		logger := logrus.WithFields(logrus.Fields{"repo": repoName, "url": url})
+		req, err := newHookRequest(url, want[url], secret)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		current, exists := have[url]
+		switch {
+		case !exists:
+			logger.Info("Creating webhook.")
+			if _, err := client.CreateRepoHook(orgName, repoName, req); err != nil {
+				errs = append(errs, fmt.Errorf("failed to create %s: %v", url, err))
+			}
+		case hookDiffers(current, req):
+			logger.Info("Updating webhook.")
+			req.Name = "" // only valid on creation
+			if err := client.EditRepoHook(orgName, repoName, current.ID, req); err != nil {
+				errs = append(errs, fmt.Errorf("failed to update %s: %v", url, err))
+			}
+		}
+	}
+	for url, h := range have {
+		if _, ok := want[url]; ok {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{"repo": repoName, "url": url}).Info("Deleting webhook.")
+		if err := client.DeleteRepoHook(orgName, repoName, h.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s: %v", url, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+type topicsClient interface {
+	GetRepoTopics(owner, name string) ([]string, error)
+	ReplaceRepoTopics(owner, name string, topics []string) error
+}
+
+// configureTopics replaces the topics of the repo when they differ from want.
+func configureTopics(client topicsClient, orgName, repoName string, want []string) error {
+	have, err := client.GetRepoTopics(orgName, repoName)
+	if err != nil {
+		return fmt.Errorf("failed to get topics: %v", err)
+	}
+	// GitHub stores topics in lower case
+	wanted := sets.NewString()
+	for _, topic := range want {
+		wanted.Insert(strings.ToLower(topic))
+	}
+	if wanted.Equal(sets.NewString(have...)) {
+		return nil
+	}
+	logrus.WithFields(logrus.Fields{"repo": repoName, "topics": wanted.List()}).Info("Replacing topics.")
+	return client.ReplaceRepoTopics(orgName, repoName, wanted.List())
+}
+
+type vulnerabilityAlertsClient interface {
+	GetVulnerabilityAlerts(owner, name string) (bool, error)
+	SetVulnerabilityAlerts(owner, name string, enabled bool) error
+}
+
+// configureVulnerabilityAlerts enables or disables vulnerability alerts when
+// they differ from want.
+func configureVulnerabilityAlerts(client vulnerabilityAlertsClient, orgName, repoName string, want bool) error {
+	have, err := client.GetVulnerabilityAlerts(orgName, repoName)
+	if err != nil {
+		return fmt.Errorf("failed to get vulnerability alerts: %v", err)
+	}
+	if have == want {
+		return nil
+	}
+	logrus.WithFields(logrus.Fields{"repo": repoName, "enabled": want}).Info("Configuring vulnerability alerts.")
+	return client.SetVulnerabilityAlerts(orgName, repoName, want)
+}
@@ -0,0 +1,390 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/test-infra/prow/config/org"
+	"k8s.io/test-infra/prow/github"
+)
+
+// fakeRepoSettingsClient records the calls made to reconcile repo settings.
+type fakeRepoSettingsClient struct {
+	collaborators []github.User
+	invitations   []github.RepoInvitation
+	hooks         []github.Hook
+	topics        []string
+	alerts        bool
+
+	calls []string
+}
+
+func (c *fakeRepoSettingsClient) call(format string, args ...interface{}) error {
+	c.calls = append(c.calls, fmt.Sprintf(format, args...))
+	return nil
+}
+
+func (c *fakeRepoSettingsClient) ListDirectCollaborators(org, repo string) ([]github.User, error) {
+	return c.collaborators, nil
+}
+
+func (c *fakeRepoSettingsClient) ListRepoInvitations(org, repo string) ([]github.RepoInvitation, error) {
+	return c.invitations, nil
+}
+
+func (c *fakeRepoSettingsClient) AddCollaborator(org, repo, user string, permission github.RepoPermissionLevel) error {
+	return c.call("AddCollaborator(%s, %s)", user, permission)
+}
+
+func (c *fakeRepoSettingsClient) RemoveCollaborator(org, repo, user string) error {
+	return c.call("RemoveCollaborator(%s)", user)
+}
+
+func (c *fakeRepoSettingsClient) ListRepoHooks(org, repo string) ([]github.Hook, error) {
+	return c.hooks, nil
+}
+
+func describeHook(req github.HookRequest) string {
+	secret := ""
+	if req.Config.Secret != nil {
+		secret = *req.Config.Secret
+	}
+	return fmt.Sprintf("%s %v active=%t %s secret=%q", req.Config.URL, req.Events, *req.Active, *req.Config.ContentType, secret)
+}
+
+func (c *fakeRepoSettingsClient) CreateRepoHook(org, repo string, req github.HookRequest) (int, error) {
+	return 1, c.call("CreateRepoHook(%s)", describeHook(req))
+}
+
+func (c *fakeRepoSettingsClient) EditRepoHook(org, repo string, id int, req github.HookRequest) error {
+	return c.call("EditRepoHook(%d, %s)", id, describeHook(req))
+}
+
+func (c *fakeRepoSettingsClient) DeleteRepoHook(org, repo string, id int) error {
+	return c.call("DeleteRepoHook(%d)", id)
+}
+
+func (c *fakeRepoSettingsClient) GetRepoTopics(owner, name string) ([]string, error) {
+	return c.topics, nil
+}
+
+func (c *fakeRepoSettingsClient) ReplaceRepoTopics(owner, name string, topics []string) error {
+	return c.call("ReplaceRepoTopics(%v)", topics)
+}
+
+func (c *fakeRepoSettingsClient) GetVulnerabilityAlerts(owner, name string) (bool, error) {
+	return c.alerts, nil
+}
+
+func (c *fakeRepoSettingsClient) SetVulnerabilityAlerts(owner, name string, enabled bool) error {
+	return c.call("SetVulnerabilityAlerts(%t)", enabled)
+}
+
+func TestConfigureCollaborators(t *testing.T) {
+	cases := []struct {
+		name          string
+		collaborators []github.User
+		invitations   []github.RepoInvitation
+		want          map[string]github.RepoPermissionLevel
+		expected      []string
+	}{
+		{
+			name:          "nothing to do",
+			collaborators: []github.User{{Login: "Alice", Permissions: github.RepoPermissions{Pull: true}}},
+			want:          map[string]github.RepoPermissionLevel{"alice": github.Read},
+		},
+		{
+			name: "add and update collaborators",
+			collaborators: []github.User{
+				{Login: "alice", Permissions: github.RepoPermissions{Pull: true}},
+			},
+			want: map[string]github.RepoPermissionLevel{"alice": github.Admin, "bob": github.Write},
+			expected: []string{
+				"AddCollaborator(alice, admin)",
+				"AddCollaborator(bob, write)",
+			},
+		},
+		{
+			name: "remove unwanted collaborators",
+			collaborators: []github.User{
+				{Login: "alice", Permissions: github.RepoPermissions{Pull: true, Push: true}},
+				{Login: "mallory", Permissions: github.RepoPermissions{Pull: true, Push: true, Admin: true}},
+			},
+			want:     map[string]github.RepoPermissionLevel{"alice": github.Write},
+			expected: []string{"RemoveCollaborator(mallory)"},
+		},
+		{
+			name: "users invited with the wanted permission are not invited again",
+			invitations: []github.RepoInvitation{
+				{Invitee: github.User{Login: "alice"}, Permissions: "write"},
+				{Invitee: github.User{Login: "bob"}, Permissions: "read"},
+				{Invitee: github.User{Login: "eve"}, Permissions: "read"},
+			},
+			want:     map[string]github.RepoPermissionLevel{"alice": github.Write, "bob": github.Admin},
+			expected: []string{"AddCollaborator(bob, admin)"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeRepoSettingsClient{collaborators: tc.collaborators, invitations: tc.invitations}
+			if err := configureCollaborators(client, "org", "repo", tc.want); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sort.Strings(client.calls)
+			if !reflect.DeepEqual(tc.expected, client.calls) {
+				t.Errorf("expected calls %q, got %q", tc.expected, client.calls)
+			}
+		})
+	}
+}
+
+func TestConfigureWebhooks(t *testing.T) {
+	json := "json"
+	no := false
+	secretName := "hook-secret"
+	secrets := func(name string) (string, error) {
+		if name != secretName {
+			return "", fmt.Errorf("unknown secret %s", name)
+		}
+		return "hunter2", nil
+	}
+
+	cases := []struct {
+		name        string
+		hooks       []github.Hook
+		want        map[string]org.Webhook
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			name:     "create with defaults",
+			want:     map[string]org.Webhook{"https://a.example.com": {}},
+			expected: []string{`CreateRepoHook(https://a.example.com [push] active=true form secret="")`},
+		},
+		{
+			name: "create with secret",
+			want: map[string]org.Webhook{
+				"https://a.example.com": {Events: []string{"push", "issues"}, ContentType: &json, Secret: &secretName},
+			},
+			expected: []string{`CreateRepoHook(https://a.example.com [issues push] active=true json secret="hunter2")`},
+		},
+		{
+			name: "unchanged hooks are left alone",
+			hooks: []github.Hook{
+				{ID: 1, Events: []string{"push", "issues"}, Active: true, Config: github.HookConfig{URL: "https://a.example.com", ContentType: &json}},
+			},
+			want: map[string]org.Webhook{
+				"https://a.example.com": {Events: []string{"issues", "push"}, ContentType: &json, Secret: &secretName},
+			},
+		},
+		{
+			name: "update changed hooks and delete unwanted ones",
+			hooks: []github.Hook{
+				{ID: 1, Events: []string{"push"}, Active: true, Config: github.HookConfig{URL: "https://a.example.com"}},
+				{ID: 2, Events: []string{"push"}, Active: true, Config: github.HookConfig{URL: "https://old.example.com"}},
+			},
+			want: map[string]org.Webhook{
+				"https://a.example.com": {Active: &no},
+			},
+			expected: []string{
+				`EditRepoHook(1, https://a.example.com [push] active=false form secret="")`,
+				"DeleteRepoHook(2)",
+			},
+		},
+		{
+			name: "unknown secrets are an error",
+			want: map[string]org.Webhook{
+				"https://a.example.com": {Secret: &json},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeRepoSettingsClient{hooks: tc.hooks}
+			err := configureWebhooks(client, secrets, "org", "repo", tc.want)
+			if err != nil && !tc.expectedErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err == nil && tc.expectedErr {
+				t.Error("expected an error")
+			}
+			if !reflect.DeepEqual(tc.expected, client.calls) {
+				t.Errorf("expected calls %q, got %q", tc.expected, client.calls)
+			}
+		})
+	}
+}
+
+func TestConfigureTopics(t *testing.T) {
+	cases := []struct {
+		name     string
+		have     []string
+		want     []string
+		expected []string
+	}{
+		{
+			name: "same topics in another order and case",
+			have: []string{"a", "b"},
+			want: []string{"B", "a"},
+		},
+		{
+			name:     "replace topics",
+			have:     []string{"a", "b"},
+			want:     []string{"c", "a"},
+			expected: []string{"ReplaceRepoTopics([a c])"},
+		},
+		{
+			name:     "clear topics",
+			have:     []string{"a"},
+			want:     []string{},
+			expected: []string{"ReplaceRepoTopics([])"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeRepoSettingsClient{topics: tc.have}
+			if err := configureTopics(client, "org", "repo", tc.want); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tc.expected, client.calls) {
+				t.Errorf("expected calls %q, got %q", tc.expected, client.calls)
+			}
+		})
+	}
+}
+
+func TestConfigureRepoSettings(t *testing.T) {
+	yes := true
+	no := false
+	orgConfig := org.Config{
+		Repos: map[string]org.Repo{
+			"active": {
+				VulnerabilityAlerts: &yes,
+				Topics:              []string{"a"},
+				Collaborators:       map[string]github.RepoPermissionLevel{"alice": github.Read},
+			},
+			"archived": {
+				Archived:            &yes,
+				VulnerabilityAlerts: &no,
+			},
+			"unmanaged": {},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		opt      options
+		expected []string
+	}{
+		{
+			name: "nothing is fixed without flags",
+		},
+		{
+			name: "only flagged settings are fixed, and not on archived repos",
+			opt:  options{fixRepoSecurity: true, fixRepoTopics: true},
+			expected: []string{
+				"ReplaceRepoTopics([a])",
+				"SetVulnerabilityAlerts(true)",
+			},
+		},
+		{
+			name:     "collaborators",
+			opt:      options{fixRepoCollaborators: true, fixRepoWebhooks: true},
+			expected: []string{"AddCollaborator(alice, read)"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeRepoSettingsClient{}
+			if err := configureRepoSettings(tc.opt, client, "org", orgConfig); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tc.expected, client.calls) {
+				t.Errorf("expected calls %q, got %q", tc.expected, client.calls)
+			}
+		})
+	}
+}
+
+func TestWebhookSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peribolos")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "hook"), []byte("hunter2\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	cases := []struct {
+		name        string
+		dir         string
+		secret      string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:     "read secret",
+			dir:      dir,
+			secret:   "hook",
+			expected: "hunter2",
+		},
+		{
+			name:        "missing --webhook-secret-dir",
+			secret:      "hook",
+			expectedErr: true,
+		},
+		{
+			name:        "secrets outside the directory are rejected",
+			dir:         dir,
+			secret:      "../hook",
+			expectedErr: true,
+		},
+		{
+			name:        "missing secret",
+			dir:         dir,
+			secret:      "other",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := options{webhookSecretDir: tc.dir}.webhookSecret(tc.secret)
+			switch {
+			case err != nil && !tc.expectedErr:
+				t.Errorf("unexpected error: %v", err)
+			case err == nil && tc.expectedErr:
+				t.Error("expected an error")
+			case actual != tc.expected:
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -408,6 +408,21 @@ func reconcile(c reconciler, key string) error {
 			return fmt.Errorf("delete pipelinerun: %v", err)
 		}
 		return nil
+	case pj.Status.State == prowjobv1.AbortedState && !pj.Complete():
+		// Somebody aborted the job, e.g. through deck, so tear down the pipeline run
+		if havePipelineRun && p.Labels[kube.CreatedByProw] == "true" {
+			logrus.Infof("Delete PipelineRun/%s of aborted job", key)
+			if err := c.deletePipelineRun(ctx, namespace, name); err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("delete pipelinerun: %v", err)
+			}
+		}
+		now := c.now()
+		newpj.Status.CompletionTime = &now
+		logrus.Infof("Update ProwJob/%s: aborted", key)
+		if _, err := c.patchProwJob(pj, newpj); err != nil {
+			return fmt.Errorf("update prow status: %v", err)
+		}
+		return nil
 	case finalState(pj.Status.State):
 		logrus.Infof("Observed finished: %s", key)
 		return nil
@@ -301,6 +301,21 @@ func TestReconcile(t *testing.T) {
 		},
 		{
 			name: "do not create pipeline run for aborted prowjob",
+			observedJob: &prowjobv1.ProwJob{
+				Spec: prowjobv1.ProwJobSpec{
+					Agent:           prowjobv1.TektonAgent,
+					PipelineRunSpec: &pipelineSpec,
+				},
+				Status: prowjobv1.ProwJobStatus{
+					State:          prowjobv1.AbortedState,
+					BuildID:        pipelineID,
+					CompletionTime: &now,
+				},
+			},
+			expectedJob: noJobChange,
+		},
+		{
+			name: "completes aborted prowjob without pipeline run",
 			observedJob: &prowjobv1.ProwJob{
 				Spec: prowjobv1.ProwJobSpec{
 					Agent:           prowjobv1.TektonAgent,
@@ -311,7 +326,40 @@ func TestReconcile(t *testing.T) {
 					BuildID: pipelineID,
 				},
 			},
-			expectedJob: noJobChange,
+			expectedJob: func(pj prowjobv1.ProwJob, _ pipelinev1alpha1.PipelineRun) prowjobv1.ProwJob {
+				pj.Status.CompletionTime = &now
+				return pj
+			},
+		},
+		{
+			name: "delete pipeline run of aborted prowjob",
+			observedJob: &prowjobv1.ProwJob{
+				Spec: prowjobv1.ProwJobSpec{
+					Agent:           prowjobv1.TektonAgent,
+					PipelineRunSpec: &pipelineSpec,
+				},
+				Status: prowjobv1.ProwJobStatus{
+					State:       prowjobv1.AbortedState,
+					Description: "Aborted by someone.",
+					BuildID:     pipelineID,
+				},
+			},
+			observedPipelineRun: func() *pipelinev1alpha1.PipelineRun {
+				pj := prowjobv1.ProwJob{}
+				pj.Spec.Type = prowjobv1.PeriodicJob
+				pj.Spec.Agent = prowjobv1.TektonAgent
+				pj.Spec.PipelineRunSpec = &pipelineSpec
+				pj.Status.BuildID = pipelineID
+				p, err := makePipelineRun(pj)
+				if err != nil {
+					panic(err)
+				}
+				return p
+			}(),
+			expectedJob: func(pj prowjobv1.ProwJob, _ pipelinev1alpha1.PipelineRun) prowjobv1.ProwJob {
+				pj.Status.CompletionTime = &now
+				return pj
+			},
 		},
 		{
 			name: "delete pipeline run after deleting prowjob",
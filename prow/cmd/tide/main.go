@@ -29,9 +29,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"k8s.io/test-infra/pkg/flagutil"
+	"k8s.io/test-infra/pkg/io"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/config/secret"
 	prowflagutil "k8s.io/test-infra/prow/flagutil"
+	"k8s.io/test-infra/prow/gerrit/client"
 	"k8s.io/test-infra/prow/git/v2"
 	"k8s.io/test-infra/prow/logrusutil"
 	"k8s.io/test-infra/prow/metrics"
@@ -68,10 +70,18 @@ type options struct {
 	// a) the gcs credentials can write to this bucket
 	// b) the default acls do not expose any private info
 	statusURI string
+
+	// gerrit makes Tide submit Gerrit changes instead of merging GitHub PRs.
+	gerrit         bool
+	cookiefilePath string
 }
 
 func (o *options) Validate() error {
-	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.storage} {
+	groups := []flagutil.OptionGroup{&o.kubernetes, &o.storage}
+	if !o.gerrit {
+		groups = append(groups, &o.github)
+	}
+	for _, group := range groups {
 		if err := group.Validate(o.dryRun); err != nil {
 			return err
 		}
@@ -95,6 +105,8 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	fs.IntVar(&o.maxRecordsPerPool, "max-records-per-pool", 1000, "The maximum number of history records stored for an individual Tide pool.")
 	fs.StringVar(&o.historyURI, "history-uri", "", "The /local/path,gs://path/to/object or s3://path/to/object to store tide action history. GCS writes will use the default object ACL for the bucket")
 	fs.StringVar(&o.statusURI, "status-path", "", "The /local/path, gs://path/to/object or s3://path/to/object to store status controller state. GCS writes will use the default object ACL for the bucket.")
+	fs.BoolVar(&o.gerrit, "gerrit", false, "If true, submit the Gerrit changes selected by tide.gerrit.queries instead of merging GitHub PRs.")
+	fs.StringVar(&o.cookiefilePath, "cookiefile", "", "Path to git http.cookiefile to authenticate to Gerrit with, leave empty for anonymous.")
 
 	fs.Parse(args)
 	o.configPath = config.ConfigPath(o.configPath)
@@ -124,35 +136,6 @@ func main() {
 	}
 	cfg := configAgent.Config
 
-	secretAgent := &secret.Agent{}
-	if err := secretAgent.Start([]string{o.github.TokenPath}); err != nil {
-		logrus.WithError(err).Fatal("Error starting secrets agent.")
-	}
-
-	githubSync, err := o.github.GitHubClientWithLogFields(secretAgent, o.dryRun, logrus.Fields{"controller": "sync"})
-	if err != nil {
-		logrus.WithError(err).Fatal("Error getting GitHub client for sync.")
-	}
-
-	githubStatus, err := o.github.GitHubClientWithLogFields(secretAgent, o.dryRun, logrus.Fields{"controller": "status-update"})
-	if err != nil {
-		logrus.WithError(err).Fatal("Error getting GitHub client for status.")
-	}
-
-	// The sync loop should be allowed more tokens than the status loop because
-	// it has to list all PRs in the pool every loop while the status loop only
-	// has to list changed PRs every loop.
-	// The sync loop should have a much lower burst allowance than the status
-	// loop which may need to update many statuses upon restarting Tide after
-	// changing the context format or starting Tide on a new repo.
-	githubSync.Throttle(o.syncThrottle, 3*tokensPerIteration(o.syncThrottle, cfg().Tide.SyncPeriod.Duration))
-	githubStatus.Throttle(o.statusThrottle, o.statusThrottle/2)
-
-	gitClient, err := o.github.GitClient(secretAgent, o.dryRun)
-	if err != nil {
-		logrus.WithError(err).Fatal("Error getting Git client.")
-	}
-
 	kubeCfg, err := o.kubernetes.InfrastructureClusterConfig(o.dryRun)
 	if err != nil {
 		logrus.WithError(err).Fatal("Error getting kubeconfig.")
@@ -163,9 +146,12 @@ func main() {
 	if err != nil {
 		logrus.WithError(err).Fatal("Error constructing mgr.")
 	}
-	c, err := tide.NewController(githubSync, githubStatus, mgr, cfg, git.ClientFactoryFrom(gitClient), o.maxRecordsPerPool, opener, o.historyURI, o.statusURI, nil)
-	if err != nil {
-		logrus.WithError(err).Fatal("Error creating Tide controller.")
+
+	var c controller
+	if o.gerrit {
+		c = newGerritController(o, cfg, mgr, opener)
+	} else {
+		c = newGitHubController(o, cfg, mgr, opener)
 	}
 	interrupts.Run(func(ctx context.Context) {
 		if err := mgr.Start(ctx.Done()); err != nil {
@@ -178,14 +164,9 @@ func main() {
 	if synced := mgr.GetCache().WaitForCacheSync(mgrSyncCtx.Done()); !synced {
 		logrus.Fatal("Timed out waiting for cachesync")
 	}
-	interrupts.OnInterrupt(func() {
-		c.Shutdown()
-		if err := gitClient.Clean(); err != nil {
-			logrus.WithError(err).Error("Could not clean up git client cache.")
-		}
-	})
+	interrupts.OnInterrupt(c.Shutdown)
 	http.Handle("/", c)
-	http.Handle("/history", c.History)
+	http.Handle("/history", c.history())
 	server := &http.Server{Addr: ":" + strconv.Itoa(o.port)}
 
 	// Push metrics to the configured prometheus pushgateway endpoint or serve them
@@ -209,7 +190,92 @@ func main() {
 	})
 }
 
-func sync(c *tide.Controller) {
+// controller syncs the pools of either GitHub PRs or Gerrit changes.
+type controller interface {
+	http.Handler
+	Sync() error
+	Shutdown()
+	history() http.Handler
+}
+
+type gitHubController struct {
+	*tide.Controller
+	gitClient interface{ Clean() error }
+}
+
+func (c *gitHubController) Shutdown() {
+	c.Controller.Shutdown()
+	if err := c.gitClient.Clean(); err != nil {
+		logrus.WithError(err).Error("Could not clean up git client cache.")
+	}
+}
+
+func (c *gitHubController) history() http.Handler {
+	return c.History
+}
+
+func newGitHubController(o options, cfg config.Getter, mgr manager.Manager, opener io.Opener) controller {
+	secretAgent := &secret.Agent{}
+	if err := secretAgent.Start([]string{o.github.TokenPath}); err != nil {
+		logrus.WithError(err).Fatal("Error starting secrets agent.")
+	}
+
+	githubSync, err := o.github.GitHubClientWithLogFields(secretAgent, o.dryRun, logrus.Fields{"controller": "sync"})
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting GitHub client for sync.")
+	}
+
+	githubStatus, err := o.github.GitHubClientWithLogFields(secretAgent, o.dryRun, logrus.Fields{"controller": "status-update"})
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting GitHub client for status.")
+	}
+
+	// The sync loop should be allowed more tokens than the status loop because
+	// it has to list all PRs in the pool every loop while the status loop only
+	// has to list changed PRs every loop.
+	// The sync loop should have a much lower burst allowance than the status
+	// loop which may need to update many statuses upon restarting Tide after
+	// changing the context format or starting Tide on a new repo.
+	githubSync.Throttle(o.syncThrottle, 3*tokensPerIteration(o.syncThrottle, cfg().Tide.SyncPeriod.Duration))
+	githubStatus.Throttle(o.statusThrottle, o.statusThrottle/2)
+
+	gitClient, err := o.github.GitClient(secretAgent, o.dryRun)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting Git client.")
+	}
+
+	c, err := tide.NewController(githubSync, githubStatus, mgr, cfg, git.ClientFactoryFrom(gitClient), o.maxRecordsPerPool, opener, o.historyURI, o.statusURI, nil)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating Tide controller.")
+	}
+	return &gitHubController{Controller: c, gitClient: gitClient}
+}
+
+type gerritController struct {
+	*tide.GerritController
+}
+
+func (c *gerritController) history() http.Handler {
+	return c.History
+}
+
+func newGerritController(o options, cfg config.Getter, mgr manager.Manager, opener io.Opener) controller {
+	// The Gerrit instances are only read at startup, so adding one to the
+	// config requires a restart.
+	gc, err := client.NewClient(cfg().Tide.Gerrit.Instances())
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating Gerrit client.")
+	}
+	gc.Start(o.cookiefilePath)
+
+	c, err := tide.NewGerritController(gc, mgr, cfg, o.maxRecordsPerPool, opener, o.historyURI, o.dryRun, nil)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating Tide controller.")
+	}
+	return &gerritController{GerritController: c}
+}
+
+func sync(c controller) {
 	if err := c.Sync(); err != nil {
 		logrus.WithError(err).Error("Error syncing.")
 	}
@@ -106,6 +106,7 @@ type ProwConfig struct {
 	// Deprecated: this option will be removed in May 2020.
 	SlackReporter        *SlackReporter       `json:"slack_reporter,omitempty"`
 	SlackReporterConfigs SlackReporterConfigs `json:"slack_reporter_configs,omitempty"`
+	WebhookReporter      WebhookReporter      `json:"webhook_reporter,omitempty"`
 	InRepoConfig         InRepoConfig         `json:"in_repo_config"`
 
 	// TODO: Move this out of the main config.
@@ -483,6 +484,18 @@ type Plank struct {
 	// JobURLPrefixConfig is the host and path prefix under which job details
 	// will be viewable. Use `org/repo`, `org` or `*`as key and an url as value
 	JobURLPrefixConfig map[string]string `json:"job_url_prefix_config,omitempty"`
+
+	// ConcurrencyQuotas limits how many jobs of an org or repo run at once.
+	// Use `org/repo` or `org` as a key, only the most specific key of a job
+	// applies. Jobs of orgs and repos without a quota are not limited.
+	ConcurrencyQuotas map[string]int `json:"concurrency_quotas,omitempty"`
+
+	// FairShareWeights are the relative shares of the build clusters that
+	// orgs and repos get when plank picks which triggered jobs to start.
+	// Use `org/repo`, `org` or `*` as a key. Repos share the weight of the
+	// most specific key that matches them, i.e. all repos of an org with a
+	// weight compete for one share. Defaults to 1.
+	FairShareWeights map[string]int `json:"fair_share_weights,omitempty"`
 }
 
 func (p Plank) GetDefaultDecorationConfigs(repo string) *prowapi.DecorationConfig {
@@ -497,6 +510,46 @@ func (p Plank) GetDefaultDecorationConfigs(repo string) *prowapi.DecorationConfi
 	return def
 }
 
+// GetConcurrencyQuota returns the key and the value of the concurrency
+// quota that applies to jobs for the given refs. It returns an empty key
+// if the jobs are not limited.
+func (p Plank) GetConcurrencyQuota(refs *prowapi.Refs) (string, int) {
+	if refs == nil {
+		return "", 0
+	}
+	orgRepo := fmt.Sprintf("%s/%s", refs.Org, refs.Repo)
+	if quota, ok := p.ConcurrencyQuotas[orgRepo]; ok {
+		return orgRepo, quota
+	}
+	if quota, ok := p.ConcurrencyQuotas[refs.Org]; ok {
+		return refs.Org, quota
+	}
+	return "", 0
+}
+
+// GetFairShare returns who jobs for the given refs share the build clusters
+// with, either an org or a repo, and the weight of that share.
+func (p Plank) GetFairShare(refs *prowapi.Refs) (string, int) {
+	if refs == nil {
+		return "", p.fairShareWeight("*")
+	}
+	orgRepo := fmt.Sprintf("%s/%s", refs.Org, refs.Repo)
+	if _, ok := p.FairShareWeights[orgRepo]; ok {
+		return orgRepo, p.fairShareWeight(orgRepo)
+	}
+	if _, ok := p.FairShareWeights[refs.Org]; ok {
+		return refs.Org, p.fairShareWeight(refs.Org)
+	}
+	return orgRepo, p.fairShareWeight("*")
+}
+
+func (p Plank) fairShareWeight(key string) int {
+	if weight, ok := p.FairShareWeights[key]; ok {
+		return weight
+	}
+	return 1
+}
+
 func (p Plank) GetJobURLPrefix(refs *prowapi.Refs) string {
 	if refs == nil {
 		return p.JobURLPrefixConfig["*"]
@@ -517,6 +570,10 @@ type Gerrit struct {
 	// RateLimit defines how many changes to query per gerrit API call
 	// default is 5
 	RateLimit int `json:"ratelimit,omitempty"`
+	// EventsInterval is how often the events-log of the binded gerrit
+	// instances is read, when the adapter consumes events.
+	// default is 5s
+	EventsInterval *metav1.Duration `json:"events_interval,omitempty"`
 }
 
 // JenkinsOperator is config for the jenkins-operator controller.
@@ -542,6 +599,22 @@ type GitHubReporter struct {
 	//
 	// defaults to both presubmit and postsubmit jobs.
 	JobTypesToReport []prowapi.ProwJobType `json:"job_types_to_report,omitempty"`
+	// CheckRunRepos lists the orgs and org/repos whose jobs are reported
+	// as check runs instead of commit statuses. Creating check runs
+	// requires the token to belong to a GitHub App. Jobs can override
+	// this with reporter_config.github.check_run.
+	CheckRunRepos []string `json:"check_run_repos,omitempty"`
+}
+
+// ReportsCheckRuns determines whether the jobs of the repo are reported as
+// check runs by default.
+func (gr *GitHubReporter) ReportsCheckRuns(org, repo string) bool {
+	for _, orgRepo := range gr.CheckRunRepos {
+		if orgRepo == org || orgRepo == org+"/"+repo {
+			return true
+		}
+	}
+	return false
 }
 
 // Sinker is config for the sinker controller.
@@ -727,6 +800,27 @@ type SlackReporter struct {
 	JobStatesToReport []prowapi.ProwJobState `json:"job_states_to_report"`
 	Channel           string                 `json:"channel"`
 	ReportTemplate    string                 `json:"report_template"`
+
+	// Threaded reports the jobs of a ref, or the states of a periodic run,
+	// as replies in the thread of a single parent message.
+	Threaded bool `json:"threaded,omitempty"`
+	// ParentTemplate renders the parent message of a thread from the first
+	// job reported in it.
+	ParentTemplate string `json:"parent_template,omitempty"`
+	// EditMessages edits the reply of a job when its state changes, instead
+	// of replying again. Only applies to threaded reports.
+	EditMessages bool `json:"edit_messages,omitempty"`
+
+	// DigestInterval is how often to send a digest of the failed jobs that
+	// were reported. No digests are sent if it is unset.
+	DigestInterval *metav1.Duration `json:"digest_interval,omitempty"`
+	// DigestChannel is the channel digests are sent to. Defaults to Channel.
+	DigestChannel string `json:"digest_channel,omitempty"`
+
+	// UserMapping maps the GitHub logins of the owners of jobs, which are the
+	// authors of the pull requests and the owners in the reporter config of
+	// the job, to the Slack user IDs mentioned when their jobs fail.
+	UserMapping map[string]string `json:"user_mapping,omitempty"`
 }
 
 // SlackReporterConfigs represents the config for the Slack reporter(s).
@@ -759,6 +853,17 @@ func (cfg *SlackReporter) DefaultAndValidate() error {
 		return errors.New("channel must be set")
 	}
 
+	// Default ParentTemplate
+	if cfg.ParentTemplate == "" {
+		cfg.ParentTemplate = `{{if eq .Spec.Type "periodic"}}Periodic job {{.Spec.Job}}{{else}}Jobs for {{with .Spec.Refs}}{{.Org}}/{{.Repo}}{{range .Pulls}} #{{.Number}}{{end}} on {{.BaseRef}}{{end}}{{end}}`
+	}
+	if cfg.DigestInterval != nil && cfg.DigestInterval.Duration <= 0 {
+		return errors.New("digest_interval must be positive")
+	}
+	if cfg.DigestChannel == "" {
+		cfg.DigestChannel = cfg.Channel
+	}
+
 	// Validate ReportTemplate
 	tmpl, err := template.New("").Parse(cfg.ReportTemplate)
 	if err != nil {
@@ -768,6 +873,166 @@ func (cfg *SlackReporter) DefaultAndValidate() error {
 		return fmt.Errorf("failed to execute report_template: %v", err)
 	}
 
+	// Validate ParentTemplate
+	tmpl, err = template.New("").Parse(cfg.ParentTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse parent_template: %v", err)
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, &prowapi.ProwJob{}); err != nil {
+		return fmt.Errorf("failed to execute parent_template: %v", err)
+	}
+
+	return nil
+}
+
+// WebhookReporter is the config of the webhook reporter of crier, which
+// sends the state changes of ProwJobs to endpoints as CloudEvents.
+type WebhookReporter struct {
+	Endpoints []WebhookEndpoint `json:"endpoints,omitempty"`
+}
+
+// WebhookEndpoint is an endpoint of the webhook reporter and the ProwJobs
+// reported to it.
+type WebhookEndpoint struct {
+	// Name identifies the endpoint in logs and metrics.
+	Name string `json:"name"`
+	// URL is where events are POSTed.
+	URL string `json:"url"`
+	// Mode is the CloudEvents content mode, structured or binary.
+	// Defaults to structured.
+	Mode string `json:"mode,omitempty"`
+
+	// Repos are the orgs and org/repos of the jobs to report, all by default.
+	// Jobs without refs only match if no repos are set.
+	Repos []string `json:"repos,omitempty"`
+	// Jobs are regexes matching the names of the jobs to report, all by
+	// default.
+	Jobs []string `json:"jobs,omitempty"`
+	// JobTypesToReport are the types of the jobs to report, all by default.
+	JobTypesToReport []prowapi.ProwJobType `json:"job_types_to_report,omitempty"`
+	// JobStatesToReport are the states to report, all by default.
+	JobStatesToReport []prowapi.ProwJobState `json:"job_states_to_report,omitempty"`
+
+	// PayloadTemplate renders the data of events from the ProwJob, in place
+	// of the default summary of the job.
+	PayloadTemplate string `json:"payload_template,omitempty"`
+	// PayloadContentType is the content type of the rendered payload.
+	// Defaults to application/json.
+	PayloadContentType string `json:"payload_content_type,omitempty"`
+
+	// JobRegexps are the compiled Jobs.
+	JobRegexps []*regexp.Regexp `json:"-"`
+	// Template is the parsed PayloadTemplate, if it is set.
+	Template *template.Template `json:"-"`
+}
+
+const (
+	// WebhookStructuredMode sends the attributes and data of events in the body.
+	WebhookStructuredMode = "structured"
+	// WebhookBinaryMode sends the attributes of events as headers and their
+	// data as the body.
+	WebhookBinaryMode = "binary"
+)
+
+// ShouldReport determines whether the state of the job is reported to the
+// endpoint.
+func (e *WebhookEndpoint) ShouldReport(pj *prowapi.ProwJob) bool {
+	if len(e.Repos) > 0 {
+		refs := pj.Spec.Refs
+		if refs == nil {
+			return false
+		}
+		matched := false
+		for _, repo := range e.Repos {
+			if repo == refs.Org || repo == refs.Org+"/"+refs.Repo {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(e.JobRegexps) > 0 {
+		matched := false
+		for _, re := range e.JobRegexps {
+			if re.MatchString(pj.Spec.Job) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(e.JobTypesToReport) > 0 {
+		matched := false
+		for _, jobType := range e.JobTypesToReport {
+			if jobType == pj.Spec.Type {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(e.JobStatesToReport) > 0 {
+		for _, state := range e.JobStatesToReport {
+			if state == pj.Status.State {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// DefaultAndValidate defaults the endpoints, compiles their regexes and
+// parses their templates.
+func (cfg *WebhookReporter) DefaultAndValidate() error {
+	names := sets.NewString()
+	for i := range cfg.Endpoints {
+		e := &cfg.Endpoints[i]
+		if e.Name == "" {
+			return errors.New("endpoints must have a name")
+		}
+		if names.Has(e.Name) {
+			return fmt.Errorf("endpoint %q is defined more than once", e.Name)
+		}
+		names.Insert(e.Name)
+		if u, err := url.Parse(e.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("endpoint %q has an invalid url %q", e.Name, e.URL)
+		}
+		if e.Mode == "" {
+			e.Mode = WebhookStructuredMode
+		}
+		if e.Mode != WebhookStructuredMode && e.Mode != WebhookBinaryMode {
+			return fmt.Errorf("endpoint %q has an invalid mode %q, it must be %s or %s", e.Name, e.Mode, WebhookStructuredMode, WebhookBinaryMode)
+		}
+		e.JobRegexps = nil
+		for _, job := range e.Jobs {
+			re, err := regexp.Compile(job)
+			if err != nil {
+				return fmt.Errorf("endpoint %q has an invalid job regex %q: %v", e.Name, job, err)
+			}
+			e.JobRegexps = append(e.JobRegexps, re)
+		}
+		if e.PayloadContentType == "" {
+			e.PayloadContentType = "application/json"
+		}
+		e.Template = nil
+		if e.PayloadTemplate != "" {
+			tmpl, err := template.New(e.Name).Parse(e.PayloadTemplate)
+			if err != nil {
+				return fmt.Errorf("endpoint %q has an invalid payload_template: %v", e.Name, err)
+			}
+			if err := tmpl.Execute(&bytes.Buffer{}, &prowapi.ProwJob{}); err != nil {
+				return fmt.Errorf("failed to execute the payload_template of endpoint %q: %v", e.Name, err)
+			}
+			e.Template = tmpl
+		}
+	}
 	return nil
 }
 
@@ -1208,6 +1473,10 @@ func (c *Config) validateComponentConfig() error {
 		}
 	}
 
+	if err := c.WebhookReporter.DefaultAndValidate(); err != nil {
+		return fmt.Errorf("failed to validate webhook_reporter config: %v", err)
+	}
+
 	// TODO(@clarketm): Remove in July 2020
 	if c.Deck.RerunAuthConfig != nil {
 		logrus.Warning("rerun_auth_config will be deprecated in July 2020, and it will be replaced with rerun_auth_configs['*'].")
@@ -1240,9 +1509,15 @@ func validateJobBase(v JobBase, jobType prowapi.ProwJobType, podNamespace string
 	if v.MaxConcurrency < 0 {
 		return fmt.Errorf("max_concurrency: %d must be a non-negative number", v.MaxConcurrency)
 	}
+	if v.Priority != 0 && v.Agent != string(prowapi.KubernetesAgent) {
+		return fmt.Errorf("priority only applies to agent: %s (found %q)", prowapi.KubernetesAgent, v.Agent)
+	}
 	if err := validateAgent(v, podNamespace); err != nil {
 		return err
 	}
+	if err := v.Retry.Validate(); err != nil {
+		return fmt.Errorf("invalid retry policy: %v", err)
+	}
 	if err := validatePodSpec(jobType, v.Spec); err != nil {
 		return err
 	}
@@ -1285,9 +1560,90 @@ func validatePresubmits(presubmits []Presubmit, podNamespace string) error {
 		validPresubmits[ps.Name] = append(validPresubmits[ps.Name], ps)
 	}
 
+	dependencies := map[string]jobDependencies{}
+	for _, ps := range presubmits {
+		dependencies[ps.Name] = dependencies[ps.Name].add(ps.RunAfterSuccess, ps.SkipReport)
+	}
+	errs = append(errs, validateRunAfterSuccess(dependencies)...)
+
 	return utilerrors.NewAggregate(errs)
 }
 
+// jobDependencies holds what validateRunAfterSuccess needs to know about the
+// jobs of one name, which may be configured once per branch.
+type jobDependencies struct {
+	runAfterSuccess sets.String
+	skipReport      bool
+}
+
+func (d jobDependencies) add(runAfterSuccess []string, skipReport bool) jobDependencies {
+	if d.runAfterSuccess == nil {
+		d.runAfterSuccess = sets.NewString()
+	}
+	d.runAfterSuccess.Insert(runAfterSuccess...)
+	d.skipReport = d.skipReport || skipReport
+	return d
+}
+
+// validateRunAfterSuccess validates the run_after_success of the jobs of one
+// repo. Dependent jobs are started when the status of the jobs they run after
+// turns successful, so all of them have to report and they must not wait on
+// each other in a cycle.
+func validateRunAfterSuccess(jobs map[string]jobDependencies) []error {
+	var errs []error
+	for _, name := range sets.StringKeySet(jobs).List() {
+		job := jobs[name]
+		if job.runAfterSuccess.Len() == 0 {
+			continue
+		}
+		if job.skipReport {
+			errs = append(errs, fmt.Errorf("job %s sets run_after_success and must not skip reporting", name))
+		}
+		for _, parent := range job.runAfterSuccess.List() {
+			if parentJob, exists := jobs[parent]; !exists {
+				errs = append(errs, fmt.Errorf("job %s runs after unknown job %s", name, parent))
+			} else if parentJob.skipReport {
+				errs = append(errs, fmt.Errorf("job %s runs after job %s, which must not skip reporting", name, parent))
+			}
+		}
+	}
+
+	// Look for cycles with a depth first search, marking jobs as visiting
+	// while their parents are searched.
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("run_after_success forms a cycle: %s", strings.Join(append(path, name), " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, parent := range jobs[name].runAfterSuccess.List() {
+			if _, exists := jobs[parent]; !exists {
+				continue
+			}
+			if err := visit(parent, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, name := range sets.StringKeySet(jobs).List() {
+		if err := visit(name, nil); err != nil {
+			errs = append(errs, err)
+			break
+		}
+	}
+	return errs
+}
+
 // ValidateRefs validates the extra refs on a presubmit for one repo
 func ValidateRefs(repo string, jobBase JobBase) error {
 	gitRefs := map[string]int{
@@ -1328,7 +1684,11 @@ func validatePostsubmits(postsubmits []Postsubmit, podNamespace string) error {
 		validPostsubmits[ps.Name] = append(validPostsubmits[ps.Name], ps)
 	}
 
-	return nil
+	dependencies := map[string]jobDependencies{}
+	for _, ps := range postsubmits {
+		dependencies[ps.Name] = dependencies[ps.Name].add(ps.RunAfterSuccess, ps.SkipReport)
+	}
+	return utilerrors.NewAggregate(validateRunAfterSuccess(dependencies))
 }
 
 // validatePeriodics validates a set of periodics
@@ -1416,6 +1776,18 @@ func parseProwConfig(c *Config) error {
 		return fmt.Errorf("validating plank config: %v", err)
 	}
 
+	for key, quota := range c.Plank.ConcurrencyQuotas {
+		if quota < 0 {
+			return fmt.Errorf("plank has invalid concurrency quota for %q (%d), it must not be negative", key, quota)
+		}
+	}
+
+	for key, weight := range c.Plank.FairShareWeights {
+		if weight <= 0 {
+			return fmt.Errorf("plank has invalid fair share weight for %q (%d), it needs to be a positive number", key, weight)
+		}
+	}
+
 	if c.Plank.PodPendingTimeout == nil {
 		c.Plank.PodPendingTimeout = &metav1.Duration{Duration: 24 * time.Hour}
 	}
@@ -1436,6 +1808,10 @@ func parseProwConfig(c *Config) error {
 		c.Gerrit.RateLimit = 5
 	}
 
+	if c.Gerrit.EventsInterval == nil {
+		c.Gerrit.EventsInterval = &metav1.Duration{Duration: 5 * time.Second}
+	}
+
 	if len(c.GitHubReporter.JobTypesToReport) == 0 {
 		c.GitHubReporter.JobTypesToReport = append(c.GitHubReporter.JobTypesToReport, prowapi.PresubmitJob, prowapi.PostsubmitJob)
 	}
@@ -1447,6 +1823,11 @@ func parseProwConfig(c *Config) error {
 			return fmt.Errorf("invalid job_types_to_report: %v", t)
 		}
 	}
+	for _, orgRepo := range c.GitHubReporter.CheckRunRepos {
+		if parts := strings.Split(orgRepo, "/"); len(parts) > 2 || parts[0] == "" || (len(parts) == 2 && parts[1] == "") {
+			return fmt.Errorf("invalid check_run_repos entry %q, must be an org or org/repo", orgRepo)
+		}
+	}
 
 	for i := range c.JenkinsOperators {
 		if err := ValidateController(&c.JenkinsOperators[i].Controller); err != nil {
@@ -1626,11 +2007,22 @@ func parseProwConfig(c *Config) error {
 		c.Tide.MergeTemplate[name] = templates
 	}
 
+	for name, depth := range c.Tide.SpeculativeDepthMap {
+		if depth < 0 {
+			return fmt.Errorf("speculative depth %d for %s is negative", depth, name)
+		}
+	}
+
 	for i, tq := range c.Tide.Queries {
 		if err := tq.Validate(); err != nil {
 			return fmt.Errorf("tide query (index %d) is invalid: %v", i, err)
 		}
 	}
+	for i, tq := range c.Tide.Gerrit.Queries {
+		if err := tq.Validate(); err != nil {
+			return fmt.Errorf("tide gerrit query (index %d) is invalid: %v", i, err)
+		}
+	}
 
 	if c.ProwJobNamespace == "" {
 		c.ProwJobNamespace = "default"
@@ -1739,6 +2131,8 @@ func validateAgent(v JobBase, podNamespace string) error {
 		return fmt.Errorf("decoration requires agent: %s (found %q)", k, agent)
 	case v.ErrorOnEviction && agent != k:
 		return fmt.Errorf("error_on_eviction only applies to agent: %s (found %q)", k, agent)
+	case v.Retry != nil && agent != k:
+		return fmt.Errorf("retry only applies to agent: %s (found %q)", k, agent)
 	case v.Namespace == nil || *v.Namespace == "":
 		return fmt.Errorf("failed to default namespace")
 	case *v.Namespace != podNamespace && agent != p:
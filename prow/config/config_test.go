@@ -23,6 +23,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"text/template"
@@ -30,6 +31,7 @@ import (
 
 	pipelinev1alpha1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/diff"
 	"k8s.io/apimachinery/pkg/util/sets"
 	utilpointer "k8s.io/utils/pointer"
@@ -519,6 +521,22 @@ func TestValidateAgent(t *testing.T) {
 			},
 			pass: true,
 		},
+		{
+			name: "retry allowed for kubernetes agent",
+			base: func(j *JobBase) {
+				j.Retry = &prowapi.RetryPolicy{MaxAttempts: 2}
+			},
+			pass: true,
+		},
+		{
+			name: "retry requires kubernetes agent",
+			base: func(j *JobBase) {
+				j.Agent = jenk
+				j.Spec = nil
+				j.DecorationConfig = nil
+				j.Retry = &prowapi.RetryPolicy{MaxAttempts: 2}
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -1049,6 +1067,16 @@ func TestValidateJobBase(t *testing.T) {
 			},
 			pass: true,
 		},
+		{
+			name: "invalid retry policy",
+			base: JobBase{
+				Name:      "name",
+				Agent:     ka,
+				Spec:      &goodSpec,
+				Namespace: &ns,
+				Retry:     &prowapi.RetryPolicy{MaxAttempts: 2, States: []prowapi.ProwJobState{prowapi.AbortedState}},
+			},
+		},
 		{
 			name: "invalid rerun_permissions",
 			base: JobBase{
@@ -1382,6 +1410,42 @@ presubmits:
 			},
 			expectError: false,
 		},
+		{
+			name: "plank concurrency quotas and fair share weights",
+			prowConfig: `
+plank:
+  concurrency_quotas:
+    org: 10
+    org/repo: 2
+  fair_share_weights:
+    "*": 1
+    org: 3`,
+			verify: func(c *Config) error {
+				if key, quota := c.Plank.GetConcurrencyQuota(&prowapi.Refs{Org: "org", Repo: "repo"}); key != "org/repo" || quota != 2 {
+					return fmt.Errorf("expected quota 2 for org/repo, got %d for %q", quota, key)
+				}
+				if share, weight := c.Plank.GetFairShare(&prowapi.Refs{Org: "org", Repo: "repo"}); share != "org" || weight != 3 {
+					return fmt.Errorf("expected weight 3 for org, got %d for %q", weight, share)
+				}
+				return nil
+			},
+		},
+		{
+			name: "reject negative concurrency quota",
+			prowConfig: `
+plank:
+  concurrency_quotas:
+    org: -1`,
+			expectError: true,
+		},
+		{
+			name: "reject fair share weight of zero",
+			prowConfig: `
+plank:
+  fair_share_weights:
+    org/repo: 0`,
+			expectError: true,
+		},
 		{
 			name: "dup presubmits main file",
 			prowConfig: `
@@ -2101,6 +2165,25 @@ github_reporter:
 `,
 			expectTypes: []prowapi.ProwJobType{prowapi.PresubmitJob, prowapi.PostsubmitJob},
 		},
+		{
+			name: "accept check run orgs and repos",
+			prowConfig: `
+github_reporter:
+  check_run_repos:
+  - kubernetes
+  - kubernetes-sigs/kind
+`,
+			expectTypes: []prowapi.ProwJobType{prowapi.PresubmitJob, prowapi.PostsubmitJob},
+		},
+		{
+			name: "reject invalid check run repos",
+			prowConfig: `
+github_reporter:
+  check_run_repos:
+  - kubernetes/
+`,
+			expectError: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -2131,6 +2214,24 @@ github_reporter:
 	}
 }
 
+func TestReportsCheckRuns(t *testing.T) {
+	gr := GitHubReporter{CheckRunRepos: []string{"kubernetes", "kubernetes-sigs/kind"}}
+	var testCases = []struct {
+		org, repo string
+		expected  bool
+	}{
+		{org: "kubernetes", repo: "test-infra", expected: true},
+		{org: "kubernetes-sigs", repo: "kind", expected: true},
+		{org: "kubernetes-sigs", repo: "cluster-api", expected: false},
+		{org: "kubernetes-sigs", repo: "", expected: false},
+	}
+	for _, tc := range testCases {
+		if actual := gr.ReportsCheckRuns(tc.org, tc.repo); actual != tc.expected {
+			t.Errorf("%s/%s: expected %t, got %t", tc.org, tc.repo, tc.expected, actual)
+		}
+	}
+}
+
 func TestValidRerunAuthConfig(t *testing.T) {
 	var testCases = []struct {
 		name        string
@@ -2466,6 +2567,101 @@ func TestPlankJobURLPrefix(t *testing.T) {
 	}
 }
 
+func TestPlankConcurrencyQuota(t *testing.T) {
+	plank := Plank{
+		ConcurrencyQuotas: map[string]int{
+			"my-org":         10,
+			"my-org/my-repo": 2,
+		},
+	}
+	testCases := []struct {
+		name          string
+		refs          *prowapi.Refs
+		expectedKey   string
+		expectedQuota int
+	}{
+		{
+			name: "Nil refs are not limited",
+		},
+		{
+			name: "Refs without quota are not limited",
+			refs: &prowapi.Refs{Org: "other-org", Repo: "my-repo"},
+		},
+		{
+			name:          "Matching repo returns quota of repo",
+			refs:          &prowapi.Refs{Org: "my-org", Repo: "my-repo"},
+			expectedKey:   "my-org/my-repo",
+			expectedQuota: 2,
+		},
+		{
+			name:          "Matching org returns quota of org",
+			refs:          &prowapi.Refs{Org: "my-org", Repo: "my-other-repo"},
+			expectedKey:   "my-org",
+			expectedQuota: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if key, quota := plank.GetConcurrencyQuota(tc.refs); key != tc.expectedKey || quota != tc.expectedQuota {
+				t.Errorf("expected quota %d for %q but got %d for %q", tc.expectedQuota, tc.expectedKey, quota, key)
+			}
+		})
+	}
+}
+
+func TestPlankFairShare(t *testing.T) {
+	testCases := []struct {
+		name           string
+		weights        map[string]int
+		refs           *prowapi.Refs
+		expectedShare  string
+		expectedWeight int
+	}{
+		{
+			name:           "Nil refs share the default weight",
+			weights:        map[string]int{"*": 2},
+			expectedWeight: 2,
+		},
+		{
+			name:           "Weight defaults to one",
+			refs:           &prowapi.Refs{Org: "my-org", Repo: "my-repo"},
+			expectedShare:  "my-org/my-repo",
+			expectedWeight: 1,
+		},
+		{
+			name:           "Repos without weight have their own share with the default weight",
+			weights:        map[string]int{"*": 2, "other-org": 5},
+			refs:           &prowapi.Refs{Org: "my-org", Repo: "my-repo"},
+			expectedShare:  "my-org/my-repo",
+			expectedWeight: 2,
+		},
+		{
+			name:           "Repos share the weight of their org",
+			weights:        map[string]int{"my-org": 5},
+			refs:           &prowapi.Refs{Org: "my-org", Repo: "my-repo"},
+			expectedShare:  "my-org",
+			expectedWeight: 5,
+		},
+		{
+			name:           "Repo weight takes precedence",
+			weights:        map[string]int{"my-org": 5, "my-org/my-repo": 3},
+			refs:           &prowapi.Refs{Org: "my-org", Repo: "my-repo"},
+			expectedShare:  "my-org/my-repo",
+			expectedWeight: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plank := Plank{FairShareWeights: tc.weights}
+			if share, weight := plank.GetFairShare(tc.refs); share != tc.expectedShare || weight != tc.expectedWeight {
+				t.Errorf("expected weight %d for %q but got %d for %q", tc.expectedWeight, tc.expectedShare, weight, share)
+			}
+		})
+	}
+}
+
 func TestValidateComponentConfig(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -2761,6 +2957,58 @@ func TestSlackReporterValidation(t *testing.T) {
 			},
 			successExpected: false,
 		},
+		{
+			name: "Threaded config with digests - no error",
+			config: func() Config {
+				slackCfg := map[string]SlackReporter{
+					"*": {
+						Channel:        "my-channel",
+						Threaded:       true,
+						DigestInterval: &metav1.Duration{Duration: time.Hour},
+					},
+				}
+				return Config{
+					ProwConfig: ProwConfig{
+						SlackReporterConfigs: slackCfg,
+					},
+				}
+			},
+			successExpected: true,
+		},
+		{
+			name: "Invalid parent template - error",
+			config: func() Config {
+				slackCfg := map[string]SlackReporter{
+					"*": {
+						Channel:        "my-channel",
+						ParentTemplate: "{{ .Undef}}",
+					},
+				}
+				return Config{
+					ProwConfig: ProwConfig{
+						SlackReporterConfigs: slackCfg,
+					},
+				}
+			},
+			successExpected: false,
+		},
+		{
+			name: "Negative digest interval - error",
+			config: func() Config {
+				slackCfg := map[string]SlackReporter{
+					"*": {
+						Channel:        "my-channel",
+						DigestInterval: &metav1.Duration{Duration: -time.Hour},
+					},
+				}
+				return Config{
+					ProwConfig: ProwConfig{
+						SlackReporterConfigs: slackCfg,
+					},
+				}
+			},
+			successExpected: false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -2866,6 +3114,101 @@ func TestValidateTriggering(t *testing.T) {
 	}
 }
 
+func TestValidateRunAfterSuccess(t *testing.T) {
+	testCases := []struct {
+		name         string
+		jobs         map[string]jobDependencies
+		expectedErrs []string
+	}{
+		{
+			name: "jobs without dependencies are valid",
+			jobs: map[string]jobDependencies{
+				"a": jobDependencies{}.add(nil, false),
+				"b": jobDependencies{}.add(nil, true),
+			},
+		},
+		{
+			name: "chain of dependencies is valid",
+			jobs: map[string]jobDependencies{
+				"build": jobDependencies{}.add(nil, false),
+				"unit":  jobDependencies{}.add([]string{"build"}, false),
+				"e2e":   jobDependencies{}.add([]string{"build", "unit"}, false),
+			},
+		},
+		{
+			name: "unknown job",
+			jobs: map[string]jobDependencies{
+				"e2e": jobDependencies{}.add([]string{"build"}, false),
+			},
+			expectedErrs: []string{"job e2e runs after unknown job build"},
+		},
+		{
+			name: "dependent and parent have to report",
+			jobs: map[string]jobDependencies{
+				"build": jobDependencies{}.add(nil, true),
+				"e2e":   jobDependencies{}.add([]string{"build"}, true),
+			},
+			expectedErrs: []string{
+				"job e2e sets run_after_success and must not skip reporting",
+				"job e2e runs after job build, which must not skip reporting",
+			},
+		},
+		{
+			name: "job running after itself",
+			jobs: map[string]jobDependencies{
+				"build": jobDependencies{}.add([]string{"build"}, false),
+			},
+			expectedErrs: []string{"run_after_success forms a cycle: build -> build"},
+		},
+		{
+			name: "cycle",
+			jobs: map[string]jobDependencies{
+				"a": jobDependencies{}.add([]string{"c"}, false),
+				"b": jobDependencies{}.add([]string{"a"}, false),
+				"c": jobDependencies{}.add([]string{"b"}, false),
+			},
+			expectedErrs: []string{"run_after_success forms a cycle: a -> c -> b -> a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actualErrs []string
+			for _, err := range validateRunAfterSuccess(tc.jobs) {
+				actualErrs = append(actualErrs, err.Error())
+			}
+			if !reflect.DeepEqual(actualErrs, tc.expectedErrs) {
+				t.Errorf("expected errors %v, got %v", tc.expectedErrs, actualErrs)
+			}
+		})
+	}
+}
+
+func TestValidatePresubmitsRunAfterSuccess(t *testing.T) {
+	ns := utilpointer.StringPtr("default")
+	presubmits := []Presubmit{{
+		JobBase:  JobBase{Name: "build", Agent: "jenkins", Namespace: ns},
+		Reporter: Reporter{Context: "build"},
+		Brancher: Brancher{Branches: []string{"master"}},
+	}, {
+		JobBase:         JobBase{Name: "e2e", Agent: "jenkins", Namespace: ns},
+		RunAfterSuccess: []string{"build"},
+		Reporter:        Reporter{Context: "e2e"},
+		Brancher:        Brancher{Branches: []string{"master"}},
+	}, {
+		JobBase:  JobBase{Name: "build", Agent: "jenkins", Namespace: ns},
+		Reporter: Reporter{Context: "build", SkipReport: true},
+		Brancher: Brancher{Branches: []string{"release"}},
+	}}
+	err := validatePresubmits(presubmits, "default")
+	if err == nil || !strings.Contains(err.Error(), "job e2e runs after job build, which must not skip reporting") {
+		t.Errorf("expected the reporting of all build jobs to be validated, got %v", err)
+	}
+	if err := validatePresubmits(presubmits[:2], "default"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
 func TestRefGetterForGitHubPullRequest(t *testing.T) {
 	testCases := []struct {
 		name   string
@@ -4087,3 +4430,64 @@ func TestDefaultAndValidateReportTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestWebhookReporterValidation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		endpoints   []WebhookEndpoint
+		expectedErr bool
+	}{
+		{
+			name:      "valid endpoints are defaulted",
+			endpoints: []WebhookEndpoint{{Name: "a", URL: "https://a.example.com/events", Jobs: []string{"^unit"}}, {Name: "b", URL: "http://b", Mode: WebhookBinaryMode}},
+		},
+		{
+			name:        "endpoint without name",
+			endpoints:   []WebhookEndpoint{{URL: "http://a"}},
+			expectedErr: true,
+		},
+		{
+			name:        "duplicate name",
+			endpoints:   []WebhookEndpoint{{Name: "a", URL: "http://a"}, {Name: "a", URL: "http://b"}},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid url",
+			endpoints:   []WebhookEndpoint{{Name: "a", URL: "not a url"}},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid mode",
+			endpoints:   []WebhookEndpoint{{Name: "a", URL: "http://a", Mode: "batched"}},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid job regex",
+			endpoints:   []WebhookEndpoint{{Name: "a", URL: "http://a", Jobs: []string{"("}}},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid payload template",
+			endpoints:   []WebhookEndpoint{{Name: "a", URL: "http://a", PayloadTemplate: "{{.Undef}}"}},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := WebhookReporter{Endpoints: tc.endpoints}
+			err := cfg.DefaultAndValidate()
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %t, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			for _, e := range cfg.Endpoints {
+				if e.Mode == "" || e.PayloadContentType != "application/json" || len(e.JobRegexps) != len(e.Jobs) {
+					t.Errorf("expected endpoint %q to be defaulted, got %+v", e.Name, e)
+				}
+			}
+		})
+	}
+}
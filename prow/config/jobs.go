@@ -91,6 +91,9 @@ type JobBase struct {
 	Labels map[string]string `json:"labels,omitempty"`
 	// MaximumConcurrency of this job, 0 implies no limit.
 	MaxConcurrency int `json:"max_concurrency,omitempty"`
+	// Priority determines the order in which plank starts triggered jobs,
+	// jobs with a higher priority are started first. Defaults to 0.
+	Priority int `json:"priority,omitempty"`
 	// Agent that will take care of running this job. Defaults to "kubernetes"
 	Agent string `json:"agent,omitempty"`
 	// Cluster is the alias of the cluster to run this job in.
@@ -105,6 +108,9 @@ type JobBase struct {
 	// If this field is unspecified or false, a new pod will be created to replace
 	// the evicted one.
 	ErrorOnEviction bool `json:"error_on_eviction,omitempty"`
+	// Retry configures automatic retries of the job when its pod ends
+	// because of an infrastructure problem, e.g. an eviction or a lost node.
+	Retry *prowapi.RetryPolicy `json:"retry,omitempty"`
 	// SourcePath contains the path where this job is defined
 	SourcePath string `json:"-"`
 	// Spec is the Kubernetes pod spec used if Agent is kubernetes.
@@ -147,6 +153,10 @@ type Presubmit struct {
 	// (Default: `/test <job name>`)
 	RerunCommand string `json:"rerun_command,omitempty"`
 
+	// RunAfterSuccess lists the names of presubmits that have to succeed on
+	// the same commit before this job is started.
+	RunAfterSuccess []string `json:"run_after_success,omitempty"`
+
 	Brancher
 
 	RegexpChangeMatcher
@@ -163,6 +173,10 @@ type Presubmit struct {
 type Postsubmit struct {
 	JobBase
 
+	// RunAfterSuccess lists the names of postsubmits that have to succeed on
+	// the same commit before this job is started.
+	RunAfterSuccess []string `json:"run_after_success,omitempty"`
+
 	RegexpChangeMatcher
 
 	Brancher
@@ -66,12 +66,48 @@ type Repo struct {
 	AllowMergeCommit *bool   `json:"allow_merge_commit,omitempty"`
 	AllowRebaseMerge *bool   `json:"allow_rebase_merge,omitempty"`
 
+	DeleteBranchOnMerge *bool `json:"delete_branch_on_merge,omitempty"`
+
 	DefaultBranch *string `json:"default_branch,omitempty"`
 	Archived      *bool   `json:"archived,omitempty"`
 
 	Previously []string `json:"previously,omitempty"`
 
 	OnCreate *RepoCreateOptions `json:"on_create,omitempty"`
+
+	// VulnerabilityAlerts enables or disables alerts for vulnerable dependencies.
+	// https://developer.github.com/v3/repos/#enable-vulnerability-alerts
+	VulnerabilityAlerts *bool `json:"vulnerability_alerts,omitempty"`
+
+	// Topics replace the topics of the repo when set, and are left alone otherwise.
+	// https://developer.github.com/v3/repos/#replace-all-repository-topics
+	Topics []string `json:"topics,omitempty"`
+
+	// Collaborators maps the users given access to the repo directly, as
+	// opposed to through the org or a team, to their permission. Other direct
+	// collaborators are removed when set, and left alone otherwise.
+	// https://developer.github.com/v3/repos/collaborators/
+	Collaborators map[string]github.RepoPermissionLevel `json:"collaborators,omitempty"`
+
+	// Webhooks maps the payload URL of each webhook of the repo to its
+	// configuration. Other webhooks are deleted when set, and left alone otherwise.
+	// https://developer.github.com/v3/repos/hooks/
+	Webhooks map[string]Webhook `json:"webhooks,omitempty"`
+}
+
+// Webhook declares a repo webhook.
+//
+// See https://developer.github.com/v3/repos/hooks/#create-a-hook
+type Webhook struct {
+	// Events defaults to push.
+	Events []string `json:"events,omitempty"`
+	// ContentType is json or form, defaulting to form.
+	ContentType *string `json:"content_type,omitempty"`
+	// Active defaults to true.
+	Active *bool `json:"active,omitempty"`
+	// Secret names the file holding the webhook's HMAC secret. Secrets are never
+	// written to the config, only read from the --webhook-secret-dir directory.
+	Secret *string `json:"secret,omitempty"`
 }
 
 // Config declares org metadata as well as its people and teams.
@@ -166,9 +202,21 @@ func PruneRepoDefaults(repo Repo) Repo {
 	pruneBool(&repo.AllowRebaseMerge, true)
 	pruneBool(&repo.AllowSquashMerge, true)
 	pruneBool(&repo.AllowMergeCommit, true)
+	pruneBool(&repo.DeleteBranchOnMerge, false)
+	pruneBool(&repo.VulnerabilityAlerts, false)
 
 	pruneBool(&repo.Archived, false)
 	pruneString(&repo.DefaultBranch, "master")
 
+	if len(repo.Topics) == 0 {
+		repo.Topics = nil
+	}
+	if len(repo.Collaborators) == 0 {
+		repo.Collaborators = nil
+	}
+	if len(repo.Webhooks) == 0 {
+		repo.Webhooks = nil
+	}
+
 	return repo
 }
@@ -22,6 +22,8 @@ import (
 	"testing"
 
 	"k8s.io/apimachinery/pkg/util/diff"
+
+	"k8s.io/test-infra/prow/github"
 )
 
 func TestPrivacy(t *testing.T) {
@@ -89,6 +91,12 @@ func TestPruneRepoDefaults(t *testing.T) {
 				AllowRebaseMerge: &yes,
 				DefaultBranch:    &master,
 				Archived:         &no,
+
+				DeleteBranchOnMerge: &no,
+				VulnerabilityAlerts: &no,
+				Topics:              []string{},
+				Collaborators:       map[string]github.RepoPermissionLevel{},
+				Webhooks:            map[string]Webhook{},
 			},
 			expected: Repo{HasProjects: &yes},
 		},
@@ -106,6 +114,12 @@ func TestPruneRepoDefaults(t *testing.T) {
 				AllowRebaseMerge: &no,
 				DefaultBranch:    &notMaster,
 				Archived:         &yes,
+
+				DeleteBranchOnMerge: &yes,
+				VulnerabilityAlerts: &yes,
+				Topics:              []string{"testing"},
+				Collaborators:       map[string]github.RepoPermissionLevel{"alice": github.Write},
+				Webhooks:            map[string]Webhook{"https://example.com/hook": {}},
 			},
 			expected: Repo{Description: &nonEmpty,
 				HomePage:         &nonEmpty,
@@ -118,6 +132,12 @@ func TestPruneRepoDefaults(t *testing.T) {
 				AllowRebaseMerge: &no,
 				DefaultBranch:    &notMaster,
 				Archived:         &yes,
+
+				DeleteBranchOnMerge: &yes,
+				VulnerabilityAlerts: &yes,
+				Topics:              []string{"testing"},
+				Collaborators:       map[string]github.RepoPermissionLevel{"alice": github.Write},
+				Webhooks:            map[string]Webhook{"https://example.com/hook": {}},
 			},
 		},
 	}
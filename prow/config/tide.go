@@ -19,6 +19,8 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"text/template"
@@ -128,6 +130,12 @@ type Tide struct {
 	// Leave this blank to disable this feature.
 	MergeLabel string `json:"merge_label,omitempty"`
 
+	// CulpritLabel is an optional label that is added to the PRs found to
+	// break a batch when bisecting it. Add it to the missing labels of the
+	// queries to keep culprits out of the pool until the label is removed.
+	// Leave this blank to disable the bisection of failed batches.
+	CulpritLabel string `json:"culprit_label,omitempty"`
+
 	// MaxGoroutines is the maximum number of goroutines spawned inside the
 	// controller to handle org/repo:branch pools. Defaults to 20. Needs to be a
 	// positive number.
@@ -146,6 +154,42 @@ type Tide struct {
 	//  0 => unlimited batch size
 	// -1 => batch merging disabled :(
 	BatchSizeLimitMap map[string]int `json:"batch_size_limit,omitempty"`
+
+	// SpeculativeDepthMap is a key/value pair of an org, org/repo or
+	// org/repo:branch as the key and the number of batches to test ahead of
+	// the pending batch as the value. Each of them is tested on top of the
+	// batches before it as if they merged, so that it can merge right after
+	// them. The "*" key can be used as a global default. Speculation is
+	// disabled unless configured.
+	SpeculativeDepthMap map[string]int `json:"speculative_depth,omitempty"`
+
+	// Gerrit configures which Gerrit changes Tide submits when it runs
+	// against Gerrit instead of GitHub.
+	Gerrit TideGerrit `json:"gerrit,omitempty"`
+}
+
+// TideGerrit configures Tide for Gerrit.
+type TideGerrit struct {
+	// Queries select the changes that Tide submits once their required
+	// presubmits passed against the tip of their branch.
+	Queries []TideGerritQuery `json:"queries,omitempty"`
+}
+
+// Instances returns the Gerrit instances and the projects on them that the
+// queries select changes from.
+func (tg *TideGerrit) Instances() map[string][]string {
+	projects := map[string]sets.String{}
+	for _, query := range tg.Queries {
+		if projects[query.Instance] == nil {
+			projects[query.Instance] = sets.NewString()
+		}
+		projects[query.Instance].Insert(query.Projects...)
+	}
+	instances := make(map[string][]string, len(projects))
+	for instance, names := range projects {
+		instances[instance] = names.List()
+	}
+	return instances
 }
 
 func (t *Tide) BatchSizeLimit(repo OrgRepo) int {
@@ -158,6 +202,21 @@ func (t *Tide) BatchSizeLimit(repo OrgRepo) int {
 	return t.BatchSizeLimitMap["*"]
 }
 
+// SpeculativeDepth returns how many batches to test ahead of the pending
+// batch of the branch of a repo.
+func (t *Tide) SpeculativeDepth(repo OrgRepo, branch string) int {
+	if depth, ok := t.SpeculativeDepthMap[repo.String()+":"+branch]; ok {
+		return depth
+	}
+	if depth, ok := t.SpeculativeDepthMap[repo.String()]; ok {
+		return depth
+	}
+	if depth, ok := t.SpeculativeDepthMap[repo.Org]; ok {
+		return depth
+	}
+	return t.SpeculativeDepthMap["*"]
+}
+
 // MergeMethod returns the merge method to use for a repo. The default of merge is
 // returned when not overridden.
 func (t *Tide) MergeMethod(repo OrgRepo) github.PullRequestMergeType {
@@ -213,6 +272,69 @@ type TideQuery struct {
 	ReviewApprovedRequired bool `json:"reviewApprovedRequired,omitempty"`
 }
 
+// TideGerritQuery is turned into Gerrit search queries, one per project. See
+// https://gerrit-review.googlesource.com/Documentation/user-search.html
+type TideGerritQuery struct {
+	// Instance is the URL of the Gerrit instance, e.g.
+	// https://android-review.googlesource.com
+	Instance string   `json:"instance"`
+	Projects []string `json:"projects"`
+
+	ExcludedBranches []string `json:"excludedBranches,omitempty"`
+	IncludedBranches []string `json:"includedBranches,omitempty"`
+
+	// Labels maps the labels changes need to the minimal vote on them, e.g.
+	// Code-Review: 2 and Verified: 1.
+	Labels map[string]int `json:"labels,omitempty"`
+}
+
+// Query returns the Gerrit search string for the open changes of the project
+// that the tide query selects.
+func (tq *TideGerritQuery) Query(project string) string {
+	toks := []string{"status:open", fmt.Sprintf("project:\"%s\"", project)}
+	for _, b := range tq.ExcludedBranches {
+		toks = append(toks, fmt.Sprintf("-branch:\"%s\"", b))
+	}
+	var branches []string
+	for _, b := range tq.IncludedBranches {
+		branches = append(branches, fmt.Sprintf("branch:\"%s\"", b))
+	}
+	if len(branches) > 0 {
+		toks = append(toks, "("+strings.Join(branches, " OR ")+")")
+	}
+	var labels []string
+	for label := range tq.Labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, l := range labels {
+		toks = append(toks, fmt.Sprintf("label:%s>=%d", l, tq.Labels[l]))
+	}
+	return strings.Join(toks, " ")
+}
+
+// Validate returns an error if the query is not valid.
+func (tq *TideGerritQuery) Validate() error {
+	if tq.Instance == "" {
+		return errors.New("'instance' must be set")
+	}
+	if u, err := url.Parse(tq.Instance); err != nil || u.Host == "" {
+		return fmt.Errorf("instance %q is not a URL", tq.Instance)
+	}
+	if len(tq.Projects) == 0 {
+		return errors.New("'projects' cannot be empty")
+	}
+	if len(tq.ExcludedBranches) > 0 && len(tq.IncludedBranches) > 0 {
+		return errors.New("both 'includedBranches' and 'excludedBranches' are specified ('excludedBranches' have no effect)")
+	}
+	for label := range tq.Labels {
+		if label == "" || strings.ContainsAny(label, " :=<>") {
+			return fmt.Errorf("%q is not a valid label name", label)
+		}
+	}
+	return nil
+}
+
 // Query returns the corresponding github search string for the tide query.
 func (tq *TideQuery) Query() string {
 	toks := []string{"is:pr", "state:open"}
@@ -61,6 +61,125 @@ func TestTideQuery(t *testing.T) {
 	checkTok("review:approved")
 }
 
+func TestTideGerritQuery(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    TideGerritQuery
+		expected string
+	}{
+		{
+			name:     "only project",
+			query:    TideGerritQuery{Instance: "https://gerrit.example.com", Projects: []string{"a"}},
+			expected: `status:open project:"a"`,
+		},
+		{
+			name: "labels are sorted",
+			query: TideGerritQuery{
+				Instance: "https://gerrit.example.com",
+				Projects: []string{"a"},
+				Labels:   map[string]int{"Verified": 1, "Code-Review": 2},
+			},
+			expected: `status:open project:"a" label:Code-Review>=2 label:Verified>=1`,
+		},
+		{
+			name: "included branches",
+			query: TideGerritQuery{
+				Instance:         "https://gerrit.example.com",
+				Projects:         []string{"a"},
+				IncludedBranches: []string{"master", "release"},
+			},
+			expected: `status:open project:"a" (branch:"master" OR branch:"release")`,
+		},
+		{
+			name: "excluded branches",
+			query: TideGerritQuery{
+				Instance:         "https://gerrit.example.com",
+				Projects:         []string{"a"},
+				ExcludedBranches: []string{"dev"},
+			},
+			expected: `status:open project:"a" -branch:"dev"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.query.Query("a"); actual != tc.expected {
+				t.Errorf("expected query %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTideGerritQuery_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		query       TideGerritQuery
+		expectError bool
+	}{
+		{
+			name:  "valid query",
+			query: TideGerritQuery{Instance: "https://gerrit.example.com", Projects: []string{"a"}, Labels: map[string]int{"Code-Review": 2}},
+		},
+		{
+			name:        "no instance",
+			query:       TideGerritQuery{Projects: []string{"a"}},
+			expectError: true,
+		},
+		{
+			name:        "instance is no URL",
+			query:       TideGerritQuery{Instance: "gerrit", Projects: []string{"a"}},
+			expectError: true,
+		},
+		{
+			name:        "no projects",
+			query:       TideGerritQuery{Instance: "https://gerrit.example.com"},
+			expectError: true,
+		},
+		{
+			name: "included and excluded branches",
+			query: TideGerritQuery{
+				Instance:         "https://gerrit.example.com",
+				Projects:         []string{"a"},
+				IncludedBranches: []string{"master"},
+				ExcludedBranches: []string{"dev"},
+			},
+			expectError: true,
+		},
+		{
+			name:        "invalid label",
+			query:       TideGerritQuery{Instance: "https://gerrit.example.com", Projects: []string{"a"}, Labels: map[string]int{"Code Review": 2}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.query.Validate()
+			if err != nil && !tc.expectError {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err == nil && tc.expectError {
+				t.Error("expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestTideGerritInstances(t *testing.T) {
+	tg := TideGerrit{Queries: []TideGerritQuery{
+		{Instance: "https://a.example.com", Projects: []string{"b", "a"}},
+		{Instance: "https://a.example.com", Projects: []string{"a", "c"}},
+		{Instance: "https://b.example.com", Projects: []string{"a"}},
+	}}
+	expected := map[string][]string{
+		"https://a.example.com": {"a", "b", "c"},
+		"https://b.example.com": {"a"},
+	}
+	if actual := tg.Instances(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected instances %v, got %v", expected, actual)
+	}
+}
+
 func TestOrgExceptionsAndRepos(t *testing.T) {
 	queries := TideQueries{
 		{
@@ -154,6 +273,36 @@ func TestMergeMethod(t *testing.T) {
 		}
 	}
 }
+func TestSpeculativeDepth(t *testing.T) {
+	ti := &Tide{
+		SpeculativeDepthMap: map[string]int{
+			"*":                          1,
+			"kubernetes":                 2,
+			"kubernetes/kubernetes":      3,
+			"kubernetes/kubernetes:main": 0,
+		},
+	}
+
+	var testcases = []struct {
+		org      string
+		repo     string
+		branch   string
+		expected int
+	}{
+		{"helm", "charts", "master", 1},
+		{"kubernetes", "test-infra", "master", 2},
+		{"kubernetes", "kubernetes", "master", 3},
+		{"kubernetes", "kubernetes", "main", 0},
+	}
+
+	for _, test := range testcases {
+		actual := ti.SpeculativeDepth(OrgRepo{Org: test.org, Repo: test.repo}, test.branch)
+		if actual != test.expected {
+			t.Errorf("Expected speculative depth %d but got %d for %s/%s:%s", test.expected, actual, test.org, test.repo, test.branch)
+		}
+	}
+}
+
 func TestMergeTemplate(t *testing.T) {
 	ti := &Tide{
 		MergeTemplate: map[string]TideMergeCommitTemplate{
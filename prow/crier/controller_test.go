@@ -220,6 +220,26 @@ func TestController_Run(t *testing.T) {
 			},
 			shouldReport: true,
 		},
+		{
+			name:        "retried job is only reported once it finished",
+			jobsOnQueue: []string{"foo"},
+			knownJobs: map[string]*prowv1.ProwJob{
+				"foo": {
+					Spec: prowv1.ProwJobSpec{
+						Job:    "foo",
+						Report: true,
+					},
+					Status: prowv1.ProwJobStatus{
+						State: prowv1.PendingState,
+						PrevReportStates: map[string]prowv1.ProwJobState{
+							reporterName: prowv1.PendingState,
+						},
+						Attempts: []prowv1.ProwJobAttempt{{BuildID: "1", State: prowv1.ErrorState, Reason: "Evicted"}},
+					},
+				},
+			},
+			shouldReport: true,
+		},
 	}
 
 	for _, test := range tests {
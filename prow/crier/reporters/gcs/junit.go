@@ -0,0 +1,127 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
+
+	pkgio "k8s.io/test-infra/pkg/io"
+	"k8s.io/test-infra/pkg/io/providers"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/crier/reporters/gcs/internal/util"
+)
+
+// JUnitReader reads the JUnit results that jobs upload to their bucket.
+type JUnitReader struct {
+	cfg    config.Getter
+	opener pkgio.Opener
+}
+
+// NewJUnitReader returns a JUnitReader that reads through the opener.
+func NewJUnitReader(cfg config.Getter, opener pkgio.Opener) *JUnitReader {
+	return &JUnitReader{cfg: cfg, opener: opener}
+}
+
+// Failures returns the failed tests in the junit*.xml artifacts of the job.
+func (jr *JUnitReader) Failures(pj *prowv1.ProwJob) ([]junit.Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	bucket, dir, err := util.GetJobDestination(jr.cfg, pj)
+	if err != nil {
+		return nil, err
+	}
+	storageProvider, bucketName, err := providers.ParseBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("%s://%s/%s/", storageProvider, bucketName, path.Join(dir, "artifacts"))
+	names, err := jr.list(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %v", err)
+	}
+
+	var failures []junit.Result
+	var record func(suite junit.Suite)
+	record = func(suite junit.Suite) {
+		for _, result := range suite.Results {
+			if result.Failure != nil {
+				failures = append(failures, result)
+			}
+		}
+		for _, child := range suite.Suites {
+			record(child)
+		}
+	}
+	for _, name := range names {
+		base := path.Base(name)
+		if !strings.HasPrefix(base, "junit") || !strings.HasSuffix(base, ".xml") {
+			continue
+		}
+		content, err := jr.read(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", name, err)
+		}
+		suites, err := junit.Parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+		for _, suite := range suites.Suites {
+			record(suite)
+		}
+	}
+	return failures, nil
+}
+
+// list returns the paths of all objects below the prefix.
+func (jr *JUnitReader) list(ctx context.Context, prefix string) ([]string, error) {
+	it, err := jr.opener.Iterator(ctx, prefix, "")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for {
+		attrs, err := it.Next(ctx)
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !attrs.IsDir {
+			names = append(names, attrs.Name)
+		}
+	}
+}
+
+func (jr *JUnitReader) read(ctx context.Context, name string) ([]byte, error) {
+	r, err := jr.opener.Reader(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	pkgio "k8s.io/test-infra/pkg/io"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/crier/reporters/gcs/internal/testutil"
+)
+
+func TestJUnitReaderFailures(t *testing.T) {
+	cfg := testutil.Fca{C: config.Config{
+		ProwConfig: config.ProwConfig{
+			Plank: config.Plank{
+				DefaultDecorationConfigs: map[string]*prowv1.DecorationConfig{"*": {
+					GCSConfiguration: &prowv1.GCSConfiguration{
+						Bucket:       "mem://kubernetes-jenkins",
+						PathStrategy: prowv1.PathStrategyExplicit,
+					},
+				}},
+			},
+		},
+	}}.Config
+	pj := &prowv1.ProwJob{
+		Spec: prowv1.ProwJobSpec{
+			Type: prowv1.PostsubmitJob,
+			Refs: &prowv1.Refs{Org: "kubernetes", Repo: "test-infra"},
+			Job:  "my-little-job",
+		},
+		Status: prowv1.ProwJobStatus{BuildID: "123"},
+	}
+	// mem:// behaves like the other providers but doesn't need credentials
+	opener, err := pkgio.NewOpenerFromGCSClient(nil, "")
+	if err != nil {
+		t.Fatalf("failed to create opener: %v", err)
+	}
+	dir := "mem://kubernetes-jenkins/logs/my-little-job/123/artifacts/"
+	for name, content := range map[string]string{
+		dir + "junit_01.xml": `<testsuites><testsuite name="suite">
+			<testcase name="TestPass"></testcase>
+			<testcase name="TestFail"><failure>foo_test.go:12: broken</failure></testcase>
+			<testsuite name="nested"><testcase name="TestNested"><failure>nested</failure></testcase></testsuite>
+		</testsuite></testsuites>`,
+		dir + "e2e/junit_runner.xml": `<testsuite name="e2e">
+			<testcase name="TestE2E"><failure>e2e</failure></testcase>
+		</testsuite>`,
+		dir + "build-log.txt": "not junit",
+	} {
+		w, err := opener.Writer(context.Background(), name)
+		if err != nil {
+			t.Fatalf("failed to open writer for %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("failed to close %s: %v", name, err)
+		}
+	}
+	reader := NewJUnitReader(cfg, opener)
+
+	results, err := reader.Failures(pj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	if diff := cmp.Diff([]string{"TestE2E", "TestFail", "TestNested"}, names); diff != "" {
+		t.Errorf("unexpected failures (-want +got):\n%s", diff)
+	}
+}
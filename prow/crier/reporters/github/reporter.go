@@ -24,6 +24,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
 	"github.com/sirupsen/logrus"
 
 	v1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
@@ -37,11 +38,18 @@ const (
 	GitHubReporterName = "github-reporter"
 )
 
+// JUnitReader reads the JUnit results of completed jobs.
+type JUnitReader interface {
+	// Failures returns the failed tests of the job.
+	Failures(pj *v1.ProwJob) ([]junit.Result, error)
+}
+
 // Client is a github reporter client
 type Client struct {
-	gc          report.GitHubClient
+	gc          report.CheckRunClient
 	config      config.Getter
 	reportAgent v1.ProwJobAgent
+	junitReader JUnitReader
 	prLocks     *shardedLock
 }
 
@@ -95,12 +103,14 @@ func (s *shardedLock) runCleanup() {
 	}()
 }
 
-// NewReporter returns a reporter client
-func NewReporter(gc report.GitHubClient, cfg config.Getter, reportAgent v1.ProwJobAgent) *Client {
+// NewReporter returns a reporter client. The junitReader is optional and
+// used to annotate check runs with the failed tests of jobs.
+func NewReporter(gc report.CheckRunClient, cfg config.Getter, reportAgent v1.ProwJobAgent, junitReader JUnitReader) *Client {
 	c := &Client{
 		gc:          gc,
 		config:      cfg,
 		reportAgent: reportAgent,
+		junitReader: junitReader,
 		prLocks: &shardedLock{
 			mapLock: &sync.Mutex{},
 			locks:   map[simplePull]*sync.Mutex{},
@@ -147,7 +157,45 @@ func (c *Client) Report(pj *v1.ProwJob) ([]*v1.ProwJob, error) {
 	}
 
 	// TODO(krzyzacy): ditch ReportTemplate, and we can drop reference to config.Getter
-	return []*v1.ProwJob{pj}, report.Report(c.gc, c.config().Plank.ReportTemplateForRepo(pj.Spec.Refs), *pj, c.config().GitHubReporter.JobTypesToReport)
+	cfg := c.config()
+	reportTemplate := cfg.Plank.ReportTemplateForRepo(pj.Spec.Refs)
+	if c.reportsCheckRun(cfg, pj) {
+		return []*v1.ProwJob{pj}, report.ReportCheckRun(c.gc, reportTemplate, *pj, cfg.GitHubReporter.JobTypesToReport, c.failures)
+	}
+	return []*v1.ProwJob{pj}, report.Report(c.gc, reportTemplate, *pj, cfg.GitHubReporter.JobTypesToReport)
+}
+
+// reportsCheckRun determines whether the job is reported as a check run
+// instead of a commit status.
+func (c *Client) reportsCheckRun(cfg *config.Config, pj *v1.ProwJob) bool {
+	if rc := pj.Spec.ReporterConfig; rc != nil && rc.GitHub != nil && rc.GitHub.CheckRun != nil {
+		return *rc.GitHub.CheckRun
+	}
+	if pj.Spec.Refs == nil {
+		return false
+	}
+	return cfg.GitHubReporter.ReportsCheckRuns(pj.Spec.Refs.Org, pj.Spec.Refs.Repo)
+}
+
+// failures returns the failed tests of the job, if the reporter can read
+// JUnit results.
+func (c *Client) failures(pj v1.ProwJob) ([]report.TestFailure, error) {
+	if c.junitReader == nil {
+		return nil, nil
+	}
+	results, err := c.junitReader.Failures(&pj)
+	if err != nil {
+		return nil, err
+	}
+	var failures []report.TestFailure
+	for _, result := range results {
+		name := result.Name
+		if result.ClassName != "" {
+			name = result.ClassName + "." + name
+		}
+		failures = append(failures, report.TestFailure{Name: name, Message: result.Message(0)})
+	}
+	return failures, nil
 }
 
 func lockKeyForPJ(pj *v1.ProwJob) (*simplePull, error) {
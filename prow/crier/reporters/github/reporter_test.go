@@ -17,15 +17,18 @@ limitations under the License.
 package github
 
 import (
+	"reflect"
 	"sync"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	v1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/gerrit/client"
 	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/github/report"
 )
 
 func TestShouldReport(t *testing.T) {
@@ -93,7 +96,7 @@ func TestShouldReport(t *testing.T) {
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			c := NewReporter(nil, nil, tc.reportAgent)
+			c := NewReporter(nil, nil, tc.reportAgent, nil)
 			if r := c.ShouldReport(&tc.pj); r == tc.report {
 				return
 			}
@@ -123,6 +126,7 @@ func TestPresumitReportingLocks(t *testing.T) {
 			}
 		},
 		v1.ProwJobAgent(""),
+		nil,
 	)
 
 	pj := &v1.ProwJob{
@@ -159,6 +163,98 @@ func TestPresumitReportingLocks(t *testing.T) {
 	wg.Wait()
 }
 
+type fakeJUnitReader []junit.Result
+
+func (f fakeJUnitReader) Failures(pj *v1.ProwJob) ([]junit.Result, error) {
+	return f, nil
+}
+
+func TestReportCheckRuns(t *testing.T) {
+	yes, no := true, false
+	var testcases = []struct {
+		name           string
+		repo           string
+		reporterConfig *v1.ReporterConfig
+		checkRun       bool
+	}{
+		{
+			name:     "repo is reported with check runs",
+			repo:     "checked",
+			checkRun: true,
+		},
+		{
+			name: "other repo is reported with statuses",
+			repo: "other",
+		},
+		{
+			name:           "job opts into check runs",
+			repo:           "other",
+			reporterConfig: &v1.ReporterConfig{GitHub: &v1.GitHubReporterConfig{CheckRun: &yes}},
+			checkRun:       true,
+		},
+		{
+			name:           "job opts out of check runs",
+			repo:           "checked",
+			reporterConfig: &v1.ReporterConfig{GitHub: &v1.GitHubReporterConfig{CheckRun: &no}},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ghc := &fakegithub.FakeClient{}
+			reporter := NewReporter(
+				ghc,
+				func() *config.Config {
+					return &config.Config{
+						ProwConfig: config.ProwConfig{
+							GitHubReporter: config.GitHubReporter{
+								JobTypesToReport: []v1.ProwJobType{v1.PostsubmitJob},
+								CheckRunRepos:    []string{"org/checked"},
+							},
+						},
+					}
+				},
+				v1.ProwJobAgent(""),
+				nil,
+			)
+			pj := &v1.ProwJob{
+				Spec: v1.ProwJobSpec{
+					Type:           v1.PostsubmitJob,
+					Context:        "job",
+					Report:         true,
+					Refs:           &v1.Refs{Org: "org", Repo: tc.repo, BaseSHA: "abcdef"},
+					ReporterConfig: tc.reporterConfig,
+				},
+				Status: v1.ProwJobStatus{State: v1.PendingState},
+			}
+			if _, err := reporter.Report(pj); err != nil {
+				t.Fatalf("error reporting: %v", err)
+			}
+			if checkRun := len(ghc.CheckRuns["abcdef"]) == 1; checkRun != tc.checkRun {
+				t.Errorf("expected check run %t, got check runs %v and statuses %v", tc.checkRun, ghc.CheckRuns, ghc.CreatedStatuses)
+			}
+			if status := len(ghc.CreatedStatuses["abcdef"]) == 1; status == tc.checkRun {
+				t.Errorf("expected status %t, got statuses %v", !tc.checkRun, ghc.CreatedStatuses)
+			}
+		})
+	}
+}
+
+func TestFailures(t *testing.T) {
+	failure := "foo_test.go:12: expected 1"
+	reporter := NewReporter(nil, nil, v1.ProwJobAgent(""), fakeJUnitReader{
+		{Name: "TestFoo", ClassName: "foo", Failure: &failure},
+	})
+	failures, err := reporter.failures(v1.ProwJob{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []report.TestFailure{{Name: "foo.TestFoo", Message: failure}}
+	if !reflect.DeepEqual(failures, expected) {
+		t.Errorf("expected %v, got %v", expected, failures)
+	}
+}
+
 func TestShardedLockCleanup(t *testing.T) {
 	t.Parallel()
 	sl := &shardedLock{mapLock: &sync.Mutex{}, locks: map[simplePull]*sync.Mutex{}}
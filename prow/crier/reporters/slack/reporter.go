@@ -20,7 +20,11 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
 	"text/template"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -32,11 +36,49 @@ import (
 
 const reporterName = "slackreporter"
 
+// threadTTL is how long threads are kept after their last report. Reports
+// for a ref after that start a new thread.
+const threadTTL = 24 * time.Hour
+
+type slackClient interface {
+	WriteMessage(text, channel string) error
+	PostMessage(channel, text, threadTimestamp string) (*slackclient.MessageRef, error)
+	UpdateMessage(message slackclient.MessageRef, text string) error
+}
+
 type slackReporter struct {
-	client *slackclient.Client
+	client slackClient
 	config func(*prowapi.Refs) config.SlackReporter
 	logger *logrus.Entry
 	dryRun bool
+	now    func() time.Time
+
+	lock sync.Mutex
+	// threads are the threads of refs and periodic runs, by thread key.
+	threads map[string]*thread
+	// digests are the failures to send in the next digest, by channel.
+	digests map[string]*digest
+}
+
+// thread is the parent message of the reports of a ref or periodic run.
+type thread struct {
+	// updated is guarded by the lock of the reporter.
+	updated time.Time
+
+	// lock serializes the reports of the thread, so that they do not race
+	// to start it, without holding up the reports of other threads.
+	lock sync.Mutex
+	// parent is nil until the thread is started.
+	parent *slackclient.MessageRef
+	// replies are the reports of the jobs in the thread, by job name.
+	replies map[string]slackclient.MessageRef
+}
+
+// digest accumulates the failed jobs of a channel between digests.
+type digest struct {
+	interval time.Duration
+	last     time.Time
+	failures []string
 }
 
 func channel(cfg config.SlackReporter, pj *v1.ProwJob) string {
@@ -46,33 +88,192 @@ func channel(cfg config.SlackReporter, pj *v1.ProwJob) string {
 	return cfg.Channel
 }
 
+func failed(pj *v1.ProwJob) bool {
+	return pj.Status.State == v1.FailureState || pj.Status.State == v1.ErrorState
+}
+
+// mentions returns the Slack mentions of the owners of the job if it failed.
+func mentions(cfg config.SlackReporter, pj *v1.ProwJob) string {
+	if !failed(pj) || len(cfg.UserMapping) == 0 {
+		return ""
+	}
+	var owners []string
+	if pj.Spec.ReporterConfig != nil && pj.Spec.ReporterConfig.Slack != nil {
+		owners = append(owners, pj.Spec.ReporterConfig.Slack.Owners...)
+	}
+	if pj.Spec.Refs != nil {
+		for _, pull := range pj.Spec.Refs.Pulls {
+			owners = append(owners, pull.Author)
+		}
+	}
+	ids := map[string]bool{}
+	for _, owner := range owners {
+		if id, ok := cfg.UserMapping[owner]; ok {
+			ids[id] = true
+		}
+	}
+	var mentions []string
+	for id := range ids {
+		mentions = append(mentions, fmt.Sprintf("<@%s>", id))
+	}
+	sort.Strings(mentions)
+	return strings.Join(mentions, " ")
+}
+
+// threadKey identifies the thread of the job: the thread of its refs, or of
+// its run for periodics.
+func threadKey(channel string, pj *v1.ProwJob) string {
+	refs := pj.Spec.Refs
+	if pj.Spec.Type == v1.PeriodicJob || refs == nil {
+		return fmt.Sprintf("%s:%s", channel, pj.Name)
+	}
+	key := fmt.Sprintf("%s:%s/%s@%s:%s", channel, refs.Org, refs.Repo, refs.BaseRef, refs.BaseSHA)
+	for _, pull := range refs.Pulls {
+		key += fmt.Sprintf(",%d:%s", pull.Number, pull.SHA)
+	}
+	return key
+}
+
+func render(text string, pj *v1.ProwJob) (string, error) {
+	tmpl, err := template.New("").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %v", err)
+	}
+	b := &bytes.Buffer{}
+	if err := tmpl.Execute(b, pj); err != nil {
+		return "", fmt.Errorf("failed to execute template: %v", err)
+	}
+	return b.String(), nil
+}
+
 func (sr *slackReporter) Report(pj *v1.ProwJob) ([]*v1.ProwJob, error) {
 	config := sr.config(pj.Spec.Refs)
 	channel := channel(config, pj)
-	b := &bytes.Buffer{}
-	tmpl, err := template.New("").Parse(config.ReportTemplate)
+	text, err := render(config.ReportTemplate, pj)
 	if err != nil {
-		sr.logger.WithField("prowjob", pj.Name).Errorf("failed to parse template: %v", err)
-		return nil, fmt.Errorf("failed to parse template: %v", err)
+		sr.logger.WithField("prowjob", pj.Name).WithError(err).Error("failed to render report template")
+		return nil, fmt.Errorf("failed to render report template: %v", err)
 	}
-	if err := tmpl.Execute(b, pj); err != nil {
-		sr.logger.WithField("prowjob", pj.Name).WithError(err).Error("failed to execute report template")
-		return nil, fmt.Errorf("failed to execute report template: %v", err)
+	if m := mentions(config, pj); m != "" {
+		text += " " + m
 	}
 	if sr.dryRun {
 		sr.logger.
 			WithField("prowjob", pj.Name).
-			WithField("messagetext", b.String()).
+			WithField("messagetext", text).
 			Debug("Skipping reporting because dry-run is enabled")
+		sr.addToDigest(config, pj)
 		return []*v1.ProwJob{pj}, nil
 	}
-	if err := sr.client.WriteMessage(b.String(), channel); err != nil {
+	if config.Threaded {
+		err = sr.reportInThread(config, channel, pj, text)
+	} else {
+		err = sr.client.WriteMessage(text, channel)
+	}
+	if err != nil {
 		sr.logger.WithError(err).Error("failed to write Slack message")
 		return nil, fmt.Errorf("failed to write Slack message: %v", err)
 	}
+	sr.addToDigest(config, pj)
 	return []*v1.ProwJob{pj}, nil
 }
 
+// reportInThread reports the job as a reply in the thread of its refs or
+// run, starting the thread if needed. The reply of the job is edited instead
+// if the config says so.
+func (sr *slackReporter) reportInThread(cfg config.SlackReporter, channel string, pj *v1.ProwJob, text string) error {
+	key := threadKey(channel, pj)
+	sr.lock.Lock()
+	now := sr.now()
+	for key, thread := range sr.threads {
+		if now.Sub(thread.updated) > threadTTL {
+			delete(sr.threads, key)
+		}
+	}
+	t, ok := sr.threads[key]
+	if !ok {
+		t = &thread{replies: map[string]slackclient.MessageRef{}}
+		sr.threads[key] = t
+	}
+	t.updated = now
+	sr.lock.Unlock()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.parent == nil {
+		parent, err := render(cfg.ParentTemplate, pj)
+		if err != nil {
+			return fmt.Errorf("failed to render parent template: %v", err)
+		}
+		message, err := sr.client.PostMessage(channel, parent, "")
+		if err != nil {
+			return err
+		}
+		t.parent = message
+	}
+
+	if reply, ok := t.replies[pj.Name]; ok && cfg.EditMessages {
+		return sr.client.UpdateMessage(reply, text)
+	}
+	reply, err := sr.client.PostMessage(t.parent.Channel, text, t.parent.Timestamp)
+	if err != nil {
+		return err
+	}
+	t.replies[pj.Name] = *reply
+	return nil
+}
+
+// addToDigest records the job for the next digest if it failed.
+func (sr *slackReporter) addToDigest(cfg config.SlackReporter, pj *v1.ProwJob) {
+	if cfg.DigestInterval == nil || !failed(pj) {
+		return
+	}
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	d, ok := sr.digests[cfg.DigestChannel]
+	if !ok {
+		d = &digest{last: sr.now()}
+		sr.digests[cfg.DigestChannel] = d
+	}
+	d.interval = cfg.DigestInterval.Duration
+	d.failures = append(d.failures, fmt.Sprintf("• <%s|%s> ended with state %s.", pj.Status.URL, pj.Spec.Job, pj.Status.State))
+}
+
+// SendDigests sends the digests of the failed jobs that are due. The lock is
+// not held while writing to Slack, failures added meanwhile are kept for
+// the next digest.
+func (sr *slackReporter) SendDigests() {
+	type due struct {
+		digest *digest
+		text   string
+		sent   int
+	}
+	sr.lock.Lock()
+	now := sr.now()
+	dues := map[string]due{}
+	for channel, d := range sr.digests {
+		if len(d.failures) == 0 || now.Sub(d.last) < d.interval {
+			continue
+		}
+		text := fmt.Sprintf("%d jobs failed in the last %s:\n%s", len(d.failures), now.Sub(d.last).Round(time.Minute), strings.Join(d.failures, "\n"))
+		dues[channel] = due{digest: d, text: text, sent: len(d.failures)}
+	}
+	sr.lock.Unlock()
+
+	for channel, due := range dues {
+		if sr.dryRun {
+			sr.logger.WithField("messagetext", due.text).Debug("Skipping digest because dry-run is enabled")
+		} else if err := sr.client.WriteMessage(due.text, channel); err != nil {
+			sr.logger.WithError(err).WithField("channel", channel).Error("failed to write Slack digest")
+			continue
+		}
+		sr.lock.Lock()
+		due.digest.last = now
+		due.digest.failures = due.digest.failures[due.sent:]
+		sr.lock.Unlock()
+	}
+}
+
 func (sr *slackReporter) GetName() string {
 	return reporterName
 }
@@ -108,9 +309,12 @@ func New(cfg func(refs *prowapi.Refs) config.SlackReporter, dryRun bool, tokenFi
 	}
 
 	return &slackReporter{
-		client: slackclient.NewClient(func() []byte { return token }),
-		config: cfg,
-		logger: logrus.WithField("component", reporterName),
-		dryRun: dryRun,
+		client:  slackclient.NewClient(func() []byte { return token }),
+		config:  cfg,
+		logger:  logrus.WithField("component", reporterName),
+		dryRun:  dryRun,
+		now:     time.Now,
+		threads: map[string]*thread{},
+		digests: map[string]*digest{},
 	}, nil
 }
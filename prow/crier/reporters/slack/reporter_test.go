@@ -17,11 +17,18 @@ limitations under the License.
 package slack
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
+	slackclient "k8s.io/test-infra/prow/slack"
 )
 
 func TestShouldReport(t *testing.T) {
@@ -338,3 +345,300 @@ func TestUsesChannelOverrideFromJob(t *testing.T) {
 		})
 	}
 }
+
+// fakeSlackClient records the messages it is sent, numbering the posted
+// messages from 1. Channel IDs are the channel names prefixed with C.
+type fakeSlackClient struct {
+	messages []string
+}
+
+func (f *fakeSlackClient) WriteMessage(text, channel string) error {
+	f.messages = append(f.messages, fmt.Sprintf("write %s: %s", channel, text))
+	return nil
+}
+
+func (f *fakeSlackClient) PostMessage(channel, text, threadTimestamp string) (*slackclient.MessageRef, error) {
+	f.messages = append(f.messages, fmt.Sprintf("post %s/%s: %s", channel, threadTimestamp, text))
+	if !strings.HasPrefix(channel, "C") {
+		channel = "C" + channel
+	}
+	return &slackclient.MessageRef{Channel: channel, Timestamp: fmt.Sprint(len(f.messages))}, nil
+}
+
+func (f *fakeSlackClient) UpdateMessage(message slackclient.MessageRef, text string) error {
+	f.messages = append(f.messages, fmt.Sprintf("update %s/%s: %s", message.Channel, message.Timestamp, text))
+	return nil
+}
+
+func newTestReporter(cfg config.SlackReporter) (*slackReporter, *fakeSlackClient) {
+	if err := cfg.DefaultAndValidate(); err != nil {
+		panic(err)
+	}
+	client := &fakeSlackClient{}
+	return &slackReporter{
+		client:  client,
+		config:  func(*v1.Refs) config.SlackReporter { return cfg },
+		logger:  logrus.NewEntry(&logrus.Logger{}),
+		now:     time.Now,
+		threads: map[string]*thread{},
+		digests: map[string]*digest{},
+	}, client
+}
+
+func testJob(name string, jobType v1.ProwJobType, state v1.ProwJobState, refs *v1.Refs) *v1.ProwJob {
+	return &v1.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v1.ProwJobSpec{Job: name, Type: jobType, Refs: refs},
+		Status:     v1.ProwJobStatus{State: state, URL: "https://prow/" + name},
+	}
+}
+
+func TestReportInThread(t *testing.T) {
+	pr := &v1.Refs{Org: "o", Repo: "r", BaseRef: "master", BaseSHA: "base", Pulls: []v1.Pull{{Number: 1, SHA: "head", Author: "alice"}}}
+	otherPR := &v1.Refs{Org: "o", Repo: "r", BaseRef: "master", BaseSHA: "base", Pulls: []v1.Pull{{Number: 2, SHA: "head"}}}
+	testCases := []struct {
+		name   string
+		edit   bool
+		jobs   []*v1.ProwJob
+		expect []string
+	}{
+		{
+			name: "jobs of a ref reply in the same thread",
+			jobs: []*v1.ProwJob{
+				testJob("unit", v1.PresubmitJob, v1.PendingState, pr),
+				testJob("e2e", v1.PresubmitJob, v1.PendingState, pr),
+				testJob("unit", v1.PresubmitJob, v1.SuccessState, pr),
+				testJob("other", v1.PresubmitJob, v1.PendingState, otherPR),
+			},
+			expect: []string{
+				"post channel/: Jobs for o/r #1 on master",
+				"post Cchannel/1: unit pending",
+				"post Cchannel/1: e2e pending",
+				"post Cchannel/1: unit success",
+				"post channel/: Jobs for o/r #2 on master",
+				"post Cchannel/5: other pending",
+			},
+		},
+		{
+			name: "state transitions edit the reply of the job",
+			edit: true,
+			jobs: []*v1.ProwJob{
+				testJob("unit", v1.PresubmitJob, v1.PendingState, pr),
+				testJob("e2e", v1.PresubmitJob, v1.PendingState, pr),
+				testJob("unit", v1.PresubmitJob, v1.SuccessState, pr),
+			},
+			expect: []string{
+				"post channel/: Jobs for o/r #1 on master",
+				"post Cchannel/1: unit pending",
+				"post Cchannel/1: e2e pending",
+				"update Cchannel/2: unit success",
+			},
+		},
+		{
+			name: "periodic runs have their own threads",
+			jobs: []*v1.ProwJob{
+				testJob("nightly", v1.PeriodicJob, v1.PendingState, nil),
+				testJob("nightly", v1.PeriodicJob, v1.FailureState, nil),
+				testJob("nightly-2", v1.PeriodicJob, v1.PendingState, nil),
+			},
+			expect: []string{
+				"post channel/: Periodic job nightly",
+				"post Cchannel/1: nightly pending",
+				"post Cchannel/1: nightly failure",
+				"post channel/: Periodic job nightly-2",
+				"post Cchannel/4: nightly-2 pending",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reporter, client := newTestReporter(config.SlackReporter{
+				Channel:        "channel",
+				ReportTemplate: "{{.Spec.Job}} {{.Status.State}}",
+				Threaded:       true,
+				EditMessages:   tc.edit,
+			})
+			for _, pj := range tc.jobs {
+				if _, err := reporter.Report(pj); err != nil {
+					t.Fatalf("failed to report: %v", err)
+				}
+			}
+			if !reflect.DeepEqual(client.messages, tc.expect) {
+				t.Errorf("expected messages %q, got %q", tc.expect, client.messages)
+			}
+		})
+	}
+}
+
+func TestThreadsExpire(t *testing.T) {
+	reporter, client := newTestReporter(config.SlackReporter{
+		Channel:        "channel",
+		ReportTemplate: "{{.Spec.Job}} {{.Status.State}}",
+		Threaded:       true,
+	})
+	now := time.Now()
+	reporter.now = func() time.Time { return now }
+	pr := &v1.Refs{Org: "o", Repo: "r", BaseRef: "master", Pulls: []v1.Pull{{Number: 1}}}
+	if _, err := reporter.Report(testJob("unit", v1.PresubmitJob, v1.PendingState, pr)); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	now = now.Add(threadTTL + time.Minute)
+	if _, err := reporter.Report(testJob("unit", v1.PresubmitJob, v1.SuccessState, pr)); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	expected := []string{
+		"post channel/: Jobs for o/r #1 on master",
+		"post Cchannel/1: unit pending",
+		"post channel/: Jobs for o/r #1 on master",
+		"post Cchannel/3: unit success",
+	}
+	if !reflect.DeepEqual(client.messages, expected) {
+		t.Errorf("expected messages %q, got %q", expected, client.messages)
+	}
+}
+
+// blockingSlackClient holds up the messages posted to the blocked channel
+// until it is released.
+type blockingSlackClient struct {
+	fakeSlackClient
+	lock    sync.Mutex
+	blocked string
+	waiting chan struct{}
+	release chan struct{}
+}
+
+func (f *blockingSlackClient) PostMessage(channel, text, threadTimestamp string) (*slackclient.MessageRef, error) {
+	if channel == f.blocked {
+		f.waiting <- struct{}{}
+		<-f.release
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.fakeSlackClient.PostMessage(channel, text, threadTimestamp)
+}
+
+func TestThreadsDoNotHoldUpEachOther(t *testing.T) {
+	reporter, _ := newTestReporter(config.SlackReporter{
+		Channel:        "channel",
+		ReportTemplate: "{{.Spec.Job}} {{.Status.State}}",
+		Threaded:       true,
+	})
+	client := &blockingSlackClient{blocked: "slow", waiting: make(chan struct{}), release: make(chan struct{})}
+	reporter.client = client
+
+	slow := testJob("slow", v1.PeriodicJob, v1.PendingState, nil)
+	slow.Spec.ReporterConfig = &v1.ReporterConfig{Slack: &v1.SlackReporterConfig{Channel: "slow"}}
+	slowDone := make(chan error)
+	go func() {
+		_, err := reporter.Report(slow)
+		slowDone <- err
+	}()
+	<-client.waiting
+
+	fastDone := make(chan error)
+	go func() {
+		_, err := reporter.Report(testJob("fast", v1.PeriodicJob, v1.PendingState, nil))
+		fastDone <- err
+	}()
+	select {
+	case err := <-fastDone:
+		if err != nil {
+			t.Errorf("failed to report: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("expected the report of another thread not to wait for Slack")
+	}
+
+	close(client.release)
+	if err := <-slowDone; err != nil {
+		t.Errorf("failed to report: %v", err)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	mapping := map[string]string{"alice": "U1", "bob": "U2", "carol": "U3"}
+	testCases := []struct {
+		name     string
+		pj       *v1.ProwJob
+		expected string
+	}{
+		{
+			name:     "authors of failed presubmits are mentioned",
+			pj:       testJob("unit", v1.PresubmitJob, v1.FailureState, &v1.Refs{Pulls: []v1.Pull{{Author: "bob"}, {Author: "unknown"}}}),
+			expected: "unit failure <@U2>",
+		},
+		{
+			name:     "successful jobs do not mention anybody",
+			pj:       testJob("unit", v1.PresubmitJob, v1.SuccessState, &v1.Refs{Pulls: []v1.Pull{{Author: "bob"}}}),
+			expected: "unit success",
+		},
+		{
+			name: "owners of the job are mentioned once",
+			pj: func() *v1.ProwJob {
+				pj := testJob("nightly", v1.PeriodicJob, v1.ErrorState, &v1.Refs{Pulls: []v1.Pull{{Author: "carol"}}})
+				pj.Spec.ReporterConfig = &v1.ReporterConfig{Slack: &v1.SlackReporterConfig{Owners: []string{"carol", "alice"}}}
+				return pj
+			}(),
+			expected: "nightly error <@U1> <@U3>",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reporter, client := newTestReporter(config.SlackReporter{
+				Channel:        "channel",
+				ReportTemplate: "{{.Spec.Job}} {{.Status.State}}",
+				UserMapping:    mapping,
+			})
+			if _, err := reporter.Report(tc.pj); err != nil {
+				t.Fatalf("failed to report: %v", err)
+			}
+			if expected := []string{"write channel: " + tc.expected}; !reflect.DeepEqual(client.messages, expected) {
+				t.Errorf("expected messages %q, got %q", expected, client.messages)
+			}
+		})
+	}
+}
+
+func TestSendDigests(t *testing.T) {
+	reporter, client := newTestReporter(config.SlackReporter{
+		Channel:        "channel",
+		ReportTemplate: "{{.Spec.Job}} {{.Status.State}}",
+		DigestInterval: &metav1.Duration{Duration: time.Hour},
+		DigestChannel:  "digests",
+	})
+	now := time.Now()
+	reporter.now = func() time.Time { return now }
+	for _, pj := range []*v1.ProwJob{
+		testJob("unit", v1.PostsubmitJob, v1.FailureState, nil),
+		testJob("lint", v1.PostsubmitJob, v1.SuccessState, nil),
+		testJob("e2e", v1.PostsubmitJob, v1.ErrorState, nil),
+	} {
+		if _, err := reporter.Report(pj); err != nil {
+			t.Fatalf("failed to report: %v", err)
+		}
+	}
+	client.messages = nil
+
+	now = now.Add(30 * time.Minute)
+	reporter.SendDigests()
+	if len(client.messages) != 0 {
+		t.Errorf("expected no digest before the interval, got %q", client.messages)
+	}
+
+	now = now.Add(30 * time.Minute)
+	reporter.SendDigests()
+	expected := []string{"write digests: 2 jobs failed in the last 1h0m0s:\n" +
+		"• <https://prow/unit|unit> ended with state failure.\n" +
+		"• <https://prow/e2e|e2e> ended with state error."}
+	if !reflect.DeepEqual(client.messages, expected) {
+		t.Errorf("expected messages %q, got %q", expected, client.messages)
+	}
+
+	now = now.Add(2 * time.Hour)
+	reporter.SendDigests()
+	if len(client.messages) != 1 {
+		t.Errorf("expected no digest without failures, got %q", client.messages)
+	}
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook reports the state changes of ProwJobs to HTTP endpoints
+// as CloudEvents.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/httpretry"
+)
+
+const (
+	reporterName = "webhookreporter"
+
+	// SignatureHeader holds the HMAC-SHA256 of the body, as sha256=<hex>.
+	SignatureHeader = "X-Prow-Signature"
+
+	// EventType is the CloudEvents type of the events.
+	EventType = "io.k8s.prow.job.state"
+	// EventSource is the CloudEvents source of the events.
+	EventSource = "/prow/crier"
+
+	structuredContentType = "application/cloudevents+json"
+
+	// deliveriesTTL is how long the endpoints an event was delivered to are
+	// remembered while some other endpoint keeps failing.
+	deliveriesTTL = time.Hour
+)
+
+// backoff is a variable for mocking out the backoff in unit tests.
+var backoff = httpretry.DefaultBackoff
+
+// JobEvent is the default data of the events, summarizing the job.
+type JobEvent struct {
+	Name           string                `json:"name"`
+	Job            string                `json:"job"`
+	Type           prowapi.ProwJobType   `json:"type"`
+	State          prowapi.ProwJobState  `json:"state"`
+	Description    string                `json:"description,omitempty"`
+	URL            string                `json:"url,omitempty"`
+	Refs           *prowapi.Refs         `json:"refs,omitempty"`
+	ExtraRefs      []prowapi.Refs        `json:"extra_refs,omitempty"`
+	StartTime      time.Time             `json:"start_time"`
+	CompletionTime *time.Time            `json:"completion_time,omitempty"`
+	Labels         map[string]string     `json:"labels,omitempty"`
+	PrevState      *prowapi.ProwJobState `json:"prev_state,omitempty"`
+}
+
+// CloudEvent is a CloudEvent 1.0 in structured mode.
+type CloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype,omitempty"`
+	Data            interface{} `json:"data,omitempty"`
+}
+
+// Client is a reporter client fed to crier controller
+type Client struct {
+	config     func() config.WebhookReporter
+	hmacSecret []byte
+	client     *http.Client
+	logger     *logrus.Entry
+	dryRun     bool
+
+	// deliveries holds the endpoints that already received events which
+	// could not be delivered to all of their endpoints, by event ID.
+	deliveriesLock sync.Mutex
+	deliveries     map[string]deliveries
+}
+
+// deliveries are the endpoints an event was delivered to.
+type deliveries struct {
+	endpoints sets.String
+	since     time.Time
+}
+
+// NewReporter creates a new webhook reporter. Events are signed with the
+// hmac secret if it is set.
+func NewReporter(cfg func() config.WebhookReporter, hmacSecret []byte, dryRun bool) *Client {
+	return &Client{
+		config:     cfg,
+		hmacSecret: hmacSecret,
+		client:     &http.Client{Timeout: time.Minute},
+		logger:     logrus.WithField("component", reporterName),
+		dryRun:     dryRun,
+		deliveries: map[string]deliveries{},
+	}
+}
+
+// GetName returns the name of the reporter
+func (c *Client) GetName() string {
+	return reporterName
+}
+
+// ShouldReport tells if a prowjob should be reported by this reporter
+func (c *Client) ShouldReport(pj *prowapi.ProwJob) bool {
+	for _, endpoint := range c.config().Endpoints {
+		if endpoint.ShouldReport(pj) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report sends the state of the prowjob to the endpoints it matches. Events
+// are delivered at least once: when an endpoint fails, the job is reported
+// again, with the same event ID, to the endpoints that did not receive it.
+func (c *Client) Report(pj *prowapi.ProwJob) ([]*prowapi.ProwJob, error) {
+	id := eventID(pj)
+	delivered := c.delivered(id)
+	var errs []error
+	for _, endpoint := range c.config().Endpoints {
+		if !endpoint.ShouldReport(pj) || delivered.Has(endpoint.Name) {
+			continue
+		}
+		l := c.logger.WithFields(logrus.Fields{"prowjob": pj.Name, "endpoint": endpoint.Name})
+		req, err := c.request(endpoint, pj)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create event for endpoint %q: %v", endpoint.Name, err))
+			continue
+		}
+		if c.dryRun {
+			l.WithField("payload", string(req.body)).Debug("Skipping reporting because dry-run is enabled")
+			continue
+		}
+		if err := httpretry.Post(c.client, endpoint.URL, req.body, req.header, backoff); err != nil {
+			errs = append(errs, fmt.Errorf("failed to report to endpoint %q: %v", endpoint.Name, err))
+			continue
+		}
+		delivered.Insert(endpoint.Name)
+		l.Debug("Reported job.")
+	}
+	if len(errs) > 0 {
+		c.remember(id, delivered)
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	c.forget(id)
+	return []*prowapi.ProwJob{pj}, nil
+}
+
+// delivered returns the endpoints the event was already delivered to.
+func (c *Client) delivered(id string) sets.String {
+	c.deliveriesLock.Lock()
+	defer c.deliveriesLock.Unlock()
+	return sets.NewString(c.deliveries[id].endpoints.UnsortedList()...)
+}
+
+// remember records the endpoints the event was delivered to, so that retries
+// skip them. Records of events that were never fully delivered expire.
+func (c *Client) remember(id string, endpoints sets.String) {
+	c.deliveriesLock.Lock()
+	defer c.deliveriesLock.Unlock()
+	now := time.Now()
+	for key, d := range c.deliveries {
+		if now.Sub(d.since) > deliveriesTTL {
+			delete(c.deliveries, key)
+		}
+	}
+	since := now
+	if d, ok := c.deliveries[id]; ok {
+		since = d.since
+	}
+	c.deliveries[id] = deliveries{endpoints: endpoints, since: since}
+}
+
+// forget drops the record of an event once it was delivered everywhere.
+func (c *Client) forget(id string) {
+	c.deliveriesLock.Lock()
+	defer c.deliveriesLock.Unlock()
+	delete(c.deliveries, id)
+}
+
+// request is an event ready to be sent.
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// data returns the data of the event for the job and its content type.
+func data(endpoint config.WebhookEndpoint, pj *prowapi.ProwJob) ([]byte, string, error) {
+	if endpoint.Template != nil {
+		b := &bytes.Buffer{}
+		if err := endpoint.Template.Execute(b, pj); err != nil {
+			return nil, "", fmt.Errorf("failed to execute payload template: %v", err)
+		}
+		return b.Bytes(), endpoint.PayloadContentType, nil
+	}
+	event := JobEvent{
+		Name:        pj.Name,
+		Job:         pj.Spec.Job,
+		Type:        pj.Spec.Type,
+		State:       pj.Status.State,
+		Description: pj.Status.Description,
+		URL:         pj.Status.URL,
+		Refs:        pj.Spec.Refs,
+		ExtraRefs:   pj.Spec.ExtraRefs,
+		StartTime:   pj.Status.StartTime.Time,
+		Labels:      pj.Labels,
+	}
+	if pj.Status.CompletionTime != nil {
+		event.CompletionTime = &pj.Status.CompletionTime.Time
+	}
+	if prev, ok := pj.Status.PrevReportStates[reporterName]; ok {
+		event.PrevState = &prev
+	}
+	b, err := json.Marshal(event)
+	return b, "application/json", err
+}
+
+// eventID identifies the event for the current state of the job. A job is
+// reported once per state, so redeliveries share the ID.
+func eventID(pj *prowapi.ProwJob) string {
+	return fmt.Sprintf("%s-%s", pj.Name, pj.Status.State)
+}
+
+// eventTime is when the job got to its current state, as far as it is known.
+func eventTime(pj *prowapi.ProwJob) time.Time {
+	if pj.Status.CompletionTime != nil {
+		return pj.Status.CompletionTime.Time
+	}
+	if pj.Status.PendingTime != nil && pj.Status.State != prowapi.TriggeredState {
+		return pj.Status.PendingTime.Time
+	}
+	return pj.Status.StartTime.Time
+}
+
+func (c *Client) request(endpoint config.WebhookEndpoint, pj *prowapi.ProwJob) (*request, error) {
+	payload, contentType, err := data(endpoint, pj)
+	if err != nil {
+		return nil, err
+	}
+	event := CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              eventID(pj),
+		Source:          EventSource,
+		Type:            EventType,
+		Subject:         pj.Spec.Job,
+		Time:            eventTime(pj),
+		DataContentType: contentType,
+	}
+
+	req := &request{header: http.Header{}}
+	if endpoint.Mode == config.WebhookBinaryMode {
+		req.header.Set("Content-Type", contentType)
+		req.header.Set("ce-specversion", event.SpecVersion)
+		req.header.Set("ce-id", event.ID)
+		req.header.Set("ce-source", event.Source)
+		req.header.Set("ce-type", event.Type)
+		req.header.Set("ce-subject", event.Subject)
+		req.header.Set("ce-time", event.Time.UTC().Format(time.RFC3339))
+		req.body = payload
+	} else {
+		event.Data = string(payload)
+		if json.Valid(payload) {
+			event.Data = json.RawMessage(payload)
+		}
+		if req.body, err = json.Marshal(event); err != nil {
+			return nil, err
+		}
+		req.header.Set("Content-Type", structuredContentType)
+	}
+	if len(c.hmacSecret) > 0 {
+		mac := hmac.New(sha256.New, c.hmacSecret)
+		mac.Write(req.body)
+		req.header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return req, nil
+}
@@ -0,0 +1,317 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/httpretry"
+)
+
+func testJob() *prowapi.ProwJob {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	completion := metav1.NewTime(start.Add(time.Hour))
+	return &prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "pj"},
+		Spec: prowapi.ProwJobSpec{
+			Job:  "unit",
+			Type: prowapi.PresubmitJob,
+			Refs: &prowapi.Refs{Org: "org", Repo: "repo", Pulls: []prowapi.Pull{{Number: 1}}},
+		},
+		Status: prowapi.ProwJobStatus{
+			State:          prowapi.FailureState,
+			URL:            "https://prow/pj",
+			StartTime:      metav1.NewTime(start),
+			CompletionTime: &completion,
+		},
+	}
+}
+
+func validated(t *testing.T, endpoints ...config.WebhookEndpoint) config.WebhookReporter {
+	cfg := config.WebhookReporter{Endpoints: endpoints}
+	if err := cfg.DefaultAndValidate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+	return cfg
+}
+
+func TestShouldReport(t *testing.T) {
+	testCases := []struct {
+		name     string
+		endpoint config.WebhookEndpoint
+		expected bool
+	}{
+		{
+			name:     "all jobs by default",
+			endpoint: config.WebhookEndpoint{},
+			expected: true,
+		},
+		{
+			name:     "matching org",
+			endpoint: config.WebhookEndpoint{Repos: []string{"other", "org"}},
+			expected: true,
+		},
+		{
+			name:     "matching repo",
+			endpoint: config.WebhookEndpoint{Repos: []string{"org/repo"}},
+			expected: true,
+		},
+		{
+			name:     "other repo",
+			endpoint: config.WebhookEndpoint{Repos: []string{"org/other"}},
+		},
+		{
+			name:     "matching job regex",
+			endpoint: config.WebhookEndpoint{Jobs: []string{"^e2e", "^un"}},
+			expected: true,
+		},
+		{
+			name:     "other job",
+			endpoint: config.WebhookEndpoint{Jobs: []string{"^e2e"}},
+		},
+		{
+			name:     "other job type",
+			endpoint: config.WebhookEndpoint{JobTypesToReport: []prowapi.ProwJobType{prowapi.PeriodicJob}},
+		},
+		{
+			name:     "matching state",
+			endpoint: config.WebhookEndpoint{JobStatesToReport: []prowapi.ProwJobState{prowapi.SuccessState, prowapi.FailureState}},
+			expected: true,
+		},
+		{
+			name:     "other state",
+			endpoint: config.WebhookEndpoint{JobStatesToReport: []prowapi.ProwJobState{prowapi.SuccessState}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.endpoint.Name = "endpoint"
+			tc.endpoint.URL = "http://endpoint"
+			cfg := validated(t, tc.endpoint)
+			c := NewReporter(func() config.WebhookReporter { return cfg }, nil, false)
+			if actual := c.ShouldReport(testJob()); actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	secret := []byte("secret")
+	testCases := []struct {
+		name     string
+		endpoint config.WebhookEndpoint
+
+		expectedHeader map[string]string
+		expectedBody   string
+	}{
+		{
+			name:     "structured",
+			endpoint: config.WebhookEndpoint{},
+			expectedHeader: map[string]string{
+				"Content-Type": structuredContentType,
+			},
+			expectedBody: `{"specversion":"1.0","id":"pj-failure","source":"/prow/crier","type":"io.k8s.prow.job.state","subject":"unit","time":"2020-01-01T01:00:00Z","datacontenttype":"application/json",` +
+				`"data":{"name":"pj","job":"unit","type":"presubmit","state":"failure","url":"https://prow/pj","refs":{"org":"org","repo":"repo","pulls":[{"number":1,"author":"","sha":""}]},"start_time":"2020-01-01T00:00:00Z","completion_time":"2020-01-01T01:00:00Z"}}`,
+		},
+		{
+			name:     "binary with a template",
+			endpoint: config.WebhookEndpoint{Mode: config.WebhookBinaryMode, PayloadTemplate: "{{.Spec.Job}} is {{.Status.State}}", PayloadContentType: "text/plain"},
+			expectedHeader: map[string]string{
+				"Content-Type":   "text/plain",
+				"Ce-Specversion": "1.0",
+				"Ce-Id":          "pj-failure",
+				"Ce-Source":      EventSource,
+				"Ce-Type":        EventType,
+				"Ce-Subject":     "unit",
+				"Ce-Time":        "2020-01-01T01:00:00Z",
+			},
+			expectedBody: "unit is failure",
+		},
+		{
+			name:     "structured with a template that is not JSON",
+			endpoint: config.WebhookEndpoint{PayloadTemplate: "{{.Spec.Job}}", PayloadContentType: "text/plain"},
+			expectedHeader: map[string]string{
+				"Content-Type": structuredContentType,
+			},
+			expectedBody: `{"specversion":"1.0","id":"pj-failure","source":"/prow/crier","type":"io.k8s.prow.job.state","subject":"unit","time":"2020-01-01T01:00:00Z","datacontenttype":"text/plain","data":"unit"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var header http.Header
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+				body, _ = ioutil.ReadAll(r.Body)
+			}))
+			defer server.Close()
+			tc.endpoint.Name = "endpoint"
+			tc.endpoint.URL = server.URL
+			cfg := validated(t, tc.endpoint, config.WebhookEndpoint{Name: "other", URL: "http://unreachable", Repos: []string{"other"}})
+			c := NewReporter(func() config.WebhookReporter { return cfg }, secret, false)
+
+			if _, err := c.Report(testJob()); err != nil {
+				t.Fatalf("failed to report: %v", err)
+			}
+			for key, expected := range tc.expectedHeader {
+				if actual := header.Get(key); actual != expected {
+					t.Errorf("expected header %s to be %q, got %q", key, expected, actual)
+				}
+			}
+			if json.Valid([]byte(tc.expectedBody)) {
+				// Compare the JSON rather than its formatting.
+				var expected, actual interface{}
+				json.Unmarshal([]byte(tc.expectedBody), &expected)
+				if err := json.Unmarshal(body, &actual); err != nil {
+					t.Fatalf("failed to unmarshal body %s: %v", body, err)
+				}
+				if !reflect.DeepEqual(actual, expected) {
+					t.Errorf("expected body %s, got %s", tc.expectedBody, body)
+				}
+			} else if string(body) != tc.expectedBody {
+				t.Errorf("expected body %s, got %s", tc.expectedBody, body)
+			}
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(body)
+			if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); header.Get(SignatureHeader) != expected {
+				t.Errorf("expected signature %s, got %s", expected, header.Get(SignatureHeader))
+			}
+		})
+	}
+}
+
+func TestReportRetries(t *testing.T) {
+	backoff = wait.Backoff{Steps: httpretry.DefaultBackoff.Steps}
+	defer func() { backoff = httpretry.DefaultBackoff }()
+
+	testCases := []struct {
+		name     string
+		statuses []int
+
+		expectedRequests int
+		expectedErr      bool
+	}{
+		{
+			name:             "server error is retried",
+			statuses:         []int{http.StatusBadGateway, http.StatusOK},
+			expectedRequests: 2,
+		},
+		{
+			name:             "client error is not retried",
+			statuses:         []int{http.StatusBadRequest},
+			expectedRequests: 1,
+			expectedErr:      true,
+		},
+		{
+			name:             "retries are bounded",
+			statuses:         []int{http.StatusServiceUnavailable},
+			expectedRequests: httpretry.DefaultBackoff.Steps,
+			expectedErr:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tc.statuses[len(tc.statuses)-1]
+				if requests < len(tc.statuses) {
+					status = tc.statuses[requests]
+				}
+				requests++
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+			cfg := validated(t, config.WebhookEndpoint{Name: "endpoint", URL: server.URL})
+			c := NewReporter(func() config.WebhookReporter { return cfg }, nil, false)
+
+			_, err := c.Report(testJob())
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error %t, got %v", tc.expectedErr, err)
+			}
+			if requests != tc.expectedRequests {
+				t.Errorf("expected %d requests, got %d", tc.expectedRequests, requests)
+			}
+		})
+	}
+}
+
+func TestReportRetriesFailedEndpoints(t *testing.T) {
+	backoff = wait.Backoff{Steps: 1}
+	defer func() { backoff = httpretry.DefaultBackoff }()
+
+	var healthyRequests, flakyRequests int
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		healthyRequests++
+	}))
+	defer healthy.Close()
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flakyRequests++
+		if flakyRequests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer flaky.Close()
+	cfg := validated(t,
+		config.WebhookEndpoint{Name: "healthy", URL: healthy.URL},
+		config.WebhookEndpoint{Name: "flaky", URL: flaky.URL},
+	)
+	c := NewReporter(func() config.WebhookReporter { return cfg }, nil, false)
+
+	if _, err := c.Report(testJob()); err == nil {
+		t.Fatal("expected an error when an endpoint fails")
+	}
+	if healthyRequests != 1 || flakyRequests != 1 {
+		t.Fatalf("expected one request to each endpoint, got %d and %d", healthyRequests, flakyRequests)
+	}
+	if _, err := c.Report(testJob()); err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	if healthyRequests != 1 {
+		t.Errorf("expected the retry to skip the endpoint that got the event, got %d requests", healthyRequests)
+	}
+	if flakyRequests != 2 {
+		t.Errorf("expected the retry to go to the endpoint that failed, got %d requests", flakyRequests)
+	}
+
+	// Once delivered everywhere, the next state goes to all endpoints again.
+	pj := testJob()
+	pj.Status.State = prowapi.SuccessState
+	if _, err := c.Report(pj); err != nil {
+		t.Fatalf("expected report to succeed, got %v", err)
+	}
+	if healthyRequests != 2 || flakyRequests != 3 {
+		t.Errorf("expected the next state to go to both endpoints, got %d and %d requests", healthyRequests, flakyRequests)
+	}
+}
@@ -34,6 +34,7 @@ import (
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/pjutil"
 )
 
 const (
@@ -98,7 +99,10 @@ type JobAgent struct {
 	jobs      []Job
 	jobsMap   map[string]Job                        // pod name -> Job
 	jobsIDMap map[string]map[string]prowapi.ProwJob // job name -> id -> ProwJob
-	mut       sync.Mutex
+	// queuePositions maps the names of triggered ProwJobs to their
+	// position in the queue of plank.
+	queuePositions map[string]int
+	mut            sync.Mutex
 }
 
 // Start will start the job and periodically update it.
@@ -130,6 +134,18 @@ func (ja *JobAgent) ProwJobs() []prowapi.ProwJob {
 	return res
 }
 
+// QueuePositions returns a thread-safe snapshot of the positions of the
+// triggered prow jobs in the queue of plank, keyed by their names.
+func (ja *JobAgent) QueuePositions() map[string]int {
+	ja.mut.Lock()
+	defer ja.mut.Unlock()
+	res := make(map[string]int, len(ja.queuePositions))
+	for name, position := range ja.queuePositions {
+		res[name] = position
+	}
+	return res
+}
+
 var jobNameRE = regexp.MustCompile(`^([\w-]+)-(\d+)$`)
 
 // GetProwJob finds the corresponding Prowjob resource from the provided job name and build ID
@@ -253,9 +269,17 @@ func (ja *JobAgent) update() error {
 		njsIDMap[j.Spec.Job][buildID] = j
 	}
 
+	// Queue positions depend on the fair share config of plank, so they
+	// are left out until a config is loaded.
+	var queuePositions map[string]int
+	if cfg := ja.config(); cfg != nil {
+		queuePositions = pjutil.QueuePositions(pjutil.QueueTriggeredJobs(pjs, cfg.Plank))
+	}
+
 	ja.mut.Lock()
 	defer ja.mut.Unlock()
 	ja.prowJobs = pjs
+	ja.queuePositions = queuePositions
 	ja.jobs = njs
 	ja.jobsMap = njsMap
 	ja.jobsIDMap = njsIDMap
@@ -24,6 +24,7 @@ import (
 	coreapi "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/kube"
 )
 
@@ -75,8 +76,9 @@ func TestGetLog(t *testing.T) {
 		},
 	}
 	ja := &JobAgent{
-		kc:   kc,
-		pkcs: map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")},
+		kc:     kc,
+		pkcs:   map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")},
+		config: func() *config.Config { return &config.Config{} },
 	}
 	if err := ja.update(); err != nil {
 		t.Fatalf("Updating: %v", err)
@@ -143,8 +145,9 @@ func TestProwJobs(t *testing.T) {
 		},
 	}
 	ja := &JobAgent{
-		kc:   kc,
-		pkcs: map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("")},
+		kc:     kc,
+		pkcs:   map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("")},
+		config: func() *config.Config { return &config.Config{} },
 	}
 	if err := ja.update(); err != nil {
 		t.Fatalf("Updating: %v", err)
@@ -217,8 +220,9 @@ func TestJobs(t *testing.T) {
 		},
 	}
 	ja := &JobAgent{
-		kc:   kc,
-		pkcs: map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("")},
+		kc:     kc,
+		pkcs:   map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("")},
+		config: func() *config.Config { return &config.Config{} },
 	}
 	if err := ja.update(); err != nil {
 		t.Fatalf("Updating: %v", err)
@@ -241,3 +245,69 @@ func TestJobs(t *testing.T) {
 		t.Errorf("Expected third job to have job name %q, but got %q.", expect, got)
 	}
 }
+
+func TestQueuePositions(t *testing.T) {
+	job := func(name string, priority int, state prowapi.ProwJobState) prowapi.ProwJob {
+		return prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: prowapi.ProwJobSpec{
+				Agent:    prowapi.KubernetesAgent,
+				Job:      name,
+				Priority: priority,
+			},
+			Status: prowapi.ProwJobStatus{State: state},
+		}
+	}
+	kc := fkc{
+		job("running", 0, prowapi.PendingState),
+		job("waiting", 0, prowapi.TriggeredState),
+		job("urgent", 1, prowapi.TriggeredState),
+	}
+	ja := &JobAgent{
+		kc:     kc,
+		pkcs:   map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("")},
+		config: func() *config.Config { return &config.Config{} },
+	}
+	if err := ja.update(); err != nil {
+		t.Fatalf("Updating: %v", err)
+	}
+
+	positions := ja.QueuePositions()
+	if expect, got := 2, len(positions); expect != got {
+		t.Fatalf("Expected %d queued jobs, but got %d.", expect, got)
+	}
+	if expect, got := 1, positions["urgent"]; expect != got {
+		t.Errorf("Expected urgent job at position %d, but got %d.", expect, got)
+	}
+	if expect, got := 2, positions["waiting"]; expect != got {
+		t.Errorf("Expected waiting job at position %d, but got %d.", expect, got)
+	}
+}
+
+func TestUpdateWithoutConfig(t *testing.T) {
+	kc := fkc{
+		prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "waiting"},
+			Spec: prowapi.ProwJobSpec{
+				Agent: prowapi.KubernetesAgent,
+				Job:   "waiting",
+			},
+			Status: prowapi.ProwJobStatus{State: prowapi.TriggeredState},
+		},
+	}
+	var ca config.Agent
+	ja := &JobAgent{
+		kc:     kc,
+		pkcs:   map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("")},
+		config: ca.Config,
+	}
+	if err := ja.update(); err != nil {
+		t.Fatalf("Updating: %v", err)
+	}
+	if expect, got := 1, len(ja.Jobs()); expect != got {
+		t.Errorf("Expected %d jobs, but got %d.", expect, got)
+	}
+	if positions := ja.QueuePositions(); len(positions) != 0 {
+		t.Errorf("Expected no queue positions without a config, but got %v.", positions)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/andygrunwald/go-gerrit"
@@ -44,6 +45,9 @@ type gerritClient interface {
 	GetBranchRevision(instance, project, branch string) (string, error)
 	SetReview(instance, id, revision, message string, labels map[string]string) error
 	Account(instance string) *gerrit.AccountInfo
+	GetChange(instance, id string) (*client.ChangeInfo, error)
+	SearchChanges(instance, query string, rateLimit int) ([]client.ChangeInfo, error)
+	GetEvents(instance string, since time.Time) ([]client.Event, error)
 }
 
 type configAgent interface {
@@ -56,6 +60,35 @@ type Controller struct {
 	prowJobClient prowJobClient
 	gc            gerritClient
 	tracker       LastSyncTracker
+	projects      map[string][]string
+	// cursors is only set when the controller consumes the events-log of
+	// the instances, Sync then only reconciles missed changes.
+	cursors EventCursorTracker
+
+	// lock serializes the processing of changes from Sync and SyncEvents.
+	lock sync.Mutex
+	// processed is when each change was last processed, so that a change
+	// seen both in the events and by Sync is only processed once.
+	processed map[changeKey]processedChange
+	// postsubmitted is when the postsubmits of each merged change were
+	// triggered, so that a change seen both in a ref-updated event and by
+	// Sync only runs them once.
+	postsubmitted map[changeKey]time.Time
+}
+
+// postsubmittedRetention is how long the changes whose postsubmits were
+// triggered are remembered. Their ref-updated event or their last update
+// seen by Sync is expected well within it.
+const postsubmittedRetention = time.Hour
+
+type changeKey struct {
+	instance string
+	id       string
+}
+
+type processedChange struct {
+	project string
+	updated time.Time
 }
 
 type LastSyncTracker interface {
@@ -63,8 +96,9 @@ type LastSyncTracker interface {
 	Update(client.LastSyncState) error
 }
 
-// NewController returns a new gerrit controller client
-func NewController(lastSyncTracker LastSyncTracker, cookiefilePath string, projects map[string][]string, prowJobClient prowv1.ProwJobInterface, cfg config.Getter) (*Controller, error) {
+// NewController returns a new gerrit controller client. The events-log of
+// the instances is only consumed if eventCursorTracker is not nil.
+func NewController(lastSyncTracker LastSyncTracker, eventCursorTracker EventCursorTracker, cookiefilePath string, projects map[string][]string, prowJobClient prowv1.ProwJobInterface, cfg config.Getter) (*Controller, error) {
 	if lastSyncTracker == nil {
 		return nil, errors.New("lastSyncTracker required")
 	}
@@ -80,6 +114,8 @@ func NewController(lastSyncTracker LastSyncTracker, cookiefilePath string, proje
 		config:        cfg,
 		gc:            c,
 		tracker:       lastSyncTracker,
+		projects:      projects,
+		cursors:       eventCursorTracker,
 	}, nil
 }
 
@@ -104,10 +140,47 @@ func (c *Controller) Sync() error {
 		logrus.Infof("Processed %d changes for instance %s", len(changes), instance)
 	}
 
-	return c.tracker.Update(latest)
+	if err := c.tracker.Update(latest); err != nil {
+		return err
+	}
+	c.forgetProcessed(c.tracker.Current())
+	return nil
 }
 
-func makeCloneURI(instance, project string) (*url.URL, error) {
+// forgetProcessed drops the changes that Sync will not see again and the
+// merged changes whose postsubmits were triggered long ago.
+func (c *Controller) forgetProcessed(syncTime client.LastSyncState) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key, change := range c.processed {
+		if lastSync, ok := syncTime[key.instance][change.project]; ok && !change.updated.After(lastSync) {
+			delete(c.processed, key)
+		}
+	}
+	for key, triggered := range c.postsubmitted {
+		if time.Since(triggered) > postsubmittedRetention {
+			delete(c.postsubmitted, key)
+		}
+	}
+}
+
+// markPostsubmitted records that the postsubmits of the merged change are
+// being triggered and returns false if they were triggered already. The
+// caller must hold c.lock.
+func (c *Controller) markPostsubmitted(instance, id string) bool {
+	key := changeKey{instance: instance, id: id}
+	if _, ok := c.postsubmitted[key]; ok {
+		return false
+	}
+	if c.postsubmitted == nil {
+		c.postsubmitted = map[changeKey]time.Time{}
+	}
+	c.postsubmitted[key] = time.Now()
+	return true
+}
+
+// MakeCloneURI returns the URI to clone the project on the gerrit instance from.
+func MakeCloneURI(instance, project string) (*url.URL, error) {
 	u, err := url.Parse(instance)
 	if err != nil {
 		return nil, fmt.Errorf("instance %s is not a url: %v", instance, err)
@@ -134,17 +207,25 @@ func listChangedFiles(changeInfo client.ChangeInfo) config.ChangedFilesProvider
 	}
 }
 
-func createRefs(reviewHost string, change client.ChangeInfo, cloneURI *url.URL, baseSHA string) (prowapi.Refs, error) {
-	rev, ok := change.Revisions[change.CurrentRevision]
-	if !ok {
-		return prowapi.Refs{}, fmt.Errorf("cannot find current revision for change %v", change.ID)
-	}
-	var codeHost string // Something like https://android.googlesource.com
+// codeHostFor returns the host serving the code reviewed on the review host,
+// something like https://android.googlesource.com.
+func codeHostFor(reviewHost string) string {
 	parts := strings.SplitN(reviewHost, ".", 2)
-	codeHost = strings.TrimSuffix(parts[0], "-review")
+	codeHost := strings.TrimSuffix(parts[0], "-review")
 	if len(parts) > 1 {
 		codeHost += "." + parts[1]
 	}
+	return codeHost
+}
+
+// CreateRefs returns the refs of the current revision of the change on top of
+// the base SHA.
+func CreateRefs(reviewHost string, change client.ChangeInfo, cloneURI *url.URL, baseSHA string) (prowapi.Refs, error) {
+	rev, ok := change.Revisions[change.CurrentRevision]
+	if !ok {
+		return prowapi.Refs{}, fmt.Errorf("cannot find current revision for change %v", change.ID)
+	}
+	codeHost := codeHostFor(reviewHost)
 	refs := prowapi.Refs{
 		Org:      cloneURI.Host,  // Something like android-review.googlesource.com
 		Repo:     change.Project, // Something like platform/build
@@ -168,11 +249,65 @@ func createRefs(reviewHost string, change client.ChangeInfo, cloneURI *url.URL,
 	return refs, nil
 }
 
+// Presubmits returns the presubmits configured for the repo at the clone URI.
+func Presubmits(cfg *config.Config, cloneURI *url.URL) []config.Presubmit {
+	presubmits := cfg.PresubmitsStatic[cloneURI.String()]
+	return append(presubmits, cfg.PresubmitsStatic[cloneURI.Host+"/"+cloneURI.Path]...)
+}
+
+// Postsubmits returns the postsubmits configured for the repo at the clone URI.
+func Postsubmits(cfg *config.Config, cloneURI *url.URL) []config.Postsubmit {
+	postsubmits := cfg.PostsubmitsStatic[cloneURI.String()]
+	return append(postsubmits, cfg.PostsubmitsStatic[cloneURI.Host+"/"+cloneURI.Path]...)
+}
+
+// NewProwJob returns a prowjob for the change on the gerrit instance, labeled
+// and annotated so that its results are reported back to the change.
+func NewProwJob(instance string, change client.ChangeInfo, spec prowapi.ProwJobSpec, jobLabels map[string]string) prowapi.ProwJob {
+	labels := make(map[string]string)
+	for k, v := range jobLabels {
+		labels[k] = v
+	}
+	labels[client.GerritRevision] = change.CurrentRevision
+
+	if _, ok := labels[client.GerritReportLabel]; !ok {
+		labels[client.GerritReportLabel] = client.CodeReview
+	}
+
+	annotations := map[string]string{
+		client.GerritID:       change.ID,
+		client.GerritInstance: instance,
+	}
+	return pjutil.NewProwJob(spec, labels, annotations)
+}
+
 // ProcessChange creates new presubmit prowjobs base off the gerrit changes
 func (c *Controller) ProcessChange(instance string, change client.ChangeInfo) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := changeKey{instance: instance, id: change.ID}
+	processed, ok := c.processed[key]
+	if ok && !change.Updated.Time.After(processed.updated) {
+		logrus.WithField("gerrit change", change.Number).Debug("Change was already processed.")
+		return nil
+	}
+	if err := c.processChange(instance, change, processed.updated); err != nil {
+		return err
+	}
+	if c.processed == nil {
+		c.processed = map[changeKey]processedChange{}
+	}
+	c.processed[key] = processedChange{project: change.Project, updated: change.Updated.Time}
+	return nil
+}
+
+// processChange triggers the jobs of the change for what happened since
+// the last sync or the last time the change was processed, whichever is later.
+func (c *Controller) processChange(instance string, change client.ChangeInfo, lastProcessed time.Time) error {
 	logger := logrus.WithField("gerrit change", change.Number)
 
-	cloneURI, err := makeCloneURI(instance, change.Project)
+	cloneURI, err := MakeCloneURI(instance, change.Project)
 	if err != nil {
 		return fmt.Errorf("failed to create clone uri: %v", err)
 	}
@@ -184,7 +319,7 @@ func (c *Controller) ProcessChange(instance string, change client.ChangeInfo) er
 
 	triggeredJobs := []string{}
 
-	refs, err := createRefs(instance, change, cloneURI, baseSHA)
+	refs, err := CreateRefs(instance, change, cloneURI, baseSHA)
 	if err != nil {
 		return fmt.Errorf("failed to get refs: %v", err)
 	}
@@ -200,10 +335,14 @@ func (c *Controller) ProcessChange(instance string, change client.ChangeInfo) er
 
 	switch change.Status {
 	case client.Merged:
+		// The ref-updated event of the change may have triggered them already,
+		// otherwise polling is the fallback for missed events.
+		if !c.markPostsubmitted(instance, change.ID) {
+			logger.Debug("Postsubmits of the change were already triggered.")
+			break
+		}
 		// TODO: Do we want to add support for dynamic postsubmits?
-		postsubmits := c.config().PostsubmitsStatic[cloneURI.String()]
-		postsubmits = append(postsubmits, c.config().PostsubmitsStatic[cloneURI.Host+"/"+cloneURI.Path]...)
-		for _, postsubmit := range postsubmits {
+		for _, postsubmit := range Postsubmits(c.config(), cloneURI) {
 			if shouldRun, err := postsubmit.ShouldRun(change.Branch, changedFiles); err != nil {
 				return fmt.Errorf("failed to determine if postsubmit %q should run: %v", postsubmit.Name, err)
 			} else if shouldRun {
@@ -215,8 +354,7 @@ func (c *Controller) ProcessChange(instance string, change client.ChangeInfo) er
 		}
 	case client.New:
 		// TODO: Do we want to add support for dynamic presubmits?
-		presubmits := c.config().PresubmitsStatic[cloneURI.String()]
-		presubmits = append(presubmits, c.config().PresubmitsStatic[cloneURI.Host+"/"+cloneURI.Path]...)
+		presubmits := Presubmits(c.config(), cloneURI)
 
 		var filters []pjutil.Filter
 		var latestReport *reporter.JobReport
@@ -250,6 +388,9 @@ func (c *Controller) ProcessChange(instance string, change client.ChangeInfo) er
 			logrus.Warnf("could not find lastTime for project %q, probably something went wrong with initTracker?", change.Project)
 			lastUpdate = time.Now()
 		}
+		if lastProcessed.After(lastUpdate) {
+			lastUpdate = lastProcessed
+		}
 
 		filter, err := messageFilter(lastUpdate, change, presubmits, latestReport, logger)
 		if err != nil {
@@ -272,23 +413,8 @@ func (c *Controller) ProcessChange(instance string, change client.ChangeInfo) er
 		}
 	}
 
-	annotations := map[string]string{
-		client.GerritID:       change.ID,
-		client.GerritInstance: instance,
-	}
-
 	for _, jSpec := range jobSpecs {
-		labels := make(map[string]string)
-		for k, v := range jSpec.labels {
-			labels[k] = v
-		}
-		labels[client.GerritRevision] = change.CurrentRevision
-
-		if _, ok := labels[client.GerritReportLabel]; !ok {
-			labels[client.GerritReportLabel] = client.CodeReview
-		}
-
-		pj := pjutil.NewProwJob(jSpec.spec, labels, annotations)
+		pj := NewProwJob(instance, change, jSpec.spec, jSpec.labels)
 		if _, err := c.prowJobClient.Create(&pj); err != nil {
 			logger.WithError(err).Errorf("fail to create prowjob %v", pj)
 		} else {
@@ -71,6 +71,18 @@ func (f *fgc) Account(instance string) *gerrit.AccountInfo {
 	return &gerrit.AccountInfo{AccountID: 42}
 }
 
+func (f *fgc) GetChange(instance, id string) (*client.ChangeInfo, error) {
+	return nil, nil
+}
+
+func (f *fgc) SearchChanges(instance, query string, rateLimit int) ([]client.ChangeInfo, error) {
+	return nil, nil
+}
+
+func (f *fgc) GetEvents(instance string, since time.Time) ([]client.Event, error) {
+	return nil, nil
+}
+
 func TestMakeCloneURI(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -107,7 +119,7 @@ func TestMakeCloneURI(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			actual, err := makeCloneURI(tc.instance, tc.project)
+			actual, err := MakeCloneURI(tc.instance, tc.project)
 			switch {
 			case err != nil:
 				if !tc.err {
@@ -179,11 +191,11 @@ func TestCreateRefs(t *testing.T) {
 			},
 		},
 	}
-	cloneURI, err := makeCloneURI(reviewHost, change.Project)
+	cloneURI, err := MakeCloneURI(reviewHost, change.Project)
 	if err != nil {
 		t.Errorf("failed to make clone URI: %v", err)
 	}
-	actual, err := createRefs(reviewHost, change, cloneURI, "abcdef")
+	actual, err := CreateRefs(reviewHost, change, cloneURI, "abcdef")
 	if err != nil {
 		t.Errorf("unexpected error creating refs: %v", err)
 	}
@@ -0,0 +1,247 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/gerrit/client"
+	"k8s.io/test-infra/prow/pjutil"
+)
+
+// deletedRev is the new revision of the ref-updated events of deleted refs.
+const deletedRev = "0000000000000000000000000000000000000000"
+
+// EventCursor is the position of the controller in the events-log of an
+// instance. The events-log does not number the events and only resolves
+// their creation time to the second, so the position is the creation time
+// of the last processed event and the digests of the events created in that
+// second that were processed. Unlike a count, the digests stay correct when
+// the events of a second are returned in a different order or when more of
+// them are logged after the cursor was saved.
+type EventCursor struct {
+	CreatedOn int      `json:"created_on"`
+	Processed []string `json:"processed,omitempty"`
+}
+
+// EventCursorTracker persists the positions in the events-log of the
+// instances, so that events are not missed across restarts.
+type EventCursorTracker interface {
+	Current() map[string]EventCursor
+	Update(map[string]EventCursor) error
+}
+
+// SyncEvents processes the events logged by the instances since the last
+// call, triggering presubmits on patchset-created and comment-added and
+// postsubmits on ref-updated.
+func (c *Controller) SyncEvents() error {
+	if c.cursors == nil {
+		return errors.New("the controller does not consume events")
+	}
+
+	current := c.cursors.Current()
+	latest := map[string]EventCursor{}
+	for instance := range c.projects {
+		cursor, ok := current[instance]
+		if !ok {
+			// Sync takes care of what happened before.
+			latest[instance] = EventCursor{CreatedOn: int(time.Now().Unix())}
+			continue
+		}
+		latest[instance] = cursor
+
+		events, err := c.gc.GetEvents(instance, time.Unix(int64(cursor.CreatedOn), 0))
+		if err != nil {
+			logrus.WithError(err).WithField("instance", instance).Error("Failed to get events.")
+			continue
+		}
+
+		// The events of the second of the cursor are returned again, skip
+		// the ones that were processed already.
+		seen := sets.NewString(cursor.Processed...)
+		var processed int
+		for _, event := range events {
+			if event.EventCreatedOn < cursor.CreatedOn {
+				continue
+			}
+			digest, err := eventDigest(event)
+			if err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{"instance": instance, "type": event.Type}).Error("Failed to identify event.")
+				continue
+			}
+			if event.EventCreatedOn > cursor.CreatedOn {
+				seen = sets.NewString()
+				cursor = EventCursor{CreatedOn: event.EventCreatedOn}
+			} else if seen.Has(digest) {
+				continue
+			}
+			seen.Insert(digest)
+
+			// Sync retries the changes we fail to process, so move on.
+			if err := c.processEvent(instance, event); err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{"instance": instance, "type": event.Type}).Error("Failed to process event.")
+			}
+			processed++
+		}
+		cursor.Processed = seen.List()
+		latest[instance] = cursor
+
+		logrus.Infof("Processed %d events for instance %s", processed, instance)
+	}
+
+	return c.cursors.Update(latest)
+}
+
+// eventDigest identifies an event among the events created in the same
+// second.
+func eventDigest(event client.Event) (string, error) {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(raw)), nil
+}
+
+// processEvent processes a single event of the instance.
+func (c *Controller) processEvent(instance string, event client.Event) error {
+	switch event.Type {
+	case client.PatchSetCreated, client.CommentAdded:
+		if !c.watches(instance, event.Change.Project) {
+			return nil
+		}
+		// Our own reports cannot ask for jobs to run.
+		if event.Type == client.CommentAdded && c.isSelf(instance, event.Author.Username) {
+			return nil
+		}
+		change, err := c.gc.GetChange(instance, changeID(event.Change))
+		if err != nil {
+			return err
+		}
+		return c.ProcessChange(instance, *change)
+	case client.RefUpdated:
+		return c.processRefUpdate(instance, event.RefUpdate)
+	}
+	return nil
+}
+
+// processRefUpdate triggers the postsubmits of the updated branch. Jobs
+// triggered for submitted changes report to the change, jobs triggered
+// for direct pushes have nothing to report to.
+func (c *Controller) processRefUpdate(instance string, update client.RefUpdate) error {
+	// Older gerrit versions send the name of the branch without refs/heads/.
+	branch := update.RefName
+	if strings.HasPrefix(branch, "refs/") && !strings.HasPrefix(branch, "refs/heads/") {
+		return nil
+	}
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	if update.NewRev == deletedRev || !c.watches(instance, update.Project) {
+		return nil
+	}
+	logger := logrus.WithFields(logrus.Fields{"instance": instance, "project": update.Project, "branch": branch, "revision": update.NewRev})
+
+	cloneURI, err := MakeCloneURI(instance, update.Project)
+	if err != nil {
+		return fmt.Errorf("failed to create clone uri: %v", err)
+	}
+
+	changes, err := c.gc.SearchChanges(instance, fmt.Sprintf("status:merged commit:%s", update.NewRev), c.config().Gerrit.RateLimit)
+	if err != nil {
+		return fmt.Errorf("failed to search for the change of %s: %v", update.NewRev, err)
+	}
+	var change *client.ChangeInfo
+	if len(changes) > 0 {
+		change = &changes[0]
+		// Sync may have found the merged change first.
+		c.lock.Lock()
+		trigger := c.markPostsubmitted(instance, change.ID)
+		c.lock.Unlock()
+		if !trigger {
+			logger.Debug("Postsubmits of the change were already triggered.")
+			return nil
+		}
+	}
+	changedFiles := func() ([]string, error) {
+		if change == nil {
+			return nil, errors.New("the files changed by a direct push are unknown")
+		}
+		return listChangedFiles(*change)()
+	}
+
+	codeHost := codeHostFor(instance)
+	refs := prowapi.Refs{
+		Org:      cloneURI.Host,
+		Repo:     update.Project,
+		BaseRef:  branch,
+		BaseSHA:  update.NewRev,
+		CloneURI: cloneURI.String(),
+		RepoLink: fmt.Sprintf("%s/%s", codeHost, update.Project),
+		BaseLink: fmt.Sprintf("%s/%s/+/%s", codeHost, update.Project, update.NewRev),
+	}
+	for _, postsubmit := range Postsubmits(c.config(), cloneURI) {
+		shouldRun, err := postsubmit.ShouldRun(branch, changedFiles)
+		if err != nil {
+			logger.WithError(err).Warnf("Not running postsubmit %s.", postsubmit.Name)
+			continue
+		}
+		if !shouldRun {
+			continue
+		}
+		spec := pjutil.PostsubmitSpec(postsubmit, refs)
+		pj := pjutil.NewProwJob(spec, postsubmit.Labels, nil)
+		if change != nil {
+			pj = NewProwJob(instance, *change, spec, postsubmit.Labels)
+		}
+		if _, err := c.prowJobClient.Create(&pj); err != nil {
+			logger.WithError(err).Errorf("fail to create prowjob %v", pj)
+		} else {
+			logger.Infof("Triggered Prowjob %s", spec.Job)
+		}
+	}
+	return nil
+}
+
+// watches determines whether the project of the instance is monitored.
+func (c *Controller) watches(instance, project string) bool {
+	for _, p := range c.projects[instance] {
+		if p == project {
+			return true
+		}
+	}
+	return false
+}
+
+// isSelf determines whether the user is the account prow uses on the instance.
+func (c *Controller) isSelf(instance, username string) bool {
+	account := c.gc.Account(instance)
+	return account != nil && account.Username != "" && account.Username == username
+}
+
+// changeID returns the identifier of the change of an event, the events only
+// carry its Change-Id which can be ambiguous across projects and branches.
+func changeID(change client.ChangeInfo) string {
+	return fmt.Sprintf("%s~%s~%s", url.PathEscape(change.Project), url.PathEscape(change.Branch), change.ID)
+}
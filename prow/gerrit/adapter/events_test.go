@@ -0,0 +1,416 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clienttesting "k8s.io/client-go/testing"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowfake "k8s.io/test-infra/prow/client/clientset/versioned/fake"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gerrit/client"
+)
+
+const eventsInstance = "https://gerrit"
+
+type fakeEventsClient struct {
+	fgc
+	events  []client.Event
+	changes map[string]client.ChangeInfo
+	merged  map[string]client.ChangeInfo
+}
+
+func (f *fakeEventsClient) Account(instance string) *gerrit.AccountInfo {
+	return &gerrit.AccountInfo{AccountID: 42, Username: "prow"}
+}
+
+func (f *fakeEventsClient) GetEvents(instance string, since time.Time) ([]client.Event, error) {
+	var events []client.Event
+	for _, event := range f.events {
+		if int64(event.EventCreatedOn) >= since.Unix() {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (f *fakeEventsClient) GetChange(instance, id string) (*client.ChangeInfo, error) {
+	change, ok := f.changes[id]
+	if !ok {
+		return nil, fmt.Errorf("change %s not found", id)
+	}
+	return &change, nil
+}
+
+func (f *fakeEventsClient) SearchChanges(instance, query string, rateLimit int) ([]client.ChangeInfo, error) {
+	for commit, change := range f.merged {
+		if query == "status:merged commit:"+commit {
+			return []client.ChangeInfo{change}, nil
+		}
+	}
+	return nil, nil
+}
+
+type fakeCursors struct {
+	val map[string]EventCursor
+}
+
+func (f *fakeCursors) Current() map[string]EventCursor {
+	return f.val
+}
+
+func (f *fakeCursors) Update(val map[string]EventCursor) error {
+	f.val = val
+	return nil
+}
+
+func eventChange(number int, project string) client.ChangeInfo {
+	revision := fmt.Sprintf("rev-%d", number)
+	return client.ChangeInfo{
+		ID:              fmt.Sprintf("%s~master~I%d", project, number),
+		Number:          number,
+		Project:         project,
+		Branch:          "master",
+		Status:          client.New,
+		CurrentRevision: revision,
+		Updated:         stampNow,
+		Revisions: map[string]client.RevisionInfo{
+			revision: {
+				Ref:     fmt.Sprintf("refs/changes/%d/1", number),
+				Created: stampNow,
+				Files:   map[string]client.FileInfo{"docs/README.md": {}},
+			},
+		},
+	}
+}
+
+func patchSetCreated(createdOn, number int, project string) client.Event {
+	return client.Event{
+		Type:           client.PatchSetCreated,
+		Change:         client.ChangeInfo{ID: fmt.Sprintf("I%d", number), Project: project, Branch: "master"},
+		EventCreatedOn: createdOn,
+	}
+}
+
+func refUpdated(createdOn int, ref, newRev string) client.Event {
+	return client.Event{
+		Type:           client.RefUpdated,
+		RefUpdate:      client.RefUpdate{Project: "proj", RefName: ref, NewRev: newRev},
+		EventCreatedOn: createdOn,
+	}
+}
+
+// cursorOf returns the cursor after processing the events of a second.
+func cursorOf(t *testing.T, createdOn int, events ...client.Event) EventCursor {
+	processed := sets.NewString()
+	for _, event := range events {
+		digest, err := eventDigest(event)
+		if err != nil {
+			t.Fatalf("failed to digest event: %v", err)
+		}
+		processed.Insert(digest)
+	}
+	return EventCursor{CreatedOn: createdOn, Processed: processed.List()}
+}
+
+func TestSyncEvents(t *testing.T) {
+	merged := eventChange(3, "proj")
+	merged.Status = client.Merged
+	ownComment := client.Event{
+		Type:           client.CommentAdded,
+		Change:         client.ChangeInfo{ID: "I1", Project: "proj", Branch: "master"},
+		Author:         gerrit.AccountInfo{Username: "prow"},
+		EventCreatedOn: 101,
+	}
+
+	var testCases = []struct {
+		name    string
+		cursors map[string]EventCursor
+		events  []client.Event
+
+		expectedJobs    []string
+		expectedReports []string
+		expectedCursor  EventCursor
+	}{
+		{
+			name:           "new patchset triggers presubmits",
+			cursors:        map[string]EventCursor{eventsInstance: {CreatedOn: 100}},
+			events:         []client.Event{patchSetCreated(101, 1, "proj")},
+			expectedJobs:   []string{"presubmit"},
+			expectedCursor: cursorOf(t, 101, patchSetCreated(101, 1, "proj")),
+		},
+		{
+			name:    "processed events are skipped",
+			cursors: map[string]EventCursor{eventsInstance: cursorOf(t, 100, patchSetCreated(100, 2, "proj"))},
+			events: []client.Event{
+				patchSetCreated(99, 2, "proj"),
+				patchSetCreated(100, 2, "proj"),
+				patchSetCreated(100, 1, "proj"),
+			},
+			expectedJobs:   []string{"presubmit"},
+			expectedCursor: cursorOf(t, 100, patchSetCreated(100, 1, "proj"), patchSetCreated(100, 2, "proj")),
+		},
+		{
+			name:    "processed events are skipped when returned in another order",
+			cursors: map[string]EventCursor{eventsInstance: cursorOf(t, 100, patchSetCreated(100, 2, "proj"))},
+			events: []client.Event{
+				patchSetCreated(100, 1, "proj"),
+				patchSetCreated(100, 2, "proj"),
+			},
+			expectedJobs:   []string{"presubmit"},
+			expectedCursor: cursorOf(t, 100, patchSetCreated(100, 1, "proj"), patchSetCreated(100, 2, "proj")),
+		},
+		{
+			name:           "events of other projects are ignored",
+			cursors:        map[string]EventCursor{eventsInstance: {CreatedOn: 100}},
+			events:         []client.Event{patchSetCreated(101, 4, "other")},
+			expectedCursor: cursorOf(t, 101, patchSetCreated(101, 4, "other")),
+		},
+		{
+			name:           "own comments are ignored",
+			cursors:        map[string]EventCursor{eventsInstance: {CreatedOn: 100}},
+			events:         []client.Event{ownComment},
+			expectedCursor: cursorOf(t, 101, ownComment),
+		},
+		{
+			name:            "submit triggers postsubmits reporting to the change",
+			cursors:         map[string]EventCursor{eventsInstance: {CreatedOn: 100}},
+			events:          []client.Event{refUpdated(101, "refs/heads/master", "rev-3")},
+			expectedJobs:    []string{"postsubmit", "postsubmit-if-changed"},
+			expectedReports: []string{"rev-3", "rev-3"},
+			expectedCursor:  cursorOf(t, 101, refUpdated(101, "refs/heads/master", "rev-3")),
+		},
+		{
+			name:            "direct push triggers postsubmits that do not depend on changed files",
+			cursors:         map[string]EventCursor{eventsInstance: {CreatedOn: 100}},
+			events:          []client.Event{refUpdated(101, "master", "pushed")},
+			expectedJobs:    []string{"postsubmit"},
+			expectedReports: []string{""},
+			expectedCursor:  cursorOf(t, 101, refUpdated(101, "master", "pushed")),
+		},
+		{
+			name:    "updates of other refs are ignored",
+			cursors: map[string]EventCursor{eventsInstance: {CreatedOn: 100}},
+			events: []client.Event{
+				refUpdated(101, "refs/changes/01/1/1", "rev-1"),
+				refUpdated(101, "refs/heads/master", deletedRev),
+			},
+			expectedCursor: cursorOf(t, 101, refUpdated(101, "refs/changes/01/1/1", "rev-1"), refUpdated(101, "refs/heads/master", deletedRev)),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			if err := cfg.SetPresubmits(map[string][]config.Presubmit{
+				"gerrit/proj": {{JobBase: config.JobBase{Name: "presubmit"}, AlwaysRun: true}},
+			}); err != nil {
+				t.Fatalf("failed to set presubmits: %v", err)
+			}
+			if err := cfg.SetPostsubmits(map[string][]config.Postsubmit{
+				"gerrit/proj": {
+					{JobBase: config.JobBase{Name: "postsubmit"}},
+					{JobBase: config.JobBase{Name: "postsubmit-if-changed"}, RegexpChangeMatcher: config.RegexpChangeMatcher{RunIfChanged: "docs/"}},
+				},
+			}); err != nil {
+				t.Fatalf("failed to set postsubmits: %v", err)
+			}
+			gc := &fakeEventsClient{
+				events: tc.events,
+				changes: map[string]client.ChangeInfo{
+					"proj~master~I1": eventChange(1, "proj"),
+					"proj~master~I2": eventChange(2, "proj"),
+				},
+				merged: map[string]client.ChangeInfo{"rev-3": merged},
+			}
+			fakeProwJobClient := prowfake.NewSimpleClientset()
+			cursors := &fakeCursors{val: tc.cursors}
+			c := &Controller{
+				config:        func() *config.Config { return cfg },
+				prowJobClient: fakeProwJobClient.ProwV1().ProwJobs("prowjobs"),
+				gc:            gc,
+				tracker:       &fakeSync{val: client.LastSyncState{eventsInstance: {"proj": timeNow.Add(-time.Minute)}}},
+				projects:      map[string][]string{eventsInstance: {"proj"}},
+				cursors:       cursors,
+			}
+
+			if err := c.SyncEvents(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var jobs, reports []string
+			for _, action := range fakeProwJobClient.Fake.Actions() {
+				if action, ok := action.(clienttesting.CreateActionImpl); ok {
+					if pj, ok := action.Object.(*prowapi.ProwJob); ok {
+						jobs = append(jobs, pj.Spec.Job)
+						if pj.Spec.Type == prowapi.PostsubmitJob {
+							reports = append(reports, pj.Labels[client.GerritRevision])
+						}
+					}
+				}
+			}
+			if !reflect.DeepEqual(jobs, tc.expectedJobs) {
+				t.Errorf("expected jobs %v to be triggered, got %v", tc.expectedJobs, jobs)
+			}
+			if !reflect.DeepEqual(reports, tc.expectedReports) {
+				t.Errorf("expected postsubmits to report to %v, got %v", tc.expectedReports, reports)
+			}
+			if actual := cursors.val[eventsInstance]; !reflect.DeepEqual(actual, tc.expectedCursor) {
+				t.Errorf("expected cursor %v, got %v", tc.expectedCursor, actual)
+			}
+		})
+	}
+}
+
+func TestSyncEventsStartsFromNow(t *testing.T) {
+	gc := &fakeEventsClient{events: []client.Event{patchSetCreated(101, 1, "proj")}}
+	cursors := &fakeCursors{val: map[string]EventCursor{}}
+	c := &Controller{
+		config:   func() *config.Config { return &config.Config{} },
+		gc:       gc,
+		projects: map[string][]string{eventsInstance: {"proj"}},
+		cursors:  cursors,
+	}
+
+	before := time.Now().Unix()
+	if err := c.SyncEvents(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cursor := cursors.val[eventsInstance]; int64(cursor.CreatedOn) < before || len(cursor.Processed) != 0 {
+		t.Errorf("expected the cursor to start from now, got %v", cursor)
+	}
+}
+
+func TestProcessChangeOnce(t *testing.T) {
+	cfg := &config.Config{}
+	if err := cfg.SetPresubmits(map[string][]config.Presubmit{
+		"gerrit/proj": {{JobBase: config.JobBase{Name: "presubmit"}, AlwaysRun: true}},
+	}); err != nil {
+		t.Fatalf("failed to set presubmits: %v", err)
+	}
+	fakeProwJobClient := prowfake.NewSimpleClientset()
+	c := &Controller{
+		config:        func() *config.Config { return cfg },
+		prowJobClient: fakeProwJobClient.ProwV1().ProwJobs("prowjobs"),
+		gc:            &fgc{},
+		tracker:       &fakeSync{val: client.LastSyncState{eventsInstance: {"proj": timeNow.Add(-time.Minute)}}},
+	}
+
+	change := eventChange(1, "proj")
+	if err := c.ProcessChange(eventsInstance, change); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Seen again by Sync, e.g. after being processed from its event.
+	if err := c.ProcessChange(eventsInstance, change); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Updated without a new patchset or a new comment.
+	change.Updated = makeStamp(timeNow.Add(time.Minute))
+	if err := c.ProcessChange(eventsInstance, change); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if created := len(fakeProwJobClient.Fake.Actions()); created != 1 {
+		t.Errorf("expected the change to be tested once, got %d prowjobs", created)
+	}
+
+	c.forgetProcessed(client.LastSyncState{eventsInstance: {"proj": timeNow.Add(time.Minute)}})
+	if len(c.processed) != 0 {
+		t.Errorf("expected changes seen by Sync to be forgotten, got %v", c.processed)
+	}
+}
+
+func TestMergedChangePostsubmitsOnce(t *testing.T) {
+	merged := eventChange(3, "proj")
+	merged.Status = client.Merged
+
+	var testCases = []struct {
+		name        string
+		eventsFirst bool
+	}{
+		{
+			name: "change found by Sync before its event",
+		},
+		{
+			name:        "change found by Sync after its event",
+			eventsFirst: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			if err := cfg.SetPostsubmits(map[string][]config.Postsubmit{
+				"gerrit/proj": {{JobBase: config.JobBase{Name: "postsubmit"}}},
+			}); err != nil {
+				t.Fatalf("failed to set postsubmits: %v", err)
+			}
+			fakeProwJobClient := prowfake.NewSimpleClientset()
+			c := &Controller{
+				config:        func() *config.Config { return cfg },
+				prowJobClient: fakeProwJobClient.ProwV1().ProwJobs("prowjobs"),
+				gc: &fakeEventsClient{
+					events: []client.Event{refUpdated(101, "refs/heads/master", "rev-3")},
+					merged: map[string]client.ChangeInfo{"rev-3": merged},
+				},
+				tracker:  &fakeSync{val: client.LastSyncState{eventsInstance: {"proj": timeNow.Add(-time.Minute)}}},
+				projects: map[string][]string{eventsInstance: {"proj"}},
+				cursors:  &fakeCursors{val: map[string]EventCursor{eventsInstance: {CreatedOn: 100}}},
+			}
+
+			syncEvents := func() {
+				if err := c.SyncEvents(); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			processChange := func() {
+				if err := c.ProcessChange(eventsInstance, merged); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if tc.eventsFirst {
+				syncEvents()
+				processChange()
+			} else {
+				processChange()
+				syncEvents()
+			}
+
+			if created := len(fakeProwJobClient.Fake.Actions()); created != 1 {
+				t.Errorf("expected the postsubmit to run once, got %d prowjobs", created)
+			}
+
+			key := changeKey{instance: eventsInstance, id: merged.ID}
+			c.forgetProcessed(client.LastSyncState{})
+			if _, ok := c.postsubmitted[key]; !ok {
+				t.Error("expected the change to be remembered within the retention")
+			}
+			c.postsubmitted[key] = time.Now().Add(-2 * postsubmittedRetention)
+			c.forgetProcessed(client.LastSyncState{})
+			if _, ok := c.postsubmitted[key]; ok {
+				t.Error("expected the change to be forgotten after the retention")
+			}
+		})
+	}
+}
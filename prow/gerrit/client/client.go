@@ -45,6 +45,13 @@ const (
 	Merged = "MERGED"
 	// New status indicates a Gerrit change is new (ie pending)
 	New = "NEW"
+
+	// PatchSetCreated is the type of the event sent when a new patchset is uploaded
+	PatchSetCreated = "patchset-created"
+	// CommentAdded is the type of the event sent when a change is commented on
+	CommentAdded = "comment-added"
+	// RefUpdated is the type of the event sent when a ref is updated, e.g. by a submit or a direct push
+	RefUpdated = "ref-updated"
 )
 
 // ProjectsFlag is the flag type for gerrit projects when initializing a gerrit client
@@ -85,6 +92,12 @@ type gerritAccount interface {
 type gerritChange interface {
 	QueryChanges(opt *gerrit.QueryChangeOptions) (*[]gerrit.ChangeInfo, *gerrit.Response, error)
 	SetReview(changeID, revisionID string, input *gerrit.ReviewInput) (*gerrit.ReviewResult, *gerrit.Response, error)
+	SubmitChange(changeID string, input *gerrit.SubmitInput) (*gerrit.ChangeInfo, *gerrit.Response, error)
+	GetChange(changeID string, opt *gerrit.ChangeOptions) (*gerrit.ChangeInfo, *gerrit.Response, error)
+}
+
+type gerritEvents interface {
+	GetEvents(options *gerrit.EventsLogOptions) ([]gerrit.EventInfo, *gerrit.Response, [][]byte, error)
 }
 
 type gerritProjects interface {
@@ -100,6 +113,7 @@ type gerritInstanceHandler struct {
 	accountService gerritAccount
 	changeService  gerritChange
 	projectService gerritProjects
+	eventsService  gerritEvents
 }
 
 // Client holds a instance:handler map
@@ -118,6 +132,12 @@ type RevisionInfo = gerrit.RevisionInfo
 // FileInfo is a gerrit.FileInfo
 type FileInfo = gerrit.FileInfo
 
+// Event is a gerrit.EventInfo
+type Event = gerrit.EventInfo
+
+// RefUpdate is a gerrit.RefUpdate
+type RefUpdate = gerrit.RefUpdate
+
 // Map from instance name to repos to lastsync time for that repo
 type LastSyncState map[string]map[string]time.Time
 
@@ -151,6 +171,7 @@ func NewClient(instances map[string][]string) (*Client, error) {
 			accountService: gc.Accounts,
 			changeService:  gc.Changes,
 			projectService: gc.Projects,
+			eventsService:  gc.EventsLog,
 		}
 	}
 
@@ -218,6 +239,88 @@ func (c *Client) QueryChanges(lastState LastSyncState, rateLimit int) map[string
 	return result
 }
 
+// SearchChanges returns all changes of the instance that match the query,
+// querying rateLimit changes per API call.
+func (c *Client) SearchChanges(instance, query string, rateLimit int) ([]ChangeInfo, error) {
+	h, ok := c.handlers[instance]
+	if !ok {
+		return nil, fmt.Errorf("not activated gerrit instance: %s", instance)
+	}
+
+	opt := &gerrit.QueryChangeOptions{}
+	opt.Query = []string{query}
+	opt.AdditionalFields = []string{"CURRENT_REVISION", "CURRENT_COMMIT", "CURRENT_FILES", "DETAILED_ACCOUNTS"}
+	opt.Limit = rateLimit
+
+	var result []ChangeInfo
+	for {
+		opt.Start = len(result)
+		changes, _, err := h.changeService.QueryChanges(opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query gerrit changes: %v", err)
+		}
+		if changes == nil || len(*changes) == 0 {
+			return result, nil
+		}
+		result = append(result, *changes...)
+		if !(*changes)[len(*changes)-1].MoreChanges {
+			return result, nil
+		}
+	}
+}
+
+// GetChange returns the change with its current revision, commit, files and messages
+func (c *Client) GetChange(instance, id string) (*ChangeInfo, error) {
+	h, ok := c.handlers[instance]
+	if !ok {
+		return nil, fmt.Errorf("not activated gerrit instance: %s", instance)
+	}
+
+	opt := &gerrit.ChangeOptions{AdditionalFields: []string{"CURRENT_REVISION", "CURRENT_COMMIT", "CURRENT_FILES", "MESSAGES"}}
+	change, _, err := h.changeService.GetChange(id, opt)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get change %s: %v", id, err)
+	}
+
+	return change, nil
+}
+
+// GetEvents returns the events the events-log plugin of the instance
+// recorded since the given time, oldest first
+func (c *Client) GetEvents(instance string, since time.Time) ([]Event, error) {
+	h, ok := c.handlers[instance]
+	if !ok {
+		return nil, fmt.Errorf("not activated gerrit instance: %s", instance)
+	}
+
+	// The plugin may be older or newer than the instance, so events we
+	// cannot parse are skipped rather than stopping the stream.
+	events, _, failures, err := h.eventsService.GetEvents(&gerrit.EventsLogOptions{From: since, IgnoreUnmarshalErrors: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %v", err)
+	}
+	if len(failures) > 0 {
+		logrus.WithField("instance", instance).Warnf("Skipped %d events that could not be parsed", len(failures))
+	}
+
+	return events, nil
+}
+
+// SubmitChange submits the change and waits for it to be merged
+func (c *Client) SubmitChange(instance, id string) (*ChangeInfo, error) {
+	h, ok := c.handlers[instance]
+	if !ok {
+		return nil, fmt.Errorf("not activated gerrit instance: %s", instance)
+	}
+
+	change, _, err := h.changeService.SubmitChange(id, &gerrit.SubmitInput{WaitForMerge: true})
+	if err != nil {
+		return nil, fmt.Errorf("cannot submit change: %v", err)
+	}
+
+	return change, nil
+}
+
 // SetReview writes a review comment base on the change id + revision
 func (c *Client) SetReview(instance, id, revision, message string, labels map[string]string) error {
 	h, ok := c.handlers[instance]
@@ -17,6 +17,7 @@ limitations under the License.
 package client
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
 	"strings"
@@ -63,6 +64,25 @@ func (f *fgc) SetReview(changeID, revisionID string, input *gerrit.ReviewInput)
 	return nil, nil, nil
 }
 
+func (f *fgc) SubmitChange(changeID string, input *gerrit.SubmitInput) (*gerrit.ChangeInfo, *gerrit.Response, error) {
+	for _, change := range f.changes[f.instance] {
+		if change.ID == changeID {
+			change.Status = Merged
+			return &change, nil, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("change %s not found", changeID)
+}
+
+func (f *fgc) GetChange(changeID string, opt *gerrit.ChangeOptions) (*gerrit.ChangeInfo, *gerrit.Response, error) {
+	for _, change := range f.changes[f.instance] {
+		if change.ID == changeID {
+			return &change, nil, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("change %s not found", changeID)
+}
+
 func makeStamp(t time.Time) gerrit.Timestamp {
 	return gerrit.Timestamp{Time: t}
 }
@@ -557,3 +577,54 @@ func TestQueryChange(t *testing.T) {
 		}
 	}
 }
+
+// pagedChanges serves the changes in pages like gerrit does.
+type pagedChanges struct {
+	fgc
+	changes []gerrit.ChangeInfo
+	queries []string
+}
+
+func (p *pagedChanges) QueryChanges(opt *gerrit.QueryChangeOptions) (*[]gerrit.ChangeInfo, *gerrit.Response, error) {
+	p.queries = append(p.queries, opt.Query...)
+	page := []gerrit.ChangeInfo{}
+	for i := opt.Start; i < len(p.changes) && i < opt.Start+opt.Limit; i++ {
+		page = append(page, p.changes[i])
+	}
+	if len(page) > 0 && opt.Start+len(page) < len(p.changes) {
+		page[len(page)-1].MoreChanges = true
+	}
+	return &page, nil, nil
+}
+
+func TestSearchChanges(t *testing.T) {
+	var changes []gerrit.ChangeInfo
+	for i := 1; i <= 7; i++ {
+		changes = append(changes, gerrit.ChangeInfo{Number: i})
+	}
+	paged := &pagedChanges{changes: changes}
+	client := &Client{
+		handlers: map[string]*gerritInstanceHandler{
+			"foo": {instance: "foo", changeService: paged},
+		},
+	}
+
+	result, err := client.SearchChanges("foo", "status:open", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var numbers []int
+	for _, change := range result {
+		numbers = append(numbers, change.Number)
+	}
+	if expected := []int{1, 2, 3, 4, 5, 6, 7}; !reflect.DeepEqual(numbers, expected) {
+		t.Errorf("expected changes %v, got %v", expected, numbers)
+	}
+	if expected := []string{"status:open", "status:open", "status:open"}; !reflect.DeepEqual(paged.queries, expected) {
+		t.Errorf("expected queries %v, got %v", expected, paged.queries)
+	}
+
+	if _, err := client.SearchChanges("bar", "status:open", 3); err == nil {
+		t.Error("expected an error for an unknown instance")
+	}
+}
@@ -78,6 +78,7 @@ type HookClient interface {
 	EditOrgHook(org string, id int, req HookRequest) error
 	CreateOrgHook(org string, req HookRequest) (int, error)
 	CreateRepoHook(org, repo string, req HookRequest) (int, error)
+	DeleteRepoHook(org, repo string, id int) error
 }
 
 // CommentClient interface for comment related API actions
@@ -132,6 +133,9 @@ type CommitClient interface {
 	ListStatuses(org, repo, ref string) ([]Status, error)
 	GetSingleCommit(org, repo, SHA string) (SingleCommit, error)
 	GetCombinedStatus(org, repo, ref string) (*CombinedStatus, error)
+	ListCheckRuns(org, repo, ref string) ([]CheckRun, error)
+	CreateCheckRun(org, repo string, checkRun CheckRun) (*CheckRun, error)
+	UpdateCheckRun(org, repo string, id int64, checkRun CheckRun) (*CheckRun, error)
 	GetRef(org, repo, ref string) (string, error)
 	DeleteRef(org, repo, ref string) error
 }
@@ -153,10 +157,18 @@ type RepositoryClient interface {
 	GetFile(org, repo, filepath, commit string) ([]byte, error)
 	IsCollaborator(org, repo, user string) (bool, error)
 	ListCollaborators(org, repo string) ([]User, error)
+	ListDirectCollaborators(org, repo string) ([]User, error)
+	ListRepoInvitations(org, repo string) ([]RepoInvitation, error)
+	AddCollaborator(org, repo, user string, permission RepoPermissionLevel) error
+	RemoveCollaborator(org, repo, user string) error
 	CreateFork(owner, repo string) error
 	ListRepoTeams(org, repo string) ([]Team, error)
 	CreateRepo(owner string, isUser bool, repo RepoCreateRequest) (*FullRepo, error)
 	UpdateRepo(owner, name string, repo RepoUpdateRequest) (*FullRepo, error)
+	GetRepoTopics(owner, name string) ([]string, error)
+	ReplaceRepoTopics(owner, name string, topics []string) error
+	GetVulnerabilityAlerts(owner, name string) (bool, error)
+	SetVulnerabilityAlerts(owner, name string, enabled bool) error
 }
 
 // TeamClient interface for team related API actions
@@ -993,6 +1005,23 @@ func (c *client) CreateRepoHook(org, repo string, req HookRequest) (int, error)
 	return c.createHook(org, &repo, req)
 }
 
+// DeleteRepoHook deletes the hook from the repo
+// https://developer.github.com/v3/repos/hooks/#delete-a-hook
+func (c *client) DeleteRepoHook(org, repo string, id int) error {
+	durationLogger := c.log("DeleteRepoHook", org, repo, id)
+	defer durationLogger()
+
+	if c.fake || c.dry {
+		return nil
+	}
+	_, err := c.request(&request{
+		method:    http.MethodDelete,
+		path:      fmt.Sprintf("/repos/%s/%s/hooks/%d", org, repo, id),
+		exitCodes: []int{204},
+	}, nil)
+	return err
+}
+
 // GetOrg returns current metadata for the org
 //
 // https://developer.github.com/v3/orgs/#get-an-organization
@@ -1798,6 +1827,99 @@ func (c *client) UpdateRepo(owner, name string, repo RepoUpdateRequest) (*FullRe
 	return &retRepo, err
 }
 
+// GetRepoTopics returns the topics of a repo.
+//
+// See https://developer.github.com/v3/repos/#get-all-repository-topics
+func (c *client) GetRepoTopics(owner, name string) ([]string, error) {
+	durationLogger := c.log("GetRepoTopics", owner, name)
+	defer durationLogger()
+
+	if c.fake {
+		return nil, nil
+	}
+	var topics repoTopics
+	_, err := c.request(&request{
+		method: http.MethodGet,
+		// This accept header enables the topics preview.
+		// https://developer.github.com/changes/2016-09-14-topics-api-preview/
+		accept:    "application/vnd.github.mercy-preview+json",
+		path:      fmt.Sprintf("/repos/%s/%s/topics", owner, name),
+		exitCodes: []int{200},
+	}, &topics)
+	return topics.Names, err
+}
+
+// ReplaceRepoTopics replaces all topics of a repo with the given ones.
+//
+// See https://developer.github.com/v3/repos/#replace-all-repository-topics
+func (c *client) ReplaceRepoTopics(owner, name string, topics []string) error {
+	durationLogger := c.log("ReplaceRepoTopics", owner, name, topics)
+	defer durationLogger()
+
+	if c.fake || c.dry {
+		return nil
+	}
+	if topics == nil {
+		topics = []string{} // GitHub rejects a null list
+	}
+	_, err := c.request(&request{
+		method:      http.MethodPut,
+		accept:      "application/vnd.github.mercy-preview+json",
+		path:        fmt.Sprintf("/repos/%s/%s/topics", owner, name),
+		requestBody: &repoTopics{Names: topics},
+		exitCodes:   []int{200},
+	}, nil)
+	return err
+}
+
+// GetVulnerabilityAlerts returns whether vulnerability alerts are enabled for a repo.
+//
+// See https://developer.github.com/v3/repos/#check-if-vulnerability-alerts-are-enabled-for-a-repository
+func (c *client) GetVulnerabilityAlerts(owner, name string) (bool, error) {
+	durationLogger := c.log("GetVulnerabilityAlerts", owner, name)
+	defer durationLogger()
+
+	if c.fake {
+		return false, nil
+	}
+	code, err := c.request(&request{
+		method: http.MethodGet,
+		// This accept header enables the vulnerability alerts preview.
+		// https://developer.github.com/changes/2019-04-24-vulnerability-alerts/
+		accept:    "application/vnd.github.dorian-preview+json",
+		path:      fmt.Sprintf("/repos/%s/%s/vulnerability-alerts", owner, name),
+		exitCodes: []int{204, 404},
+	}, nil)
+	if err != nil {
+		return false, err
+	}
+	return code == 204, nil
+}
+
+// SetVulnerabilityAlerts enables or disables vulnerability alerts for a repo.
+//
+// See https://developer.github.com/v3/repos/#enable-vulnerability-alerts
+// and https://developer.github.com/v3/repos/#disable-vulnerability-alerts
+func (c *client) SetVulnerabilityAlerts(owner, name string, enabled bool) error {
+	durationLogger := c.log("SetVulnerabilityAlerts", owner, name, enabled)
+	defer durationLogger()
+
+	if c.fake || c.dry {
+		return nil
+	}
+	method := http.MethodPut
+	if !enabled {
+		method = http.MethodDelete
+	}
+	_, err := c.request(&request{
+		method:    method,
+		accept:    "application/vnd.github.dorian-preview+json",
+		path:      fmt.Sprintf("/repos/%s/%s/vulnerability-alerts", owner, name),
+		exitCodes: []int{204},
+	}, nil)
+	return err
+}
+
 // GetRepos returns all repos in an org.
 //
 // This call uses multiple API tokens when results are paginated.
@@ -2037,6 +2159,66 @@ func (c *client) GetCombinedStatus(org, repo, ref string) (*CombinedStatus, erro
 	return &combinedStatus, err
 }
 
+// acceptChecksPreview is required by the checks API during its preview.
+const acceptChecksPreview = "application/vnd.github.antiope-preview+json"
+
+// ListCheckRuns returns the check runs for a given ref.
+//
+// See https://developer.github.com/v3/checks/runs/#list-check-runs-for-a-specific-ref
+func (c *client) ListCheckRuns(org, repo, ref string) ([]CheckRun, error) {
+	durationLogger := c.log("ListCheckRuns", org, repo, ref)
+	defer durationLogger()
+
+	var checkRuns []CheckRun
+	err := c.readPaginatedResults(
+		fmt.Sprintf("/repos/%s/%s/commits/%s/check-runs", org, repo, ref),
+		acceptChecksPreview,
+		func() interface{} {
+			return &CheckRunList{}
+		},
+		func(obj interface{}) {
+			checkRuns = append(checkRuns, obj.(*CheckRunList).CheckRuns...)
+		},
+	)
+	return checkRuns, err
+}
+
+// CreateCheckRun creates a check run on the HeadSHA of the check run.
+//
+// See https://developer.github.com/v3/checks/runs/#create-a-check-run
+func (c *client) CreateCheckRun(org, repo string, checkRun CheckRun) (*CheckRun, error) {
+	durationLogger := c.log("CreateCheckRun", org, repo, checkRun.Name, checkRun.HeadSHA)
+	defer durationLogger()
+
+	var created CheckRun
+	_, err := c.request(&request{
+		method:      http.MethodPost,
+		path:        fmt.Sprintf("/repos/%s/%s/check-runs", org, repo),
+		accept:      acceptChecksPreview,
+		requestBody: &checkRun,
+		exitCodes:   []int{201},
+	}, &created)
+	return &created, err
+}
+
+// UpdateCheckRun updates the check run with the given ID.
+//
+// See https://developer.github.com/v3/checks/runs/#update-a-check-run
+func (c *client) UpdateCheckRun(org, repo string, id int64, checkRun CheckRun) (*CheckRun, error) {
+	durationLogger := c.log("UpdateCheckRun", org, repo, id)
+	defer durationLogger()
+
+	var updated CheckRun
+	_, err := c.request(&request{
+		method:      http.MethodPatch,
+		path:        fmt.Sprintf("/repos/%s/%s/check-runs/%d", org, repo, id),
+		accept:      acceptChecksPreview,
+		requestBody: &checkRun,
+		exitCodes:   []int{200},
+	}, &updated)
+	return &updated, err
+}
+
 // getLabels is a helper function that retrieves a paginated list of labels from a github URI path.
 func (c *client) getLabels(path string) ([]Label, error) {
 	var labels []Label
@@ -3052,6 +3234,111 @@ func (c *client) ListCollaborators(org, repo string) ([]User, error) {
 	return users, nil
 }
 
+// ListDirectCollaborators gets a list of the users who have been given access
+// to a repo directly, rather than through the org or a team, along with their
+// permissions.
+//
+// See https://developer.github.com/v3/repos/collaborators/#list-collaborators
+func (c *client) ListDirectCollaborators(org, repo string) ([]User, error) {
+	durationLogger := c.log("ListDirectCollaborators", org, repo)
+	defer durationLogger()
+
+	if c.fake {
+		return nil, nil
+	}
+	path := fmt.Sprintf("/repos/%s/%s/collaborators", org, repo)
+	var users []User
+	err := c.readPaginatedResultsWithValues(
+		path,
+		url.Values{
+			"per_page":    []string{"100"},
+			"affiliation": []string{"direct"},
+		},
+		acceptNone,
+		func() interface{} {
+			return &[]User{}
+		},
+		func(obj interface{}) {
+			users = append(users, *(obj.(*[]User))...)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ListRepoInvitations lists the pending invitations to collaborate on a repo.
+//
+// See https://developer.github.com/v3/repos/invitations/#list-invitations-for-a-repository
+func (c *client) ListRepoInvitations(org, repo string) ([]RepoInvitation, error) {
+	durationLogger := c.log("ListRepoInvitations", org, repo)
+	defer durationLogger()
+
+	if c.fake {
+		return nil, nil
+	}
+	path := fmt.Sprintf("/repos/%s/%s/invitations", org, repo)
+	var invitations []RepoInvitation
+	err := c.readPaginatedResults(
+		path,
+		acceptNone,
+		func() interface{} {
+			return &[]RepoInvitation{}
+		},
+		func(obj interface{}) {
+			invitations = append(invitations, *(obj.(*[]RepoInvitation))...)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// AddCollaborator invites the user to collaborate on the repo with the given
+// permission, or updates the permission of an existing collaborator.
+//
+// See https://developer.github.com/v3/repos/collaborators/#add-user-as-a-collaborator
+func (c *client) AddCollaborator(org, repo, user string, permission RepoPermissionLevel) error {
+	durationLogger := c.log("AddCollaborator", org, repo, user, permission)
+	defer durationLogger()
+
+	if c.fake || c.dry {
+		return nil
+	}
+	data := struct {
+		Permission TeamPermission `json:"permission"`
+	}{
+		Permission: TeamPermissionFromLevel(permission),
+	}
+	_, err := c.request(&request{
+		method:      http.MethodPut,
+		path:        fmt.Sprintf("/repos/%s/%s/collaborators/%s", org, repo, user),
+		requestBody: &data,
+		exitCodes:   []int{201, 204},
+	}, nil)
+	return err
+}
+
+// RemoveCollaborator removes the user as a collaborator on the repo.
+//
+// See https://developer.github.com/v3/repos/collaborators/#remove-user-as-a-collaborator
+func (c *client) RemoveCollaborator(org, repo, user string) error {
+	durationLogger := c.log("RemoveCollaborator", org, repo, user)
+	defer durationLogger()
+
+	if c.fake || c.dry {
+		return nil
+	}
+	_, err := c.request(&request{
+		method:    http.MethodDelete,
+		path:      fmt.Sprintf("/repos/%s/%s/collaborators/%s", org, repo, user),
+		exitCodes: []int{204},
+	}, nil)
+	return err
+}
+
 // CreateFork creates a fork for the authenticated user. Forking a repository
 // happens asynchronously. Therefore, we may have to wait a short period before
 // accessing the git objects. If this takes longer than 5 minutes, GitHub
@@ -458,6 +458,77 @@ func TestCreateStatus(t *testing.T) {
 	}
 }
 
+func TestListCheckRuns(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.Header.Get("Accept") != acceptChecksPreview {
+			t.Errorf("Bad accept header: %s", r.Header.Get("Accept"))
+		}
+		var list CheckRunList
+		if r.URL.Path == "/repos/k8s/kuber/commits/abcdef/check-runs" {
+			list = CheckRunList{Total: 2, CheckRuns: []CheckRun{{ID: 1, Name: "build"}}}
+			w.Header().Set("Link", fmt.Sprintf(`<blorp>; rel="first", <https://%s/someotherpath>; rel="next"`, r.Host))
+		} else if r.URL.Path == "/someotherpath" {
+			list = CheckRunList{Total: 2, CheckRuns: []CheckRun{{ID: 2, Name: "test"}}}
+		} else {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := json.Marshal(list)
+		if err != nil {
+			t.Fatalf("Didn't expect error: %v", err)
+		}
+		fmt.Fprint(w, string(b))
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	checkRuns, err := c.ListCheckRuns("k8s", "kuber", "abcdef")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if len(checkRuns) != 2 {
+		t.Errorf("Expected two check runs, found %d: %v", len(checkRuns), checkRuns)
+	} else if checkRuns[0].ID != 1 || checkRuns[1].ID != 2 {
+		t.Errorf("Wrong check run IDs: %v", checkRuns)
+	}
+}
+
+func TestCreateCheckRun(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/check-runs" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var checkRun CheckRun
+		if err := json.Unmarshal(b, &checkRun); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		} else if checkRun.Name != "build" || checkRun.HeadSHA != "abcdef" {
+			t.Errorf("Wrong check run: %v", checkRun)
+		}
+		checkRun.ID = 42
+		b, err = json.Marshal(checkRun)
+		if err != nil {
+			t.Fatalf("Didn't expect error: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, string(b))
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	created, err := c.CreateCheckRun("k8s", "kuber", CheckRun{Name: "build", HeadSHA: "abcdef"})
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if created.ID != 42 {
+		t.Errorf("Expected the created check run, got %v", created)
+	}
+}
+
 func TestListIssues(t *testing.T) {
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -2214,3 +2285,93 @@ func TestUpdateRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestAddCollaborator(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/org/repo/collaborators/user" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		} else if body["permission"] != "push" {
+			t.Errorf("Expected push permission, got %q", body["permission"])
+		}
+		http.Error(w, "204 No Content", http.StatusNoContent)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.AddCollaborator("org", "repo", "user", Write); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestReplaceRepoTopics(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/org/repo/topics" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		if string(b) != `{"names":[]}` {
+			t.Errorf("Expected an empty list of topics, got %s", b)
+		}
+		w.Write(b)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.ReplaceRepoTopics("org", "repo", nil); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestGetVulnerabilityAlerts(t *testing.T) {
+	for _, status := range []int{http.StatusNoContent, http.StatusNotFound} {
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				t.Errorf("Bad method: %s", r.Method)
+			}
+			if r.URL.Path != "/repos/org/repo/vulnerability-alerts" {
+				t.Errorf("Bad request path: %s", r.URL.Path)
+			}
+			http.Error(w, http.StatusText(status), status)
+		}))
+		c := getClient(ts.URL)
+		enabled, err := c.GetVulnerabilityAlerts("org", "repo")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		} else if expected := status == http.StatusNoContent; enabled != expected {
+			t.Errorf("Expected enabled=%t for status %d, got %t", expected, status, enabled)
+		}
+		ts.Close()
+	}
+}
+
+func TestDeleteRepoHook(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/org/repo/hooks/5" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		http.Error(w, "204 No Content", http.StatusNoContent)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.DeleteRepoHook("org", "repo", 5); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
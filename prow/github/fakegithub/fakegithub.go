@@ -49,8 +49,10 @@ type FakeClient struct {
 	Reviews             map[int][]github.Review
 	CombinedStatuses    map[string]*github.CombinedStatus
 	CreatedStatuses     map[string][]github.Status
-	IssueEvents         map[int][]github.ListedIssueEvent
-	Commits             map[string]github.SingleCommit
+	// CheckRuns maps commits to their check runs
+	CheckRuns   map[string][]github.CheckRun
+	IssueEvents map[int][]github.ListedIssueEvent
+	Commits     map[string]github.SingleCommit
 
 	//All Labels That Exist In The Repo
 	RepoLabelsExisting []string
@@ -306,6 +308,58 @@ func (f *FakeClient) GetCombinedStatus(owner, repo, ref string) (*github.Combine
 	return f.CombinedStatuses[ref], nil
 }
 
+// ListCheckRuns returns the check runs on a commit.
+func (f *FakeClient) ListCheckRuns(org, repo, ref string) ([]github.CheckRun, error) {
+	return f.CheckRuns[ref], nil
+}
+
+// CreateCheckRun adds a check run to its head commit.
+func (f *FakeClient) CreateCheckRun(org, repo string, checkRun github.CheckRun) (*github.CheckRun, error) {
+	if f.CheckRuns == nil {
+		f.CheckRuns = map[string][]github.CheckRun{}
+	}
+	var id int64
+	for _, checkRuns := range f.CheckRuns {
+		id += int64(len(checkRuns))
+	}
+	checkRun.ID = id + 1
+	f.CheckRuns[checkRun.HeadSHA] = append(f.CheckRuns[checkRun.HeadSHA], checkRun)
+	return &checkRun, nil
+}
+
+// UpdateCheckRun updates the fields of a check run that are set.
+func (f *FakeClient) UpdateCheckRun(org, repo string, id int64, checkRun github.CheckRun) (*github.CheckRun, error) {
+	for sha, checkRuns := range f.CheckRuns {
+		for i := range checkRuns {
+			if checkRuns[i].ID != id {
+				continue
+			}
+			existing := &f.CheckRuns[sha][i]
+			if checkRun.Status != "" {
+				existing.Status = checkRun.Status
+			}
+			if checkRun.Conclusion != "" {
+				existing.Conclusion = checkRun.Conclusion
+			}
+			if checkRun.DetailsURL != "" {
+				existing.DetailsURL = checkRun.DetailsURL
+			}
+			if checkRun.CompletedAt != nil {
+				existing.CompletedAt = checkRun.CompletedAt
+			}
+			if checkRun.Output != nil {
+				existing.Output = checkRun.Output
+			}
+			if checkRun.Actions != nil {
+				existing.Actions = checkRun.Actions
+			}
+			updated := *existing
+			return &updated, nil
+		}
+	}
+	return nil, fmt.Errorf("check run %d not found", id)
+}
+
 // GetRepoLabels gets labels in a repo.
 func (f *FakeClient) GetRepoLabels(owner, repo string) ([]github.Label, error) {
 	la := []github.Label{}
@@ -98,3 +98,16 @@ func PermissionsFromLevel(permission RepoPermissionLevel) RepoPermissions {
 		return RepoPermissions{}
 	}
 }
+
+// TeamPermissionFromLevel adapts a repo permission level to the
+// permission names used when granting access to a repo.
+func TeamPermissionFromLevel(permission RepoPermissionLevel) TeamPermission {
+	switch permission {
+	case Admin:
+		return RepoAdmin
+	case Write:
+		return RepoPush
+	default:
+		return RepoPull
+	}
+}
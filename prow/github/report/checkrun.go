@@ -0,0 +1,234 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/github"
+)
+
+// RerunActionIdentifier identifies the action of a check run that asks to
+// run the job again.
+const RerunActionIdentifier = "rerun"
+
+const (
+	// maxAnnotations is the number of annotations GitHub accepts per request.
+	maxAnnotations = 50
+	// maxListedFailures is the number of failed tests listed in a summary.
+	maxListedFailures = 20
+	// maxAnnotationMessage keeps annotations readable, GitHub accepts 64KB.
+	maxAnnotationMessage = 4096
+)
+
+// failureLocationRe matches a relative path with line number in a failure
+// message, like "pkg/foo/foo_test.go:42".
+var failureLocationRe = regexp.MustCompile(`(?:^|[\s(])((?:[\w.-]+/)*[\w-]+\.[a-zA-Z]+):(\d+)`)
+
+// CheckRunClient provides a client interface to report job status updates
+// through GitHub check runs and comments.
+type CheckRunClient interface {
+	GitHubClient
+	ListCheckRuns(org, repo, ref string) ([]github.CheckRun, error)
+	CreateCheckRun(org, repo string, checkRun github.CheckRun) (*github.CheckRun, error)
+	UpdateCheckRun(org, repo string, id int64, checkRun github.CheckRun) (*github.CheckRun, error)
+}
+
+// TestFailure is a failed test of a job.
+type TestFailure struct {
+	Name    string
+	Message string
+}
+
+// FailureGetter returns the failed tests of a completed job.
+type FailureGetter func(pj prowapi.ProwJob) ([]TestFailure, error)
+
+// ReportCheckRun is like Report, but reports the job as a check run instead
+// of a commit status. The failed tests returned by getFailures, if set, are
+// listed in the summary of the check run and annotate the lines they point to.
+func ReportCheckRun(ghc CheckRunClient, reportTemplate *template.Template, pj prowapi.ProwJob, validTypes []prowapi.ProwJobType, getFailures FailureGetter) error {
+	if ghc == nil {
+		return fmt.Errorf("trying to report pj %s, but found empty github client", pj.ObjectMeta.Name)
+	}
+
+	if !ShouldReport(pj, validTypes) {
+		return nil
+	}
+
+	// we are not reporting for batch jobs, we can consider support that in the future
+	if len(pj.Spec.Refs.Pulls) > 1 {
+		return nil
+	}
+
+	var failures []TestFailure
+	if getFailures != nil && pj.Complete() && pj.Status.State != prowapi.SuccessState {
+		var err error
+		if failures, err = getFailures(pj); err != nil {
+			// The job is still worth reporting without its failed tests.
+			logrus.WithError(err).WithField("prowjob", pj.Name).Warn("Failed to get the failed tests of the job.")
+		}
+	}
+
+	if err := reportCheckRun(ghc, pj, failures); err != nil {
+		return fmt.Errorf("error setting check run: %v", err)
+	}
+	return reportComment(ghc, reportTemplate, pj)
+}
+
+// reportCheckRun creates the check run of the job or updates it if it
+// already exists.
+func reportCheckRun(ghc CheckRunClient, pj prowapi.ProwJob, failures []TestFailure) error {
+	refs := pj.Spec.Refs
+	sha := refs.BaseSHA
+	if len(refs.Pulls) > 0 {
+		sha = refs.Pulls[0].SHA
+	}
+	checkRun := checkRunFor(pj, failures)
+	existing, err := ghc.ListCheckRuns(refs.Org, refs.Repo, sha)
+	if err != nil {
+		return err
+	}
+	for _, run := range existing {
+		// Every run of the job gets a check run of its own.
+		if run.Name == checkRun.Name && run.ExternalID == checkRun.ExternalID {
+			_, err := ghc.UpdateCheckRun(refs.Org, refs.Repo, run.ID, checkRun)
+			return err
+		}
+	}
+	checkRun.HeadSHA = sha
+	_, err = ghc.CreateCheckRun(refs.Org, refs.Repo, checkRun)
+	return err
+}
+
+func checkRunFor(pj prowapi.ProwJob, failures []TestFailure) github.CheckRun {
+	title := string(pj.Status.State)
+	if pj.Status.Description != "" {
+		title = truncate(pj.Status.Description)
+	}
+	startTime := pj.Status.StartTime.Time
+	checkRun := github.CheckRun{
+		Name:       pj.Spec.Context,
+		DetailsURL: pj.Status.URL,
+		ExternalID: pj.Name,
+		Status:     github.CheckRunStatusInProgress,
+		StartedAt:  &startTime,
+		Output: &github.CheckRunOutput{
+			Title:   title,
+			Summary: checkRunSummary(pj, failures),
+		},
+	}
+	if pj.Status.State == prowapi.TriggeredState {
+		checkRun.Status = github.CheckRunStatusQueued
+	}
+	if !pj.Complete() {
+		return checkRun
+	}
+
+	completionTime := pj.Status.CompletionTime.Time
+	checkRun.Status = github.CheckRunStatusCompleted
+	checkRun.CompletedAt = &completionTime
+	checkRun.Output.Annotations = annotationsFor(failures)
+	switch pj.Status.State {
+	case prowapi.SuccessState:
+		checkRun.Conclusion = github.CheckRunConclusionSuccess
+	case prowapi.AbortedState:
+		checkRun.Conclusion = github.CheckRunConclusionCancelled
+	default:
+		checkRun.Conclusion = github.CheckRunConclusionFailure
+	}
+	if pj.Spec.Type == prowapi.PresubmitJob && pj.Status.State != prowapi.SuccessState {
+		checkRun.Actions = []github.CheckRunAction{{
+			Label:       "Re-run",
+			Description: "Run this job again.",
+			Identifier:  RerunActionIdentifier,
+		}}
+	}
+	return checkRun
+}
+
+// checkRunSummary describes the job and lists its failed tests in markdown.
+func checkRunSummary(pj prowapi.ProwJob, failures []TestFailure) string {
+	duration := "-"
+	if pj.Complete() {
+		duration = pj.Status.CompletionTime.Sub(pj.Status.StartTime.Time).Round(time.Second).String()
+	}
+	lines := []string{
+		"Job | State | Duration",
+		"--- | --- | ---",
+		fmt.Sprintf("`%s` | %s | %s", pj.Spec.Job, pj.Status.State, duration),
+	}
+	if pj.Spec.RerunCommand != "" {
+		lines = append(lines, "", fmt.Sprintf("Say `%s` to run the job again.", pj.Spec.RerunCommand))
+	}
+	if len(failures) == 0 {
+		return strings.Join(lines, "\n")
+	}
+	plural := ""
+	if len(failures) > 1 {
+		plural = "s"
+	}
+	lines = append(lines, "", fmt.Sprintf("%d test%s **failed**:", len(failures), plural), "")
+	for i, failure := range failures {
+		if i == maxListedFailures {
+			lines = append(lines, fmt.Sprintf("- and %d more", len(failures)-maxListedFailures))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("- `%s`", failure.Name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// annotationsFor annotates the lines that failed tests point to in their
+// messages. Failures that don't point to a line are only listed in the
+// summary.
+func annotationsFor(failures []TestFailure) []github.CheckRunAnnotation {
+	var annotations []github.CheckRunAnnotation
+	for _, failure := range failures {
+		if len(annotations) == maxAnnotations {
+			break
+		}
+		match := failureLocationRe.FindStringSubmatch(failure.Message)
+		if match == nil {
+			continue
+		}
+		line, err := strconv.Atoi(match[2])
+		if err != nil || line < 1 {
+			continue
+		}
+		message := failure.Message
+		if len(message) > maxAnnotationMessage {
+			message = message[:maxAnnotationMessage-len(elide)] + elide
+		}
+		annotations = append(annotations, github.CheckRunAnnotation{
+			Path:            strings.TrimPrefix(match[1], "./"),
+			StartLine:       line,
+			EndLine:         line,
+			AnnotationLevel: github.CheckRunAnnotationFailure,
+			Title:           failure.Name,
+			Message:         message,
+		})
+	}
+	return annotations
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func checkRunJob(name string, state prowapi.ProwJobState) prowapi.ProwJob {
+	start := metav1.NewTime(time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC))
+	pj := prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: prowapi.ProwJobSpec{
+			Job:          "pull-test-infra-bazel",
+			Type:         prowapi.PresubmitJob,
+			Context:      "pull-test-infra-bazel",
+			RerunCommand: "/test pull-test-infra-bazel",
+			Report:       true,
+			Refs: &prowapi.Refs{
+				Org:  "k8s",
+				Repo: "test-infra",
+				Pulls: []prowapi.Pull{{
+					Author: "me",
+					Number: 1,
+					SHA:    "abcdef",
+				}},
+			},
+		},
+		Status: prowapi.ProwJobStatus{
+			State:     state,
+			StartTime: start,
+			URL:       "https://prow.k8s.io/view/gs/bucket/1",
+		},
+	}
+	if state != prowapi.TriggeredState && state != prowapi.PendingState {
+		completion := metav1.NewTime(start.Add(90 * time.Second))
+		pj.Status.CompletionTime = &completion
+	}
+	return pj
+}
+
+func TestReportCheckRun(t *testing.T) {
+	validTypes := []prowapi.ProwJobType{prowapi.PresubmitJob}
+	ghc := &fakegithub.FakeClient{IssueComments: map[int][]github.IssueComment{}}
+	failures := []TestFailure{{Name: "TestFoo", Message: "    prow/foo/foo_test.go:42: expected bar"}}
+	getFailures := func(pj prowapi.ProwJob) ([]TestFailure, error) {
+		return failures, nil
+	}
+
+	if err := ReportCheckRun(ghc, nil, checkRunJob("job-1", prowapi.PendingState), validTypes, getFailures); err != nil {
+		t.Fatalf("failed to report pending job: %v", err)
+	}
+	if err := ReportCheckRun(ghc, nil, checkRunJob("job-1", prowapi.FailureState), validTypes, getFailures); err != nil {
+		t.Fatalf("failed to report failed job: %v", err)
+	}
+	checkRuns := ghc.CheckRuns["abcdef"]
+	if len(checkRuns) != 1 {
+		t.Fatalf("expected the check run of the job to be updated, got %d check runs", len(checkRuns))
+	}
+	checkRun := checkRuns[0]
+	if checkRun.Status != github.CheckRunStatusCompleted || checkRun.Conclusion != github.CheckRunConclusionFailure {
+		t.Errorf("expected a failed check run, got status %q and conclusion %q", checkRun.Status, checkRun.Conclusion)
+	}
+	expectedAnnotations := []github.CheckRunAnnotation{{
+		Path:            "prow/foo/foo_test.go",
+		StartLine:       42,
+		EndLine:         42,
+		AnnotationLevel: github.CheckRunAnnotationFailure,
+		Title:           "TestFoo",
+		Message:         failures[0].Message,
+	}}
+	if !reflect.DeepEqual(checkRun.Output.Annotations, expectedAnnotations) {
+		t.Errorf("expected annotations %v, got %v", expectedAnnotations, checkRun.Output.Annotations)
+	}
+	if len(checkRun.Actions) != 1 || checkRun.Actions[0].Identifier != RerunActionIdentifier {
+		t.Errorf("expected a re-run action, got %v", checkRun.Actions)
+	}
+	if len(ghc.IssueCommentsAdded) != 1 {
+		t.Errorf("expected a comment about the failed job, got %v", ghc.IssueCommentsAdded)
+	}
+	if len(ghc.CreatedStatuses) != 0 {
+		t.Errorf("expected no statuses, got %v", ghc.CreatedStatuses)
+	}
+
+	// A job that runs again gets a check run of its own.
+	if err := ReportCheckRun(ghc, nil, checkRunJob("job-2", prowapi.SuccessState), validTypes, nil); err != nil {
+		t.Fatalf("failed to report job: %v", err)
+	}
+	if checkRuns := ghc.CheckRuns["abcdef"]; len(checkRuns) != 2 || checkRuns[1].Conclusion != github.CheckRunConclusionSuccess {
+		t.Errorf("expected a second, successful check run, got %v", checkRuns)
+	}
+}
+
+func TestReportCheckRunWithoutFailures(t *testing.T) {
+	ghc := &fakegithub.FakeClient{}
+	getFailures := func(pj prowapi.ProwJob) ([]TestFailure, error) {
+		return nil, errors.New("no junit")
+	}
+	if err := ReportCheckRun(ghc, nil, checkRunJob("job", prowapi.ErrorState), []prowapi.ProwJobType{prowapi.PresubmitJob}, getFailures); err != nil {
+		t.Fatalf("expected the job to be reported without its failures, got %v", err)
+	}
+	if checkRuns := ghc.CheckRuns["abcdef"]; len(checkRuns) != 1 || checkRuns[0].Conclusion != github.CheckRunConclusionFailure {
+		t.Errorf("expected a failed check run, got %v", checkRuns)
+	}
+}
+
+func TestCheckRunFor(t *testing.T) {
+	var testcases = []struct {
+		name               string
+		state              prowapi.ProwJobState
+		expectedStatus     string
+		expectedConclusion string
+		expectedActions    int
+	}{
+		{
+			name:           "triggered job is queued",
+			state:          prowapi.TriggeredState,
+			expectedStatus: github.CheckRunStatusQueued,
+		},
+		{
+			name:           "pending job is in progress",
+			state:          prowapi.PendingState,
+			expectedStatus: github.CheckRunStatusInProgress,
+		},
+		{
+			name:               "successful job can't be re-run",
+			state:              prowapi.SuccessState,
+			expectedStatus:     github.CheckRunStatusCompleted,
+			expectedConclusion: github.CheckRunConclusionSuccess,
+		},
+		{
+			name:               "aborted job is cancelled",
+			state:              prowapi.AbortedState,
+			expectedStatus:     github.CheckRunStatusCompleted,
+			expectedConclusion: github.CheckRunConclusionCancelled,
+			expectedActions:    1,
+		},
+		{
+			name:               "errored job failed",
+			state:              prowapi.ErrorState,
+			expectedStatus:     github.CheckRunStatusCompleted,
+			expectedConclusion: github.CheckRunConclusionFailure,
+			expectedActions:    1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkRun := checkRunFor(checkRunJob("job", tc.state), nil)
+			if checkRun.Status != tc.expectedStatus {
+				t.Errorf("expected status %q, got %q", tc.expectedStatus, checkRun.Status)
+			}
+			if checkRun.Conclusion != tc.expectedConclusion {
+				t.Errorf("expected conclusion %q, got %q", tc.expectedConclusion, checkRun.Conclusion)
+			}
+			if len(checkRun.Actions) != tc.expectedActions {
+				t.Errorf("expected %d actions, got %v", tc.expectedActions, checkRun.Actions)
+			}
+			if (checkRun.CompletedAt != nil) != (tc.expectedConclusion != "") {
+				t.Errorf("expected completion time to be set only for completed jobs, got %v", checkRun.CompletedAt)
+			}
+		})
+	}
+}
+
+func TestCheckRunSummary(t *testing.T) {
+	var failures []TestFailure
+	for i := 0; i < maxListedFailures+5; i++ {
+		failures = append(failures, TestFailure{Name: "TestFoo"})
+	}
+	summary := checkRunSummary(checkRunJob("job", prowapi.FailureState), failures)
+	for _, expected := range []string{
+		"`pull-test-infra-bazel` | failure | 1m30s",
+		"Say `/test pull-test-infra-bazel` to run the job again.",
+		"25 tests **failed**:",
+		"- and 5 more",
+	} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("expected summary to contain %q, got:\n%s", expected, summary)
+		}
+	}
+}
+
+func TestAnnotationsFor(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		message  string
+		expected []github.CheckRunAnnotation
+	}{
+		{
+			name:    "go test failure",
+			message: "    foo_test.go:12: expected 1, got 2",
+			expected: []github.CheckRunAnnotation{{
+				Path: "foo_test.go", StartLine: 12, EndLine: 12,
+			}},
+		},
+		{
+			name:    "relative path",
+			message: "error at ./hack/verify.sh:3",
+			expected: []github.CheckRunAnnotation{{
+				Path: "hack/verify.sh", StartLine: 3, EndLine: 3,
+			}},
+		},
+		{
+			name:    "absolute paths and URLs are not annotated",
+			message: "panic at /go/src/foo/foo.go:12, see https://example.com:8080",
+		},
+		{
+			name:    "no location",
+			message: "timed out",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := range tc.expected {
+				tc.expected[i].AnnotationLevel = github.CheckRunAnnotationFailure
+				tc.expected[i].Title = "TestFoo"
+				tc.expected[i].Message = tc.message
+			}
+			annotations := annotationsFor([]TestFailure{{Name: "TestFoo", Message: tc.message}})
+			if !reflect.DeepEqual(annotations, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, annotations)
+			}
+		})
+	}
+}
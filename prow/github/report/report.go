@@ -146,7 +146,13 @@ func Report(ghc GitHubClient, reportTemplate *template.Template, pj prowapi.Prow
 	if err := reportStatus(ghc, pj); err != nil {
 		return fmt.Errorf("error setting status: %v", err)
 	}
+	return reportComment(ghc, reportTemplate, pj)
+}
 
+// reportComment creates, updates or deletes the comment that lists the
+// failed jobs of the PR.
+func reportComment(ghc GitHubClient, reportTemplate *template.Template, pj prowapi.ProwJob) error {
+	refs := pj.Spec.Refs
 	// Report manually aborted Jenkins jobs and jobs with invalid pod specs alongside
 	// test successes/failures.
 	if !pj.Complete() {
@@ -154,6 +154,120 @@ type CombinedStatus struct {
 	State    string   `json:"state"`
 }
 
+// These are possible Status values for a check run.
+const (
+	CheckRunStatusQueued     = "queued"
+	CheckRunStatusInProgress = "in_progress"
+	CheckRunStatusCompleted  = "completed"
+)
+
+// These are possible Conclusion values for a completed check run.
+const (
+	CheckRunConclusionSuccess        = "success"
+	CheckRunConclusionFailure        = "failure"
+	CheckRunConclusionNeutral        = "neutral"
+	CheckRunConclusionCancelled      = "cancelled"
+	CheckRunConclusionSkipped        = "skipped"
+	CheckRunConclusionTimedOut       = "timed_out"
+	CheckRunConclusionActionRequired = "action_required"
+)
+
+// These are possible AnnotationLevel values for a check run annotation.
+const (
+	CheckRunAnnotationNotice  = "notice"
+	CheckRunAnnotationWarning = "warning"
+	CheckRunAnnotationFailure = "failure"
+)
+
+// CheckRun is the result of a check on a commit.
+//
+// See https://developer.github.com/v3/checks/runs/
+type CheckRun struct {
+	ID          int64            `json:"id,omitempty"`
+	Name        string           `json:"name"`
+	HeadSHA     string           `json:"head_sha,omitempty"`
+	DetailsURL  string           `json:"details_url,omitempty"`
+	ExternalID  string           `json:"external_id,omitempty"`
+	Status      string           `json:"status,omitempty"`
+	Conclusion  string           `json:"conclusion,omitempty"`
+	StartedAt   *time.Time       `json:"started_at,omitempty"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	Output      *CheckRunOutput  `json:"output,omitempty"`
+	Actions     []CheckRunAction `json:"actions,omitempty"`
+	// CheckSuite is only set in responses.
+	CheckSuite *CheckSuite `json:"check_suite,omitempty"`
+	// App is only set in responses.
+	App *App `json:"app,omitempty"`
+	// PullRequests is only set in responses and lists the pull requests
+	// whose head is the checked commit.
+	PullRequests []CheckRunPullRequest `json:"pull_requests,omitempty"`
+}
+
+// CheckRunPullRequest is a pull request that a check run belongs to.
+type CheckRunPullRequest struct {
+	Number int               `json:"number"`
+	Head   PullRequestBranch `json:"head"`
+	Base   PullRequestBranch `json:"base"`
+}
+
+// CheckRunOutput describes the result of a check run.
+type CheckRunOutput struct {
+	Title   string `json:"title,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Text    string `json:"text,omitempty"`
+	// Annotations are limited to 50 per request, further annotations are
+	// added by updating the check run.
+	Annotations []CheckRunAnnotation `json:"annotations,omitempty"`
+}
+
+// CheckRunAnnotation points to a line of a file that caused a check run
+// to fail.
+type CheckRunAnnotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"`
+	Title           string `json:"title,omitempty"`
+	Message         string `json:"message"`
+	RawDetails      string `json:"raw_details,omitempty"`
+}
+
+// CheckRunAction is a button shown on a check run that triggers a
+// check_run event with the requested_action action.
+type CheckRunAction struct {
+	// Label is at most 20 characters long.
+	Label string `json:"label"`
+	// Description is at most 40 characters long.
+	Description string `json:"description"`
+	// Identifier is at most 20 characters long.
+	Identifier string `json:"identifier"`
+}
+
+// CheckSuite is the group of check runs of an app on a commit.
+//
+// See https://developer.github.com/v3/checks/suites/
+type CheckSuite struct {
+	ID         int64  `json:"id"`
+	HeadBranch string `json:"head_branch"`
+	HeadSHA    string `json:"head_sha"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	App        *App   `json:"app,omitempty"`
+}
+
+// App is a GitHub App.
+type App struct {
+	ID   int64  `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// CheckRunList is a page of check runs.
+type CheckRunList struct {
+	Total     int        `json:"total_count"`
+	CheckRuns []CheckRun `json:"check_runs"`
+}
+
 // User is a GitHub user account.
 type User struct {
 	Login       string          `json:"login"`
@@ -341,9 +455,10 @@ type Repo struct {
 type FullRepo struct {
 	Repo
 
-	AllowSquashMerge bool `json:"allow_squash_merge,omitempty"`
-	AllowMergeCommit bool `json:"allow_merge_commit,omitempty"`
-	AllowRebaseMerge bool `json:"allow_rebase_merge,omitempty"`
+	AllowSquashMerge    bool `json:"allow_squash_merge,omitempty"`
+	AllowMergeCommit    bool `json:"allow_merge_commit,omitempty"`
+	AllowRebaseMerge    bool `json:"allow_rebase_merge,omitempty"`
+	DeleteBranchOnMerge bool `json:"delete_branch_on_merge,omitempty"`
 }
 
 // RepoRequest contains metadata used in requests to create or update a Repo.
@@ -363,6 +478,8 @@ type RepoRequest struct {
 	AllowSquashMerge *bool   `json:"allow_squash_merge,omitempty"`
 	AllowMergeCommit *bool   `json:"allow_merge_commit,omitempty"`
 	AllowRebaseMerge *bool   `json:"allow_rebase_merge,omitempty"`
+
+	DeleteBranchOnMerge *bool `json:"delete_branch_on_merge,omitempty"`
 }
 
 // RepoCreateRequest contains metadata used in requests to create a repo.
@@ -398,6 +515,7 @@ func (r RepoRequest) ToRepo() *FullRepo {
 	setBool(&repo.AllowSquashMerge, r.AllowSquashMerge)
 	setBool(&repo.AllowMergeCommit, r.AllowMergeCommit)
 	setBool(&repo.AllowRebaseMerge, r.AllowRebaseMerge)
+	setBool(&repo.DeleteBranchOnMerge, r.DeleteBranchOnMerge)
 
 	return &repo
 }
@@ -406,7 +524,7 @@ func (r RepoRequest) ToRepo() *FullRepo {
 func (r RepoRequest) Defined() bool {
 	return r.Name != nil || r.Description != nil || r.Homepage != nil || r.Private != nil ||
 		r.HasIssues != nil || r.HasProjects != nil || r.HasWiki != nil || r.AllowSquashMerge != nil ||
-		r.AllowMergeCommit != nil || r.AllowRebaseMerge != nil
+		r.AllowMergeCommit != nil || r.AllowRebaseMerge != nil || r.DeleteBranchOnMerge != nil
 }
 
 // RepoUpdateRequest contains metadata used for updating a repository
@@ -490,6 +608,24 @@ const (
 	RepoAdmin TeamPermission = "admin"
 )
 
+// RepoInvitation is a pending invitation to collaborate on a repo.
+//
+// See https://developer.github.com/v3/repos/invitations/
+type RepoInvitation struct {
+	ID      int  `json:"id"`
+	Invitee User `json:"invitee"`
+	Inviter User `json:"inviter"`
+	// Permissions is read, write or admin.
+	Permissions RepoPermissionLevel `json:"permissions"`
+}
+
+// repoTopics is the list of topics of a repo.
+//
+// See https://developer.github.com/v3/repos/#replace-all-repository-topics
+type repoTopics struct {
+	Names []string `json:"names"`
+}
+
 // Branch contains general branch information.
 type Branch struct {
 	Name      string `json:"name"`
@@ -764,6 +900,39 @@ type StatusEvent struct {
 	Context     string `json:"context,omitempty"`
 	Sender      User   `json:"sender,omitempty"`
 	Repo        Repo   `json:"repository,omitempty"`
+	// Branches contain the commit, up to ten of them are listed.
+	Branches []Branch `json:"branches,omitempty"`
+
+	// GUID is included in the header of the request received by GitHub.
+	GUID string
+}
+
+// CheckRunEventAction enumerates the triggers of a CheckRunEvent.
+type CheckRunEventAction string
+
+const (
+	// CheckRunActionCreated means a check run was created.
+	CheckRunActionCreated CheckRunEventAction = "created"
+	// CheckRunActionCompleted means a check run completed.
+	CheckRunActionCompleted CheckRunEventAction = "completed"
+	// CheckRunActionRerequested means someone asked to run the check again.
+	CheckRunActionRerequested CheckRunEventAction = "rerequested"
+	// CheckRunActionRequestedAction means someone clicked an action button
+	// of the check run.
+	CheckRunActionRequestedAction CheckRunEventAction = "requested_action"
+)
+
+// CheckRunEvent fires whenever a check run is created, completed or
+// requested again.
+//
+// See https://developer.github.com/v3/activity/events/types/#checkrunevent
+type CheckRunEvent struct {
+	Action   CheckRunEventAction `json:"action"`
+	CheckRun CheckRun            `json:"check_run"`
+	// RequestedAction is set for the requested_action action.
+	RequestedAction *CheckRunAction `json:"requested_action,omitempty"`
+	Repo            Repo            `json:"repository"`
+	Sender          User            `json:"sender"`
 
 	// GUID is included in the header of the request received by GitHub.
 	GUID string
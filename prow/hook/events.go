@@ -61,8 +61,8 @@ var (
 	}
 )
 
-func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
-	defer s.wg.Done()
+func (s *Server) handleReviewEvent(l *logrus.Entry, wg *eventWaitGroup, re github.ReviewEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  re.Repo.Owner.Login,
 		github.RepoLogField: re.Repo.Name,
@@ -73,9 +73,10 @@ func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
 	})
 	l.Infof("Review %s.", re.Action)
 	for p, h := range s.Plugins.ReviewEventHandlers(re.PullRequest.Base.Repo.Owner.Login, re.PullRequest.Base.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.ReviewEventHandler) {
-			defer s.wg.Done()
+			defer wg.Done()
+			defer handlePanic(l, wg, p)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				re.Repo.Owner.Login,
@@ -98,6 +99,7 @@ func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
 	}
 	s.handleGenericComment(
 		l,
+		wg,
 		&github.GenericCommentEvent{
 			GUID:         re.GUID,
 			IsPR:         true,
@@ -116,8 +118,8 @@ func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
 	)
 }
 
-func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewCommentEvent) {
-	defer s.wg.Done()
+func (s *Server) handleReviewCommentEvent(l *logrus.Entry, wg *eventWaitGroup, rce github.ReviewCommentEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  rce.Repo.Owner.Login,
 		github.RepoLogField: rce.Repo.Name,
@@ -128,9 +130,10 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 	})
 	l.Infof("Review comment %s.", rce.Action)
 	for p, h := range s.Plugins.ReviewCommentEventHandlers(rce.PullRequest.Base.Repo.Owner.Login, rce.PullRequest.Base.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.ReviewCommentEventHandler) {
-			defer s.wg.Done()
+			defer wg.Done()
+			defer handlePanic(l, wg, p)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				rce.Repo.Owner.Login,
@@ -153,6 +156,7 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 	}
 	s.handleGenericComment(
 		l,
+		wg,
 		&github.GenericCommentEvent{
 			GUID:         rce.GUID,
 			IsPR:         true,
@@ -171,8 +175,8 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 	)
 }
 
-func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEvent) {
-	defer s.wg.Done()
+func (s *Server) handlePullRequestEvent(l *logrus.Entry, wg *eventWaitGroup, pr github.PullRequestEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  pr.Repo.Owner.Login,
 		github.RepoLogField: pr.Repo.Name,
@@ -182,9 +186,10 @@ func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEv
 	})
 	l.Infof("Pull request %s.", pr.Action)
 	for p, h := range s.Plugins.PullRequestHandlers(pr.PullRequest.Base.Repo.Owner.Login, pr.PullRequest.Base.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.PullRequestHandler) {
-			defer s.wg.Done()
+			defer wg.Done()
+			defer handlePanic(l, wg, p)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				pr.Repo.Owner.Login,
@@ -209,6 +214,7 @@ func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEv
 	}
 	s.handleGenericComment(
 		l,
+		wg,
 		&github.GenericCommentEvent{
 			ID:           pr.PullRequest.ID,
 			GUID:         pr.GUID,
@@ -228,8 +234,8 @@ func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEv
 	)
 }
 
-func (s *Server) handlePushEvent(l *logrus.Entry, pe github.PushEvent) {
-	defer s.wg.Done()
+func (s *Server) handlePushEvent(l *logrus.Entry, wg *eventWaitGroup, pe github.PushEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  pe.Repo.Owner.Name,
 		github.RepoLogField: pe.Repo.Name,
@@ -238,9 +244,10 @@ func (s *Server) handlePushEvent(l *logrus.Entry, pe github.PushEvent) {
 	})
 	l.Info("Push event.")
 	for p, h := range s.Plugins.PushEventHandlers(pe.Repo.Owner.Name, pe.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.PushEventHandler) {
-			defer s.wg.Done()
+			defer wg.Done()
+			defer handlePanic(l, wg, p)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, s.Metrics.Metrics, l, p)
 			start := time.Now()
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": "none", "plugin": p}
@@ -253,8 +260,8 @@ func (s *Server) handlePushEvent(l *logrus.Entry, pe github.PushEvent) {
 	}
 }
 
-func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
-	defer s.wg.Done()
+func (s *Server) handleIssueEvent(l *logrus.Entry, wg *eventWaitGroup, i github.IssueEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  i.Repo.Owner.Login,
 		github.RepoLogField: i.Repo.Name,
@@ -264,9 +271,10 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 	})
 	l.Infof("Issue %s.", i.Action)
 	for p, h := range s.Plugins.IssueHandlers(i.Repo.Owner.Login, i.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.IssueHandler) {
-			defer s.wg.Done()
+			defer wg.Done()
+			defer handlePanic(l, wg, p)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				i.Repo.Owner.Login,
@@ -291,6 +299,7 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 	}
 	s.handleGenericComment(
 		l,
+		wg,
 		&github.GenericCommentEvent{
 			ID:           i.Issue.ID,
 			GUID:         i.GUID,
@@ -310,8 +319,8 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 	)
 }
 
-func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueCommentEvent) {
-	defer s.wg.Done()
+func (s *Server) handleIssueCommentEvent(l *logrus.Entry, wg *eventWaitGroup, ic github.IssueCommentEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  ic.Repo.Owner.Login,
 		github.RepoLogField: ic.Repo.Name,
@@ -321,9 +330,10 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 	})
 	l.Infof("Issue comment %s.", ic.Action)
 	for p, h := range s.Plugins.IssueCommentHandlers(ic.Repo.Owner.Login, ic.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.IssueCommentHandler) {
-			defer s.wg.Done()
+			defer wg.Done()
+			defer handlePanic(l, wg, p)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				ic.Repo.Owner.Login,
@@ -346,6 +356,7 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 	}
 	s.handleGenericComment(
 		l,
+		wg,
 		&github.GenericCommentEvent{
 			ID:           ic.Issue.ID,
 			GUID:         ic.GUID,
@@ -365,8 +376,8 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 	)
 }
 
-func (s *Server) handleStatusEvent(l *logrus.Entry, se github.StatusEvent) {
-	defer s.wg.Done()
+func (s *Server) handleStatusEvent(l *logrus.Entry, wg *eventWaitGroup, se github.StatusEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  se.Repo.Owner.Login,
 		github.RepoLogField: se.Repo.Name,
@@ -377,9 +388,10 @@ func (s *Server) handleStatusEvent(l *logrus.Entry, se github.StatusEvent) {
 	})
 	l.Infof("Status description %s.", se.Description)
 	for p, h := range s.Plugins.StatusEventHandlers(se.Repo.Owner.Login, se.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.StatusEventHandler) {
-			defer s.wg.Done()
+			defer wg.Done()
+			defer handlePanic(l, wg, p)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, s.Metrics.Metrics, l, p)
 			start := time.Now()
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": "none", "plugin": p}
@@ -392,6 +404,34 @@ func (s *Server) handleStatusEvent(l *logrus.Entry, se github.StatusEvent) {
 	}
 }
 
+func (s *Server) handleCheckRunEvent(l *logrus.Entry, wg *eventWaitGroup, cre github.CheckRunEvent) {
+	defer wg.Done()
+	l = l.WithFields(logrus.Fields{
+		github.OrgLogField:  cre.Repo.Owner.Login,
+		github.RepoLogField: cre.Repo.Name,
+		"check_run":         cre.CheckRun.Name,
+		"sha":               cre.CheckRun.HeadSHA,
+		"action":            cre.Action,
+		"id":                cre.CheckRun.ID,
+	})
+	l.Infof("Check run %s.", cre.Action)
+	for p, h := range s.Plugins.CheckRunEventHandlers(cre.Repo.Owner.Login, cre.Repo.Name) {
+		wg.Add(1)
+		go func(p string, h plugins.CheckRunEventHandler) {
+			defer wg.Done()
+			defer handlePanic(l, wg, p)
+			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, s.Metrics.Metrics, l, p)
+			start := time.Now()
+			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(cre.Action), "plugin": p}
+			if err := h(agent, cre); err != nil {
+				agent.Logger.WithError(err).Error("Error handling CheckRunEvent.")
+				s.Metrics.PluginHandleErrors.With(labels).Inc()
+			}
+			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+		}(p, h)
+	}
+}
+
 // genericCommentAction normalizes the action string to a GenericCommentEventAction or returns ""
 // if the action is unrelated to the comment text. (For example a PR 'label' action.)
 func genericCommentAction(action string) github.GenericCommentEventAction {
@@ -407,11 +447,12 @@ func genericCommentAction(action string) github.GenericCommentEventAction {
 	return ""
 }
 
-func (s *Server) handleGenericComment(l *logrus.Entry, ce *github.GenericCommentEvent) {
+func (s *Server) handleGenericComment(l *logrus.Entry, wg *eventWaitGroup, ce *github.GenericCommentEvent) {
 	for p, h := range s.Plugins.GenericCommentHandlers(ce.Repo.Owner.Login, ce.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.GenericCommentHandler) {
-			defer s.wg.Done()
+			defer wg.Done()
+			defer handlePanic(l, wg, p)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				ce.Repo.Owner.Login,
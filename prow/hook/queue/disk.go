@@ -0,0 +1,169 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// pruneInterval is how often expired events are removed from the disk.
+const pruneInterval = time.Minute
+
+// validGUID matches the GUIDs that are safe to use as file names.
+var validGUID = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// disk is a Queue that keeps every event in a JSON file of a directory.
+type disk struct {
+	sync.Mutex
+	dir       string
+	retention time.Duration
+	lastPrune time.Time
+}
+
+// NewDisk returns a Queue keeping the events in the directory for the
+// retention. The directory is created if it does not exist.
+func NewDisk(dir string, retention time.Duration) (Queue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %v", err)
+	}
+	return &disk{dir: dir, retention: retention}, nil
+}
+
+func (d *disk) path(guid string) (string, error) {
+	if !validGUID.MatchString(guid) {
+		return "", fmt.Errorf("invalid event GUID %q", guid)
+	}
+	return filepath.Join(d.dir, guid+".json"), nil
+}
+
+func (d *disk) Put(event Event) error {
+	path, err := d.path(event.GUID)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %v", err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	// Write to a temporary file first so that events are never half written.
+	tmp, err := ioutil.TempFile(d.dir, ".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create event file: %v", err)
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write event: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write event: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write event: %v", err)
+	}
+
+	// The event is persisted, expired ones are removed on a later attempt.
+	if time.Since(d.lastPrune) > pruneInterval {
+		d.lastPrune = time.Now()
+		if err := d.prune(); err != nil {
+			logrus.WithError(err).Warn("Failed to prune expired events.")
+		}
+	}
+	return nil
+}
+
+// prune removes the files of the expired events.
+func (d *disk) prune() error {
+	files, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list events: %v", err)
+	}
+	expired := time.Now().Add(-d.retention)
+	for _, file := range files {
+		if file.ModTime().Before(expired) {
+			if err := os.Remove(filepath.Join(d.dir, file.Name())); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove expired event: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *disk) Get(guid string) (*Event, error) {
+	path, err := d.path(guid)
+	if err != nil {
+		return nil, err
+	}
+	d.Lock()
+	defer d.Unlock()
+	event, err := readEvent(path)
+	if os.IsNotExist(err) {
+		return nil, NotFoundError{GUID: guid}
+	}
+	return event, err
+}
+
+func (d *disk) List(since, until time.Time) ([]Event, error) {
+	d.Lock()
+	defer d.Unlock()
+	files, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %v", err)
+	}
+	var events []Event
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		event, err := readEvent(filepath.Join(d.dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if inRange(*event, since, until) {
+			events = append(events, *event)
+		}
+	}
+	sortEvents(events)
+	return events, nil
+}
+
+func readEvent(path string) (*Event, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var event Event
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event %s: %v", filepath.Base(path), err)
+	}
+	return &event, nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package queue persists the webhooks received by hook until they are
+// processed, so that they survive restarts and can be replayed.
+package queue
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event is a webhook as it was received.
+type Event struct {
+	GUID     string      `json:"guid"`
+	Type     string      `json:"type"`
+	Payload  []byte      `json:"payload"`
+	Header   http.Header `json:"header,omitempty"`
+	Received time.Time   `json:"received"`
+
+	// Attempts is how many times the processing of the event started.
+	Attempts int `json:"attempts"`
+	// Acked is whether the event was processed to completion.
+	Acked bool `json:"acked"`
+}
+
+// Queue persists events. Events are kept for replay after they are acked,
+// until they expire.
+type Queue interface {
+	// Put persists the event, replacing the event with the same GUID.
+	Put(Event) error
+	// Get returns the event with the GUID.
+	Get(guid string) (*Event, error)
+	// List returns the events received in [since, until), oldest first.
+	List(since, until time.Time) ([]Event, error)
+}
+
+// NotFoundError is returned when getting an event that does not exist or
+// has expired.
+type NotFoundError struct {
+	GUID string
+}
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("event %s not found", e.GUID)
+}
+
+// IsNotFound determines whether the error is a NotFoundError.
+func IsNotFound(err error) bool {
+	_, ok := err.(NotFoundError)
+	return ok
+}
+
+// Unacked returns the events of the queue that were not processed to
+// completion, oldest first.
+func Unacked(q Queue) ([]Event, error) {
+	events, err := q.List(time.Time{}, time.Now().Add(time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	var unacked []Event
+	for _, event := range events {
+		if !event.Acked {
+			unacked = append(unacked, event)
+		}
+	}
+	return unacked, nil
+}
+
+// Ack marks the event with the GUID as processed to completion.
+func Ack(q Queue, guid string) error {
+	event, err := q.Get(guid)
+	if err != nil {
+		return err
+	}
+	event.Acked = true
+	return q.Put(*event)
+}
+
+// sortEvents sorts events by the time they were received.
+func sortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Received.Before(events[j].Received)
+	})
+}
+
+// inRange determines whether the event was received in [since, until).
+func inRange(event Event, since, until time.Time) bool {
+	return !event.Received.Before(since) && event.Received.Before(until)
+}
+
+// memory is a Queue that keeps events in memory. Events do not survive
+// restarts, but can still be replayed.
+type memory struct {
+	sync.Mutex
+	retention time.Duration
+	events    map[string]Event
+	lastPrune time.Time
+}
+
+// NewMemory returns a Queue keeping the events in memory for the retention.
+func NewMemory(retention time.Duration) Queue {
+	return &memory{retention: retention, events: map[string]Event{}}
+}
+
+func (m *memory) Put(event Event) error {
+	m.Lock()
+	defer m.Unlock()
+	m.events[event.GUID] = event
+	if time.Since(m.lastPrune) <= pruneInterval {
+		return nil
+	}
+	m.lastPrune = time.Now()
+	expired := m.lastPrune.Add(-m.retention)
+	for guid, event := range m.events {
+		if event.Received.Before(expired) {
+			delete(m.events, guid)
+		}
+	}
+	return nil
+}
+
+func (m *memory) Get(guid string) (*Event, error) {
+	m.Lock()
+	defer m.Unlock()
+	event, ok := m.events[guid]
+	if !ok {
+		return nil, NotFoundError{GUID: guid}
+	}
+	return &event, nil
+}
+
+func (m *memory) List(since, until time.Time) ([]Event, error) {
+	m.Lock()
+	defer m.Unlock()
+	var events []Event
+	for _, event := range m.events {
+		if inRange(event, since, until) {
+			events = append(events, event)
+		}
+	}
+	sortEvents(events)
+	return events, nil
+}
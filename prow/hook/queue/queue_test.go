@@ -0,0 +1,144 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func guids(events []Event) []string {
+	var result []string
+	for _, event := range events {
+		result = append(result, event.GUID)
+	}
+	return result
+}
+
+func TestQueues(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	testCases := []struct {
+		name  string
+		queue func(t *testing.T) (Queue, func())
+	}{
+		{
+			name: "memory",
+			queue: func(t *testing.T) (Queue, func()) {
+				return NewMemory(time.Hour), func() {}
+			},
+		},
+		{
+			name: "disk",
+			queue: func(t *testing.T) (Queue, func()) {
+				dir, err := ioutil.TempDir("", "queue")
+				if err != nil {
+					t.Fatalf("failed to create temporary directory: %v", err)
+				}
+				q, err := NewDisk(filepath.Join(dir, "events"), time.Hour)
+				if err != nil {
+					t.Fatalf("failed to create queue: %v", err)
+				}
+				return q, func() { os.RemoveAll(dir) }
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, cleanup := tc.queue(t)
+			defer cleanup()
+			events := []Event{
+				{GUID: "second", Type: "push", Payload: []byte(`{}`), Received: now.Add(-time.Minute)},
+				{GUID: "first", Type: "issues", Payload: []byte(`{"action":"opened"}`), Header: http.Header{"X-Github-Event": {"issues"}}, Received: now.Add(-2 * time.Minute)},
+				{GUID: "third", Type: "status", Payload: []byte(`{}`), Received: now},
+			}
+			for _, event := range events {
+				if err := q.Put(event); err != nil {
+					t.Fatalf("failed to put event: %v", err)
+				}
+			}
+
+			event, err := q.Get("first")
+			if err != nil {
+				t.Fatalf("failed to get event: %v", err)
+			}
+			if !reflect.DeepEqual(*event, events[1]) {
+				t.Errorf("expected event %+v, got %+v", events[1], *event)
+			}
+			if _, err := q.Get("missing"); !IsNotFound(err) {
+				t.Errorf("expected a not found error for a missing event, got %v", err)
+			}
+
+			listed, err := q.List(now.Add(-2*time.Minute), now)
+			if err != nil {
+				t.Fatalf("failed to list events: %v", err)
+			}
+			if expected := []string{"first", "second"}; !reflect.DeepEqual(guids(listed), expected) {
+				t.Errorf("expected events %v in range, got %v", expected, guids(listed))
+			}
+
+			if err := Ack(q, "second"); err != nil {
+				t.Fatalf("failed to ack event: %v", err)
+			}
+			unacked, err := Unacked(q)
+			if err != nil {
+				t.Fatalf("failed to list unacked events: %v", err)
+			}
+			if expected := []string{"first", "third"}; !reflect.DeepEqual(guids(unacked), expected) {
+				t.Errorf("expected unacked events %v, got %v", expected, guids(unacked))
+			}
+		})
+	}
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	q := NewMemory(time.Hour)
+	if err := q.Put(Event{GUID: "old", Received: time.Now().Add(-2 * time.Hour)}); err != nil {
+		t.Fatalf("failed to put event: %v", err)
+	}
+	if err := q.Put(Event{GUID: "new", Received: time.Now()}); err != nil {
+		t.Fatalf("failed to put event: %v", err)
+	}
+	if _, err := q.Get("old"); !IsNotFound(err) {
+		t.Errorf("expected the old event to expire, got %v", err)
+	}
+	if _, err := q.Get("new"); err != nil {
+		t.Errorf("expected the new event to be kept, got %v", err)
+	}
+}
+
+func TestDiskInvalidGUID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	q, err := NewDisk(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	for _, guid := range []string{"", "../escape", "a/b", ".hidden"} {
+		if err := q.Put(Event{GUID: guid}); err == nil {
+			t.Errorf("expected an error for GUID %q", guid)
+		}
+	}
+}
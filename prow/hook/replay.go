@@ -0,0 +1,115 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/hook/queue"
+)
+
+// ReplayResponse lists the GUIDs of the replayed events.
+type ReplayResponse struct {
+	Replayed []string `json:"replayed"`
+}
+
+// ServeReplay processes persisted events again, whether they were processed
+// already or not. Events are selected by their GUID with the guid parameter,
+// which may be repeated, or by the time they were received with the since and
+// until parameters in RFC 3339 format. Until defaults to now.
+//
+// The endpoint is meant for administrators, it must not be exposed publicly.
+func (s *Server) ServeReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "405 Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.Queue == nil {
+		http.Error(w, "Events are not persisted, they cannot be replayed.", http.StatusBadRequest)
+		return
+	}
+	events, status, err := s.replayedEvents(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	response := ReplayResponse{Replayed: []string{}}
+	for _, event := range events {
+		l := logrus.WithFields(logrus.Fields{eventTypeField: event.Type, github.EventGUID: event.GUID})
+		l.Info("Replaying event.")
+		if err := s.processEvent(event); err != nil {
+			l.WithError(err).Error("Error parsing event.")
+			continue
+		}
+		response.Replayed = append(response.Replayed, event.GUID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logrus.WithError(err).Error("Failed to write replay response.")
+	}
+}
+
+// replayedEvents returns the events selected by the request, or the status
+// and error to reply with.
+func (s *Server) replayedEvents(r *http.Request) ([]queue.Event, int, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid parameters: %v", err)
+	}
+	guids := r.Form["guid"]
+	since, until := r.Form.Get("since"), r.Form.Get("until")
+	switch {
+	case len(guids) > 0 && (since != "" || until != ""):
+		return nil, http.StatusBadRequest, fmt.Errorf("events are selected by guid or by time range, not both")
+	case len(guids) > 0:
+		var events []queue.Event
+		for _, guid := range guids {
+			event, err := s.Queue.Get(guid)
+			if queue.IsNotFound(err) {
+				return nil, http.StatusNotFound, err
+			}
+			if err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+			events = append(events, *event)
+		}
+		return events, http.StatusOK, nil
+	case since != "":
+		start, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid since: %v", err)
+		}
+		end := time.Now()
+		if until != "" {
+			if end, err = time.Parse(time.RFC3339, until); err != nil {
+				return nil, http.StatusBadRequest, fmt.Errorf("invalid until: %v", err)
+			}
+		}
+		events, err := s.Queue.List(start, end)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		return events, http.StatusOK, nil
+	}
+	return nil, http.StatusBadRequest, fmt.Errorf("select events with guid or since")
+}
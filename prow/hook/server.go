@@ -17,14 +17,14 @@ limitations under the License.
 package hook
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -32,6 +32,8 @@ import (
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	_ "k8s.io/test-infra/prow/hook/plugin-imports"
+	"k8s.io/test-infra/prow/hook/queue"
+	"k8s.io/test-infra/prow/httpretry"
 	"k8s.io/test-infra/prow/plugins"
 )
 
@@ -43,6 +45,9 @@ type Server struct {
 	ConfigAgent    *config.Agent
 	TokenGenerator func() []byte
 	Metrics        *Metrics
+	// Queue persists the events until they are processed, so that they
+	// are processed at least once. Events are not persisted if it is nil.
+	Queue queue.Queue
 
 	// c is an http client used for dispatching events
 	// to external plugin services.
@@ -65,14 +70,111 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
+	event := queue.Event{
+		GUID:     eventGUID,
+		Type:     eventType,
+		Payload:  payload,
+		Header:   r.Header,
+		Received: time.Now(),
+	}
+	if s.Queue != nil {
+		// Still process the event, it is just not durable.
+		if err := s.Queue.Put(event); err != nil {
+			logrus.WithError(err).WithField(github.EventGUID, eventGUID).Error("Failed to persist event.")
+		}
+	}
 	fmt.Fprint(w, "Event received. Have a nice day.")
 
-	if err := s.demuxEvent(eventType, eventGUID, payload, r.Header); err != nil {
+	if err := s.processEvent(event); err != nil {
 		logrus.WithError(err).Error("Error parsing event.")
 	}
 }
 
-func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.Header) error {
+// eventWaitGroup tracks the handlers of a single event, as well as all of the
+// handlers of the server for the graceful shutdown.
+type eventWaitGroup struct {
+	server *sync.WaitGroup
+	event  sync.WaitGroup
+	// panicked is set when a handler of the event panicked.
+	panicked int32
+}
+
+func (wg *eventWaitGroup) Add(delta int) {
+	wg.server.Add(delta)
+	wg.event.Add(delta)
+}
+
+func (wg *eventWaitGroup) Done() {
+	wg.event.Done()
+	wg.server.Done()
+}
+
+// handlePanic keeps a plugin panicking while handling an event from taking
+// down the other plugins handling events. The event is not acked, so that it
+// is processed again when hook restarts.
+func handlePanic(l *logrus.Entry, wg *eventWaitGroup, plugin string) {
+	if r := recover(); r != nil {
+		l.WithField("plugin", plugin).Errorf("Plugin panicked while handling event: %v\n%s", r, debug.Stack())
+		atomic.StoreInt32(&wg.panicked, 1)
+	}
+}
+
+// processEvent handles the event and acks it once all of its handlers are
+// done without panicking, if the server has a queue.
+func (s *Server) processEvent(event queue.Event) error {
+	wg := &eventWaitGroup{server: &s.wg}
+	if s.Queue != nil {
+		event.Attempts++
+		if err := s.Queue.Put(event); err != nil {
+			logrus.WithError(err).WithField(github.EventGUID, event.GUID).Error("Failed to persist event.")
+		}
+	}
+	// Events that cannot be parsed never will be, so they are acked too.
+	err := s.demuxEvent(event.Type, event.GUID, event.Payload, event.Header, wg)
+	if s.Queue != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			wg.event.Wait()
+			if atomic.LoadInt32(&wg.panicked) != 0 {
+				logrus.WithField(github.EventGUID, event.GUID).Warn("Not acking event, a plugin panicked while handling it.")
+				return
+			}
+			if err := queue.Ack(s.Queue, event.GUID); err != nil {
+				logrus.WithError(err).WithField(github.EventGUID, event.GUID).Error("Failed to ack event.")
+			}
+		}()
+	}
+	return err
+}
+
+// ProcessUnacked processes the events of the queue that were not processed
+// to completion, e.g. because hook restarted while handling them. Events
+// that were attempted maxAttempts times already are left alone, they can
+// still be replayed.
+func (s *Server) ProcessUnacked(maxAttempts int) error {
+	if s.Queue == nil {
+		return nil
+	}
+	events, err := queue.Unacked(s.Queue)
+	if err != nil {
+		return fmt.Errorf("failed to list unacked events: %v", err)
+	}
+	for _, event := range events {
+		l := logrus.WithFields(logrus.Fields{eventTypeField: event.Type, github.EventGUID: event.GUID, "attempts": event.Attempts})
+		if event.Attempts >= maxAttempts {
+			l.Warn("Not processing event again, it was attempted too many times.")
+			continue
+		}
+		l.Info("Processing unacked event.")
+		if err := s.processEvent(event); err != nil {
+			l.WithError(err).Error("Error parsing event.")
+		}
+	}
+	return nil
+}
+
+func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.Header, wg *eventWaitGroup) error {
 	l := logrus.WithFields(
 		logrus.Fields{
 			eventTypeField:   eventType,
@@ -94,8 +196,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		}
 		i.GUID = eventGUID
 		srcRepo = i.Repo.FullName
-		s.wg.Add(1)
-		go s.handleIssueEvent(l, i)
+		wg.Add(1)
+		go s.handleIssueEvent(l, wg, i)
 	case "issue_comment":
 		var ic github.IssueCommentEvent
 		if err := json.Unmarshal(payload, &ic); err != nil {
@@ -103,8 +205,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		}
 		ic.GUID = eventGUID
 		srcRepo = ic.Repo.FullName
-		s.wg.Add(1)
-		go s.handleIssueCommentEvent(l, ic)
+		wg.Add(1)
+		go s.handleIssueCommentEvent(l, wg, ic)
 	case "pull_request":
 		var pr github.PullRequestEvent
 		if err := json.Unmarshal(payload, &pr); err != nil {
@@ -112,8 +214,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		}
 		pr.GUID = eventGUID
 		srcRepo = pr.Repo.FullName
-		s.wg.Add(1)
-		go s.handlePullRequestEvent(l, pr)
+		wg.Add(1)
+		go s.handlePullRequestEvent(l, wg, pr)
 	case "pull_request_review":
 		var re github.ReviewEvent
 		if err := json.Unmarshal(payload, &re); err != nil {
@@ -121,8 +223,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		}
 		re.GUID = eventGUID
 		srcRepo = re.Repo.FullName
-		s.wg.Add(1)
-		go s.handleReviewEvent(l, re)
+		wg.Add(1)
+		go s.handleReviewEvent(l, wg, re)
 	case "pull_request_review_comment":
 		var rce github.ReviewCommentEvent
 		if err := json.Unmarshal(payload, &rce); err != nil {
@@ -130,8 +232,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		}
 		rce.GUID = eventGUID
 		srcRepo = rce.Repo.FullName
-		s.wg.Add(1)
-		go s.handleReviewCommentEvent(l, rce)
+		wg.Add(1)
+		go s.handleReviewCommentEvent(l, wg, rce)
 	case "push":
 		var pe github.PushEvent
 		if err := json.Unmarshal(payload, &pe); err != nil {
@@ -139,8 +241,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		}
 		pe.GUID = eventGUID
 		srcRepo = pe.Repo.FullName
-		s.wg.Add(1)
-		go s.handlePushEvent(l, pe)
+		wg.Add(1)
+		go s.handlePushEvent(l, wg, pe)
 	case "status":
 		var se github.StatusEvent
 		if err := json.Unmarshal(payload, &se); err != nil {
@@ -148,14 +250,23 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		}
 		se.GUID = eventGUID
 		srcRepo = se.Repo.FullName
-		s.wg.Add(1)
-		go s.handleStatusEvent(l, se)
+		wg.Add(1)
+		go s.handleStatusEvent(l, wg, se)
+	case "check_run":
+		var cre github.CheckRunEvent
+		if err := json.Unmarshal(payload, &cre); err != nil {
+			return err
+		}
+		cre.GUID = eventGUID
+		srcRepo = cre.Repo.FullName
+		wg.Add(1)
+		go s.handleCheckRunEvent(l, wg, cre)
 	default:
 		l.Debug("Ignoring unhandled event type. (Might still be handled by external plugins.)")
 	}
 	// Demux events only to external plugins that require this event.
 	if external := s.needDemux(eventType, srcRepo); len(external) > 0 {
-		go s.demuxExternal(l, external, payload, h)
+		s.demuxExternal(l, wg, external, payload, h)
 	}
 	return nil
 }
@@ -191,12 +302,13 @@ func (s *Server) needDemux(eventType, srcRepo string) []plugins.ExternalPlugin {
 }
 
 // demuxExternal dispatches the provided payload to the external plugins.
-func (s *Server) demuxExternal(l *logrus.Entry, externalPlugins []plugins.ExternalPlugin, payload []byte, h http.Header) {
+func (s *Server) demuxExternal(l *logrus.Entry, wg *eventWaitGroup, externalPlugins []plugins.ExternalPlugin, payload []byte, h http.Header) {
+	h = h.Clone()
 	h.Set("User-Agent", "ProwHook")
 	for _, p := range externalPlugins {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p plugins.ExternalPlugin) {
-			defer s.wg.Done()
+			defer wg.Done()
 			if err := s.dispatch(p.Endpoint, payload, h); err != nil {
 				l.WithError(err).WithField("external-plugin", p.Name).Error("Error dispatching event to external plugin.")
 			} else {
@@ -207,48 +319,18 @@ func (s *Server) demuxExternal(l *logrus.Entry, externalPlugins []plugins.Extern
 }
 
 // dispatch creates a new request using the provided payload and headers
-// and dispatches the request to the provided endpoint.
+// and dispatches the request to the provided endpoint. Requests that fail
+// to reach the endpoint or that it fails to handle are retried with backoff.
 func (s *Server) dispatch(endpoint string, payload []byte, h http.Header) error {
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(payload))
-	if err != nil {
-		return err
-	}
-	req.Header = h
-	resp, err := s.do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	rb, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return fmt.Errorf("response has status %q and body %q", resp.Status, string(rb))
-	}
-	return nil
+	return httpretry.Post(&s.c, endpoint, payload, h, dispatchBackoff)
 }
 
+// dispatchBackoff is a variable for mocking out the backoff in unit tests.
+var dispatchBackoff = httpretry.DefaultBackoff
+
 // GracefulShutdown implements a graceful shutdown protocol. It handles all requests sent before
 // receiving the shutdown signal.
 func (s *Server) GracefulShutdown() {
 	s.wg.Wait() // Handle remaining requests
 	return
 }
-
-func (s *Server) do(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-	backoff := 100 * time.Millisecond
-	maxRetries := 5
-
-	for retries := 0; retries < maxRetries; retries++ {
-		resp, err = s.c.Do(req)
-		if err == nil {
-			break
-		}
-		time.Sleep(backoff)
-		backoff *= 2
-	}
-	return resp, err
-}
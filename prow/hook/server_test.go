@@ -17,13 +17,24 @@ limitations under the License.
 package hook
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/hook/queue"
+	"k8s.io/test-infra/prow/httpretry"
 	"k8s.io/test-infra/prow/plugins"
+	"k8s.io/test-infra/prow/repoowners"
 )
 
 func TestServeHTTPErrors(t *testing.T) {
@@ -288,3 +299,290 @@ func TestNeedDemux(t *testing.T) {
 		}
 	}
 }
+
+func TestDispatchRetries(t *testing.T) {
+	dispatchBackoff = wait.Backoff{Steps: httpretry.DefaultBackoff.Steps}
+	defer func() { dispatchBackoff = httpretry.DefaultBackoff }()
+
+	var testCases = []struct {
+		name     string
+		statuses []int
+
+		expectedRequests int
+		expectedErr      bool
+	}{
+		{
+			name:             "success is not retried",
+			statuses:         []int{http.StatusOK},
+			expectedRequests: 1,
+		},
+		{
+			name:             "server error is retried",
+			statuses:         []int{http.StatusInternalServerError, http.StatusOK},
+			expectedRequests: 2,
+		},
+		{
+			name:             "rate limit is retried",
+			statuses:         []int{http.StatusTooManyRequests, http.StatusOK},
+			expectedRequests: 2,
+		},
+		{
+			name:             "client error is not retried",
+			statuses:         []int{http.StatusBadRequest},
+			expectedRequests: 1,
+			expectedErr:      true,
+		},
+		{
+			name:             "retries are bounded",
+			statuses:         []int{http.StatusServiceUnavailable},
+			expectedRequests: httpretry.DefaultBackoff.Steps,
+			expectedErr:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int
+			endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if body, _ := ioutil.ReadAll(r.Body); string(body) != "{}" {
+					t.Errorf("expected payload {}, got %q", string(body))
+				}
+				status := tc.statuses[len(tc.statuses)-1]
+				if requests < len(tc.statuses) {
+					status = tc.statuses[requests]
+				}
+				requests++
+				w.WriteHeader(status)
+			}))
+			defer endpoint.Close()
+
+			s := &Server{}
+			err := s.dispatch(endpoint.URL, []byte("{}"), http.Header{})
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error %t, got %v", tc.expectedErr, err)
+			}
+			if requests != tc.expectedRequests {
+				t.Errorf("expected %d requests, got %d", tc.expectedRequests, requests)
+			}
+		})
+	}
+}
+
+// queueServer returns a server with a memory queue that dispatches events
+// for foo/bar to an external plugin, which returns the status.
+func queueServer(status int) (*Server, *httptest.Server, *int32) {
+	var requests int32
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(status)
+	}))
+	pa := &plugins.ConfigAgent{}
+	pa.Set(&plugins.Configuration{
+		ExternalPlugins: map[string][]plugins.ExternalPlugin{
+			"foo/bar": {{Name: "external", Endpoint: endpoint.URL}},
+		},
+	})
+	return &Server{Metrics: NewMetrics(), Plugins: pa, Queue: queue.NewMemory(time.Hour)}, endpoint, &requests
+}
+
+func issueEvent(guid string, received time.Time) queue.Event {
+	return queue.Event{
+		GUID:     guid,
+		Type:     "issues",
+		Payload:  []byte(`{"action":"opened","repository":{"full_name":"foo/bar","owner":{"login":"foo"},"name":"bar"}}`),
+		Header:   http.Header{},
+		Received: received,
+	}
+}
+
+func TestProcessEventAcks(t *testing.T) {
+	s, endpoint, requests := queueServer(http.StatusOK)
+	defer endpoint.Close()
+
+	event := issueEvent("guid", time.Now())
+	if err := s.Queue.Put(event); err != nil {
+		t.Fatalf("failed to put event: %v", err)
+	}
+	if err := s.processEvent(event); err != nil {
+		t.Fatalf("failed to process event: %v", err)
+	}
+	s.GracefulShutdown()
+
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Errorf("expected the external plugin to be sent the event once, got %d", n)
+	}
+	processed, err := s.Queue.Get("guid")
+	if err != nil {
+		t.Fatalf("failed to get event: %v", err)
+	}
+	if !processed.Acked || processed.Attempts != 1 {
+		t.Errorf("expected the event to be acked after one attempt, got %+v", processed)
+	}
+}
+
+func TestProcessEventDoesNotAckPanics(t *testing.T) {
+	plugins.RegisterIssueHandler(
+		"panicking",
+		func(pc plugins.Agent, ie github.IssueEvent) error {
+			panic("oops")
+		},
+		nil,
+	)
+	s, endpoint, _ := queueServer(http.StatusOK)
+	defer endpoint.Close()
+	s.Plugins.Set(&plugins.Configuration{Plugins: map[string][]string{"foo/bar": {"panicking"}}})
+	s.ConfigAgent = &config.Agent{}
+	s.ClientAgent = &plugins.ClientAgent{
+		GitHubClient: github.NewFakeClient(),
+		OwnersClient: repoowners.NewClient(nil, nil, func(org, repo string) bool { return false }, func(org, repo string) bool { return false }, func() config.OwnersDirBlacklist { return config.OwnersDirBlacklist{} }),
+	}
+
+	event := issueEvent("guid", time.Now())
+	if err := s.Queue.Put(event); err != nil {
+		t.Fatalf("failed to put event: %v", err)
+	}
+	if err := s.processEvent(event); err != nil {
+		t.Fatalf("failed to process event: %v", err)
+	}
+	s.GracefulShutdown()
+
+	processed, err := s.Queue.Get("guid")
+	if err != nil {
+		t.Fatalf("failed to get event: %v", err)
+	}
+	if processed.Acked {
+		t.Errorf("expected the event to stay unacked after a plugin panicked, got %+v", processed)
+	}
+}
+
+func TestProcessUnacked(t *testing.T) {
+	s, endpoint, requests := queueServer(http.StatusOK)
+	defer endpoint.Close()
+
+	now := time.Now()
+	pending := issueEvent("pending", now.Add(-2*time.Minute))
+	pending.Attempts = 1
+	exhausted := issueEvent("exhausted", now.Add(-time.Minute))
+	exhausted.Attempts = 3
+	done := issueEvent("done", now)
+	done.Acked = true
+	for _, event := range []queue.Event{pending, exhausted, done} {
+		if err := s.Queue.Put(event); err != nil {
+			t.Fatalf("failed to put event: %v", err)
+		}
+	}
+
+	if err := s.ProcessUnacked(3); err != nil {
+		t.Fatalf("failed to process unacked events: %v", err)
+	}
+	s.GracefulShutdown()
+
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Errorf("expected a single event to be processed, got %d", n)
+	}
+	unacked, err := queue.Unacked(s.Queue)
+	if err != nil {
+		t.Fatalf("failed to list unacked events: %v", err)
+	}
+	if len(unacked) != 1 || unacked[0].GUID != "exhausted" {
+		t.Errorf("expected only the exhausted event to stay unacked, got %+v", unacked)
+	}
+}
+
+func TestServeReplay(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	var testCases = []struct {
+		name   string
+		method string
+		query  string
+
+		expectedCode     int
+		expectedReplayed []string
+	}{
+		{
+			name:             "by guid",
+			method:           http.MethodPost,
+			query:            "guid=first&guid=third",
+			expectedCode:     http.StatusOK,
+			expectedReplayed: []string{"first", "third"},
+		},
+		{
+			name:         "unknown guid",
+			method:       http.MethodPost,
+			query:        "guid=first&guid=missing",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:             "by time range",
+			method:           http.MethodPost,
+			query:            "since=" + now.Add(-90*time.Second).Format(time.RFC3339) + "&until=" + now.Format(time.RFC3339),
+			expectedCode:     http.StatusOK,
+			expectedReplayed: []string{"second"},
+		},
+		{
+			name:             "until defaults to now",
+			method:           http.MethodPost,
+			query:            "since=" + now.Add(-90*time.Second).Format(time.RFC3339),
+			expectedCode:     http.StatusOK,
+			expectedReplayed: []string{"second", "third"},
+		},
+		{
+			name:         "invalid since",
+			method:       http.MethodPost,
+			query:        "since=yesterday",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "guid and time range",
+			method:       http.MethodPost,
+			query:        "guid=first&since=" + now.Format(time.RFC3339),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "no selection",
+			method:       http.MethodPost,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "get",
+			method:       http.MethodGet,
+			query:        "guid=first",
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, endpoint, requests := queueServer(http.StatusOK)
+			defer endpoint.Close()
+			for i, guid := range []string{"first", "second", "third"} {
+				event := issueEvent(guid, now.Add(time.Duration(i-2)*time.Minute))
+				event.Acked = true
+				if err := s.Queue.Put(event); err != nil {
+					t.Fatalf("failed to put event: %v", err)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			s.ServeReplay(w, httptest.NewRequest(tc.method, "/replay?"+tc.query, nil))
+			s.GracefulShutdown()
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedCode, w.Code, w.Body.String())
+			}
+			if n := int(atomic.LoadInt32(requests)); n != len(tc.expectedReplayed) {
+				t.Errorf("expected %d events to be sent to the external plugin, got %d", len(tc.expectedReplayed), n)
+			}
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+			var response ReplayResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if !reflect.DeepEqual(response.Replayed, tc.expectedReplayed) {
+				t.Errorf("expected replayed events %v, got %v", tc.expectedReplayed, response.Replayed)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hookreplay
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func (c Call) String() string {
+	if c.Body == "" {
+		return fmt.Sprintf("%s %s", c.Method, c.Path)
+	}
+	return fmt.Sprintf("%s %s %s", c.Method, c.Path, c.Body)
+}
+
+// Diff compares the calls plugins made in two replays. Plugins handle events
+// concurrently, so the order of the calls is ignored. Calls only made before
+// are prefixed with -, calls only made after with +, grouped by plugin.
+func Diff(before, after []Call) string {
+	counts := map[string]map[string]int{}
+	count := func(calls []Call, delta int) {
+		for _, call := range calls {
+			if counts[call.Plugin] == nil {
+				counts[call.Plugin] = map[string]int{}
+			}
+			counts[call.Plugin][call.String()] += delta
+		}
+	}
+	count(before, -1)
+	count(after, 1)
+
+	var plugins []string
+	for plugin := range counts {
+		plugins = append(plugins, plugin)
+	}
+	sort.Strings(plugins)
+	var diff strings.Builder
+	for _, plugin := range plugins {
+		var lines []string
+		for call, n := range counts[plugin] {
+			prefix := "+"
+			if n < 0 {
+				prefix, n = "-", -n
+			}
+			for i := 0; i < n; i++ {
+				lines = append(lines, prefix+" "+call)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		// Sort by call, then removals first.
+		sort.Slice(lines, func(i, j int) bool {
+			if lines[i][2:] != lines[j][2:] {
+				return lines[i][2:] < lines[j][2:]
+			}
+			return lines[i][0] == '-' && lines[j][0] == '+'
+		})
+		name := plugin
+		if name == "" {
+			name = "(no plugin)"
+		}
+		fmt.Fprintf(&diff, "%s:\n", name)
+		for _, line := range lines {
+			fmt.Fprintf(&diff, "  %s\n", line)
+		}
+	}
+	return diff.String()
+}
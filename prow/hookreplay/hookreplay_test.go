@@ -0,0 +1,212 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hookreplay
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/hook/queue"
+)
+
+var hmac = []byte("abcde12345")
+
+const payload = `{"action":"opened","repository":{"full_name":"foo/bar"}}`
+
+func TestRecordAndReplay(t *testing.T) {
+	archive := queue.NewMemory(time.Hour)
+	recorder := httptest.NewServer(&Recorder{Archive: archive, TokenGenerator: func() []byte { return hmac }})
+	defer recorder.Close()
+
+	invalid := queue.Event{GUID: "invalid", Type: "issues", Payload: []byte(payload)}
+	if err := Send(recorder.URL, []byte("wrong"), invalid); err == nil {
+		t.Error("expected a webhook with an invalid signature to be rejected")
+	}
+	recorded := queue.Event{GUID: "guid", Type: "issues", Payload: []byte(payload), Header: http.Header{"X-Custom": {"value"}}}
+	if err := Send(recorder.URL, hmac, recorded); err != nil {
+		t.Fatalf("failed to send webhook: %v", err)
+	}
+
+	events, err := Select(archive, []string{"guid"}, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("failed to select webhooks: %v", err)
+	}
+	if len(events) != 1 || string(events[0].Payload) != payload || events[0].Header.Get("X-Custom") != "value" {
+		t.Fatalf("expected the webhook to be recorded with its headers, got %+v", events)
+	}
+	if _, err := Select(archive, []string{"invalid"}, time.Time{}, time.Time{}); !queue.IsNotFound(err) {
+		t.Errorf("expected the invalid webhook not to be recorded, got %v", err)
+	}
+	if events, err := Select(archive, nil, time.Now().Add(-time.Minute), time.Now()); err != nil || len(events) != 1 {
+		t.Errorf("expected the webhook to be selected by time, got %+v, %v", events, err)
+	}
+
+	var replayed *http.Request
+	var body []byte
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replayed = r
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer hook.Close()
+	if err := Send(hook.URL, []byte("local"), events[0]); err != nil {
+		t.Fatalf("failed to replay webhook: %v", err)
+	}
+	if replayed.Header.Get("X-GitHub-Delivery") != "guid" || replayed.Header.Get("X-GitHub-Event") != "issues" || replayed.Header.Get("X-Custom") != "value" {
+		t.Errorf("expected the webhook to be replayed with its headers, got %v", replayed.Header)
+	}
+	if !bytes.Equal(body, []byte(payload)) {
+		t.Errorf("expected payload %s, got %s", payload, body)
+	}
+	if sig := github.PayloadSignature(body, []byte("local")); replayed.Header.Get("X-Hub-Signature") != sig {
+		t.Errorf("expected the webhook to be signed with the local secret")
+	}
+}
+
+func TestResponder(t *testing.T) {
+	var upstreamRequests []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamRequests = append(upstreamRequests, r.Method+" "+r.URL.RequestURI())
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("expected the authorization to be proxied, got %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Link", `</repos/foo/bar/labels?page=2>; rel="next"`)
+		w.Write([]byte(`[{"name":"bug"}]`))
+	}))
+	defer upstream.Close()
+
+	recorded := []Exchange{
+		{Method: http.MethodGet, Path: "/user", Status: http.StatusOK, Body: `{"login":"first"}`},
+		{Method: http.MethodGet, Path: "/user", Status: http.StatusOK, Body: `{"login":"second"}`},
+	}
+	responder := NewResponder(upstream.URL, recorded)
+	server := httptest.NewServer(responder)
+	defer server.Close()
+
+	do := func(method, path, body, userAgent string) (int, string, http.Header) {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("User-Agent", userAgent)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(b), resp.Header
+	}
+
+	for _, expected := range []string{`{"login":"first"}`, `{"login":"second"}`, `{"login":"second"}`} {
+		if _, body, _ := do(http.MethodGet, "/user", "", "hook/v1"); body != expected {
+			t.Errorf("expected recorded response %s, got %s", expected, body)
+		}
+	}
+	status, body, header := do(http.MethodGet, "/repos/foo/bar/labels", "", "hook.label/v1")
+	if status != http.StatusOK || body != `[{"name":"bug"}]` || header.Get("Link") == "" {
+		t.Errorf("expected the read to be proxied, got %d %s %v", status, body, header)
+	}
+	if status, _, _ := do(http.MethodPost, "/repos/foo/bar/issues/1/labels", `["bug"]`, "hook.label/v1"); status != http.StatusCreated {
+		t.Errorf("expected the write to be answered with %d, got %d", http.StatusCreated, status)
+	}
+	if status, _, _ := do(http.MethodPost, "/graphql", `{"query":"query{viewer{login}}"}`, "hook.trigger/v1"); status != http.StatusOK {
+		t.Errorf("expected the GraphQL query to be proxied, got %d", status)
+	}
+	if status, _, _ := do(http.MethodPost, "/graphql", `{"query":"mutation{x}"}`, "hook.trigger/v1"); status != http.StatusCreated {
+		t.Errorf("expected the GraphQL mutation not to be proxied, got %d", status)
+	}
+
+	if expected := []string{"GET /repos/foo/bar/labels", "POST /graphql"}; !reflect.DeepEqual(upstreamRequests, expected) {
+		t.Errorf("expected upstream requests %v, got %v", expected, upstreamRequests)
+	}
+	if exchanges := responder.Exchanges(); len(exchanges) != 4 || exchanges[2].Path != "/repos/foo/bar/labels" || exchanges[2].Header.Get("Link") == "" {
+		t.Errorf("expected the proxied reads to be recorded after the recorded responses, got %+v", exchanges)
+	}
+	expectedCalls := []Call{
+		{Method: http.MethodGet, Path: "/user"},
+		{Method: http.MethodGet, Path: "/user"},
+		{Method: http.MethodGet, Path: "/user"},
+		{Plugin: "label", Method: http.MethodGet, Path: "/repos/foo/bar/labels"},
+		{Plugin: "label", Method: http.MethodPost, Path: "/repos/foo/bar/issues/1/labels", Body: `["bug"]`},
+		{Plugin: "trigger", Method: http.MethodPost, Path: "/graphql", Body: `{"query":"query{viewer{login}}"}`},
+		{Plugin: "trigger", Method: http.MethodPost, Path: "/graphql", Body: `{"query":"mutation{x}"}`},
+	}
+	if calls := responder.Calls(); !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("expected calls %+v, got %+v", expectedCalls, calls)
+	}
+
+	offline := httptest.NewServer(NewResponder("", nil))
+	defer offline.Close()
+	resp, err := http.Get(offline.URL + "/user")
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected reads that were not recorded to be not found, got %d", resp.StatusCode)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		before, after []Call
+		expected      string
+	}{
+		{
+			name:   "order is ignored",
+			before: []Call{{Plugin: "label", Method: "GET", Path: "/a"}, {Plugin: "size", Method: "GET", Path: "/b"}},
+			after:  []Call{{Plugin: "size", Method: "GET", Path: "/b"}, {Plugin: "label", Method: "GET", Path: "/a"}},
+		},
+		{
+			name: "changed calls are grouped by plugin",
+			before: []Call{
+				{Plugin: "size", Method: "GET", Path: "/b"},
+				{Plugin: "label", Method: "POST", Path: "/labels", Body: `["bug"]`},
+				{Plugin: "label", Method: "GET", Path: "/a"},
+			},
+			after: []Call{
+				{Plugin: "size", Method: "GET", Path: "/b"},
+				{Plugin: "label", Method: "POST", Path: "/labels", Body: `["feature"]`},
+				{Plugin: "label", Method: "GET", Path: "/a"},
+				{Plugin: "label", Method: "GET", Path: "/a"},
+				{Method: "GET", Path: "/user"},
+			},
+			expected: "(no plugin):\n" +
+				"  + GET /user\n" +
+				"label:\n" +
+				"  + GET /a\n" +
+				"  - POST /labels [\"bug\"]\n" +
+				"  + POST /labels [\"feature\"]\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := Diff(tc.before, tc.after); diff != tc.expected {
+				t.Errorf("expected diff:\n%s\ngot:\n%s", tc.expected, diff)
+			}
+		})
+	}
+}
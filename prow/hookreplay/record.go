@@ -0,0 +1,63 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hookreplay records GitHub webhooks and replays them against a
+// local hook, which talks to a GitHub API responder, so that plugin authors
+// can reproduce production incidents and compare the API calls of plugins.
+package hookreplay
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/hook/queue"
+)
+
+// Recorder archives the webhooks it receives. The archive has the format of
+// the hook queue, so events persisted by hook can be replayed too.
+type Recorder struct {
+	Archive        queue.Queue
+	TokenGenerator func() []byte
+}
+
+// ServeHTTP validates the webhook and archives it with its headers.
+func (rec *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	eventType, eventGUID, payload, ok, _ := github.ValidateWebhook(w, r, rec.TokenGenerator)
+	if !ok {
+		return
+	}
+	l := logrus.WithFields(logrus.Fields{"event-type": eventType, github.EventGUID: eventGUID})
+	event := queue.Event{
+		GUID:     eventGUID,
+		Type:     eventType,
+		Payload:  payload,
+		Header:   r.Header,
+		Received: time.Now(),
+		// Recorded events are only ever replayed on demand.
+		Acked: true,
+	}
+	if err := rec.Archive.Put(event); err != nil {
+		l.WithError(err).Error("Failed to record event.")
+		http.Error(w, "500 Internal Server Error: Failed to record event", http.StatusInternalServerError)
+		return
+	}
+	l.Info("Recorded event.")
+	fmt.Fprint(w, "Event recorded.")
+}
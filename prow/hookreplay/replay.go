@@ -0,0 +1,78 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hookreplay
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/hook/queue"
+)
+
+// Select returns the archived events with the GUIDs, or the events received
+// in [since, until) if no GUID is given, oldest first.
+func Select(archive queue.Queue, guids []string, since, until time.Time) ([]queue.Event, error) {
+	if len(guids) == 0 {
+		return archive.List(since, until)
+	}
+	var events []queue.Event
+	for _, guid := range guids {
+		event, err := archive.Get(guid)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+	return events, nil
+}
+
+// Send sends the event to the hook at the address as GitHub sent it,
+// signing it with the hmac secret of the hook.
+func Send(address string, hmac []byte, event queue.Event) error {
+	req, err := http.NewRequest(http.MethodPost, address, bytes.NewReader(event.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header = event.Header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("X-GitHub-Event", event.Type)
+	req.Header.Set("X-GitHub-Delivery", event.GUID)
+	req.Header.Set("X-Hub-Signature", github.PayloadSignature(event.Payload, hmac))
+	req.Header.Set("content-type", "application/json")
+	// The length of the recorded request is set from the body.
+	req.Header.Del("Content-Length")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	rb, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("response from hook has status %d and body %s", resp.StatusCode, string(bytes.TrimSpace(rb)))
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hookreplay
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Exchange is a request to the GitHub API and the response to it.
+type Exchange struct {
+	Method string `json:"method"`
+	// Path is the request URI, including the query.
+	Path        string `json:"path"`
+	RequestBody string `json:"request_body,omitempty"`
+
+	Status int `json:"status"`
+	// Header holds the response headers the GitHub client relies on.
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Call is a request a plugin made to the GitHub API.
+type Call struct {
+	Plugin string `json:"plugin"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body,omitempty"`
+}
+
+// recordedHeaders are the response headers kept in exchanges. Link headers
+// only need their request URIs, which the GitHub client follows to
+// paginate, so they work against the responder too.
+var recordedHeaders = []string{"Content-Type", "Link"}
+
+// Responder stands in for the GitHub API of a local hook. It serves the
+// responses of its exchanges, or proxies reads to the upstream API and
+// records its responses. Writes are never proxied, so a hook that is not in
+// dry-run mode can run against it, and every call plugins make is logged.
+type Responder struct {
+	// Upstream is the GitHub API reads are proxied to, if set.
+	Upstream string
+	// Client is used to proxy reads.
+	Client *http.Client
+
+	lock      sync.Mutex
+	exchanges map[string][]Exchange
+	// served counts the responses served for each key, so that repeated
+	// requests are answered in the order they were recorded.
+	served   map[string]int
+	recorded []Exchange
+	calls    []Call
+}
+
+// NewResponder returns a responder serving the exchanges.
+func NewResponder(upstream string, exchanges []Exchange) *Responder {
+	r := &Responder{
+		Upstream:  upstream,
+		Client:    http.DefaultClient,
+		exchanges: map[string][]Exchange{},
+		served:    map[string]int{},
+	}
+	for _, exchange := range exchanges {
+		key := exchangeKey(exchange.Method, exchange.Path, exchange.RequestBody)
+		r.exchanges[key] = append(r.exchanges[key], exchange)
+	}
+	return r
+}
+
+func exchangeKey(method, path, body string) string {
+	return method + " " + path + "\n" + body
+}
+
+// isRead determines whether the request only reads from GitHub. GraphQL
+// queries are sent with POST, unlike mutations.
+func isRead(method, path, body string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		return strings.HasSuffix(path, "/graphql") && !strings.Contains(body, `"query":"mutation`)
+	}
+	return false
+}
+
+// pluginFromUserAgent returns the plugin in the user agent of the GitHub
+// client, which has the format component.plugin/version.
+func pluginFromUserAgent(userAgent string) string {
+	identifier := strings.SplitN(userAgent, "/", 2)[0]
+	if parts := strings.SplitN(identifier, ".", 2); len(parts) == 2 {
+		return parts[1]
+	}
+	return ""
+}
+
+func (r *Responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusInternalServerError)
+		return
+	}
+	path := req.URL.RequestURI()
+	r.lock.Lock()
+	r.calls = append(r.calls, Call{Plugin: pluginFromUserAgent(req.UserAgent()), Method: req.Method, Path: path, Body: string(body)})
+	r.lock.Unlock()
+
+	exchange, err := r.respond(req, path, body)
+	if err != nil {
+		logrus.WithError(err).WithField("path", path).Error("Failed to proxy request.")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	for _, header := range recordedHeaders {
+		if value := exchange.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	w.WriteHeader(exchange.Status)
+	fmt.Fprint(w, exchange.Body)
+}
+
+// respond returns the recorded exchange for the request, proxying and
+// recording it if there is none.
+func (r *Responder) respond(req *http.Request, path string, body []byte) (*Exchange, error) {
+	key := exchangeKey(req.Method, path, string(body))
+	r.lock.Lock()
+	if exchanges := r.exchanges[key]; len(exchanges) > 0 {
+		// Repeat the last response once the recorded ones are exhausted.
+		i := r.served[key]
+		if i >= len(exchanges) {
+			i = len(exchanges) - 1
+		}
+		r.served[key]++
+		r.lock.Unlock()
+		return &exchanges[i], nil
+	}
+	r.lock.Unlock()
+
+	if !isRead(req.Method, path, string(body)) {
+		return defaultWrite(req.Method), nil
+	}
+	if r.Upstream == "" {
+		return &Exchange{Status: http.StatusNotFound, Body: `{"message":"Not Found"}`}, nil
+	}
+	exchange, err := r.proxy(req, path, body)
+	if err != nil {
+		return nil, err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.recorded = append(r.recorded, *exchange)
+	return exchange, nil
+}
+
+// defaultWrite answers writes that were not recorded with the status the
+// GitHub API answers them with when they succeed.
+func defaultWrite(method string) *Exchange {
+	switch method {
+	case http.MethodPost:
+		return &Exchange{Status: http.StatusCreated, Body: "{}"}
+	case http.MethodDelete:
+		return &Exchange{Status: http.StatusNoContent}
+	}
+	return &Exchange{Status: http.StatusOK, Body: "{}"}
+}
+
+func (r *Responder) proxy(req *http.Request, path string, body []byte) (*Exchange, error) {
+	upstream, err := http.NewRequest(req.Method, strings.TrimSuffix(r.Upstream, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	// Keep the authorization and accept headers of the GitHub client.
+	upstream.Header = req.Header.Clone()
+	resp, err := r.Client.Do(upstream)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	exchange := &Exchange{
+		Method:      req.Method,
+		Path:        path,
+		RequestBody: string(body),
+		Status:      resp.StatusCode,
+		Header:      http.Header{},
+		Body:        string(respBody),
+	}
+	for _, header := range recordedHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			exchange.Header.Set(header, value)
+		}
+	}
+	return exchange, nil
+}
+
+// Exchanges returns the exchanges the responder served, the recorded ones
+// first, followed by the ones it proxied.
+func (r *Responder) Exchanges() []Exchange {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var keys []string
+	for key := range r.exchanges {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var exchanges []Exchange
+	for _, key := range keys {
+		exchanges = append(exchanges, r.exchanges[key]...)
+	}
+	return append(exchanges, r.recorded...)
+}
+
+// Calls returns the calls plugins made, in the order they were made.
+func (r *Responder) Calls() []Call {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Call(nil), r.calls...)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package httpretry POSTs payloads to HTTP endpoints, retrying the requests
+// that may succeed when sent again.
+package httpretry
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// DefaultBackoff makes five attempts, waiting a second after the first one
+// and twice as long after every other one.
+var DefaultBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Steps:    5,
+}
+
+// Post POSTs the body with the header to url. Requests that fail to reach
+// the endpoint, or that it answers with a server error or 429 Too Many
+// Requests, are retried with the backoff. The error of the last attempt is
+// returned.
+func Post(client *http.Client, url string, body []byte, header http.Header, backoff wait.Backoff) error {
+	var err error
+	// The condition never fails, so the result only tells whether the
+	// attempts ran out.
+	_ = wait.ExponentialBackoff(backoff, func() (bool, error) {
+		var retry bool
+		retry, err = postOnce(client, url, body, header)
+		return !retry, nil
+	})
+	return err
+}
+
+// postOnce POSTs the body once, reporting whether it may succeed if retried.
+func postOnce(client *http.Client, url string, body []byte, header http.Header) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	rb, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("response has status %q and body %q", resp.Status, string(rb))
+	}
+	return false, nil
+}
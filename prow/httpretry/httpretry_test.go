@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpretry
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestPost(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		statuses []int
+
+		expectedRequests int
+		expectedErr      bool
+	}{
+		{
+			name:             "success is not retried",
+			statuses:         []int{http.StatusOK},
+			expectedRequests: 1,
+		},
+		{
+			name:             "server error is retried",
+			statuses:         []int{http.StatusInternalServerError, http.StatusOK},
+			expectedRequests: 2,
+		},
+		{
+			name:             "rate limit is retried",
+			statuses:         []int{http.StatusTooManyRequests, http.StatusOK},
+			expectedRequests: 2,
+		},
+		{
+			name:             "client error is not retried",
+			statuses:         []int{http.StatusBadRequest},
+			expectedRequests: 1,
+			expectedErr:      true,
+		},
+		{
+			name:             "retries are bounded",
+			statuses:         []int{http.StatusServiceUnavailable},
+			expectedRequests: 3,
+			expectedErr:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int
+			endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if body, _ := ioutil.ReadAll(r.Body); string(body) != "{}" {
+					t.Errorf("expected payload {}, got %q", string(body))
+				}
+				if r.Header.Get("X-Test") != "value" {
+					t.Errorf("expected header X-Test: value, got %q", r.Header.Get("X-Test"))
+				}
+				status := tc.statuses[len(tc.statuses)-1]
+				if requests < len(tc.statuses) {
+					status = tc.statuses[requests]
+				}
+				requests++
+				w.WriteHeader(status)
+			}))
+			defer endpoint.Close()
+
+			header := http.Header{}
+			header.Set("X-Test", "value")
+			err := Post(endpoint.Client(), endpoint.URL, []byte("{}"), header, wait.Backoff{Steps: 3})
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error %t, got %v", tc.expectedErr, err)
+			}
+			if requests != tc.expectedRequests {
+				t.Errorf("expected %d requests, got %d", tc.expectedRequests, requests)
+			}
+		})
+	}
+}
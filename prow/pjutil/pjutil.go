@@ -164,7 +164,9 @@ func specFromJobBase(jb config.JobBase) prowapi.ProwJobSpec {
 		Cluster:         jb.Cluster,
 		Namespace:       namespace,
 		MaxConcurrency:  jb.MaxConcurrency,
+		Priority:        jb.Priority,
 		ErrorOnEviction: jb.ErrorOnEviction,
+		Retry:           jb.Retry,
 
 		ExtraRefs:        jb.ExtraRefs,
 		DecorationConfig: jb.DecorationConfig,
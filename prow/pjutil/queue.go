@@ -0,0 +1,135 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjutil
+
+import (
+	"sort"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+// QueueRefs returns the refs that determine which org and repo a job counts
+// against when it is queued: the refs under test or, for jobs without them,
+// the first extra refs.
+func QueueRefs(pj *prowapi.ProwJob) *prowapi.Refs {
+	if pj.Spec.Refs != nil {
+		return pj.Spec.Refs
+	}
+	if len(pj.Spec.ExtraRefs) > 0 {
+		return &pj.Spec.ExtraRefs[0]
+	}
+	return nil
+}
+
+// QueueTriggeredJobs returns the triggered jobs of the kubernetes agent in
+// the order plank tries to start them. Jobs with a higher priority come
+// first. Jobs of the same priority are ordered by a weighted fair queue over
+// the orgs and repos configured in the plank config, which accounts for the
+// jobs they already run.
+func QueueTriggeredJobs(pjs []prowapi.ProwJob, cfg config.Plank) []prowapi.ProwJob {
+	usage := map[string]int{}
+	var triggered []prowapi.ProwJob
+	for _, pj := range pjs {
+		if pj.Spec.Agent != prowapi.KubernetesAgent {
+			continue
+		}
+		switch pj.Status.State {
+		case prowapi.PendingState:
+			tenant, _ := cfg.GetFairShare(QueueRefs(&pj))
+			usage[tenant]++
+		case prowapi.TriggeredState:
+			triggered = append(triggered, pj)
+		}
+	}
+
+	sort.SliceStable(triggered, func(i, j int) bool {
+		if triggered[i].Spec.Priority != triggered[j].Spec.Priority {
+			return triggered[i].Spec.Priority > triggered[j].Spec.Priority
+		}
+		return triggered[i].CreationTimestamp.Before(&triggered[j].CreationTimestamp)
+	})
+
+	queue := make([]prowapi.ProwJob, 0, len(triggered))
+	for start := 0; start < len(triggered); {
+		end := start
+		for end < len(triggered) && triggered[end].Spec.Priority == triggered[start].Spec.Priority {
+			end++
+		}
+		queue = append(queue, fairShare(triggered[start:end], usage, cfg)...)
+		start = end
+	}
+	return queue
+}
+
+// fairShare interleaves jobs of the same priority, which must be sorted by
+// creation time. The next job is always taken from the org or repo that uses
+// the smallest part of its share, counting both its running jobs and the
+// jobs queued so far. Ties go to the older job. The usage is updated with
+// the queued jobs.
+func fairShare(jobs []prowapi.ProwJob, usage map[string]int, cfg config.Plank) []prowapi.ProwJob {
+	type share struct {
+		tenant string
+		weight int
+		jobs   []prowapi.ProwJob
+	}
+	var shares []*share
+	byTenant := map[string]*share{}
+	for _, pj := range jobs {
+		tenant, weight := cfg.GetFairShare(QueueRefs(&pj))
+		s, ok := byTenant[tenant]
+		if !ok {
+			s = &share{tenant: tenant, weight: weight}
+			byTenant[tenant] = s
+			shares = append(shares, s)
+		}
+		s.jobs = append(s.jobs, pj)
+	}
+
+	queue := make([]prowapi.ProwJob, 0, len(jobs))
+	for len(queue) < len(jobs) {
+		var next *share
+		for _, s := range shares {
+			if len(s.jobs) == 0 {
+				continue
+			}
+			if next == nil {
+				next = s
+				continue
+			}
+			// Compare usage/weight without dividing.
+			mine, theirs := usage[s.tenant]*next.weight, usage[next.tenant]*s.weight
+			if mine < theirs || mine == theirs && s.jobs[0].CreationTimestamp.Before(&next.jobs[0].CreationTimestamp) {
+				next = s
+			}
+		}
+		queue = append(queue, next.jobs[0])
+		next.jobs = next.jobs[1:]
+		usage[next.tenant]++
+	}
+	return queue
+}
+
+// QueuePositions maps the names of the jobs in the queue to their position,
+// starting at one.
+func QueuePositions(queue []prowapi.ProwJob) map[string]int {
+	positions := make(map[string]int, len(queue))
+	for i, pj := range queue {
+		positions[pj.Name] = i + 1
+	}
+	return positions
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+func TestQueueTriggeredJobs(t *testing.T) {
+	now := time.Now()
+	job := func(name, orgRepo string, priority int, state prowapi.ProwJobState, age time.Duration) prowapi.ProwJob {
+		pj := prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+			Spec: prowapi.ProwJobSpec{
+				Agent:    prowapi.KubernetesAgent,
+				Priority: priority,
+			},
+			Status: prowapi.ProwJobStatus{
+				State: state,
+			},
+		}
+		if orgRepo != "" {
+			parts := strings.SplitN(orgRepo, "/", 2)
+			pj.Spec.Refs = &prowapi.Refs{Org: parts[0], Repo: parts[1]}
+		}
+		return pj
+	}
+
+	var testCases = []struct {
+		name     string
+		pjs      []prowapi.ProwJob
+		weights  map[string]int
+		expected []string
+	}{
+		{
+			name: "single repo is ordered by creation",
+			pjs: []prowapi.ProwJob{
+				job("b", "org/repo", 0, prowapi.TriggeredState, time.Minute),
+				job("a", "org/repo", 0, prowapi.TriggeredState, time.Hour),
+				job("c", "org/repo", 0, prowapi.TriggeredState, time.Second),
+			},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name: "only triggered kubernetes jobs are queued",
+			pjs: []prowapi.ProwJob{
+				job("a", "org/repo", 0, prowapi.TriggeredState, time.Minute),
+				job("b", "org/repo", 0, prowapi.PendingState, time.Minute),
+				job("c", "org/repo", 0, prowapi.SuccessState, time.Minute),
+				func() prowapi.ProwJob {
+					pj := job("d", "org/repo", 0, prowapi.TriggeredState, time.Minute)
+					pj.Spec.Agent = prowapi.JenkinsAgent
+					return pj
+				}(),
+			},
+			expected: []string{"a"},
+		},
+		{
+			name: "higher priority comes first",
+			pjs: []prowapi.ProwJob{
+				job("old", "org/repo", 0, prowapi.TriggeredState, time.Hour),
+				job("important", "org/repo", 10, prowapi.TriggeredState, time.Second),
+				job("unimportant", "org/repo", -1, prowapi.TriggeredState, 2*time.Hour),
+			},
+			expected: []string{"important", "old", "unimportant"},
+		},
+		{
+			name: "repos take turns",
+			pjs: []prowapi.ProwJob{
+				job("flood-1", "org/flood", 0, prowapi.TriggeredState, 5*time.Minute),
+				job("flood-2", "org/flood", 0, prowapi.TriggeredState, 4*time.Minute),
+				job("flood-3", "org/flood", 0, prowapi.TriggeredState, 3*time.Minute),
+				job("other-1", "org/other", 0, prowapi.TriggeredState, time.Minute),
+				job("other-2", "org/other", 0, prowapi.TriggeredState, time.Second),
+			},
+			expected: []string{"flood-1", "other-1", "flood-2", "other-2", "flood-3"},
+		},
+		{
+			name: "running jobs count against the share",
+			pjs: []prowapi.ProwJob{
+				job("running-1", "org/flood", 0, prowapi.PendingState, time.Hour),
+				job("running-2", "org/flood", 0, prowapi.PendingState, time.Hour),
+				job("flood", "org/flood", 0, prowapi.TriggeredState, 5*time.Minute),
+				job("other-1", "org/other", 0, prowapi.TriggeredState, time.Minute),
+				job("other-2", "org/other", 0, prowapi.TriggeredState, time.Second),
+			},
+			expected: []string{"other-1", "other-2", "flood"},
+		},
+		{
+			name: "weights give bigger shares",
+			pjs: []prowapi.ProwJob{
+				job("big-1", "org/big", 0, prowapi.TriggeredState, 5*time.Minute),
+				job("big-2", "org/big", 0, prowapi.TriggeredState, 4*time.Minute),
+				job("big-3", "org/big", 0, prowapi.TriggeredState, 3*time.Minute),
+				job("small-1", "org/small", 0, prowapi.TriggeredState, 2*time.Minute),
+				job("small-2", "org/small", 0, prowapi.TriggeredState, time.Minute),
+			},
+			weights:  map[string]int{"org/big": 2},
+			expected: []string{"big-1", "small-1", "big-2", "big-3", "small-2"},
+		},
+		{
+			name: "repos of an org with a weight share it",
+			pjs: []prowapi.ProwJob{
+				job("a-1", "org/a", 0, prowapi.TriggeredState, 5*time.Minute),
+				job("b-1", "org/b", 0, prowapi.TriggeredState, 4*time.Minute),
+				job("other-1", "other/repo", 0, prowapi.TriggeredState, 3*time.Minute),
+				job("a-2", "org/a", 0, prowapi.TriggeredState, 2*time.Minute),
+			},
+			weights:  map[string]int{"org": 1},
+			expected: []string{"a-1", "other-1", "b-1", "a-2"},
+		},
+		{
+			name: "jobs without refs share one share",
+			pjs: []prowapi.ProwJob{
+				job("periodic-1", "", 0, prowapi.TriggeredState, 5*time.Minute),
+				job("periodic-2", "", 0, prowapi.TriggeredState, 4*time.Minute),
+				job("presubmit", "org/repo", 0, prowapi.TriggeredState, time.Minute),
+			},
+			expected: []string{"periodic-1", "presubmit", "periodic-2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			queue := QueueTriggeredJobs(tc.pjs, config.Plank{FairShareWeights: tc.weights})
+			var actual []string
+			for _, pj := range queue {
+				actual = append(actual, pj.Name)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected queue %v, got %v", tc.expected, actual)
+			}
+			positions := QueuePositions(queue)
+			for i, name := range tc.expected {
+				if positions[name] != i+1 {
+					t.Errorf("expected %s at position %d, got %d", name, i+1, positions[name])
+				}
+			}
+		})
+	}
+}
+
+func TestQueueRefs(t *testing.T) {
+	refs := prowapi.Refs{Org: "org", Repo: "repo"}
+	extraRefs := prowapi.Refs{Org: "extra", Repo: "repo"}
+	var testCases = []struct {
+		name     string
+		spec     prowapi.ProwJobSpec
+		expected *prowapi.Refs
+	}{
+		{
+			name: "no refs",
+		},
+		{
+			name:     "refs",
+			spec:     prowapi.ProwJobSpec{Refs: &refs, ExtraRefs: []prowapi.Refs{extraRefs}},
+			expected: &refs,
+		},
+		{
+			name:     "extra refs",
+			spec:     prowapi.ProwJobSpec{ExtraRefs: []prowapi.Refs{extraRefs}},
+			expected: &extraRefs,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := QueueRefs(&prowapi.ProwJob{Spec: tc.spec}); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
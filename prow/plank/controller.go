@@ -80,12 +80,19 @@ type Controller struct {
 	// pendingJobs is a short-lived cache that helps in limiting
 	// the maximum concurrency of jobs.
 	pendingJobs map[string]int
+	// pendingQuotas counts the pending jobs per concurrency quota
+	// of the plank config.
+	pendingQuotas map[string]int
 
 	// If `lock` is acquired as well, `lock` must be acquired before locking
 	// pjLock
 	pjLock sync.RWMutex
 	// shared across the controller and a goroutine that gathers metrics.
 	pjs []prowapi.ProwJob
+	// queue holds the triggered jobs in the order we try to start them,
+	// queuePositions maps their names to their position in the queue.
+	queue          []prowapi.ProwJob
+	queuePositions map[string]int
 
 	// if skip report job results to github
 	skipReport bool
@@ -105,6 +112,7 @@ func NewController(pjClient ctrlruntimeclient.Client, buildClients map[string]ct
 		log:           logger,
 		config:        cfg,
 		pendingJobs:   make(map[string]int),
+		pendingQuotas: make(map[string]int),
 		totURL:        totURL,
 		selector:      selector,
 		skipReport:    skipReport,
@@ -129,8 +137,15 @@ func (c *Controller) canExecuteConcurrently(pj *prowapi.ProwJob) bool {
 		}
 	}
 
+	quotaKey, quota := c.config().Plank.GetConcurrencyQuota(pjutil.QueueRefs(pj))
+	if quotaKey != "" && c.pendingQuotas[quotaKey] >= quota {
+		c.log.WithFields(pjutil.ProwJobFields(pj)).Debugf("Not starting another job for %s, already %d running.", quotaKey, c.pendingQuotas[quotaKey])
+		return false
+	}
+
 	if pj.Spec.MaxConcurrency == 0 {
 		c.pendingJobs[pj.Spec.Job]++
+		c.incrementPendingQuota(quotaKey)
 		return true
 	}
 
@@ -149,7 +164,7 @@ func (c *Controller) canExecuteConcurrently(pj *prowapi.ProwJob) bool {
 		if foundPJ.Spec.Job != pj.Spec.Job {
 			continue
 		}
-		if foundPJ.CreationTimestamp.Before(&pj.CreationTimestamp) {
+		if c.queuedBefore(&foundPJ, pj) {
 			olderMatchingPJs++
 		}
 	}
@@ -163,15 +178,43 @@ func (c *Controller) canExecuteConcurrently(pj *prowapi.ProwJob) bool {
 	}
 
 	c.pendingJobs[pj.Spec.Job]++
+	c.incrementPendingQuota(quotaKey)
 	return true
 }
 
+// queuedBefore returns true if a is ahead of b in the queue of triggered
+// jobs. Jobs that are not queued are ordered by creation time. Callers must
+// hold pjLock.
+func (c *Controller) queuedBefore(a, b *prowapi.ProwJob) bool {
+	posA, queuedA := c.queuePositions[a.Name]
+	posB, queuedB := c.queuePositions[b.Name]
+	if queuedA && queuedB {
+		return posA < posB
+	}
+	return a.CreationTimestamp.Before(&b.CreationTimestamp)
+}
+
 // incrementNumPendingJobs increments the amount of
-// pending ProwJobs for the given job identifier
-func (c *Controller) incrementNumPendingJobs(job string) {
+// pending ProwJobs for the given job identifier and
+// its concurrency quota
+func (c *Controller) incrementNumPendingJobs(pj prowapi.ProwJob) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	c.pendingJobs[job]++
+	c.pendingJobs[pj.Spec.Job]++
+	quotaKey, _ := c.config().Plank.GetConcurrencyQuota(pjutil.QueueRefs(&pj))
+	c.incrementPendingQuota(quotaKey)
+}
+
+// incrementPendingQuota counts a pending job against the given
+// concurrency quota. Callers must hold lock.
+func (c *Controller) incrementPendingQuota(quotaKey string) {
+	if quotaKey == "" {
+		return
+	}
+	if c.pendingQuotas == nil {
+		c.pendingQuotas = make(map[string]int)
+	}
+	c.pendingQuotas[quotaKey]++
 }
 
 // setPreviousReportState sets the github key for PrevReportStates
@@ -265,18 +308,30 @@ func (c *Controller) Sync() error {
 		syncErrs = append(syncErrs, err)
 	}
 
+	// Order triggered jobs by priority and fair share so jobs further up
+	// the queue get a better chance to be picked up earlier.
+	queue := pjutil.QueueTriggeredJobs(k8sJobs, c.config().Plank)
+
 	// Share what we have for gathering metrics.
 	c.pjLock.Lock()
 	c.pjs = k8sJobs
+	c.queue = queue
+	c.queuePositions = pjutil.QueuePositions(queue)
 	c.pjLock.Unlock()
 
-	pendingCh, triggeredCh, abortedCh := pjutil.PartitionActive(k8sJobs)
+	pendingCh, _, abortedCh := pjutil.PartitionActive(k8sJobs)
+	triggeredCh := make(chan prowapi.ProwJob, len(queue))
+	for _, pj := range queue {
+		triggeredCh <- pj
+	}
+	close(triggeredCh)
 	errCh := make(chan error, len(k8sJobs))
 	reportCh := make(chan prowapi.ProwJob, len(k8sJobs))
 
 	// Reinstantiate on every resync of the controller instead of trying
 	// to keep this in sync with the state of the world.
 	c.pendingJobs = make(map[string]int)
+	c.pendingQuotas = make(map[string]int)
 	// Sync pending jobs first so we can determine what is the maximum
 	// number of new jobs we can trigger when syncing the non-pendings.
 	maxSyncRoutines := c.config().Plank.MaxGoroutines
@@ -322,6 +377,7 @@ func (c *Controller) SyncMetrics() {
 	c.pjLock.RLock()
 	defer c.pjLock.RUnlock()
 	kube.GatherProwJobMetrics(c.pjs)
+	gatherQueueMetrics(c.queue, c.config().Plank)
 }
 
 // terminateDupes aborts presubmits that have a newer version. It modifies pjs
@@ -379,11 +435,26 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]corev1.Pod
 	prevState := pj.Status.State
 	prevPJ := *pj.DeepCopy()
 
+	// reason is why the pod ended, if the job is retried it is recorded in the attempt.
+	var reason string
 	pod, podExists := pm[pj.ObjectMeta.Name]
+	if podExists && isRetriedPod(pj, pod) {
+		// The pod belongs to an attempt we already recorded, but it has not been
+		// deleted yet. Get rid of it, a new pod is started in the next resync.
+		c.incrementNumPendingJobs(pj)
+		return c.deletePod(pj, pod)
+	}
 	if !podExists {
-		c.incrementNumPendingJobs(pj.Spec.Job)
+		c.incrementNumPendingJobs(pj)
+		if attempts := len(pj.Status.Attempts); attempts > 0 {
+			last := pj.Status.Attempts[attempts-1]
+			if wait := pj.Spec.Retry.BackoffFor(attempts) - c.clock.Since(last.CompletionTime.Time); wait > 0 {
+				c.log.WithFields(pjutil.ProwJobFields(&pj)).Debugf("Waiting %v before retrying the job.", wait)
+				return nil
+			}
+		}
 		// Pod is missing. This can happen in case the previous pod was deleted manually or by
-		// a rescheduler. Start a new pod.
+		// a rescheduler, or the job is retried. Start a new pod.
 		id, pn, err := c.startPod(pj)
 		if err != nil {
 			if !isRequestError(err) {
@@ -396,13 +467,24 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]corev1.Pod
 		} else {
 			pj.Status.BuildID = id
 			pj.Status.PodName = pn
+			if attempts := len(pj.Status.Attempts); attempts > 0 {
+				pj.Status.Description = fmt.Sprintf("Job retried, attempt %d of %d.", attempts+1, pj.Spec.Retry.MaxAttempts)
+			}
 			c.log.WithFields(pjutil.ProwJobFields(&pj)).Info("Pod is missing, starting a new pod")
 		}
 	} else {
 
 		switch pod.Status.Phase {
 		case corev1.PodUnknown:
-			c.incrementNumPendingJobs(pj.Spec.Job)
+			if pj.Spec.Retry != nil {
+				// The job has a retry policy, so it decides whether we start a new pod.
+				pj.SetComplete()
+				pj.Status.State = prowapi.ErrorState
+				pj.Status.Description = "Pod is in unknown state."
+				reason = prowapi.PodUnknownReason
+				break
+			}
+			c.incrementNumPendingJobs(pj)
 			// Pod is in Unknown state. This can happen if there is a problem with
 			// the node. Delete the old pod, we'll start a new one next loop.
 			c.log.WithFields(pjutil.ProwJobFields(&pj)).Info("Pod is in unknown state, deleting & restarting pod")
@@ -423,16 +505,18 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]corev1.Pod
 		case corev1.PodFailed:
 			if pod.Status.Reason == Evicted {
 				// Pod was evicted.
-				if pj.Spec.ErrorOnEviction {
-					// ErrorOnEviction is enabled, complete the PJ and mark it as errored.
+				if pj.Spec.ErrorOnEviction || pj.Spec.Retry != nil {
+					// ErrorOnEviction is enabled or the retry policy decides whether
+					// we start a new pod, complete the PJ and mark it as errored.
 					pj.SetComplete()
 					pj.Status.State = prowapi.ErrorState
 					pj.Status.Description = "Job pod was evicted by the cluster."
+					reason = Evicted
 					break
 				}
 				// ErrorOnEviction is disabled. Delete the pod now and recreate it in
 				// the next resync.
-				c.incrementNumPendingJobs(pj.Spec.Job)
+				c.incrementNumPendingJobs(pj)
 				client, ok := c.buildClients[pj.ClusterAlias()]
 				if !ok {
 					return fmt.Errorf("evicted pod %s: unknown cluster alias %q", pod.Name, pj.ClusterAlias())
@@ -444,6 +528,7 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]corev1.Pod
 			pj.SetComplete()
 			pj.Status.State = prowapi.FailureState
 			pj.Status.Description = "Job failed."
+			reason = podFailureReason(pod)
 
 		case corev1.PodPending:
 			maxPodPending := c.config().Plank.PodPendingTimeout.Duration
@@ -455,6 +540,7 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]corev1.Pod
 					pj.SetComplete()
 					pj.Status.State = prowapi.ErrorState
 					pj.Status.Description = "Pod scheduling timeout."
+					reason = prowapi.PodSchedulingTimeoutReason
 					c.log.WithFields(pjutil.ProwJobFields(&pj)).Info("Marked job for stale unscheduled pod as errored.")
 					break
 				}
@@ -464,17 +550,18 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]corev1.Pod
 				pj.SetComplete()
 				pj.Status.State = prowapi.ErrorState
 				pj.Status.Description = "Pod pending timeout."
+				reason = prowapi.PodPendingTimeoutReason
 				c.log.WithFields(pjutil.ProwJobFields(&pj)).Info("Marked job for stale pending pod as errored.")
 				break
 			}
 			// Pod is running. Do nothing.
-			c.incrementNumPendingJobs(pj.Spec.Job)
+			c.incrementNumPendingJobs(pj)
 			return nil
 		case corev1.PodRunning:
 			maxPodRunning := c.config().Plank.PodRunningTimeout.Duration
 			if pod.Status.StartTime.IsZero() || time.Since(pod.Status.StartTime.Time) < maxPodRunning {
 				// Pod is still running. Do nothing.
-				c.incrementNumPendingJobs(pj.Spec.Job)
+				c.incrementNumPendingJobs(pj)
 				return nil
 			}
 
@@ -493,11 +580,17 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]corev1.Pod
 			c.log.WithFields(pjutil.ProwJobFields(&pj)).Info("Deleted stale running pod.")
 		default:
 			// other states, ignore
-			c.incrementNumPendingJobs(pj.Spec.Job)
+			c.incrementNumPendingJobs(pj)
 			return nil
 		}
 	}
 
+	if podExists && pj.Complete() && pj.Spec.Retry.ShouldRetry(len(pj.Status.Attempts)+1, pj.Status.State, reason) {
+		// Nothing is reported for a retried attempt, the job stays pending until
+		// the last attempt ends.
+		return c.retryJob(pj, prevPJ, pod, reason)
+	}
+
 	pj.Status.URL = pjutil.JobURL(c.config().Plank, pj, c.log)
 
 	reports <- pj
@@ -511,6 +604,71 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]corev1.Pod
 	return c.prowJobClient.Patch(c.ctx, pj.DeepCopy(), ctrlruntimeclient.MergeFrom(&prevPJ))
 }
 
+// retryJob records the attempt that just ended, moves the job back to pending
+// and deletes the pod of the attempt so that the next resync starts a new one.
+func (c *Controller) retryJob(pj, prevPJ prowapi.ProwJob, pod corev1.Pod, reason string) error {
+	c.incrementNumPendingJobs(pj)
+	attempt := prowapi.ProwJobAttempt{
+		BuildID:        pj.Status.BuildID,
+		PodName:        pod.Name,
+		CompletionTime: *pj.Status.CompletionTime,
+		State:          pj.Status.State,
+		Reason:         reason,
+		Description:    pj.Status.Description,
+	}
+	pj.Status.Attempts = append(pj.Status.Attempts, attempt)
+	pj.Status.CompletionTime = nil
+	pj.Status.State = prowapi.PendingState
+	pj.Status.Description = fmt.Sprintf("%s Retrying.", attempt.Description)
+	c.log.WithFields(pjutil.ProwJobFields(&pj)).
+		WithField("attempt", len(pj.Status.Attempts)).
+		WithField("reason", reason).Info("Retrying job.")
+
+	// Record the attempt before deleting the pod, otherwise it is lost if the update fails.
+	if err := c.prowJobClient.Patch(c.ctx, pj.DeepCopy(), ctrlruntimeclient.MergeFrom(&prevPJ)); err != nil {
+		return fmt.Errorf("failed to record attempt: %v", err)
+	}
+	return c.deletePod(pj, pod)
+}
+
+// deletePod deletes the pod of the given job, a pod that is already gone is no error.
+func (c *Controller) deletePod(pj prowapi.ProwJob, pod corev1.Pod) error {
+	client, ok := c.buildClients[pj.ClusterAlias()]
+	if !ok {
+		return fmt.Errorf("pod %s: unknown cluster alias %q", pod.Name, pj.ClusterAlias())
+	}
+	c.log.WithField("name", pod.Name).Debug("Delete Pod.")
+	if err := client.Delete(c.ctx, &pod); err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete pod %s: %v", pod.Name, err)
+	}
+	return nil
+}
+
+// isRetriedPod returns true if the pod ran the last recorded attempt of the job.
+func isRetriedPod(pj prowapi.ProwJob, pod corev1.Pod) bool {
+	attempts := len(pj.Status.Attempts)
+	if attempts == 0 {
+		return false
+	}
+	return pj.Status.Attempts[attempts-1].BuildID == getPodBuildID(&pod)
+}
+
+// podFailureReason returns why a failed pod ended: the reason of the pod status
+// or else the termination reason of the first container that failed.
+func podFailureReason(pod corev1.Pod) string {
+	if pod.Status.Reason != "" {
+		return pod.Status.Reason
+	}
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+				return terminated.Reason
+			}
+		}
+	}
+	return ""
+}
+
 func (c *Controller) syncAbortedJob(pj prowapi.ProwJob, pm map[string]corev1.Pod, _ chan<- prowapi.ProwJob) error {
 	if pj.Status.State != prowapi.AbortedState || pj.Complete() {
 		return nil
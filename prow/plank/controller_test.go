@@ -841,6 +841,7 @@ func TestSyncPendingJob(t *testing.T) {
 		expectedCreatedPJs int
 		expectedReport     bool
 		expectedURL        string
+		expectedAttempts   int
 	}{
 		{
 			name: "reset when pod goes missing",
@@ -1031,6 +1032,263 @@ func TestSyncPendingJob(t *testing.T) {
 			expectedReport:   true,
 			expectedURL:      "boop-42/error",
 		},
+		{
+			name: "retry evicted pod",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-42",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Retry:   &prowapi.RetryPolicy{MaxAttempts: 2},
+					PodSpec: &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "boop-42",
+					BuildID: "1",
+				},
+			},
+			pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "boop-42",
+						Namespace: "pods",
+					},
+					Status: v1.PodStatus{
+						Phase:  v1.PodFailed,
+						Reason: Evicted,
+					},
+				},
+			},
+			expectedState:    prowapi.PendingState,
+			expectedNumPods:  0,
+			expectedAttempts: 1,
+		},
+		{
+			name: "error evicted pod when retries are exhausted",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-42",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Retry:   &prowapi.RetryPolicy{MaxAttempts: 2},
+					PodSpec: &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:    prowapi.PendingState,
+					PodName:  "boop-42",
+					BuildID:  "2",
+					Attempts: []prowapi.ProwJobAttempt{{BuildID: "1", State: prowapi.ErrorState, Reason: Evicted}},
+				},
+			},
+			pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "boop-42",
+						Namespace: "pods",
+					},
+					Spec: v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{{Name: "BUILD_ID", Value: "2"}}}}},
+					Status: v1.PodStatus{
+						Phase:  v1.PodFailed,
+						Reason: Evicted,
+					},
+				},
+			},
+			expectedComplete: true,
+			expectedState:    prowapi.ErrorState,
+			expectedNumPods:  1,
+			expectedReport:   true,
+			expectedURL:      "boop-42/error",
+			expectedAttempts: 1,
+		},
+		{
+			name: "do not retry failed pod by default",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-42",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Retry:   &prowapi.RetryPolicy{MaxAttempts: 2},
+					PodSpec: &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "boop-42",
+				},
+			},
+			pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "boop-42",
+						Namespace: "pods",
+					},
+					Status: v1.PodStatus{
+						Phase: v1.PodFailed,
+					},
+				},
+			},
+			expectedComplete: true,
+			expectedState:    prowapi.FailureState,
+			expectedNumPods:  1,
+			expectedReport:   true,
+			expectedURL:      "boop-42/failure",
+		},
+		{
+			name: "retry failed pod with retryable container termination reason",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-42",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Retry: &prowapi.RetryPolicy{
+						MaxAttempts: 3,
+						States:      []prowapi.ProwJobState{prowapi.FailureState},
+						Reasons:     []string{"OOMKilled"},
+					},
+					PodSpec: &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "boop-42",
+				},
+			},
+			pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "boop-42",
+						Namespace: "pods",
+					},
+					Status: v1.PodStatus{
+						Phase: v1.PodFailed,
+						ContainerStatuses: []v1.ContainerStatus{{
+							Name:  "test-name",
+							State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+						}},
+					},
+				},
+			},
+			expectedState:    prowapi.PendingState,
+			expectedNumPods:  0,
+			expectedAttempts: 1,
+		},
+		{
+			name: "retry pod in unknown state",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-41",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Retry:   &prowapi.RetryPolicy{MaxAttempts: 2, Reasons: []string{prowapi.PodUnknownReason}},
+					PodSpec: &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "boop-41",
+				},
+			},
+			pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "boop-41",
+						Namespace: "pods",
+					},
+					Status: v1.PodStatus{
+						Phase: v1.PodUnknown,
+					},
+				},
+			},
+			expectedState:    prowapi.PendingState,
+			expectedNumPods:  0,
+			expectedAttempts: 1,
+		},
+		{
+			name: "delete pod of recorded attempt",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-42",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Retry:   &prowapi.RetryPolicy{MaxAttempts: 2},
+					PodSpec: &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:    prowapi.PendingState,
+					PodName:  "boop-42",
+					BuildID:  "1",
+					Attempts: []prowapi.ProwJobAttempt{{BuildID: "1", State: prowapi.ErrorState, Reason: Evicted}},
+				},
+			},
+			pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "boop-42",
+						Namespace: "pods",
+					},
+					Spec: v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{{Name: "BUILD_ID", Value: "1"}}}}},
+					Status: v1.PodStatus{
+						Phase:  v1.PodFailed,
+						Reason: Evicted,
+					},
+				},
+			},
+			expectedState:    prowapi.PendingState,
+			expectedNumPods:  0,
+			expectedAttempts: 1,
+		},
+		{
+			name: "wait for backoff before starting the next attempt",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-41",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Type:    prowapi.PostsubmitJob,
+					Retry:   &prowapi.RetryPolicy{MaxAttempts: 2, Backoff: &prowapi.Duration{Duration: time.Hour}},
+					PodSpec: &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+					Refs:    &prowapi.Refs{Org: "fejtaverse"},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:    prowapi.PendingState,
+					PodName:  "boop-41",
+					Attempts: []prowapi.ProwJobAttempt{{BuildID: "1", State: prowapi.ErrorState, CompletionTime: metav1.NewTime(time.Now())}},
+				},
+			},
+			expectedState:    prowapi.PendingState,
+			expectedNumPods:  0,
+			expectedAttempts: 1,
+		},
+		{
+			name: "start the next attempt after backoff",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-41",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Type:    prowapi.PostsubmitJob,
+					Retry:   &prowapi.RetryPolicy{MaxAttempts: 2, Backoff: &prowapi.Duration{Duration: time.Hour}},
+					PodSpec: &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+					Refs:    &prowapi.Refs{Org: "fejtaverse"},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:    prowapi.PendingState,
+					PodName:  "boop-41",
+					Attempts: []prowapi.ProwJobAttempt{{BuildID: "1", State: prowapi.ErrorState, CompletionTime: metav1.NewTime(time.Now().Add(-2 * time.Hour))}},
+				},
+			},
+			expectedState:    prowapi.PendingState,
+			expectedNumPods:  1,
+			expectedReport:   true,
+			expectedURL:      "boop-41/pending",
+			expectedAttempts: 1,
+		},
 		{
 			name: "running pod",
 			pj: prowapi.ProwJob{
@@ -1329,6 +1587,9 @@ func TestSyncPendingJob(t *testing.T) {
 		if actual.Complete() != tc.expectedComplete {
 			t.Errorf("for case %q got wrong completion", tc.name)
 		}
+		if got := len(actual.Status.Attempts); got != tc.expectedAttempts {
+			t.Errorf("for case %q got %d attempts, expected %d", tc.name, got, tc.expectedAttempts)
+		}
 		if tc.expectedReport && len(reports) != 1 {
 			t.Errorf("for case %q wanted one report but got %d", tc.name, len(reports))
 		}
@@ -1664,6 +1925,9 @@ func TestMaxConcurency(t *testing.T) {
 		prowJob          prowapi.ProwJob
 		existingProwJobs []prowapi.ProwJob
 		pendingJobs      map[string]int
+		pendingQuotas    map[string]int
+		quotas           map[string]int
+		queuePositions   map[string]int
 		expectedResult   bool
 	}{
 		{
@@ -1759,6 +2023,63 @@ func TestMaxConcurency(t *testing.T) {
 			pendingJobs:    map[string]int{"my-pj": 1},
 			expectedResult: true,
 		},
+		{
+			name: "Have newer jobs ahead in the queue, cannot execute",
+			prowJob: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "behind",
+				},
+				Spec: prowapi.ProwJobSpec{
+					MaxConcurrency: 1,
+					Job:            "my-pj"},
+			},
+			existingProwJobs: []prowapi.ProwJob{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "ahead",
+						CreationTimestamp: metav1.Now(),
+					},
+					Spec: prowapi.ProwJobSpec{Job: "my-pj"},
+					Status: prowapi.ProwJobStatus{
+						State: prowapi.TriggeredState,
+					}},
+			},
+			queuePositions: map[string]int{"ahead": 1, "behind": 2},
+			expectedResult: false,
+		},
+		{
+			name: "Num pending reaches org quota",
+			prowJob: prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					Job:  "my-pj",
+					Refs: &prowapi.Refs{Org: "org", Repo: "repo"}},
+			},
+			quotas:         map[string]int{"org": 2},
+			pendingQuotas:  map[string]int{"org": 2},
+			expectedResult: false,
+		},
+		{
+			name: "Repo quota takes precedence over org quota",
+			prowJob: prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					Job:  "my-pj",
+					Refs: &prowapi.Refs{Org: "org", Repo: "repo"}},
+			},
+			quotas:         map[string]int{"org": 2, "org/repo": 5},
+			pendingQuotas:  map[string]int{"org": 2, "org/repo": 1},
+			expectedResult: true,
+		},
+		{
+			name: "Quota applies to extra refs of jobs without refs",
+			prowJob: prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					Job:       "my-pj",
+					ExtraRefs: []prowapi.Refs{{Org: "org", Repo: "repo"}}},
+			},
+			quotas:         map[string]int{"org/repo": 1},
+			pendingQuotas:  map[string]int{"org/repo": 1},
+			expectedResult: false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1772,13 +2093,17 @@ func TestMaxConcurency(t *testing.T) {
 				prowJobs = append(prowJobs, &tc.existingProwJobs[i])
 			}
 			buildClients := map[string]ctrlruntimeclient.Client{}
+			fca := newFakeConfigAgent(t, 0)
+			fca.c.Plank.ConcurrencyQuotas = tc.quotas
 			c := Controller{
-				pjs:          tc.existingProwJobs,
-				buildClients: buildClients,
-				log:          logrus.NewEntry(logrus.StandardLogger()),
-				config:       newFakeConfigAgent(t, 0).Config,
-				pendingJobs:  tc.pendingJobs,
-				clock:        clock.RealClock{},
+				pjs:            tc.existingProwJobs,
+				queuePositions: tc.queuePositions,
+				buildClients:   buildClients,
+				log:            logrus.NewEntry(logrus.StandardLogger()),
+				config:         fca.Config,
+				pendingJobs:    tc.pendingJobs,
+				pendingQuotas:  tc.pendingQuotas,
+				clock:          clock.RealClock{},
 			}
 			logrus.SetLevel(logrus.DebugLevel)
 
@@ -0,0 +1,74 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/pjutil"
+)
+
+var queueMetrics = struct {
+	length   *prometheus.GaugeVec
+	position *prometheus.GaugeVec
+}{
+	length: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "plank_queue_length",
+		Help: "Number of triggered prowjobs waiting to be started, by the org or repo whose fair share they use.",
+	}, []string{
+		"share",
+	}),
+	position: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "plank_queue_position",
+		Help: "Position of the first triggered prowjob of a job in the queue of jobs waiting to be started.",
+	}, []string{
+		"job_name",
+		"priority",
+		"share",
+	}),
+}
+
+func init() {
+	prometheus.MustRegister(queueMetrics.length)
+	prometheus.MustRegister(queueMetrics.position)
+}
+
+// gatherQueueMetrics replaces the queue metrics with the given queue.
+func gatherQueueMetrics(queue []prowapi.ProwJob, cfg config.Plank) {
+	queueMetrics.length.Reset()
+	queueMetrics.position.Reset()
+	// Only the first prowjob of each job is recorded so that the metric
+	// has one series per job rather than one per prowjob.
+	seen := sets.NewString()
+	for i, pj := range queue {
+		share, _ := cfg.GetFairShare(pjutil.QueueRefs(&pj))
+		queueMetrics.length.WithLabelValues(share).Inc()
+		priority := strconv.Itoa(pj.Spec.Priority)
+		key := strings.Join([]string{pj.Spec.Job, priority, share}, "/")
+		if seen.Has(key) {
+			continue
+		}
+		seen.Insert(key)
+		queueMetrics.position.WithLabelValues(pj.Spec.Job, priority, share).Set(float64(i + 1))
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"math/rand"
 	"regexp"
 	"sort"
+	"strings"
 
 	githubql "github.com/shurcooL/githubv4"
 	"github.com/sirupsen/logrus"
@@ -66,10 +67,21 @@ func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhel
 		reviewCount = *config.Blunderbuss.FileWeightCount
 	}
 
+	configInfo := configString(reviewCount)
+	if config.Blunderbuss.MaxOpenReviews > 0 {
+		configInfo += fmt.Sprintf(" Reviewers with %d or more open review requests are skipped.", config.Blunderbuss.MaxOpenReviews)
+	}
+	if config.Blunderbuss.BalanceLoad {
+		configInfo += " Reviewers with the fewest open review requests are preferred."
+	}
+	if len(config.Blunderbuss.OutOfOffice) > 0 || config.Blunderbuss.OutOfOfficeFile != "" {
+		configInfo += " Reviewers who are out of office are skipped."
+	}
+
 	pluginHelp := &pluginhelp.PluginHelp{
 		Description: "The blunderbuss plugin automatically requests reviews from reviewers when a new PR is created. The reviewers are selected based on the reviewers specified in the OWNERS files that apply to the files modified by the PR.",
 		Config: map[string]string{
-			"": configString(reviewCount),
+			"": configInfo,
 		},
 	}
 	pluginHelp.AddCommand(pluginhelp.Command{
@@ -116,6 +128,8 @@ type githubClient interface {
 	RequestReview(org, repo string, number int, logins []string) error
 	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
 	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
+	GetFile(org, repo, filepath, commit string) ([]byte, error)
+	CreateComment(owner, repo string, number int, comment string) error
 	Query(context.Context, interface{}, map[string]interface{}) error
 }
 
@@ -140,18 +154,7 @@ func handlePullRequest(ghc githubClient, roc repoownersClient, log *logrus.Entry
 		return nil
 	}
 
-	return handle(
-		ghc,
-		roc,
-		log,
-		config.ReviewerCount,
-		config.FileWeightCount,
-		config.MaxReviewerCount,
-		config.ExcludeApprovers,
-		config.UseStatusAvailability,
-		repo,
-		pr,
-	)
+	return handle(ghc, roc, log, config, repo, pr)
 }
 
 func handleGenericCommentEvent(pc plugins.Agent, ce github.GenericCommentEvent) error {
@@ -183,21 +186,11 @@ func handleGenericComment(ghc githubClient, roc repoownersClient, log *logrus.En
 		return fmt.Errorf("error loading PullRequest: %v", err)
 	}
 
-	return handle(
-		ghc,
-		roc,
-		log,
-		config.ReviewerCount,
-		config.FileWeightCount,
-		config.MaxReviewerCount,
-		config.ExcludeApprovers,
-		config.UseStatusAvailability,
-		repo,
-		pr,
-	)
+	return handle(ghc, roc, log, config, repo, pr)
 }
 
-func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerCount, oldReviewCount *int, maxReviewers int, excludeApprovers bool, useStatusAvailability bool, repo *github.Repo, pr *github.PullRequest) error {
+func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, config plugins.Blunderbuss, repo *github.Repo, pr *github.PullRequest) error {
+	reviewerCount, oldReviewCount, maxReviewers := config.ReviewerCount, config.FileWeightCount, config.MaxReviewerCount
 	oc, err := roc.LoadRepoOwners(repo.Owner.Login, repo.Name, pr.Base.Ref)
 	if err != nil {
 		return fmt.Errorf("error loading RepoOwners: %v", err)
@@ -210,22 +203,28 @@ func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerC
 
 	var reviewers []string
 	var requiredReviewers []string
+	var picker *reviewerPicker
 	switch {
 	case oldReviewCount != nil:
 		reviewers = getReviewersOld(log, oc, pr.User.Login, changes, *oldReviewCount)
 	case reviewerCount != nil:
-		reviewers, requiredReviewers, err = getReviewers(oc, ghc, log, pr.User.Login, changes, *reviewerCount, useStatusAvailability)
+		outOfOffice, err := loadOutOfOffice(ghc, config, repo.Owner.Login, repo.Name, pr.Base.Ref)
+		if err != nil {
+			return err
+		}
+		picker = newReviewerPicker(ghc, log, config, repo.Owner.Login, outOfOffice)
+		reviewers, requiredReviewers, err = getReviewers(oc, picker, pr.User.Login, changes, *reviewerCount)
 		if err != nil {
 			return err
 		}
 		if missing := *reviewerCount - len(reviewers); missing > 0 {
-			if !excludeApprovers {
+			if !config.ExcludeApprovers {
 				// Attempt to use approvers as additional reviewers. This must use
 				// reviewerCount instead of missing because owners can be both reviewers
 				// and approvers and the search might stop too early if it finds
 				// duplicates.
 				frc := fallbackReviewersClient{ownersClient: oc}
-				approvers, _, err := getReviewers(frc, ghc, log, pr.User.Login, changes, *reviewerCount, useStatusAvailability)
+				approvers, _, err := getReviewers(frc, picker, pr.User.Login, changes, *reviewerCount)
 				if err != nil {
 					return err
 				}
@@ -248,19 +247,24 @@ func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerC
 	// add required reviewers if any
 	reviewers = append(reviewers, requiredReviewers...)
 
-	if len(reviewers) > 0 {
-		log.Infof("Requesting reviews from users %s.", reviewers)
-		return ghc.RequestReview(repo.Owner.Login, repo.Name, pr.Number, reviewers)
+	if len(reviewers) == 0 {
+		return nil
+	}
+	log.Infof("Requesting reviews from users %s.", reviewers)
+	if err := ghc.RequestReview(repo.Owner.Login, repo.Name, pr.Number, reviewers); err != nil {
+		return err
 	}
-	return nil
+	if picker == nil || !picker.explains() {
+		return nil
+	}
+	return ghc.CreateComment(repo.Owner.Login, repo.Name, pr.Number, picker.explanation(reviewers))
 }
 
-func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, author string, files []github.PullRequestChange, minReviewers int, useStatusAvailability bool) ([]string, []string, error) {
+func getReviewers(rc reviewersClient, picker *reviewerPicker, author string, files []github.PullRequestChange, minReviewers int) ([]string, []string, error) {
 	authorSet := sets.NewString(github.NormLogin(author))
 	reviewers := sets.NewString()
 	requiredReviewers := sets.NewString()
 	leafReviewers := sets.NewString()
-	busyReviewers := sets.NewString()
 	ownersSeen := sets.NewString()
 	// first build 'reviewers' by taking a unique reviewer from each OWNERS file.
 	for _, file := range files {
@@ -278,14 +282,14 @@ func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, autho
 			continue
 		}
 		leafReviewers = leafReviewers.Union(fileUnusedLeafs)
-		if r := findReviewer(ghc, log, useStatusAvailability, &busyReviewers, &fileUnusedLeafs); r != "" {
+		if r := picker.pick(&fileUnusedLeafs); r != "" {
 			reviewers.Insert(r)
 		}
 	}
 	// now ensure that we request review from at least minReviewers reviewers. Favor leaf reviewers.
 	unusedLeafs := leafReviewers.Difference(reviewers)
 	for reviewers.Len() < minReviewers && unusedLeafs.Len() > 0 {
-		if r := findReviewer(ghc, log, useStatusAvailability, &busyReviewers, &unusedLeafs); r != "" {
+		if r := picker.pick(&unusedLeafs); r != "" {
 			reviewers.Insert(r)
 		}
 	}
@@ -295,7 +299,7 @@ func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, autho
 		}
 		fileReviewers := rc.Reviewers(file.Filename).Difference(authorSet)
 		for reviewers.Len() < minReviewers && fileReviewers.Len() > 0 {
-			if r := findReviewer(ghc, log, useStatusAvailability, &busyReviewers, &fileReviewers); r != "" {
+			if r := picker.pick(&fileReviewers); r != "" {
 				reviewers.Insert(r)
 			}
 		}
@@ -312,36 +316,254 @@ func popRandom(set *sets.String) string {
 	return sel
 }
 
-// findReviewer finds a reviewer from a set, potentially using status
-// availability.
-func findReviewer(ghc githubClient, log *logrus.Entry, useStatusAvailability bool, busyReviewers, targetSet *sets.String) string {
-	// if we don't care about status availability, just pop a target from the set
-	if !useStatusAvailability {
-		return popRandom(targetSet)
+// reviewerPicker picks reviewers from sets of candidates, skipping those who
+// are out of office, at their open review cap or busy, and preferring those
+// with the fewest open review requests when balancing load.
+type reviewerPicker struct {
+	ghc githubClient
+	log *logrus.Entry
+	org string
+
+	useStatusAvailability bool
+	maxOpenReviews        int
+	balanceLoad           bool
+	outOfOffice           sets.String
+
+	// unavailable caches the candidates already skipped
+	unavailable sets.String
+	// load caches the number of open review requests of each candidate,
+	// -1 meaning it could not be determined
+	load map[string]int
+	// notes records why each candidate was skipped
+	notes map[string]string
+}
+
+func newReviewerPicker(ghc githubClient, log *logrus.Entry, config plugins.Blunderbuss, org string, outOfOffice sets.String) *reviewerPicker {
+	return &reviewerPicker{
+		ghc:                   ghc,
+		log:                   log,
+		org:                   org,
+		useStatusAvailability: config.UseStatusAvailability,
+		maxOpenReviews:        config.MaxOpenReviews,
+		balanceLoad:           config.BalanceLoad,
+		outOfOffice:           outOfOffice,
+		unavailable:           sets.NewString(),
+		load:                  map[string]int{},
+		notes:                 map[string]string{},
 	}
+}
 
-	// if we do care, start looping through the candidates
-	for {
-		if targetSet.Len() == 0 {
-			// if there are no candidates left, then break
-			break
+// pick pops candidates from targetSet until it finds an available one.
+// It returns an empty string if none of them is available.
+func (p *reviewerPicker) pick(targetSet *sets.String) string {
+	for targetSet.Len() > 0 {
+		var candidate string
+		if p.balanceLoad {
+			candidate = p.popLeastLoaded(targetSet)
+		} else {
+			candidate = popRandom(targetSet)
+		}
+		if p.available(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// loadSamples is how many candidates popLeastLoaded compares, so that large
+// sets of candidates do not cost a search per candidate.
+const loadSamples = 5
+
+// popLeastLoaded pops the candidate with the fewest open review requests
+// among a random sample of the candidates not known to be unavailable, ties
+// being broken at random.
+func (p *reviewerPicker) popLeastLoaded(set *sets.String) string {
+	var eligible []string
+	for _, candidate := range set.List() {
+		if p.unavailable.Has(candidate) {
+			continue
 		}
-		candidate := popRandom(targetSet)
-		if busyReviewers.Has(candidate) {
-			// we've already verified this reviewer is busy
+		if p.outOfOffice.Has(candidate) {
+			p.skip(candidate, "out of office")
 			continue
 		}
-		busy, err := isUserBusy(ghc, candidate)
+		eligible = append(eligible, candidate)
+	}
+	if len(eligible) == 0 {
+		// everyone left is unavailable
+		return popRandom(set)
+	}
+	rand.Shuffle(len(eligible), func(i, j int) {
+		eligible[i], eligible[j] = eligible[j], eligible[i]
+	})
+	if len(eligible) > loadSamples {
+		eligible = eligible[:loadSamples]
+	}
+
+	least := sets.NewString()
+	min := math.MaxInt32
+	for _, candidate := range eligible {
+		load := p.openReviews(candidate)
+		if load < 0 {
+			// sort reviewers with an unknown load last
+			load = math.MaxInt32 - 1
+		}
+		if load < min {
+			min = load
+			least = sets.NewString()
+		}
+		if load == min {
+			least.Insert(candidate)
+		}
+	}
+	candidate := popRandom(&least)
+	set.Delete(candidate)
+	return candidate
+}
+
+// available reports whether candidate can be requested to review.
+func (p *reviewerPicker) available(candidate string) bool {
+	if p.unavailable.Has(candidate) {
+		return false
+	}
+	if p.outOfOffice.Has(candidate) {
+		p.skip(candidate, "out of office")
+		return false
+	}
+	if p.maxOpenReviews > 0 {
+		if load := p.openReviews(candidate); load >= p.maxOpenReviews {
+			p.skip(candidate, fmt.Sprintf("%s, at the limit of %d", openReviewsString(load), p.maxOpenReviews))
+			return false
+		}
+	}
+	if p.useStatusAvailability {
+		busy, err := isUserBusy(p.ghc, candidate)
 		if err != nil {
-			log.Errorf("error checking user availability: %v", err)
+			p.log.Errorf("error checking user availability: %v", err)
 		}
-		if !busy {
-			return candidate
+		if busy {
+			p.skip(candidate, "busy according to their GitHub status")
+			return false
 		}
-		// if we haven't returned the candidate, then they must be busy.
-		busyReviewers.Insert(candidate)
 	}
-	return ""
+	return true
+}
+
+// skip records that candidate is unavailable and why.
+func (p *reviewerPicker) skip(candidate, note string) {
+	p.unavailable.Insert(candidate)
+	p.notes[candidate] = note
+}
+
+// openReviews returns the number of open PRs in the org requesting a review
+// from login, or -1 if it cannot be determined.
+func (p *reviewerPicker) openReviews(login string) int {
+	if load, ok := p.load[login]; ok {
+		return load
+	}
+	load, err := countOpenReviews(p.ghc, p.org, login)
+	if err != nil {
+		p.log.WithError(err).Errorf("Failed to count the open review requests of %s.", login)
+		load = -1
+	}
+	p.load[login] = load
+	return load
+}
+
+// explains reports whether the picker considered more than randomness and
+// GitHub statuses, making its choices worth explaining.
+func (p *reviewerPicker) explains() bool {
+	return p.maxOpenReviews > 0 || p.balanceLoad || p.outOfOffice.Len() > 0
+}
+
+// explanation returns a comment explaining why the reviewers were requested.
+func (p *reviewerPicker) explanation(reviewers []string) string {
+	requested := sets.NewString()
+	for _, reviewer := range reviewers {
+		requested.Insert(github.NormLogin(reviewer))
+	}
+	var lines []string
+	describe := func(login, note string) {
+		lines = append(lines, fmt.Sprintf("- %s: %s", login, note))
+	}
+	for _, login := range requested.List() {
+		note := "requested"
+		if load, ok := p.load[login]; ok && load >= 0 {
+			note += ", " + openReviewsString(load)
+		}
+		describe(login, note)
+	}
+	considered := sets.StringKeySet(p.load).Union(sets.StringKeySet(p.notes)).Difference(requested)
+	for _, login := range considered.List() {
+		if note, skipped := p.notes[login]; skipped {
+			describe(login, "skipped, "+note)
+		} else if load := p.load[login]; load >= 0 {
+			describe(login, "not picked, "+openReviewsString(load))
+		}
+	}
+	return fmt.Sprintf(explanationFormat, strings.Join(reviewers, ", "), strings.Join(lines, "\n"))
+}
+
+const explanationFormat = `Requested reviews from %s.
+
+<details>
+<summary>How the reviewers were picked</summary>
+
+%s
+</details>
+`
+
+func openReviewsString(load int) string {
+	if load == 1 {
+		return "1 open review request"
+	}
+	return fmt.Sprintf("%d open review requests", load)
+}
+
+// loadOutOfOffice returns the users out of office according to the config
+// and the out of office file of the repo, if there is one.
+func loadOutOfOffice(ghc githubClient, config plugins.Blunderbuss, org, repo, base string) (sets.String, error) {
+	outOfOffice := sets.NewString()
+	for _, login := range config.OutOfOffice {
+		outOfOffice.Insert(github.NormLogin(login))
+	}
+	if config.OutOfOfficeFile == "" {
+		return outOfOffice, nil
+	}
+	b, err := ghc.GetFile(org, repo, config.OutOfOfficeFile, base)
+	if err != nil {
+		if _, notFound := err.(*github.FileNotFound); notFound {
+			return outOfOffice, nil
+		}
+		return nil, fmt.Errorf("error getting %s: %v", config.OutOfOfficeFile, err)
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		outOfOffice.Insert(github.NormLogin(strings.Fields(line)[0]))
+	}
+	return outOfOffice, nil
+}
+
+type openReviewsQuery struct {
+	Search struct {
+		IssueCount githubql.Int
+	} `graphql:"search(type: ISSUE, first: 1, query: $query)"`
+}
+
+// countOpenReviews returns the number of open PRs in the org requesting a
+// review from login.
+func countOpenReviews(ghc githubClient, org, login string) (int, error) {
+	var query openReviewsQuery
+	vars := map[string]interface{}{
+		"query": githubql.String(fmt.Sprintf("org:%s is:pr is:open archived:false review-requested:%s", org, login)),
+	}
+	if err := ghc.Query(context.Background(), &query, vars); err != nil {
+		return 0, err
+	}
+	return int(query.Search.IssueCount), nil
 }
 
 type githubAvailabilityQuery struct {
@@ -19,6 +19,7 @@ package blunderbuss
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"reflect"
@@ -42,6 +43,13 @@ type fakeGitHubClient struct {
 	pr        *github.PullRequest
 	changes   []github.PullRequestChange
 	requested []string
+	// files maps paths in the repo to their content
+	files map[string][]byte
+	// openReviews maps users to their number of open review requests
+	openReviews map[string]int
+	// openReviewsQueries counts the searches for open review requests
+	openReviewsQueries int
+	comments           []string
 }
 
 func newFakeGitHubClient(pr *github.PullRequest, filesChanged []string) *fakeGitHubClient {
@@ -83,7 +91,32 @@ func (c *fakeGitHubClient) GetPullRequest(org, repo string, num int) (*github.Pu
 	return c.pr, nil
 }
 
+func (c *fakeGitHubClient) GetFile(org, repo, filepath, commit string) ([]byte, error) {
+	if b, ok := c.files[filepath]; ok {
+		return b, nil
+	}
+	return nil, &github.FileNotFound{}
+}
+
+func (c *fakeGitHubClient) CreateComment(owner, repo string, number int, comment string) error {
+	c.comments = append(c.comments, comment)
+	return nil
+}
+
 func (c *fakeGitHubClient) Query(ctx context.Context, q interface{}, vars map[string]interface{}) error {
+	if oq, ok := q.(*openReviewsQuery); ok {
+		c.openReviewsQueries++
+		query := string(vars["query"].(githubql.String))
+		if !strings.HasPrefix(query, "org:org ") {
+			return fmt.Errorf("unexpected query %q", query)
+		}
+		for user, count := range c.openReviews {
+			if strings.HasSuffix(query, " review-requested:"+user) {
+				oq.Search.IssueCount = githubql.Int(count)
+			}
+		}
+		return nil
+	}
 	sq, ok := q.(*githubAvailabilityQuery)
 	if !ok {
 		return errors.New("unexpected query type")
@@ -322,7 +355,7 @@ func TestHandleWithExcludeApproversOnlyReviewers(t *testing.T) {
 
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, nil, tc.maxReviewerCount, true, false, &repo, &pr,
+			plugins.Blunderbuss{ReviewerCount: &tc.reviewerCount, MaxReviewerCount: tc.maxReviewerCount, ExcludeApprovers: true}, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -364,7 +397,7 @@ func TestHandleWithoutExcludeApproversNoReviewers(t *testing.T) {
 
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, nil, tc.maxReviewerCount, false, false, &repo, &pr,
+			plugins.Blunderbuss{ReviewerCount: &tc.reviewerCount, MaxReviewerCount: tc.maxReviewerCount}, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -484,7 +517,7 @@ func TestHandleWithoutExcludeApproversMixed(t *testing.T) {
 		fghc := newFakeGitHubClient(&pr, tc.filesChanged)
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, nil, tc.maxReviewerCount, false, false, &repo, &pr,
+			plugins.Blunderbuss{ReviewerCount: &tc.reviewerCount, MaxReviewerCount: tc.maxReviewerCount}, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -587,7 +620,7 @@ func TestHandleOld(t *testing.T) {
 
 			err := handle(
 				fghc, froc, logrus.WithField("plugin", PluginName),
-				nil, &tc.reviewerCount, 0, false, false, &repo, &pr,
+				plugins.Blunderbuss{FileWeightCount: &tc.reviewerCount}, &repo, &pr,
 			)
 			if err != nil {
 				t.Fatalf("unexpected error from handle: %v", err)
@@ -815,6 +848,24 @@ func TestHelpProvider(t *testing.T) {
 			enabledRepos:       enabledRepos,
 			configInfoIncludes: []string{configString(2)},
 		},
+		{
+			name: "load balancing specified",
+			config: &plugins.Configuration{
+				Blunderbuss: plugins.Blunderbuss{
+					ReviewerCount:   &[]int{2}[0],
+					MaxOpenReviews:  5,
+					BalanceLoad:     true,
+					OutOfOfficeFile: "OUT_OF_OFFICE",
+				},
+			},
+			enabledRepos: enabledRepos,
+			configInfoIncludes: []string{
+				configString(2),
+				"Reviewers with 5 or more open review requests are skipped.",
+				"Reviewers with the fewest open review requests are preferred.",
+				"Reviewers who are out of office are skipped.",
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -886,7 +937,7 @@ func TestPopActiveReviewer(t *testing.T) {
 		fghc := newFakeGitHubClient(&pr, tc.filesChanged)
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, nil, tc.maxReviewerCount, false, true, &repo, &pr,
+			plugins.Blunderbuss{ReviewerCount: &tc.reviewerCount, MaxReviewerCount: tc.maxReviewerCount, UseStatusAvailability: true}, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -906,3 +957,165 @@ func TestPopActiveReviewer(t *testing.T) {
 		}
 	}
 }
+
+func TestHandleWithLoadBalancing(t *testing.T) {
+	froc := &fakeRepoownersClient{
+		foc: &fakeOwnersClient{
+			owners: map[string]string{
+				"a.go": "1",
+			},
+			reviewers: map[string]sets.String{
+				"a.go": sets.NewString("alice", "brad", "carl", "dave"),
+			},
+			leafReviewers: map[string]sets.String{
+				"a.go": sets.NewString("alice", "brad", "carl", "dave"),
+			},
+		},
+	}
+	openReviews := map[string]int{"alice": 5, "brad": 1, "carl": 3, "dave": 0}
+
+	var testcases = []struct {
+		name              string
+		reviewerCount     int
+		maxOpenReviews    int
+		balanceLoad       bool
+		outOfOffice       []string
+		outOfOfficeFile   string
+		files             map[string][]byte
+		expectedRequested []string
+		expectedComment   []string
+	}{
+		{
+			name:              "balance load picks the least loaded reviewers",
+			reviewerCount:     2,
+			balanceLoad:       true,
+			expectedRequested: []string{"brad", "dave"},
+			expectedComment: []string{
+				"- brad: requested, 1 open review request",
+				"- dave: requested, 0 open review requests",
+				"- alice: not picked, 5 open review requests",
+				"- carl: not picked, 3 open review requests",
+			},
+		},
+		{
+			name:              "reviewers at the cap are skipped",
+			reviewerCount:     4,
+			maxOpenReviews:    3,
+			expectedRequested: []string{"brad", "dave"},
+			expectedComment: []string{
+				"- brad: requested, 1 open review request",
+				"- dave: requested, 0 open review requests",
+				"- alice: skipped, 5 open review requests, at the limit of 3",
+				"- carl: skipped, 3 open review requests, at the limit of 3",
+			},
+		},
+		{
+			name:              "reviewers out of office are skipped",
+			reviewerCount:     2,
+			balanceLoad:       true,
+			outOfOffice:       []string{"Dave"},
+			outOfOfficeFile:   "OOO",
+			files:             map[string][]byte{"OOO": []byte("# back next week\n@brad\n\n")},
+			expectedRequested: []string{"carl", "alice"},
+			expectedComment: []string{
+				"- alice: requested, 5 open review requests",
+				"- carl: requested, 3 open review requests",
+				"- brad: skipped, out of office",
+				"- dave: skipped, out of office",
+			},
+		},
+		{
+			name:              "a missing out of office file is ignored",
+			reviewerCount:     1,
+			balanceLoad:       true,
+			outOfOfficeFile:   "OOO",
+			expectedRequested: []string{"dave"},
+			expectedComment: []string{
+				"- dave: requested, 0 open review requests",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			pr := github.PullRequest{Number: 5, User: github.User{Login: "author"}}
+			repo := github.Repo{Owner: github.User{Login: "org"}, Name: "repo"}
+			fghc := newFakeGitHubClient(&pr, []string{"a.go"})
+			fghc.files = tc.files
+			fghc.openReviews = openReviews
+			config := plugins.Blunderbuss{
+				ReviewerCount:    &tc.reviewerCount,
+				ExcludeApprovers: true,
+				MaxOpenReviews:   tc.maxOpenReviews,
+				BalanceLoad:      tc.balanceLoad,
+				OutOfOffice:      tc.outOfOffice,
+				OutOfOfficeFile:  tc.outOfOfficeFile,
+			}
+			if err := handle(fghc, froc, logrus.WithField("plugin", PluginName), config, &repo, &pr); err != nil {
+				t.Fatalf("unexpected error from handle: %v", err)
+			}
+
+			sort.Strings(fghc.requested)
+			sort.Strings(tc.expectedRequested)
+			if !reflect.DeepEqual(fghc.requested, tc.expectedRequested) {
+				t.Errorf("expected the requested reviewers to be %q, but got %q.", tc.expectedRequested, fghc.requested)
+			}
+			if len(fghc.comments) != 1 {
+				t.Fatalf("expected one comment, got %q", fghc.comments)
+			}
+			if !strings.Contains(fghc.comments[0], "<details>") {
+				t.Errorf("expected the reasoning to be collapsed, got %q", fghc.comments[0])
+			}
+			if got := strings.Join(tc.expectedComment, "\n"); !strings.Contains(fghc.comments[0], got) {
+				t.Errorf("expected comment to contain:\n%s\ngot:\n%s", got, fghc.comments[0])
+			}
+		})
+	}
+}
+
+func TestHandleWithLoadBalancingSamplesCandidates(t *testing.T) {
+	candidates := sets.NewString()
+	for i := 0; i < 50; i++ {
+		candidates.Insert(fmt.Sprintf("user%d", i))
+	}
+	froc := &fakeRepoownersClient{
+		foc: &fakeOwnersClient{
+			owners:        map[string]string{"a.go": "1"},
+			reviewers:     map[string]sets.String{"a.go": candidates},
+			leafReviewers: map[string]sets.String{"a.go": candidates},
+		},
+	}
+	pr := github.PullRequest{Number: 5, User: github.User{Login: "author"}}
+	repo := github.Repo{Owner: github.User{Login: "org"}, Name: "repo"}
+	fghc := newFakeGitHubClient(&pr, []string{"a.go"})
+	reviewerCount := 2
+	config := plugins.Blunderbuss{ReviewerCount: &reviewerCount, ExcludeApprovers: true, BalanceLoad: true}
+	if err := handle(fghc, froc, logrus.WithField("plugin", PluginName), config, &repo, &pr); err != nil {
+		t.Fatalf("unexpected error from handle: %v", err)
+	}
+	if len(fghc.requested) != reviewerCount {
+		t.Errorf("expected %d reviewers to be requested, got %q", reviewerCount, fghc.requested)
+	}
+	if max := reviewerCount * loadSamples; fghc.openReviewsQueries > max {
+		t.Errorf("expected at most %d searches for open review requests, got %d", max, fghc.openReviewsQueries)
+	}
+}
+
+func TestHandleWithoutLoadBalancingDoesNotComment(t *testing.T) {
+	froc := &fakeRepoownersClient{
+		foc: &fakeOwnersClient{
+			owners:        map[string]string{"a.go": "1"},
+			reviewers:     map[string]sets.String{"a.go": sets.NewString("alice")},
+			leafReviewers: map[string]sets.String{"a.go": sets.NewString("alice")},
+		},
+	}
+	pr := github.PullRequest{Number: 5, User: github.User{Login: "author"}}
+	repo := github.Repo{Owner: github.User{Login: "org"}, Name: "repo"}
+	fghc := newFakeGitHubClient(&pr, []string{"a.go"})
+	reviewerCount := 1
+	if err := handle(fghc, froc, logrus.WithField("plugin", PluginName), plugins.Blunderbuss{ReviewerCount: &reviewerCount}, &repo, &pr); err != nil {
+		t.Fatalf("unexpected error from handle: %v", err)
+	}
+	if len(fghc.comments) != 0 {
+		t.Errorf("expected no comment, got %q", fghc.comments)
+	}
+}
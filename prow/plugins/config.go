@@ -133,6 +133,22 @@ type Blunderbuss struct {
 	// additional token per successful reviewer (and potentially more depending on
 	// how many busy reviewers it had to pass over).
 	UseStatusAvailability bool `json:"use_status_availability,omitempty"`
+	// MaxOpenReviews caps the number of open PRs in the org a reviewer can have
+	// pending review requests on. Reviewers at the cap are not requested to
+	// review. Defaults to 0 meaning no limit. Finding the load of a reviewer
+	// uses one additional token per reviewer considered.
+	MaxOpenReviews int `json:"max_open_reviews,omitempty"`
+	// BalanceLoad makes blunderbuss prefer the eligible reviewers with the
+	// fewest open review requests in the org instead of picking at random.
+	// Each reviewer is picked among a random sample of up to 5 candidates,
+	// which bounds the additional tokens used per reviewer.
+	BalanceLoad bool `json:"balance_load,omitempty"`
+	// OutOfOffice lists the GitHub users blunderbuss never requests reviews from.
+	OutOfOffice []string `json:"out_of_office,omitempty"`
+	// OutOfOfficeFile is the path of a file in the repo that lists more users
+	// who are out of office, one per line. Lines starting with # are ignored.
+	// The file is read from the base branch of the PR.
+	OutOfOfficeFile string `json:"out_of_office_file,omitempty"`
 }
 
 // Owners contains configuration related to handling OWNERS files.
@@ -986,6 +1002,12 @@ func validateBlunderbuss(b *Blunderbuss) error {
 	if b.FileWeightCount != nil && *b.FileWeightCount < 1 {
 		return fmt.Errorf("invalid file_weight_count: %v (needs to be positive)", *b.FileWeightCount)
 	}
+	if b.MaxOpenReviews < 0 {
+		return fmt.Errorf("invalid max_open_reviews: %v (needs to be non-negative)", b.MaxOpenReviews)
+	}
+	if b.FileWeightCount != nil && (b.MaxOpenReviews > 0 || b.BalanceLoad || len(b.OutOfOffice) > 0 || b.OutOfOfficeFile != "") {
+		return errors.New("max_open_reviews, balance_load, out_of_office and out_of_office_file cannot be used with file_weight_count in blunderbuss")
+	}
 	if b.FileWeightCount != nil {
 		warnDeprecated(&warnBlunderbussFileWeightCount, 5*time.Minute, "file_weight_count is being deprecated in favour of max_request_count. Please ensure your configuration is updated before the end of May 2019.")
 	}
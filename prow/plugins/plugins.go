@@ -52,6 +52,7 @@ var (
 	reviewEventHandlers        = map[string]ReviewEventHandler{}
 	reviewCommentEventHandlers = map[string]ReviewCommentEventHandler{}
 	statusEventHandlers        = map[string]StatusEventHandler{}
+	checkRunEventHandlers      = map[string]CheckRunEventHandler{}
 	CommentMap                 = genyaml.NewCommentMap("prow/plugins/config.go")
 )
 
@@ -100,6 +101,15 @@ func RegisterStatusEventHandler(name string, fn StatusEventHandler, help HelpPro
 	statusEventHandlers[name] = fn
 }
 
+// CheckRunEventHandler defines the function contract for a github.CheckRunEvent handler.
+type CheckRunEventHandler func(Agent, github.CheckRunEvent) error
+
+// RegisterCheckRunEventHandler registers a plugin's github.CheckRunEvent handler.
+func RegisterCheckRunEventHandler(name string, fn CheckRunEventHandler, help HelpProvider) {
+	pluginHelp[name] = help
+	checkRunEventHandlers[name] = fn
+}
+
 // PushEventHandler defines the function contract for a github.PushEvent handler.
 type PushEventHandler func(Agent, github.PushEvent) error
 
@@ -389,6 +399,21 @@ func (pa *ConfigAgent) StatusEventHandlers(owner, repo string) map[string]Status
 	return hs
 }
 
+// CheckRunEventHandlers returns a map of plugin names to handlers for the repo.
+func (pa *ConfigAgent) CheckRunEventHandlers(owner, repo string) map[string]CheckRunEventHandler {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	hs := map[string]CheckRunEventHandler{}
+	for _, p := range pa.getPlugins(owner, repo) {
+		if h, ok := checkRunEventHandlers[p]; ok {
+			hs[p] = h
+		}
+	}
+
+	return hs
+}
+
 // PushEventHandlers returns a map of plugin names to handlers for the repo.
 func (pa *ConfigAgent) PushEventHandlers(owner, repo string) map[string]PushEventHandler {
 	pa.mut.Lock()
@@ -439,6 +464,9 @@ func EventsForPlugin(name string) []string {
 	if _, ok := statusEventHandlers[name]; ok {
 		events = append(events, "status")
 	}
+	if _, ok := checkRunEventHandlers[name]; ok {
+		events = append(events, "check_run")
+	}
 	if _, ok := genericCommentHandlers[name]; ok {
 		events = append(events, "GenericCommentEvent (any event for user text)")
 	}
@@ -0,0 +1,133 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"fmt"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/report"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// isRerunRequest determines whether someone asked to run the job of the
+// check run again, either with the "Re-run" action prow adds to failed check
+// runs or with the re-run button of GitHub.
+func isRerunRequest(cre github.CheckRunEvent) bool {
+	switch cre.Action {
+	case github.CheckRunActionRerequested:
+		return true
+	case github.CheckRunActionRequestedAction:
+		return cre.RequestedAction != nil && cre.RequestedAction.Identifier == report.RerunActionIdentifier
+	}
+	return false
+}
+
+// handleCRE runs the presubmit that reported the check run again on the
+// open PRs whose head is the checked commit.
+func handleCRE(c Client, trigger plugins.Trigger, cre github.CheckRunEvent) error {
+	if !isRerunRequest(cre) {
+		return nil
+	}
+
+	org, repo := cre.Repo.Owner.Login, cre.Repo.Name
+	numbers, err := pullRequestsForCheckRun(c, cre)
+	if err != nil {
+		return err
+	}
+	if len(numbers) == 0 {
+		c.Logger.Debug("Check run does not belong to an open PR, skipping.")
+		return nil
+	}
+	senderTrusted, err := TrustedUser(c.GitHubClient, trigger.OnlyOrgMembers, trigger.TrustedOrg, cre.Sender.Login, org, repo)
+	if err != nil {
+		return fmt.Errorf("error checking trust of %s: %v", cre.Sender.Login, err)
+	}
+
+	var errs []error
+	for _, number := range numbers {
+		pr, err := c.GitHubClient.GetPullRequest(org, repo, number)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// The PR may have moved on since the check run was reported.
+		if pr.State != "open" || pr.Head.SHA != cre.CheckRun.HeadSHA {
+			continue
+		}
+		// Like for /retest, anyone may ask to run the jobs of a trusted PR again.
+		if !senderTrusted {
+			_, trusted, err := TrustedPullRequest(c.GitHubClient, trigger, pr.User.Login, org, repo, number, nil)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !trusted {
+				c.Logger.Infof("Not running %s again for %s on untrusted PR #%d.", cre.CheckRun.Name, cre.Sender.Login, number)
+				continue
+			}
+		}
+		baseSHA, err := c.GitHubClient.GetRef(org, repo, "heads/"+pr.Base.Ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get baseSHA: %v", err))
+			continue
+		}
+
+		presubmits := getPresubmits(c.Logger, c.GitClient, c.Config, org+"/"+repo,
+			func() (string, error) { return baseSHA, nil },
+			func() (string, error) { return pr.Head.SHA, nil })
+		var toRun []config.Presubmit
+		for _, job := range presubmits {
+			if job.Context == cre.CheckRun.Name && job.CouldRun(pr.Base.Ref) {
+				toRun = append(toRun, job)
+			}
+		}
+		if len(toRun) == 0 {
+			c.Logger.Debugf("No presubmit of PR #%d reports %s, skipping.", number, cre.CheckRun.Name)
+			continue
+		}
+		if err := RunAndSkipJobs(c, pr, baseSHA, toRun, nil, cre.GUID, true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// pullRequestsForCheckRun returns the numbers of the open PRs whose head is
+// the checked commit. GitHub does not list PRs from forks on check runs, so
+// they are searched for by commit.
+func pullRequestsForCheckRun(c Client, cre github.CheckRunEvent) ([]int, error) {
+	var numbers []int
+	for _, pr := range cre.CheckRun.PullRequests {
+		numbers = append(numbers, pr.Number)
+	}
+	if len(numbers) > 0 {
+		return numbers, nil
+	}
+	org, repo := cre.Repo.Owner.Login, cre.Repo.Name
+	issues, err := c.GitHubClient.FindIssues(fmt.Sprintf("%s repo:%s/%s type:pr state:open", cre.CheckRun.HeadSHA, org, repo), "", false)
+	if err != nil {
+		return nil, fmt.Errorf("error searching for PRs matching commit: %v", err)
+	}
+	for _, issue := range issues {
+		numbers = append(numbers, issue.Number)
+	}
+	return numbers, nil
+}
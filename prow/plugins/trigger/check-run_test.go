@@ -0,0 +1,188 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/client/clientset/versioned/fake"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/github/report"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestHandleCRE(t *testing.T) {
+	const sha = "abcdef"
+	presubmits := []config.Presubmit{{
+		JobBase:  config.JobBase{Name: "build"},
+		Reporter: config.Reporter{Context: "build-context"},
+	}, {
+		JobBase:  config.JobBase{Name: "unit"},
+		Reporter: config.Reporter{Context: "unit-context"},
+	}}
+	rerun := &github.CheckRunAction{Identifier: report.RerunActionIdentifier}
+
+	var testCases = []struct {
+		name            string
+		action          github.CheckRunEventAction
+		requestedAction *github.CheckRunAction
+		checkRun        string
+		sender          string
+		headSHA         string
+		prLabels        []string
+		fromFork        bool
+
+		expectedJobs sets.String
+	}{
+		{
+			name:            "re-run action runs the job again",
+			action:          github.CheckRunActionRequestedAction,
+			checkRun:        "unit-context",
+			requestedAction: rerun,
+			sender:          "member",
+			expectedJobs:    sets.NewString("unit"),
+		},
+		{
+			name:         "re-requested check run runs the job again",
+			action:       github.CheckRunActionRerequested,
+			checkRun:     "build-context",
+			sender:       "member",
+			expectedJobs: sets.NewString("build"),
+		},
+		{
+			name:         "PRs from forks are searched for",
+			action:       github.CheckRunActionRerequested,
+			checkRun:     "build-context",
+			sender:       "member",
+			fromFork:     true,
+			expectedJobs: sets.NewString("build"),
+		},
+		{
+			name:            "other actions are ignored",
+			action:          github.CheckRunActionRequestedAction,
+			checkRun:        "unit-context",
+			requestedAction: &github.CheckRunAction{Identifier: "other"},
+			sender:          "member",
+		},
+		{
+			name:     "completed check runs are ignored",
+			action:   github.CheckRunActionCompleted,
+			checkRun: "unit-context",
+			sender:   "member",
+		},
+		{
+			name:     "check runs of other apps are ignored",
+			action:   github.CheckRunActionRerequested,
+			checkRun: "lint",
+			sender:   "member",
+		},
+		{
+			name:     "outdated check runs are ignored",
+			action:   github.CheckRunActionRerequested,
+			checkRun: "unit-context",
+			sender:   "member",
+			headSHA:  "newer",
+		},
+		{
+			name:     "untrusted user can't run the jobs of an untrusted PR",
+			action:   github.CheckRunActionRerequested,
+			checkRun: "unit-context",
+			sender:   "stranger",
+		},
+		{
+			name:         "untrusted user can run the jobs of a trusted PR again",
+			action:       github.CheckRunActionRerequested,
+			checkRun:     "unit-context",
+			sender:       "stranger",
+			prLabels:     []string{labels.OkToTest},
+			expectedJobs: sets.NewString("unit"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := github.Repo{Owner: github.User{Login: "org"}, Name: "repo"}
+			headSHA := sha
+			if tc.headSHA != "" {
+				headSHA = tc.headSHA
+			}
+			var issueLabels []string
+			for _, label := range tc.prLabels {
+				issueLabels = append(issueLabels, "org/repo#1:"+label)
+			}
+			fakeGitHubClient := &fakegithub.FakeClient{
+				OrgMembers: map[string][]string{"org": {"member"}},
+				PullRequests: map[int]*github.PullRequest{
+					1: {
+						Number: 1,
+						State:  "open",
+						User:   github.User{Login: "contributor"},
+						Base:   github.PullRequestBranch{Ref: "master", Repo: repo},
+						Head:   github.PullRequestBranch{SHA: headSHA},
+					},
+				},
+				IssueLabelsExisting: issueLabels,
+			}
+			fakeProwJobClient := fake.NewSimpleClientset()
+			c := Client{
+				GitHubClient:  fakeGitHubClient,
+				ProwJobClient: fakeProwJobClient.ProwV1().ProwJobs("prowjobs"),
+				Config:        &config.Config{},
+				Logger:        logrus.WithField("plugin", PluginName),
+			}
+			if err := c.Config.SetPresubmits(map[string][]config.Presubmit{"org/repo": presubmits}); err != nil {
+				t.Fatalf("failed to set presubmits: %v", err)
+			}
+
+			cre := github.CheckRunEvent{
+				Action:          tc.action,
+				RequestedAction: tc.requestedAction,
+				CheckRun:        github.CheckRun{Name: tc.checkRun, HeadSHA: sha},
+				Repo:            repo,
+				Sender:          github.User{Login: tc.sender},
+			}
+			if !tc.fromFork {
+				cre.CheckRun.PullRequests = []github.CheckRunPullRequest{{Number: 1}}
+			}
+			if err := handleCRE(c, plugins.Trigger{}, cre); err != nil {
+				t.Fatalf("handleCRE returned unexpected error: %v", err)
+			}
+
+			pjs, err := fakeProwJobClient.ProwV1().ProwJobs("prowjobs").List(metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("could not list prowjobs: %v", err)
+			}
+			started := sets.NewString()
+			for _, pj := range pjs.Items {
+				started.Insert(pj.Spec.Job)
+			}
+			if tc.expectedJobs == nil {
+				tc.expectedJobs = sets.NewString()
+			}
+			if !started.Equal(tc.expectedJobs) {
+				t.Errorf("expected jobs %v to start, got %v", tc.expectedJobs.List(), started.List())
+			}
+		})
+	}
+}
@@ -17,6 +17,10 @@ limitations under the License.
 package trigger
 
 import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
@@ -70,23 +74,46 @@ func handlePE(c Client, pe github.PushEvent) error {
 
 	postsubmits := getPostsubmits(c.Logger, c.GitClient, c.Config, org+"/"+repo, shaGetter)
 
+	var toRun []config.Postsubmit
+	running := sets.NewString()
 	for _, j := range postsubmits {
 		if shouldRun, err := j.ShouldRun(pe.Branch(), listPushEventChanges(pe)); err != nil {
 			return err
 		} else if !shouldRun {
 			continue
 		}
-		refs := createRefs(pe)
-		labels := make(map[string]string)
-		for k, v := range j.Labels {
-			labels[k] = v
+		toRun = append(toRun, j)
+		running.Insert(j.Name)
+	}
+
+	for _, j := range toRun {
+		// The commit was just pushed, so only the jobs that run along with
+		// this one can hold it back.
+		if waitingOn := running.Intersection(sets.NewString(j.RunAfterSuccess...)); waitingOn.Len() > 0 {
+			if j.SkipReport {
+				continue
+			}
+			c.Logger.Infof("Waiting on %s before starting %s build.", strings.Join(waitingOn.List(), ", "), j.Name)
+			if err := c.GitHubClient.CreateStatus(org, repo, pe.After, waitingStatusFor(j.Context, waitingOn.List())); err != nil {
+				return err
+			}
+			continue
 		}
-		labels[github.EventGUID] = pe.GUID
-		pj := pjutil.NewProwJob(pjutil.PostsubmitSpec(j, refs), labels, j.Annotations)
-		c.Logger.WithFields(pjutil.ProwJobFields(&pj)).Info("Creating a new prowjob.")
-		if _, err := c.ProwJobClient.Create(&pj); err != nil {
+		if err := runPostsubmit(c, j, createRefs(pe), pe.GUID); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+func runPostsubmit(c Client, job config.Postsubmit, refs prowapi.Refs, eventGUID string) error {
+	labels := make(map[string]string)
+	for k, v := range job.Labels {
+		labels[k] = v
+	}
+	labels[github.EventGUID] = eventGUID
+	pj := pjutil.NewProwJob(pjutil.PostsubmitSpec(job, refs), labels, job.Annotations)
+	c.Logger.WithFields(pjutil.ProwJobFields(&pj)).Info("Creating a new prowjob.")
+	_, err := c.ProwJobClient.Create(&pj)
+	return err
+}
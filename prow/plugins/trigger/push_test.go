@@ -17,6 +17,7 @@ limitations under the License.
 package trigger
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -59,9 +60,10 @@ func TestCreateRefs(t *testing.T) {
 
 func TestHandlePE(t *testing.T) {
 	testCases := []struct {
-		name      string
-		pe        github.PushEvent
-		jobsToRun int
+		name             string
+		pe               github.PushEvent
+		jobsToRun        int
+		expectedStatuses []github.Status
 	}{
 		{
 			name: "branch deleted",
@@ -151,6 +153,23 @@ func TestHandlePE(t *testing.T) {
 			},
 			jobsToRun: 1,
 		},
+		{
+			name: "jobs wait for the jobs they run after",
+			pe: github.PushEvent{
+				Ref:   "refs/heads/master",
+				After: "abcdef",
+				Repo: github.Repo{
+					Owner: github.User{Login: "org4"},
+					Name:  "repo4",
+				},
+			},
+			jobsToRun: 1,
+			expectedStatuses: []github.Status{{
+				State:       github.StatusPending,
+				Context:     "deploy-context",
+				Description: "Waiting on build.",
+			}},
+		},
 	}
 	for _, tc := range testCases {
 		g := &fakegithub.FakeClient{}
@@ -189,6 +208,21 @@ func TestHandlePE(t *testing.T) {
 					},
 				},
 			},
+			"org4/repo4": {
+				{
+					JobBase: config.JobBase{
+						Name: "build",
+					},
+					Reporter: config.Reporter{Context: "build-context"},
+				},
+				{
+					JobBase: config.JobBase{
+						Name: "deploy",
+					},
+					RunAfterSuccess: []string{"build"},
+					Reporter:        config.Reporter{Context: "deploy-context"},
+				},
+			},
 		}
 		if err := c.Config.SetPostsubmits(postsubmits); err != nil {
 			t.Fatalf("failed to set postsubmits: %v", err)
@@ -207,5 +241,8 @@ func TestHandlePE(t *testing.T) {
 		if numStarted != tc.jobsToRun {
 			t.Errorf("test %q: expected %d jobs to run, got %d", tc.name, tc.jobsToRun, numStarted)
 		}
+		if actual := g.CreatedStatuses[tc.pe.After]; !reflect.DeepEqual(actual, tc.expectedStatuses) {
+			t.Errorf("test %q: created incorrect statuses: %s", tc.name, diff.ObjectReflectDiff(actual, tc.expectedStatuses))
+		}
 	}
 }
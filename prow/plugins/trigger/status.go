@@ -0,0 +1,224 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"fmt"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+// dependentJob is what readyJobs needs to know about a presubmit or
+// postsubmit.
+type dependentJob struct {
+	name            string
+	context         string
+	runAfterSuccess []string
+}
+
+// readyJobs returns the names of the jobs that wait for the job reporting
+// the successful parentContext and that do not wait for any other job. A job
+// waits for the jobs in its run_after_success that reported anything but
+// success.
+func readyJobs(jobs []dependentJob, parentContext string, statuses map[string]github.Status) sets.String {
+	contexts := map[string]string{}
+	for _, job := range jobs {
+		contexts[job.name] = job.context
+	}
+
+	ready := sets.NewString()
+	for _, job := range jobs {
+		if status, reported := statuses[job.context]; !reported || !isWaiting(status) {
+			continue
+		}
+		var isDependent, blocked bool
+		for _, parent := range job.runAfterSuccess {
+			context, exists := contexts[parent]
+			if !exists {
+				continue
+			}
+			isDependent = isDependent || context == parentContext
+			if status, reported := statuses[context]; reported && status.State != github.StatusSuccess {
+				blocked = true
+			}
+		}
+		if isDependent && !blocked {
+			ready.Insert(job.name)
+		}
+	}
+	return ready
+}
+
+// hasDependentJobs determines whether any of the statically configured jobs
+// of the repo set run_after_success.
+func hasDependentJobs(cfg *config.Config, orgRepo string) bool {
+	for _, job := range cfg.PresubmitsStatic[orgRepo] {
+		if len(job.RunAfterSuccess) > 0 {
+			return true
+		}
+	}
+	for _, job := range cfg.PostsubmitsStatic[orgRepo] {
+		if len(job.RunAfterSuccess) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSE starts the jobs that wait for the job which just reported
+// success, once all the jobs they run after succeeded.
+func handleSE(c Client, se github.StatusEvent) error {
+	if se.State != github.StatusSuccess {
+		return nil
+	}
+
+	org, repo := se.Repo.Owner.Login, se.Repo.Name
+	orgRepo := org + "/" + repo
+	if !c.Config.InRepoConfigEnabled(orgRepo) && !hasDependentJobs(c.Config, orgRepo) {
+		return nil
+	}
+
+	combinedStatus, err := c.GitHubClient.GetCombinedStatus(org, repo, se.SHA)
+	if err != nil {
+		return fmt.Errorf("failed to get the statuses of %s: %v", se.SHA, err)
+	}
+	statuses := map[string]github.Status{}
+	var waiting bool
+	if combinedStatus != nil {
+		for _, status := range combinedStatus.Statuses {
+			statuses[status.Context] = status
+			waiting = waiting || isWaiting(status)
+		}
+	}
+	if !waiting {
+		return nil
+	}
+
+	return utilerrors.NewAggregate([]error{
+		runWaitingPresubmits(c, se, statuses),
+		runWaitingPostsubmits(c, se, statuses),
+	})
+}
+
+// runWaitingPresubmits starts the ready presubmits of the open PRs whose head
+// is the commit of the status.
+func runWaitingPresubmits(c Client, se github.StatusEvent, statuses map[string]github.Status) error {
+	org, repo := se.Repo.Owner.Login, se.Repo.Name
+	issues, err := c.GitHubClient.FindIssues(fmt.Sprintf("%s repo:%s/%s type:pr state:open", se.SHA, org, repo), "", false)
+	if err != nil {
+		return fmt.Errorf("error searching for PRs matching commit: %v", err)
+	}
+
+	var errs []error
+	for _, issue := range issues {
+		pr, err := c.GitHubClient.GetPullRequest(org, repo, issue.Number)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// The search also matches PRs that contain the commit somewhere
+		// in their history.
+		if pr.Head.SHA != se.SHA {
+			continue
+		}
+		baseSHA, err := c.GitHubClient.GetRef(org, repo, "heads/"+pr.Base.Ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get baseSHA: %v", err))
+			continue
+		}
+
+		presubmits := getPresubmits(c.Logger, c.GitClient, c.Config, org+"/"+repo,
+			func() (string, error) { return baseSHA, nil },
+			func() (string, error) { return pr.Head.SHA, nil })
+		var jobs []dependentJob
+		for _, job := range presubmits {
+			if job.CouldRun(pr.Base.Ref) {
+				jobs = append(jobs, dependentJob{name: job.Name, context: job.Context, runAfterSuccess: job.RunAfterSuccess})
+			}
+		}
+		ready := readyJobs(jobs, se.Context, statuses)
+		for _, job := range presubmits {
+			if !ready.Has(job.Name) || !job.CouldRun(pr.Base.Ref) {
+				continue
+			}
+			if err := runRequested(c, pr, baseSHA, []config.Presubmit{job}, se.GUID); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err := c.GitHubClient.CreateStatus(org, repo, se.SHA, startedStatusFor(job.Context)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// runWaitingPostsubmits starts the ready postsubmits of the branches that
+// contain the commit of the status.
+func runWaitingPostsubmits(c Client, se github.StatusEvent, statuses map[string]github.Status) error {
+	org, repo := se.Repo.Owner.Login, se.Repo.Name
+	postsubmits := getPostsubmits(c.Logger, c.GitClient, c.Config, org+"/"+repo, func() (string, error) { return se.SHA, nil })
+
+	var errs []error
+	started := sets.NewString()
+	for _, branch := range se.Branches {
+		var jobs []dependentJob
+		for _, job := range postsubmits {
+			if job.CouldRun(branch.Name) {
+				jobs = append(jobs, dependentJob{name: job.Name, context: job.Context, runAfterSuccess: job.RunAfterSuccess})
+			}
+		}
+		ready := readyJobs(jobs, se.Context, statuses).Difference(started)
+		for _, job := range postsubmits {
+			if !ready.Has(job.Name) || !job.CouldRun(branch.Name) {
+				continue
+			}
+			refs := prowapi.Refs{
+				Org:     org,
+				Repo:    repo,
+				BaseRef: branch.Name,
+				BaseSHA: se.SHA,
+			}
+			c.Logger.Infof("Starting %s build.", job.Name)
+			started.Insert(job.Name)
+			if err := runPostsubmit(c, job, refs, se.GUID); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err := c.GitHubClient.CreateStatus(org, repo, se.SHA, startedStatusFor(job.Context)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// startedStatusFor replaces the waiting status of a job once it was started,
+// so that later status events do not start it again. The reporter of the
+// ProwJob takes over from there.
+func startedStatusFor(context string) github.Status {
+	return github.Status{
+		State:       github.StatusPending,
+		Context:     context,
+		Description: "Job triggered.",
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/client/clientset/versioned/fake"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func TestHandleSE(t *testing.T) {
+	const sha = "abcdef"
+	waiting := func(context string, waitingOn ...string) github.Status {
+		return waitingStatusFor(context, waitingOn)
+	}
+	status := func(context, state string) github.Status {
+		return github.Status{Context: context, State: state}
+	}
+	presubmits := []config.Presubmit{{
+		JobBase:  config.JobBase{Name: "build"},
+		Reporter: config.Reporter{Context: "build-context"},
+	}, {
+		JobBase:  config.JobBase{Name: "unit"},
+		Reporter: config.Reporter{Context: "unit-context"},
+	}, {
+		JobBase:         config.JobBase{Name: "e2e"},
+		RunAfterSuccess: []string{"build", "unit"},
+		Reporter:        config.Reporter{Context: "e2e-context"},
+	}}
+	postsubmits := []config.Postsubmit{{
+		JobBase:  config.JobBase{Name: "publish"},
+		Reporter: config.Reporter{Context: "publish-context"},
+	}, {
+		JobBase:         config.JobBase{Name: "deploy"},
+		RunAfterSuccess: []string{"publish"},
+		Reporter:        config.Reporter{Context: "deploy-context"},
+		Brancher:        config.Brancher{Branches: []string{"master"}},
+	}}
+
+	var testCases = []struct {
+		name     string
+		se       github.StatusEvent
+		headSHA  string
+		statuses []github.Status
+
+		expectedJobs     sets.String
+		expectedStatuses []github.Status
+	}{
+		{
+			name:     "pending status is ignored",
+			se:       github.StatusEvent{Context: "build-context", State: github.StatusPending},
+			statuses: []github.Status{status("build-context", github.StatusPending), status("unit-context", github.StatusSuccess), waiting("e2e-context", "build", "unit")},
+		},
+		{
+			name:     "repo without dependent jobs is ignored",
+			se:       github.StatusEvent{Context: "build-context", State: github.StatusSuccess, Repo: github.Repo{Owner: github.User{Login: "org"}, Name: "other"}},
+			statuses: []github.Status{status("build-context", github.StatusSuccess), status("unit-context", github.StatusSuccess), waiting("e2e-context", "build", "unit")},
+		},
+		{
+			name:             "dependent starts once all jobs it runs after succeeded",
+			se:               github.StatusEvent{Context: "build-context", State: github.StatusSuccess},
+			statuses:         []github.Status{status("build-context", github.StatusSuccess), status("unit-context", github.StatusSuccess), waiting("e2e-context", "build", "unit")},
+			expectedJobs:     sets.NewString("e2e"),
+			expectedStatuses: []github.Status{startedStatusFor("e2e-context")},
+		},
+		{
+			name:     "dependent keeps waiting while another job it runs after failed",
+			se:       github.StatusEvent{Context: "build-context", State: github.StatusSuccess},
+			statuses: []github.Status{status("build-context", github.StatusSuccess), status("unit-context", github.StatusFailure), waiting("e2e-context", "build", "unit")},
+		},
+		{
+			name:     "dependent that was not waiting is not started",
+			se:       github.StatusEvent{Context: "build-context", State: github.StatusSuccess},
+			statuses: []github.Status{status("build-context", github.StatusSuccess), status("unit-context", github.StatusSuccess), status("e2e-context", github.StatusFailure)},
+		},
+		{
+			name:     "dependent of an unrelated job is not started",
+			se:       github.StatusEvent{Context: "lint-context", State: github.StatusSuccess},
+			statuses: []github.Status{status("lint-context", github.StatusSuccess), status("build-context", github.StatusSuccess), waiting("e2e-context", "unit")},
+		},
+		{
+			name:     "dependent of an outdated commit is not started",
+			se:       github.StatusEvent{Context: "build-context", State: github.StatusSuccess},
+			headSHA:  "newer",
+			statuses: []github.Status{status("build-context", github.StatusSuccess), status("unit-context", github.StatusSuccess), waiting("e2e-context", "build", "unit")},
+		},
+		{
+			name:             "postsubmit starts on the branches of the commit",
+			se:               github.StatusEvent{Context: "publish-context", State: github.StatusSuccess, Branches: []github.Branch{{Name: "feature"}, {Name: "master"}}},
+			statuses:         []github.Status{status("publish-context", github.StatusSuccess), waiting("deploy-context", "publish")},
+			expectedJobs:     sets.NewString("deploy"),
+			expectedStatuses: []github.Status{startedStatusFor("deploy-context")},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.se.SHA = sha
+			if tc.se.Repo.Name == "" {
+				tc.se.Repo = github.Repo{Owner: github.User{Login: "org"}, Name: "repo"}
+			}
+			headSHA := sha
+			if tc.headSHA != "" {
+				headSHA = tc.headSHA
+			}
+			fakeGitHubClient := &fakegithub.FakeClient{
+				PullRequests: map[int]*github.PullRequest{
+					1: {
+						Number: 1,
+						Base:   github.PullRequestBranch{Ref: "master", Repo: tc.se.Repo},
+						Head:   github.PullRequestBranch{SHA: headSHA},
+					},
+				},
+				CombinedStatuses: map[string]*github.CombinedStatus{
+					sha: {SHA: sha, Statuses: tc.statuses},
+				},
+			}
+			fakeProwJobClient := fake.NewSimpleClientset()
+			c := Client{
+				GitHubClient:  fakeGitHubClient,
+				ProwJobClient: fakeProwJobClient.ProwV1().ProwJobs("prowjobs"),
+				Config:        &config.Config{},
+				Logger:        logrus.WithField("plugin", PluginName),
+			}
+			if err := c.Config.SetPresubmits(map[string][]config.Presubmit{"org/repo": presubmits}); err != nil {
+				t.Fatalf("failed to set presubmits: %v", err)
+			}
+			if err := c.Config.SetPostsubmits(map[string][]config.Postsubmit{"org/repo": postsubmits}); err != nil {
+				t.Fatalf("failed to set postsubmits: %v", err)
+			}
+
+			if err := handleSE(c, tc.se); err != nil {
+				t.Fatalf("handleSE returned unexpected error: %v", err)
+			}
+
+			pjs, err := fakeProwJobClient.ProwV1().ProwJobs("prowjobs").List(metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("could not list prowjobs: %v", err)
+			}
+			started := sets.NewString()
+			for _, pj := range pjs.Items {
+				started.Insert(pj.Spec.Job)
+				if pj.Spec.Refs.BaseSHA != fakegithub.TestRef && pj.Spec.Type == prowapi.PresubmitJob {
+					t.Errorf("expected presubmit %s to test base %s, got %s", pj.Spec.Job, fakegithub.TestRef, pj.Spec.Refs.BaseSHA)
+				}
+				if pj.Spec.Type == prowapi.PostsubmitJob && (pj.Spec.Refs.BaseRef != "master" || pj.Spec.Refs.BaseSHA != sha) {
+					t.Errorf("expected postsubmit %s to test master at %s, got %s at %s", pj.Spec.Job, sha, pj.Spec.Refs.BaseRef, pj.Spec.Refs.BaseSHA)
+				}
+			}
+			if tc.expectedJobs == nil {
+				tc.expectedJobs = sets.NewString()
+			}
+			if !started.Equal(tc.expectedJobs) {
+				t.Errorf("expected jobs %v to start, got %v", tc.expectedJobs.List(), started.List())
+			}
+			if actual := fakeGitHubClient.CreatedStatuses[sha]; !reflect.DeepEqual(actual, tc.expectedStatuses) {
+				t.Errorf("created incorrect statuses: %s", diff.ObjectReflectDiff(actual, tc.expectedStatuses))
+			}
+		})
+	}
+}
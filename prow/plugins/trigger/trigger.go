@@ -42,6 +42,8 @@ func init() {
 	plugins.RegisterGenericCommentHandler(PluginName, handleGenericCommentEvent, helpProvider)
 	plugins.RegisterPullRequestHandler(PluginName, handlePullRequest, helpProvider)
 	plugins.RegisterPushEventHandler(PluginName, handlePush, helpProvider)
+	plugins.RegisterStatusEventHandler(PluginName, handleStatusEvent, helpProvider)
+	plugins.RegisterCheckRunEventHandler(PluginName, handleCheckRunEvent, helpProvider)
 }
 
 func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
@@ -57,7 +59,7 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 	pluginHelp := &pluginhelp.PluginHelp{
 		Description: `The trigger plugin starts tests in reaction to commands and pull request events. It is responsible for ensuring that test jobs are only run on trusted PRs. A PR is considered trusted if the author is a member of the 'trusted organization' for the repository or if such a member has left an '/ok-to-test' command on the PR.
 <br>Trigger starts jobs automatically when a new trusted PR is created or when an untrusted PR becomes trusted, but it can also be used to start jobs manually via the '/test' command.
-<br>The '/retest' command can be used to rerun jobs that have reported failure.`,
+<br>The '/retest' command can be used to rerun jobs that have reported failure. Jobs that report check runs can also be run again with the re-run buttons of the check run.`,
 		Config: configInfo,
 	}
 	pluginHelp.AddCommand(pluginhelp.Command{
@@ -106,6 +108,7 @@ type githubClient interface {
 	RemoveLabel(org, repo string, number int, label string) error
 	DeleteStaleComments(org, repo string, number int, comments []github.IssueComment, isStale func(github.IssueComment) bool) error
 	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
 }
 
 type trustedPullRequestClient interface {
@@ -160,6 +163,14 @@ func handlePush(pc plugins.Agent, pe github.PushEvent) error {
 	return handlePE(getClient(pc), pe)
 }
 
+func handleStatusEvent(pc plugins.Agent, se github.StatusEvent) error {
+	return handleSE(getClient(pc), se)
+}
+
+func handleCheckRunEvent(pc plugins.Agent, cre github.CheckRunEvent) error {
+	return handleCRE(getClient(pc), pc.PluginConfig.TriggerFor(cre.Repo.Owner.Login, cre.Repo.Name), cre)
+}
+
 // TrustedUser returns true if user is trusted in repo.
 // Trusted users are either repo collaborators, org members or trusted org members.
 func TrustedUser(ghc trustedUserClient, onlyOrgMembers bool, trustedOrg, user, org, repo string) (bool, error) {
@@ -202,20 +213,49 @@ func skippedStatusFor(context string) github.Status {
 	}
 }
 
+// waitingDescriptionPrefix starts the description of the statuses of jobs
+// that wait for the jobs in their run_after_success to succeed.
+const waitingDescriptionPrefix = "Waiting on "
+
+func waitingStatusFor(context string, waitingOn []string) github.Status {
+	description := waitingDescriptionPrefix + strings.Join(waitingOn, ", ") + "."
+	// GitHub rejects descriptions longer than 140 characters.
+	if len(description) > 140 {
+		description = fmt.Sprintf("%s%d jobs.", waitingDescriptionPrefix, len(waitingOn))
+	}
+	return github.Status{
+		State:       github.StatusPending,
+		Context:     context,
+		Description: description,
+	}
+}
+
+// isWaiting determines whether the status was posted for a job that waits
+// for the jobs in its run_after_success to succeed.
+func isWaiting(status github.Status) bool {
+	return status.State == github.StatusPending && strings.HasPrefix(status.Description, waitingDescriptionPrefix)
+}
+
 // RunAndSkipJobs executes the config.Presubmits that are requested and posts skipped statuses
-// for the reporting jobs that are skipped
+// for the reporting jobs that are skipped. Requested jobs that run after the success of other
+// jobs are reported as pending until those succeeded.
 func RunAndSkipJobs(c Client, pr *github.PullRequest, baseSHA string, requestedJobs []config.Presubmit, skippedJobs []config.Presubmit, eventGUID string, elideSkippedContexts bool) error {
 	if err := validateContextOverlap(requestedJobs, skippedJobs); err != nil {
 		c.Logger.WithError(err).Warn("Could not run or skip requested jobs, overlapping contexts.")
 		return err
 	}
-	runErr := runRequested(c, pr, baseSHA, requestedJobs, eventGUID)
+	toRun, toWait, err := partitionWaitingJobs(c, pr, baseSHA, requestedJobs, skippedJobs)
+	if err != nil {
+		return err
+	}
+	runErr := runRequested(c, pr, baseSHA, toRun, eventGUID)
+	waitErr := waitRequested(c, pr, toWait)
 	var skipErr error
 	if !elideSkippedContexts {
 		skipErr = skipRequested(c, pr, skippedJobs)
 	}
 
-	return utilerrors.NewAggregate([]error{runErr, skipErr})
+	return utilerrors.NewAggregate([]error{runErr, waitErr, skipErr})
 }
 
 // validateContextOverlap ensures that there will be no overlap in contexts between a set of jobs running and a set to skip
@@ -250,6 +290,98 @@ func runRequested(c Client, pr *github.PullRequest, baseSHA string, requestedJob
 	return utilerrors.NewAggregate(errors)
 }
 
+// waitingJob is a requested presubmit that has to wait for the jobs it runs
+// after to succeed.
+type waitingJob struct {
+	config.Presubmit
+	waitingOn []string
+}
+
+// partitionWaitingJobs splits the requested presubmits into the ones that can
+// start right away and the ones that wait for the jobs in their
+// run_after_success. A job waits for the jobs that are requested along with
+// it and for the jobs that reported anything but success on the head of the
+// PR. Jobs that did not report or that are skipped do not hold it back.
+func partitionWaitingJobs(c Client, pr *github.PullRequest, baseSHA string, requestedJobs, skippedJobs []config.Presubmit) ([]config.Presubmit, []waitingJob, error) {
+	var hasDependencies bool
+	requested, skipped := sets.NewString(), sets.NewString()
+	for _, job := range requestedJobs {
+		requested.Insert(job.Name)
+		hasDependencies = hasDependencies || len(job.RunAfterSuccess) > 0
+	}
+	if !hasDependencies {
+		return requestedJobs, nil, nil
+	}
+	for _, job := range skippedJobs {
+		skipped.Insert(job.Name)
+	}
+
+	org, repo := pr.Base.Repo.Owner.Login, pr.Base.Repo.Name
+	combinedStatus, err := c.GitHubClient.GetCombinedStatus(org, repo, pr.Head.SHA)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the statuses of %s: %v", pr.Head.SHA, err)
+	}
+	states := map[string]string{}
+	if combinedStatus != nil {
+		for _, status := range combinedStatus.Statuses {
+			states[status.Context] = status.State
+		}
+	}
+	contexts := map[string]string{}
+	presubmits := getPresubmits(c.Logger, c.GitClient, c.Config, org+"/"+repo,
+		func() (string, error) { return baseSHA, nil },
+		func() (string, error) { return pr.Head.SHA, nil })
+	for _, job := range presubmits {
+		if job.CouldRun(pr.Base.Ref) {
+			contexts[job.Name] = job.Context
+		}
+	}
+
+	var toRun []config.Presubmit
+	var toWait []waitingJob
+	for _, job := range requestedJobs {
+		var waitingOn []string
+		for _, parent := range job.RunAfterSuccess {
+			if skipped.Has(parent) {
+				continue
+			}
+			if requested.Has(parent) {
+				waitingOn = append(waitingOn, parent)
+				continue
+			}
+			context, exists := contexts[parent]
+			if !exists {
+				continue
+			}
+			if state, reported := states[context]; reported && state != github.StatusSuccess {
+				waitingOn = append(waitingOn, parent)
+			}
+		}
+		if len(waitingOn) > 0 {
+			toWait = append(toWait, waitingJob{Presubmit: job, waitingOn: waitingOn})
+		} else {
+			toRun = append(toRun, job)
+		}
+	}
+	return toRun, toWait, nil
+}
+
+// waitRequested posts pending statuses for the requested presubmits that wait
+// for other jobs to succeed
+func waitRequested(c Client, pr *github.PullRequest, waitingJobs []waitingJob) error {
+	var errors []error
+	for _, job := range waitingJobs {
+		if job.SkipReport {
+			continue
+		}
+		c.Logger.Infof("Waiting on %s before starting %s build.", strings.Join(job.waitingOn, ", "), job.Name)
+		if err := c.GitHubClient.CreateStatus(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Head.SHA, waitingStatusFor(job.Context, job.waitingOn)); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
 // skipRequested posts skipped statuses for the config.Presubmits that are requested
 func skipRequested(c Client, pr *github.PullRequest, skippedJobs []config.Presubmit) error {
 	var errors []error
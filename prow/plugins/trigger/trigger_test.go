@@ -86,6 +86,8 @@ func TestRunAndSkipJobs(t *testing.T) {
 
 		requestedJobs        []config.Presubmit
 		skippedJobs          []config.Presubmit
+		presubmits           []config.Presubmit // configured for the repo
+		existingStatuses     []github.Status
 		elideSkippedContexts bool
 		jobCreationErrs      sets.String // job names which fail creation
 
@@ -278,6 +280,87 @@ func TestRunAndSkipJobs(t *testing.T) {
 			}},
 			expectedErr: true,
 		},
+		{
+			name: "jobs wait for the jobs they run after when those are requested, too",
+			requestedJobs: []config.Presubmit{{
+				JobBase:  config.JobBase{Name: "build"},
+				Reporter: config.Reporter{Context: "build-context"},
+			}, {
+				JobBase:  config.JobBase{Name: "unit"},
+				Reporter: config.Reporter{Context: "unit-context"},
+			}, {
+				JobBase:         config.JobBase{Name: "e2e"},
+				RunAfterSuccess: []string{"build", "unit"},
+				Reporter:        config.Reporter{Context: "e2e-context"},
+			}},
+			expectedJobs: sets.NewString("build", "unit"),
+			expectedStatuses: []github.Status{{
+				State:       github.StatusPending,
+				Context:     "e2e-context",
+				Description: "Waiting on build, unit.",
+			}},
+		},
+		{
+			name: "jobs wait for the jobs they run after which did not succeed",
+			requestedJobs: []config.Presubmit{{
+				JobBase:         config.JobBase{Name: "e2e"},
+				RunAfterSuccess: []string{"build", "unit"},
+				Reporter:        config.Reporter{Context: "e2e-context"},
+			}},
+			presubmits: []config.Presubmit{{
+				JobBase:  config.JobBase{Name: "build"},
+				Reporter: config.Reporter{Context: "build-context"},
+			}, {
+				JobBase:  config.JobBase{Name: "unit"},
+				Reporter: config.Reporter{Context: "unit-context"},
+			}},
+			existingStatuses: []github.Status{
+				{State: github.StatusSuccess, Context: "build-context"},
+				{State: github.StatusFailure, Context: "unit-context"},
+			},
+			expectedStatuses: []github.Status{
+				{State: github.StatusSuccess, Context: "build-context"},
+				{State: github.StatusFailure, Context: "unit-context"},
+				{State: github.StatusPending, Context: "e2e-context", Description: "Waiting on unit."},
+			},
+		},
+		{
+			name: "jobs start once the jobs they run after succeeded",
+			requestedJobs: []config.Presubmit{{
+				JobBase:         config.JobBase{Name: "e2e"},
+				RunAfterSuccess: []string{"build"},
+				Reporter:        config.Reporter{Context: "e2e-context"},
+			}},
+			presubmits: []config.Presubmit{{
+				JobBase:  config.JobBase{Name: "build"},
+				Reporter: config.Reporter{Context: "build-context"},
+			}},
+			existingStatuses: []github.Status{
+				{State: github.StatusSuccess, Context: "build-context"},
+			},
+			expectedJobs: sets.NewString("e2e"),
+			expectedStatuses: []github.Status{
+				{State: github.StatusSuccess, Context: "build-context"},
+			},
+		},
+		{
+			name: "jobs do not wait for skipped jobs or jobs that never reported",
+			requestedJobs: []config.Presubmit{{
+				JobBase:         config.JobBase{Name: "e2e"},
+				RunAfterSuccess: []string{"build", "unit"},
+				Reporter:        config.Reporter{Context: "e2e-context"},
+			}},
+			skippedJobs: []config.Presubmit{{
+				JobBase:  config.JobBase{Name: "build"},
+				Reporter: config.Reporter{Context: "build-context"},
+			}},
+			presubmits: []config.Presubmit{{
+				JobBase:  config.JobBase{Name: "unit"},
+				Reporter: config.Reporter{Context: "unit-context"},
+			}},
+			elideSkippedContexts: true,
+			expectedJobs:         sets.NewString("e2e"),
+		},
 	}
 
 	pr := &github.PullRequest{
@@ -296,7 +379,16 @@ func TestRunAndSkipJobs(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		fakeGitHubClient := fakegithub.FakeClient{}
+		fakeGitHubClient := fakegithub.FakeClient{
+			CombinedStatuses: map[string]*github.CombinedStatus{
+				pr.Head.SHA: {Statuses: testCase.existingStatuses},
+			},
+		}
+		for _, status := range testCase.existingStatuses {
+			if err := fakeGitHubClient.CreateStatus("org", "repo", pr.Head.SHA, status); err != nil {
+				t.Fatalf("%s: failed to create status: %v", testCase.name, err)
+			}
+		}
 		fakeProwJobClient := fake.NewSimpleClientset()
 		fakeProwJobClient.PrependReactor("*", "*", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
 			switch action := action.(type) {
@@ -314,8 +406,11 @@ func TestRunAndSkipJobs(t *testing.T) {
 		client := Client{
 			GitHubClient:  &fakeGitHubClient,
 			ProwJobClient: fakeProwJobClient.ProwV1().ProwJobs("prowjobs"),
-			Logger:        logrus.WithField("testcase", testCase.name),
-			GitClient:     nil,
+			Config: &config.Config{JobConfig: config.JobConfig{PresubmitsStatic: map[string][]config.Presubmit{
+				"org/repo": append(testCase.presubmits, append(testCase.requestedJobs, testCase.skippedJobs...)...),
+			}}},
+			Logger:    logrus.WithField("testcase", testCase.name),
+			GitClient: nil,
 		}
 
 		err := RunAndSkipJobs(client, pr, fakegithub.TestRef, testCase.requestedJobs, testCase.skippedJobs, "event-guid", testCase.elideSkippedContexts)
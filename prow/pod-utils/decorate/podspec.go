@@ -28,6 +28,7 @@ import (
 	"github.com/sirupsen/logrus"
 	coreapi "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
@@ -58,6 +59,7 @@ const (
 	s3CredentialsMountPath  = "/secrets/s3-storage"
 	outputMountName         = "output"
 	outputMountPath         = "/output"
+	censoringMountPath      = "/secrets/censoring"
 	oauthTokenFilename      = "oauth-token"
 )
 
@@ -681,7 +683,11 @@ func decorate(spec *coreapi.PodSpec, pj *prowapi.ProwJob, rawEnv map[string]stri
 		return fmt.Errorf("wrap container: %v", err)
 	}
 
-	sidecar, err := Sidecar(pj.Spec.DecorationConfig, blobStorageOptions, blobStorageMounts, logMount, outputMount, encodedJobSpec, !RequirePassingEntries, *wrapperOptions)
+	var secretMounts []coreapi.VolumeMount
+	if pj.Spec.DecorationConfig.ShouldCensorSecrets() {
+		secretMounts = censoringMounts(spec)
+	}
+	sidecar, err := Sidecar(pj.Spec.DecorationConfig, blobStorageOptions, blobStorageMounts, logMount, outputMount, secretMounts, encodedJobSpec, !RequirePassingEntries, *wrapperOptions)
 	if err != nil {
 		return fmt.Errorf("create sidecar: %v", err)
 	}
@@ -708,6 +714,31 @@ func decorate(spec *coreapi.PodSpec, pj *prowapi.ProwJob, rawEnv map[string]stri
 	return nil
 }
 
+// censoringMounts returns read-only mounts for the sidecar of the secret
+// volumes mounted into the test container, so that it can censor them.
+func censoringMounts(spec *coreapi.PodSpec) []coreapi.VolumeMount {
+	secretVolumes := sets.NewString()
+	for _, volume := range spec.Volumes {
+		if volume.Secret != nil {
+			secretVolumes.Insert(volume.Name)
+		}
+	}
+	var mounts []coreapi.VolumeMount
+	mounted := sets.NewString()
+	for _, mount := range spec.Containers[0].VolumeMounts {
+		if !secretVolumes.Has(mount.Name) || mounted.Has(mount.Name) {
+			continue
+		}
+		mounted.Insert(mount.Name)
+		mounts = append(mounts, coreapi.VolumeMount{
+			Name:      mount.Name,
+			MountPath: filepath.Join(censoringMountPath, mount.Name),
+			ReadOnly:  true,
+		})
+	}
+	return mounts
+}
+
 // DetermineWorkDir determines the working directory to use for a given set of refs to clone
 func DetermineWorkDir(baseDir string, refs []prowapi.Refs) string {
 	for _, ref := range refs {
@@ -723,13 +754,25 @@ const (
 	RequirePassingEntries = true
 )
 
-func Sidecar(config *prowapi.DecorationConfig, gcsOptions gcsupload.Options, blobStorageMounts []coreapi.VolumeMount, logMount coreapi.VolumeMount, outputMount *coreapi.VolumeMount, encodedJobSpec string, requirePassingEntries bool, wrappers ...wrapper.Options) (*coreapi.Container, error) {
+// Sidecar creates the container that uploads the logs and artifacts of the
+// wrapped containers. The sidecar censors the secrets mounted by the
+// secretMounts from them.
+func Sidecar(config *prowapi.DecorationConfig, gcsOptions gcsupload.Options, blobStorageMounts []coreapi.VolumeMount, logMount coreapi.VolumeMount, outputMount *coreapi.VolumeMount, secretMounts []coreapi.VolumeMount, encodedJobSpec string, requirePassingEntries bool, wrappers ...wrapper.Options) (*coreapi.Container, error) {
 	gcsOptions.Items = append(gcsOptions.Items, artifactsDir(logMount))
-	sidecarConfigEnv, err := sidecar.Encode(sidecar.Options{
-		GcsOptions: &gcsOptions,
-		Entries:    wrappers,
-		EntryError: requirePassingEntries,
-	})
+	var secretDirectories []string
+	for _, mount := range secretMounts {
+		secretDirectories = append(secretDirectories, mount.MountPath)
+	}
+	options := sidecar.Options{
+		GcsOptions:        &gcsOptions,
+		Entries:           wrappers,
+		EntryError:        requirePassingEntries,
+		SecretDirectories: secretDirectories,
+	}
+	if len(secretMounts) > 0 {
+		options.CensoringOptions = config.CensoringOptions
+	}
+	sidecarConfigEnv, err := sidecar.Encode(options)
 	if err != nil {
 		return nil, err
 	}
@@ -738,6 +781,7 @@ func Sidecar(config *prowapi.DecorationConfig, gcsOptions gcsupload.Options, blo
 	if outputMount != nil {
 		mounts = append(mounts, *outputMount)
 	}
+	mounts = append(mounts, secretMounts...)
 
 	container := &coreapi.Container{
 		Name:    "sidecar",
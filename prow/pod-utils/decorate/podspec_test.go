@@ -18,7 +18,9 @@ package decorate
 
 import (
 	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -2015,3 +2017,90 @@ func TestProwJobToPod_setsTerminationGracePeriodSeconds(t *testing.T) {
 		})
 	}
 }
+
+func TestProwJobToPod_mountsSecretsToCensor(t *testing.T) {
+	podSpec := func() *coreapi.PodSpec {
+		return &coreapi.PodSpec{
+			Containers: []coreapi.Container{{
+				VolumeMounts: []coreapi.VolumeMount{
+					{Name: "token", MountPath: "/etc/token"},
+					{Name: "token", MountPath: "/etc/token-again", SubPath: "key"},
+					{Name: "cache", MountPath: "/cache"},
+				},
+			}},
+			Volumes: []coreapi.Volume{
+				{Name: "token", VolumeSource: coreapi.VolumeSource{Secret: &coreapi.SecretVolumeSource{SecretName: "token"}}},
+				{Name: "cache", VolumeSource: coreapi.VolumeSource{EmptyDir: &coreapi.EmptyDirVolumeSource{}}},
+				{Name: "unmounted", VolumeSource: coreapi.VolumeSource{Secret: &coreapi.SecretVolumeSource{SecretName: "unmounted"}}},
+			},
+		}
+	}
+	maxSize := int64(1024)
+	censoringOptions := &prowapi.CensoringOptions{MaxArtifactSize: &maxSize}
+
+	testCases := []struct {
+		name                string
+		censorSecrets       *bool
+		expectedMounts      []coreapi.VolumeMount
+		expectedDirectories []string
+		expectedOptions     *prowapi.CensoringOptions
+	}{
+		{
+			name:                "secrets mounted into the test container are censored by default",
+			expectedMounts:      []coreapi.VolumeMount{{Name: "token", MountPath: "/secrets/censoring/token", ReadOnly: true}},
+			expectedDirectories: []string{"/secrets/censoring/token"},
+			expectedOptions:     censoringOptions,
+		},
+		{
+			name:          "secrets are not censored when disabled",
+			censorSecrets: utilpointer.BoolPtr(false),
+		},
+	}
+
+	for idx := range testCases {
+		tc := testCases[idx]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pj := &prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					PodSpec: podSpec(),
+					DecorationConfig: &prowapi.DecorationConfig{
+						UtilityImages:    &prowapi.UtilityImages{},
+						CensorSecrets:    tc.censorSecrets,
+						CensoringOptions: censoringOptions,
+					},
+				},
+			}
+			if err := decorate(pj.Spec.PodSpec, pj, map[string]string{}, ""); err != nil {
+				t.Fatalf("decoration failed: %v", err)
+			}
+			container := pj.Spec.PodSpec.Containers[len(pj.Spec.PodSpec.Containers)-1]
+			if container.Name != "sidecar" {
+				t.Fatalf("expected the sidecar to be the last container, got %s", container.Name)
+			}
+			var actualMounts []coreapi.VolumeMount
+			for _, mount := range container.VolumeMounts {
+				if strings.HasPrefix(mount.MountPath, censoringMountPath) {
+					actualMounts = append(actualMounts, mount)
+				}
+			}
+			if !reflect.DeepEqual(actualMounts, tc.expectedMounts) {
+				t.Errorf("expected sidecar mounts %v, got %v", tc.expectedMounts, actualMounts)
+			}
+			options := sidecar.NewOptions()
+			for _, env := range container.Env {
+				if env.Name == options.ConfigVar() {
+					if err := options.LoadConfig(env.Value); err != nil {
+						t.Fatalf("failed to load sidecar options: %v", err)
+					}
+				}
+			}
+			if !reflect.DeepEqual(options.SecretDirectories, tc.expectedDirectories) {
+				t.Errorf("expected secret directories %v, got %v", tc.expectedDirectories, options.SecretDirectories)
+			}
+			if !reflect.DeepEqual(options.CensoringOptions, tc.expectedOptions) {
+				t.Errorf("expected censoring options %v, got %v", tc.expectedOptions, options.CensoringOptions)
+			}
+		})
+	}
+}
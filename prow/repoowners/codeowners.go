@@ -0,0 +1,332 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repoowners
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+const codeOwnersFileName = "CODEOWNERS"
+
+// codeOwnersDirs lists the directories GitHub looks for a CODEOWNERS file
+// in, in the order it looks.
+//
+// See https://help.github.com/en/github/creating-cloning-and-archiving-repositories/about-code-owners#codeowners-file-location
+var codeOwnersDirs = []string{".github", baseDirConvention, "docs"}
+
+// teamResolver returns the logins of the members of a GitHub team.
+type teamResolver func(org, slug string) (sets.String, error)
+
+// codeOwnersRule is a line of a CODEOWNERS file.
+type codeOwnersRule struct {
+	pattern string
+	// dir is the longest directory (or file) the pattern is rooted in
+	dir string
+	// re matches paths relative to dir, nil matching everything
+	re     *regexp.Regexp
+	owners []string
+}
+
+// codeOwnersEntry is where the owners of a CODEOWNERS rule are recorded.
+type codeOwnersEntry struct {
+	dir string
+	re  *regexp.Regexp
+}
+
+// parseCodeOwners parses the content of a CODEOWNERS file.
+// Lines that cannot be parsed are returned as errors along with the other rules.
+func parseCodeOwners(b []byte) ([]codeOwnersRule, []error) {
+	var rules []codeOwnersRule
+	var errs []error
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		rule := codeOwnersRule{pattern: fields[0]}
+		var err error
+		if rule.dir, rule.re, err = codeOwnersPattern(rule.pattern); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %v", line, err))
+			continue
+		}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			rule.owners = append(rule.owners, owner)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return rules, errs
+}
+
+// codeOwnersPattern splits a gitignore style CODEOWNERS pattern into the
+// directory it is rooted in and a regexp matching paths relative to that
+// directory. A nil regexp matches the whole directory.
+func codeOwnersPattern(pattern string) (string, *regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "!") {
+		return "", nil, fmt.Errorf("negated pattern %q is not supported", pattern)
+	}
+	p := pattern
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	// Patterns with a slash other than a trailing one are relative to the
+	// root, the others match at any depth.
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" || (!anchored && p == "*") {
+		return baseDirConvention, nil, nil
+	}
+
+	segments := strings.Split(p, "/")
+	var prefix []string
+	if anchored {
+		for _, segment := range segments {
+			if strings.ContainsAny(segment, `*?[\`) {
+				break
+			}
+			prefix = append(prefix, segment)
+		}
+	}
+	dir := strings.Join(prefix, "/")
+	rest := segments[len(prefix):]
+	if len(rest) == 0 {
+		return dir, nil, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(.*/)?")
+	}
+	for i, segment := range rest {
+		last := i == len(rest)-1
+		if segment == "**" {
+			if last {
+				expr.WriteString(".*")
+			} else {
+				expr.WriteString("(.*/)?")
+			}
+			continue
+		}
+		expr.WriteString(globToRegexp(segment))
+		if !last {
+			expr.WriteString("/")
+		}
+	}
+	last := rest[len(rest)-1]
+	switch {
+	case dirOnly:
+		expr.WriteString("/.*")
+	case !strings.ContainsAny(last, "*?["):
+		// a plain name matches a file or everything in a directory,
+		// while a wildcard like docs/* does not match nested files
+		expr.WriteString("(/.*)?")
+	}
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	return dir, re, nil
+}
+
+// globToRegexp converts a single path segment of a glob into a regexp.
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				expr.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expr.String()
+}
+
+// loadCodeOwners reads the first CODEOWNERS file GitHub would use, if any, and
+// makes its owners the approvers and reviewers of the paths they own.
+// Owners may be users or @org/team entries, which resolve to the team members.
+// Email addresses cannot be mapped to GitHub users and are skipped.
+//
+// Teams that cannot be resolved are skipped.
+//
+// CODEOWNERS rules are recorded in the same structures as OWNERS files, with
+// two differences. Like on GitHub, only the last matching rule of the file
+// applies, and every directory with rules is treated as having no parent
+// owners.
+func (o *RepoOwners) loadCodeOwners(resolveTeam teamResolver) error {
+	var path string
+	var b []byte
+	for _, dir := range codeOwnersDirs {
+		candidate := filepath.Join(o.baseDir, dir, codeOwnersFileName)
+		var err error
+		b, err = ioutil.ReadFile(candidate)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", candidate, err)
+		}
+		path = candidate
+		break
+	}
+	if path == "" {
+		return nil
+	}
+	log := o.log.WithField("path", path)
+
+	rules, errs := parseCodeOwners(b)
+	for _, err := range errs {
+		log.WithError(err).Warn("Skipping invalid CODEOWNERS rule.")
+	}
+
+	teams := map[string]sets.String{}
+	o.codeOwners = []codeOwnersEntry{}
+	for _, rule := range rules {
+		owners := sets.NewString()
+		for _, owner := range rule.owners {
+			if !strings.HasPrefix(owner, "@") {
+				log.WithField("owner", owner).Debug("Skipping CODEOWNERS owner that is not a GitHub user or team.")
+				continue
+			}
+			name := strings.TrimPrefix(owner, "@")
+			parts := strings.SplitN(name, "/", 2)
+			if len(parts) == 1 {
+				owners.Insert(github.NormLogin(name))
+				continue
+			}
+			team := strings.ToLower(name)
+			members, looked := teams[team]
+			if !looked {
+				var err error
+				if members, err = resolveTeam(parts[0], parts[1]); err != nil {
+					log.WithError(err).WithField("owner", owner).Warn("Skipping CODEOWNERS team that cannot be resolved.")
+				}
+				// a team that cannot be resolved is remembered without
+				// members, so it is only looked up once per file
+				teams[team] = members
+			}
+			owners = owners.Union(members)
+		}
+		o.codeOwners = append(o.codeOwners, codeOwnersEntry{dir: rule.dir, re: rule.re})
+		// Rules owning a whole directory share where their owners are
+		// recorded, the last one replaces the owners of the others even if
+		// it has none.
+		delete(o.approvers[rule.dir], rule.re)
+		delete(o.reviewers[rule.dir], rule.re)
+		logins := owners.List()
+		o.applyConfigToPath(rule.dir, rule.re, &Config{Approvers: logins, Reviewers: logins})
+		o.applyOptionsToPath(rule.dir, dirOptions{NoParentOwners: true})
+	}
+	log.Infof("Loaded %d CODEOWNERS rules.", len(rules))
+	return nil
+}
+
+// isEmpty reports whether no owners, labels or options were loaded.
+func (o *RepoOwners) isEmpty() bool {
+	return len(o.approvers) == 0 && len(o.reviewers) == 0 && len(o.requiredReviewers) == 0 &&
+		len(o.labels) == 0 && len(o.options) == 0
+}
+
+// errNoCodeOwnersRule is returned when no CODEOWNERS rule matches a path.
+var errNoCodeOwnersRule = errors.New("no matching CODEOWNERS rule")
+
+// codeOwnersRuleFor finds the CODEOWNERS rule that applies to path: the last
+// matching rule of the file. It returns the directory and the regexp of the
+// rule.
+func (o *RepoOwners) codeOwnersRuleFor(path string) (string, *regexp.Regexp, error) {
+	path = canonicalize(path)
+	for i := len(o.codeOwners) - 1; i >= 0; i-- {
+		rule := o.codeOwners[i]
+		if rule.dir != baseDirConvention && path != rule.dir && !strings.HasPrefix(path, rule.dir+"/") {
+			continue
+		}
+		relative, err := filepath.Rel(rule.dir, path)
+		if err != nil {
+			return "", nil, err
+		}
+		if rule.re == nil || rule.re.MatchString(relative) {
+			return rule.dir, rule.re, nil
+		}
+	}
+	return "", nil, errNoCodeOwnersRule
+}
+
+// codeOwnersForFile returns the owners people maps to the CODEOWNERS rule
+// that applies to path.
+func (o *RepoOwners) codeOwnersForFile(path string, people map[string]map[*regexp.Regexp]sets.String) sets.String {
+	d, re, err := o.codeOwnersRuleFor(path)
+	if err != nil {
+		if err != errNoCodeOwnersRule {
+			o.log.WithError(err).WithField("path", path).Error("Unable to find the CODEOWNERS rule for path.")
+		}
+		return sets.NewString()
+	}
+	return sets.NewString(people[d][re].List()...)
+}
+
+// findCodeOwnersForFile returns the key approvers and reviewers of path are
+// recorded under. That is the directory of the rule for path when the rule
+// owns the whole directory, and path itself when the rule only owns some of
+// its files so that the path is not mistaken for the rest of the directory.
+func (o *RepoOwners) findCodeOwnersForFile(path string, people map[string]map[*regexp.Regexp]sets.String) string {
+	d, re, err := o.codeOwnersRuleFor(path)
+	if err != nil || len(people[d][re]) == 0 {
+		return baseDirConvention
+	}
+	if re != nil {
+		return path
+	}
+	return d
+}
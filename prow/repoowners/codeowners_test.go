@@ -0,0 +1,339 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repoowners
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/git/localgit"
+)
+
+func TestCodeOwnersPattern(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		expectedDir string
+		// a nil regexp matches everything
+		expectedAll bool
+		matches     []string
+		nonMatches  []string
+		expectErr   bool
+	}{
+		{
+			pattern:     "*",
+			expectedDir: "",
+			expectedAll: true,
+		},
+		{
+			pattern:     "*.js",
+			expectedDir: "",
+			matches:     []string{"app.js", "src/app.js", "src/lib/app.js"},
+			nonMatches:  []string{"app.jsx", "app.js/README.md"},
+		},
+		{
+			pattern:     "/docs/",
+			expectedDir: "docs",
+			expectedAll: true,
+		},
+		{
+			pattern:     "docs/",
+			expectedDir: "",
+			matches:     []string{"docs/README.md", "src/docs/README.md", "docs/guide/intro.md"},
+			nonMatches:  []string{"docs", "src/docs"},
+		},
+		{
+			pattern:     "docs/*",
+			expectedDir: "docs",
+			matches:     []string{"README.md", "guide"},
+			nonMatches:  []string{"guide/intro.md"},
+		},
+		{
+			pattern:     "**/logs",
+			expectedDir: "",
+			matches:     []string{"logs", "logs/today.log", "build/logs", "build/logs/today.log"},
+			nonMatches:  []string{"build/logs.txt", "mylogs"},
+		},
+		{
+			pattern:     "/apps/github",
+			expectedDir: "apps/github",
+			expectedAll: true,
+		},
+		{
+			pattern:     "/apps/**/*.go",
+			expectedDir: "apps",
+			matches:     []string{"main.go", "github/main.go", "github/api/types.go"},
+			nonMatches:  []string{"README.md", "github/main.go.orig"},
+		},
+		{
+			pattern:     "build/log?/[!a]*.txt",
+			expectedDir: "build",
+			matches:     []string{"logs/build.txt"},
+			nonMatches:  []string{"logs/all.txt", "log/build.txt", "logs/nested/build.txt"},
+		},
+		{
+			pattern:   "!vendor",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			dir, re, err := codeOwnersPattern(test.pattern)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dir != test.expectedDir {
+				t.Errorf("expected dir %q, got %q", test.expectedDir, dir)
+			}
+			if (re == nil) != test.expectedAll {
+				t.Fatalf("expected nil regexp: %t, got %v", test.expectedAll, re)
+			}
+			for _, path := range test.matches {
+				if !re.MatchString(path) {
+					t.Errorf("expected %s to match %q", re, path)
+				}
+			}
+			for _, path := range test.nonMatches {
+				if re.MatchString(path) {
+					t.Errorf("expected %s not to match %q", re, path)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCodeOwners(t *testing.T) {
+	rules, errs := parseCodeOwners([]byte(`# the default owners
+*    @alice  @org/team
+
+  /docs/ @bob # docs team
+!vendor @carl
+*.md docs@example.com
+`))
+	if len(errs) != 1 {
+		t.Errorf("expected one error for the negated pattern, got %v", errs)
+	}
+	var got [][]string
+	for _, rule := range rules {
+		got = append(got, append([]string{rule.pattern}, rule.owners...))
+	}
+	expected := [][]string{
+		{"*", "@alice", "@org/team"},
+		{"/docs/", "@bob"},
+		{"*.md", "docs@example.com"},
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected rules %v, got %v", expected, got)
+	}
+}
+
+func TestLoadCodeOwners(t *testing.T) {
+	testLoadCodeOwners(localgit.New, t)
+}
+
+func TestLoadCodeOwnersV2(t *testing.T) {
+	testLoadCodeOwners(localgit.NewV2, t)
+}
+
+func testLoadCodeOwners(clients localgit.Clients, t *testing.T) {
+	files := map[string][]byte{
+		".github/CODEOWNERS": []byte(`# outsider is not a collaborator
+*             @cjwagner @outsider
+*.md          @alice
+/src/         @bob @org/reviewers
+/src/vendor/  vendor@example.com
+/src/api/*.go @MML
+docs/*        @carl
+*.js          @carl
+`),
+		// GitHub only reads the first CODEOWNERS file it finds
+		"CODEOWNERS": []byte("* @maggie\n"),
+	}
+	client, cleanup, err := getTestClient(files, false, false, false, false, nil, nil, nil, nil, clients)
+	if err != nil {
+		t.Fatalf("Error creating test client: %v.", err)
+	}
+	defer cleanup()
+
+	r, err := client.LoadRepoOwners("org", "repo", "master")
+	if err != nil {
+		t.Fatalf("Unexpected error loading RepoOwners: %v.", err)
+	}
+
+	tests := []struct {
+		path               string
+		expectedOwners     sets.String
+		expectedOwnersFile string
+	}{
+		{
+			path:               "main.go",
+			expectedOwners:     sets.NewString("cjwagner"),
+			expectedOwnersFile: "",
+		},
+		{
+			path:               "README.md",
+			expectedOwners:     sets.NewString("alice"),
+			expectedOwnersFile: "README.md",
+		},
+		{
+			path:               "src/main.go",
+			expectedOwners:     sets.NewString("alice", "bob"),
+			expectedOwnersFile: "src",
+		},
+		{
+			// the last matching rule wins
+			path:               "src/README.md",
+			expectedOwners:     sets.NewString("alice", "bob"),
+			expectedOwnersFile: "src",
+		},
+		{
+			// even over earlier rules for deeper directories
+			path:               "src/app.js",
+			expectedOwners:     sets.NewString("carl"),
+			expectedOwnersFile: "src/app.js",
+		},
+		{
+			path:               "src/api/app.js",
+			expectedOwners:     sets.NewString("carl"),
+			expectedOwnersFile: "src/api/app.js",
+		},
+		{
+			path:               "src/vendor/lib/lib.go",
+			expectedOwners:     sets.NewString(),
+			expectedOwnersFile: "",
+		},
+		{
+			path:               "src/api/types.go",
+			expectedOwners:     sets.NewString("mml"),
+			expectedOwnersFile: "src/api/types.go",
+		},
+		{
+			path:               "src/api/v1/types.go",
+			expectedOwners:     sets.NewString("alice", "bob"),
+			expectedOwnersFile: "src",
+		},
+		{
+			path:               "docs/intro.txt",
+			expectedOwners:     sets.NewString("carl"),
+			expectedOwnersFile: "docs/intro.txt",
+		},
+		{
+			path:               "docs/guide/intro.txt",
+			expectedOwners:     sets.NewString("cjwagner"),
+			expectedOwnersFile: "",
+		},
+	}
+	for _, test := range tests {
+		if got := r.Approvers(test.path); !got.Equal(test.expectedOwners) {
+			t.Errorf("Expected approvers of %s to be %v, got %v.", test.path, test.expectedOwners.List(), got.List())
+		}
+		if got := r.LeafApprovers(test.path); !got.Equal(test.expectedOwners) {
+			t.Errorf("Expected leaf approvers of %s to be %v, got %v.", test.path, test.expectedOwners.List(), got.List())
+		}
+		if got := r.Reviewers(test.path); !got.Equal(test.expectedOwners) {
+			t.Errorf("Expected reviewers of %s to be %v, got %v.", test.path, test.expectedOwners.List(), got.List())
+		}
+		if got := r.FindApproverOwnersForFile(test.path); got != test.expectedOwnersFile {
+			t.Errorf("Expected the approvers of %s to come from %q, got %q.", test.path, test.expectedOwnersFile, got)
+		}
+		if got := r.FindReviewersOwnersForFile(test.path); got != test.expectedOwnersFile {
+			t.Errorf("Expected the reviewers of %s to come from %q, got %q.", test.path, test.expectedOwnersFile, got)
+		}
+		if !r.IsNoParentOwners(test.expectedOwnersFile) {
+			t.Errorf("Expected %q to have no parent owners.", test.expectedOwnersFile)
+		}
+	}
+}
+
+func TestLoadCodeOwnersPrecedence(t *testing.T) {
+	testLoadCodeOwnersPrecedence(localgit.New, t)
+}
+
+func TestLoadCodeOwnersPrecedenceV2(t *testing.T) {
+	testLoadCodeOwnersPrecedence(localgit.NewV2, t)
+}
+
+func testLoadCodeOwnersPrecedence(clients localgit.Clients, t *testing.T) {
+	tests := []struct {
+		name              string
+		files             map[string][]byte
+		expectedApprovers sets.String
+	}{
+		{
+			name: "OWNERS files take precedence over CODEOWNERS",
+			files: map[string][]byte{
+				"src/OWNERS":         []byte("approvers:\n- maggie"),
+				".github/CODEOWNERS": []byte("* @alice\n"),
+			},
+			expectedApprovers: sets.NewString("maggie"),
+		},
+		{
+			name: "CODEOWNERS in docs is used as a last resort",
+			files: map[string][]byte{
+				"docs/CODEOWNERS": []byte("* @alice\n"),
+			},
+			expectedApprovers: sets.NewString("alice"),
+		},
+		{
+			name: "no owners at all",
+			files: map[string][]byte{
+				"src/main.go": []byte("package main"),
+			},
+			expectedApprovers: sets.NewString(),
+		},
+		{
+			name: "unknown teams are skipped",
+			files: map[string][]byte{
+				"CODEOWNERS": []byte("* @alice @org/missing\n"),
+			},
+			expectedApprovers: sets.NewString("alice"),
+		},
+		{
+			name: "the last rule owning a directory wins even without owners",
+			files: map[string][]byte{
+				"CODEOWNERS": []byte("* @alice\n/ docs@example.com\n"),
+			},
+			expectedApprovers: sets.NewString(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, cleanup, err := getTestClient(test.files, false, false, false, false, nil, nil, nil, nil, clients)
+			if err != nil {
+				t.Fatalf("Error creating test client: %v.", err)
+			}
+			defer cleanup()
+
+			r, err := client.LoadRepoOwners("org", "repo", "master")
+			if err != nil {
+				t.Fatalf("Unexpected error loading RepoOwners: %v.", err)
+			}
+			if got := r.Approvers("src/main.go"); !got.Equal(test.expectedApprovers) {
+				t.Errorf("Expected approvers %v, got %v.", test.expectedApprovers.List(), got.List())
+			}
+		})
+	}
+}
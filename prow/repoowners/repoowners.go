@@ -75,6 +75,8 @@ type FullConfig struct {
 type githubClient interface {
 	ListCollaborators(org, repo string) ([]github.User, error)
 	GetRef(org, repo, ref string) (string, error)
+	GetTeamBySlug(slug string, org string) (*github.Team, error)
+	ListTeamMembers(id int, role string) ([]github.TeamMember, error)
 }
 
 func newCache() *cache {
@@ -126,6 +128,9 @@ type cacheEntry struct {
 	sha     string
 	aliases RepoAliases
 	owners  *RepoOwners
+	// teamsExpire is when the members of the CODEOWNERS teams the owners
+	// were loaded with expire, it is zero if they name no teams
+	teamsExpire time.Time
 }
 
 func (entry cacheEntry) matchesMDYAML(mdYAML bool) bool {
@@ -136,6 +141,63 @@ func (entry cacheEntry) fullyLoaded() bool {
 	return entry.sha != "" && entry.aliases != nil && entry.owners != nil
 }
 
+// teamsExpired reports whether the owners must be loaded again for their
+// CODEOWNERS teams, whose membership changes without the repo changing.
+func (entry cacheEntry) teamsExpired() bool {
+	return !entry.teamsExpire.IsZero() && !time.Now().Before(entry.teamsExpire)
+}
+
+const (
+	// teamMembersTTL is how long the members of a team are cached.
+	teamMembersTTL = 10 * time.Minute
+	// teamRetryInterval is how long a team that cannot be resolved is
+	// skipped before it is looked up again.
+	teamRetryInterval = time.Minute
+)
+
+func newTeamCache() *teamCache {
+	return &teamCache{
+		lock: &sync.Mutex{},
+		data: map[string]teamCacheEntry{},
+	}
+}
+
+// teamCache holds the members of GitHub teams until they expire. Failed
+// lookups are not cached.
+type teamCache struct {
+	lock *sync.Mutex
+	data map[string]teamCacheEntry
+}
+
+type teamCacheEntry struct {
+	members sets.String
+	expires time.Time
+}
+
+// members returns the members of the org/slug team and when they expire,
+// resolving them unless they are cached. After a failed lookup the team
+// expires after teamRetryInterval.
+func (c *teamCache) members(org, slug string, resolve teamResolver) (sets.String, time.Time, error) {
+	key := strings.ToLower(org + "/" + slug)
+	now := time.Now()
+	c.lock.Lock()
+	entry, ok := c.data[key]
+	c.lock.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.members, entry.expires, nil
+	}
+
+	members, err := resolve(org, slug)
+	if err != nil {
+		return nil, now.Add(teamRetryInterval), err
+	}
+	entry = teamCacheEntry{members: members, expires: now.Add(teamMembersTTL)}
+	c.lock.Lock()
+	c.data[key] = entry
+	c.lock.Unlock()
+	return entry.members, entry.expires, nil
+}
+
 // Interface is an interface to work with OWNERS files.
 type Interface interface {
 	LoadRepoAliases(org, repo, base string) (RepoAliases, error)
@@ -163,6 +225,7 @@ type delegate struct {
 	ownersDirBlacklist func() prowConf.OwnersDirBlacklist
 
 	cache *cache
+	teams *teamCache
 }
 
 // WithFields clones the client, keeping the underlying delegate the same but adding
@@ -198,6 +261,7 @@ func NewClient(
 		delegate: &delegate{
 			git:   gc,
 			cache: newCache(),
+			teams: newTeamCache(),
 
 			mdYAMLEnabled:      mdYAMLEnabled,
 			skipCollaborators:  skipCollaborators,
@@ -236,6 +300,9 @@ type RepoOwners struct {
 	requiredReviewers map[string]map[*regexp.Regexp]sets.String
 	labels            map[string]map[*regexp.Regexp]sets.String
 	options           map[string]dirOptions
+	// codeOwners holds the rules in file order when the owners come from
+	// a CODEOWNERS file instead of OWNERS files
+	codeOwners []codeOwnersEntry
 
 	baseDir      string
 	enableMDYAML bool
@@ -330,7 +397,7 @@ func (c *Client) cacheEntryFor(org, repo, base, cloneRef, fullName, sha string,
 	}()
 	entry, ok, entryLock := c.cache.getEntry(fullName)
 	defer entryLock.Unlock()
-	if !ok || entry.sha != sha || entry.owners == nil || !entry.matchesMDYAML(mdYaml) {
+	if !ok || entry.sha != sha || entry.owners == nil || !entry.matchesMDYAML(mdYaml) || entry.teamsExpired() {
 		start := time.Now()
 		gitRepo, err := c.git.ClientFor(org, repo)
 		if err != nil {
@@ -339,7 +406,7 @@ func (c *Client) cacheEntryFor(org, repo, base, cloneRef, fullName, sha string,
 		log.WithField("duration", time.Since(start).String()).Debugf("Completed git.ClientFor(%s, %s)", org, repo)
 		defer gitRepo.Clean()
 
-		reusable := entry.fullyLoaded() && entry.matchesMDYAML(mdYaml)
+		reusable := entry.fullyLoaded() && entry.matchesMDYAML(mdYaml) && !entry.teamsExpired()
 		// In most sha changed cases, the files associated with the owners are unchanged.
 		// The cached entry can continue to be used, so need do git diff
 		if reusable {
@@ -351,6 +418,7 @@ func (c *Client) cacheEntryFor(org, repo, base, cloneRef, fullName, sha string,
 			log.WithField("duration", time.Since(start).String()).Debugf("Completed git.Diff(%s, %s)", sha, entry.sha)
 			start = time.Now()
 			for _, change := range changes {
+				// this also catches changes to CODEOWNERS files
 				if mdYaml && strings.HasSuffix(change, ".md") ||
 					strings.HasSuffix(change, aliasesFileName) ||
 					strings.HasSuffix(change, ownersFileName) {
@@ -391,18 +459,44 @@ func (c *Client) cacheEntryFor(org, repo, base, cloneRef, fullName, sha string,
 			log.WithField("duration", time.Since(start).String()).Debugf("Completed dirBlacklist loading")
 
 			start = time.Now()
-			entry.owners, err = loadOwnersFrom(gitRepo.Directory(), mdYaml, entry.aliases, dirBlacklist, log)
+			var teamsExpire time.Time
+			resolveTeam := func(org, slug string) (sets.String, error) {
+				members, expires, err := c.teams.members(org, slug, c.teamMembers)
+				if teamsExpire.IsZero() || expires.Before(teamsExpire) {
+					teamsExpire = expires
+				}
+				return members, err
+			}
+			entry.owners, err = loadOwnersFrom(gitRepo.Directory(), mdYaml, entry.aliases, dirBlacklist, resolveTeam, log)
 			if err != nil {
 				return cacheEntry{}, fmt.Errorf("failed to load RepoOwners for %s: %v", fullName, err)
 			}
 			log.WithField("duration", time.Since(start).String()).Debugf("Completed loadOwnersFrom(%s, %t, entry.aliases, dirBlacklist, log)", gitRepo.Directory(), mdYaml)
 			entry.sha = sha
+			entry.teamsExpire = teamsExpire
 			c.cache.setEntry(fullName, entry)
 		}
 	}
 	return entry, nil
 }
 
+// teamMembers returns the logins of the members of the org/slug team.
+func (c *Client) teamMembers(org, slug string) (sets.String, error) {
+	team, err := c.ghc.GetTeamBySlug(slug, org)
+	if err != nil {
+		return nil, err
+	}
+	members, err := c.ghc.ListTeamMembers(team.ID, github.RoleAll)
+	if err != nil {
+		return nil, err
+	}
+	logins := sets.NewString()
+	for _, member := range members {
+		logins.Insert(github.NormLogin(member.Login))
+	}
+	return logins, nil
+}
+
 // ExpandAlias returns members of an alias
 func (a RepoAliases) ExpandAlias(alias string) sets.String {
 	if a == nil {
@@ -459,7 +553,9 @@ func loadAliasesFrom(baseDir string, log *logrus.Entry) RepoAliases {
 	return result
 }
 
-func loadOwnersFrom(baseDir string, mdYaml bool, aliases RepoAliases, dirBlacklist []*regexp.Regexp, log *logrus.Entry) (*RepoOwners, error) {
+// loadOwnersFrom loads the OWNERS files under baseDir. Repos without any
+// OWNERS files fall back to their CODEOWNERS file, if they have one.
+func loadOwnersFrom(baseDir string, mdYaml bool, aliases RepoAliases, dirBlacklist []*regexp.Regexp, resolveTeam teamResolver, log *logrus.Entry) (*RepoOwners, error) {
 	o := &RepoOwners{
 		RepoAliases:  aliases,
 		baseDir:      baseDir,
@@ -475,7 +571,13 @@ func loadOwnersFrom(baseDir string, mdYaml bool, aliases RepoAliases, dirBlackli
 		dirBlacklist: dirBlacklist,
 	}
 
-	return o, filepath.Walk(o.baseDir, o.walkFunc)
+	if err := filepath.Walk(o.baseDir, o.walkFunc); err != nil {
+		return o, err
+	}
+	if !o.isEmpty() {
+		return o, nil
+	}
+	return o, o.loadCodeOwners(resolveTeam)
 }
 
 // by default, github's api doesn't root the project directory at "/" and instead uses the empty string for the base dir
@@ -764,12 +866,18 @@ func findOwnersForFile(log *logrus.Entry, path string, ownerMap map[string]map[*
 // FindApproverOwnersForFile returns the OWNERS file path furthest down the tree for a specified file
 // that contains an approvers section
 func (o *RepoOwners) FindApproverOwnersForFile(path string) string {
+	if o.codeOwners != nil {
+		return o.findCodeOwnersForFile(path, o.approvers)
+	}
 	return findOwnersForFile(o.log, path, o.approvers)
 }
 
 // FindReviewersOwnersForFile returns the OWNERS file path furthest down the tree for a specified file
 // that contains a reviewers section
 func (o *RepoOwners) FindReviewersOwnersForFile(path string) string {
+	if o.codeOwners != nil {
+		return o.findCodeOwnersForFile(path, o.reviewers)
+	}
 	return findOwnersForFile(o.log, path, o.reviewers)
 }
 
@@ -780,7 +888,11 @@ func (o *RepoOwners) FindLabelsForFile(path string) sets.String {
 }
 
 // IsNoParentOwners checks if an OWNERS file path refers to an OWNERS file with NoParentOwners enabled.
+// Owners from a CODEOWNERS file never have parent owners.
 func (o *RepoOwners) IsNoParentOwners(path string) bool {
+	if o.codeOwners != nil {
+		return true
+	}
 	return o.options[path].NoParentOwners
 }
 
@@ -790,6 +902,9 @@ func (o *RepoOwners) IsNoParentOwners(path string) bool {
 // leafOnly indicates whether only the OWNERS deepest in the tree (closest to the file)
 // should be returned or if all OWNERS in filepath should be returned
 func (o *RepoOwners) entriesForFile(path string, people map[string]map[*regexp.Regexp]sets.String, leafOnly bool) sets.String {
+	if o.codeOwners != nil {
+		return o.codeOwnersForFile(path, people)
+	}
 	d := path
 	if !o.enableMDYAML || !strings.HasSuffix(path, ".md") {
 		// if path is a directory, this will remove the leaf directory, and returns "." for topmost dir
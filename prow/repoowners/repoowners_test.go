@@ -17,14 +17,17 @@ limitations under the License.
 package repoowners
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -126,6 +129,8 @@ type cacheOptions struct {
 type fakeGitHubClient struct {
 	Collaborators []string
 	ref           string
+	// TeamMembers maps org/slug to the logins of the team members
+	TeamMembers map[string][]string
 }
 
 func (f *fakeGitHubClient) ListCollaborators(org, repo string) ([]github.User, error) {
@@ -140,6 +145,35 @@ func (f *fakeGitHubClient) GetRef(org, repo, ref string) (string, error) {
 	return f.ref, nil
 }
 
+func (f *fakeGitHubClient) teams() []string {
+	var teams []string
+	for team := range f.TeamMembers {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+	return teams
+}
+
+func (f *fakeGitHubClient) GetTeamBySlug(slug string, org string) (*github.Team, error) {
+	for id, team := range f.teams() {
+		if team == org+"/"+slug {
+			return &github.Team{ID: id, Slug: slug}, nil
+		}
+	}
+	return nil, fmt.Errorf("team %s/%s not found", org, slug)
+}
+
+func (f *fakeGitHubClient) ListTeamMembers(id int, role string) ([]github.TeamMember, error) {
+	if role != github.RoleAll {
+		return nil, fmt.Errorf("unexpected role %s", role)
+	}
+	var members []github.TeamMember
+	for _, login := range f.TeamMembers[f.teams()[id]] {
+		members = append(members, github.TeamMember{Login: login})
+	}
+	return members, nil
+}
+
 func getTestClient(
 	files map[string][]byte,
 	enableMdYaml,
@@ -249,7 +283,10 @@ labels:
 		// mark this entry is cache
 		entry.owners.baseDir = "cache"
 	}
-	ghc := &fakeGitHubClient{Collaborators: []string{"cjwagner", "k8s-ci-robot", "alice", "bob", "carl", "mml", "maggie"}}
+	ghc := &fakeGitHubClient{
+		Collaborators: []string{"cjwagner", "k8s-ci-robot", "alice", "bob", "carl", "mml", "maggie"},
+		TeamMembers:   map[string][]string{"org/reviewers": {"Alice", "bob"}},
+	}
 	ghc.ref, err = localGit.RevParse("org", "repo", "HEAD")
 	if err != nil {
 		return nil, nil, fmt.Errorf("cannot get commit SHA: %v", err)
@@ -260,6 +297,7 @@ labels:
 			delegate: &delegate{
 				git:   git,
 				cache: cache,
+				teams: newTeamCache(),
 
 				mdYAMLEnabled: func(org, repo string) bool {
 					return enableMdYaml
@@ -1399,3 +1437,104 @@ func TestCacheDoesntRace(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestTeamCache(t *testing.T) {
+	var lookups int
+	members := sets.NewString("alice")
+	var lookupErr error
+	resolve := func(org, slug string) (sets.String, error) {
+		lookups++
+		return members, lookupErr
+	}
+	cache := newTeamCache()
+
+	got, expires, err := cache.members("org", "team", resolve)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v.", err)
+	}
+	if !got.Equal(members) || lookups != 1 {
+		t.Errorf("Expected %v from 1 lookup, got %v from %d.", members.List(), got.List(), lookups)
+	}
+	if until := time.Until(expires); until <= teamRetryInterval || until > teamMembersTTL {
+		t.Errorf("Expected the members to expire after about %v, got %v.", teamMembersTTL, until)
+	}
+
+	members = sets.NewString("bob")
+	if got, _, _ = cache.members("Org", "Team", resolve); !got.Equal(sets.NewString("alice")) || lookups != 1 {
+		t.Errorf("Expected the cached members [alice] without another lookup, got %v from %d lookups.", got.List(), lookups)
+	}
+
+	// once they expire the members are looked up again, failed lookups are
+	// not cached
+	entry := cache.data["org/team"]
+	entry.expires = time.Now()
+	cache.data["org/team"] = entry
+	lookupErr = errors.New("injected error")
+	if _, expires, err = cache.members("org", "team", resolve); err == nil || lookups != 2 {
+		t.Errorf("Expected a failed lookup, got %v after %d lookups.", err, lookups)
+	}
+	if until := time.Until(expires); until <= 0 || until > teamRetryInterval {
+		t.Errorf("Expected a failed lookup to expire after about %v, got %v.", teamRetryInterval, until)
+	}
+	lookupErr = nil
+	if got, _, _ = cache.members("org", "team", resolve); !got.Equal(members) || lookups != 3 {
+		t.Errorf("Expected %v from another lookup, got %v from %d lookups.", members.List(), got.List(), lookups)
+	}
+}
+
+func TestLoadRepoOwnersReloadsTeams(t *testing.T) {
+	testLoadRepoOwnersReloadsTeams(localgit.New, t)
+}
+
+func TestLoadRepoOwnersReloadsTeamsV2(t *testing.T) {
+	testLoadRepoOwnersReloadsTeams(localgit.NewV2, t)
+}
+
+func testLoadRepoOwnersReloadsTeams(clients localgit.Clients, t *testing.T) {
+	files := map[string][]byte{
+		".github/CODEOWNERS": []byte("* @org/reviewers\n"),
+	}
+	client, cleanup, err := getTestClient(files, false, true, false, false, nil, nil, nil, nil, clients)
+	if err != nil {
+		t.Fatalf("Error creating test client: %v.", err)
+	}
+	defer cleanup()
+	ghc := client.ghc.(*fakeGitHubClient)
+	key := "org/repo:master"
+	expire := func() {
+		entry := client.cache.data[key]
+		entry.teamsExpire = time.Now()
+		client.cache.data[key] = entry
+		for team, member := range client.teams.data {
+			member.expires = time.Now()
+			client.teams.data[team] = member
+		}
+	}
+	check := func(expected sets.String) {
+		t.Helper()
+		r, err := client.LoadRepoOwners("org", "repo", "master")
+		if err != nil {
+			t.Fatalf("Unexpected error loading RepoOwners: %v.", err)
+		}
+		if got := r.Approvers("main.go"); !got.Equal(expected) {
+			t.Errorf("Expected approvers %v, got %v.", expected.List(), got.List())
+		}
+	}
+
+	check(sets.NewString("alice", "bob"))
+	ghc.TeamMembers = map[string][]string{"org/reviewers": {"carl"}}
+	check(sets.NewString("alice", "bob"))
+	expire()
+	check(sets.NewString("carl"))
+
+	// a team that cannot be resolved is looked up again
+	ghc.TeamMembers = nil
+	expire()
+	check(sets.NewString())
+	if client.cache.data[key].teamsExpire.IsZero() {
+		t.Error("Expected the owners to be reloaded after a failed team lookup.")
+	}
+	ghc.TeamMembers = map[string][]string{"org/reviewers": {"maggie"}}
+	expire()
+	check(sets.NewString("maggie"))
+}
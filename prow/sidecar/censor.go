@@ -0,0 +1,287 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultMaxArtifactSize is the size of the biggest text artifact that
+	// is censored unless the censoring options say otherwise.
+	defaultMaxArtifactSize = 100 * 1024 * 1024
+	// censorBufferSize is how much data is censored at a time.
+	censorBufferSize = 64 * 1024
+	// sniffSize is how much of an artifact is inspected to tell whether it
+	// holds text. Like git, we consider anything with a NUL byte binary.
+	sniffSize = 8000
+)
+
+// censor loads the secrets to censor and censors them from the artifacts in
+// place. It returns a reader that censors them from the build log.
+func (o Options) censor(buildLog io.Reader) (io.Reader, error) {
+	if len(o.SecretDirectories) == 0 {
+		return buildLog, nil
+	}
+	secrets, err := loadSecrets(o.SecretDirectories)
+	if err != nil {
+		return nil, fmt.Errorf("could not load secrets: %v", err)
+	}
+	if len(secrets) == 0 {
+		return buildLog, nil
+	}
+	logrus.Infof("Censoring %d secret values.", len(secrets))
+	if err := o.censorArtifacts(secrets); err != nil {
+		return nil, fmt.Errorf("could not censor artifacts: %v", err)
+	}
+	return censoringReader(buildLog, secrets), nil
+}
+
+// loadSecrets reads the values of all files in the directories. Every value
+// is censored as is, without surrounding whitespace and base64 encoded. The
+// secrets are returned longest first.
+func loadSecrets(dirs []string) ([][]byte, error) {
+	values := map[string]struct{}{}
+	add := func(value []byte) {
+		if len(bytes.TrimSpace(value)) == 0 {
+			return
+		}
+		values[string(value)] = struct{}{}
+		values[base64.StdEncoding.EncodeToString(value)] = struct{}{}
+	}
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// Secret volumes hold their files as symlinks into a
+			// hidden directory, so we resolve them.
+			if info, err = os.Stat(path); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			value, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			add(value)
+			add(bytes.TrimSpace(value))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not read secrets from %s: %v", dir, err)
+		}
+	}
+
+	secrets := make([][]byte, 0, len(values))
+	for value := range values {
+		secrets = append(secrets, []byte(value))
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		if len(secrets[i]) != len(secrets[j]) {
+			return len(secrets[i]) > len(secrets[j])
+		}
+		return bytes.Compare(secrets[i], secrets[j]) < 0
+	})
+	return secrets, nil
+}
+
+// markSecrets marks every byte of data that is part of an occurrence of one
+// of the secrets. Occurrences are found in data as it is, so secrets which
+// overlap or contain one another are all marked in full.
+func markSecrets(data []byte, marked []bool, secrets [][]byte) {
+	for _, secret := range secrets {
+		for start := 0; start < len(data); {
+			i := bytes.Index(data[start:], secret)
+			if i < 0 {
+				break
+			}
+			i += start
+			for j := i; j < i+len(secret); j++ {
+				marked[j] = true
+			}
+			start = i + 1
+		}
+	}
+}
+
+// censorStream copies src to dst, censoring the secrets on the way. Data is
+// only written once no secret can start in it without being marked, and the
+// data held back is kept as it was read so that secrets reaching into the
+// next read are still found.
+func censorStream(dst io.Writer, src io.Reader, secrets [][]byte) error {
+	var overlap int
+	for _, secret := range secrets {
+		if len(secret)-1 > overlap {
+			overlap = len(secret) - 1
+		}
+	}
+	buf := make([]byte, censorBufferSize+overlap)
+	marked := make([]bool, len(buf))
+	out := make([]byte, len(buf))
+	var filled int
+	for {
+		n, err := io.ReadFull(src, buf[filled:])
+		filled += n
+		done := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !done {
+			return err
+		}
+		markSecrets(buf[:filled], marked[:filled], secrets)
+		// Every secret starting before the overlap ends within the
+		// buffer, so it has been marked already.
+		ready := filled - overlap
+		if done {
+			ready = filled
+		}
+		for i := 0; i < ready; i++ {
+			out[i] = buf[i]
+			if marked[i] {
+				out[i] = '*'
+			}
+		}
+		if _, err := dst.Write(out[:ready]); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		filled = copy(buf, buf[ready:filled])
+		copy(marked, marked[ready:ready+filled])
+		for i := filled; i < len(marked); i++ {
+			marked[i] = false
+		}
+	}
+}
+
+// censoringReader returns a reader of src with the secrets censored.
+func censoringReader(src io.Reader, secrets [][]byte) io.Reader {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(censorStream(writer, src, secrets))
+	}()
+	return reader
+}
+
+// censorArtifacts censors the secrets from the text artifacts in place.
+// Artifacts matching an exclusion are left alone. Text artifacts that are
+// too big to be censored are removed, so that they are not uploaded.
+func (o Options) censorArtifacts(secrets [][]byte) error {
+	maxSize := int64(defaultMaxArtifactSize)
+	var exclude []string
+	if o.CensoringOptions != nil {
+		if o.CensoringOptions.MaxArtifactSize != nil {
+			maxSize = *o.CensoringOptions.MaxArtifactSize
+		}
+		exclude = o.CensoringOptions.ExcludeArtifacts
+	}
+
+	for _, item := range o.GcsOptions.Items {
+		info, err := os.Stat(item)
+		if err != nil {
+			// The upload will warn about this.
+			continue
+		}
+		root := item
+		if !info.IsDir() {
+			root = filepath.Dir(item)
+		}
+		err = filepath.Walk(item, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			relPath, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			for _, pattern := range exclude {
+				if matched, _ := filepath.Match(pattern, relPath); matched {
+					logrus.Infof("Not censoring excluded artifact %s.", relPath)
+					return nil
+				}
+			}
+			return censorArtifact(path, secrets, maxSize)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// censorArtifact censors a single text artifact by writing a censored copy
+// next to it and moving the copy over it. This also replaces symlinks, so
+// that their target is not uploaded uncensored.
+func censorArtifact(path string, secrets [][]byte, maxSize int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		// Dangling symlinks are not uploaded.
+		return nil
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("could not read %s: %v", path, err)
+	}
+	if bytes.IndexByte(head[:n], 0) >= 0 {
+		return nil
+	}
+	if info.Size() > maxSize {
+		logrus.Warnf("Not uploading %s: its size of %d bytes exceeds the %d bytes that can be censored.", path, info.Size(), maxSize)
+		return os.Remove(path)
+	}
+
+	censored, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".censoring")
+	if err != nil {
+		return fmt.Errorf("could not create censored copy of %s: %v", path, err)
+	}
+	defer os.Remove(censored.Name())
+	if err := censorStream(censored, io.MultiReader(bytes.NewReader(head[:n]), file), secrets); err != nil {
+		censored.Close()
+		return fmt.Errorf("could not censor %s: %v", path, err)
+	}
+	if err := censored.Close(); err != nil {
+		return fmt.Errorf("could not write censored copy of %s: %v", path, err)
+	}
+	if err := os.Chmod(censored.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("could not set mode of censored copy of %s: %v", path, err)
+	}
+	return os.Rename(censored.Name(), path)
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/gcsupload"
+)
+
+func TestLoadSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	// Mimic the layout of a secret volume.
+	data := filepath.Join(dir, "..data")
+	if err := os.Mkdir(data, 0755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+	for name, value := range map[string]string{"token": "hunter2\n", "empty": " \n", "other": "hunter2"} {
+		if err := ioutil.WriteFile(filepath.Join(data, name), []byte(value), 0644); err != nil {
+			t.Fatalf("failed to write secret: %v", err)
+		}
+		if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+			t.Fatalf("failed to link secret: %v", err)
+		}
+	}
+
+	secrets, err := loadSecrets([]string{dir})
+	if err != nil {
+		t.Fatalf("failed to load secrets: %v", err)
+	}
+	var actual []string
+	for _, secret := range secrets {
+		actual = append(actual, string(secret))
+	}
+	expected := []string{"aHVudGVyMg==", "aHVudGVyMgo=", "hunter2\n", "hunter2"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected secrets %q, got %q", expected, actual)
+	}
+
+	if _, err := loadSecrets([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestCensorStream(t *testing.T) {
+	secrets := [][]byte{[]byte("secret-with-suffix"), []byte("secret"), []byte("with")}
+	padding := strings.Repeat("x", censorBufferSize-3)
+	// the long secret starts in the data held back after the first read,
+	// while the short secrets it contains end within the first read
+	heldBack := strings.Repeat("x", censorBufferSize+5)
+	var testCases = []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "nothing to censor",
+			input:    "hello world",
+			expected: "hello world",
+		},
+		{
+			name:     "every occurrence is censored",
+			input:    "secret and secretsecret",
+			expected: "****** and ************",
+		},
+		{
+			name:     "longer secrets are censored as a whole",
+			input:    "a secret-with-suffix",
+			expected: "a ******************",
+		},
+		{
+			name:     "secret across the buffer boundary is censored",
+			input:    padding + "secret-with-suffix" + padding,
+			expected: padding + "******************" + padding,
+		},
+		{
+			name:     "secret containing others across the buffer boundary is censored",
+			input:    heldBack + "secret-with-suffix" + padding,
+			expected: heldBack + "******************" + padding,
+		},
+		{
+			name:     "partial secret at the end is kept",
+			input:    padding + "secre",
+			expected: padding + "secre",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual bytes.Buffer
+			if err := censorStream(&actual, strings.NewReader(tc.input), secrets); err != nil {
+				t.Fatalf("failed to censor: %v", err)
+			}
+			if actual.String() != tc.expected {
+				t.Errorf("expected %d bytes %.40q, got %d bytes %.40q", len(tc.expected), tc.expected, actual.Len(), actual.String())
+			}
+		})
+	}
+
+	censored, err := ioutil.ReadAll(censoringReader(strings.NewReader("my secret"), secrets))
+	if err != nil {
+		t.Fatalf("failed to read censored log: %v", err)
+	}
+	if string(censored) != "my ******" {
+		t.Errorf("expected censored log, got %q", string(censored))
+	}
+}
+
+func TestCensorArtifacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	artifacts := map[string]string{
+		"build.log":          "using secret",
+		"nested/junit.xml":   "<failure>secret</failure>",
+		"binary":             "secret\x00",
+		"excluded/notes.txt": "secret",
+		"big.txt":            strings.Repeat("x", 100),
+	}
+	for name, content := range artifacts {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write artifact: %v", err)
+		}
+	}
+	outside := filepath.Join(dir, "..", filepath.Base(dir)+"-outside")
+	if err := ioutil.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	defer os.Remove(outside)
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	maxSize := int64(50)
+	o := Options{
+		GcsOptions: &gcsupload.Options{Items: []string{dir}},
+		CensoringOptions: &prowapi.CensoringOptions{
+			MaxArtifactSize:  &maxSize,
+			ExcludeArtifacts: []string{"excluded/*"},
+		},
+	}
+	if err := o.censorArtifacts([][]byte{[]byte("secret")}); err != nil {
+		t.Fatalf("failed to censor artifacts: %v", err)
+	}
+
+	expected := map[string]string{
+		"build.log":          "using ******",
+		"nested/junit.xml":   "<failure>******</failure>",
+		"binary":             "secret\x00",
+		"excluded/notes.txt": "secret",
+		"link":               "******",
+	}
+	actual := map[string]string{}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(dir, path)
+		actual[relPath] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read artifacts: %v", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected artifacts %q, got %q", expected, actual)
+	}
+	if content, err := ioutil.ReadFile(outside); err != nil || string(content) != "secret" {
+		t.Errorf("expected the symlink target to be left alone, got %q: %v", string(content), err)
+	}
+}
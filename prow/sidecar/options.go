@@ -22,6 +22,7 @@ import (
 	"flag"
 	"fmt"
 
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/gcsupload"
 	"k8s.io/test-infra/prow/pod-utils/wrapper"
 )
@@ -46,6 +47,13 @@ type Options struct {
 
 	// EntryError requires all entries to pass in order to exit cleanly.
 	EntryError bool `json:"entry_error,omitempty"`
+
+	// SecretDirectories are the directories holding the secrets mounted
+	// into the test container. Their values are censored from the build
+	// log and the artifacts before they are uploaded.
+	SecretDirectories []string `json:"secret_directories,omitempty"`
+	// CensoringOptions configures how secrets are censored.
+	CensoringOptions *prowapi.CensoringOptions `json:"censoring_options,omitempty"`
 }
 
 func (o Options) entries() []wrapper.Options {
@@ -102,7 +102,27 @@ func (o Options) Run(ctx context.Context) (int, error) {
 
 	buildLog := logReader(entries)
 	metadata := combineMetadata(entries)
-	return failures, o.doUpload(spec, passed, aborted, metadata, buildLog)
+	censoredLog, err := o.censor(buildLog)
+	if err != nil {
+		err = fmt.Errorf("failed to censor secrets: %v", err)
+		logrus.WithError(err).Error("Withholding the build log and artifacts.")
+		o, censoredLog = o.withhold(err)
+		if uploadErr := o.doUpload(spec, passed, aborted, metadata, censoredLog); uploadErr != nil {
+			logrus.WithError(uploadErr).Error("Failed to upload finished.json.")
+		}
+		return failures, err
+	}
+	return failures, o.doUpload(spec, passed, aborted, metadata, censoredLog)
+}
+
+// withhold returns options that upload no artifacts and a build log that only
+// holds the reason for withholding the real one. The job still has to report
+// that it finished even when its output cannot be uploaded safely.
+func (o Options) withhold(reason error) (Options, io.Reader) {
+	gcsOptions := *o.GcsOptions
+	gcsOptions.Items = nil
+	o.GcsOptions = &gcsOptions
+	return o, strings.NewReader(fmt.Sprintf("The build log and artifacts were withheld: %v\n", reason))
 }
 
 const errorKey = "sidecar-errors"
@@ -29,6 +29,7 @@ import (
 	"testing"
 
 	"k8s.io/test-infra/prow/entrypoint"
+	"k8s.io/test-infra/prow/gcsupload"
 	"k8s.io/test-infra/prow/pod-utils/wrapper"
 
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -326,3 +327,21 @@ func TestLogReader(t *testing.T) {
 	}
 
 }
+
+func TestWithhold(t *testing.T) {
+	o := Options{GcsOptions: &gcsupload.Options{Items: []string{"/logs/artifacts"}}}
+	withheld, log := o.withhold(errors.New("no secrets"))
+	if len(withheld.GcsOptions.Items) != 0 {
+		t.Errorf("expected no artifacts to be uploaded, got %v", withheld.GcsOptions.Items)
+	}
+	if len(o.GcsOptions.Items) != 1 {
+		t.Errorf("expected the original options to be left alone, got %v", o.GcsOptions.Items)
+	}
+	buf, err := ioutil.ReadAll(log)
+	if err != nil {
+		t.Fatalf("failed to read the log: %v", err)
+	}
+	if expected := "The build log and artifacts were withheld: no secrets\n"; string(buf) != expected {
+		t.Errorf("expected log %q, got %q", expected, string(buf))
+	}
+}
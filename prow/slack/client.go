@@ -44,6 +44,7 @@ type Client struct {
 
 const (
 	chatPostMessage = "https://slack.com/api/chat.postMessage"
+	chatUpdate      = "https://slack.com/api/chat.update"
 
 	botName      = "prow"
 	botIconEmoji = ":prow:"
@@ -83,28 +84,38 @@ func (sl *Client) urlValues() *url.Values {
 	return &uv
 }
 
-func (sl *Client) postMessage(url string, uv *url.Values) error {
+// MessageRef identifies a message posted to Slack.
+type MessageRef struct {
+	// Channel is the ID of the channel of the message.
+	Channel string
+	// Timestamp is the ID of the message in the channel.
+	Timestamp string
+}
+
+func (sl *Client) postMessage(url string, uv *url.Values) (*MessageRef, error) {
 	resp, err := http.PostForm(url, *uv)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	body, _ := ioutil.ReadAll(resp.Body)
 	apiResponse := struct {
-		Ok    bool   `json:"ok"`
-		Error string `json:"error"`
+		Ok        bool   `json:"ok"`
+		Error     string `json:"error"`
+		Channel   string `json:"channel"`
+		Timestamp string `json:"ts"`
 	}{}
 
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return fmt.Errorf("API returned invalid JSON (%q): %v", string(body), err)
+		return nil, fmt.Errorf("API returned invalid JSON (%q): %v", string(body), err)
 	}
 
 	if resp.StatusCode != 200 || !apiResponse.Ok {
-		return fmt.Errorf("request failed: %v", apiResponse.Error)
+		return nil, fmt.Errorf("request failed: %v", apiResponse.Error)
 	}
 
-	return nil
+	return &MessageRef{Channel: apiResponse.Channel, Timestamp: apiResponse.Timestamp}, nil
 }
 
 // WriteMessage adds text to channel
@@ -118,5 +129,40 @@ func (sl *Client) WriteMessage(text, channel string) error {
 	uv.Add("channel", channel)
 	uv.Add("text", text)
 
+	_, err := sl.postMessage(chatPostMessage, uv)
+	return err
+}
+
+// PostMessage adds text to channel, as a reply in the thread of the message
+// with the threadTimestamp if it is set, and returns the posted message.
+func (sl *Client) PostMessage(channel, text, threadTimestamp string) (*MessageRef, error) {
+	sl.log("PostMessage", channel, text, threadTimestamp)
+	if sl.fake {
+		return &MessageRef{Channel: channel, Timestamp: "fake"}, nil
+	}
+
+	var uv = sl.urlValues()
+	uv.Add("channel", channel)
+	uv.Add("text", text)
+	if threadTimestamp != "" {
+		uv.Add("thread_ts", threadTimestamp)
+	}
+
 	return sl.postMessage(chatPostMessage, uv)
 }
+
+// UpdateMessage replaces the text of a message posted by the client.
+func (sl *Client) UpdateMessage(message MessageRef, text string) error {
+	sl.log("UpdateMessage", message.Channel, message.Timestamp, text)
+	if sl.fake {
+		return nil
+	}
+
+	var uv = sl.urlValues()
+	uv.Add("channel", message.Channel)
+	uv.Add("ts", message.Timestamp)
+	uv.Add("text", text)
+
+	_, err := sl.postMessage(chatUpdate, uv)
+	return err
+}
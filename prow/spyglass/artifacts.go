@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"k8s.io/test-infra/pkg/io/providers"
 	"k8s.io/test-infra/prow/spyglass/lenses"
 )
 
@@ -35,6 +36,7 @@ func (s *Spyglass) ListArtifacts(src string) ([]string, error) {
 	switch keyType {
 	case gcsKeyType:
 		gcsKey = key
+	case s3KeyType:
 	case prowKeyType:
 		if gcsKey, err = s.prowToGCS(key); err != nil {
 			logrus.Warningf("Failed to get gcs source for prow job: %v", err)
@@ -43,7 +45,12 @@ func (s *Spyglass) ListArtifacts(src string) ([]string, error) {
 		return nil, fmt.Errorf("Unrecognized key type for src: %v", src)
 	}
 
-	artifactNames, err := s.GCSArtifactFetcher.artifacts(gcsKey)
+	var artifactNames []string
+	if keyType == s3KeyType {
+		artifactNames, err = s.StorageArtifactFetcher.artifacts(providers.S3, key)
+	} else {
+		artifactNames, err = s.GCSArtifactFetcher.artifacts(gcsKey)
+	}
 	logFound := false
 	for _, name := range artifactNames {
 		if name == "build-log.txt" {
@@ -106,6 +113,7 @@ func (s *Spyglass) FetchArtifacts(src string, podName string, sizeLimit int64, a
 	switch keyType {
 	case gcsKeyType:
 		gcsKey = strings.TrimSuffix(key, "/")
+	case s3KeyType:
 	case prowKeyType:
 		if gcsKey, err = s.prowToGCS(key); err != nil {
 			logrus.Warningln(err)
@@ -116,7 +124,13 @@ func (s *Spyglass) FetchArtifacts(src string, podName string, sizeLimit int64, a
 
 	podLogNeeded := false
 	for _, name := range artifactNames {
-		art, err := s.GCSArtifactFetcher.artifact(gcsKey, name, sizeLimit)
+		var art lenses.Artifact
+		var err error
+		if keyType == s3KeyType {
+			art, err = s.StorageArtifactFetcher.artifact(providers.S3, key, name, sizeLimit)
+		} else {
+			art, err = s.GCSArtifactFetcher.artifact(gcsKey, name, sizeLimit)
+		}
 		if err == nil {
 			// Actually try making a request, because calling GCSArtifactFetcher.artifact does no I/O.
 			// (these files are being explicitly requested and so will presumably soon be accessed, so
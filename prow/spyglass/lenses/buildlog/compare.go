@@ -0,0 +1,365 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	// maxAlignEdits bounds the work spent aligning two logs. Logs that need more
+	// edits than this are shown as entirely replaced instead.
+	maxAlignEdits = 2000
+)
+
+// Kinds of rows in a build log comparison.
+const (
+	sameLine    = "same"
+	removedLine = "removed"
+	addedLine   = "added"
+	changedLine = "changed"
+)
+
+// normalizers replace the parts of a log line that differ on every run, so
+// that otherwise identical lines are aligned with each other.
+var normalizers = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	// RFC 3339 and similar date-times, e.g. 2020-01-02T15:04:05.999Z
+	{regexp.MustCompile(`\d{4}-\d\d-\d\d[T ]\d\d:\d\d:\d\d(\.\d+)?(Z|[+-]\d\d:?\d\d)?`), "<timestamp>"},
+	// glog headers, e.g. I0102 15:04:05.999999   12345
+	{regexp.MustCompile(`^[IWEF]\d{4} \d\d:\d\d:\d\d\.\d+\s+\d+`), "<timestamp>"},
+	// bare times of day, e.g. 15:04:05
+	{regexp.MustCompile(`\b\d\d:\d\d:\d\d(\.\d+)?\b`), "<timestamp>"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<guid>"},
+}
+
+// normalizeLine returns the line with timestamps and GUIDs replaced by placeholders.
+func normalizeLine(line string) string {
+	for _, n := range normalizers {
+		line = n.re.ReplaceAllString(line, n.replacement)
+	}
+	return line
+}
+
+// DiffLine is a single row of a side-by-side build log comparison.
+// A line number of 0 means the row has no line on that side.
+type DiffLine struct {
+	Kind       string
+	Before     string
+	BeforeLine int
+	After      string
+	AfterLine  int
+}
+
+// DiffGroup holds consecutive rows that are either all shown or all collapsed.
+type DiffGroup struct {
+	Skip  bool
+	Lines []DiffLine
+}
+
+// LinesSkipped returns the number of rows collapsed in a diff group.
+func (g DiffGroup) LinesSkipped() int {
+	return len(g.Lines)
+}
+
+// LogComparisonView holds the comparison of a single log file.
+type LogComparisonView struct {
+	ArtifactName string
+	BeforeLink   string
+	AfterLink    string
+	Identical    bool
+	TooDifferent bool
+	Groups       []DiffGroup
+}
+
+// BuildLogsComparisonView holds the comparison of each log file.
+type BuildLogsComparisonView struct {
+	LogViews []LogComparisonView
+}
+
+// Compare returns the <body> content comparing the build logs of two runs.
+func (lens Lens) Compare(before, after []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	view := BuildLogsComparisonView{LogViews: []LogComparisonView{}}
+	for _, name := range artifactNames(before, after) {
+		cv := LogComparisonView{ArtifactName: name}
+		var beforeLines, afterLines []string
+		if a, ok := artifactByName(before, name); ok {
+			cv.BeforeLink = a.CanonicalLink()
+			lines, err := logLinesAll(a)
+			if err != nil {
+				logrus.WithError(err).Info("Error reading log.")
+				continue
+			}
+			beforeLines = lines
+		}
+		if a, ok := artifactByName(after, name); ok {
+			cv.AfterLink = a.CanonicalLink()
+			lines, err := logLinesAll(a)
+			if err != nil {
+				logrus.WithError(err).Info("Error reading log.")
+				continue
+			}
+			afterLines = lines
+		}
+		var rows []DiffLine
+		rows, cv.TooDifferent = diffLines(beforeLines, afterLines)
+		cv.Groups = groupDiffLines(rows)
+		cv.Identical = true
+		for _, row := range rows {
+			if row.Kind != sameLine {
+				cv.Identical = false
+				break
+			}
+		}
+		view.LogViews = append(view.LogViews, cv)
+	}
+	return executeTemplate(resourceDir, "compare", view)
+}
+
+// artifactNames returns the sorted names of the artifacts in either list.
+func artifactNames(before, after []lenses.Artifact) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, a := range append(append([]lenses.Artifact{}, before...), after...) {
+		if !seen[a.JobPath()] {
+			seen[a.JobPath()] = true
+			names = append(names, a.JobPath())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// diffLines aligns two logs on their normalized lines and returns the rows of
+// a side-by-side comparison. Runs of removed lines followed by added lines are
+// paired up as changed lines. The returned bool is true if the logs were too
+// different to align, in which case every line is reported as changed.
+func diffLines(before, after []string) ([]DiffLine, bool) {
+	a := make([]string, len(before))
+	for i, line := range before {
+		a[i] = normalizeLine(line)
+	}
+	b := make([]string, len(after))
+	for i, line := range after {
+		b[i] = normalizeLine(line)
+	}
+	ops, ok := align(a, b)
+
+	var rows []DiffLine
+	var removed, added []int
+	flush := func() {
+		for i := 0; i < len(removed) || i < len(added); i++ {
+			row := DiffLine{Kind: changedLine}
+			if i < len(removed) {
+				row.Before, row.BeforeLine = before[removed[i]], removed[i]+1
+			} else {
+				row.Kind = addedLine
+			}
+			if i < len(added) {
+				row.After, row.AfterLine = after[added[i]], added[i]+1
+			} else {
+				row.Kind = removedLine
+			}
+			rows = append(rows, row)
+		}
+		removed, added = nil, nil
+	}
+	for _, op := range ops {
+		switch {
+		case op.before >= 0 && op.after >= 0:
+			flush()
+			rows = append(rows, DiffLine{
+				Kind:       sameLine,
+				Before:     before[op.before],
+				BeforeLine: op.before + 1,
+				After:      after[op.after],
+				AfterLine:  op.after + 1,
+			})
+		case op.before >= 0:
+			removed = append(removed, op.before)
+		default:
+			added = append(added, op.after)
+		}
+	}
+	flush()
+	return rows, !ok
+}
+
+// groupDiffLines collapses the unchanged rows that are not near any change.
+func groupDiffLines(rows []DiffLine) []DiffGroup {
+	shown := make([]bool, len(rows))
+	for i, row := range rows {
+		if row.Kind == sameLine {
+			continue
+		}
+		for d := -neighborLines; d <= neighborLines; d++ {
+			if i+d >= 0 && i+d < len(rows) {
+				shown[i+d] = true
+			}
+		}
+	}
+
+	// Collapsing only a handful of lines isn't worth the extra click.
+	for start := 0; start < len(rows); {
+		end := start
+		for end < len(rows) && shown[end] == shown[start] {
+			end++
+		}
+		if !shown[start] && end-start < minLinesSkipped {
+			for i := start; i < end; i++ {
+				shown[i] = true
+			}
+		}
+		start = end
+	}
+
+	var groups []DiffGroup
+	for i, row := range rows {
+		skip := !shown[i]
+		if len(groups) == 0 || groups[len(groups)-1].Skip != skip {
+			groups = append(groups, DiffGroup{Skip: skip})
+		}
+		groups[len(groups)-1].Lines = append(groups[len(groups)-1].Lines, row)
+	}
+	return groups
+}
+
+// alignOp is a single step of an alignment: a line present in both logs, a line
+// only in before (after is -1) or a line only in after (before is -1).
+type alignOp struct {
+	before, after int
+}
+
+// align computes a shortest edit script between a and b using Myers' algorithm.
+// If more than maxAlignEdits edits are needed, it gives up and returns every
+// line of a as removed and every line of b as added, along with false.
+func align(a, b []string) ([]alignOp, bool) {
+	// Trim the common prefix and suffix, which is most of the log in the usual case.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []alignOp
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, alignOp{i, i})
+	}
+	middle, ok := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for _, op := range middle {
+		if op.before >= 0 {
+			op.before += prefix
+		}
+		if op.after >= 0 {
+			op.after += prefix
+		}
+		ops = append(ops, op)
+	}
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, alignOp{len(a) - suffix + i, len(b) - suffix + i})
+	}
+	return ops, ok
+}
+
+func myers(a, b []string) ([]alignOp, bool) {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > maxAlignEdits {
+		max = maxAlignEdits
+	}
+	// v[offset+k] is the furthest x reached on diagonal k; trace[d] holds v
+	// for diagonals -d..d as it was before step d.
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m), true
+			}
+		}
+	}
+
+	ops := make([]alignOp, 0, n+m)
+	for i := range a {
+		ops = append(ops, alignOp{i, -1})
+	}
+	for j := range b {
+		ops = append(ops, alignOp{-1, j})
+	}
+	return ops, false
+}
+
+func backtrack(trace [][]int, n, m int) []alignOp {
+	var ops []alignOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, alignOp{x, y})
+		}
+		if prevK == k+1 {
+			ops = append(ops, alignOp{-1, y - 1})
+		} else {
+			ops = append(ops, alignOp{x - 1, -1})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, alignOp{x, y})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
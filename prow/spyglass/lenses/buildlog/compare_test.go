@@ -0,0 +1,231 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{
+			name:     "no variable parts",
+			line:     "Running tests...",
+			expected: "Running tests...",
+		},
+		{
+			name:     "RFC 3339 timestamp",
+			line:     `time="2020-01-02T15:04:05Z" level=info msg="done"`,
+			expected: `time="<timestamp>" level=info msg="done"`,
+		},
+		{
+			name:     "timestamp with fractional seconds and offset",
+			line:     "2020-01-02 15:04:05.123+01:00 starting",
+			expected: "<timestamp> starting",
+		},
+		{
+			name:     "glog header",
+			line:     "E0102 15:04:05.999999   12345 main.go:10] boom",
+			expected: "<timestamp> main.go:10] boom",
+		},
+		{
+			name:     "bare time of day",
+			line:     "[15:04:05] step 3",
+			expected: "[<timestamp>] step 3",
+		},
+		{
+			name:     "GUID",
+			line:     "created pod 3F2504E0-4F89-11D3-9A0C-0305E82C3301 in ns ci-op-1",
+			expected: "created pod <guid> in ns ci-op-1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := normalizeLine(tc.line); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name         string
+		before       []string
+		after        []string
+		expected     []DiffLine
+		tooDifferent bool
+	}{
+		{
+			name:   "identical apart from timestamps",
+			before: []string{"I0102 10:00:00.000001 1 a.go:1] start", "done"},
+			after:  []string{"I0102 11:30:00.000002 7 a.go:1] start", "done"},
+			expected: []DiffLine{
+				{Kind: sameLine, Before: "I0102 10:00:00.000001 1 a.go:1] start", BeforeLine: 1, After: "I0102 11:30:00.000002 7 a.go:1] start", AfterLine: 1},
+				{Kind: sameLine, Before: "done", BeforeLine: 2, After: "done", AfterLine: 2},
+			},
+		},
+		{
+			name:   "line added",
+			before: []string{"a", "c"},
+			after:  []string{"a", "b", "c"},
+			expected: []DiffLine{
+				{Kind: sameLine, Before: "a", BeforeLine: 1, After: "a", AfterLine: 1},
+				{Kind: addedLine, After: "b", AfterLine: 2},
+				{Kind: sameLine, Before: "c", BeforeLine: 2, After: "c", AfterLine: 3},
+			},
+		},
+		{
+			name:   "line removed",
+			before: []string{"a", "b", "c"},
+			after:  []string{"a", "c"},
+			expected: []DiffLine{
+				{Kind: sameLine, Before: "a", BeforeLine: 1, After: "a", AfterLine: 1},
+				{Kind: removedLine, Before: "b", BeforeLine: 2},
+				{Kind: sameLine, Before: "c", BeforeLine: 3, After: "c", AfterLine: 2},
+			},
+		},
+		{
+			name:   "lines changed are paired up",
+			before: []string{"a", "PASS", "ok", "z"},
+			after:  []string{"a", "FAIL", "z"},
+			expected: []DiffLine{
+				{Kind: sameLine, Before: "a", BeforeLine: 1, After: "a", AfterLine: 1},
+				{Kind: changedLine, Before: "PASS", BeforeLine: 2, After: "FAIL", AfterLine: 2},
+				{Kind: removedLine, Before: "ok", BeforeLine: 3},
+				{Kind: sameLine, Before: "z", BeforeLine: 4, After: "z", AfterLine: 3},
+			},
+		},
+		{
+			name:   "interleaved changes",
+			before: []string{"a", "b", "c", "d"},
+			after:  []string{"b", "x", "d", "e"},
+			expected: []DiffLine{
+				{Kind: removedLine, Before: "a", BeforeLine: 1},
+				{Kind: sameLine, Before: "b", BeforeLine: 2, After: "b", AfterLine: 1},
+				{Kind: changedLine, Before: "c", BeforeLine: 3, After: "x", AfterLine: 2},
+				{Kind: sameLine, Before: "d", BeforeLine: 4, After: "d", AfterLine: 3},
+				{Kind: addedLine, After: "e", AfterLine: 4},
+			},
+		},
+		{
+			name:  "log missing before",
+			after: []string{"a"},
+			expected: []DiffLine{
+				{Kind: addedLine, After: "a", AfterLine: 1},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, tooDifferent := diffLines(tc.before, tc.after)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected rows:\n%+v\ngot:\n%+v", tc.expected, actual)
+			}
+			if tooDifferent != tc.tooDifferent {
+				t.Errorf("expected tooDifferent %t, got %t", tc.tooDifferent, tooDifferent)
+			}
+		})
+	}
+}
+
+func TestDiffLinesTooDifferent(t *testing.T) {
+	var before, after []string
+	for i := 0; i < maxAlignEdits; i++ {
+		before = append(before, fmt.Sprintf("before %d", i))
+		after = append(after, fmt.Sprintf("after %d", i))
+	}
+	rows, tooDifferent := diffLines(before, after)
+	if !tooDifferent {
+		t.Error("expected the logs to be too different to align")
+	}
+	if len(rows) != maxAlignEdits {
+		t.Fatalf("expected %d rows, got %d", maxAlignEdits, len(rows))
+	}
+	for _, row := range rows {
+		if row.Kind != changedLine {
+			t.Fatalf("expected every row to be changed, got %+v", row)
+		}
+	}
+}
+
+func TestGroupDiffLines(t *testing.T) {
+	same := DiffLine{Kind: sameLine}
+	changed := DiffLine{Kind: changedLine}
+	rows := func(kinds ...DiffLine) []DiffLine { return kinds }
+	repeat := func(row DiffLine, n int) []DiffLine {
+		var result []DiffLine
+		for i := 0; i < n; i++ {
+			result = append(result, row)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		rows     []DiffLine
+		expected []DiffGroup
+	}{
+		{
+			name:     "no rows",
+			expected: nil,
+		},
+		{
+			name:     "all unchanged",
+			rows:     repeat(same, 20),
+			expected: []DiffGroup{{Skip: true, Lines: repeat(same, 20)}},
+		},
+		{
+			name:     "too few unchanged lines to collapse",
+			rows:     repeat(same, 3),
+			expected: []DiffGroup{{Lines: repeat(same, 3)}},
+		},
+		{
+			name: "change in the middle",
+			rows: append(append(repeat(same, 20), changed), repeat(same, 20)...),
+			expected: []DiffGroup{
+				{Skip: true, Lines: repeat(same, 15)},
+				{Lines: append(append(repeat(same, 5), changed), repeat(same, 5)...)},
+				{Skip: true, Lines: repeat(same, 15)},
+			},
+		},
+		{
+			name: "short unchanged runs are not collapsed",
+			rows: append(rows(changed), append(repeat(same, 13), changed)...),
+			expected: []DiffGroup{
+				{Lines: append(rows(changed), append(repeat(same, 13), changed)...)},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := groupDiffLines(tc.rows)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected groups:\n%+v\ngot:\n%+v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	flakyStatus  testStatus = "Flaky"
+	absentStatus testStatus = "Absent"
+
+	// A test's duration change is only reported if it is at least
+	// minDurationChange and at least minDurationRatio of its previous duration.
+	minDurationChange = 10 * time.Second
+	minDurationRatio  = 0.5
+)
+
+// TestComparison describes a single test in two runs.
+type TestComparison struct {
+	Name         string
+	Before       *JunitResult
+	BeforeStatus testStatus
+	After        *JunitResult
+	AfterStatus  testStatus
+}
+
+// DurationChange returns how much longer the test took in the second run.
+func (tc TestComparison) DurationChange() time.Duration {
+	if tc.Before == nil || tc.After == nil {
+		return 0
+	}
+	return tc.After.Duration() - tc.Before.Duration()
+}
+
+// ComparisonView holds the data needed to render the comparison of two runs' tests.
+type ComparisonView struct {
+	NumTestsBefore  int
+	NumTestsAfter   int
+	NewlyFailing    []TestComparison
+	Fixed           []TestComparison
+	StillFailing    []TestComparison
+	DurationChanges []TestComparison
+}
+
+// Compare renders the <body> comparing the JUnit results of two runs.
+func (lens Lens) Compare(before, after []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	view := compareJvds(lens.getJvd(before), lens.getJvd(after))
+
+	junitTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+		return fmt.Sprintf("Failed to load template file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := junitTemplate.ExecuteTemplate(&buf, "compare", view); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+
+	return buf.String()
+}
+
+type testKey struct {
+	class string
+	name  string
+}
+
+type testOutcome struct {
+	result JunitResult
+	status testStatus
+}
+
+// outcomes indexes the tests of a run by class and name.
+func outcomes(jvd JVD) map[testKey]testOutcome {
+	results := map[testKey]testOutcome{}
+	add := func(tests []TestResult, status testStatus) {
+		for _, test := range tests {
+			first := test.Junit[0]
+			results[testKey{first.ClassName, first.Name}] = testOutcome{result: first, status: status}
+		}
+	}
+	add(jvd.Passed, passedStatus)
+	add(jvd.Skipped, skippedStatus)
+	add(jvd.Flaky, flakyStatus)
+	add(jvd.Failed, failedStatus)
+	return results
+}
+
+func compareJvds(before, after JVD) ComparisonView {
+	view := ComparisonView{
+		NumTestsBefore: before.NumTests,
+		NumTestsAfter:  after.NumTests,
+	}
+	beforeOutcomes := outcomes(before)
+	afterOutcomes := outcomes(after)
+
+	keys := map[testKey]bool{}
+	for k := range beforeOutcomes {
+		keys[k] = true
+	}
+	for k := range afterOutcomes {
+		keys[k] = true
+	}
+	for k := range keys {
+		tc := TestComparison{Name: k.name, BeforeStatus: absentStatus, AfterStatus: absentStatus}
+		if o, ok := beforeOutcomes[k]; ok {
+			result := o.result
+			tc.Before, tc.BeforeStatus = &result, o.status
+		}
+		if o, ok := afterOutcomes[k]; ok {
+			result := o.result
+			tc.After, tc.AfterStatus = &result, o.status
+		}
+
+		switch {
+		case tc.BeforeStatus == failedStatus && tc.AfterStatus == failedStatus:
+			view.StillFailing = append(view.StillFailing, tc)
+		case tc.AfterStatus == failedStatus:
+			view.NewlyFailing = append(view.NewlyFailing, tc)
+		case tc.BeforeStatus == failedStatus && (tc.AfterStatus == passedStatus || tc.AfterStatus == flakyStatus):
+			view.Fixed = append(view.Fixed, tc)
+		}
+
+		if tc.BeforeStatus != skippedStatus && tc.AfterStatus != skippedStatus && significantDurationChange(tc) {
+			view.DurationChanges = append(view.DurationChanges, tc)
+		}
+	}
+
+	byName := func(tests []TestComparison) {
+		sort.Slice(tests, func(i, j int) bool { return tests[i].Name < tests[j].Name })
+	}
+	byName(view.NewlyFailing)
+	byName(view.Fixed)
+	byName(view.StillFailing)
+	sort.Slice(view.DurationChanges, func(i, j int) bool {
+		a, b := abs(view.DurationChanges[i].DurationChange()), abs(view.DurationChanges[j].DurationChange())
+		if a == b {
+			return view.DurationChanges[i].Name < view.DurationChanges[j].Name
+		}
+		return a > b
+	})
+	return view
+}
+
+func significantDurationChange(tc TestComparison) bool {
+	if tc.Before == nil || tc.After == nil {
+		return false
+	}
+	change := abs(tc.DurationChange())
+	return change >= minDurationChange && float64(change) >= minDurationRatio*float64(tc.Before.Duration())
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+func TestCompare(t *testing.T) {
+	junitFile := func(cases string) lenses.Artifact {
+		return &FakeArtifact{
+			path:      "artifacts/junit_01.xml",
+			content:   []byte(`<testsuites><testsuite name="suite">` + cases + `</testsuite></testsuites>`),
+			sizeLimit: 500e6,
+		}
+	}
+	pass := func(name string, seconds string) string {
+		return `<testcase classname="c" name="` + name + `" time="` + seconds + `"></testcase>`
+	}
+	fail := func(name string) string {
+		return `<testcase classname="c" name="` + name + `" time="1"><failure>oops</failure></testcase>`
+	}
+	skip := func(name string) string {
+		return `<testcase classname="c" name="` + name + `" time="0"><skipped/></testcase>`
+	}
+
+	tests := []struct {
+		name                    string
+		before                  []lenses.Artifact
+		after                   []lenses.Artifact
+		expectedNewlyFailing    []string
+		expectedFixed           []string
+		expectedStillFailing    []string
+		expectedDurationChanges []string
+	}{
+		{
+			name:   "no changes",
+			before: []lenses.Artifact{junitFile(pass("a", "1") + fail("b"))},
+			after:  []lenses.Artifact{junitFile(pass("a", "1") + fail("b"))},
+
+			expectedStillFailing: []string{"b"},
+		},
+		{
+			name:   "newly failing and fixed",
+			before: []lenses.Artifact{junitFile(pass("a", "1") + fail("b") + skip("c"))},
+			after:  []lenses.Artifact{junitFile(fail("a") + pass("b", "1") + fail("c") + fail("d"))},
+
+			expectedNewlyFailing: []string{"a", "c", "d"},
+			expectedFixed:        []string{"b"},
+		},
+		{
+			name:  "no results before",
+			after: []lenses.Artifact{junitFile(pass("a", "1") + fail("b"))},
+
+			expectedNewlyFailing: []string{"b"},
+		},
+		{
+			name:   "tests that disappear are not fixed",
+			before: []lenses.Artifact{junitFile(fail("a") + fail("b"))},
+			after:  []lenses.Artifact{junitFile(skip("a"))},
+		},
+		{
+			name:   "duration changes must be large enough",
+			before: []lenses.Artifact{junitFile(pass("small", "1") + pass("relative", "100") + pass("slower", "20") + pass("faster", "60"))},
+			after:  []lenses.Artifact{junitFile(pass("small", "8") + pass("relative", "120") + pass("slower", "40") + pass("faster", "10"))},
+
+			expectedDurationChanges: []string{"faster", "slower"},
+		},
+	}
+
+	names := func(tests []TestComparison) []string {
+		var result []string
+		for _, test := range tests {
+			result = append(result, test.Name)
+		}
+		return result
+	}
+	check := func(t *testing.T, what string, expected, actual []string) {
+		if len(expected) == 0 && len(actual) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected %s tests %v, got %v", what, expected, actual)
+		}
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lens := Lens{}
+			view := compareJvds(lens.getJvd(tc.before), lens.getJvd(tc.after))
+			check(t, "newly failing", tc.expectedNewlyFailing, names(view.NewlyFailing))
+			check(t, "fixed", tc.expectedFixed, names(view.Fixed))
+			check(t, "still failing", tc.expectedStillFailing, names(view.StillFailing))
+			check(t, "duration changes", tc.expectedDurationChanges, names(view.DurationChanges))
+		})
+	}
+}
@@ -70,6 +70,15 @@ type Lens interface {
 	Callback(artifacts []Artifact, resourceDir string, data string, config json.RawMessage) string
 }
 
+// Comparer is implemented by lenses that can also render the difference between two job runs.
+// Lenses that do not implement it are left out of the compare view.
+type Comparer interface {
+	// Compare returns a string that is injected into the rendered lens's <body> in the compare view.
+	// The artifacts in before come from the baseline run and those in after from the run compared to it;
+	// either may be missing artifacts that the other has.
+	Compare(before, after []Artifact, resourceDir string, config json.RawMessage) string
+}
+
 // Artifact represents some output of a prow job
 type Artifact interface {
 	// ReadAt reads len(p) bytes of the artifact at offset off. (unsupported on some compressed files)
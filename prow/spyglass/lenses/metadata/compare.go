@@ -0,0 +1,121 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// MetadataDiff is a single row of the comparison of two runs' metadata.
+type MetadataDiff struct {
+	Key     string
+	Before  string
+	After   string
+	Changed bool
+}
+
+// Compare creates a view comparing the metadata of two runs.
+func (lens Lens) Compare(before, after []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	var buf bytes.Buffer
+	diffs := compareMetadata(lens.getMetadataViewData(before), lens.getMetadataViewData(after))
+
+	metadataTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("Failed to load template: %v", err)
+	}
+
+	if err := metadataTemplate.ExecuteTemplate(&buf, "compare", diffs); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+func result(data MetadataViewData) string {
+	switch {
+	case data.StartTime.IsZero() && !data.Finished:
+		return ""
+	case !data.Finished:
+		return "running"
+	case data.Passed:
+		return "passed"
+	default:
+		return "failed"
+	}
+}
+
+func compareMetadata(before, after MetadataViewData) []MetadataDiff {
+	row := func(key, before, after string) MetadataDiff {
+		return MetadataDiff{Key: key, Before: before, After: after, Changed: before != after}
+	}
+	timestamp := func(data MetadataViewData) string {
+		if data.StartTime.IsZero() {
+			return ""
+		}
+		return data.StartTime.String()
+	}
+	elapsed := func(data MetadataViewData) string {
+		if data.StartTime.IsZero() {
+			return ""
+		}
+		return data.Elapsed.String()
+	}
+	diffs := []MetadataDiff{
+		row("Result", result(before), result(after)),
+		row("Started", timestamp(before), timestamp(after)),
+		row("Elapsed", elapsed(before), elapsed(after)),
+	}
+	if before.Hint != "" || after.Hint != "" {
+		diffs = append(diffs, row("Hint", before.Hint, after.Hint))
+	}
+
+	keys := map[string]bool{}
+	for k := range before.Metadata {
+		keys[k] = true
+	}
+	for k := range after.Metadata {
+		keys[k] = true
+	}
+	var sortedKeys []string
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+	for _, k := range sortedKeys {
+		b, a := metadataString(before.Metadata[k]), metadataString(after.Metadata[k])
+		if b == "" && a == "" {
+			continue
+		}
+		diffs = append(diffs, row(k, b, a))
+	}
+	return diffs
+}
+
+func metadataString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareMetadata(t *testing.T) {
+	start := time.Unix(1577836800, 0)
+	tests := []struct {
+		name     string
+		before   MetadataViewData
+		after    MetadataViewData
+		expected []MetadataDiff
+	}{
+		{
+			name: "passed then failed with changed metadata",
+			before: MetadataViewData{
+				StartTime: start,
+				Finished:  true,
+				Passed:    true,
+				Elapsed:   time.Minute,
+				Metadata:  map[string]interface{}{"node": "node-a", "repo-commit": "abc", "removed": "x"},
+			},
+			after: MetadataViewData{
+				StartTime: start,
+				Finished:  true,
+				Elapsed:   2 * time.Minute,
+				Hint:      "The job may have executed on an unhealthy node.",
+				Metadata:  map[string]interface{}{"node": "node-b", "repo-commit": "abc", "added": "y", "empty": ""},
+			},
+			expected: []MetadataDiff{
+				{Key: "Result", Before: "passed", After: "failed", Changed: true},
+				{Key: "Started", Before: start.String(), After: start.String()},
+				{Key: "Elapsed", Before: "1m0s", After: "2m0s", Changed: true},
+				{Key: "Hint", After: "The job may have executed on an unhealthy node.", Changed: true},
+				{Key: "added", After: "y", Changed: true},
+				{Key: "node", Before: "node-a", After: "node-b", Changed: true},
+				{Key: "removed", Before: "x", Changed: true},
+				{Key: "repo-commit", Before: "abc", After: "abc"},
+			},
+		},
+		{
+			name: "nothing recorded before",
+			after: MetadataViewData{
+				StartTime: start,
+				Elapsed:   time.Second,
+			},
+			expected: []MetadataDiff{
+				{Key: "Result", After: "running", Changed: true},
+				{Key: "Started", After: start.String(), Changed: true},
+				{Key: "Elapsed", After: "1s", Changed: true},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := compareMetadata(tc.before, tc.after)
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("unexpected comparison (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
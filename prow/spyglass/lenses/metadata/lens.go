@@ -79,18 +79,34 @@ func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data
 	return ""
 }
 
+// MetadataViewData holds the data needed to render the metadata of a job run.
+type MetadataViewData struct {
+	StartTime    time.Time
+	FinishedTime time.Time
+	Finished     bool
+	Passed       bool
+	Elapsed      time.Duration
+	Hint         string
+	Metadata     map[string]interface{}
+}
+
 // Body creates a view for prow job metadata.
 func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
 	var buf bytes.Buffer
-	type MetadataViewData struct {
-		StartTime    time.Time
-		FinishedTime time.Time
-		Finished     bool
-		Passed       bool
-		Elapsed      time.Duration
-		Hint         string
-		Metadata     map[string]interface{}
+	metadataViewData := lens.getMetadataViewData(artifacts)
+
+	metadataTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("Failed to load template: %v", err)
 	}
+
+	if err := metadataTemplate.ExecuteTemplate(&buf, "body", metadataViewData); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+func (lens Lens) getMetadataViewData(artifacts []lenses.Artifact) MetadataViewData {
 	metadataViewData := MetadataViewData{}
 	started := gcs.Started{}
 	finished := gcs.Finished{}
@@ -148,15 +164,7 @@ func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data stri
 		}
 	}
 
-	metadataTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
-	if err != nil {
-		return fmt.Sprintf("Failed to load template: %v", err)
-	}
-
-	if err := metadataTemplate.ExecuteTemplate(&buf, "body", metadataViewData); err != nil {
-		logrus.WithError(err).Error("Error executing template.")
-	}
-	return buf.String()
+	return metadataViewData
 }
 
 var failedMountRegex = regexp.MustCompile(`MountVolume.SetUp failed for volume "(.+?)" : (.+)`)
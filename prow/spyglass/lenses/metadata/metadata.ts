@@ -16,7 +16,12 @@ function handleClick(e: MouseEvent): void {
 }
 
 function getLocalStartTime(): void {
-  document.getElementById('show-table-link')!.onclick = handleClick;
+  const link = document.getElementById('show-table-link');
+  // The compare view has no summary to localise.
+  if (!link) {
+    return;
+  }
+  link.onclick = handleClick;
   const elem = document.getElementById("summary-start-time")!;
   elem.innerText = moment(elem.innerText, DATE_FORMAT).calendar().replace(/Last|Yesterday|Today|Tomorrow/,
       (m) => m.charAt(0).toLowerCase() + m.substr(1));
@@ -32,6 +32,8 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/GoogleCloudPlatform/testgrid/metadata"
+	pkgio "k8s.io/test-infra/pkg/io"
+	"k8s.io/test-infra/pkg/io/providers"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/deck/jobs"
@@ -42,6 +44,7 @@ import (
 // Key types specify the way Spyglass will fetch artifact handles
 const (
 	gcsKeyType  = "gcs"
+	s3KeyType   = providers.S3
 	prowKeyType = "prowjob"
 )
 
@@ -59,6 +62,7 @@ type Spyglass struct {
 	testgrid *TestGrid
 
 	*GCSArtifactFetcher
+	*StorageArtifactFetcher
 	*PodLogArtifactFetcher
 }
 
@@ -67,6 +71,10 @@ type LensRequest struct {
 	Source    string   `json:"src"`
 	Index     int      `json:"index"`
 	Artifacts []string `json:"artifacts"`
+	// CompareSource and CompareArtifacts identify the run that Source is
+	// compared to, and are only set by the compare view.
+	CompareSource    string   `json:"compareSrc,omitempty"`
+	CompareArtifacts []string `json:"compareArtifacts,omitempty"`
 }
 
 // ExtraLink represents an extra link to be added to the Spyglass page.
@@ -76,13 +84,15 @@ type ExtraLink struct {
 	URL         string
 }
 
-// New constructs a Spyglass object from a JobAgent, a config.Agent, and a storage Client.
-func New(ctx context.Context, ja *jobs.JobAgent, cfg config.Getter, c *storage.Client, gcsCredsFile string, useCookieAuth bool) *Spyglass {
+// New constructs a Spyglass object from a JobAgent, a config.Agent, an opener for
+// storage other than GCS, and a storage Client.
+func New(ctx context.Context, ja *jobs.JobAgent, cfg config.Getter, opener pkgio.Opener, c *storage.Client, gcsCredsFile string, useCookieAuth bool) *Spyglass {
 	return &Spyglass{
-		JobAgent:              ja,
-		config:                cfg,
-		PodLogArtifactFetcher: NewPodLogArtifactFetcher(ja),
-		GCSArtifactFetcher:    NewGCSArtifactFetcher(c, gcsCredsFile, useCookieAuth),
+		JobAgent:               ja,
+		config:                 cfg,
+		PodLogArtifactFetcher:  NewPodLogArtifactFetcher(ja),
+		GCSArtifactFetcher:     NewGCSArtifactFetcher(c, gcsCredsFile, useCookieAuth),
+		StorageArtifactFetcher: NewStorageArtifactFetcher(opener),
 		testgrid: &TestGrid{
 			conf:   cfg,
 			client: c,
@@ -141,8 +151,8 @@ func (sg *Spyglass) ResolveSymlink(src string) (string, error) {
 		return "", fmt.Errorf("error parsing src: %v", src)
 	}
 	switch keyType {
-	case prowKeyType:
-		return src, nil // prowjob keys cannot be symlinks.
+	case prowKeyType, s3KeyType:
+		return src, nil // only GCS keys can be symlinks.
 	case gcsKeyType:
 		parts := strings.SplitN(key, "/", 2)
 		if len(parts) != 2 {
@@ -187,7 +197,7 @@ func (sg *Spyglass) JobPath(src string) (string, error) {
 	}
 	split := strings.Split(key, "/")
 	switch keyType {
-	case gcsKeyType:
+	case gcsKeyType, s3KeyType:
 		if len(split) < 4 {
 			return "", fmt.Errorf("invalid key %s: expected <bucket-name>/<log-type>/.../<job-name>/<build-id>", key)
 		}
@@ -195,12 +205,19 @@ func (sg *Spyglass) JobPath(src string) (string, error) {
 		bktName := split[0]
 		logType := split[1]
 		jobName := split[len(split)-2]
+		var jobPath string
 		if logType == gcs.NonPRLogs {
-			return path.Dir(key), nil
+			jobPath = path.Dir(key)
 		} else if logType == gcs.PRLogs {
-			return path.Join(bktName, gcs.PRLogs, "directory", jobName), nil
+			jobPath = path.Join(bktName, gcs.PRLogs, "directory", jobName)
+		} else {
+			return "", fmt.Errorf("unrecognized %s key: %s", keyType, key)
+		}
+		// Job history only prefixes buckets of storage providers other than GCS
+		if keyType == s3KeyType {
+			jobPath = path.Join(providers.S3, jobPath)
 		}
-		return "", fmt.Errorf("unrecognized GCS key: %s", key)
+		return jobPath, nil
 	case prowKeyType:
 		if len(split) < 2 {
 			return "", fmt.Errorf("invalid key %s: expected <job-name>/<build-id>", key)
@@ -217,7 +234,14 @@ func (sg *Spyglass) JobPath(src string) (string, error) {
 		if job.Spec.DecorationConfig.GCSConfiguration == nil {
 			return "", fmt.Errorf("failed to locate GCS upload bucket for %s: missing GCS configuration", jobName)
 		}
-		bktName := job.Spec.DecorationConfig.GCSConfiguration.Bucket
+		storageProvider, bktName, err := providers.ParseBucket(job.Spec.DecorationConfig.GCSConfiguration.Bucket)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse upload bucket for %s: %v", jobName, err)
+		}
+		// Job history only prefixes buckets of storage providers other than GCS
+		if storageProvider != providers.GS {
+			bktName = path.Join(storageProvider, bktName)
+		}
 		if job.Spec.Type == prowapi.PresubmitJob {
 			return path.Join(bktName, gcs.PRLogs, "directory", jobName), nil
 		}
@@ -239,7 +263,7 @@ func (sg *Spyglass) ProwJobName(src string) (string, error) {
 	var jobName string
 	var buildID string
 	switch keyType {
-	case gcsKeyType:
+	case gcsKeyType, s3KeyType:
 		if len(split) < 4 {
 			return "", fmt.Errorf("invalid key %s: expected <bucket-name>/<log-type>/.../<job-name>/<build-id>", key)
 		}
@@ -276,6 +300,8 @@ func (sg *Spyglass) RunPath(src string) (string, error) {
 		return key, nil
 	case prowKeyType:
 		return sg.prowToGCS(key)
+	case s3KeyType:
+		return "", fmt.Errorf("%s is not stored in GCS", src)
 	default:
 		return "", fmt.Errorf("unrecognized key type for src: %v", src)
 	}
@@ -294,7 +320,7 @@ func (sg *Spyglass) RunToPR(src string) (string, string, int, error) {
 		return "", "", 0, fmt.Errorf("expected more URL components in %q", src)
 	}
 	switch keyType {
-	case gcsKeyType:
+	case gcsKeyType, s3KeyType:
 		// In theory, we could derive this information without trying to parse the URL by instead fetching the
 		// data from uploaded artifacts. In practice, that would not be a great solution: it would require us
 		// to try pulling two different metadata files (one for bootstrap and one for podutils), then parse them
@@ -243,7 +243,7 @@ func TestViews(t *testing.T) {
 					},
 				},
 			}
-			sg := New(context.Background(), fakeJa, c.Config, fakeGCSClient, "", false)
+			sg := New(context.Background(), fakeJa, c.Config, nil, fakeGCSClient, "", false)
 			_, ls := sg.Lenses(tc.lenses)
 			for _, l := range ls {
 				var found bool
@@ -349,6 +349,21 @@ func TestJobPath(t *testing.T) {
 				BuildID: "2222",
 			},
 		},
+		prowapi.ProwJob{
+			Spec: prowapi.ProwJobSpec{
+				Type: prowapi.PeriodicJob,
+				Job:  "example-s3-periodic-job",
+				DecorationConfig: &prowapi.DecorationConfig{
+					GCSConfiguration: &prowapi.GCSConfiguration{
+						Bucket: "s3://chum-bucket",
+					},
+				},
+			},
+			Status: prowapi.ProwJobStatus{
+				PodName: "flying-whales",
+				BuildID: "3333",
+			},
+		},
 		prowapi.ProwJob{
 			Spec: prowapi.ProwJobSpec{
 				Type: prowapi.PresubmitJob,
@@ -400,6 +415,16 @@ func TestJobPath(t *testing.T) {
 			src:        "gcs/kubernetes-jenkins/pr-logs/pull/test-infra/0000/example-job-name/314159",
 			expJobPath: "kubernetes-jenkins/pr-logs/directory/example-job-name",
 		},
+		{
+			name:       "non-presubmit job in S3",
+			src:        "s3/chum-bucket/logs/example-job-name/123",
+			expJobPath: "s3/chum-bucket/logs/example-job-name",
+		},
+		{
+			name:       "presubmit job in S3",
+			src:        "s3/chum-bucket/pr-logs/pull/test-infra/0000/example-job-name/314159/",
+			expJobPath: "s3/chum-bucket/pr-logs/directory/example-job-name",
+		},
 		{
 			name:       "non-presubmit Prow job",
 			src:        "prowjob/example-periodic-job/1111",
@@ -410,6 +435,11 @@ func TestJobPath(t *testing.T) {
 			src:        "prowjob/example-presubmit-job/2222",
 			expJobPath: "chum-bucket/pr-logs/directory/example-presubmit-job",
 		},
+		{
+			name:       "Prow job in S3",
+			src:        "prowjob/example-s3-periodic-job/3333",
+			expJobPath: "s3/chum-bucket/logs/example-s3-periodic-job",
+		},
 		{
 			name:     "nonexistent job",
 			src:      "prowjob/example-periodic-job/0000",
@@ -439,7 +469,7 @@ func TestJobPath(t *testing.T) {
 	for _, tc := range testCases {
 		fakeGCSClient := fakeGCSServer.Client()
 		fca := config.Agent{}
-		sg := New(context.Background(), fakeJa, fca.Config, fakeGCSClient, "", false)
+		sg := New(context.Background(), fakeJa, fca.Config, nil, fakeGCSClient, "", false)
 		jobPath, err := sg.JobPath(tc.src)
 		if tc.expError && err == nil {
 			t.Errorf("test %q: JobPath(%q) expected error", tc.name, tc.src)
@@ -565,7 +595,7 @@ func TestProwJobName(t *testing.T) {
 	for _, tc := range testCases {
 		fakeGCSClient := fakeGCSServer.Client()
 		fca := config.Agent{}
-		sg := New(context.Background(), fakeJa, fca.Config, fakeGCSClient, "", false)
+		sg := New(context.Background(), fakeJa, fca.Config, nil, fakeGCSClient, "", false)
 		jobPath, err := sg.ProwJobName(tc.src)
 		if tc.expError && err == nil {
 			t.Errorf("test %q: JobPath(%q) expected error", tc.name, tc.src)
@@ -659,6 +689,11 @@ func TestRunPath(t *testing.T) {
 			src:        "prowjob/example-periodic-job/1111",
 			expRunPath: "chum-bucket/logs/example-periodic-job/1111",
 		},
+		{
+			name:     "job in S3 is not in GCS",
+			src:      "s3/chum-bucket/logs/example-job-name/123",
+			expError: true,
+		},
 		{
 			name:       "Prow presubmit job with full path",
 			src:        "prowjob/example-presubmit-job/2222",
@@ -690,7 +725,7 @@ func TestRunPath(t *testing.T) {
 				},
 			},
 		})
-		sg := New(context.Background(), fakeJa, fca.Config, fakeGCSClient, "", false)
+		sg := New(context.Background(), fakeJa, fca.Config, nil, fakeGCSClient, "", false)
 		jobPath, err := sg.RunPath(tc.src)
 		if tc.expError && err == nil {
 			t.Errorf("test %q: RunPath(%q) expected error, got  %q", tc.name, tc.src, jobPath)
@@ -850,7 +885,7 @@ func TestRunToPR(t *testing.T) {
 				},
 			},
 		})
-		sg := New(context.Background(), fakeJa, fca.Config, fakeGCSClient, "", false)
+		sg := New(context.Background(), fakeJa, fca.Config, nil, fakeGCSClient, "", false)
 		org, repo, num, err := sg.RunToPR(tc.src)
 		if tc.expError && err == nil {
 			t.Errorf("test %q: RunToPR(%q) expected error", tc.name, tc.src)
@@ -937,7 +972,7 @@ func TestProwToGCS(t *testing.T) {
 		}
 		fakeJa = jobs.NewJobAgent(kc, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")}, fakeConfigAgent.Config)
 		fakeJa.Start()
-		sg := New(context.Background(), fakeJa, fakeConfigAgent.Config, fakeGCSClient, "", false)
+		sg := New(context.Background(), fakeJa, fakeConfigAgent.Config, nil, fakeGCSClient, "", false)
 
 		p, err := sg.prowToGCS(tc.key)
 		if err != nil && !tc.expectError {
@@ -1072,7 +1107,7 @@ func TestGCSPathRoundTrip(t *testing.T) {
 
 		fakeGCSClient := fakeGCSServer.Client()
 
-		sg := New(context.Background(), fakeJa, fakeConfigAgent.Config, fakeGCSClient, "", false)
+		sg := New(context.Background(), fakeJa, fakeConfigAgent.Config, nil, fakeGCSClient, "", false)
 		gcspath, _, _ := gcsupload.PathsForJob(
 			&prowapi.GCSConfiguration{Bucket: "test-bucket", PathStrategy: tc.pathStrategy},
 			&downwardapi.JobSpec{
@@ -1184,7 +1219,7 @@ func TestTestGridLink(t *testing.T) {
 				},
 			},
 		})
-		sg := New(context.Background(), fakeJa, fca.Config, fakeGCSClient, "", false)
+		sg := New(context.Background(), fakeJa, fca.Config, nil, fakeGCSClient, "", false)
 		sg.testgrid = &tg
 		link, err := sg.TestGridLink(tc.src)
 		if tc.expError {
@@ -1231,7 +1266,7 @@ func TestFetchArtifactsPodLog(t *testing.T) {
 
 	fakeGCSClient := fakeGCSServer.Client()
 
-	sg := New(context.Background(), fakeJa, fakeConfigAgent.Config, fakeGCSClient, "", false)
+	sg := New(context.Background(), fakeJa, fakeConfigAgent.Config, nil, fakeGCSClient, "", false)
 	testKeys := []string{
 		"prowjob/job/123",
 		"gcs/kubernetes-jenkins/logs/job/123/",
@@ -1389,7 +1424,7 @@ func TestResolveSymlink(t *testing.T) {
 
 		fakeGCSClient := fakeGCSServer.Client()
 
-		sg := New(context.Background(), fakeJa, fakeConfigAgent.Config, fakeGCSClient, "", false)
+		sg := New(context.Background(), fakeJa, fakeConfigAgent.Config, nil, fakeGCSClient, "", false)
 
 		result, err := sg.ResolveSymlink(tc.path)
 		if err != nil {
@@ -1481,7 +1516,7 @@ func TestExtraLinks(t *testing.T) {
 			fakeConfigAgent := fca{}
 			fakeJa = jobs.NewJobAgent(fkc{}, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA")}, fakeConfigAgent.Config)
 			fakeJa.Start()
-			sg := New(context.Background(), fakeJa, fakeConfigAgent.Config, gcsClient, "", false)
+			sg := New(context.Background(), fakeJa, fakeConfigAgent.Config, nil, gcsClient, "", false)
 
 			result, err := sg.ExtraLinks("gcs/test-bucket/logs/some-job/42")
 			if err != nil {
@@ -0,0 +1,133 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/sirupsen/logrus"
+
+	pkgio "k8s.io/test-infra/pkg/io"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// StorageArtifactFetcher fetches artifacts through an io.Opener, which
+// supports storage providers other than GCS, e.g. S3.
+type StorageArtifactFetcher struct {
+	opener pkgio.Opener
+}
+
+// NewStorageArtifactFetcher creates a new ArtifactFetcher reading through the opener
+func NewStorageArtifactFetcher(opener pkgio.Opener) *StorageArtifactFetcher {
+	return &StorageArtifactFetcher{
+		opener: opener,
+	}
+}
+
+// runPath returns the full path of the run stored at key, a <bucket>/<path> key
+// of the storage provider, e.g. s3://bucket/logs/job/123/
+func runPath(storageProvider, key string) string {
+	return fmt.Sprintf("%s://%s/", storageProvider, strings.Trim(key, "/"))
+}
+
+// artifacts lists all artifacts available for the run stored at key
+func (af *StorageArtifactFetcher) artifacts(storageProvider, key string) ([]string, error) {
+	if af.opener == nil {
+		return nil, fmt.Errorf("cannot list %s artifacts without an opener", storageProvider)
+	}
+	listStart := time.Now()
+	ctx := context.Background()
+	prefix := runPath(storageProvider, key)
+	it, err := af.opener.Iterator(ctx, prefix, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %v", prefix, err)
+	}
+	artifacts := []string{}
+	for {
+		attrs, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return artifacts, fmt.Errorf("failed to list %s: %v", prefix, err)
+		}
+		artifacts = append(artifacts, strings.TrimPrefix(attrs.Name, prefix))
+	}
+	logrus.WithField("duration", time.Since(listStart).String()).Infof("Listed %d artifacts.", len(artifacts))
+	return artifacts, nil
+}
+
+// artifact constructs an artifact of the run stored at key. Like with GCS, the
+// artifact is constructed even if it does not exist, but all reads will fail.
+func (af *StorageArtifactFetcher) artifact(storageProvider, key, artifactName string, sizeLimit int64) (lenses.Artifact, error) {
+	if af.opener == nil {
+		return nil, fmt.Errorf("cannot fetch %s artifacts without an opener", storageProvider)
+	}
+	p := runPath(storageProvider, key) + artifactName
+	handle := &openerArtifactHandle{opener: af.opener, path: p}
+	return NewGCSArtifact(context.Background(), handle, p, artifactName, sizeLimit), nil
+}
+
+// openerArtifactHandle reads an artifact through an io.Opener
+type openerArtifactHandle struct {
+	opener pkgio.Opener
+	path   string
+}
+
+func (h *openerArtifactHandle) Attrs(ctx context.Context) (*storage.ObjectAttrs, error) {
+	attrs, err := h.opener.Attributes(ctx, h.path)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.ObjectAttrs{
+		Size:            attrs.Size,
+		Updated:         attrs.Updated,
+		ContentType:     attrs.ContentType,
+		ContentEncoding: attrs.ContentEncoding,
+		Metadata:        attrs.Metadata,
+	}, nil
+}
+
+func (h *openerArtifactHandle) NewReader(ctx context.Context) (io.ReadCloser, error) {
+	return h.opener.Reader(ctx, h.path)
+}
+
+// NewRangeReader reads length bytes from offset, or everything after offset
+// if length is negative. The opener cannot read ranges, so it skips to offset.
+func (h *openerArtifactHandle) NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	rc, err := h.opener.Reader(ctx, h.path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(ioutil.Discard, rc, offset); err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("failed to skip to offset %d: %v", offset, err)
+	}
+	if length < 0 {
+		return rc, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{Reader: io.LimitReader(rc, length), Closer: rc}, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	pkgio "k8s.io/test-infra/pkg/io"
+)
+
+func TestStorageArtifactFetcher(t *testing.T) {
+	// mem:// behaves like s3:// buckets but doesn't need credentials
+	opener, err := pkgio.NewOpenerFromGCSClient(nil, "")
+	if err != nil {
+		t.Fatalf("failed to create opener: %v", err)
+	}
+	objects := map[string]string{
+		"logs/example-ci-run/403/build-log.txt":          "Oh wow\nlogs\nthis is\ncrazy",
+		"logs/example-ci-run/403/artifacts/junit_01.xml": "<testsuite/>",
+		"logs/example-ci-run/404/build-log.txt":          "another run",
+	}
+	for name, content := range objects {
+		w, err := opener.Writer(context.Background(), "mem://test-bucket/"+name)
+		if err != nil {
+			t.Fatalf("failed to open writer for %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("failed to close %s: %v", name, err)
+		}
+	}
+	af := NewStorageArtifactFetcher(opener)
+
+	names, err := af.artifacts("mem", "test-bucket/logs/example-ci-run/403/")
+	if err != nil {
+		t.Fatalf("failed to list artifacts: %v", err)
+	}
+	sort.Strings(names)
+	if expected := []string{"artifacts/junit_01.xml", "build-log.txt"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected artifacts %v, got %v", expected, names)
+	}
+
+	artifact, err := af.artifact("mem", "test-bucket/logs/example-ci-run/403", "build-log.txt", 500e6)
+	if err != nil {
+		t.Fatalf("failed to get artifact: %v", err)
+	}
+	if link, expected := artifact.CanonicalLink(), "mem://test-bucket/logs/example-ci-run/403/build-log.txt"; link != expected {
+		t.Errorf("expected link %q, got %q", expected, link)
+	}
+	if size, err := artifact.Size(); err != nil || size != 25 {
+		t.Errorf("expected size 25, got %d (error: %v)", size, err)
+	}
+	content, err := artifact.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read artifact: %v", err)
+	}
+	if expected := objects["logs/example-ci-run/403/build-log.txt"]; string(content) != expected {
+		t.Errorf("expected content %q, got %q", expected, string(content))
+	}
+	p := make([]byte, 4)
+	if _, err := artifact.ReadAt(p, 7); err != nil {
+		t.Fatalf("failed to read artifact at offset: %v", err)
+	}
+	if expected := "logs"; string(p) != expected {
+		t.Errorf("expected to read %q at offset, got %q", expected, string(p))
+	}
+
+	missing, err := af.artifact("mem", "test-bucket/logs/example-ci-run/403", "finished.json", 500e6)
+	if err != nil {
+		t.Fatalf("failed to get artifact: %v", err)
+	}
+	if _, err := missing.Size(); err == nil {
+		t.Error("expected an error getting the size of a missing artifact")
+	}
+}
@@ -18,6 +18,7 @@ package statusreconciler
 
 import (
 	"context"
+	"errors"
 	"io/ioutil"
 	"os"
 	"reflect"
@@ -42,6 +43,18 @@ func (t *testOpener) Writer(ctx context.Context, path string, _ ...io.WriterOpti
 	return os.Create(path)
 }
 
+func (t *testOpener) Iterator(ctx context.Context, prefix, delimiter string) (io.ObjectIterator, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (t *testOpener) Attributes(ctx context.Context, path string) (io.Attributes, error) {
+	return io.Attributes{}, errors.New("not implemented")
+}
+
+func (t *testOpener) Delete(ctx context.Context, path string) error {
+	return errors.New("not implemented")
+}
+
 func TestLoadState(t *testing.T) {
 	config := config.Config{
 		ProwConfig: config.ProwConfig{
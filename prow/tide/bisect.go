@@ -0,0 +1,370 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/tide/history"
+)
+
+// Failed batches are bisected to find the PRs that break them: the batch is
+// split in halves which are tested on their own, the failing halves are
+// split again until single PRs are found to fail. Those are the culprits,
+// they are labeled and told about the failure. The rest of the batch is
+// then tested again and merged.
+//
+// The results of the halves are the results of the batch and presubmit jobs
+// testing exactly their PRs on top of the base SHA, so only the failed batch
+// being bisected is recorded in the history. The bisection ends once the
+// branch moves.
+
+// batchResult is the result of the jobs testing some PRs together.
+type batchResult struct {
+	pulls []prowapi.Pull
+	state simpleState
+	// failedJob is the URL of a failed job.
+	failedJob string
+}
+
+// pullsKey identifies the PRs tested by a job.
+func pullsKey(pulls []prowapi.Pull) string {
+	var keys []string
+	for _, pull := range pulls {
+		keys = append(keys, fmt.Sprintf("%d@%s", pull.Number, pull.SHA))
+	}
+	return strings.Join(keys, ",")
+}
+
+// batchResults returns the results of the required presubmit and batch jobs
+// of the subpool by the PRs they test.
+func batchResults(sp *subpool) map[string]*batchResult {
+	type contextResult struct {
+		state simpleState
+		url   string
+	}
+	contexts := map[string]map[string]contextResult{}
+	results := map[string]*batchResult{}
+	for _, pj := range sp.pjs {
+		if pj.Spec.Type != prowapi.PresubmitJob && pj.Spec.Type != prowapi.BatchJob {
+			continue
+		}
+		if len(pj.Spec.Refs.Pulls) == 0 {
+			continue
+		}
+		if cc, ok := sp.cc[pj.Spec.Refs.Pulls[0].Number]; ok && cc.IsOptional(pj.Spec.Context) {
+			continue
+		}
+		key := pullsKey(pj.Spec.Refs.Pulls)
+		if _, ok := results[key]; !ok {
+			results[key] = &batchResult{pulls: pj.Spec.Refs.Pulls}
+			contexts[key] = map[string]contextResult{}
+		}
+		// Store the best result for this ref+context.
+		state := toSimpleState(pj.Status.State)
+		if r, ok := contexts[key][pj.Spec.Context]; !ok || r.state == failureState || state == successState {
+			contexts[key][pj.Spec.Context] = contextResult{state: state, url: pj.Status.URL}
+		}
+	}
+	for key, result := range results {
+		result.state = successState
+		for _, r := range contexts[key] {
+			if r.state == failureState {
+				result.state = failureState
+				result.failedJob = r.url
+				break
+			}
+			if r.state == pendingState {
+				result.state = pendingState
+			}
+		}
+	}
+	return results
+}
+
+// bisectionStep is what is left to do to bisect a batch.
+type bisectionStep struct {
+	// trigger are the halves that are not tested yet.
+	trigger [][]prowapi.Pull
+	// pending is whether halves are being tested.
+	pending bool
+	// culprits are the PRs found to fail on their own and the URLs of their
+	// failed jobs.
+	culprits   []prowapi.Pull
+	failedJobs map[int]string
+}
+
+// bisect walks the bisection of the pulls, splitting them in halves for as
+// long as they fail.
+func bisect(results map[string]*batchResult, pulls []prowapi.Pull, step *bisectionStep) {
+	result, ok := results[pullsKey(pulls)]
+	if !ok {
+		step.trigger = append(step.trigger, pulls)
+		return
+	}
+	switch result.state {
+	case pendingState:
+		step.pending = true
+	case failureState:
+		if len(pulls) == 1 {
+			step.culprits = append(step.culprits, pulls[0])
+			step.failedJobs[pulls[0].Number] = result.failedJob
+			return
+		}
+		bisect(results, pulls[:len(pulls)/2], step)
+		bisect(results, pulls[len(pulls)/2:], step)
+	}
+}
+
+// failedBatch picks the largest failed batch of PRs that are still in the
+// subpool to bisect. The halves of the batch bisected last are batches of
+// their own, they are not bisected again.
+func failedBatch(sp *subpool, results map[string]*batchResult, last *history.Bisection) *batchResult {
+	var candidates []*batchResult
+	for _, result := range results {
+		if result.state != failureState || len(result.pulls) < 2 {
+			continue
+		}
+		if _, ok := poolPRs(sp, result.pulls); !ok {
+			continue
+		}
+		if last != nil && pullsSubset(result.pulls, last.Batch) {
+			continue
+		}
+		candidates = append(candidates, result)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if len(candidates[i].pulls) != len(candidates[j].pulls) {
+			return len(candidates[i].pulls) > len(candidates[j].pulls)
+		}
+		return pullsKey(candidates[i].pulls) < pullsKey(candidates[j].pulls)
+	})
+	return candidates[0]
+}
+
+// pullsSubset determines whether all of the pulls are part of the batch.
+func pullsSubset(pulls, batch []prowapi.Pull) bool {
+	for _, pull := range pulls {
+		if !hasPull(batch, pull) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasPull(pulls []prowapi.Pull, pull prowapi.Pull) bool {
+	for _, p := range pulls {
+		if p.Number == pull.Number && p.SHA == pull.SHA {
+			return true
+		}
+	}
+	return false
+}
+
+// poolPRs returns the PRs of the subpool for the pulls, if they are all still
+// in the pool at the same head.
+func poolPRs(sp *subpool, pulls []prowapi.Pull) ([]PullRequest, bool) {
+	var prs []PullRequest
+	for _, pull := range pulls {
+		found := false
+		for _, pr := range sp.prs {
+			if int(pr.Number) == pull.Number && string(pr.HeadRefOID) == pull.SHA {
+				prs = append(prs, pr)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return prs, true
+}
+
+// bisectBatch takes the next action of the bisection of a failed batch of the
+// subpool, if there is one. It reports whether it took over the sync and the
+// state of the bisection to record if it changed.
+func (c *Controller) bisectBatch(sp subpool, batchPending, batchMerges []PullRequest) (Action, []PullRequest, *history.Bisection, bool, error) {
+	label := c.config().Tide.CulpritLabel
+	if label == "" || c.History == nil {
+		return Wait, nil, nil, false, nil
+	}
+	results := batchResults(&sp)
+	bisection := c.History.Bisection(poolKey(sp.org, sp.repo, sp.branch), sp.sha)
+	step := bisectionStep{failedJobs: map[int]string{}}
+	if bisection != nil {
+		bisect(results, bisection.Batch, &step)
+	}
+	if bisection == nil || bisectionDone(results, bisection, &step) {
+		// Do not start bisecting while a batch may merge.
+		if len(batchPending) > 0 || len(batchMerges) > 0 {
+			return Wait, nil, nil, false, nil
+		}
+		failed := failedBatch(&sp, results, bisection)
+		if failed == nil {
+			return Wait, nil, nil, false, nil
+		}
+		sp.log.WithField("batch", pullNumbers(failed.pulls)).Info("Bisecting failed batch.")
+		bisection = &history.Bisection{Batch: failed.pulls, FailedJob: failed.failedJob}
+		step = bisectionStep{failedJobs: map[int]string{}}
+		bisect(results, bisection.Batch, &step)
+	}
+	log := sp.log.WithField("batch", pullNumbers(bisection.Batch))
+
+	if len(step.trigger) > 0 {
+		var halves [][]PullRequest
+		for _, pulls := range step.trigger {
+			prs, ok := poolPRs(&sp, pulls)
+			if !ok {
+				log.Info("PRs of the failed batch left the pool or changed, abandoning its bisection.")
+				return Wait, nil, nil, false, nil
+			}
+			halves = append(halves, prs)
+		}
+		var targets []PullRequest
+		for _, prs := range halves {
+			presubmits, err := c.presubmitsForBatch(prs, sp.org, sp.repo, sp.sha, sp.branch)
+			if err != nil {
+				return Bisect, nil, bisection, true, err
+			}
+			if err := c.trigger(sp, presubmits, prs); err != nil {
+				return Bisect, targets, bisection, true, err
+			}
+			targets = append(targets, prs...)
+		}
+		return Bisect, targets, bisection, true, nil
+	}
+	if step.pending {
+		return Wait, nil, nil, true, nil
+	}
+
+	// All halves were tested, tell the culprits.
+	var marked []PullRequest
+	culprits := append([]prowapi.Pull{}, bisection.Culprits...)
+	for _, culprit := range step.culprits {
+		if hasPull(bisection.Culprits, culprit) {
+			continue
+		}
+		if err := c.markCulprit(sp, bisection, culprit, step.failedJobs[culprit.Number], label); err != nil {
+			return Bisect, marked, &history.Bisection{Batch: bisection.Batch, FailedJob: bisection.FailedJob, Culprits: culprits}, true, err
+		}
+		culprits = append(culprits, culprit)
+		marked = append(marked, PullRequest{Number: githubql.Int(culprit.Number), HeadRefOID: githubql.String(culprit.SHA)})
+	}
+	if len(marked) > 0 {
+		log.WithField("culprits", prNumbers(marked)).Info("Found the culprits of the failed batch.")
+		return Bisect, marked, &history.Bisection{Batch: bisection.Batch, FailedJob: bisection.FailedJob, Culprits: culprits}, true, nil
+	}
+
+	// Test the rest of the batch again and merge it.
+	remaining := remainingPulls(bisection)
+	if len(bisection.Culprits) == 0 || len(remaining) == 0 {
+		return Wait, nil, nil, false, nil
+	}
+	prs, ok := poolPRs(&sp, remaining)
+	if !ok {
+		log.Info("PRs of the failed batch left the pool or changed, abandoning its bisection.")
+		return Wait, nil, nil, false, nil
+	}
+	result, ok := results[pullsKey(remaining)]
+	switch {
+	case !ok:
+		presubmits, err := c.presubmitsForBatch(prs, sp.org, sp.repo, sp.sha, sp.branch)
+		if err != nil {
+			return Bisect, nil, bisection, true, err
+		}
+		return Bisect, prs, bisection, true, c.trigger(sp, presubmits, prs)
+	case result.state == pendingState:
+		return Wait, nil, nil, true, nil
+	case result.state == successState:
+		return MergeBatch, prs, nil, true, c.mergePRs(sp, prs)
+	}
+	// The PRs fail together but not on their own, leave them to the usual
+	// serial testing.
+	return Wait, nil, nil, false, nil
+}
+
+// bisectionDone determines whether there is nothing left to do for the
+// bisection, so that another failed batch may be bisected.
+func bisectionDone(results map[string]*batchResult, bisection *history.Bisection, step *bisectionStep) bool {
+	if len(step.trigger) > 0 || step.pending {
+		return false
+	}
+	for _, culprit := range step.culprits {
+		if !hasPull(bisection.Culprits, culprit) {
+			return false
+		}
+	}
+	remaining := remainingPulls(bisection)
+	if len(bisection.Culprits) == 0 || len(remaining) == 0 {
+		return true
+	}
+	result, ok := results[pullsKey(remaining)]
+	return ok && result.state == failureState
+}
+
+// remainingPulls returns the PRs of the bisected batch that are not culprits.
+func remainingPulls(bisection *history.Bisection) []prowapi.Pull {
+	var remaining []prowapi.Pull
+	for _, pull := range bisection.Batch {
+		if !hasPull(bisection.Culprits, pull) {
+			remaining = append(remaining, pull)
+		}
+	}
+	return remaining
+}
+
+// markCulprit labels the culprit of a failed batch and links it to the job
+// that failed when testing it on its own.
+func (c *Controller) markCulprit(sp subpool, bisection *history.Bisection, culprit prowapi.Pull, failedJob, label string) error {
+	log := sp.log.WithFields(logrus.Fields{"pr": culprit.Number, "sha": culprit.SHA})
+	if err := c.ghc.AddLabel(sp.org, sp.repo, culprit.Number, label); err != nil {
+		return fmt.Errorf("failed to label culprit #%d: %v", culprit.Number, err)
+	}
+	var batch []string
+	for _, pull := range bisection.Batch {
+		batch = append(batch, fmt.Sprintf("#%d", pull.Number))
+	}
+	job := "its jobs fail"
+	if failedJob != "" {
+		job = fmt.Sprintf("[a job](%s) fails", failedJob)
+	}
+	comment := fmt.Sprintf("Tide found this PR to break the batch %s by bisecting it, %s when testing it on its own at %s.\n\nThe PR has been labeled `%s`, remove the label once the failure is fixed.",
+		strings.Join(batch, " "), job, culprit.SHA, label)
+	if err := c.ghc.CreateComment(sp.org, sp.repo, culprit.Number, comment); err != nil {
+		return fmt.Errorf("failed to comment on culprit #%d: %v", culprit.Number, err)
+	}
+	log.Info("Marked culprit of failed batch.")
+	return nil
+}
+
+func pullNumbers(pulls []prowapi.Pull) []int {
+	var nums []int
+	for _, pull := range pulls {
+		nums = append(nums, pull.Number)
+	}
+	return nums
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/tide/history"
+)
+
+type bisectJob struct {
+	prs   []int
+	state prowapi.ProwJobState
+}
+
+func bisectPulls(numbers ...int) []prowapi.Pull {
+	var pulls []prowapi.Pull
+	for _, number := range numbers {
+		pulls = append(pulls, prowapi.Pull{Number: number, SHA: fmt.Sprintf("sha-%d", number)})
+	}
+	return pulls
+}
+
+func TestBisectBatch(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+
+	failedBatch := bisectJob{prs: []int{1, 2, 3, 4}, state: prowapi.FailureState}
+	var testCases = []struct {
+		name         string
+		label        string
+		prs          []int
+		changed      int
+		jobs         []bisectJob
+		bisection    *history.Bisection
+		batchPending bool
+
+		expectedBisecting bool
+		expectedAction    Action
+		expectedTriggered []string
+		expectedCulprits  []int
+		expectedMerged    int
+		expectedRecord    *history.Bisection
+	}{
+		{
+			name:              "failed batch is split in halves",
+			label:             "culprit",
+			prs:               []int{1, 2, 3, 4},
+			jobs:              []bisectJob{failedBatch},
+			expectedBisecting: true,
+			expectedAction:    Bisect,
+			expectedTriggered: []string{"[1 2]", "[3 4]"},
+			expectedRecord:    &history.Bisection{Batch: bisectPulls(1, 2, 3, 4), FailedJob: "https://prow/1,2,3,4"},
+		},
+		{
+			name:  "failed batch is not bisected without a culprit label",
+			prs:   []int{1, 2, 3, 4},
+			jobs:  []bisectJob{failedBatch},
+			label: "",
+		},
+		{
+			name:         "failed batch is not bisected while a batch is pending",
+			label:        "culprit",
+			prs:          []int{1, 2, 3, 4},
+			jobs:         []bisectJob{failedBatch},
+			batchPending: true,
+		},
+		{
+			name:  "pending halves are waited for",
+			label: "culprit",
+			prs:   []int{1, 2, 3, 4},
+			jobs: []bisectJob{
+				failedBatch,
+				{prs: []int{1, 2}, state: prowapi.PendingState},
+				{prs: []int{3, 4}, state: prowapi.SuccessState},
+			},
+			bisection:         &history.Bisection{Batch: bisectPulls(1, 2, 3, 4)},
+			expectedBisecting: true,
+			expectedAction:    Wait,
+		},
+		{
+			name:  "failed half is split again",
+			label: "culprit",
+			prs:   []int{1, 2, 3, 4},
+			jobs: []bisectJob{
+				failedBatch,
+				{prs: []int{1, 2}, state: prowapi.FailureState},
+				{prs: []int{3, 4}, state: prowapi.SuccessState},
+			},
+			bisection:         &history.Bisection{Batch: bisectPulls(1, 2, 3, 4)},
+			expectedBisecting: true,
+			expectedAction:    Bisect,
+			expectedTriggered: []string{"[1]", "[2]"},
+			expectedRecord:    &history.Bisection{Batch: bisectPulls(1, 2, 3, 4)},
+		},
+		{
+			name:  "failing PR is marked as culprit",
+			label: "culprit",
+			prs:   []int{1, 2, 3, 4},
+			jobs: []bisectJob{
+				failedBatch,
+				{prs: []int{1, 2}, state: prowapi.FailureState},
+				{prs: []int{3, 4}, state: prowapi.SuccessState},
+				{prs: []int{1}, state: prowapi.SuccessState},
+				{prs: []int{2}, state: prowapi.FailureState},
+			},
+			bisection:         &history.Bisection{Batch: bisectPulls(1, 2, 3, 4)},
+			expectedBisecting: true,
+			expectedAction:    Bisect,
+			expectedCulprits:  []int{2},
+			expectedRecord:    &history.Bisection{Batch: bisectPulls(1, 2, 3, 4), Culprits: bisectPulls(2)},
+		},
+		{
+			name:  "rest of the batch is tested again",
+			label: "culprit",
+			prs:   []int{1, 3, 4},
+			jobs: []bisectJob{
+				failedBatch,
+				{prs: []int{1, 2}, state: prowapi.FailureState},
+				{prs: []int{3, 4}, state: prowapi.SuccessState},
+				{prs: []int{1}, state: prowapi.SuccessState},
+				{prs: []int{2}, state: prowapi.FailureState},
+			},
+			bisection:         &history.Bisection{Batch: bisectPulls(1, 2, 3, 4), Culprits: bisectPulls(2)},
+			expectedBisecting: true,
+			expectedAction:    Bisect,
+			expectedTriggered: []string{"[1 3 4]"},
+			expectedRecord:    &history.Bisection{Batch: bisectPulls(1, 2, 3, 4), Culprits: bisectPulls(2)},
+		},
+		{
+			name:  "rest of the batch is merged",
+			label: "culprit",
+			prs:   []int{1, 3, 4},
+			jobs: []bisectJob{
+				failedBatch,
+				{prs: []int{1, 2}, state: prowapi.FailureState},
+				{prs: []int{3, 4}, state: prowapi.SuccessState},
+				{prs: []int{1}, state: prowapi.SuccessState},
+				{prs: []int{2}, state: prowapi.FailureState},
+				{prs: []int{1, 3, 4}, state: prowapi.SuccessState},
+			},
+			bisection:         &history.Bisection{Batch: bisectPulls(1, 2, 3, 4), Culprits: bisectPulls(2)},
+			expectedBisecting: true,
+			expectedAction:    MergeBatch,
+			expectedMerged:    3,
+		},
+		{
+			name:  "rest of the batch failing again ends the bisection",
+			label: "culprit",
+			prs:   []int{1, 3, 4},
+			jobs: []bisectJob{
+				failedBatch,
+				{prs: []int{1, 2}, state: prowapi.FailureState},
+				{prs: []int{3, 4}, state: prowapi.SuccessState},
+				{prs: []int{1}, state: prowapi.SuccessState},
+				{prs: []int{2}, state: prowapi.FailureState},
+				{prs: []int{1, 3, 4}, state: prowapi.FailureState},
+			},
+			bisection: &history.Bisection{Batch: bisectPulls(1, 2, 3, 4), Culprits: bisectPulls(2)},
+		},
+		{
+			name:  "bisection without culprits ends",
+			label: "culprit",
+			prs:   []int{1, 2, 3, 4},
+			jobs: []bisectJob{
+				failedBatch,
+				{prs: []int{1, 2}, state: prowapi.SuccessState},
+				{prs: []int{3, 4}, state: prowapi.SuccessState},
+			},
+			bisection: &history.Bisection{Batch: bisectPulls(1, 2, 3, 4)},
+		},
+		{
+			name:      "bisection is abandoned when a PR changed",
+			label:     "culprit",
+			prs:       []int{1, 2, 3, 4},
+			changed:   3,
+			jobs:      []bisectJob{failedBatch},
+			bisection: &history.Bisection{Batch: bisectPulls(1, 2, 3, 4)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{
+				JobConfig: config.JobConfig{
+					PresubmitsStatic: map[string][]config.Presubmit{
+						"o/r": {{AlwaysRun: true, Reporter: config.Reporter{Context: "foo"}}},
+					},
+				},
+				ProwConfig: config.ProwConfig{
+					ProwJobNamespace: "default",
+					Tide:             config.Tide{CulpritLabel: tc.label},
+				},
+			}
+			hist, err := history.New(100, nil, "")
+			if err != nil {
+				t.Fatalf("failed to create history client: %v", err)
+			}
+			if tc.bisection != nil {
+				hist.RecordBisection(poolKey("o", "r", "master"), string(Bisect), "base", "", nil, tc.bisection)
+			}
+			ghc := &fgc{}
+			mgr := newFakeManager()
+			log := logrus.WithField("test", tc.name)
+			c := &Controller{
+				ctx:           context.Background(),
+				logger:        log,
+				config:        func() *config.Config { return cfg },
+				ghc:           ghc,
+				prowJobClient: mgr.GetClient(),
+				changedFiles:  &changedFilesAgent{},
+				History:       hist,
+			}
+
+			sp := subpool{org: "o", repo: "r", branch: "master", sha: "base", log: log}
+			for _, number := range tc.prs {
+				sha := fmt.Sprintf("sha-%d", number)
+				if number == tc.changed {
+					sha = "changed"
+				}
+				sp.prs = append(sp.prs, PullRequest{Number: githubql.Int(number), HeadRefOID: githubql.String(sha)})
+			}
+			for _, job := range tc.jobs {
+				jobType := prowapi.BatchJob
+				if len(job.prs) == 1 {
+					jobType = prowapi.PresubmitJob
+				}
+				var numbers []string
+				for _, number := range job.prs {
+					numbers = append(numbers, fmt.Sprint(number))
+				}
+				sp.pjs = append(sp.pjs, prowapi.ProwJob{
+					Spec: prowapi.ProwJobSpec{
+						Type:    jobType,
+						Context: "foo",
+						Refs:    &prowapi.Refs{Org: "o", Repo: "r", BaseRef: "master", BaseSHA: "base", Pulls: bisectPulls(job.prs...)},
+					},
+					Status: prowapi.ProwJobStatus{State: job.state, URL: "https://prow/" + strings.Join(numbers, ",")},
+				})
+			}
+			var batchPending []PullRequest
+			if tc.batchPending {
+				batchPending = sp.prs
+			}
+
+			action, _, record, bisecting, err := c.bisectBatch(sp, batchPending, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bisecting != tc.expectedBisecting {
+				t.Fatalf("expected bisecting %t, got %t", tc.expectedBisecting, bisecting)
+			}
+			if bisecting && action != tc.expectedAction {
+				t.Errorf("expected action %s, got %s", tc.expectedAction, action)
+			}
+			if !reflect.DeepEqual(record, tc.expectedRecord) {
+				t.Errorf("expected to record bisection %+v, got %+v", tc.expectedRecord, record)
+			}
+
+			pjs := &prowapi.ProwJobList{}
+			if err := mgr.GetClient().List(context.Background(), pjs); err != nil {
+				t.Fatalf("failed to list prowjobs: %v", err)
+			}
+			var triggered []string
+			for _, pj := range pjs.Items {
+				triggered = append(triggered, fmt.Sprint(pullNumbers(pj.Spec.Refs.Pulls)))
+			}
+			sort.Strings(triggered)
+			if !reflect.DeepEqual(triggered, tc.expectedTriggered) {
+				t.Errorf("expected %v to be tested, got %v", tc.expectedTriggered, triggered)
+			}
+
+			var culprits []int
+			for number, labels := range ghc.labels {
+				if !reflect.DeepEqual(labels, []string{"culprit"}) {
+					t.Errorf("expected #%d to be labeled culprit, got %v", number, labels)
+				}
+				if len(ghc.comments[number]) != 1 || !strings.Contains(ghc.comments[number][0], fmt.Sprintf("https://prow/%d", number)) {
+					t.Errorf("expected #%d to be told about its failed job, got %v", number, ghc.comments[number])
+				}
+				culprits = append(culprits, number)
+			}
+			if !reflect.DeepEqual(culprits, tc.expectedCulprits) {
+				t.Errorf("expected culprits %v, got %v", tc.expectedCulprits, culprits)
+			}
+			if ghc.merged != tc.expectedMerged {
+				t.Errorf("expected %d PRs to be merged, got %d", tc.expectedMerged, ghc.merged)
+			}
+		})
+	}
+}
@@ -0,0 +1,426 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"k8s.io/test-infra/pkg/io"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gerrit/adapter"
+	"k8s.io/test-infra/prow/gerrit/client"
+	"k8s.io/test-infra/prow/pjutil"
+	"k8s.io/test-infra/prow/tide/history"
+)
+
+type gerritClient interface {
+	SearchChanges(instance, query string, rateLimit int) ([]client.ChangeInfo, error)
+	GetBranchRevision(instance, project, branch string) (string, error)
+	SubmitChange(instance, id string) (*client.ChangeInfo, error)
+}
+
+// GerritController submits the Gerrit changes that the tide.gerrit queries
+// select once their required presubmits passed against the tip of their
+// branch. Changes are tested and submitted one at a time per branch.
+type GerritController struct {
+	ctx           context.Context
+	logger        *logrus.Entry
+	config        config.Getter
+	gc            gerritClient
+	prowJobClient ctrlruntimeclient.Client
+	dryRun        bool
+
+	m     sync.Mutex
+	pools []Pool
+
+	History *history.History
+}
+
+// NewGerritController makes a GerritController out of the given clients.
+// In dry-run mode it triggers jobs but does not submit changes.
+func NewGerritController(gc *client.Client, mgr manager, cfg config.Getter, maxRecordsPerPool int, opener io.Opener, historyURI string, dryRun bool, logger *logrus.Entry) (*GerritController, error) {
+	if logger == nil {
+		logger = logrus.NewEntry(logrus.StandardLogger())
+	}
+	hist, err := history.New(maxRecordsPerPool, opener, historyURI)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing history client from %q: %v", historyURI, err)
+	}
+	return newGerritController(logger, gc, mgr, cfg, hist, dryRun)
+}
+
+func newGerritController(logger *logrus.Entry, gc gerritClient, mgr manager, cfg config.Getter, hist *history.History, dryRun bool) (*GerritController, error) {
+	if err := mgr.GetFieldIndexer().IndexField(
+		&prowapi.ProwJob{},
+		cacheIndexName,
+		cacheIndexFunc,
+	); err != nil {
+		return nil, fmt.Errorf("failed to add baseSHA index to cache: %v", err)
+	}
+	return &GerritController{
+		ctx:           context.Background(),
+		logger:        logger.WithField("controller", "gerrit-sync"),
+		config:        cfg,
+		gc:            gc,
+		prowJobClient: mgr.GetClient(),
+		dryRun:        dryRun,
+		History:       hist,
+	}, nil
+}
+
+// Shutdown flushes the action history.
+func (c *GerritController) Shutdown() {
+	c.History.Flush()
+}
+
+// ServeHTTP serves the pools of the last sync like Controller.ServeHTTP.
+func (c *GerritController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	servePools(w, c.logger, c.pools)
+}
+
+// gerritSubpool is a subpool of the changes on a branch of a Gerrit project.
+type gerritSubpool struct {
+	subpool
+	instance string
+	cloneURI *url.URL
+	changes  map[int]client.ChangeInfo
+}
+
+// Sync submits or tests the changes of every subpool.
+func (c *GerritController) Sync() error {
+	start := time.Now()
+	defer func() {
+		duration := time.Since(start)
+		c.logger.WithField("duration", duration.String()).Info("Synced")
+		tideMetrics.syncDuration.Set(duration.Seconds())
+		tideMetrics.syncHeartbeat.WithLabelValues("gerrit-sync").Inc()
+	}()
+
+	cfg := c.config()
+	sps := map[string]*gerritSubpool{}
+	for _, query := range cfg.Tide.Gerrit.Queries {
+		for _, project := range query.Projects {
+			q := query.Query(project)
+			changes, err := c.gc.SearchChanges(query.Instance, q, cfg.Gerrit.RateLimit)
+			if err != nil {
+				// Don't let one project hold back the others.
+				c.logger.WithError(err).WithField("query", q).Error("Failed to query changes.")
+				continue
+			}
+			for _, change := range changes {
+				if err := c.addChange(sps, query.Instance, change); err != nil {
+					c.logger.WithError(err).WithField("change", change.Number).Error("Failed to add change to its subpool.")
+				}
+			}
+		}
+	}
+
+	pools := make([]Pool, 0, len(sps))
+	for _, sp := range sps {
+		if err := c.initSubpoolData(sp); err != nil {
+			sp.log.WithError(err).Error("Error initializing subpool.")
+			continue
+		}
+		if len(sp.prs) == 0 {
+			continue
+		}
+		pool, err := c.syncSubpool(sp)
+		if err != nil {
+			tideMetrics.poolErrors.WithLabelValues(sp.org, sp.repo, sp.branch).Inc()
+			sp.log.WithError(err).Error("Error syncing subpool.")
+		}
+		pools = append(pools, pool)
+	}
+	sortPools(pools)
+	c.m.Lock()
+	c.pools = pools
+	c.m.Unlock()
+
+	c.History.Flush()
+	return nil
+}
+
+// addChange adds the change to the subpool of its branch, creating the
+// subpool and looking up the tip of the branch first if necessary.
+func (c *GerritController) addChange(sps map[string]*gerritSubpool, instance string, change client.ChangeInfo) error {
+	cloneURI, err := adapter.MakeCloneURI(instance, change.Project)
+	if err != nil {
+		return fmt.Errorf("failed to create clone uri: %v", err)
+	}
+	key := poolKey(cloneURI.Host, change.Project, change.Branch)
+	if sps[key] == nil {
+		sha, err := c.gc.GetBranchRevision(instance, change.Project, change.Branch)
+		if err != nil {
+			return fmt.Errorf("failed to get SHA from base branch: %v", err)
+		}
+		sps[key] = &gerritSubpool{
+			subpool: subpool{
+				log: c.logger.WithFields(logrus.Fields{
+					"org":      cloneURI.Host,
+					"repo":     change.Project,
+					"branch":   change.Branch,
+					"base-sha": sha,
+				}),
+				org:    cloneURI.Host,
+				repo:   change.Project,
+				branch: change.Branch,
+				sha:    sha,
+			},
+			instance: instance,
+			cloneURI: cloneURI,
+			changes:  map[int]client.ChangeInfo{},
+		}
+	}
+	sp := sps[key]
+	sp.prs = append(sp.prs, gerritPullRequest(sp.org, change))
+	sp.changes[change.Number] = change
+	return nil
+}
+
+// initSubpoolData looks up the jobs tested against the tip of the branch and
+// the required presubmits of the changes, and drops the changes that failed
+// them from the subpool. Like PRs with failing contexts on GitHub, those wait
+// for their author to push a fix or ask for a retest.
+func (c *GerritController) initSubpoolData(sp *gerritSubpool) error {
+	cfg := c.config()
+	pjs := &prowapi.ProwJobList{}
+	if err := c.prowJobClient.List(
+		c.ctx,
+		pjs,
+		ctrlruntimeclient.MatchingField(cacheIndexName, cacheIndexKey(sp.org, sp.repo, sp.branch, sp.sha)),
+		ctrlruntimeclient.InNamespace(cfg.ProwJobNamespace),
+	); err != nil {
+		return fmt.Errorf("failed to list jobs: %v", err)
+	}
+	sp.pjs = pjs.Items
+
+	presubmits := adapter.Presubmits(cfg, sp.cloneURI)
+
+	sp.presubmits = map[int][]config.Presubmit{}
+	var prs []PullRequest
+	for _, pr := range sp.prs {
+		change := sp.changes[int(pr.Number)]
+		required, err := requiredPresubmits(presubmits, func(ps config.Presubmit) (bool, error) {
+			return ps.ShouldRun(sp.branch, changedFiles(change), false, false)
+		})
+		if err != nil {
+			return err
+		}
+		failed, err := c.failedPresubmits(change, required)
+		if err != nil {
+			return err
+		}
+		if failed.Len() > 0 {
+			sp.log.WithFields(pr.logFields()).WithField("failed", failed.List()).Debug("Filtering out change with failed presubmits.")
+			continue
+		}
+		sp.presubmits[int(pr.Number)] = required
+		prs = append(prs, pr)
+	}
+	sp.prs = prs
+	return nil
+}
+
+// failedPresubmits returns the contexts of the presubmits whose latest run on
+// the current revision of the change failed, no matter what it was tested
+// against.
+func (c *GerritController) failedPresubmits(change client.ChangeInfo, presubmits []config.Presubmit) (sets.String, error) {
+	pjs := &prowapi.ProwJobList{}
+	if err := c.prowJobClient.List(
+		c.ctx,
+		pjs,
+		ctrlruntimeclient.MatchingLabels{client.GerritRevision: change.CurrentRevision},
+		ctrlruntimeclient.InNamespace(c.config().ProwJobNamespace),
+	); err != nil {
+		return nil, fmt.Errorf("failed to list jobs of change %d: %v", change.Number, err)
+	}
+	latest := map[string]prowapi.ProwJob{}
+	for _, pj := range pjs.Items {
+		if pj.Spec.Type != prowapi.PresubmitJob {
+			continue
+		}
+		if prev, ok := latest[pj.Spec.Context]; !ok || prev.CreationTimestamp.Before(&pj.CreationTimestamp) {
+			latest[pj.Spec.Context] = pj
+		}
+	}
+	failed := sets.NewString()
+	for _, ps := range presubmits {
+		if pj, ok := latest[ps.Context]; ok && toSimpleState(pj.Status.State) == failureState {
+			failed.Insert(ps.Context)
+		}
+	}
+	return failed, nil
+}
+
+func (c *GerritController) syncSubpool(sp *gerritSubpool) (Pool, error) {
+	sp.log.Infof("Syncing subpool: %d changes, %d PJs.", len(sp.prs), len(sp.pjs))
+	successes, pendings, missings, missingTests := accumulate(sp.presubmits, sp.prs, sp.pjs, sp.log)
+	sp.log.WithFields(logrus.Fields{
+		"changes-passing": prNumbers(successes),
+		"changes-pending": prNumbers(pendings),
+		"changes-missing": prNumbers(missings),
+	}).Info("Subpool accumulated.")
+
+	act, targets, err := c.takeAction(sp, successes, pendings, missings, missingTests)
+	var errorString string
+	if err != nil {
+		errorString = err.Error()
+	}
+	if recordableActions[act] {
+		c.History.Record(
+			poolKey(sp.org, sp.repo, sp.branch),
+			string(act),
+			sp.sha,
+			errorString,
+			prMeta(targets...),
+		)
+	}
+
+	sp.log.WithFields(logrus.Fields{
+		"action":  string(act),
+		"targets": prNumbers(targets),
+	}).Info("Subpool synced.")
+	tideMetrics.pooledPRs.WithLabelValues(sp.org, sp.repo, sp.branch).Set(float64(len(sp.prs)))
+	tideMetrics.updateTime.WithLabelValues(sp.org, sp.repo, sp.branch).Set(float64(time.Now().Unix()))
+	return Pool{
+			Org:    sp.org,
+			Repo:   sp.repo,
+			Branch: sp.branch,
+
+			SuccessPRs: successes,
+			PendingPRs: pendings,
+			MissingPRs: missings,
+
+			Action: act,
+			Target: targets,
+			Error:  errorString,
+
+			GerritInstance: sp.instance,
+		},
+		err
+}
+
+// takeAction submits the passing change with the smallest number or, if no
+// change is passing or being tested, tests the missing change with the
+// smallest number. As submitting moves the tip of the branch, the other
+// changes are tested again afterwards.
+func (c *GerritController) takeAction(sp *gerritSubpool, successes, pendings, missings []PullRequest, missingTests map[int][]config.Presubmit) (Action, []PullRequest, error) {
+	if len(successes) > 0 {
+		pr := smallestNumber(successes)
+		return Merge, []PullRequest{pr}, c.submit(sp, pr)
+	}
+	if len(missings) > 0 && len(pendings) == 0 {
+		pr := smallestNumber(missings)
+		return Trigger, []PullRequest{pr}, c.trigger(sp, missingTests[int(pr.Number)], pr)
+	}
+	return Wait, nil, nil
+}
+
+func smallestNumber(prs []PullRequest) PullRequest {
+	smallest := prs[0]
+	for _, pr := range prs[1:] {
+		if pr.Number < smallest.Number {
+			smallest = pr
+		}
+	}
+	return smallest
+}
+
+func (c *GerritController) submit(sp *gerritSubpool, pr PullRequest) error {
+	log := sp.log.WithFields(pr.logFields())
+	if c.dryRun {
+		log.Info("Not submitting change in dry-run mode.")
+		return nil
+	}
+	change := sp.changes[int(pr.Number)]
+	if _, err := c.gc.SubmitChange(sp.instance, change.ID); err != nil {
+		log.WithError(err).Error("Submit failed.")
+		return fmt.Errorf("failed submitting change %d: %v", change.Number, err)
+	}
+	log.Info("Submitted.")
+	tideMetrics.merges.WithLabelValues(sp.org, sp.repo, sp.branch).Observe(1)
+	return nil
+}
+
+func (c *GerritController) trigger(sp *gerritSubpool, presubmits []config.Presubmit, pr PullRequest) error {
+	change := sp.changes[int(pr.Number)]
+	refs, err := adapter.CreateRefs(sp.instance, change, sp.cloneURI, sp.sha)
+	if err != nil {
+		return fmt.Errorf("failed to get refs: %v", err)
+	}
+
+	// If multiple required jobs have the same context, we assume the
+	// same shard will be run to provide those contexts
+	triggeredContexts := sets.NewString()
+	for _, ps := range presubmits {
+		if triggeredContexts.Has(ps.Context) {
+			continue
+		}
+		triggeredContexts.Insert(ps.Context)
+		pj := adapter.NewProwJob(sp.instance, change, pjutil.PresubmitSpec(ps, refs), ps.Labels)
+		pj.Namespace = c.config().ProwJobNamespace
+		log := sp.log.WithFields(pjutil.ProwJobFields(&pj))
+		if err := c.prowJobClient.Create(c.ctx, &pj); err != nil {
+			return fmt.Errorf("failed to create a ProwJob for job: %q, change: %d: %v", pj.Spec.Job, change.Number, err)
+		}
+		log.Debug("Created ProwJob on the cluster.")
+	}
+	return nil
+}
+
+// gerritPullRequest coerces the change to a PullRequest so that it can share
+// the pool logic and dashboard of GitHub PRs.
+func gerritPullRequest(host string, change client.ChangeInfo) PullRequest {
+	var pr PullRequest
+	pr.Number = githubql.Int(change.Number)
+	pr.Author.Login = githubql.String(change.Owner.Username)
+	pr.BaseRef.Name = githubql.String(change.Branch)
+	pr.BaseRef.Prefix = "refs/heads/"
+	pr.HeadRefOID = githubql.String(change.CurrentRevision)
+	pr.Repository.Name = githubql.String(change.Project)
+	pr.Repository.NameWithOwner = githubql.String(host + "/" + change.Project)
+	pr.Repository.Owner.Login = githubql.String(host)
+	pr.Title = githubql.String(change.Subject)
+	pr.UpdatedAt = githubql.DateTime{Time: change.Updated.Time}
+	return pr
+}
+
+// changedFiles lists the files changed by the current revision of the change.
+func changedFiles(change client.ChangeInfo) config.ChangedFilesProvider {
+	return func() ([]string, error) {
+		var changed []string
+		for file := range change.Revisions[change.CurrentRevision].Files {
+			changed = append(changed, file)
+		}
+		sort.Strings(changed)
+		return changed, nil
+	}
+}
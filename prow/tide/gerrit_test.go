@@ -0,0 +1,275 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-gerrit"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gerrit/client"
+	"k8s.io/test-infra/prow/tide/history"
+)
+
+const gerritInstance = "https://gerrit.example.com"
+
+type fakeGerritClient struct {
+	changes   map[string][]client.ChangeInfo
+	branches  map[string]string
+	submitted []string
+}
+
+func (f *fakeGerritClient) SearchChanges(instance, query string, rateLimit int) ([]client.ChangeInfo, error) {
+	if instance != gerritInstance {
+		return nil, fmt.Errorf("unknown instance %s", instance)
+	}
+	return f.changes[query], nil
+}
+
+func (f *fakeGerritClient) GetBranchRevision(instance, project, branch string) (string, error) {
+	return f.branches[project+"/"+branch], nil
+}
+
+func (f *fakeGerritClient) SubmitChange(instance, id string) (*client.ChangeInfo, error) {
+	f.submitted = append(f.submitted, id)
+	return &client.ChangeInfo{ID: id, Status: client.Merged}, nil
+}
+
+func gerritChange(number int) client.ChangeInfo {
+	revision := fmt.Sprintf("rev-%d", number)
+	return client.ChangeInfo{
+		ID:              fmt.Sprintf("proj~master~%d", number),
+		Number:          number,
+		Project:         "proj",
+		Branch:          "master",
+		Status:          client.New,
+		CurrentRevision: revision,
+		Revisions: map[string]client.RevisionInfo{
+			revision: {Ref: fmt.Sprintf("refs/changes/%d/1", number)},
+		},
+		Owner: gerrit.AccountInfo{Username: "author"},
+	}
+}
+
+func gerritJob(number int, baseSHA string, state prowapi.ProwJobState, created time.Time) *prowapi.ProwJob {
+	revision := fmt.Sprintf("rev-%d", number)
+	return &prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              fmt.Sprintf("%d-%s-%s", number, baseSHA, state),
+			Namespace:         "default",
+			Labels:            map[string]string{client.GerritRevision: revision},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: prowapi.ProwJobSpec{
+			Type:    prowapi.PresubmitJob,
+			Job:     "unit",
+			Context: "unit",
+			Refs: &prowapi.Refs{
+				Org:     "gerrit.example.com",
+				Repo:    "proj",
+				BaseRef: "master",
+				BaseSHA: baseSHA,
+				Pulls:   []prowapi.Pull{{Number: number, SHA: revision}},
+			},
+		},
+		Status: prowapi.ProwJobStatus{State: state},
+	}
+}
+
+func TestGerritSync(t *testing.T) {
+	now := time.Now()
+	var testCases = []struct {
+		name    string
+		changes []int
+		jobs    []runtime.Object
+		dryRun  bool
+
+		expectedAction    Action
+		expectedTargets   []int
+		expectedSubmitted []string
+		expectedTriggered []int
+		expectNoPool      bool
+	}{
+		{
+			name:              "untested change is tested",
+			changes:           []int{1},
+			expectedAction:    Trigger,
+			expectedTargets:   []int{1},
+			expectedTriggered: []int{1},
+		},
+		{
+			name:              "passing change is submitted",
+			changes:           []int{1},
+			jobs:              []runtime.Object{gerritJob(1, "tip", prowapi.SuccessState, now)},
+			expectedAction:    Merge,
+			expectedTargets:   []int{1},
+			expectedSubmitted: []string{"proj~master~1"},
+		},
+		{
+			name:            "passing change is not submitted in dry-run mode",
+			changes:         []int{1},
+			jobs:            []runtime.Object{gerritJob(1, "tip", prowapi.SuccessState, now)},
+			dryRun:          true,
+			expectedAction:  Merge,
+			expectedTargets: []int{1},
+		},
+		{
+			name:              "change with stale results is tested again",
+			changes:           []int{1},
+			jobs:              []runtime.Object{gerritJob(1, "old", prowapi.SuccessState, now)},
+			expectedAction:    Trigger,
+			expectedTargets:   []int{1},
+			expectedTriggered: []int{1},
+		},
+		{
+			name:           "tested change waits for its jobs",
+			changes:        []int{1, 2},
+			jobs:           []runtime.Object{gerritJob(2, "tip", prowapi.PendingState, now)},
+			expectedAction: Wait,
+		},
+		{
+			name:         "failed change is not tested again",
+			changes:      []int{1},
+			jobs:         []runtime.Object{gerritJob(1, "old", prowapi.FailureState, now)},
+			expectNoPool: true,
+		},
+		{
+			name:    "change that passed after failing is submitted",
+			changes: []int{1},
+			jobs: []runtime.Object{
+				gerritJob(1, "old", prowapi.FailureState, now.Add(-time.Hour)),
+				gerritJob(1, "tip", prowapi.SuccessState, now),
+			},
+			expectedAction:    Merge,
+			expectedTargets:   []int{1},
+			expectedSubmitted: []string{"proj~master~1"},
+		},
+		{
+			name:    "changes are submitted one at a time",
+			changes: []int{3, 2},
+			jobs: []runtime.Object{
+				gerritJob(2, "tip", prowapi.SuccessState, now),
+				gerritJob(3, "tip", prowapi.SuccessState, now),
+			},
+			expectedAction:    Merge,
+			expectedTargets:   []int{2},
+			expectedSubmitted: []string{"proj~master~2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query := config.TideGerritQuery{
+				Instance: gerritInstance,
+				Projects: []string{"proj"},
+				Labels:   map[string]int{"Code-Review": 2},
+			}
+			cfg := &config.Config{ProwConfig: config.ProwConfig{
+				ProwJobNamespace: "default",
+				Tide:             config.Tide{Gerrit: config.TideGerrit{Queries: []config.TideGerritQuery{query}}},
+			}}
+			if err := cfg.SetPresubmits(map[string][]config.Presubmit{
+				gerritInstance + "/proj": {{
+					JobBase:   config.JobBase{Name: "unit"},
+					Reporter:  config.Reporter{Context: "unit"},
+					AlwaysRun: true,
+				}},
+			}); err != nil {
+				t.Fatalf("failed to set presubmits: %v", err)
+			}
+			var changes []client.ChangeInfo
+			for _, number := range tc.changes {
+				changes = append(changes, gerritChange(number))
+			}
+			gc := &fakeGerritClient{
+				changes:  map[string][]client.ChangeInfo{query.Query("proj"): changes},
+				branches: map[string]string{"proj/master": "tip"},
+			}
+			hist, err := history.New(100, nil, "")
+			if err != nil {
+				t.Fatalf("failed to create history client: %v", err)
+			}
+			mgr := newFakeManager(tc.jobs...)
+			c, err := newGerritController(logrus.WithField("component", "tide"), gc, mgr, func() *config.Config { return cfg }, hist, tc.dryRun)
+			if err != nil {
+				t.Fatalf("failed to create controller: %v", err)
+			}
+
+			if err := c.Sync(); err != nil {
+				t.Fatalf("unexpected error syncing: %v", err)
+			}
+
+			if tc.expectNoPool {
+				if len(c.pools) != 0 {
+					t.Errorf("expected no pools, got %v", c.pools)
+				}
+			} else {
+				if len(c.pools) != 1 {
+					t.Fatalf("expected one pool, got %v", c.pools)
+				}
+				pool := c.pools[0]
+				if pool.Org != "gerrit.example.com" || pool.Repo != "proj" || pool.Branch != "master" || pool.GerritInstance != gerritInstance {
+					t.Errorf("unexpected pool %s/%s:%s on %s", pool.Org, pool.Repo, pool.Branch, pool.GerritInstance)
+				}
+				if pool.Action != tc.expectedAction {
+					t.Errorf("expected action %s, got %s", tc.expectedAction, pool.Action)
+				}
+				if targets := prNumbers(pool.Target); !reflect.DeepEqual(targets, tc.expectedTargets) {
+					t.Errorf("expected targets %v, got %v", tc.expectedTargets, targets)
+				}
+			}
+			if !reflect.DeepEqual(gc.submitted, tc.expectedSubmitted) {
+				t.Errorf("expected submitted changes %v, got %v", tc.expectedSubmitted, gc.submitted)
+			}
+
+			pjs := &prowapi.ProwJobList{}
+			if err := mgr.GetClient().List(context.Background(), pjs); err != nil {
+				t.Fatalf("failed to list prowjobs: %v", err)
+			}
+			existing := sets.NewString()
+			for _, job := range tc.jobs {
+				existing.Insert(job.(*prowapi.ProwJob).Name)
+			}
+			var triggered []int
+			for _, pj := range pjs.Items {
+				if existing.Has(pj.Name) {
+					continue
+				}
+				if pj.Spec.Refs.BaseSHA != "tip" {
+					t.Errorf("expected job to test against the tip of the branch, got %s", pj.Spec.Refs.BaseSHA)
+				}
+				if pj.Labels[client.GerritRevision] != pj.Spec.Refs.Pulls[0].SHA || pj.Annotations[client.GerritInstance] != gerritInstance {
+					t.Errorf("expected job to be reported to gerrit, got labels %v and annotations %v", pj.Labels, pj.Annotations)
+				}
+				triggered = append(triggered, pj.Spec.Refs.Pulls[0].Number)
+			}
+			if !reflect.DeepEqual(triggered, tc.expectedTriggered) {
+				t.Errorf("expected changes %v to be tested, got %v", tc.expectedTriggered, triggered)
+			}
+		})
+	}
+}
@@ -113,6 +113,21 @@ type Record struct {
 	BaseSHA string         `json:"baseSHA,omitempty"`
 	Target  []prowapi.Pull `json:"target,omitempty"`
 	Err     string         `json:"err,omitempty"`
+	// Bisection is the state of the bisection of a failed batch after the
+	// action, if the action was part of one.
+	Bisection *Bisection `json:"bisection,omitempty"`
+}
+
+// Bisection is the state of the bisection of a failed batch into the PRs
+// that break it.
+type Bisection struct {
+	// Batch is the failed batch, in the order its PRs were tested.
+	Batch []prowapi.Pull `json:"batch"`
+	// FailedJob is the URL of a failed job of the batch.
+	FailedJob string `json:"failedJob,omitempty"`
+	// Culprits are the PRs of the batch that were found to fail on their
+	// own and were marked as such.
+	Culprits []prowapi.Pull `json:"culprits,omitempty"`
 }
 
 // New creates a new History struct with the specificed recordLog size limit.
@@ -143,20 +158,48 @@ func New(maxRecordsPerKey int, opener io.Opener, path string) (*History, error)
 
 // Record appends an entry to the recordlog specified by the poolKey.
 func (h *History) Record(poolKey, action, baseSHA, err string, targets []prowapi.Pull) {
+	h.RecordBisection(poolKey, action, baseSHA, err, targets, nil)
+}
+
+// RecordBisection appends an entry for an action taken as part of the
+// bisection of a failed batch to the recordlog specified by the poolKey.
+func (h *History) RecordBisection(poolKey, action, baseSHA, err string, targets []prowapi.Pull, bisection *Bisection) {
 	t := now()
 	sort.Sort(ByNum(targets))
 	h.addRecord(
 		poolKey,
 		&Record{
-			Time:    t,
-			Action:  action,
-			BaseSHA: baseSHA,
-			Target:  targets,
-			Err:     err,
+			Time:      t,
+			Action:    action,
+			BaseSHA:   baseSHA,
+			Target:    targets,
+			Err:       err,
+			Bisection: bisection,
 		},
 	)
 }
 
+// Bisection returns the state of the latest bisection of the pool recorded
+// since the branch was last at baseSHA, if any. Bisections only apply to the
+// base SHA they were tested on, they end once the branch moves.
+func (h *History) Bisection(poolKey, baseSHA string) *Bisection {
+	h.Lock()
+	defer h.Unlock()
+	log, ok := h.logs[poolKey]
+	if !ok {
+		return nil
+	}
+	for _, rec := range log.toSlice() {
+		if rec.BaseSHA != baseSHA {
+			return nil
+		}
+		if rec.Bisection != nil {
+			return rec.Bisection
+		}
+	}
+	return nil
+}
+
 func (h *History) addRecord(poolKey string, rec *Record) {
 	h.Lock()
 	defer h.Unlock()
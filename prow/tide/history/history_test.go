@@ -155,6 +155,18 @@ func (t *testOpener) Writer(ctx context.Context, path string, _ ...pkgio.WriterO
 	return t, nil
 }
 
+func (t *testOpener) Iterator(ctx context.Context, prefix, delimiter string) (pkgio.ObjectIterator, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (t *testOpener) Attributes(ctx context.Context, path string) (pkgio.Attributes, error) {
+	return pkgio.Attributes{}, errors.New("not implemented")
+}
+
+func (t *testOpener) Delete(ctx context.Context, path string) error {
+	return errors.New("not implemented")
+}
+
 func (t *testOpener) Write(p []byte) (n int, err error) {
 	if t.closed {
 		return 0, errors.New("writer is already closed")
@@ -182,6 +194,70 @@ func (t *testOpener) Close() error {
 	return nil
 }
 
+func TestBisection(t *testing.T) {
+	bisection := &Bisection{Batch: []prowapi.Pull{{Number: 1, SHA: "a"}, {Number: 2, SHA: "b"}}}
+	culprits := &Bisection{Batch: bisection.Batch, Culprits: []prowapi.Pull{{Number: 2, SHA: "b"}}}
+	testCases := []struct {
+		name    string
+		record  func(hist *History)
+		baseSHA string
+
+		expected *Bisection
+	}{
+		{
+			name:    "no records",
+			record:  func(hist *History) {},
+			baseSHA: "sha",
+		},
+		{
+			name: "latest bisection at the base SHA",
+			record: func(hist *History) {
+				hist.RecordBisection("pool", "BISECT", "sha", "", nil, bisection)
+				hist.Record("pool", "TRIGGER", "sha", "", nil)
+				hist.RecordBisection("pool", "BISECT", "sha", "", nil, culprits)
+				hist.Record("pool", "TRIGGER", "sha", "", nil)
+			},
+			baseSHA:  "sha",
+			expected: culprits,
+		},
+		{
+			name: "bisection ends when the branch moves",
+			record: func(hist *History) {
+				hist.RecordBisection("pool", "BISECT", "sha", "", nil, bisection)
+				hist.Record("pool", "MERGE_BATCH", "sha", "", nil)
+				hist.Record("pool", "TRIGGER", "new-sha", "", nil)
+			},
+			baseSHA: "new-sha",
+		},
+		{
+			name: "bisection of another base SHA",
+			record: func(hist *History) {
+				hist.RecordBisection("pool", "BISECT", "sha", "", nil, bisection)
+			},
+			baseSHA: "other-sha",
+		},
+		{
+			name: "bisection of another pool",
+			record: func(hist *History) {
+				hist.RecordBisection("other-pool", "BISECT", "sha", "", nil, bisection)
+			},
+			baseSHA: "sha",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hist, err := New(10, nil, "")
+			if err != nil {
+				t.Fatalf("Failed to create history client: %v", err)
+			}
+			tc.record(hist)
+			if actual := hist.Bisection("pool", tc.baseSHA); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Expected bisection %+v, got %+v.", tc.expected, actual)
+			}
+		})
+	}
+}
+
 func TestReadHistory(t *testing.T) {
 	tcs := []struct {
 		name           string
@@ -0,0 +1,138 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+// Speculative batches are tested on top of the pending batch, as if it
+// merged: their refs list the PRs of the pending batch before their own.
+// While the pending batch runs they are just larger batches. Once it
+// merged, the branch is where the speculative batches predicted it would be,
+// so their jobs are used as if they were tested on top of the new base.
+
+// speculation is the merge of a batch, which speculative batches may have
+// been tested on top of.
+type speculation struct {
+	// from is the base SHA the batch was tested on, to the base SHA it
+	// merged into.
+	from, to string
+	pulls    []prowapi.Pull
+}
+
+// recordSpeculation records the merge of the batch, if the subpool speculates.
+func (c *Controller) recordSpeculation(sp subpool, batch []PullRequest) {
+	depth := c.config().Tide.SpeculativeDepth(config.OrgRepo{Org: sp.org, Repo: sp.repo}, sp.branch)
+	if depth == 0 {
+		return
+	}
+	// The branch may have moved for other reasons in the meantime, in which
+	// case speculative batches are tested on top of the wrong base. That is
+	// the same race merging a batch already has.
+	sha, err := c.ghc.GetRef(sp.org, sp.repo, "heads/"+sp.branch)
+	if err != nil {
+		sp.log.WithError(err).Warn("Failed to get the merged base SHA, speculative batches will be tested again.")
+		return
+	}
+
+	key := poolKey(sp.org, sp.repo, sp.branch)
+	c.speculationLock.Lock()
+	defer c.speculationLock.Unlock()
+	if c.speculations == nil {
+		c.speculations = map[string][]speculation{}
+	}
+	speculations := append(c.speculations[key], speculation{from: sp.sha, to: sha, pulls: prMeta(batch...)})
+	// Jobs speculate on at most depth batches merging.
+	if len(speculations) > depth {
+		speculations = speculations[len(speculations)-depth:]
+	}
+	c.speculations[key] = speculations
+}
+
+// speculativeJobs returns the batch jobs tested on top of batches that
+// merged since, as if they were tested on top of the base SHA of the
+// subpool.
+func (c *Controller) speculativeJobs(sp *subpool) ([]prowapi.ProwJob, error) {
+	key := poolKey(sp.org, sp.repo, sp.branch)
+	c.speculationLock.Lock()
+	speculations := c.speculations[key]
+	c.speculationLock.Unlock()
+
+	var result []prowapi.ProwJob
+	var merged []prowapi.Pull
+	sha := sp.sha
+	for i := len(speculations) - 1; i >= 0; i-- {
+		s := speculations[i]
+		if s.to != sha {
+			// The branch moved without us.
+			break
+		}
+		merged = append(append([]prowapi.Pull{}, s.pulls...), merged...)
+		sha = s.from
+
+		pjs := &prowapi.ProwJobList{}
+		if err := c.prowJobClient.List(
+			c.ctx,
+			pjs,
+			ctrlruntimeclient.MatchingField(cacheIndexName, cacheIndexKey(sp.org, sp.repo, sp.branch, s.from)),
+			ctrlruntimeclient.InNamespace(c.config().ProwJobNamespace)); err != nil {
+			return nil, err
+		}
+		for _, pj := range pjs.Items {
+			if pj.Spec.Type != prowapi.BatchJob || !pullsPrefix(merged, pj.Spec.Refs.Pulls) {
+				continue
+			}
+			pj = *pj.DeepCopy()
+			pj.Spec.Refs.BaseSHA = sp.sha
+			pj.Spec.Refs.Pulls = pj.Spec.Refs.Pulls[len(merged):]
+			result = append(result, pj)
+		}
+	}
+	if len(result) > 0 {
+		sp.log.Debugf("Found %d speculative prowjobs.", len(result))
+	}
+	return result, nil
+}
+
+// pullsPrefix determines whether the pulls start with the prefix and more.
+func pullsPrefix(prefix, pulls []prowapi.Pull) bool {
+	if len(prefix) >= len(pulls) {
+		return false
+	}
+	for i, pull := range prefix {
+		if pull.Number != pulls[i].Number || pull.SHA != pulls[i].SHA {
+			return false
+		}
+	}
+	return true
+}
+
+// ownPRs returns the PRs each speculative batch adds to the batch it was
+// tested on top of.
+func ownPRs(pending []PullRequest, speculativeBatches [][]PullRequest) [][]PullRequest {
+	var own [][]PullRequest
+	base := pending
+	for _, batch := range speculativeBatches {
+		own = append(own, batch[len(base):])
+		base = batch
+	}
+	return own
+}
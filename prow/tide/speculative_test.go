@@ -0,0 +1,190 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+func speculativeJob(name, baseSHA string, jobType prowapi.ProwJobType, numbers ...int) *prowapi.ProwJob {
+	pj := &prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: prowapi.ProwJobSpec{
+			Type:    jobType,
+			Job:     "foo",
+			Context: "foo",
+			Refs:    &prowapi.Refs{Org: "o", Repo: "r", BaseRef: "master", BaseSHA: baseSHA},
+		},
+	}
+	for _, number := range numbers {
+		pj.Spec.Refs.Pulls = append(pj.Spec.Refs.Pulls, prowapi.Pull{Number: number, SHA: fmt.Sprintf("sha-%d", number)})
+	}
+	return pj
+}
+
+func TestSpeculativeJobs(t *testing.T) {
+	type merge struct {
+		from, to string
+		prs      []int
+	}
+	var testCases = []struct {
+		name   string
+		depth  int
+		merges []merge
+		jobs   []runtime.Object
+		sha    string
+
+		// expected maps the names of the reused jobs to the PRs they test
+		// on top of sha.
+		expected map[string][]int
+	}{
+		{
+			name:   "jobs on top of the merged batch are reused",
+			depth:  1,
+			merges: []merge{{from: "base-1", to: "base-2", prs: []int{1, 2}}},
+			jobs: []runtime.Object{
+				speculativeJob("batch", "base-1", prowapi.BatchJob, 1, 2),
+				speculativeJob("speculative", "base-1", prowapi.BatchJob, 1, 2, 3),
+				speculativeJob("other-batch", "base-1", prowapi.BatchJob, 1, 4),
+				speculativeJob("presubmit", "base-1", prowapi.PresubmitJob, 3),
+			},
+			sha:      "base-2",
+			expected: map[string][]int{"speculative": {3}},
+		},
+		{
+			name:  "jobs on top of a chain of merged batches are reused",
+			depth: 2,
+			merges: []merge{
+				{from: "base-1", to: "base-2", prs: []int{1, 2}},
+				{from: "base-2", to: "base-3", prs: []int{3}},
+			},
+			jobs: []runtime.Object{
+				speculativeJob("first", "base-1", prowapi.BatchJob, 1, 2, 3, 4),
+				speculativeJob("second", "base-2", prowapi.BatchJob, 3, 5),
+			},
+			sha:      "base-3",
+			expected: map[string][]int{"first": {4}, "second": {5}},
+		},
+		{
+			name:  "only depth merges are remembered",
+			depth: 1,
+			merges: []merge{
+				{from: "base-1", to: "base-2", prs: []int{1, 2}},
+				{from: "base-2", to: "base-3", prs: []int{3}},
+			},
+			jobs: []runtime.Object{
+				speculativeJob("first", "base-1", prowapi.BatchJob, 1, 2, 3, 4),
+				speculativeJob("second", "base-2", prowapi.BatchJob, 3, 5),
+			},
+			sha:      "base-3",
+			expected: map[string][]int{"second": {5}},
+		},
+		{
+			name:   "jobs are not reused when the branch moved without us",
+			depth:  1,
+			merges: []merge{{from: "base-1", to: "base-2", prs: []int{1, 2}}},
+			jobs: []runtime.Object{
+				speculativeJob("speculative", "base-1", prowapi.BatchJob, 1, 2, 3),
+			},
+			sha: "pushed",
+		},
+		{
+			name:   "merges are not recorded without speculation",
+			merges: []merge{{from: "base-1", to: "base-2", prs: []int{1, 2}}},
+			jobs: []runtime.Object{
+				speculativeJob("speculative", "base-1", prowapi.BatchJob, 1, 2, 3),
+			},
+			sha: "base-2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{ProwConfig: config.ProwConfig{
+				ProwJobNamespace: "default",
+				Tide:             config.Tide{SpeculativeDepthMap: map[string]int{"o/r": tc.depth}},
+			}}
+			mgr := newFakeManager(tc.jobs...)
+			if err := mgr.GetFieldIndexer().IndexField(&prowapi.ProwJob{}, cacheIndexName, cacheIndexFunc); err != nil {
+				t.Fatalf("failed to add index: %v", err)
+			}
+			ghc := &fgc{refs: map[string]string{}}
+			c := &Controller{
+				ctx:           context.Background(),
+				config:        func() *config.Config { return cfg },
+				ghc:           ghc,
+				prowJobClient: mgr.GetClient(),
+			}
+			log := logrus.WithField("test", tc.name)
+
+			for _, m := range tc.merges {
+				var batch []PullRequest
+				for _, number := range m.prs {
+					batch = append(batch, PullRequest{Number: githubql.Int(number), HeadRefOID: githubql.String(fmt.Sprintf("sha-%d", number))})
+				}
+				ghc.refs["o/r heads/master"] = m.to
+				c.recordSpeculation(subpool{org: "o", repo: "r", branch: "master", sha: m.from, log: log}, batch)
+			}
+
+			pjs, err := c.speculativeJobs(&subpool{org: "o", repo: "r", branch: "master", sha: tc.sha, log: log})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var actual map[string][]int
+			for _, pj := range pjs {
+				if pj.Spec.Refs.BaseSHA != tc.sha {
+					t.Errorf("expected job %s to be tested on top of %s, got %s", pj.Name, tc.sha, pj.Spec.Refs.BaseSHA)
+				}
+				if actual == nil {
+					actual = map[string][]int{}
+				}
+				for _, pull := range pj.Spec.Refs.Pulls {
+					actual[pj.Name] = append(actual[pj.Name], pull.Number)
+				}
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected reused jobs %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestOwnPRs(t *testing.T) {
+	pending := []PullRequest{{Number: 1}, {Number: 2}}
+	speculative := [][]PullRequest{
+		{{Number: 1}, {Number: 2}, {Number: 3}},
+		{{Number: 1}, {Number: 2}, {Number: 3}, {Number: 4}, {Number: 5}},
+	}
+	var actual [][]int
+	for _, own := range ownPRs(pending, speculative) {
+		actual = append(actual, prNumbers(own))
+	}
+	if expected := [][]int{{3}, {4, 5}}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected own PRs %v, got %v", expected, actual)
+	}
+}
@@ -57,6 +57,9 @@ type githubClient interface {
 	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
 	GetRef(string, string, string) (string, error)
 	GetRepo(owner, name string) (github.FullRepo, error)
+	AddLabel(org, repo string, number int, label string) error
+	CreateComment(owner, repo string, number int, comment string) error
+	ListCheckRuns(org, repo, ref string) ([]github.CheckRun, error)
 	Merge(string, string, int, github.MergeDetails) error
 	Query(context.Context, interface{}, map[string]interface{}) error
 }
@@ -88,6 +91,11 @@ type Controller struct {
 
 	mergeChecker *mergeChecker
 
+	// speculations records the merges of batches that speculative batches
+	// may have been tested on top of, by subpool.
+	speculationLock sync.Mutex
+	speculations    map[string][]speculation
+
 	History *history.History
 }
 
@@ -97,21 +105,25 @@ type Action string
 
 // Constants for various actions the controller might take
 const (
-	Wait         Action = "WAIT"
-	Trigger             = "TRIGGER"
-	TriggerBatch        = "TRIGGER_BATCH"
-	Merge               = "MERGE"
-	MergeBatch          = "MERGE_BATCH"
-	PoolBlocked         = "BLOCKED"
+	Wait                    Action = "WAIT"
+	Trigger                        = "TRIGGER"
+	TriggerBatch                   = "TRIGGER_BATCH"
+	TriggerSpeculativeBatch        = "TRIGGER_SPECULATIVE_BATCH"
+	Bisect                         = "BISECT"
+	Merge                          = "MERGE"
+	MergeBatch                     = "MERGE_BATCH"
+	PoolBlocked                    = "BLOCKED"
 )
 
 // recordableActions is the subset of actions that we keep historical record of.
 // Ignore idle actions to avoid flooding the records with useless data.
 var recordableActions = map[Action]bool{
-	Trigger:      true,
-	TriggerBatch: true,
-	Merge:        true,
-	MergeBatch:   true,
+	Trigger:                 true,
+	TriggerBatch:            true,
+	TriggerSpeculativeBatch: true,
+	Bisect:                  true,
+	Merge:                   true,
+	MergeBatch:              true,
 }
 
 // Pool represents information about a tide pool. There is one for every
@@ -130,12 +142,19 @@ type Pool struct {
 
 	// Empty if there is no pending batch.
 	BatchPending []PullRequest
+	// The batches tested on top of the pending batch, each on top of the
+	// ones before it, as if they merged.
+	SpeculativeBatches [][]PullRequest `json:",omitempty"`
 
 	// Which action did we last take, and to what target(s), if any.
 	Action   Action
 	Target   []PullRequest
 	Blockers []blockers.Blocker
 	Error    string
+
+	// GerritInstance is the Gerrit instance that hosts the changes of the
+	// pool, empty for pools of GitHub PRs.
+	GerritInstance string `json:",omitempty"`
 }
 
 // Prometheus Metrics
@@ -437,13 +456,17 @@ func (c *Controller) Sync() error {
 func (c *Controller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	c.m.Lock()
 	defer c.m.Unlock()
-	b, err := json.Marshal(c.pools)
+	servePools(w, c.logger, c.pools)
+}
+
+func servePools(w http.ResponseWriter, logger *logrus.Entry, pools []Pool) {
+	b, err := json.Marshal(pools)
 	if err != nil {
-		c.logger.WithError(err).Error("Encoding JSON.")
+		logger.WithError(err).Error("Encoding JSON.")
 		b = []byte("[]")
 	}
 	if _, err = w.Write(b); err != nil {
-		c.logger.WithError(err).Error("Writing JSON response.")
+		logger.WithError(err).Error("Writing JSON response.")
 	}
 }
 
@@ -776,7 +799,9 @@ func pickSmallestPassingNumber(log *logrus.Entry, ghc githubClient, prs []PullRe
 // accumulateBatch looks at existing batch ProwJobs and, if applicable, returns:
 // * A list of PRs that are part of a batch test that finished successfully
 // * A list of PRs that are part of a batch test that hasn't finished yet but didn't have any failures so far
-func (c *Controller) accumulateBatch(sp subpool) (successBatch []PullRequest, pendingBatch []PullRequest) {
+// * The lists of PRs of the pending batch tests speculating on the pending
+//   batch, each extending the one before it
+func (c *Controller) accumulateBatch(sp subpool) (successBatch []PullRequest, pendingBatch []PullRequest, speculativeBatches [][]PullRequest) {
 	sp.log.Debug("accumulating PRs for batch testing")
 	prNums := make(map[int]PullRequest)
 	for _, pr := range sp.prs {
@@ -829,6 +854,7 @@ func (c *Controller) accumulateBatch(sp subpool) (successBatch []PullRequest, pe
 			states[ref].jobStates[context] = jobState
 		}
 	}
+	var pendingBatches, failedBatches [][]PullRequest
 	for ref, state := range states {
 		if !state.validPulls {
 			continue
@@ -851,15 +877,67 @@ func (c *Controller) accumulateBatch(sp subpool) (successBatch []PullRequest, pe
 			}
 		}
 		switch overallState {
-		// Currently we only consider 1 pending batch and 1 success batch at a time.
-		// If more are somehow present they will be ignored.
 		case pendingState:
-			pendingBatch = state.prs
+			pendingBatches = append(pendingBatches, state.prs)
 		case successState:
-			successBatch = state.prs
+			// Speculative batches include the batches they were tested on
+			// top of, so prefer the largest one.
+			if len(state.prs) > len(successBatch) {
+				successBatch = state.prs
+			}
+		case failureState:
+			failedBatches = append(failedBatches, state.prs)
+		}
+	}
+
+	// Batches tested on top of a failed batch are discarded, they would
+	// need it to merge.
+	var validBatches [][]PullRequest
+	for _, batch := range pendingBatches {
+		discarded := false
+		for _, failed := range failedBatches {
+			if isBatchPrefix(failed, batch) {
+				sp.log.WithField("batch", prNumbers(batch)).Debugf("speculative batch discarded, batch %v failed", prNumbers(failed))
+				discarded = true
+				break
+			}
+		}
+		if !discarded {
+			validBatches = append(validBatches, batch)
+		}
+	}
+	sort.Slice(validBatches, func(i, j int) bool {
+		if len(validBatches[i]) != len(validBatches[j]) {
+			return len(validBatches[i]) < len(validBatches[j])
+		}
+		return fmt.Sprint(prNumbers(validBatches[i])) < fmt.Sprint(prNumbers(validBatches[j]))
+	})
+	// Currently we only consider 1 pending batch and the batches speculating
+	// on it at a time. If more are somehow present they will be ignored.
+	if len(validBatches) > 0 {
+		pendingBatch = validBatches[0]
+		top := pendingBatch
+		for _, batch := range validBatches[1:] {
+			if isBatchPrefix(top, batch) {
+				speculativeBatches = append(speculativeBatches, batch)
+				top = batch
+			}
+		}
+	}
+	return successBatch, pendingBatch, speculativeBatches
+}
+
+// isBatchPrefix determines whether the batch was tested on top of the prefix.
+func isBatchPrefix(prefix, batch []PullRequest) bool {
+	if len(prefix) >= len(batch) {
+		return false
+	}
+	for i, pr := range prefix {
+		if pr.Number != batch[i].Number || pr.HeadRefOID != batch[i].HeadRefOID {
+			return false
 		}
 	}
-	return successBatch, pendingBatch
+	return true
 }
 
 // accumulate returns the supplied PRs sorted into three buckets based on their
@@ -891,11 +969,29 @@ func accumulate(presubmits map[int][]config.Presubmit, prs []PullRequest, pjs []
 				psStates[name] = successState
 			}
 		}
+		// A presubmit that runs after others is only started once they
+		// succeeded, so until then it is waiting rather than missing.
+		contexts := map[string]string{}
+		for _, ps := range presubmits[int(pr.Number)] {
+			contexts[ps.Name] = ps.Context
+		}
+		waiting := func(ps config.Presubmit) bool {
+			for _, parent := range ps.RunAfterSuccess {
+				if context, required := contexts[parent]; required && psStates[context] != successState {
+					return true
+				}
+			}
+			return false
+		}
 		// The overall result for the PR is the worst of the best of all its
 		// required Presubmits
 		overallState := successState
 		for _, ps := range presubmits[int(pr.Number)] {
-			if s, ok := psStates[ps.Context]; !ok {
+			if s, ok := psStates[ps.Context]; (!ok || s == failureState) && waiting(ps) {
+				// The presubmits it waits for are missing, failing or pending
+				// and hold back the PR.
+				log.WithFields(pr.logFields()).Debugf("presubmit %s waiting on %v", ps.Context, ps.RunAfterSuccess)
+			} else if !ok {
 				// No PJ with correct baseSHA+headSHA exists
 				missingTests[int(pr.Number)] = append(missingTests[int(pr.Number)], ps)
 				log.WithFields(pr.logFields()).Debugf("missing presubmit %s", ps.Context)
@@ -931,7 +1027,10 @@ func prNumbers(prs []PullRequest) []int {
 	return nums
 }
 
-func (c *Controller) pickBatch(sp subpool, cc map[int]contextChecker) ([]PullRequest, []config.Presubmit, error) {
+// pickBatch picks the oldest passing PRs that merge together. The batch is
+// picked on top of the base PRs, as if they merged, which are left out of it.
+// The presubmits are those required by the base and the batch together.
+func (c *Controller) pickBatch(sp subpool, cc map[int]contextChecker, base []PullRequest) ([]PullRequest, []config.Presubmit, error) {
 	batchLimit := c.config().Tide.BatchSizeLimit(config.OrgRepo{Org: sp.org, Repo: sp.repo})
 	if batchLimit < 0 {
 		sp.log.Debug("Batch merges disabled by configuration in this repo.")
@@ -941,8 +1040,12 @@ func (c *Controller) pickBatch(sp subpool, cc map[int]contextChecker) ([]PullReq
 	// we must choose the oldest PRs for the batch
 	sort.Slice(sp.prs, func(i, j int) bool { return sp.prs[i].Number < sp.prs[j].Number })
 
+	inBase := sets.NewInt(prNumbers(base)...)
 	var candidates []PullRequest
 	for _, pr := range sp.prs {
+		if inBase.Has(int(pr.Number)) {
+			continue
+		}
 		if isPassingTests(sp.log, c.ghc, pr, cc[int(pr.Number)]) {
 			candidates = append(candidates, pr)
 		}
@@ -971,6 +1074,14 @@ func (c *Controller) pickBatch(sp subpool, cc map[int]contextChecker) ([]PullReq
 	if err := r.Checkout(sp.sha); err != nil {
 		return nil, nil, err
 	}
+	for _, pr := range base {
+		if ok, err := r.Merge(string(pr.HeadRefOID)); err != nil {
+			return nil, nil, err
+		} else if !ok {
+			sp.log.WithFields(pr.logFields()).Debug("base of the batch does not merge, no batch will be created")
+			return nil, nil, nil
+		}
+	}
 
 	var res []PullRequest
 	for _, pr := range candidates {
@@ -987,7 +1098,11 @@ func (c *Controller) pickBatch(sp subpool, cc map[int]contextChecker) ([]PullReq
 		}
 	}
 
-	presubmits, err := c.presubmitsForBatch(res, sp.org, sp.repo, sp.sha, sp.branch)
+	if len(res) == 0 {
+		return nil, nil, nil
+	}
+	all := append(append([]PullRequest{}, base...), res...)
+	presubmits, err := c.presubmitsForBatch(all, sp.org, sp.repo, sp.sha, sp.branch)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1222,10 +1337,14 @@ func (c *Controller) trigger(sp subpool, presubmits []config.Presubmit, prs []Pu
 	return nil
 }
 
-func (c *Controller) takeAction(sp subpool, batchPending, successes, pendings, missings, batchMerges []PullRequest, missingSerialTests map[int][]config.Presubmit) (Action, []PullRequest, error) {
+func (c *Controller) takeAction(sp subpool, batchPending []PullRequest, speculativeBatches [][]PullRequest, successes, pendings, missings, batchMerges []PullRequest, missingSerialTests map[int][]config.Presubmit) (Action, []PullRequest, error) {
 	// Merge the batch!
 	if len(batchMerges) > 0 {
-		return MergeBatch, batchMerges, c.mergePRs(sp, batchMerges)
+		if err := c.mergePRs(sp, batchMerges); err != nil {
+			return MergeBatch, batchMerges, err
+		}
+		c.recordSpeculation(sp, batchMerges)
+		return MergeBatch, batchMerges, nil
 	}
 	// Do not merge PRs while waiting for a batch to complete. We don't want to
 	// invalidate the old batch result.
@@ -1240,7 +1359,7 @@ func (c *Controller) takeAction(sp subpool, batchPending, successes, pendings, m
 	}
 	// If we have no batch, trigger one.
 	if len(sp.prs) > 1 && len(batchPending) == 0 {
-		batch, presubmits, err := c.pickBatch(sp, sp.cc)
+		batch, presubmits, err := c.pickBatch(sp, sp.cc, nil)
 		if err != nil {
 			return Wait, nil, err
 		}
@@ -1248,6 +1367,22 @@ func (c *Controller) takeAction(sp subpool, batchPending, successes, pendings, m
 			return TriggerBatch, batch, c.trigger(sp, presubmits, batch)
 		}
 	}
+	// If we have room to speculate, test the next batch on top of the
+	// pending ones as if they merged.
+	depth := c.config().Tide.SpeculativeDepth(config.OrgRepo{Org: sp.org, Repo: sp.repo}, sp.branch)
+	if len(batchPending) > 0 && len(speculativeBatches) < depth {
+		base := batchPending
+		if len(speculativeBatches) > 0 {
+			base = speculativeBatches[len(speculativeBatches)-1]
+		}
+		batch, presubmits, err := c.pickBatch(sp, sp.cc, base)
+		if err != nil {
+			return Wait, nil, err
+		}
+		if len(batch) > 0 {
+			return TriggerSpeculativeBatch, batch, c.trigger(sp, presubmits, append(append([]PullRequest{}, base...), batch...))
+		}
+	}
 	// If we have no serial jobs pending or successful, trigger one.
 	if len(missings) > 0 && len(pendings) == 0 && len(successes) == 0 {
 		if ok, pr := pickSmallestPassingNumber(sp.log, c.ghc, missings, sp.cc); ok {
@@ -1374,20 +1509,20 @@ func (c *Controller) presubmitsByPull(sp *subpool) (map[int][]config.Presubmit,
 		filteredPRs = append(filteredPRs, pr)
 		log.Debugf("Found %d possible presubmits", len(presubmitsForPull))
 
-		for _, ps := range presubmitsForPull {
-			if !ps.ContextRequired() {
-				continue
-			}
-
+		required, err := requiredPresubmits(presubmitsForPull, func(ps config.Presubmit) (bool, error) {
 			shouldRun, err := ps.ShouldRun(sp.branch, c.changedFiles.prChanges(&pr), false, false)
 			if err != nil {
-				return nil, err
+				return false, err
 			}
 			if !shouldRun {
 				log.WithField("context", ps.Context).Debug("Presubmit excluded by ps.ShouldRun")
-				continue
 			}
-
+			return shouldRun, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, ps := range required {
 			record(int(pr.Number), ps)
 		}
 	}
@@ -1410,38 +1545,98 @@ func (c *Controller) presubmitsForBatch(prs []PullRequest, org, repo, baseSHA, b
 	}
 	log.Debugf("Found %d possible presubmits for batch", len(presubmits))
 
-	var result []config.Presubmit
-	for _, ps := range presubmits {
-		if !ps.ContextRequired() {
-			continue
-		}
-
+	result, err := requiredPresubmits(presubmits, func(ps config.Presubmit) (bool, error) {
 		shouldRun, err := ps.ShouldRun(baseBranch, c.changedFiles.batchChanges(prs), false, false)
 		if err != nil {
-			return nil, err
+			return false, err
 		}
 		if !shouldRun {
 			log.WithField("context", ps.Context).Debug("Presubmit excluded by ps.ShouldRun")
-			continue
 		}
-
-		result = append(result, ps)
+		return shouldRun, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	log.Debugf("After filtering, %d presubmits remained for batch", len(result))
 	return result, nil
 }
 
+// requiredPresubmits returns the presubmits that have to pass before a merge:
+// the presubmits that are required and should run, and the presubmits those
+// run after if they should run, too. A required presubmit that waits for an
+// optional one does not pass before the optional one succeeded.
+func requiredPresubmits(presubmits []config.Presubmit, shouldRun func(config.Presubmit) (bool, error)) ([]config.Presubmit, error) {
+	runs := make([]bool, len(presubmits))
+	running, required := sets.NewString(), sets.NewString()
+	var toVisit []string
+	for i, ps := range presubmits {
+		if !ps.ContextRequired() && !isParent(presubmits, ps.Name) {
+			continue
+		}
+		run, err := shouldRun(ps)
+		if err != nil {
+			return nil, err
+		}
+		if !run {
+			continue
+		}
+		runs[i] = true
+		running.Insert(ps.Name)
+		if ps.ContextRequired() && !required.Has(ps.Name) {
+			required.Insert(ps.Name)
+			toVisit = append(toVisit, ps.Name)
+		}
+	}
+	for len(toVisit) > 0 {
+		name := toVisit[0]
+		toVisit = toVisit[1:]
+		for i, ps := range presubmits {
+			if !runs[i] || ps.Name != name {
+				continue
+			}
+			for _, parent := range ps.RunAfterSuccess {
+				if running.Has(parent) && !required.Has(parent) {
+					required.Insert(parent)
+					toVisit = append(toVisit, parent)
+				}
+			}
+		}
+	}
+
+	var result []config.Presubmit
+	for i, ps := range presubmits {
+		if runs[i] && required.Has(ps.Name) {
+			result = append(result, ps)
+		}
+	}
+	return result, nil
+}
+
+// isParent determines whether any of the presubmits runs after the named one.
+func isParent(presubmits []config.Presubmit, name string) bool {
+	for _, ps := range presubmits {
+		for _, parent := range ps.RunAfterSuccess {
+			if parent == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (c *Controller) syncSubpool(sp subpool, blocks []blockers.Blocker) (Pool, error) {
 	sp.log.Infof("Syncing subpool: %d PRs, %d PJs.", len(sp.prs), len(sp.pjs))
 	successes, pendings, missings, missingSerialTests := accumulate(sp.presubmits, sp.prs, sp.pjs, sp.log)
-	batchMerge, batchPending := c.accumulateBatch(sp)
+	batchMerge, batchPending, speculativeBatches := c.accumulateBatch(sp)
 	sp.log.WithFields(logrus.Fields{
-		"prs-passing":   prNumbers(successes),
-		"prs-pending":   prNumbers(pendings),
-		"prs-missing":   prNumbers(missings),
-		"batch-passing": prNumbers(batchMerge),
-		"batch-pending": prNumbers(batchPending),
+		"prs-passing":         prNumbers(successes),
+		"prs-pending":         prNumbers(pendings),
+		"prs-missing":         prNumbers(missings),
+		"batch-passing":       prNumbers(batchMerge),
+		"batch-pending":       prNumbers(batchPending),
+		"batches-speculative": len(speculativeBatches),
 	}).Info("Subpool accumulated.")
 
 	var act Action
@@ -1451,17 +1646,23 @@ func (c *Controller) syncSubpool(sp subpool, blocks []blockers.Blocker) (Pool, e
 	if len(blocks) > 0 {
 		act = PoolBlocked
 	} else {
-		act, targets, err = c.takeAction(sp, batchPending, successes, pendings, missings, batchMerge, missingSerialTests)
+		var bisection *history.Bisection
+		var bisecting bool
+		act, targets, bisection, bisecting, err = c.bisectBatch(sp, batchPending, batchMerge)
+		if !bisecting {
+			act, targets, err = c.takeAction(sp, batchPending, speculativeBatches, successes, pendings, missings, batchMerge, missingSerialTests)
+		}
 		if err != nil {
 			errorString = err.Error()
 		}
 		if recordableActions[act] {
-			c.History.Record(
+			c.History.RecordBisection(
 				poolKey(sp.org, sp.repo, sp.branch),
 				string(act),
 				sp.sha,
 				errorString,
 				prMeta(targets...),
+				bisection,
 			)
 		}
 	}
@@ -1481,7 +1682,8 @@ func (c *Controller) syncSubpool(sp subpool, blocks []blockers.Blocker) (Pool, e
 			PendingPRs: pendings,
 			MissingPRs: missings,
 
-			BatchPending: batchPending,
+			BatchPending:       batchPending,
+			SpeculativeBatches: ownPRs(batchPending, speculativeBatches),
 
 			Action:   act,
 			Target:   targets,
@@ -1523,6 +1725,9 @@ func sortPools(pools []Pool) {
 		sortPRs(pools[i].PendingPRs)
 		sortPRs(pools[i].MissingPRs)
 		sortPRs(pools[i].BatchPending)
+		for _, batch := range pools[i].SpeculativeBatches {
+			sortPRs(batch)
+		}
 	}
 }
 
@@ -1592,6 +1797,12 @@ func (c *Controller) dividePool(pool map[string]PullRequest) (map[string]*subpoo
 		}
 		c.logger.WithField("subpool", subpoolkey).Debugf("Found %d prowjobs.", len(pjs.Items))
 		sps[subpoolkey].pjs = pjs.Items
+
+		speculativeJobs, err := c.speculativeJobs(sp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list speculative jobs for subpool %s: %v", subpoolkey, err)
+		}
+		sps[subpoolkey].pjs = append(sps[subpoolkey].pjs, speculativeJobs...)
 	}
 	return sps, nil
 }
@@ -1645,6 +1856,36 @@ type Commit struct {
 		Contexts []Context
 	}
 	OID githubql.String `graphql:"oid"`
+	// CheckSuites are kept small to stay below the node limit of the search
+	// query (100 PRs * 4 commits * 5 suites * 10 runs = 20,000 nodes).
+	// Commits with more check runs have them listed separately.
+	CheckSuites CheckSuites `graphql:"checkSuites(first: 5)"`
+}
+
+// CheckSuites holds graphql data about the check suites of a commit.
+type CheckSuites struct {
+	PageInfo struct {
+		HasNextPage githubql.Boolean
+	}
+	Nodes []CheckSuite
+}
+
+// CheckSuite holds graphql data about a check suite and its check runs.
+type CheckSuite struct {
+	CheckRuns struct {
+		PageInfo struct {
+			HasNextPage githubql.Boolean
+		}
+		Nodes []CheckRun
+	} `graphql:"checkRuns(first: 10, filterBy: {checkType: LATEST})"`
+}
+
+// CheckRun holds graphql data about a check run.
+type CheckRun struct {
+	Name       githubql.String
+	Status     githubql.String
+	Conclusion githubql.String
+	StartedAt  githubql.DateTime
 }
 
 // Context holds graphql response data for github contexts.
@@ -1683,6 +1924,7 @@ func (pr *PullRequest) logFields() logrus.Fields {
 }
 
 // headContexts gets the status contexts for the commit with OID == pr.HeadRefOID
+// along with its check runs coerced to contexts.
 //
 // First, we try to get this value from the commits we got with the PR query.
 // Unfortunately the 'last' commit ordering is determined by author date
@@ -1690,16 +1932,26 @@ func (pr *PullRequest) logFields() logrus.Fields {
 // branch the 'last' commit isn't necessarily the logically last commit.
 // We list multiple commits with the query to increase our chance of success,
 // but if we don't find the head commit we have to ask GitHub for it
-// specifically (this costs an API token).
+// specifically (this costs API tokens). The same goes for the check runs of
+// commits with more check runs than the query lists.
 func headContexts(log *logrus.Entry, ghc githubClient, pr *PullRequest) ([]Context, error) {
 	for _, node := range pr.Commits.Nodes {
-		if node.Commit.OID == pr.HeadRefOID {
-			return node.Commit.Status.Contexts, nil
+		if node.Commit.OID != pr.HeadRefOID {
+			continue
 		}
+		checkRuns, complete := node.Commit.CheckSuites.checkRuns()
+		if !complete {
+			log.Info("Check runs of the head commit were truncated. Listing them from GitHub...")
+			var err error
+			if checkRuns, err = listCheckRuns(ghc, pr); err != nil {
+				return nil, err
+			}
+		}
+		return append(append([]Context{}, node.Commit.Status.Contexts...), checkRunContexts(checkRuns)...), nil
 	}
 	// We didn't get the head commit from the query (the commits must not be
 	// logically ordered) so we need to specifically ask GitHub for the status
-	// and coerce it to a graphql type.
+	// and check runs and coerce them to graphql types.
 	org := string(pr.Repository.Owner.Login)
 	repo := string(pr.Repository.Name)
 	// Log this event so we can tune the number of commits we list to minimize this.
@@ -1719,16 +1971,114 @@ func headContexts(log *logrus.Entry, ghc githubClient, pr *PullRequest) ([]Conte
 			},
 		)
 	}
-	// Add a commit with these contexts to pr for future look ups.
-	pr.Commits.Nodes = append(pr.Commits.Nodes,
-		struct{ Commit Commit }{
-			Commit: Commit{
-				OID:    pr.HeadRefOID,
-				Status: struct{ Contexts []Context }{Contexts: contexts},
-			},
-		},
-	)
-	return contexts, nil
+	checkRuns, err := listCheckRuns(ghc, pr)
+	if err != nil {
+		return nil, err
+	}
+	// Add a commit with these contexts and check runs to pr for future look ups.
+	commit := Commit{
+		OID:    pr.HeadRefOID,
+		Status: struct{ Contexts []Context }{Contexts: contexts},
+	}
+	commit.CheckSuites.Nodes = []CheckSuite{{}}
+	commit.CheckSuites.Nodes[0].CheckRuns.Nodes = checkRuns
+	pr.Commits.Nodes = append(pr.Commits.Nodes, struct{ Commit Commit }{Commit: commit})
+	return append(contexts, checkRunContexts(checkRuns)...), nil
+}
+
+// checkRuns returns the check runs of all check suites, and whether none of
+// them were left out of the query.
+func (c CheckSuites) checkRuns() ([]CheckRun, bool) {
+	complete := !bool(c.PageInfo.HasNextPage)
+	var checkRuns []CheckRun
+	for _, suite := range c.Nodes {
+		checkRuns = append(checkRuns, suite.CheckRuns.Nodes...)
+		if suite.CheckRuns.PageInfo.HasNextPage {
+			complete = false
+		}
+	}
+	return checkRuns, complete
+}
+
+// listCheckRuns lists the check runs on the head commit of the PR and
+// coerces them to graphql types.
+func listCheckRuns(ghc githubClient, pr *PullRequest) ([]CheckRun, error) {
+	org := string(pr.Repository.Owner.Login)
+	repo := string(pr.Repository.Name)
+	listed, err := ghc.ListCheckRuns(org, repo, string(pr.HeadRefOID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the check runs: %v", err)
+	}
+	checkRuns := make([]CheckRun, 0, len(listed))
+	for _, checkRun := range listed {
+		checkRuns = append(checkRuns, CheckRun{
+			Name:       githubql.String(checkRun.Name),
+			Status:     githubql.String(strings.ToUpper(checkRun.Status)),
+			Conclusion: githubql.String(strings.ToUpper(checkRun.Conclusion)),
+		})
+		if checkRun.StartedAt != nil {
+			checkRuns[len(checkRuns)-1].StartedAt = githubql.DateTime{Time: *checkRun.StartedAt}
+		}
+	}
+	return checkRuns, nil
+}
+
+// latestCheckRuns keeps only the newest check run of every name. Re-running a
+// check adds a run, possibly in another check suite, and the old run stays on
+// the commit. Runs that have not started yet are the newest ones.
+func latestCheckRuns(checkRuns []CheckRun) []CheckRun {
+	newer := func(a, b CheckRun) bool {
+		switch {
+		case a.StartedAt.IsZero():
+			return true
+		case b.StartedAt.IsZero():
+			return false
+		}
+		return !a.StartedAt.Before(b.StartedAt.Time)
+	}
+	latest := make([]CheckRun, 0, len(checkRuns))
+	index := map[githubql.String]int{}
+	for _, checkRun := range checkRuns {
+		i, seen := index[checkRun.Name]
+		if !seen {
+			index[checkRun.Name] = len(latest)
+			latest = append(latest, checkRun)
+			continue
+		}
+		if newer(checkRun, latest[i]) {
+			latest[i] = checkRun
+		}
+	}
+	return latest
+}
+
+// checkRunContexts coerces check runs, e.g. those of GitHub Actions, to
+// contexts so that they gate merges just like statuses do. Only the newest
+// run of every check counts.
+func checkRunContexts(checkRuns []CheckRun) []Context {
+	checkRuns = latestCheckRuns(checkRuns)
+	contexts := make([]Context, 0, len(checkRuns))
+	for _, checkRun := range checkRuns {
+		status := strings.ToLower(string(checkRun.Status))
+		conclusion := strings.ToLower(string(checkRun.Conclusion))
+		context := Context{
+			Context:     checkRun.Name,
+			Description: githubql.String(conclusion),
+		}
+		switch {
+		case status != github.CheckRunStatusCompleted:
+			context.State = githubql.StatusStatePending
+			context.Description = githubql.String(status)
+		case conclusion == github.CheckRunConclusionSuccess,
+			conclusion == github.CheckRunConclusionNeutral,
+			conclusion == github.CheckRunConclusionSkipped:
+			context.State = githubql.StatusStateSuccess
+		default:
+			context.State = githubql.StatusStateFailure
+		}
+		contexts = append(contexts, context)
+	}
+	return contexts
 }
 
 func orgRepoQueryString(orgs, repos []string, orgExceptions map[string]sets.String) string {
@@ -97,8 +97,9 @@ func TestAccumulateBatch(t *testing.T) {
 		prowJobs       []prowjob
 		prowYAMLGetter config.ProwYAMLGetter
 
-		merges  []int
-		pending bool
+		merges      []int
+		pending     bool
+		speculative [][]int
 	}{
 		{
 			name: "no batches running",
@@ -224,6 +225,44 @@ func TestAccumulateBatch(t *testing.T) {
 			pending: false,
 			merges:  []int{2},
 		},
+		{
+			name: "speculative batches on top of pending batch",
+			presubmits: []config.Presubmit{
+				{Reporter: config.Reporter{Context: "foo"}},
+			},
+			pulls: []pull{{1, "a"}, {2, "b"}, {3, "c"}, {4, "d"}},
+			prowJobs: []prowjob{
+				{job: "foo", state: prowapi.PendingState, prs: []pull{{1, "a"}, {2, "b"}}},
+				{job: "foo", state: prowapi.PendingState, prs: []pull{{1, "a"}, {2, "b"}, {3, "c"}, {4, "d"}}},
+				{job: "foo", state: prowapi.PendingState, prs: []pull{{1, "a"}, {2, "b"}, {3, "c"}}},
+				{job: "foo", state: prowapi.PendingState, prs: []pull{{3, "c"}, {4, "d"}}},
+			},
+			pending:     true,
+			speculative: [][]int{{1, 2, 3}, {1, 2, 3, 4}},
+		},
+		{
+			name: "speculative batch on top of failed batch is discarded",
+			presubmits: []config.Presubmit{
+				{Reporter: config.Reporter{Context: "foo"}},
+			},
+			pulls: []pull{{1, "a"}, {2, "b"}, {3, "c"}},
+			prowJobs: []prowjob{
+				{job: "foo", state: prowapi.FailureState, prs: []pull{{1, "a"}, {2, "b"}}},
+				{job: "foo", state: prowapi.PendingState, prs: []pull{{1, "a"}, {2, "b"}, {3, "c"}}},
+			},
+		},
+		{
+			name: "successful speculative batch merges with the batch it was tested on top of",
+			presubmits: []config.Presubmit{
+				{Reporter: config.Reporter{Context: "foo"}},
+			},
+			pulls: []pull{{1, "a"}, {2, "b"}, {3, "c"}},
+			prowJobs: []prowjob{
+				{job: "foo", state: prowapi.SuccessState, prs: []pull{{1, "a"}, {2, "b"}}},
+				{job: "foo", state: prowapi.SuccessState, prs: []pull{{1, "a"}, {2, "b"}, {3, "c"}}},
+			},
+			merges: []int{1, 2, 3},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -280,11 +319,18 @@ func TestAccumulateBatch(t *testing.T) {
 				changedFiles: &changedFilesAgent{},
 				logger:       logrus.WithField("test", test.name),
 			}
-			merges, pending := c.accumulateBatch(subpool{org: "org", repo: "repo", prs: pulls, pjs: pjs, log: logrus.WithField("test", test.name)})
+			merges, pending, speculative := c.accumulateBatch(subpool{org: "org", repo: "repo", prs: pulls, pjs: pjs, log: logrus.WithField("test", test.name)})
 			if (len(pending) > 0) != test.pending {
 				t.Errorf("For case \"%s\", got wrong pending.", test.name)
 			}
 			testPullsMatchList(t, test.name, merges, test.merges)
+			var speculativeNumbers [][]int
+			for _, batch := range speculative {
+				speculativeNumbers = append(speculativeNumbers, prNumbers(batch))
+			}
+			if !reflect.DeepEqual(speculativeNumbers, test.speculative) {
+				t.Errorf("For case \"%s\", got speculative batches %v, expected %v.", test.name, speculativeNumbers, test.speculative)
+			}
 		})
 	}
 }
@@ -316,6 +362,7 @@ func TestAccumulate(t *testing.T) {
 		successes []int
 		pendings  []int
 		none      []int
+		missing   map[int][]string
 	}{
 		{
 			pullRequests: map[int]string{1: "", 2: "", 3: "", 4: "", 5: "", 6: "", 7: ""},
@@ -480,6 +527,28 @@ func TestAccumulate(t *testing.T) {
 			pendings:  []int{},
 			none:      []int{},
 		},
+		{
+			pullRequests: map[int]string{1: "", 2: "", 3: "", 4: ""},
+			presubmits: func() map[int][]config.Presubmit {
+				jobs := []config.Presubmit{
+					{JobBase: config.JobBase{Name: "build"}, Reporter: config.Reporter{Context: "build"}},
+					{JobBase: config.JobBase{Name: "e2e"}, Reporter: config.Reporter{Context: "e2e"}, RunAfterSuccess: []string{"build"}},
+				}
+				return map[int][]config.Presubmit{1: jobs, 2: jobs, 3: jobs, 4: jobs}
+			}(),
+			prowJobs: []prowjob{
+				{1, "build", prowapi.FailureState, ""},
+				{2, "build", prowapi.PendingState, ""},
+				{3, "build", prowapi.SuccessState, ""},
+				{4, "build", prowapi.SuccessState, ""},
+				{4, "e2e", prowapi.SuccessState, ""},
+			},
+
+			successes: []int{4},
+			pendings:  []int{2},
+			none:      []int{1, 3},
+			missing:   map[int][]string{1: {"build"}, 3: {"e2e"}},
+		},
 	}
 
 	for i, test := range tests {
@@ -503,12 +572,23 @@ func TestAccumulate(t *testing.T) {
 			})
 		}
 
-		successes, pendings, nones, _ := accumulate(test.presubmits, pulls, pjs, logrus.NewEntry(logrus.New()))
+		successes, pendings, nones, missingTests := accumulate(test.presubmits, pulls, pjs, logrus.NewEntry(logrus.New()))
 
 		t.Logf("test run %d", i)
 		testPullsMatchList(t, "successes", successes, test.successes)
 		testPullsMatchList(t, "pendings", pendings, test.pendings)
 		testPullsMatchList(t, "nones", nones, test.none)
+		if test.missing != nil {
+			missing := map[int][]string{}
+			for num, presubmits := range missingTests {
+				for _, ps := range presubmits {
+					missing[num] = append(missing[num], ps.Context)
+				}
+			}
+			if !reflect.DeepEqual(missing, test.missing) {
+				t.Errorf("expected missing tests %v, got %v", test.missing, missing)
+			}
+		}
 	}
 }
 
@@ -524,6 +604,11 @@ type fgc struct {
 
 	expectedSHA    string
 	combinedStatus map[string]string
+	checkRuns      map[string][]github.CheckRun
+	checkRunLists  int
+
+	labels   map[int][]string
+	comments map[int][]string
 }
 
 func (f *fgc) GetRepo(o, r string) (github.FullRepo, error) {
@@ -540,6 +625,22 @@ func (f *fgc) GetRepo(o, r string) (github.FullRepo, error) {
 	return repo, nil
 }
 
+func (f *fgc) AddLabel(org, repo string, number int, label string) error {
+	if f.labels == nil {
+		f.labels = map[int][]string{}
+	}
+	f.labels[number] = append(f.labels[number], label)
+	return f.err
+}
+
+func (f *fgc) CreateComment(owner, repo string, number int, comment string) error {
+	if f.comments == nil {
+		f.comments = map[int][]string{}
+	}
+	f.comments[number] = append(f.comments[number], comment)
+	return f.err
+}
+
 func (f *fgc) GetRef(o, r, ref string) (string, error) {
 	return f.refs[o+"/"+r+" "+ref], f.err
 }
@@ -595,6 +696,11 @@ func (f *fgc) GetCombinedStatus(org, repo, ref string) (*github.CombinedStatus,
 		nil
 }
 
+func (f *fgc) ListCheckRuns(org, repo, ref string) ([]github.CheckRun, error) {
+	f.checkRunLists++
+	return f.checkRuns[ref], nil
+}
+
 func (f *fgc) GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error) {
 	if number != 100 {
 		return nil, nil
@@ -918,6 +1024,7 @@ func testPickBatch(clients localgit.Clients, t *testing.T) {
 	})
 	c := &Controller{
 		logger: logrus.WithField("component", "tide"),
+		ghc:    &fgc{},
 		gc:     gc,
 		config: ca.Config,
 	}
@@ -932,7 +1039,7 @@ func testPickBatch(clients localgit.Clients, t *testing.T) {
 		6: &config.TideContextPolicy{},
 		7: &config.TideContextPolicy{},
 		8: &config.TideContextPolicy{},
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("Error from pickBatch: %v", err)
 	}
@@ -1136,13 +1243,90 @@ func testTakeAction(clients localgit.Clients, t *testing.T) {
 		batchMerges  []int
 		presubmits   map[int][]config.Presubmit
 		mergeErrs    map[int]error
-
-		merged           int
-		triggered        int
-		triggeredBatches int
-		action           Action
-		expectErr        bool
+		// pendingBatch and speculativeBatches are the PRs of the pending
+		// batch and those each speculative batch adds on top of it.
+		pendingBatch       []int
+		speculativeBatches [][]int
+		speculativeDepth   int
+
+		merged            int
+		triggered         int
+		triggeredBatches  int
+		batchPulls        []int
+		action            Action
+		expectErr         bool
+		expectSpeculation bool
 	}{
+		{
+			name: "pending batch with room to speculate, should trigger speculative batch",
+
+			pendingBatch:     []int{1, 2},
+			nones:            []int{3, 4},
+			speculativeDepth: 1,
+			presubmits: map[int][]config.Presubmit{
+				100: {
+					{Reporter: config.Reporter{Context: "foo"}},
+				},
+			},
+			triggered:        1,
+			triggeredBatches: 1,
+			batchPulls:       []int{1, 2, 3, 4},
+			action:           TriggerSpeculativeBatch,
+		},
+		{
+			name: "speculative batch with room to speculate, should trigger speculative batch on top of it",
+
+			pendingBatch:       []int{1, 2},
+			speculativeBatches: [][]int{{3}},
+			nones:              []int{4, 5},
+			speculativeDepth:   2,
+			presubmits: map[int][]config.Presubmit{
+				100: {
+					{Reporter: config.Reporter{Context: "foo"}},
+				},
+			},
+			triggered:        1,
+			triggeredBatches: 1,
+			batchPulls:       []int{1, 2, 3, 4, 5},
+			action:           TriggerSpeculativeBatch,
+		},
+		{
+			name: "speculative depth reached, should trigger serial",
+
+			pendingBatch:       []int{1, 2},
+			speculativeBatches: [][]int{{3}},
+			nones:              []int{4, 5},
+			speculativeDepth:   1,
+			presubmits: map[int][]config.Presubmit{
+				100: {
+					{Reporter: config.Reporter{Context: "foo"}},
+				},
+			},
+			triggered: 1,
+			action:    Trigger,
+		},
+		{
+			name: "pending batch without speculation, should trigger serial",
+
+			pendingBatch: []int{1, 2},
+			nones:        []int{3, 4},
+			presubmits: map[int][]config.Presubmit{
+				100: {
+					{Reporter: config.Reporter{Context: "foo"}},
+				},
+			},
+			triggered: 1,
+			action:    Trigger,
+		},
+		{
+			name: "batch merge with speculation, should record the merge",
+
+			batchMerges:       []int{1, 2},
+			speculativeDepth:  1,
+			merged:            2,
+			action:            MergeBatch,
+			expectSpeculation: true,
+		},
 		{
 			name: "no prs to test, should do nothing",
 
@@ -1424,7 +1608,10 @@ func testTakeAction(clients localgit.Clients, t *testing.T) {
 	for _, tc := range testcases {
 		ca := &config.Agent{}
 		pjNamespace := "pj-ns"
-		cfg := &config.Config{ProwConfig: config.ProwConfig{ProwJobNamespace: pjNamespace}}
+		cfg := &config.Config{ProwConfig: config.ProwConfig{
+			ProwJobNamespace: pjNamespace,
+			Tide:             config.Tide{SpeculativeDepthMap: map[string]int{"*": tc.speculativeDepth}},
+		}}
 		if err := cfg.SetPresubmits(
 			map[string][]config.Presubmit{
 				"o/r": {
@@ -1527,8 +1714,17 @@ func testTakeAction(clients localgit.Clients, t *testing.T) {
 		if tc.batchPending {
 			batchPending = []PullRequest{{}}
 		}
+		if len(tc.pendingBatch) > 0 {
+			batchPending = genPulls(tc.pendingBatch)
+		}
+		var speculativeBatches [][]PullRequest
+		top := batchPending
+		for _, batch := range tc.speculativeBatches {
+			top = append(append([]PullRequest{}, top...), genPulls(batch)...)
+			speculativeBatches = append(speculativeBatches, top)
+		}
 		t.Logf("Test case: %s", tc.name)
-		if act, _, err := c.takeAction(sp, batchPending, genPulls(tc.successes), genPulls(tc.pendings), genPulls(tc.nones), genPulls(tc.batchMerges), sp.presubmits); err != nil && !tc.expectErr {
+		if act, _, err := c.takeAction(sp, batchPending, speculativeBatches, genPulls(tc.successes), genPulls(tc.pendings), genPulls(tc.nones), genPulls(tc.batchMerges), sp.presubmits); err != nil && !tc.expectErr {
 			t.Errorf("Unexpected error in takeAction: %v", err)
 			continue
 		} else if err == nil && tc.expectErr {
@@ -1567,6 +1763,18 @@ func testTakeAction(clients localgit.Clients, t *testing.T) {
 			if len(job.Spec.Refs.Pulls) <= 1 {
 				t.Error("Found a batch job that doesn't contain multiple pull refs!")
 			}
+			if tc.batchPulls != nil {
+				var pulls []int
+				for _, pull := range job.Spec.Refs.Pulls {
+					pulls = append(pulls, pull.Number)
+				}
+				if !reflect.DeepEqual(pulls, tc.batchPulls) {
+					t.Errorf("Wrong pulls in batch. Got %v, expected %v.", pulls, tc.batchPulls)
+				}
+			}
+		}
+		if recorded := len(c.speculations["o/r:master"]) > 0; recorded != tc.expectSpeculation {
+			t.Errorf("Expected the merge to be recorded for speculation: %t, got %t.", tc.expectSpeculation, recorded)
 		}
 	}
 }
@@ -1682,6 +1890,177 @@ func TestHeadContexts(t *testing.T) {
 	}
 }
 
+func TestHeadCheckRunContexts(t *testing.T) {
+	headSHA := "head"
+	checkRuns := []github.CheckRun{
+		{Name: "queued", Status: github.CheckRunStatusQueued},
+		{Name: "running", Status: github.CheckRunStatusInProgress},
+		{Name: "success", Status: github.CheckRunStatusCompleted, Conclusion: github.CheckRunConclusionSuccess},
+		{Name: "neutral", Status: github.CheckRunStatusCompleted, Conclusion: github.CheckRunConclusionNeutral},
+		{Name: "skipped", Status: github.CheckRunStatusCompleted, Conclusion: github.CheckRunConclusionSkipped},
+		{Name: "failure", Status: github.CheckRunStatusCompleted, Conclusion: github.CheckRunConclusionFailure},
+		{Name: "timed-out", Status: github.CheckRunStatusCompleted, Conclusion: github.CheckRunConclusionTimedOut},
+		{Name: "action-required", Status: github.CheckRunStatusCompleted, Conclusion: github.CheckRunConclusionActionRequired},
+	}
+	// the graphql API returns the same check runs in upper case
+	var queried []CheckRun
+	for _, checkRun := range checkRuns {
+		queried = append(queried, CheckRun{
+			Name:       githubql.String(checkRun.Name),
+			Status:     githubql.String(strings.ToUpper(checkRun.Status)),
+			Conclusion: githubql.String(strings.ToUpper(checkRun.Conclusion)),
+		})
+	}
+	expected := map[string]githubql.StatusState{
+		"status":          githubql.StatusStateSuccess,
+		"queued":          githubql.StatusStatePending,
+		"running":         githubql.StatusStatePending,
+		"success":         githubql.StatusStateSuccess,
+		"neutral":         githubql.StatusStateSuccess,
+		"skipped":         githubql.StatusStateSuccess,
+		"failure":         githubql.StatusStateFailure,
+		"timed-out":       githubql.StatusStateFailure,
+		"action-required": githubql.StatusStateFailure,
+	}
+
+	testCases := []struct {
+		name          string
+		suites        func() CheckSuites
+		expectedLists int
+	}{
+		{
+			name: "check runs come from the query",
+			suites: func() CheckSuites {
+				var suites CheckSuites
+				suites.Nodes = []CheckSuite{{}, {}}
+				suites.Nodes[0].CheckRuns.Nodes = queried[:4]
+				suites.Nodes[1].CheckRuns.Nodes = queried[4:]
+				return suites
+			},
+		},
+		{
+			name: "truncated check runs are listed",
+			suites: func() CheckSuites {
+				var suites CheckSuites
+				suites.Nodes = []CheckSuite{{}}
+				suites.Nodes[0].CheckRuns.Nodes = queried[:2]
+				suites.Nodes[0].CheckRuns.PageInfo.HasNextPage = true
+				return suites
+			},
+			expectedLists: 1,
+		},
+		{
+			name: "truncated check suites are listed",
+			suites: func() CheckSuites {
+				var suites CheckSuites
+				suites.Nodes = []CheckSuite{{}}
+				suites.Nodes[0].CheckRuns.Nodes = queried[:2]
+				suites.PageInfo.HasNextPage = true
+				return suites
+			},
+			expectedLists: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fgc := &fgc{checkRuns: map[string][]github.CheckRun{headSHA: checkRuns}}
+			pr := &PullRequest{HeadRefOID: githubql.String(headSHA)}
+			pr.Commits.Nodes = append(pr.Commits.Nodes, struct{ Commit Commit }{Commit{
+				OID: githubql.String(headSHA),
+				Status: struct{ Contexts []Context }{
+					Contexts: []Context{{Context: "status", State: githubql.StatusStateSuccess}},
+				},
+				CheckSuites: tc.suites(),
+			}})
+
+			contexts, err := headContexts(logrus.WithField("component", "tide"), fgc, pr)
+			if err != nil {
+				t.Fatalf("Unexpected error from headContexts: %v", err)
+			}
+			states := map[string]githubql.StatusState{}
+			for _, ctx := range contexts {
+				states[string(ctx.Context)] = ctx.State
+			}
+			if !reflect.DeepEqual(states, expected) {
+				t.Errorf("Expected context states %v, but got %v", expected, states)
+			}
+			if fgc.checkRunLists != tc.expectedLists {
+				t.Errorf("Expected %d check run lists, but got %d", tc.expectedLists, fgc.checkRunLists)
+			}
+		})
+	}
+}
+
+func TestHeadCheckRunContextsOfRetestedChecks(t *testing.T) {
+	headSHA := "head"
+	failedAt := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	passedAt := failedAt.Add(time.Hour)
+	checkRuns := []github.CheckRun{
+		{Name: "retested", Status: github.CheckRunStatusCompleted, Conclusion: github.CheckRunConclusionFailure, StartedAt: &failedAt},
+		{Name: "retested", Status: github.CheckRunStatusCompleted, Conclusion: github.CheckRunConclusionSuccess, StartedAt: &passedAt},
+		{Name: "rerunning", Status: github.CheckRunStatusCompleted, Conclusion: github.CheckRunConclusionSuccess, StartedAt: &failedAt},
+		{Name: "rerunning", Status: github.CheckRunStatusQueued},
+	}
+	var queried []CheckRun
+	for _, checkRun := range checkRuns {
+		run := CheckRun{
+			Name:       githubql.String(checkRun.Name),
+			Status:     githubql.String(strings.ToUpper(checkRun.Status)),
+			Conclusion: githubql.String(strings.ToUpper(checkRun.Conclusion)),
+		}
+		if checkRun.StartedAt != nil {
+			run.StartedAt = githubql.DateTime{Time: *checkRun.StartedAt}
+		}
+		queried = append(queried, run)
+	}
+	expected := []Context{
+		{Context: "retested", Description: "success", State: githubql.StatusStateSuccess},
+		{Context: "rerunning", Description: "queued", State: githubql.StatusStatePending},
+	}
+
+	testCases := []struct {
+		name   string
+		suites func() CheckSuites
+	}{
+		{
+			name: "check runs of several suites come from the query",
+			suites: func() CheckSuites {
+				var suites CheckSuites
+				suites.Nodes = []CheckSuite{{}, {}}
+				suites.Nodes[0].CheckRuns.Nodes = []CheckRun{queried[0], queried[2]}
+				suites.Nodes[1].CheckRuns.Nodes = []CheckRun{queried[1], queried[3]}
+				return suites
+			},
+		},
+		{
+			name: "check runs are listed",
+			suites: func() CheckSuites {
+				var suites CheckSuites
+				suites.PageInfo.HasNextPage = true
+				return suites
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fgc := &fgc{checkRuns: map[string][]github.CheckRun{headSHA: checkRuns}}
+			pr := &PullRequest{HeadRefOID: githubql.String(headSHA)}
+			pr.Commits.Nodes = append(pr.Commits.Nodes, struct{ Commit Commit }{Commit{
+				OID:         githubql.String(headSHA),
+				CheckSuites: tc.suites(),
+			}})
+
+			contexts, err := headContexts(logrus.WithField("component", "tide"), fgc, pr)
+			if err != nil {
+				t.Fatalf("Unexpected error from headContexts: %v", err)
+			}
+			if !reflect.DeepEqual(contexts, expected) {
+				t.Errorf("Expected contexts %v, but got %v", expected, contexts)
+			}
+		})
+	}
+}
+
 func testPR(org, repo, branch string, number int, mergeable githubql.MergeableState) PullRequest {
 	pr := PullRequest{
 		Number:     githubql.Int(number),
@@ -2192,7 +2571,7 @@ func TestFilterSubpool(t *testing.T) {
 
 			configGetter := func() *config.Config { return &config.Config{} }
 			mmc := newMergeChecker(configGetter, &fgc{})
-			filtered := filterSubpool(nil, mmc.isAllowed, sp)
+			filtered := filterSubpool(&fgc{}, mmc.isAllowed, sp)
 			if len(tc.expectedPRs) == 0 {
 				if filtered != nil {
 					t.Fatalf("Expected subpool to be pruned, but got: %v", filtered)
@@ -2534,6 +2913,44 @@ func TestPresubmitsByPull(t *testing.T) {
 			}}},
 			expectedChangeCache: map[changeCacheKey][]string{{number: 100, sha: "sha"}: {"FILE"}},
 		},
+		{
+			name: "jobs that required jobs run after are required",
+			presubmits: []config.Presubmit{
+				{
+					JobBase:   config.JobBase{Name: "build"},
+					Reporter:  config.Reporter{Context: "build"},
+					AlwaysRun: true,
+					Optional:  true,
+				},
+				{
+					JobBase:         config.JobBase{Name: "e2e"},
+					Reporter:        config.Reporter{Context: "e2e"},
+					AlwaysRun:       true,
+					RunAfterSuccess: []string{"build", "manual"},
+				},
+				{
+					JobBase:  config.JobBase{Name: "manual"},
+					Reporter: config.Reporter{Context: "manual"},
+				},
+				{
+					JobBase:   config.JobBase{Name: "lint"},
+					Reporter:  config.Reporter{Context: "lint"},
+					AlwaysRun: true,
+					Optional:  true,
+				},
+			},
+			expectedPresubmits: map[int][]config.Presubmit{100: {{
+				JobBase:   config.JobBase{Name: "build"},
+				Reporter:  config.Reporter{Context: "build"},
+				AlwaysRun: true,
+				Optional:  true,
+			}, {
+				JobBase:         config.JobBase{Name: "e2e"},
+				Reporter:        config.Reporter{Context: "e2e"},
+				AlwaysRun:       true,
+				RunAfterSuccess: []string{"build", "manual"},
+			}}},
+		},
 		{
 			name: "inrepoconfig presubmits get only added to the corresponding pull",
 			presubmits: []config.Presubmit{{
@@ -3387,6 +3804,9 @@ func (c *indexingClient) List(ctx context.Context, list runtime.Object, opts ...
 		opt.ApplyToList(listOpts)
 	}
 
+	if listOpts.FieldSelector == nil {
+		return nil
+	}
 	if n := len(listOpts.FieldSelector.Requirements()); n == 0 {
 		return nil
 	} else if n > 1 {